@@ -1,6 +1,6 @@
 /*
  * Copyright text:
- * This file was last modified at 2024-07-10 21:53 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * main.go
@@ -23,6 +23,7 @@ import (
 	"github.com/pressly/goose/v3"
 	"github.com/vskurikhin/gofavorites/internal/alog"
 	"github.com/vskurikhin/gofavorites/internal/controllers"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
 	"github.com/vskurikhin/gofavorites/internal/env"
 	"github.com/vskurikhin/gofavorites/internal/interceptors"
 	"github.com/vskurikhin/gofavorites/internal/middleware"
@@ -35,6 +36,7 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -43,6 +45,8 @@ import (
 	_ "google.golang.org/grpc/encoding/gzip"
 )
 
+const mongoBootstrapTimeout = 30 * time.Second
+
 var (
 	buildVersion = "N/A"
 	buildDate    = "N/A"
@@ -83,9 +87,74 @@ func run(ctx context.Context) {
 	prop := env.GetProperties()
 	sLog = alog.GetLogger()
 	dbMigrations(prop)
+	checkAuthProvider(prop)
+	checkAuthPolicy(prop)
+	checkStore(prop)
+	checkOutbox(prop)
+	checkUpkKeyring(ctx, prop)
+	mongoBootstrap(ctx, prop)
 	serve(ctx, prop)
 }
 
+// checkAuthProvider проверка способа входа: с неизвестным auth.provider
+// сервис не запускается.
+func checkAuthProvider(prop env.Properties) {
+	if err := services.CheckAuthProvider(prop); err != nil {
+		sLog.Error(env.MSG+"checkAuthProvider", "msg", "Неизвестный способ проверки учётных данных", "err", err)
+		log.Fatal(err)
+	}
+}
+
+// checkAuthPolicy проверка политики доступа: если файл политики не прочитан,
+// сервис не запускается.
+func checkAuthPolicy(prop env.Properties) {
+	if err := env.CheckAuthPolicy(prop); err != nil {
+		sLog.Error(env.MSG+"checkAuthPolicy", "msg", "Политика доступа не загружена", "err", err)
+		log.Fatal(err)
+	}
+}
+
+// checkStore проверка хранилища синхронизации: с неизвестным именем хранилища
+// или неоткрытым файлом встроенного хранилища сервис не запускается.
+func checkStore(prop env.Properties) {
+	if err := mongo.CheckStore(prop); err != nil {
+		sLog.Error(env.MSG+"checkStore", "msg", "Хранилище синхронизации недоступно", "err", err)
+		log.Fatal(err)
+	}
+}
+
+// checkOutbox проверка диспетчера outbox: без него изменения избранного
+// не доходят до MongoDB, поэтому с выключенным диспетчером сервис не запускается.
+func checkOutbox(prop env.Properties) {
+	if err := services.CheckOutbox(prop); err != nil {
+		sLog.Error(env.MSG+"checkOutbox", "msg", "Диспетчер outbox выключен", "err", err)
+		log.Fatal(err)
+	}
+}
+
+// checkUpkKeyring проверка связки версий секрета UPK: без версии, которой
+// записаны данные пользователей, эти данные не дешифровать, поэтому сервис не запускается.
+func checkUpkKeyring(ctx context.Context, prop env.Properties) {
+	if err := services.CheckUpkKeyring(ctx, prop); err != nil {
+		sLog.Error(env.MSG+"checkUpkKeyring", "msg", "В связке нет версии секрета UPK", "err", err)
+		log.Fatal(err)
+	}
+}
+
+// mongoBootstrap подготовка коллекции MongoDB до начала обслуживания запросов:
+// без уникального индекса (upk, isin) условный upsert создаёт дубликаты,
+// поэтому сервис не запускается, если коллекцию подготовить не удалось.
+func mongoBootstrap(ctx context.Context, prop env.Properties) {
+
+	ctx, cancel := context.WithTimeout(ctx, mongoBootstrapTimeout)
+	defer cancel()
+
+	if err := mongo.Bootstrap(ctx, prop); err != nil {
+		sLog.Error(env.MSG+"mongoBootstrap", "msg", "Ошибка подготовки коллекции MongoDB", "err", err)
+		log.Fatal(err)
+	}
+}
+
 func dbMigrations(prop env.Properties) {
 
 	pool := prop.DBPool()
@@ -124,27 +193,51 @@ func serve(ctx context.Context, prop env.Properties) {
 
 	httpServer := makeHTTP(prop)
 	grpcServer := makeGRPC(prop)
+	workersCtx, workersCancel := context.WithCancel(ctx)
+	defer workersCancel()
 
-	go func() {
-		<-sigint
+	gracefulStop := sync.OnceFunc(func() {
+		workersCancel()
 		grpcServer.GracefulStop()
 		sLog.Info(env.MSG+"graceful stop", "msg", "Выключение сервера gRPC")
 		if err := httpServer.Shutdown(); err != nil {
 			sLog.Error(env.MSG+"graceful stop", "msg", "Ошибка при выключение сервера HTTP", "err", err)
 		}
 		sLog.Info(env.MSG+"graceful stop", "msg", "Выключение сервера HTTP")
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), services.SyncDrainTimeout(prop))
+		defer drainCancel()
+		if err := services.GetSyncExecutor(prop).Shutdown(drainCtx); err != nil {
+			sLog.Error(env.MSG+"graceful stop", "msg", "Ошибка при завершении задач синхронизации", "err", err)
+		}
+		sLog.Info(env.MSG+"graceful stop", "msg", "Завершение задач синхронизации")
+		if err := prop.MongodbClient().Disconnect(drainCtx); err != nil {
+			sLog.Error(env.MSG+"graceful stop", "msg", "Ошибка при отключении от MongoDB", "err", err)
+		}
+		if err := mongo.CloseStore(prop); err != nil {
+			sLog.Error(env.MSG+"graceful stop", "msg", "Ошибка при закрытии хранилища синхронизации", "err", err)
+		}
+		if err := prop.ExternalAssetGRPCClient().Close(); err != nil {
+			sLog.Error(env.MSG+"graceful stop", "msg", "Ошибка при закрытии клиента gRPC-сервиса инструментов", "err", err)
+		}
+		if err := prop.ExternalAuthGRPCClient().Close(); err != nil {
+			sLog.Error(env.MSG+"graceful stop", "msg", "Ошибка при закрытии клиента gRPC-сервиса аутентификации", "err", err)
+		}
 		close(idleConnsClosed)
+	})
+	go func() {
+		<-sigint
+		gracefulStop()
 	}()
 	go func() {
 		<-ctx.Done()
-		grpcServer.GracefulStop()
-		sLog.Info(env.MSG+"graceful stop", "msg", "Выключение сервера gRPC")
-		if err := httpServer.Shutdown(); err != nil {
-			sLog.Error(env.MSG+"graceful stop", "msg", "Ошибка при выключение сервера HTTP", "err", err)
-		}
-		sLog.Info(env.MSG+"graceful stop", "msg", "Выключение сервера HTTP")
-		close(idleConnsClosed)
+		gracefulStop()
 	}()
+	go services.GetOutboxDispatcher(prop).Run(workersCtx)
+	go services.GetTombstoneCompactor(prop).Run(workersCtx)
+	go services.GetTokenCompactor(prop).Run(workersCtx)
+	go services.GetReconciler(prop).Run(workersCtx)
+	go services.GetChangeWatcher(prop).Run(workersCtx)
+	go services.GetUpkRotator(prop).Run(workersCtx)
 	go func() {
 		sLog.Info(env.MSG+"start app", "msg", "Сервер gRPC начал работу")
 		if err := grpcServer.Serve(listen); err != nil {
@@ -195,23 +288,110 @@ func makeHTTP(prop env.Properties) *fiber.App {
 	}
 	micro.Route("/auth", func(router fiber.Router) {
 		router.Post("/login", controllers.GetAuthController(prop).SignInUser)
+		router.Post(
+			"/logout",
+			middleware.GetUserJwtHandler(prop).DeserializeUser,
+			controllers.GetAuthController(prop).Logout,
+		)
+		router.Post("/refresh", controllers.GetAuthController(prop).Refresh)
 	})
+	app.Get("/.well-known/jwks.json", controllers.GetAuthController(prop).Jwks)
 	app.Get("/swagger/*", swagger.New(swagger.Config{PreauthorizeApiKey: "Bearer"}))
+	micro.Post(
+		"/favorites/delete",
+		controllers.Deprecated("/api/v1/users/me/favorites"),
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetFavoritesController(prop).Delete,
+	)
 	micro.Get(
 		"/favorites/get",
+		controllers.Deprecated("/api/v1/users/me/favorites"),
 		middleware.GetUserJwtHandler(prop).DeserializeUser,
 		controllers.GetFavoritesController(prop).GetForUser,
 	)
 	micro.Post(
 		"/favorites/get",
+		controllers.Deprecated("/api/v1/users/me/favorites"),
 		middleware.GetUserJwtHandler(prop).DeserializeUser,
 		controllers.GetFavoritesController(prop).Get,
 	)
 	micro.Post(
 		"/favorites/set",
+		controllers.Deprecated("/api/v1/users/me/favorites"),
 		middleware.GetUserJwtHandler(prop).DeserializeUser,
 		controllers.GetFavoritesController(prop).Set,
 	)
+	micro.Get(
+		"/v1/users/me/favorites",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetFavoritesController(prop).ListV1,
+	)
+	micro.Delete(
+		"/v1/users/me/favorites/:isin",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetFavoritesController(prop).DeleteV1,
+	)
+	micro.Get(
+		"/v1/users/me/favorites/:isin",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetFavoritesController(prop).GetV1,
+	)
+	micro.Put(
+		"/v1/users/me/favorites/:isin",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetFavoritesController(prop).PutV1,
+	)
+	micro.Delete(
+		"/v1/users/me",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetPrivacyController(prop).Erase,
+	)
+	micro.Get(
+		"/v1/users/me/export",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetPrivacyController(prop).Export,
+	)
+	micro.Post(
+		"/notes/delete",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetNotesController(prop).Delete,
+	)
+	micro.Get(
+		"/notes/get",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetNotesController(prop).GetForUser,
+	)
+	micro.Post(
+		"/notes/get",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetNotesController(prop).Get,
+	)
+	micro.Post(
+		"/notes/set",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetNotesController(prop).Set,
+	)
+	micro.Get(
+		"/admin/outbox",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetAdminController(prop).Outbox,
+	)
+	micro.Get(
+		"/admin/reconcile",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetAdminController(prop).Reconcile,
+	)
+	micro.Get(
+		"/admin/upk-rotation",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetAdminController(prop).UpkRotation,
+	)
+	micro.Get(
+		"/admin/policy",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		controllers.GetAdminController(prop).Policy,
+	)
+	micro.Get("/health/ready", controllers.GetHealthController(prop).Ready)
 	micro.Get("/health", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"status":  "success",
@@ -249,6 +429,12 @@ func makeGRPC(prop env.Properties) *grpc.Server {
 	grpcServer := grpc.NewServer(opts...)
 	favoritesService := services.GetFavoritesService(prop)
 	pb.RegisterFavoritesServiceServer(grpcServer, favoritesService)
+	notesService := services.GetNotesService(prop)
+	pb.RegisterNotesServiceServer(grpcServer, notesService)
+	otpService := services.GetOtpService(prop)
+	pb.RegisterOtpServiceServer(grpcServer, otpService)
+	privacyService := services.GetPrivacyService(prop)
+	pb.RegisterPrivacyServiceServer(grpcServer, privacyService)
 	reflection.Register(grpcServer)
 
 	return grpcServer
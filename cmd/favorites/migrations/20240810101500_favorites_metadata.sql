@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE favorites
+    ADD COLUMN IF NOT EXISTS metadata text NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN favorites.metadata IS 'Arbitrary text metadata, example website, person, bank ... etc';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE favorites
+    DROP COLUMN IF EXISTS metadata;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE notes
+(
+    id         uuid PRIMARY KEY DEFAULT pg_catalog.uuid_generate_v4(),
+    name       varchar   NOT NULL,
+    user_upk   varchar   NOT NULL,
+    body       text      NOT NULL DEFAULT '',
+    version    bigint,
+    deleted    bool,
+    created_at timestamp NOT NULL,
+    updated_at timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS notes_bkey
+    ON notes (name, user_upk);
+
+ALTER TABLE notes
+    ADD FOREIGN KEY (user_upk)
+        REFERENCES users (upk);
+
+COMMENT ON COLUMN notes.body IS 'AES-GCM encrypted note text, base64';
+COMMENT ON COLUMN notes.version IS 'CBUL version';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notes;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS set_created_at_notes ON notes;
+CREATE TRIGGER set_created_at_notes
+    BEFORE INSERT
+    ON notes
+    FOR EACH ROW
+EXECUTE FUNCTION set_created_at();
+
+DROP TRIGGER IF EXISTS set_update_at_notes ON notes;
+CREATE TRIGGER set_update_at_notes
+    BEFORE UPDATE
+    ON notes
+    FOR EACH ROW
+EXECUTE FUNCTION set_update_at();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS set_update_at_notes ON notes;
+DROP TRIGGER IF EXISTS set_created_at_notes ON notes;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE otp_secrets
+(
+    id         uuid PRIMARY KEY DEFAULT pg_catalog.uuid_generate_v4(),
+    name       varchar   NOT NULL,
+    user_upk   varchar   NOT NULL,
+    kind       varchar   NOT NULL DEFAULT 'TOTP' CHECK (kind IN ('TOTP', 'HOTP')),
+    secret     text      NOT NULL,
+    digits     int       NOT NULL DEFAULT 6,
+    period     int       NOT NULL DEFAULT 30,
+    counter    bigint    NOT NULL DEFAULT 0,
+    deleted    bool,
+    created_at timestamp NOT NULL,
+    updated_at timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS otp_secrets_bkey
+    ON otp_secrets (name, user_upk);
+
+ALTER TABLE otp_secrets
+    ADD FOREIGN KEY (user_upk)
+        REFERENCES users (upk);
+
+COMMENT ON COLUMN otp_secrets.secret IS 'AES-GCM encrypted TOTP/HOTP seed, base64';
+COMMENT ON COLUMN otp_secrets.counter IS 'HOTP moving factor';
+
+CREATE TABLE otp_codes
+(
+    id         uuid PRIMARY KEY DEFAULT pg_catalog.uuid_generate_v4(),
+    name       varchar   NOT NULL,
+    user_upk   varchar   NOT NULL,
+    code_hash  varchar   NOT NULL,
+    used_at    timestamp,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS otp_codes_bkey
+    ON otp_codes (name, user_upk, code_hash);
+
+ALTER TABLE otp_codes
+    ADD FOREIGN KEY (name, user_upk)
+        REFERENCES otp_secrets (name, user_upk)
+        ON DELETE CASCADE;
+
+COMMENT ON COLUMN otp_codes.code_hash IS 'SHA-256 of single-use activation code, hex';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS otp_codes;
+DROP TABLE IF EXISTS otp_secrets;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS set_created_at_otp_secrets ON otp_secrets;
+CREATE TRIGGER set_created_at_otp_secrets
+    BEFORE INSERT
+    ON otp_secrets
+    FOR EACH ROW
+EXECUTE FUNCTION set_created_at();
+
+DROP TRIGGER IF EXISTS set_update_at_otp_secrets ON otp_secrets;
+CREATE TRIGGER set_update_at_otp_secrets
+    BEFORE UPDATE
+    ON otp_secrets
+    FOR EACH ROW
+EXECUTE FUNCTION set_update_at();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS set_update_at_otp_secrets ON otp_secrets;
+DROP TRIGGER IF EXISTS set_created_at_otp_secrets ON otp_secrets;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE refresh_tokens
+(
+    id         uuid PRIMARY KEY DEFAULT pg_catalog.uuid_generate_v4(),
+    token_hash varchar   NOT NULL,
+    subject    text      NOT NULL,
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS refresh_tokens_bkey
+    ON refresh_tokens (token_hash);
+
+COMMENT ON COLUMN refresh_tokens.token_hash IS 'SHA-256 of opaque refresh token, hex';
+COMMENT ON COLUMN refresh_tokens.subject IS 'AES-GCM encrypted JWT subject (personal key), base64';
+
+CREATE TABLE revoked_tokens
+(
+    jti        varchar PRIMARY KEY,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx
+    ON revoked_tokens (expires_at);
+
+COMMENT ON TABLE revoked_tokens IS 'access tokens revoked before expiry, keyed by JWT ID';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE refresh_tokens
+    ADD COLUMN IF NOT EXISTS role varchar NOT NULL DEFAULT 'USER';
+
+COMMENT ON COLUMN refresh_tokens.role IS 'JWT role claim re-issued on refresh';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS role;
+-- +goose StatementEnd
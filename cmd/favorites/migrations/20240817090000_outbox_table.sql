@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE outbox
+(
+    id              bigserial PRIMARY KEY,
+    aggregate       varchar   NOT NULL,
+    op              varchar   NOT NULL,
+    isin            varchar   NOT NULL,
+    user_upk        varchar   NOT NULL,
+    status          varchar   NOT NULL DEFAULT 'pending',
+    attempts        int       NOT NULL DEFAULT 0,
+    last_error      text,
+    next_attempt_at timestamp NOT NULL DEFAULT now(),
+    created_at      timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx
+    ON outbox (next_attempt_at, id) WHERE status = 'pending';
+
+COMMENT ON TABLE outbox IS 'replication events Postgres to MongoDB, written in the same transaction as the change';
+COMMENT ON COLUMN outbox.status IS 'pending or dead (dead-letter after max attempts), delivered events are deleted';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox;
+-- +goose StatementEnd
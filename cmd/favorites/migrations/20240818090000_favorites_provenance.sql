@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE favorites
+    ADD COLUMN IF NOT EXISTS shard_id   varchar,
+    ADD COLUMN IF NOT EXISTS written_at timestamp;
+
+COMMENT ON COLUMN favorites.shard_id IS 'shard (sync.shard_id) that last wrote the item, NULL for rows written before provenance';
+COMMENT ON COLUMN favorites.written_at IS 'time of the last write of the item by the shard, UTC';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE favorites
+    DROP COLUMN IF EXISTS written_at,
+    DROP COLUMN IF EXISTS shard_id;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE resume_tokens
+(
+    name       varchar PRIMARY KEY,
+    token      bytea     NOT NULL,
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE resume_tokens IS 'resume tokens of MongoDB change streams read by this shard, keyed by stream name';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS resume_tokens;
+-- +goose StatementEnd
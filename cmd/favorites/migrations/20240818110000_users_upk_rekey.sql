@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS sealed_key bytea;
+
+COMMENT ON COLUMN users.upk IS 'User primary key: HMAC-SHA256 blind index of personal key, base64; legacy rows keep single-block AES until rekeyed';
+COMMENT ON COLUMN users.sealed_key IS 'AES-GCM encrypted personal key bound to upk, NULL for rows not rekeyed yet';
+
+ALTER TABLE favorites
+    DROP CONSTRAINT IF EXISTS favorites_user_upk_fkey,
+    ADD FOREIGN KEY (user_upk)
+        REFERENCES users (upk)
+        ON UPDATE CASCADE;
+
+ALTER TABLE notes
+    DROP CONSTRAINT IF EXISTS notes_user_upk_fkey,
+    ADD FOREIGN KEY (user_upk)
+        REFERENCES users (upk)
+        ON UPDATE CASCADE;
+
+ALTER TABLE otp_secrets
+    DROP CONSTRAINT IF EXISTS otp_secrets_user_upk_fkey,
+    ADD FOREIGN KEY (user_upk)
+        REFERENCES users (upk)
+        ON UPDATE CASCADE;
+
+ALTER TABLE otp_codes
+    DROP CONSTRAINT IF EXISTS otp_codes_name_user_upk_fkey,
+    ADD FOREIGN KEY (name, user_upk)
+        REFERENCES otp_secrets (name, user_upk)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE otp_codes
+    DROP CONSTRAINT IF EXISTS otp_codes_name_user_upk_fkey,
+    ADD FOREIGN KEY (name, user_upk)
+        REFERENCES otp_secrets (name, user_upk)
+        ON DELETE CASCADE;
+
+ALTER TABLE otp_secrets
+    DROP CONSTRAINT IF EXISTS otp_secrets_user_upk_fkey,
+    ADD FOREIGN KEY (user_upk)
+        REFERENCES users (upk);
+
+ALTER TABLE notes
+    DROP CONSTRAINT IF EXISTS notes_user_upk_fkey,
+    ADD FOREIGN KEY (user_upk)
+        REFERENCES users (upk);
+
+ALTER TABLE favorites
+    DROP CONSTRAINT IF EXISTS favorites_user_upk_fkey,
+    ADD FOREIGN KEY (user_upk)
+        REFERENCES users (upk);
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS sealed_key;
+-- +goose StatementEnd
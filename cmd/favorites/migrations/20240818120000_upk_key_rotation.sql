@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS key_version int NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN users.key_version IS 'version of UPK secret key the upk and sealed_key are derived with, 0 for rows before key rotation';
+
+CREATE TABLE upk_rotations
+(
+    key_version int PRIMARY KEY,
+    after_upk   varchar   NOT NULL DEFAULT '',
+    rotated     bigint    NOT NULL DEFAULT 0,
+    failed      bigint    NOT NULL DEFAULT 0,
+    started_at  timestamp NOT NULL DEFAULT now(),
+    finished_at timestamp,
+    updated_at  timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE upk_rotations IS 'progress of re-encryption of users to UPK secret key version, keyed by the version';
+COMMENT ON COLUMN upk_rotations.after_upk IS 'last upk processed, the job resumes with the next one after restart';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS upk_rotations;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS key_version;
+-- +goose StatementEnd
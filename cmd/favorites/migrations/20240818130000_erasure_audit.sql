@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE erasure_audit
+(
+    id               bigserial PRIMARY KEY,
+    key_version      int       NOT NULL DEFAULT 0,
+    users            bigint    NOT NULL DEFAULT 0,
+    favorites        bigint    NOT NULL DEFAULT 0,
+    notes            bigint    NOT NULL DEFAULT 0,
+    otp_secrets      bigint    NOT NULL DEFAULT 0,
+    outbox           bigint    NOT NULL DEFAULT 0,
+    synced_favorites bigint    NOT NULL DEFAULT 0,
+    synced_notes     bigint    NOT NULL DEFAULT 0,
+    erased_at        timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE erasure_audit IS 'erasures of user data on request of the user, counts of removed rows and documents only, without upk or personal key';
+COMMENT ON COLUMN erasure_audit.synced_favorites IS 'documents removed from the sync store (MongoDB favorites collection)';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS erasure_audit;
+-- +goose StatementEnd
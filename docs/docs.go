@@ -23,6 +23,185 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "security": [
+                    {
+                        "none": []
+                    }
+                ],
+                "description": "открытые ключи проверки подписи JWT (RS256/ES256) в формате JWKS, при HS256 набор пуст",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "открытые ключи JWT",
+                "responses": {
+                    "200": {
+                        "description": "набор открытых ключей",
+                        "schema": {
+                            "$ref": "#/definitions/jwt.JWKS"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/outbox": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "состояние диспетчера outbox и отставание репликации PostgreSQL → MongoDB",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "репликация outbox",
+                "responses": {
+                    "200": {
+                        "description": "состояние outbox",
+                        "schema": {
+                            "$ref": "#/definitions/services.OutboxStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "очередь outbox недоступна",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/policy": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "действующая политика доступа: роли для методов gRPC и маршрутов HTTP",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "политика доступа",
+                "responses": {
+                    "200": {
+                        "description": "политика доступа",
+                        "schema": {
+                            "$ref": "#/definitions/policy.Policy"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/reconcile": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "отчёт последнего прохода фоновой сверки избранного: счётчики и расхождения по пользователям",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "сверка PostgreSQL и MongoDB",
+                "responses": {
+                    "200": {
+                        "description": "отчёт о расхождениях",
+                        "schema": {
+                            "$ref": "#/definitions/services.ReconcileReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/upk-rotation": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "ход фонового перешифрования пользователей: версия секрета, последний UPK и счётчики",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "перешифрование на текущую версию секрета UPK",
+                "responses": {
+                    "200": {
+                        "description": "ход перешифрования",
+                        "schema": {
+                            "$ref": "#/definitions/services.UpkRotationReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/api/auth/login": {
             "post": {
                 "security": [
@@ -76,21 +255,24 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "502": {
+                        "description": "сервис аутентификации недоступен",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/api/favorites/get": {
-            "get": {
+        "/api/auth/logout": {
+            "post": {
                 "security": [
-                    {
-                        "Bearer": []
-                    },
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "избранное получения инструментов для пользователя",
+                "description": "отзыв текущего JWT по утверждению jti и погашение токена обновления",
                 "consumes": [
                     "application/json"
                 ],
@@ -98,24 +280,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Favorites"
+                    "Auth"
+                ],
+                "summary": "выход",
+                "parameters": [
+                    {
+                        "description": "Формат запроса JSON (body)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RefreshRequest"
+                        }
+                    }
                 ],
-                "summary": "избранное",
                 "responses": {
                     "200": {
-                        "description": "успешная обработка запроса",
+                        "description": "выход выполнен",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/dto.Favorites"
-                                }
-                            }
+                            "type": "string"
                         }
                     },
                     "401": {
-                        "description": "пользователь не авторизован",
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "string"
                         }
@@ -127,14 +313,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/auth/refresh": {
             "post": {
                 "security": [
                     {
                         "none": []
                     }
                 ],
-                "description": "избранное получения инструмента для пользователя",
+                "description": "погашение токена обновления и выпуск новой пары токенов, токен берётся из тела запроса или cookie refresh_token",
                 "consumes": [
                     "application/json"
                 ],
@@ -142,35 +330,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Favorites"
+                    "Auth"
                 ],
-                "summary": "избранное",
+                "summary": "обновление токена",
                 "parameters": [
                     {
                         "description": "Формат запроса JSON (body)",
                         "name": "request",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.Favorites"
+                            "$ref": "#/definitions/dto.RefreshRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "получение инструмента",
-                        "schema": {
-                            "$ref": "#/definitions/dto.Favorites"
-                        }
-                    },
-                    "400": {
-                        "description": "неверный формат запроса",
+                        "description": "токены успешно обновлены",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "401": {
-                        "description": "пользователь не авторизован",
+                        "description": "токен обновления недействителен, истёк или уже использован",
                         "schema": {
                             "type": "string"
                         }
@@ -184,14 +365,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/favorites/set": {
+        "/api/favorites/delete": {
             "post": {
                 "security": [
                     {
                         "none": []
                     }
                 ],
-                "description": "избранное сохранение инструмента для пользователя",
+                "description": "избранное удаление инструмента для пользователя",
                 "consumes": [
                     "application/json"
                 ],
@@ -202,6 +383,7 @@ const docTemplate = `{
                     "Favorites"
                 ],
                 "summary": "избранное",
+                "deprecated": true,
                 "parameters": [
                     {
                         "description": "Формат запроса JSON (body)",
@@ -215,7 +397,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "получение инструмента",
+                        "description": "удалённый инструмент",
                         "schema": {
                             "$ref": "#/definitions/dto.Favorites"
                         }
@@ -240,39 +422,1221 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.Favorites": {
-            "type": "object",
-            "required": [
-                "asset_type",
-                "isin"
-            ],
-            "properties": {
-                "asset_type": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "isin": {
-                    "type": "string"
-                }
-            }
         },
-        "dto.SignInRequest": {
-            "type": "object",
-            "required": [
-                "password",
-                "user_name"
-            ],
-            "properties": {
-                "password": {
-                    "type": "string"
-                },
-                "user_name": {
-                    "type": "string"
+        "/api/favorites/get": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "избранное получения инструментов для пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Favorites"
+                ],
+                "summary": "избранное",
+                "deprecated": true,
+                "responses": {
+                    "200": {
+                        "description": "успешная обработка запроса",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/dto.Favorites"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "none": []
+                    }
+                ],
+                "description": "избранное получения инструмента для пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Favorites"
+                ],
+                "summary": "избранное",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Формат запроса JSON (body)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.Favorites"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "получение инструмента",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Favorites"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/favorites/set": {
+            "post": {
+                "security": [
+                    {
+                        "none": []
+                    }
+                ],
+                "description": "избранное сохранение инструмента для пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Favorites"
+                ],
+                "summary": "избранное",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Формат запроса JSON (body)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.Favorites"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "получение инструмента",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Favorites"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/health/ready": {
+            "get": {
+                "description": "проверка готовности (readiness): доступность MongoDB и статистика пула соединений,\nсостояние соединений с внешними gRPC-сервисами. Недоступность внешних gRPC-сервисов\nне снимает готовность: поиск инструментов и пользователей выполняется и в базе данных.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "готовность сервиса",
+                "responses": {
+                    "200": {
+                        "description": "сервис готов",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.Readiness"
+                        }
+                    },
+                    "503": {
+                        "description": "зависимость недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.Readiness"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes/delete": {
+            "post": {
+                "security": [
+                    {
+                        "none": []
+                    }
+                ],
+                "description": "удаление заметки пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notes"
+                ],
+                "summary": "заметки",
+                "parameters": [
+                    {
+                        "description": "Формат запроса JSON (body)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.Note"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "удалённая заметка",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes/get": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "получение заметок пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notes"
+                ],
+                "summary": "заметки",
+                "responses": {
+                    "200": {
+                        "description": "успешная обработка запроса",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/dto.Note"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "none": []
+                    }
+                ],
+                "description": "получение заметки пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notes"
+                ],
+                "summary": "заметки",
+                "parameters": [
+                    {
+                        "description": "Формат запроса JSON (body)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.Note"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "заметка",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes/set": {
+            "post": {
+                "security": [
+                    {
+                        "none": []
+                    }
+                ],
+                "description": "сохранение заметки пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Notes"
+                ],
+                "summary": "заметки",
+                "parameters": [
+                    {
+                        "description": "Формат запроса JSON (body)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.Note"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "заметка",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Note"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/me": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "удаление всех данных пользователя, в ответе запись аудита удаления без персональных данных",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Privacy"
+                ],
+                "summary": "персональные данные",
+                "responses": {
+                    "200": {
+                        "description": "запись аудита удаления",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Erasure"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/me/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "выгрузка всех данных пользователя: избранное, удалённое избранное, заметки, секреты OTP без самих секретов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Privacy"
+                ],
+                "summary": "персональные данные",
+                "responses": {
+                    "200": {
+                        "description": "данные пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserData"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/me/favorites": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "избранное получения инструментов для пользователя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Favorites v1"
+                ],
+                "summary": "избранное",
+                "responses": {
+                    "200": {
+                        "description": "успешная обработка запроса",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/dto.Favorites"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "ошибка внешнего хранилища",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/me/favorites/{isin}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "избранное получения инструмента для пользователя, версия возвращается в заголовке ETag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Favorites v1"
+                ],
+                "summary": "избранное",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ISIN инструмента",
+                        "name": "isin",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "получение инструмента",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Favorites"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "инструмент не найден в избранном",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "избранное сохранение инструмента для пользователя,\nожидаемая версия передаётся в заголовке If-Match или в поле version",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Favorites v1"
+                ],
+                "summary": "избранное",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ISIN инструмента",
+                        "name": "isin",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ожидаемая версия (ETag)",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Формат запроса JSON (body)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.Favorites"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "сохранённый инструмент",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Favorites"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "конфликт версий",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "пользователь или инструмент не найден во внешнем сервисе",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "избранное удаление инструмента для пользователя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Favorites v1"
+                ],
+                "summary": "избранное",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ISIN инструмента",
+                        "name": "isin",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "удалённый инструмент",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Favorites"
+                        }
+                    },
+                    "400": {
+                        "description": "неверный формат запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "пользователь не авторизован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "инструмент не найден в избранном",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controllers.GRPCReadiness": {
+            "type": "object",
+            "properties": {
+                "connection": {
+                    "$ref": "#/definitions/tool.GRPCClientStats"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.MongoDBReadiness": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "pool": {
+                    "$ref": "#/definitions/tool.MongoPoolStats"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.Readiness": {
+            "type": "object",
+            "properties": {
+                "asset_grpc": {
+                    "$ref": "#/definitions/controllers.GRPCReadiness"
+                },
+                "auth_grpc": {
+                    "$ref": "#/definitions/controllers.GRPCReadiness"
+                },
+                "mongodb": {
+                    "$ref": "#/definitions/controllers.MongoDBReadiness"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Erasure": {
+            "type": "object",
+            "properties": {
+                "erased_at": {
+                    "type": "string"
+                },
+                "favorites": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "integer"
+                },
+                "otp_secrets": {
+                    "type": "integer"
+                },
+                "outbox": {
+                    "type": "integer"
+                },
+                "synced_favorites": {
+                    "type": "integer"
+                },
+                "synced_notes": {
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.Favorites": {
+            "type": "object",
+            "required": [
+                "asset_type",
+                "isin"
+            ],
+            "properties": {
+                "asset_type": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isin": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "string"
+                },
+                "shard_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                },
+                "written_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Note": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.OtpSecret": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "digits": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "period": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.SignInRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "user_name"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "user_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UserData": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "favorites": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.Favorites"
+                    }
+                },
+                "notes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.Note"
+                    }
+                },
+                "otp_secrets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.OtpSecret"
+                    }
+                },
+                "synced_favorites": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.Favorites"
+                    }
+                },
+                "upk": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "jwt.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                },
+                "y": {
+                    "type": "string"
+                }
+            }
+        },
+        "jwt.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/jwt.JWK"
+                    }
+                }
+            }
+        },
+        "policy.Policy": {
+            "type": "object",
+            "properties": {
+                "grpc": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "http": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "services.OutboxStatus": {
+            "type": "object",
+            "properties": {
+                "dead": {
+                    "type": "integer"
+                },
+                "dead_lettered": {
+                    "type": "integer"
+                },
+                "delivered": {
+                    "type": "integer"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "lag_seconds": {
+                    "type": "number"
+                },
+                "last_delivered_at": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "oldest_pending": {
+                    "type": "string"
+                },
+                "pending": {
+                    "type": "integer"
+                },
+                "running": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "services.ReconcileReport": {
+            "type": "object",
+            "properties": {
+                "acknowledge": {
+                    "type": "integer"
+                },
+                "delete_mongodb": {
+                    "type": "integer"
+                },
+                "delete_postgresql": {
+                    "type": "integer"
+                },
+                "drifted": {
+                    "type": "integer"
+                },
+                "drifts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.UserDrift"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "repaired": {
+                    "type": "integer"
+                },
+                "save_mongodb": {
+                    "type": "integer"
+                },
+                "save_postgresql": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "truncated": {
+                    "type": "boolean"
+                },
+                "users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.UpkRotationReport": {
+            "type": "object",
+            "properties": {
+                "after_upk": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "key_version": {
+                    "type": "integer"
+                },
+                "resumed": {
+                    "type": "boolean"
+                },
+                "rotated": {
+                    "type": "integer"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.UserDrift": {
+            "type": "object",
+            "properties": {
+                "acknowledge": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "delete_mongodb": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "delete_postgresql": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "save_mongodb": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "save_postgresql": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "upk": {
+                    "type": "string"
+                }
+            }
+        },
+        "tool.GRPCClientStats": {
+            "type": "object",
+            "properties": {
+                "addresses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "tool.MongoPoolStats": {
+            "type": "object",
+            "properties": {
+                "checked_out": {
+                    "type": "integer"
+                },
+                "checkout_failed": {
+                    "type": "integer"
+                },
+                "cleared": {
+                    "type": "integer"
+                },
+                "closed": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "integer"
+                },
+                "in_use": {
+                    "type": "integer"
+                },
+                "max_pool_size": {
+                    "type": "integer"
+                },
+                "open": {
+                    "type": "integer"
                 }
             }
         }
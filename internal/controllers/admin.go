@@ -0,0 +1,127 @@
+/*
+ * This file was last modified at 2024-08-18 06:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * admin.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/policy"
+	"github.com/vskurikhin/gofavorites/internal/services"
+)
+
+// Admin операционные (служебные) конечные точки, доступны роли ADMIN.
+type Admin struct {
+	authPolicy *policy.Policy
+	outbox     services.OutboxDispatcher
+	reconciler services.Reconciler
+	upkRotator services.UpkRotator
+}
+
+var (
+	onceAdmin = new(sync.Once)
+	adminCont *Admin
+)
+
+// GetAdminController — потокобезопасное (thread-safe) создание
+// REST веб-сервиса операционных конечных точек.
+func GetAdminController(prop env.Properties) *Admin {
+
+	onceAdmin.Do(func() {
+		adminCont = new(Admin)
+		adminCont.authPolicy = prop.AuthPolicy()
+		adminCont.outbox = services.GetOutboxDispatcher(prop)
+		adminCont.reconciler = services.GetReconciler(prop)
+		adminCont.upkRotator = services.GetUpkRotator(prop)
+	})
+	return adminCont
+}
+
+// Outbox handler
+//
+//	@Summary		репликация outbox
+//	@Description	состояние диспетчера outbox и отставание репликации PostgreSQL → MongoDB
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200					{object}	services.OutboxStatus	"состояние outbox"
+//	@Failure		401					{string}	string					"Unauthorized"
+//	@Failure		403					{string}	string					"Forbidden"
+//	@Failure		503					{object}	string					"очередь outbox недоступна"
+//	@Security		BearerAuth
+//	@Router			/api/admin/outbox	[get]
+func (a *Admin) Outbox(c *fiber.Ctx) error {
+
+	status, err := a.outbox.Status(c.Context())
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusServiceUnavailable).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "data": status})
+	}
+	return c.
+		Status(fiber.StatusOK).
+		JSON(status)
+}
+
+// Reconcile handler
+//
+//	@Summary		сверка PostgreSQL и MongoDB
+//	@Description	отчёт последнего прохода фоновой сверки избранного: счётчики и расхождения по пользователям
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200					{object}	services.ReconcileReport	"отчёт о расхождениях"
+//	@Failure		401					{string}	string						"Unauthorized"
+//	@Failure		403					{string}	string						"Forbidden"
+//	@Security		BearerAuth
+//	@Router			/api/admin/reconcile	[get]
+func (a *Admin) Reconcile(c *fiber.Ctx) error {
+	return c.
+		Status(fiber.StatusOK).
+		JSON(a.reconciler.Report())
+}
+
+// UpkRotation handler
+//
+//	@Summary		перешифрование на текущую версию секрета UPK
+//	@Description	ход фонового перешифрования пользователей: версия секрета, последний UPK и счётчики
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200						{object}	services.UpkRotationReport	"ход перешифрования"
+//	@Failure		401						{string}	string						"Unauthorized"
+//	@Failure		403						{string}	string						"Forbidden"
+//	@Security		BearerAuth
+//	@Router			/api/admin/upk-rotation	[get]
+func (a *Admin) UpkRotation(c *fiber.Ctx) error {
+	return c.
+		Status(fiber.StatusOK).
+		JSON(a.upkRotator.Report())
+}
+
+// Policy handler
+//
+//	@Summary		политика доступа
+//	@Description	действующая политика доступа: роли для методов gRPC и маршрутов HTTP
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200					{object}	policy.Policy	"политика доступа"
+//	@Failure		401					{string}	string			"Unauthorized"
+//	@Failure		403					{string}	string			"Forbidden"
+//	@Security		BearerAuth
+//	@Router			/api/admin/policy	[get]
+func (a *Admin) Policy(c *fiber.Ctx) error {
+	return c.
+		Status(fiber.StatusOK).
+		JSON(a.authPolicy)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
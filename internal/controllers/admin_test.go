@@ -0,0 +1,159 @@
+/*
+ * This file was last modified at 2024-08-18 06:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * admin_test.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/policy"
+	"github.com/vskurikhin/gofavorites/internal/services"
+)
+
+func Test_Admin_Policy(t *testing.T) {
+	prop := env.GetProperties()
+	app := fiber.New()
+	app.Get("/", GetAdminController(prop).Policy)
+
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	var got policy.Policy
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, prop.AuthPolicy().HTTP, got.HTTP)
+	assert.Equal(t, []string{policy.RoleAdmin}, got.HTTP["GET /api/admin/policy"])
+}
+
+func Test_Admin_Outbox(t *testing.T) {
+	var tests = []struct {
+		name   string
+		outbox services.OutboxDispatcher
+		want   int
+	}{
+		{
+			name:   "positive test #0 Admin.Outbox",
+			outbox: outboxStub{status: services.OutboxStatus{Enabled: true, Pending: 2, LagSeconds: 1.5}},
+			want:   fiber.StatusOK,
+		},
+		{
+			name:   "negative test #1 Admin.Outbox backlog unavailable",
+			outbox: outboxStub{err: fmt.Errorf("test")},
+			want:   fiber.StatusServiceUnavailable,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", (&Admin{outbox: test.outbox}).Outbox)
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, test.want, resp.StatusCode, "Status code")
+
+			if test.want == fiber.StatusOK {
+				var got services.OutboxStatus
+				assert.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+				assert.Equal(t, int64(2), got.Pending)
+				assert.Equal(t, 1.5, got.LagSeconds)
+			}
+		})
+	}
+}
+
+func Test_Admin_Reconcile(t *testing.T) {
+	app := fiber.New()
+	report := services.ReconcileReport{
+		DryRun:  true,
+		Users:   3,
+		Drifted: 1,
+		Drifts:  []services.UserDrift{{Upk: "test", SaveMongoDB: []string{"isin"}}},
+	}
+	app.Get("/", (&Admin{reconciler: reconcilerStub{report: report}}).Reconcile)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "Status code")
+
+	var got services.ReconcileReport
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, report, got)
+}
+
+func Test_Admin_UpkRotation(t *testing.T) {
+	app := fiber.New()
+	report := services.UpkRotationReport{KeyVersion: 2, Running: true, AfterUpk: "upk", Rotated: 3, Failed: 1}
+	app.Get("/", (&Admin{upkRotator: upkRotatorStub{report: report}}).UpkRotation)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "Status code")
+
+	var got services.UpkRotationReport
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, report, got)
+}
+
+type outboxStub struct {
+	status services.OutboxStatus
+	err    error
+}
+
+func (o outboxStub) Dispatch(_ context.Context) (int, error) {
+	return 0, o.err
+}
+
+func (o outboxStub) Notify() {}
+
+func (o outboxStub) Run(_ context.Context) {}
+
+func (o outboxStub) Status(_ context.Context) (services.OutboxStatus, error) {
+	return o.status, o.err
+}
+
+type reconcilerStub struct {
+	report services.ReconcileReport
+}
+
+func (r reconcilerStub) Reconcile(_ context.Context) (services.ReconcileReport, error) {
+	return r.report, nil
+}
+
+func (r reconcilerStub) Report() services.ReconcileReport {
+	return r.report
+}
+
+func (r reconcilerStub) Run(_ context.Context) {}
+
+type upkRotatorStub struct {
+	report services.UpkRotationReport
+}
+
+func (u upkRotatorStub) Rotate(_ context.Context) (services.UpkRotationReport, error) {
+	return u.report, nil
+}
+
+func (u upkRotatorStub) Report() services.UpkRotationReport {
+	return u.report
+}
+
+func (u upkRotatorStub) Run(_ context.Context) {}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
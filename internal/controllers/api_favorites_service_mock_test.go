@@ -40,6 +40,21 @@ func (m *MockApiFavoritesService) EXPECT() *MockApiFavoritesServiceMockRecorder
 	return m.recorder
 }
 
+// ApiFavoritesDelete mocks base method.
+func (m *MockApiFavoritesService) ApiFavoritesDelete(ctx context.Context, model models.Favorites) (models.Favorites, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApiFavoritesDelete", ctx, model)
+	ret0, _ := ret[0].(models.Favorites)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApiFavoritesDelete indicates an expected call of ApiFavoritesDelete.
+func (mr *MockApiFavoritesServiceMockRecorder) ApiFavoritesDelete(ctx, model any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApiFavoritesDelete", reflect.TypeOf((*MockApiFavoritesService)(nil).ApiFavoritesDelete), ctx, model)
+}
+
 // ApiFavoritesGet mocks base method.
 func (m *MockApiFavoritesService) ApiFavoritesGet(ctx context.Context, model models.Favorites) (models.Favorites, error) {
 	m.ctrl.T.Helper()
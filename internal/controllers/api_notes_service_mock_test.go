@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/services/api_notes_service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/services/api_notes_service.go -package=controllers
+//
+
+// Package controllers is a generated GoMock package.
+package controllers
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/vskurikhin/gofavorites/internal/models"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockApiNotesService is a mock of ApiNotesService interface.
+type MockApiNotesService struct {
+	ctrl     *gomock.Controller
+	recorder *MockApiNotesServiceMockRecorder
+}
+
+// MockApiNotesServiceMockRecorder is the mock recorder for MockApiNotesService.
+type MockApiNotesServiceMockRecorder struct {
+	mock *MockApiNotesService
+}
+
+// NewMockApiNotesService creates a new mock instance.
+func NewMockApiNotesService(ctrl *gomock.Controller) *MockApiNotesService {
+	mock := &MockApiNotesService{ctrl: ctrl}
+	mock.recorder = &MockApiNotesServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApiNotesService) EXPECT() *MockApiNotesServiceMockRecorder {
+	return m.recorder
+}
+
+// ApiNotesDelete mocks base method.
+func (m *MockApiNotesService) ApiNotesDelete(ctx context.Context, model models.Note) (models.Note, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApiNotesDelete", ctx, model)
+	ret0, _ := ret[0].(models.Note)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApiNotesDelete indicates an expected call of ApiNotesDelete.
+func (mr *MockApiNotesServiceMockRecorder) ApiNotesDelete(ctx, model any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApiNotesDelete", reflect.TypeOf((*MockApiNotesService)(nil).ApiNotesDelete), ctx, model)
+}
+
+// ApiNotesGet mocks base method.
+func (m *MockApiNotesService) ApiNotesGet(ctx context.Context, model models.Note) (models.Note, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApiNotesGet", ctx, model)
+	ret0, _ := ret[0].(models.Note)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApiNotesGet indicates an expected call of ApiNotesGet.
+func (mr *MockApiNotesServiceMockRecorder) ApiNotesGet(ctx, model any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApiNotesGet", reflect.TypeOf((*MockApiNotesService)(nil).ApiNotesGet), ctx, model)
+}
+
+// ApiNotesGetForUser mocks base method.
+func (m *MockApiNotesService) ApiNotesGetForUser(ctx context.Context, model models.User) ([]models.Note, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApiNotesGetForUser", ctx, model)
+	ret0, _ := ret[0].([]models.Note)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApiNotesGetForUser indicates an expected call of ApiNotesGetForUser.
+func (mr *MockApiNotesServiceMockRecorder) ApiNotesGetForUser(ctx, model any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApiNotesGetForUser", reflect.TypeOf((*MockApiNotesService)(nil).ApiNotesGetForUser), ctx, model)
+}
+
+// ApiNotesSet mocks base method.
+func (m *MockApiNotesService) ApiNotesSet(ctx context.Context, model models.Note) (models.Note, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApiNotesSet", ctx, model)
+	ret0, _ := ret[0].(models.Note)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApiNotesSet indicates an expected call of ApiNotesSet.
+func (mr *MockApiNotesServiceMockRecorder) ApiNotesSet(ctx, model any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApiNotesSet", reflect.TypeOf((*MockApiNotesService)(nil).ApiNotesSet), ctx, model)
+}
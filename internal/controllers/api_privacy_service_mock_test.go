@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/services/api_privacy_service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/services/api_privacy_service.go -package=controllers
+//
+
+// Package controllers is a generated GoMock package.
+package controllers
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/vskurikhin/gofavorites/internal/models"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockApiPrivacyService is a mock of ApiPrivacyService interface.
+type MockApiPrivacyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockApiPrivacyServiceMockRecorder
+}
+
+// MockApiPrivacyServiceMockRecorder is the mock recorder for MockApiPrivacyService.
+type MockApiPrivacyServiceMockRecorder struct {
+	mock *MockApiPrivacyService
+}
+
+// NewMockApiPrivacyService creates a new mock instance.
+func NewMockApiPrivacyService(ctrl *gomock.Controller) *MockApiPrivacyService {
+	mock := &MockApiPrivacyService{ctrl: ctrl}
+	mock.recorder = &MockApiPrivacyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApiPrivacyService) EXPECT() *MockApiPrivacyServiceMockRecorder {
+	return m.recorder
+}
+
+// ApiPrivacyErase mocks base method.
+func (m *MockApiPrivacyService) ApiPrivacyErase(ctx context.Context, model models.User) (models.Erasure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApiPrivacyErase", ctx, model)
+	ret0, _ := ret[0].(models.Erasure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApiPrivacyErase indicates an expected call of ApiPrivacyErase.
+func (mr *MockApiPrivacyServiceMockRecorder) ApiPrivacyErase(ctx, model any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApiPrivacyErase", reflect.TypeOf((*MockApiPrivacyService)(nil).ApiPrivacyErase), ctx, model)
+}
+
+// ApiPrivacyExport mocks base method.
+func (m *MockApiPrivacyService) ApiPrivacyExport(ctx context.Context, model models.User) (models.UserData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApiPrivacyExport", ctx, model)
+	ret0, _ := ret[0].(models.UserData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApiPrivacyExport indicates an expected call of ApiPrivacyExport.
+func (mr *MockApiPrivacyServiceMockRecorder) ApiPrivacyExport(ctx, model any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApiPrivacyExport", reflect.TypeOf((*MockApiPrivacyService)(nil).ApiPrivacyExport), ctx, model)
+}
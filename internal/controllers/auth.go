@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 20:17 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-15 14:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * auth.go
@@ -11,20 +11,24 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
 	"github.com/vskurikhin/gofavorites/internal/env"
 	"github.com/vskurikhin/gofavorites/internal/jwt"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/vskurikhin/gofavorites/internal/services"
 )
 
 type Auth struct {
-	token      string
-	jwtManager jwt.Manager
-	jwtMaxAge  int
+	authenticator services.Authenticator
+	jwtManager    jwt.Manager
+	jwtMaxAge     int
+	refreshMaxAge int
+	tokenServ     services.TokenService
 }
 
 var (
@@ -38,9 +42,11 @@ func GetAuthController(prop env.Properties) *Auth {
 
 	onceAuth.Do(func() {
 		authCont = new(Auth)
-		authCont.token = prop.Config().Token()
+		authCont.authenticator = services.GetAuthenticator(prop)
 		authCont.jwtManager = jwt.GetJWTManager(prop)
 		authCont.jwtMaxAge = prop.JwtMaxAgeSec()
+		authCont.refreshMaxAge = int(prop.Config().JwtRefreshExpiresIn().Seconds())
+		authCont.tokenServ = services.GetTokenService(prop)
 	})
 	return authCont
 }
@@ -57,6 +63,7 @@ func GetAuthController(prop env.Properties) *Auth {
 //	@Success		200				{object}	dto.SignInRequest	"пользователь успешно аутентифицирован"
 //	@Failure		400				{object}	dto.SignInRequest	"неверный формат запроса"
 //	@Failure		401				{object}	dto.SignInRequest	"неверная пара логин/пароль"
+//	@Failure		502				{string}	string			"сервис аутентификации недоступен"
 //	@Failure		500				{string}	string			"Internal Server Error"
 //	@Router			/api/auth/login	[post]
 func (a *Auth) SignInUser(c *fiber.Ctx) error {
@@ -69,15 +76,23 @@ func (a *Auth) SignInUser(c *fiber.Ctx) error {
 			Status(fiber.StatusBadRequest).
 			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
 	}
-	errors := dto.ValidateStruct(payload)
+	errs := dto.ValidateStruct(payload)
 
-	if errors != nil {
+	if errs != nil {
 		return c.
 			Status(fiber.StatusBadRequest).
-			JSON(errors)
+			JSON(errs)
 	}
-	err := bcrypt.CompareHashAndPassword([]byte(a.token), []byte(payload.Password))
+	identity, err := a.authenticator.Authenticate(c.UserContext(), payload.UserName, payload.Password)
 
+	if err != nil && !errors.Is(err, services.ErrInvalidCredentials) {
+		return c.
+			Status(fiber.StatusBadGateway).
+			JSON(fiber.Map{
+				"status":  "fail",
+				"message": "authentication service unavailable", "requestId": requestId,
+			})
+	}
 	if err != nil {
 		return c.
 			Status(fiber.StatusUnauthorized).
@@ -87,28 +102,139 @@ func (a *Auth) SignInUser(c *fiber.Ctx) error {
 			})
 	}
 
-	tokenString, err := a.jwtManager.Generate(payload)
+	pair, err := a.tokenServ.Issue(c.UserContext(), identity)
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusBadGateway).
+			JSON(fiber.Map{
+				"status":  "fail",
+				"message": fmt.Sprintf("generating JWT Token failed: %v", err),
+			})
+	}
+	return a.tokenPairResponse(c, pair)
+}
+
+// Refresh handler
+//
+//	@Summary		обновление токена
+//	@Description	погашение токена обновления и выпуск новой пары токенов, токен берётся из тела запроса или cookie refresh_token
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		none
+//	@Param			request				body		dto.RefreshRequest	false	"Формат запроса JSON (body)"
+//	@Success		200					{string}	string				"токены успешно обновлены"
+//	@Failure		401					{string}	string				"токен обновления недействителен, истёк или уже использован"
+//	@Failure		500					{string}	string				"Internal Server Error"
+//	@Router			/api/auth/refresh	[post]
+func (a *Auth) Refresh(c *fiber.Ctx) error {
+
+	requestId := c.Locals("requestid")
+	refreshToken := a.refreshToken(c)
+	pair, err := a.tokenServ.Refresh(c.UserContext(), refreshToken)
+
+	if errors.Is(err, services.ErrRefreshTokenInvalid) {
+		return c.
+			Status(fiber.StatusUnauthorized).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
+	}
+	if err != nil {
+		return c.
+			Status(fiber.StatusInternalServerError).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
+	}
+	return a.tokenPairResponse(c, pair)
+}
+
+// Logout handler
+//
+//	@Summary		выход
+//	@Description	отзыв текущего JWT по утверждению jti и погашение токена обновления
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		dto.RefreshRequest	false	"Формат запроса JSON (body)"
+//	@Success		200				{string}	string				"выход выполнен"
+//	@Failure		401				{string}	string				"Unauthorized"
+//	@Failure		500				{string}	string				"Internal Server Error"
+//	@Security		BearerAuth
+//	@Router			/api/auth/logout	[post]
+func (a *Auth) Logout(c *fiber.Ctx) error {
+
+	requestId := c.Locals("requestid")
+	jti, _ := c.Locals("jti").(string)
+	exp, _ := c.Locals("exp").(time.Time)
+
+	if err := a.tokenServ.Logout(c.UserContext(), jti, exp, a.refreshToken(c)); err != nil {
+		return c.
+			Status(fiber.StatusInternalServerError).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
+	}
+	expired := time.Now().Add(-time.Hour)
+	c.Cookie(&fiber.Cookie{Name: "token", Path: "/", Expires: expired, HTTPOnly: true, Domain: "localhost"})
+	c.Cookie(&fiber.Cookie{Name: "refresh_token", Path: "/api/auth", Expires: expired, HTTPOnly: true, Domain: "localhost"})
+
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{"status": "success", "requestId": requestId})
+}
+
+// Jwks handler
+//
+//	@Summary		открытые ключи JWT
+//	@Description	открытые ключи проверки подписи JWT (RS256/ES256) в формате JWKS, при HS256 набор пуст
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		none
+//	@Success		200						{object}	jwt.JWKS	"набор открытых ключей"
+//	@Router			/.well-known/jwks.json	[get]
+func (a *Auth) Jwks(c *fiber.Ctx) error {
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.
+		Status(fiber.StatusOK).
+		JSON(a.jwtManager.JWKS())
+}
+
+func (a *Auth) refreshToken(c *fiber.Ctx) string {
+
+	var payload dto.RefreshRequest
+
+	if len(c.Body()) > 0 && c.BodyParser(&payload) == nil && payload.RefreshToken != "" {
+		return payload.RefreshToken
+	}
+	return c.Cookies("refresh_token")
+}
+
+func (a *Auth) tokenPairResponse(c *fiber.Ctx, pair services.TokenPair) error {
 
 	c.Cookie(&fiber.Cookie{
 		Name:     "token",
-		Value:    tokenString,
+		Value:    pair.AccessToken,
 		Path:     "/",
 		MaxAge:   a.jwtMaxAge,
 		Secure:   false,
 		HTTPOnly: true,
 		Domain:   "localhost",
 	})
-	if err != nil {
-		return c.
-			Status(fiber.StatusBadGateway).
-			JSON(fiber.Map{
-				"status":  "fail",
-				"message": fmt.Sprintf("generating JWT Token failed: %v", err),
-			})
+	result := fiber.Map{"status": "success", "requestId": c.Locals("requestid"), "token": pair.AccessToken}
+
+	if pair.RefreshToken != "" {
+		c.Cookie(&fiber.Cookie{
+			Name:     "refresh_token",
+			Value:    pair.RefreshToken,
+			Path:     "/api/auth",
+			MaxAge:   a.refreshMaxAge,
+			Secure:   false,
+			HTTPOnly: true,
+			Domain:   "localhost",
+		})
+		result["refresh_token"] = pair.RefreshToken
 	}
 	return c.
 		Status(fiber.StatusOK).
-		JSON(fiber.Map{"status": "success", "requestId": requestId, "token": tokenString})
+		JSON(result)
 }
 
 //!-
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-24 10:23 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-15 14:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * auth_test.go
@@ -12,6 +12,7 @@ package controllers
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
@@ -21,7 +22,14 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/utils"
+	"github.com/stretchr/testify/assert"
 	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/jwt"
+	"github.com/vskurikhin/gofavorites/internal/policy"
+	"github.com/vskurikhin/gofavorites/internal/services"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // go test -run TestDeserializeUser
@@ -48,5 +56,218 @@ func Test_Auth_SignInUser_Positive_1(t *testing.T) {
 	})
 }
 
+func Test_Auth_SignInUser_Authenticator(t *testing.T) {
+	var tests = []struct {
+		name        string
+		personalKey string
+		role        string
+		err         error
+		want        int
+	}{
+		{
+			name:        "Auth SignInUser positive subject is verified personal key",
+			personalKey: "personal-key-of-alice",
+			role:        policy.RoleUser,
+			want:        200,
+		},
+		{
+			name:        "Auth SignInUser positive role is verified role",
+			personalKey: "personal-key-of-root",
+			role:        policy.RoleAdmin,
+			want:        200,
+		},
+		{
+			name: "Auth SignInUser negative invalid credentials",
+			err:  services.ErrInvalidCredentials,
+			want: 401,
+		},
+		{
+			name: "Auth SignInUser negative authentication service unavailable",
+			err:  status.Error(codes.Unavailable, "unavailable"),
+			want: 502,
+		},
+	}
+	prop := env.GetProperties()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			authenticator := NewMockAuthenticator(ctrl)
+			authenticator.
+				EXPECT().
+				Authenticate(gomock.Any(), gomock.Eq("alice"), gomock.Eq("secret")).
+				Return(services.Identity{PersonalKey: test.personalKey, Role: test.role}, test.err).
+				Times(1)
+			app := fiber.New()
+			app.Post("/", getTestAuthController(prop, authenticator).SignInUser)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/", bytes.NewBufferString(`{"user_name":"alice","password":"secret"}`))
+			req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+			resp, err := app.Test(req)
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, test.want, resp.StatusCode, "Status code")
+
+			if test.want != 200 {
+				return
+			}
+			var body struct {
+				Token string `json:"token"`
+			}
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+			claims, err := jwt.GetJWTManager(prop).Verify(body.Token)
+			assert.Nil(t, err)
+			assert.Equal(t, test.personalKey, claims.UserName())
+			assert.Equal(t, test.personalKey, claims.Subject)
+			assert.Equal(t, test.role, claims.Role())
+		})
+	}
+}
+
+func Test_Auth_Refresh(t *testing.T) {
+	var tests = []struct {
+		name   string
+		body   string
+		cookie string
+		err    error
+		want   int
+	}{
+		{
+			name: "Auth Refresh positive refresh token from body",
+			body: `{"refresh_token":"refresh"}`,
+			want: 200,
+		},
+		{
+			name:   "Auth Refresh positive refresh token from cookie",
+			cookie: "refresh",
+			want:   200,
+		},
+		{
+			name: "Auth Refresh negative refresh token is invalid",
+			body: `{"refresh_token":"refresh"}`,
+			err:  services.ErrRefreshTokenInvalid,
+			want: 401,
+		},
+		{
+			name: "Auth Refresh negative internal error",
+			body: `{"refresh_token":"refresh"}`,
+			err:  fmt.Errorf("database is down"),
+			want: 500,
+		},
+	}
+	prop := env.GetProperties()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			tokenService := NewMockTokenService(ctrl)
+			tokenService.
+				EXPECT().
+				Refresh(gomock.Any(), gomock.Eq("refresh")).
+				Return(services.TokenPair{AccessToken: "access", RefreshToken: "refresh2"}, test.err).
+				Times(1)
+			authCont := getTestAuthController(prop, nil)
+			authCont.tokenServ = tokenService
+			app := fiber.New()
+			app.Post("/", authCont.Refresh)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/", bytes.NewBufferString(test.body))
+			req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+			if test.cookie != "" {
+				req.Header.Set("Cookie", "refresh_token="+test.cookie)
+			}
+			resp, err := app.Test(req)
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, test.want, resp.StatusCode, "Status code")
+
+			if test.want != 200 {
+				return
+			}
+			var body struct {
+				Token        string `json:"token"`
+				RefreshToken string `json:"refresh_token"`
+			}
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "access", body.Token)
+			assert.Equal(t, "refresh2", body.RefreshToken)
+		})
+	}
+}
+
+func Test_Auth_Logout(t *testing.T) {
+	var tests = []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "Auth Logout positive",
+			want: 200,
+		},
+		{
+			name: "Auth Logout negative internal error",
+			err:  fmt.Errorf("database is down"),
+			want: 500,
+		},
+	}
+	prop := env.GetProperties()
+	exp := time.Now().Add(time.Hour)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			tokenService := NewMockTokenService(ctrl)
+			tokenService.
+				EXPECT().
+				Logout(gomock.Any(), gomock.Eq("jti"), gomock.Eq(exp), gomock.Eq("refresh")).
+				Return(test.err).
+				Times(1)
+			authCont := getTestAuthController(prop, nil)
+			authCont.tokenServ = tokenService
+			app := fiber.New()
+			app.Post("/", func(c *fiber.Ctx) error {
+				c.Locals("jti", "jti")
+				c.Locals("exp", exp)
+				return c.Next()
+			}, authCont.Logout)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/", nil)
+			req.Header.Set("Cookie", "refresh_token=refresh")
+
+			resp, err := app.Test(req)
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, test.want, resp.StatusCode, "Status code")
+		})
+	}
+}
+
+func Test_Auth_Jwks(t *testing.T) {
+	prop := env.GetProperties()
+	app := fiber.New()
+	app.Get("/.well-known/jwks.json", getTestAuthController(prop, nil).Jwks)
+
+	req := httptest.NewRequest(fiber.MethodGet, "/.well-known/jwks.json", nil)
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	var jwks jwt.JWKS
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&jwks))
+	assert.NotNil(t, jwks.Keys)
+	assert.Empty(t, jwks.Keys)
+}
+
+func getTestAuthController(prop env.Properties, authenticator services.Authenticator) *Auth {
+
+	authCont = new(Auth)
+	authCont.authenticator = authenticator
+	authCont.jwtManager = jwt.GetJWTManager(prop)
+	authCont.jwtMaxAge = prop.JwtMaxAgeSec()
+	authCont.tokenServ = services.GetTokenService(prop)
+
+	return authCont
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
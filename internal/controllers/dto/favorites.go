@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-31 14:52 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 03:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites.go
@@ -10,10 +10,17 @@
 // Package dto TODO.
 package dto
 
+import "time"
+
 type Favorites struct {
-	ID        string `json:"id"`
-	Isin      string `json:"isin" validate:"required"`
-	AssetType string `json:"asset_type" validate:"required"`
+	ID        string     `json:"id"`
+	Isin      string     `json:"isin" validate:"required"`
+	AssetType string     `json:"asset_type" validate:"required"`
+	Metadata  string     `json:"metadata"`
+	Version   int64      `json:"version,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	ShardID   string     `json:"shard_id,omitempty"`
+	WrittenAt *time.Time `json:"written_at,omitempty"`
 }
 
 //!-
@@ -0,0 +1,22 @@
+/*
+ * This file was last modified at 2024-08-12 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * note.go
+ * $Id$
+ */
+//!+
+
+// Package dto TODO.
+package dto
+
+type Note struct {
+	ID      string `json:"id"`
+	Name    string `json:"name" validate:"required"`
+	Body    string `json:"body"`
+	Version int64  `json:"version,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
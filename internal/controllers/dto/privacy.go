@@ -0,0 +1,50 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * privacy.go
+ * $Id$
+ */
+//!+
+
+// Package dto TODO.
+package dto
+
+import "time"
+
+// UserData выгрузка всех данных пользователя.
+type UserData struct {
+	User            string      `json:"user"`
+	Upk             string      `json:"upk"`
+	Version         int64       `json:"version"`
+	CreatedAt       *time.Time  `json:"created_at,omitempty"`
+	Favorites       []Favorites `json:"favorites"`
+	SyncedFavorites []Favorites `json:"synced_favorites"`
+	Notes           []Note      `json:"notes"`
+	OtpSecrets      []OtpSecret `json:"otp_secrets"`
+}
+
+// OtpSecret секрет одноразовых паролей без самого секрета.
+type OtpSecret struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Digits    int32     `json:"digits"`
+	Period    int32     `json:"period"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Erasure запись аудита удаления данных пользователя.
+type Erasure struct {
+	ID              int64     `json:"id"`
+	Users           int64     `json:"users"`
+	Favorites       int64     `json:"favorites"`
+	Notes           int64     `json:"notes"`
+	OtpSecrets      int64     `json:"otp_secrets"`
+	Outbox          int64     `json:"outbox"`
+	SyncedFavorites int64     `json:"synced_favorites"`
+	SyncedNotes     int64     `json:"synced_notes"`
+	ErasedAt        time.Time `json:"erased_at"`
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -13,6 +13,11 @@ package dto
 type SignInRequest struct {
 	UserName string `json:"user_name"  validate:"required"`
 	Password string `json:"password"  validate:"required"`
+	Role     string `json:"-" swaggerignore:"true"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 //!-
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 20:17 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites.go
@@ -28,7 +28,6 @@ type Favorites struct {
 	jwtExpiresIn  time.Duration
 	jwtMaxAge     int
 	jwtSecret     string
-	token         string
 }
 
 var (
@@ -46,14 +45,76 @@ func GetFavoritesController(prop env.Properties) *Favorites {
 		favoritesCont.jwtExpiresIn = prop.JwtExpiresIn()
 		favoritesCont.jwtMaxAge = prop.JwtMaxAgeSec()
 		favoritesCont.jwtSecret = prop.JwtSecret()
-		favoritesCont.token = prop.Config().Token()
 	})
 	return favoritesCont
 }
 
-// Get handler
+// Delete handler, устаревший: заменён DELETE /api/v1/users/me/favorites/{isin}.
 //
 //	@Summary		избранное
+//	@Deprecated
+//	@Description	избранное удаление инструмента для пользователя
+//	@Tags			Favorites
+//	@Accept			json
+//	@Produce		json
+//	@Security		none
+//	@Param			request			body		dto.Favorites	true	"Формат запроса JSON (body)"
+//	@Success		200				{object}	dto.Favorites	"удалённый инструмент"
+//	@Failure		400				{object}	string	"неверный формат запроса"
+//	@Failure		401				{object}	string	"пользователь не авторизован"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/api/favorites/delete	[post]
+func (f *Favorites) Delete(c *fiber.Ctx) error {
+
+	var payload dto.Favorites
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
+	}
+	errors := dto.ValidateStruct(payload)
+
+	if errors != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(errors)
+	}
+	model := models.FavoritesFromDto(payload, user, "")
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	favorites, err := f.favoritesServ.ApiFavoritesDelete(ctx, model)
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{
+				"status":  "fail",
+				"message": fmt.Sprintf("error: %v", err),
+			})
+	}
+	response := favorites.ToDto()
+
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"favorites": response, "user": user},
+		})
+}
+
+// Get handler, устаревший: заменён GET /api/v1/users/me/favorites/{isin}.
+//
+//	@Summary		избранное
+//	@Deprecated
 //	@Description	избранное получения инструмента для пользователя
 //	@Tags			Favorites
 //	@Accept			json
@@ -112,9 +173,10 @@ func (f *Favorites) Get(c *fiber.Ctx) error {
 		})
 }
 
-// GetForUser handler
+// GetForUser handler, устаревший: заменён GET /api/v1/users/me/favorites.
 //
 //	@Summary		избранное
+//	@Deprecated
 //	@Description	избранное получения инструментов для пользователя
 //	@Security	Bearer
 //	@Tags			Favorites
@@ -158,9 +220,10 @@ func (f *Favorites) GetForUser(c *fiber.Ctx) error {
 		})
 }
 
-// Set handler
+// Set handler, устаревший: заменён PUT /api/v1/users/me/favorites/{isin}.
 //
 //	@Summary		избранное
+//	@Deprecated
 //	@Description	избранное сохранение инструмента для пользователя
 //	@Tags			Favorites
 //	@Accept			json
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-26 11:26 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites_test.go
@@ -12,6 +12,7 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http/httptest"
 	"testing"
@@ -86,6 +87,22 @@ func TestFavoritesGet(t *testing.T) {
 			name: "negative test #12 Favorites.Set #3",
 			fRun: negativeFavoritesSet3,
 		},
+		{
+			name: "positive test #13 Favorites.Delete",
+			fRun: positiveFavoritesDelete,
+		},
+		{
+			name: "negative test #14 Favorites.Delete #0",
+			fRun: negativeFavoritesDelete0,
+		},
+		{
+			name: "negative test #15 Favorites.Delete #1",
+			fRun: negativeFavoritesDelete1,
+		},
+		{
+			name: "positive test #16 Favorites.Set user from token subject",
+			fRun: positiveFavoritesSetSubject,
+		},
 	}
 
 	assert.NotNil(t, t)
@@ -466,12 +483,135 @@ func negativeFavoritesSet3(t *testing.T) {
 	utils.AssertEqual(t, 400, resp.StatusCode, "Status code")
 }
 
+func positiveFavoritesDelete(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+	app := fiber.New()
+	app.Use(requestid.New())
+
+	var favoritesServ = NewMockApiFavoritesService(ctrl)
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesDelete(gomock.Any(), gomock.Any()).
+		Return(models.Favorites{}, nil).
+		AnyTimes()
+	app.Post("/",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		getTestFavoritesController(prop, favoritesServ).Delete,
+	)
+	tokenString, err := getTokenString(prop)
+	assert.Nil(t, err)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/", bytes.NewBufferString(`{"isin":"test","asset_type":"test"}`))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesDelete0(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+	app := fiber.New()
+	app.Use(requestid.New())
+
+	var favoritesServ = NewMockApiFavoritesService(ctrl)
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesDelete(gomock.Any(), gomock.Any()).
+		Return(models.Favorites{}, nil).
+		AnyTimes()
+	app.Post("/",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		getTestFavoritesController(prop, favoritesServ).Delete,
+	)
+	tokenString, err := getTokenString(prop)
+	assert.Nil(t, err)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/", bytes.NewBufferString(`{}`))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 400, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesDelete1(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+	app := fiber.New()
+	app.Use(requestid.New())
+
+	var favoritesServ = NewMockApiFavoritesService(ctrl)
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesDelete(gomock.Any(), gomock.Any()).
+		Return(models.Favorites{}, services.ErrRequestNil).
+		AnyTimes()
+	app.Post("/",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		getTestFavoritesController(prop, favoritesServ).Delete,
+	)
+	tokenString, err := getTokenString(prop)
+	assert.Nil(t, err)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/", bytes.NewBufferString(`{"isin":"test","asset_type":"test"}`))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 400, resp.StatusCode, "Status code")
+}
+
+func positiveFavoritesSetSubject(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+	app := fiber.New()
+	app.Use(requestid.New())
+
+	var favoritesServ = NewMockApiFavoritesService(ctrl)
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesSet(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, model models.Favorites) (models.Favorites, error) {
+			assert.Equal(t, "test", model.User().PersonalKey())
+			assert.Equal(t, "", model.User().Upk())
+			return model, nil
+		}).
+		Times(1)
+	app.Post("/",
+		middleware.GetUserJwtHandler(prop).DeserializeUser,
+		getTestFavoritesController(prop, favoritesServ).Set,
+	)
+	tokenString, err := getTokenString(prop)
+	assert.Nil(t, err)
+
+	body := `{"isin":"test","asset_type":"test","user":{"personal_key":"other","upk":"other"}}`
+	req := httptest.NewRequest(fiber.MethodPost, "/", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
 func getTokenString(prop env.Properties) (string, error) {
 	tokenByte := jwt.New(jwt.SigningMethodHS256)
 	now := time.Now().UTC()
 	claims := tokenByte.Claims.(jwt.MapClaims)
 
 	claims["sub"] = "test"
+	claims["username"] = "test"
+	claims["role"] = "USER"
 	claims["exp"] = now.Add(prop.JwtExpiresIn()).Unix()
 	claims["iat"] = now.Unix()
 	claims["nbf"] = now.Unix()
@@ -487,7 +627,6 @@ func getTestFavoritesController(prop env.Properties, favoritesServ services.ApiF
 	favoritesCont.jwtExpiresIn = prop.JwtExpiresIn()
 	favoritesCont.jwtMaxAge = prop.JwtMaxAgeSec()
 	favoritesCont.jwtSecret = prop.JwtSecret()
-	favoritesCont.token = prop.Config().Token()
 
 	return favoritesCont
 }
@@ -0,0 +1,273 @@
+/*
+ * This file was last modified at 2024-08-17 11:20 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * favorites_v1.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/services"
+)
+
+// Deprecated обработчик помечающий устаревший маршрут заголовками Deprecation и Link
+// со ссылкой на маршрут который его заменяет.
+func Deprecated(successor string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		c.Set(fiber.HeaderLink, fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		return c.Next()
+	}
+}
+
+// DeleteV1 handler
+//
+//	@Summary		избранное
+//	@Description	избранное удаление инструмента для пользователя
+//	@Tags			Favorites v1
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			isin	path		string			true	"ISIN инструмента"
+//	@Success		200		{object}	dto.Favorites	"удалённый инструмент"
+//	@Failure		400		{object}	string			"неверный формат запроса"
+//	@Failure		401		{object}	string			"пользователь не авторизован"
+//	@Failure		404		{object}	string			"инструмент не найден в избранном"
+//	@Failure		500		{string}	string			"Internal Server Error"
+//	@Router			/api/v1/users/me/favorites/{isin}	[delete]
+func (f *Favorites) DeleteV1(c *fiber.Ctx) error {
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	model := models.FavoritesFromDto(dto.Favorites{Isin: c.Params("isin")}, user, "")
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	favorites, err := f.favoritesServ.ApiFavoritesDelete(ctx, model)
+
+	if err != nil {
+		return favoritesErrorV1(c, requestId, err)
+	}
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"favorites": favorites.ToDto(), "user": user},
+		})
+}
+
+// GetV1 handler
+//
+//	@Summary		избранное
+//	@Description	избранное получения инструмента для пользователя, версия возвращается в заголовке ETag
+//	@Tags			Favorites v1
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			isin	path		string			true	"ISIN инструмента"
+//	@Success		200		{object}	dto.Favorites	"получение инструмента"
+//	@Failure		401		{object}	string			"пользователь не авторизован"
+//	@Failure		404		{object}	string			"инструмент не найден в избранном"
+//	@Failure		500		{string}	string			"Internal Server Error"
+//	@Router			/api/v1/users/me/favorites/{isin}	[get]
+func (f *Favorites) GetV1(c *fiber.Ctx) error {
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	model := models.FavoritesFromDto(dto.Favorites{Isin: c.Params("isin")}, user, "")
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	favorites, err := f.favoritesServ.ApiFavoritesGet(ctx, model)
+
+	if err == nil && favorites.Deleted() {
+		err = fmt.Errorf("%w: isin: %s deleted", services.ErrFavoritesNotFound, c.Params("isin"))
+	}
+	if err != nil {
+		return favoritesErrorV1(c, requestId, err)
+	}
+	c.Set(fiber.HeaderETag, strconv.Quote(strconv.FormatInt(favorites.Version(), 10)))
+
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"favorites": favorites.ToDto(), "user": user},
+		})
+}
+
+// ListV1 handler
+//
+//	@Summary		избранное
+//	@Description	избранное получения инструментов для пользователя
+//	@Tags			Favorites v1
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{array}		[]dto.Favorites	"успешная обработка запроса"
+//	@Failure		401	{object}	string			"пользователь не авторизован"
+//	@Failure		500	{string}	string			"Internal Server Error"
+//	@Failure		502	{object}	string			"ошибка внешнего хранилища"
+//	@Router			/api/v1/users/me/favorites	[get]
+func (f *Favorites) ListV1(c *fiber.Ctx) error {
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	model := models.MakeUser(user, "")
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	favorites, err := f.favoritesServ.ApiFavoritesGetForUser(ctx, model)
+
+	if err != nil {
+		return favoritesErrorV1(c, requestId, err)
+	}
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"favorites": models.FavoritesSliceToDto(favorites), "user": user},
+		})
+}
+
+// PutV1 handler
+//
+//	@Summary		избранное
+//	@Description	избранное сохранение инструмента для пользователя,
+//	@Description	ожидаемая версия передаётся в заголовке If-Match или в поле version
+//	@Tags			Favorites v1
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			isin		path		string			true	"ISIN инструмента"
+//	@Param			If-Match	header		string			false	"ожидаемая версия (ETag)"
+//	@Param			request		body		dto.Favorites	true	"Формат запроса JSON (body)"
+//	@Success		200			{object}	dto.Favorites	"сохранённый инструмент"
+//	@Failure		400			{object}	string			"неверный формат запроса"
+//	@Failure		401			{object}	string			"пользователь не авторизован"
+//	@Failure		409			{object}	string			"конфликт версий"
+//	@Failure		500			{string}	string			"Internal Server Error"
+//	@Failure		502			{object}	string			"пользователь или инструмент не найден во внешнем сервисе"
+//	@Router			/api/v1/users/me/favorites/{isin}	[put]
+func (f *Favorites) PutV1(c *fiber.Ctx) error {
+
+	var payload dto.Favorites
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
+	}
+	isin := c.Params("isin")
+
+	if payload.Isin != "" && payload.Isin != isin {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "isin mismatch"})
+	}
+	payload.Isin = isin
+	errs := dto.ValidateStruct(payload)
+
+	if errs != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(errs)
+	}
+	version, err := ifMatchVersion(c.Get(fiber.HeaderIfMatch), payload.Version)
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
+	}
+	model := models.FavoritesFromDto(payload, user, "").WithVersion(version)
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	favorites, err := f.favoritesServ.ApiFavoritesSet(ctx, model)
+
+	if err != nil {
+		return favoritesErrorV1(c, requestId, err)
+	}
+	c.Set(fiber.HeaderETag, strconv.Quote(strconv.FormatInt(favorites.Version(), 10)))
+
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"favorites": favorites.ToDto(), "user": user},
+		})
+}
+
+// favoritesErrorV1 соответствие ошибок сервиса избранного кодам ответа HTTP.
+func favoritesErrorV1(c *fiber.Ctx, requestId any, err error) error {
+
+	status := fiber.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, services.ErrFavoritesNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrFavoritesVersionConflict):
+		status = fiber.StatusConflict
+	case errors.Is(err, services.ErrUpstreamLookup):
+		status = fiber.StatusBadGateway
+	}
+	return c.
+		Status(status).
+		JSON(fiber.Map{
+			"status":    "fail",
+			"requestId": requestId,
+			"message":   fmt.Sprintf("error: %v", err),
+		})
+}
+
+// ifMatchVersion ожидаемая версия из заголовка If-Match, если заголовок не задан версия из тела запроса.
+func ifMatchVersion(ifMatch string, version int64) (int64, error) {
+
+	ifMatch = strings.TrimPrefix(strings.TrimSpace(ifMatch), "W/")
+
+	if ifMatch == "" || ifMatch == "*" {
+		return version, nil
+	}
+	result, err := strconv.ParseInt(strings.Trim(ifMatch, `"`), 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("bad If-Match: %s", ifMatch)
+	}
+	return result, nil
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
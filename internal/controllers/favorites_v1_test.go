@@ -0,0 +1,357 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * favorites_v1_test.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/middleware"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/services"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFavoritesV1(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "positive test #0 Favorites.GetV1",
+			fRun: positiveFavoritesGetV1,
+		},
+		{
+			name: "positive test #1 Favorites.ListV1",
+			fRun: positiveFavoritesListV1,
+		},
+		{
+			name: "positive test #2 Favorites.PutV1 If-Match",
+			fRun: positiveFavoritesPutV1,
+		},
+		{
+			name: "positive test #3 Favorites.DeleteV1",
+			fRun: positiveFavoritesDeleteV1,
+		},
+		{
+			name: "negative test #4 Favorites.GetV1 not found",
+			fRun: negativeFavoritesGetV1NotFound,
+		},
+		{
+			name: "negative test #5 Favorites.GetV1 deleted",
+			fRun: negativeFavoritesGetV1Deleted,
+		},
+		{
+			name: "negative test #6 Favorites.PutV1 version conflict",
+			fRun: negativeFavoritesPutV1Conflict,
+		},
+		{
+			name: "negative test #7 Favorites.PutV1 upstream lookup",
+			fRun: negativeFavoritesPutV1Upstream,
+		},
+		{
+			name: "negative test #8 Favorites.PutV1 isin mismatch",
+			fRun: negativeFavoritesPutV1IsinMismatch,
+		},
+		{
+			name: "negative test #9 Favorites.PutV1 bad If-Match",
+			fRun: negativeFavoritesPutV1BadIfMatch,
+		},
+		{
+			name: "negative test #10 Favorites.DeleteV1 not found",
+			fRun: negativeFavoritesDeleteV1NotFound,
+		},
+		{
+			name: "negative test #11 Favorites.ListV1 internal error",
+			fRun: negativeFavoritesListV1,
+		},
+		{
+			name: "positive test #12 Deprecated",
+			fRun: positiveDeprecated,
+		},
+		{
+			name: "positive test #13 Favorites.PutV1 after DeleteV1",
+			fRun: positiveFavoritesPutV1AfterDeleteV1,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func positiveFavoritesGetV1(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesGet(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, model models.Favorites) (models.Favorites, error) {
+			assert.Equal(t, "test", model.Asset().Isin())
+			assert.Equal(t, "test", model.User().PersonalKey())
+			return models.FavoritesFromDto(dto.Favorites{Isin: "test"}, "test", "").WithVersion(3), nil
+		}).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodGet, "/test", "", "")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, `"3"`, resp.Header.Get(fiber.HeaderETag), "ETag")
+}
+
+func positiveFavoritesListV1(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesGetForUser(gomock.Any(), gomock.Any()).
+		Return(make([]models.Favorites, 0), nil).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodGet, "/", "", "")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+func positiveFavoritesPutV1(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesSet(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, model models.Favorites) (models.Favorites, error) {
+			assert.Equal(t, "test", model.Asset().Isin())
+			assert.Equal(t, int64(2), model.Version())
+			return model.WithVersion(3), nil
+		}).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodPut, "/test", `{"asset_type":"test","version":1}`, `W/"2"`)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, `"3"`, resp.Header.Get(fiber.HeaderETag), "ETag")
+}
+
+func positiveFavoritesDeleteV1(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesDelete(gomock.Any(), gomock.Any()).
+		Return(models.Favorites{}, nil).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodDelete, "/test", "", "")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesGetV1NotFound(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesGet(gomock.Any(), gomock.Any()).
+		Return(models.Favorites{}, fmt.Errorf("%w: %w", services.ErrFavoritesNotFound, pgx.ErrNoRows)).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodGet, "/test", "", "")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesGetV1Deleted(t *testing.T) {
+
+	a := entity.MakeTAttributes(sql.NullBool{Bool: true, Valid: true}, time.Now(), sql.NullTime{})
+	deleted := models.FavoritesFromEntity(entity.MakeFavorites(uuid.New(), entity.Asset{}, entity.User{}, sql.NullInt64{}, a))
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesGet(gomock.Any(), gomock.Any()).
+		Return(deleted, nil).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodGet, "/test", "", "")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesPutV1Conflict(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesSet(gomock.Any(), gomock.Any()).
+		Return(models.Favorites{}, services.ErrFavoritesVersionConflict).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodPut, "/test", `{"asset_type":"test"}`, `"1"`)
+	utils.AssertEqual(t, 409, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesPutV1Upstream(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesSet(gomock.Any(), gomock.Any()).
+		Return(models.Favorites{}, fmt.Errorf("%w: asset by isin: test not found", services.ErrUpstreamLookup)).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodPut, "/test", `{"asset_type":"test"}`, "")
+	utils.AssertEqual(t, 502, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesPutV1IsinMismatch(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodPut, "/test", `{"isin":"other","asset_type":"test"}`, "")
+	utils.AssertEqual(t, 400, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesPutV1BadIfMatch(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodPut, "/test", `{"asset_type":"test"}`, `"abc"`)
+	utils.AssertEqual(t, 400, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesDeleteV1NotFound(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesDelete(gomock.Any(), gomock.Any()).
+		Return(models.Favorites{}, services.ErrFavoritesNotFound).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodDelete, "/test", "", "")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+}
+
+func negativeFavoritesListV1(t *testing.T) {
+
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesGetForUser(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("test")).
+		Times(1)
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodGet, "/", "", "")
+	utils.AssertEqual(t, 500, resp.StatusCode, "Status code")
+}
+
+func positiveDeprecated(t *testing.T) {
+
+	app := fiber.New()
+	app.Get("/", Deprecated("/api/v1/users/me/favorites"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "true", resp.Header.Get("Deprecation"), "Deprecation")
+	utils.AssertEqual(t, `</api/v1/users/me/favorites>; rel="successor-version"`, resp.Header.Get(fiber.HeaderLink), "Link")
+}
+
+func positiveFavoritesPutV1AfterDeleteV1(t *testing.T) {
+
+	var version int64
+	var deleted bool
+	stored := func() models.Favorites {
+		a := entity.MakeTAttributes(sql.NullBool{Bool: deleted, Valid: deleted}, time.Now(), sql.NullTime{})
+		asset := entity.MakeAsset("test", entity.MakeAssetType("test", entity.DefaultTAttributes()), entity.DefaultTAttributes())
+		return models.FavoritesFromEntity(entity.MakeFavorites(uuid.New(), asset, entity.User{}, sql.NullInt64{Int64: version, Valid: true}, a))
+	}
+	favoritesServ := NewMockApiFavoritesService(gomock.NewController(t))
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesSet(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, models.Favorites) (models.Favorites, error) {
+			// повторное добавление снимает надгробие
+			version, deleted = version+1, false
+			return stored(), nil
+		}).
+		Times(2)
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesDelete(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, models.Favorites) (models.Favorites, error) {
+			version, deleted = version+1, true
+			return stored(), nil
+		}).
+		Times(1)
+	favoritesServ.
+		EXPECT().
+		ApiFavoritesGet(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, models.Favorites) (models.Favorites, error) {
+			return stored(), nil
+		}).
+		Times(2)
+
+	resp := testFavoritesV1(t, favoritesServ, fiber.MethodPut, "/test", `{"asset_type":"test"}`, "")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	resp = testFavoritesV1(t, favoritesServ, fiber.MethodDelete, "/test", "", "")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	resp = testFavoritesV1(t, favoritesServ, fiber.MethodGet, "/test", "", "")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+
+	resp = testFavoritesV1(t, favoritesServ, fiber.MethodPut, "/test", `{"asset_type":"test"}`, "")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, `"3"`, resp.Header.Get(fiber.HeaderETag), "ETag")
+	resp = testFavoritesV1(t, favoritesServ, fiber.MethodGet, "/test", "", "")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, `"3"`, resp.Header.Get(fiber.HeaderETag), "ETag")
+}
+
+func testFavoritesV1(
+	t *testing.T,
+	favoritesServ services.ApiFavoritesService,
+	method, target, body, ifMatch string,
+) *http.Response {
+
+	prop := env.GetProperties()
+	app := fiber.New()
+	app.Use(requestid.New())
+
+	controller := getTestFavoritesController(prop, favoritesServ)
+	deserializeUser := middleware.GetUserJwtHandler(prop).DeserializeUser
+	app.Get("/", deserializeUser, controller.ListV1)
+	app.Delete("/:isin", deserializeUser, controller.DeleteV1)
+	app.Get("/:isin", deserializeUser, controller.GetV1)
+	app.Put("/:isin", deserializeUser, controller.PutV1)
+
+	tokenString, err := getTokenString(prop)
+	assert.Nil(t, err)
+
+	var reader io.Reader
+	if body != "" {
+		reader = bytes.NewBufferString(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	if ifMatch != "" {
+		req.Header.Set(fiber.HeaderIfMatch, ifMatch)
+	}
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+
+	return resp
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
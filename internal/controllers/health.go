@@ -0,0 +1,167 @@
+/*
+ * This file was last modified at 2024-08-18 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * health.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"google.golang.org/grpc/connectivity"
+)
+
+const (
+	healthConnecting = "connecting"
+	healthDisabled   = "disabled"
+	healthDown       = "down"
+	healthUp         = "up"
+	// readinessTimeout время на проверку доступности зависимостей.
+	readinessTimeout = 2 * time.Second
+)
+
+// Health проверки состояния сервиса для оркестратора.
+type Health struct {
+	assetGRPC grpcProbe
+	authGRPC  grpcProbe
+	mongodb   mongoProbe
+}
+
+// Readiness готовность сервиса принимать запросы.
+type Readiness struct {
+	Status    string           `json:"status"`
+	AssetGRPC GRPCReadiness    `json:"asset_grpc"`
+	AuthGRPC  GRPCReadiness    `json:"auth_grpc"`
+	MongoDB   MongoDBReadiness `json:"mongodb"`
+}
+
+// GRPCReadiness состояние соединения с внешним gRPC-сервисом.
+type GRPCReadiness struct {
+	Status     string                `json:"status"`
+	Connection *tool.GRPCClientStats `json:"connection,omitempty"`
+}
+
+// MongoDBReadiness доступность MongoDB и статистика пула соединений клиента.
+type MongoDBReadiness struct {
+	Status string               `json:"status"`
+	Error  string               `json:"error,omitempty"`
+	Pool   *tool.MongoPoolStats `json:"pool,omitempty"`
+}
+
+// grpcProbe состояние соединения с внешним gRPC-сервисом, см. tool.GRPCClient.
+type grpcProbe interface {
+	Connect()
+	Stats() tool.GRPCClientStats
+}
+
+// mongoProbe проверка доступности MongoDB, см. tool.MongoClient.
+type mongoProbe interface {
+	Ping(ctx context.Context) error
+	Stats() tool.MongoPoolStats
+}
+
+var (
+	onceHealth = new(sync.Once)
+	healthCont *Health
+)
+
+// GetHealthController — потокобезопасное (thread-safe) создание
+// REST веб-сервиса проверок состояния.
+func GetHealthController(prop env.Properties) *Health {
+
+	onceHealth.Do(func() {
+		healthCont = new(Health)
+		// адрес не задан — клиент внешнего gRPC-сервиса не создан.
+		if client := prop.ExternalAssetGRPCClient(); client != nil {
+			healthCont.assetGRPC = client
+		}
+		if client := prop.ExternalAuthGRPCClient(); client != nil {
+			healthCont.authGRPC = client
+		}
+		// клиент не создан — подключение к MongoDB выключено в конфигурации.
+		if client := prop.MongodbClient(); client != nil {
+			healthCont.mongodb = client
+		}
+	})
+	return healthCont
+}
+
+// Ready handler
+//
+//	@Summary		готовность сервиса
+//	@Description	проверка готовности (readiness): доступность MongoDB и статистика пула соединений,
+//	@Description	состояние соединений с внешними gRPC-сервисами. Недоступность внешних gRPC-сервисов
+//	@Description	не снимает готовность: поиск инструментов и пользователей выполняется и в базе данных.
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200					{object}	Readiness	"сервис готов"
+//	@Failure		503					{object}	Readiness	"зависимость недоступна"
+//	@Router			/api/health/ready	[get]
+func (h *Health) Ready(c *fiber.Ctx) error {
+
+	ctx, cancel := context.WithTimeout(c.Context(), readinessTimeout)
+	defer cancel()
+
+	readiness := Readiness{
+		Status:    "success",
+		AssetGRPC: grpcReadiness(h.assetGRPC),
+		AuthGRPC:  grpcReadiness(h.authGRPC),
+		MongoDB:   h.mongoDBReadiness(ctx),
+	}
+
+	if readiness.MongoDB.Status == healthDown {
+		readiness.Status = "fail"
+		return c.
+			Status(fiber.StatusServiceUnavailable).
+			JSON(readiness)
+	}
+	return c.
+		Status(fiber.StatusOK).
+		JSON(readiness)
+}
+
+func grpcReadiness(probe grpcProbe) GRPCReadiness {
+
+	if probe == nil {
+		return GRPCReadiness{Status: healthDisabled}
+	}
+	stats := probe.Stats()
+
+	switch stats.State {
+	case connectivity.Ready.String():
+		return GRPCReadiness{Status: healthUp, Connection: &stats}
+	case connectivity.Idle.String():
+		// соединение простаивало и закрыто, открывается заново к следующей проверке.
+		probe.Connect()
+		return GRPCReadiness{Status: healthConnecting, Connection: &stats}
+	case connectivity.Connecting.String():
+		return GRPCReadiness{Status: healthConnecting, Connection: &stats}
+	}
+	return GRPCReadiness{Status: healthDown, Connection: &stats}
+}
+
+func (h *Health) mongoDBReadiness(ctx context.Context) MongoDBReadiness {
+
+	if h.mongodb == nil {
+		return MongoDBReadiness{Status: healthDisabled}
+	}
+	stats := h.mongodb.Stats()
+
+	if err := h.mongodb.Ping(ctx); err != nil {
+		return MongoDBReadiness{Status: healthDown, Error: err.Error(), Pool: &stats}
+	}
+	return MongoDBReadiness{Status: healthUp, Pool: &stats}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,133 @@
+/*
+ * This file was last modified at 2024-08-18 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * health_test.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"google.golang.org/grpc/connectivity"
+)
+
+func Test_Health_Ready(t *testing.T) {
+	var tests = []struct {
+		name      string
+		assetGRPC grpcProbe
+		mongodb   mongoProbe
+		want      int
+		status    string
+		grpc      string
+	}{
+		{
+			name:    "positive test #0 Health.Ready",
+			mongodb: mongoProbeStub{stats: tool.MongoPoolStats{Open: 2, InUse: 1, MaxPoolSize: 100}},
+			want:    fiber.StatusOK,
+			status:  healthUp,
+			grpc:    healthDisabled,
+		},
+		{
+			name:   "positive test #1 Health.Ready MongoDB disabled",
+			want:   fiber.StatusOK,
+			status: healthDisabled,
+			grpc:   healthDisabled,
+		},
+		{
+			name:    "negative test #2 Health.Ready MongoDB is down",
+			mongodb: mongoProbeStub{err: fmt.Errorf("test")},
+			want:    fiber.StatusServiceUnavailable,
+			status:  healthDown,
+			grpc:    healthDisabled,
+		},
+		{
+			name:      "positive test #3 Health.Ready asset gRPC is ready",
+			assetGRPC: &grpcProbeStub{state: connectivity.Ready},
+			want:      fiber.StatusOK,
+			status:    healthDisabled,
+			grpc:      healthUp,
+		},
+		{
+			name:      "positive test #4 Health.Ready asset gRPC is idle",
+			assetGRPC: &grpcProbeStub{state: connectivity.Idle},
+			want:      fiber.StatusOK,
+			status:    healthDisabled,
+			grpc:      healthConnecting,
+		},
+		{
+			name:      "negative test #5 Health.Ready asset gRPC is down",
+			assetGRPC: &grpcProbeStub{state: connectivity.TransientFailure},
+			want:      fiber.StatusOK,
+			status:    healthDisabled,
+			grpc:      healthDown,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", (&Health{assetGRPC: test.assetGRPC, mongodb: test.mongodb}).Ready)
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, test.want, resp.StatusCode, "Status code")
+
+			var got Readiness
+			assert.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+			assert.Equal(t, test.status, got.MongoDB.Status)
+			assert.Equal(t, test.grpc, got.AssetGRPC.Status)
+			assert.Equal(t, healthDisabled, got.AuthGRPC.Status)
+
+			if test.mongodb != nil {
+				assert.Equal(t, test.mongodb.Stats(), *got.MongoDB.Pool)
+			} else {
+				assert.Nil(t, got.MongoDB.Pool)
+			}
+			if stub, ok := test.assetGRPC.(*grpcProbeStub); ok {
+				assert.Equal(t, stub.state.String(), got.AssetGRPC.Connection.State)
+				assert.Equal(t, stub.state == connectivity.Idle, stub.connected)
+			}
+		})
+	}
+}
+
+type grpcProbeStub struct {
+	connected bool
+	state     connectivity.State
+}
+
+func (g *grpcProbeStub) Connect() {
+	g.connected = true
+}
+
+func (g *grpcProbeStub) Stats() tool.GRPCClientStats {
+	return tool.GRPCClientStats{Addresses: []string{"127.0.0.1:8444"}, State: g.state.String()}
+}
+
+type mongoProbeStub struct {
+	stats tool.MongoPoolStats
+	err   error
+}
+
+func (m mongoProbeStub) Ping(_ context.Context) error {
+	return m.err
+}
+
+func (m mongoProbeStub) Stats() tool.MongoPoolStats {
+	return m.stats
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
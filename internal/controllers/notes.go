@@ -0,0 +1,274 @@
+/*
+ * This file was last modified at 2024-08-12 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * notes.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/services"
+)
+
+type Notes struct {
+	notesServ services.ApiNotesService
+}
+
+var (
+	onceNotes = new(sync.Once)
+	notesCont *Notes
+)
+
+// GetNotesController — потокобезопасное (thread-safe) создание
+// REST веб-сервиса хранения зашифрованных заметок пользователя.
+func GetNotesController(prop env.Properties) *Notes {
+
+	onceNotes.Do(func() {
+		notesCont = new(Notes)
+		notesCont.notesServ = services.GetNotesService(prop)
+	})
+	return notesCont
+}
+
+// Delete handler
+//
+//	@Summary		заметки
+//	@Description	удаление заметки пользователя
+//	@Tags			Notes
+//	@Accept			json
+//	@Produce		json
+//	@Security		none
+//	@Param			request			body		dto.Note	true	"Формат запроса JSON (body)"
+//	@Success		200				{object}	dto.Note	"удалённая заметка"
+//	@Failure		400				{object}	string	"неверный формат запроса"
+//	@Failure		401				{object}	string	"пользователь не авторизован"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/api/notes/delete	[post]
+func (n *Notes) Delete(c *fiber.Ctx) error {
+
+	var payload dto.Note
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
+	}
+	errors := dto.ValidateStruct(payload)
+
+	if errors != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(errors)
+	}
+	model := models.NoteFromDto(payload, user, "")
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	note, err := n.notesServ.ApiNotesDelete(ctx, model)
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{
+				"status":  "fail",
+				"message": fmt.Sprintf("error: %v", err),
+			})
+	}
+	response := note.ToDto()
+
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"note": response, "user": user},
+		})
+}
+
+// Get handler
+//
+//	@Summary		заметки
+//	@Description	получение заметки пользователя
+//	@Tags			Notes
+//	@Accept			json
+//	@Produce		json
+//	@Security		none
+//	@Param			request			body		dto.Note	true	"Формат запроса JSON (body)"
+//	@Success		200				{object}	dto.Note	"заметка"
+//	@Failure		400				{object}	string	"неверный формат запроса"
+//	@Failure		401				{object}	string	"пользователь не авторизован"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/api/notes/get	[post]
+func (n *Notes) Get(c *fiber.Ctx) error {
+
+	var payload dto.Note
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
+	}
+	errors := dto.ValidateStruct(payload)
+
+	if errors != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(errors)
+	}
+	model := models.NoteFromDto(payload, user, "")
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	note, err := n.notesServ.ApiNotesGet(ctx, model)
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{
+				"status":  "fail",
+				"message": fmt.Sprintf("error: %v", err),
+			})
+	}
+	response := note.ToDto()
+
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"note": response, "user": user},
+		})
+}
+
+// GetForUser handler
+//
+//	@Summary		заметки
+//	@Description	получение заметок пользователя
+//	@Security	Bearer
+//	@Tags			Notes
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200				{array}		[]dto.Note	"успешная обработка запроса"
+//	@Failure		401				{object}	string	"пользователь не авторизован"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/api/notes/get 	[get]
+func (n *Notes) GetForUser(c *fiber.Ctx) error {
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	model := models.MakeUser(user, "")
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	notes, err := n.notesServ.ApiNotesGetForUser(ctx, model)
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{
+				"status":  "fail",
+				"message": fmt.Sprintf("error: %v", err),
+			})
+	}
+	response := models.NotesSliceToDto(notes)
+
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"notes": response, "user": user},
+		})
+}
+
+// Set handler
+//
+//	@Summary		заметки
+//	@Description	сохранение заметки пользователя
+//	@Tags			Notes
+//	@Accept			json
+//	@Produce		json
+//	@Security		none
+//	@Param			request			body		dto.Note	true	"Формат запроса JSON (body)"
+//	@Success		200				{object}	dto.Note	"заметка"
+//	@Failure		400				{object}	string	"неверный формат запроса"
+//	@Failure		401				{object}	string	"пользователь не авторизован"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/api/notes/set	[post]
+func (n *Notes) Set(c *fiber.Ctx) error {
+
+	var payload dto.Note
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "message": err.Error(), "requestId": requestId})
+	}
+	errors := dto.ValidateStruct(payload)
+
+	if errors != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(errors)
+	}
+	model := models.NoteFromDto(payload, user, "")
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	note, err := n.notesServ.ApiNotesSet(ctx, model)
+	response := note.ToDto()
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{
+				"status":  "fail",
+				"message": fmt.Sprintf("error: %v", err),
+			})
+	}
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"note": response, "user": user},
+		})
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
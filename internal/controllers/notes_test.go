@@ -0,0 +1,246 @@
+/*
+ * This file was last modified at 2024-08-12 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * notes_test.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/middleware"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/services"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNotes(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "positive test #0 Notes.Get",
+			fRun: positiveNotesGet,
+		},
+		{
+			name: "positive test #1 Notes.GetForUser",
+			fRun: positiveNotesGetForUser,
+		},
+		{
+			name: "positive test #2 Notes.Set",
+			fRun: positiveNotesSet,
+		},
+		{
+			name: "positive test #3 Notes.Delete",
+			fRun: positiveNotesDelete,
+		},
+		{
+			name: "negative test #4 Notes.Get unauthorized",
+			fRun: negativeNotesGet0,
+		},
+		{
+			name: "negative test #5 Notes.Get without name",
+			fRun: negativeNotesGet1,
+		},
+		{
+			name: "negative test #6 Notes.GetForUser service error",
+			fRun: negativeNotesGetForUser0,
+		},
+		{
+			name: "negative test #7 Notes.Set service error",
+			fRun: negativeNotesSet0,
+		},
+		{
+			name: "negative test #8 Notes.Delete empty body",
+			fRun: negativeNotesDelete0,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func positiveNotesGet(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var notesServ = NewMockApiNotesService(ctrl)
+	notesServ.
+		EXPECT().
+		ApiNotesGet(gomock.Any(), gomock.Any()).
+		Return(models.Note{}, nil).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodPost, getTestNotesController(notesServ).Get)
+	resp := testNotesRequest(t, prop, app, fiber.MethodPost, bytes.NewBufferString(`{"name":"test","body":"test"}`), true)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+func positiveNotesGetForUser(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var notesServ = NewMockApiNotesService(ctrl)
+	notesServ.
+		EXPECT().
+		ApiNotesGetForUser(gomock.Any(), gomock.Any()).
+		Return(make([]models.Note, 0), nil).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodGet, getTestNotesController(notesServ).GetForUser)
+	resp := testNotesRequest(t, prop, app, fiber.MethodGet, nil, true)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+func positiveNotesSet(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var notesServ = NewMockApiNotesService(ctrl)
+	notesServ.
+		EXPECT().
+		ApiNotesSet(gomock.Any(), gomock.Any()).
+		Return(models.Note{}, nil).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodPost, getTestNotesController(notesServ).Set)
+	resp := testNotesRequest(t, prop, app, fiber.MethodPost, bytes.NewBufferString(`{"name":"test","body":"test"}`), true)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+func positiveNotesDelete(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var notesServ = NewMockApiNotesService(ctrl)
+	notesServ.
+		EXPECT().
+		ApiNotesDelete(gomock.Any(), gomock.Any()).
+		Return(models.Note{}, nil).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodPost, getTestNotesController(notesServ).Delete)
+	resp := testNotesRequest(t, prop, app, fiber.MethodPost, bytes.NewBufferString(`{"name":"test"}`), true)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+func negativeNotesGet0(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var notesServ = NewMockApiNotesService(ctrl)
+	app := getTestNotesApp(prop, fiber.MethodPost, getTestNotesController(notesServ).Get)
+	resp := testNotesRequest(t, prop, app, fiber.MethodPost, bytes.NewBufferString(`{"name":"test"}`), false)
+	utils.AssertEqual(t, 401, resp.StatusCode, "Status code")
+}
+
+func negativeNotesGet1(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var notesServ = NewMockApiNotesService(ctrl)
+	app := getTestNotesApp(prop, fiber.MethodPost, getTestNotesController(notesServ).Get)
+	resp := testNotesRequest(t, prop, app, fiber.MethodPost, bytes.NewBufferString(`{"body":"test"}`), true)
+	utils.AssertEqual(t, 400, resp.StatusCode, "Status code")
+}
+
+func negativeNotesGetForUser0(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var notesServ = NewMockApiNotesService(ctrl)
+	notesServ.
+		EXPECT().
+		ApiNotesGetForUser(gomock.Any(), gomock.Any()).
+		Return(nil, services.ErrRequestNil).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodGet, getTestNotesController(notesServ).GetForUser)
+	resp := testNotesRequest(t, prop, app, fiber.MethodGet, nil, true)
+	utils.AssertEqual(t, 400, resp.StatusCode, "Status code")
+}
+
+func negativeNotesSet0(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var notesServ = NewMockApiNotesService(ctrl)
+	notesServ.
+		EXPECT().
+		ApiNotesSet(gomock.Any(), gomock.Any()).
+		Return(models.Note{}, services.ErrRequestNil).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodPost, getTestNotesController(notesServ).Set)
+	resp := testNotesRequest(t, prop, app, fiber.MethodPost, bytes.NewBufferString(`{"name":"test","body":"test"}`), true)
+	utils.AssertEqual(t, 400, resp.StatusCode, "Status code")
+}
+
+func negativeNotesDelete0(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var notesServ = NewMockApiNotesService(ctrl)
+	app := getTestNotesApp(prop, fiber.MethodPost, getTestNotesController(notesServ).Delete)
+	resp := testNotesRequest(t, prop, app, fiber.MethodPost, bytes.NewBufferString(``), true)
+	utils.AssertEqual(t, 400, resp.StatusCode, "Status code")
+}
+
+func getTestNotesApp(prop env.Properties, method string, handler fiber.Handler) *fiber.App {
+
+	app := fiber.New()
+	app.Use(requestid.New())
+	app.Add(method, "/", middleware.GetUserJwtHandler(prop).DeserializeUser, handler)
+
+	return app
+}
+
+func testNotesRequest(t *testing.T, prop env.Properties, app *fiber.App, method string, body io.Reader, auth bool) *http.Response {
+
+	req := httptest.NewRequest(method, "/", body)
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	if auth {
+		tokenString, err := getTokenString(prop)
+		assert.Nil(t, err)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+	}
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+
+	return resp
+}
+
+func getTestNotesController(notesServ services.ApiNotesService) *Notes {
+
+	notesCont = new(Notes)
+	notesCont.notesServ = notesServ
+
+	return notesCont
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,131 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * privacy.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/services"
+)
+
+type Privacy struct {
+	privacyServ services.ApiPrivacyService
+}
+
+var (
+	oncePrivacy = new(sync.Once)
+	privacyCont *Privacy
+)
+
+// GetPrivacyController — потокобезопасное (thread-safe) создание
+// REST веб-сервиса выгрузки и удаления данных пользователя.
+func GetPrivacyController(prop env.Properties) *Privacy {
+
+	oncePrivacy.Do(func() {
+		privacyCont = new(Privacy)
+		privacyCont.privacyServ = services.GetPrivacyService(prop)
+	})
+	return privacyCont
+}
+
+// Erase handler
+//
+//	@Summary		персональные данные
+//	@Description	удаление всех данных пользователя, в ответе запись аудита удаления без персональных данных
+//	@Tags			Privacy
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200		{object}	dto.Erasure	"запись аудита удаления"
+//	@Failure		400		{object}	string		"неверный формат запроса"
+//	@Failure		401		{object}	string		"пользователь не авторизован"
+//	@Failure		500		{string}	string		"Internal Server Error"
+//	@Router			/api/v1/users/me	[delete]
+func (p *Privacy) Erase(c *fiber.Ctx) error {
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	erasure, err := p.privacyServ.ApiPrivacyErase(ctx, models.MakeUser(user, ""))
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusInternalServerError).
+			JSON(fiber.Map{
+				"status":    "fail",
+				"requestId": requestId,
+				"message":   fmt.Sprintf("error: %v", err),
+			})
+	}
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      fiber.Map{"erasure": erasure.ToDto()},
+		})
+}
+
+// Export handler
+//
+//	@Summary		персональные данные
+//	@Description	выгрузка всех данных пользователя: избранное, удалённое избранное, заметки, секреты OTP без самих секретов
+//	@Tags			Privacy
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200		{object}	dto.UserData	"данные пользователя"
+//	@Failure		400		{object}	string			"неверный формат запроса"
+//	@Failure		401		{object}	string			"пользователь не авторизован"
+//	@Failure		500		{string}	string			"Internal Server Error"
+//	@Router			/api/v1/users/me/export	[get]
+func (p *Privacy) Export(c *fiber.Ctx) error {
+
+	requestId := c.Locals("requestid")
+	user, ok := c.Locals("user").(string)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"status": "fail", "requestId": requestId, "message": "user failed"})
+	}
+	ctx := context.WithValue(c.Context(), "request-id", requestId)
+	data, err := p.privacyServ.ApiPrivacyExport(ctx, models.MakeUser(user, ""))
+
+	if err != nil {
+		return c.
+			Status(fiber.StatusInternalServerError).
+			JSON(fiber.Map{
+				"status":    "fail",
+				"requestId": requestId,
+				"message":   fmt.Sprintf("error: %v", err),
+			})
+	}
+	return c.
+		Status(fiber.StatusOK).
+		JSON(fiber.Map{
+			"status":    "success",
+			"requestId": requestId,
+			"data":      data.ToDto(),
+		})
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,144 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * privacy_test.go
+ * $Id$
+ */
+//!+
+
+// Package controllers REST-ful (endpoints) конечные точки REST веб-сервиса.
+package controllers
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/services"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPrivacy(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "positive test #0 Privacy.Export",
+			fRun: positivePrivacyExport,
+		},
+		{
+			name: "positive test #1 Privacy.Erase",
+			fRun: positivePrivacyErase,
+		},
+		{
+			name: "negative test #2 Privacy.Erase unauthorized",
+			fRun: negativePrivacyErase0,
+		},
+		{
+			name: "negative test #3 Privacy.Export service error",
+			fRun: negativePrivacyExport0,
+		},
+		{
+			name: "negative test #4 Privacy.Erase service error",
+			fRun: negativePrivacyErase1,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func positivePrivacyExport(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var privacyServ = NewMockApiPrivacyService(ctrl)
+	privacyServ.
+		EXPECT().
+		ApiPrivacyExport(gomock.Any(), models.MakeUser("test", "")).
+		Return(models.UserData{}, nil).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodGet, getTestPrivacyController(privacyServ).Export)
+	resp := testNotesRequest(t, prop, app, fiber.MethodGet, nil, true)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+func positivePrivacyErase(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var privacyServ = NewMockApiPrivacyService(ctrl)
+	privacyServ.
+		EXPECT().
+		ApiPrivacyErase(gomock.Any(), models.MakeUser("test", "")).
+		Return(models.Erasure{}, nil).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodDelete, getTestPrivacyController(privacyServ).Erase)
+	resp := testNotesRequest(t, prop, app, fiber.MethodDelete, nil, true)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+func negativePrivacyErase0(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var privacyServ = NewMockApiPrivacyService(ctrl)
+	app := getTestNotesApp(prop, fiber.MethodDelete, getTestPrivacyController(privacyServ).Erase)
+	resp := testNotesRequest(t, prop, app, fiber.MethodDelete, nil, false)
+	utils.AssertEqual(t, 401, resp.StatusCode, "Status code")
+}
+
+func negativePrivacyExport0(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var privacyServ = NewMockApiPrivacyService(ctrl)
+	privacyServ.
+		EXPECT().
+		ApiPrivacyExport(gomock.Any(), gomock.Any()).
+		Return(models.UserData{}, services.ErrRequestNil).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodGet, getTestPrivacyController(privacyServ).Export)
+	resp := testNotesRequest(t, prop, app, fiber.MethodGet, nil, true)
+	utils.AssertEqual(t, 500, resp.StatusCode, "Status code")
+}
+
+func negativePrivacyErase1(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	prop := env.GetProperties()
+
+	var privacyServ = NewMockApiPrivacyService(ctrl)
+	privacyServ.
+		EXPECT().
+		ApiPrivacyErase(gomock.Any(), gomock.Any()).
+		Return(models.Erasure{}, services.ErrSyncStoreErase).
+		Times(1)
+	app := getTestNotesApp(prop, fiber.MethodDelete, getTestPrivacyController(privacyServ).Erase)
+	resp := testNotesRequest(t, prop, app, fiber.MethodDelete, nil, true)
+	utils.AssertEqual(t, 500, resp.StatusCode, "Status code")
+}
+
+func getTestPrivacyController(privacyServ services.ApiPrivacyService) *Privacy {
+
+	privacyCont = new(Privacy)
+	privacyCont.privacyServ = privacyServ
+
+	return privacyCont
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
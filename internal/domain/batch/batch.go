@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-03 14:56 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 03:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * batch.go
@@ -32,17 +32,28 @@ type FavoritesInsertsBatch interface {
 	Do(ctx context.Context, favorites []entity.Favorites, upk string) error
 }
 
+type NotesInsertsBatch interface {
+	Do(ctx context.Context, notes []entity.Note, upk string) error
+}
+
 type postgres struct {
 	pool *pgxpool.Pool
 	sLog *slog.Logger
 }
 
+type notesPostgres struct {
+	postgres
+}
+
 var (
-	ErrBadPool    = fmt.Errorf("bad Database pool")
-	oncePostgres  = new(sync.Once)
-	batchPostgres *postgres
+	ErrBadPool         = fmt.Errorf("bad Database pool")
+	oncePostgres       = new(sync.Once)
+	batchPostgres      *postgres
+	onceNotesPostgres  = new(sync.Once)
+	batchNotesPostgres *notesPostgres
 )
 var _ FavoritesInsertsBatch = (*postgres)(nil)
+var _ NotesInsertsBatch = (*notesPostgres)(nil)
 
 func GetBatchPostgres(prop env.Properties) FavoritesInsertsBatch {
 	oncePostgres.Do(func() {
@@ -53,6 +64,15 @@ func GetBatchPostgres(prop env.Properties) FavoritesInsertsBatch {
 	return batchPostgres
 }
 
+func GetNotesBatchPostgres(prop env.Properties) NotesInsertsBatch {
+	onceNotesPostgres.Do(func() {
+		batchNotesPostgres = new(notesPostgres)
+		batchNotesPostgres.pool = prop.DBPool()
+		batchNotesPostgres.sLog = prop.Logger()
+	})
+	return batchNotesPostgres
+}
+
 func (p *postgres) Do(ctx context.Context, favorites []entity.Favorites, upk string) error {
 
 	if len(favorites) < 1 {
@@ -95,12 +115,52 @@ func (p *postgres) Do(ctx context.Context, favorites []entity.Favorites, upk str
 		args = append(args, []any{f.Asset().Isin(), f.Asset().AssetType().Name(), f.Asset().CreatedAt(), f.Asset().UpdatedAt()})
 		sqls = append(sqls, `
 			INSERT INTO favorites
-    		(isin, user_upk, version, deleted, created_at)
-    		VALUES ($1, $2, $3, NULL, $4)
+    		(isin, user_upk, version, metadata, deleted, shard_id, written_at, created_at)
+    		VALUES ($1, $2, $3, $6, NULL, NULLIF($7, ''), $8, $4)
 			ON CONFLICT (isin, user_upk)
-			DO UPDATE SET version = $3, deleted = NULL, updated_at = $5
+			DO UPDATE SET version = $3, metadata = $6, deleted = NULL,
+			shard_id = NULLIF($7, ''), written_at = $8, updated_at = $5
+		`)
+		args = append(args, []any{
+			f.Asset().Isin(), f.User().Upk(), f.Version(), f.CreatedAt(), f.UpdatedAt(), f.Metadata(),
+			f.Provenance().ShardID(), f.Provenance().WrittenAt(),
+		})
+	}
+	return rowsPostgreSQL(ctx, p.sLog, p.pool, sqls, args)
+}
+
+func (p *notesPostgres) Do(ctx context.Context, notes []entity.Note, upk string) error {
+
+	if len(notes) < 1 {
+		return nil
+	}
+	maxVersion := slices.MaxFunc[[]entity.Note, entity.Note](notes, func(x, y entity.Note) int {
+		if x.User().Version() > y.User().Version() {
+			return 1
+		} else if x.User().Version() < y.User().Version() {
+			return -1
+		}
+		return 0
+	})
+	sqls := make([]string, 0, len(notes)+1)
+	args := make([][]any, 0, len(notes)+1)
+	sqls = append(sqls, `
+		INSERT INTO users
+		(upk, version, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (upk)
+		DO UPDATE SET version = users.version + 1
+	`)
+	args = append(args, []any{upk, maxVersion.User().Version(), maxVersion.User().CreatedAt()})
+
+	for _, n := range notes {
+		sqls = append(sqls, `
+			INSERT INTO notes
+    		(name, user_upk, body, version, deleted, created_at)
+    		VALUES ($1, $2, $3, $4, NULL, $5)
+			ON CONFLICT (name, user_upk)
+			DO UPDATE SET body = $3, version = $4, deleted = NULL, updated_at = $6
 		`)
-		args = append(args, []any{f.Asset().Isin(), f.User().Upk(), f.Version(), f.CreatedAt(), f.UpdatedAt()})
+		args = append(args, []any{n.Name(), n.User().Upk(), n.Body(), n.Version(), n.CreatedAt(), n.UpdatedAt()})
 	}
 	return rowsPostgreSQL(ctx, p.sLog, p.pool, sqls, args)
 }
@@ -27,6 +27,10 @@ func TestFavoritesInsertsBatch(t *testing.T) {
 			name: "test #1 negative",
 			fRun: testBatchDoNegative,
 		},
+		{
+			name: "test #2 negative notes",
+			fRun: testNotesBatchDoNegative,
+		},
 	}
 
 	assert.NotNil(t, t)
@@ -48,5 +52,18 @@ func testBatchDoNegative(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func testNotesBatchDoNegative(t *testing.T) {
+	defer func() { _ = recover() }() // TODO
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	batch := GetNotesBatchPostgres(prop)
+	err := batch.Do(context.Background(), nil, "")
+	assert.Nil(t, err)
+	err = batch.Do(context.Background(), []entity.Note{{}}, "")
+	assert.NotNil(t, err)
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
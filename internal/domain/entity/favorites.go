@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-04 20:14 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites.go
@@ -28,10 +28,11 @@ const (
 	KeyFormat = "%32s%s"
 
 	FavoritesSelectSQL = `SELECT
-	f.id, f.version, f.deleted, f.created_at, f.updated_at,
+	f.id, f.version, f.metadata, f.deleted, f.created_at, f.updated_at,
     a.isin, a.deleted, a.created_at, a.updated_at,
     t.name, t.deleted, t.created_at, t.updated_at,
-    u.upk, u.version, u.deleted, u.created_at, u.updated_at
+    u.upk, u.version, u.deleted, u.created_at, u.updated_at,
+    COALESCE(f.shard_id, ''), f.written_at
     FROM favorites f
     JOIN assets a ON f.isin = a.isin
     JOIN asset_types t ON a.asset_type = t.name 
@@ -39,10 +40,11 @@ const (
     WHERE f.isin = $1 AND f.user_upk = $2`
 
 	FavoritesSelectForUserSQL = `SELECT
-	f.id, f.version, f.deleted, f.created_at, f.updated_at,
+	f.id, f.version, f.metadata, f.deleted, f.created_at, f.updated_at,
     a.isin, a.deleted, a.created_at, a.updated_at,
     t.name, t.deleted, t.created_at, t.updated_at,
-    u.upk, u.version, u.deleted, u.created_at, u.updated_at
+    u.upk, u.version, u.deleted, u.created_at, u.updated_at,
+    COALESCE(f.shard_id, ''), f.written_at
     FROM favorites f
     JOIN assets a ON f.isin = a.isin
     JOIN asset_types t ON a.asset_type = t.name 
@@ -54,7 +56,8 @@ const (
 	AND u.deleted IS NOT TRUE`
 
 	FavoritesDeleteSQL = `UPDATE favorites
-	SET deleted = true, version = (SELECT u.version FROM users u WHERE u.upk = $3)
+	SET deleted = true, version = (SELECT u.version FROM users u WHERE u.upk = $3),
+	shard_id = COALESCE(NULLIF($4, ''), shard_id), written_at = COALESCE($5, written_at)
 	WHERE isin = $1 AND user_upk = $2
 	RETURNING id, isin, user_upk, version, deleted, created_at, updated_at`
 
@@ -68,10 +71,19 @@ const (
     WHERE isin = $1 AND user_upk = $2
     RETURNING version, updated_at`
 
+	FavoritesDeleteTxUserSQL = `UPDATE users
+	SET version = version + 1
+	WHERE upk = $2 AND EXISTS (
+		SELECT 1 FROM favorites f WHERE f.isin = $1 AND f.user_upk = $2 AND f.deleted IS NOT TRUE
+	)`
+
+	// FavoritesDeleteTxSQL надгробие получает версию пользователя, поднятую
+	// FavoritesDeleteTxUserSQL в той же транзакции.
 	FavoritesDeleteTxSQL = `UPDATE favorites
-	SET version = version + 1, deleted = true
-	WHERE isin = $1 AND user_upk = $2
-	RETURNING id, isin, user_upk, version, deleted, created_at, updated_at`
+	SET version = (SELECT u.version FROM users u WHERE u.upk = $2), deleted = true, shard_id = NULLIF($3, ''), written_at = $4
+	WHERE isin = $1 AND user_upk = $2 AND deleted IS NOT TRUE
+	RETURNING id, isin, user_upk, version, deleted, created_at, updated_at,
+	(SELECT u.version FROM users u WHERE u.upk = $2)`
 
 	FavoritesUpsertTxAssetSQL = `INSERT INTO assets
 	(isin, asset_type, created_at)
@@ -91,11 +103,19 @@ const (
 	ON CONFLICT (upk)
 	DO UPDATE SET version = users.version + 1`
 
+	// FavoritesUpsertTxFavoritesSQL ожидаемая версия $9 (If-Match) сравнивается
+	// с версией строки под блокировкой ON CONFLICT: при несовпадении строка
+	// не возвращается и транзакция откатывается. Изменение присваивает строке
+	// текущую версию пользователя, поэтому повтор с той же версией не пройдёт.
+	// Повторное добавление удалённой записи снимает надгробие.
 	FavoritesUpsertTxFavoritesSQL = `INSERT INTO favorites
-    (isin, user_upk, created_at)
-    VALUES ($1, $2, $3)
+    (isin, user_upk, metadata, shard_id, written_at, created_at)
+    SELECT $1::varchar, $2::varchar, $6::text, NULLIF($7::varchar, ''), $8::timestamp, $3::timestamp
+    WHERE $9::bigint <= 0 OR EXISTS (SELECT 1 FROM favorites WHERE isin = $1 AND user_upk = $2)
 	ON CONFLICT (isin, user_upk)
-	DO UPDATE SET updated_at = $4
+	DO UPDATE SET metadata = $6, deleted = NULL, shard_id = NULLIF($7, ''), written_at = $8, updated_at = $4,
+	version = (SELECT u.version FROM users u WHERE u.upk = $2)
+	WHERE $9::bigint <= 0 OR favorites.version = $9::bigint
     RETURNING id, isin, user_upk, version, deleted, created_at, updated_at,
 	(SELECT created_at FROM asset_types WHERE name = $5),
 	(SELECT created_at FROM assets WHERE isin = $1),
@@ -105,10 +125,13 @@ const (
 
 type Favorites struct {
 	TAttributes
-	id      uuid.UUID
-	asset   Asset
-	user    User
-	version sql.NullInt64
+	id       uuid.UUID
+	asset    Asset
+	user     User
+	version  sql.NullInt64
+	metadata string
+	// provenance шард и время последней записи.
+	provenance Provenance
 }
 
 type favorites struct {
@@ -116,9 +139,12 @@ type favorites struct {
 	Asset     asset
 	User      user
 	Version   int64
+	Metadata  string       `json:",omitempty"`
 	Deleted   JsonNullBool `json:",omitempty"`
 	CreatedAt time.Time
 	UpdatedAt JsonNullTime `json:",omitempty"`
+	ShardID   string       `json:",omitempty"`
+	WrittenAt JsonNullTime `json:",omitempty"`
 }
 
 var _ domain.Entity = (*Favorites)(nil)
@@ -132,6 +158,7 @@ func GetFavorites(ctx context.Context, repo domain.Repo[*Favorites], isin, upk s
 		err = scanner.Scan(
 			&result.id,
 			&result.version,
+			&result.metadata,
 			&result.deleted,
 			&result.createdAt,
 			&result.updatedAt,
@@ -151,6 +178,9 @@ func GetFavorites(ctx context.Context, repo domain.Repo[*Favorites], isin, upk s
 			&result.user.deleted,
 			&result.user.createdAt,
 			&result.user.updatedAt,
+
+			&result.provenance.shardID,
+			&result.provenance.writtenAt,
 		)
 	})
 	if er0 != nil {
@@ -171,6 +201,7 @@ func GetFavoritesForUser(ctx context.Context, repo domain.Repo[*Favorites], upk
 		err = scanner.Scan(
 			&result.id,
 			&result.version,
+			&result.metadata,
 			&result.deleted,
 			&result.createdAt,
 			&result.updatedAt,
@@ -190,6 +221,9 @@ func GetFavoritesForUser(ctx context.Context, repo domain.Repo[*Favorites], upk
 			&result.user.deleted,
 			&result.user.createdAt,
 			&result.user.updatedAt,
+
+			&result.provenance.shardID,
+			&result.provenance.writtenAt,
 		)
 		results = append(results, result)
 		return &result
@@ -238,6 +272,32 @@ func (f Favorites) Version() sql.NullInt64 {
 	return f.version
 }
 
+func (f Favorites) Metadata() string {
+	return f.metadata
+}
+
+func (f Favorites) WithMetadata(metadata string) Favorites {
+	t := f
+	t.metadata = metadata
+	return t
+}
+
+func (f Favorites) Provenance() Provenance {
+	return f.provenance
+}
+
+func (f Favorites) WithProvenance(provenance Provenance) Favorites {
+	t := f
+	t.provenance = provenance
+	return t
+}
+
+func (f Favorites) WithUser(user User) Favorites {
+	t := f
+	t.user = user
+	return t
+}
+
 func (f Favorites) Deleted() sql.NullBool {
 	return f.deleted
 }
@@ -262,6 +322,7 @@ func (f *Favorites) Delete(ctx context.Context, dtf domain.Dft[*Favorites], inTr
 	er0 := dtf.DoDelete(ctx, f, func(scanner domain.Scanner) {
 		err = scanner.Scan(
 			&fv.id, &fv.asset.isin, &fv.user.upk, &fv.version, &fv.deleted, &fv.createdAt, &fv.updatedAt,
+			&fv.user.version,
 		)
 		if err != nil {
 			slog.ErrorContext(ctx, env.MSG+"Favorites.Delete", "err", err)
@@ -273,6 +334,7 @@ func (f *Favorites) Delete(ctx context.Context, dtf domain.Dft[*Favorites], inTr
 			f.updatedAt = fv.updatedAt
 			f.asset.isin = fv.asset.isin
 			f.user.upk = fv.user.upk
+			f.user.version = fv.user.version
 			inTransaction()
 		}
 	})
@@ -283,7 +345,7 @@ func (f *Favorites) Delete(ctx context.Context, dtf domain.Dft[*Favorites], inTr
 }
 
 func (f *Favorites) DeleteArgs() []any {
-	return []any{f.asset.isin, f.user.upk, f.user.upk}
+	return []any{f.asset.isin, f.user.upk, f.user.upk, f.provenance.shardID, f.provenance.writtenAt}
 }
 
 func (f *Favorites) DeleteSQL() string {
@@ -293,10 +355,14 @@ func (f *Favorites) DeleteSQL() string {
 func (f *Favorites) DeleteTxArgs() domain.TxArgs {
 	return domain.TxArgs{
 		SQLs: []string{
+			FavoritesDeleteTxUserSQL,
+			OutboxFavoritesDeleteTxSQL,
 			FavoritesDeleteTxSQL,
 		},
 		Args: [][]any{
 			{f.asset.isin, f.user.upk},
+			{f.asset.isin, f.user.upk},
+			{f.asset.isin, f.user.upk, f.provenance.shardID, f.provenance.writtenAt},
 		},
 	}
 }
@@ -310,9 +376,12 @@ func (f *Favorites) FromJSON(data []byte) (err error) {
 		return err
 	}
 	f.id = t.ID
+	f.metadata = t.Metadata
 	f.deleted = t.Deleted.ToNullBool()
 	f.createdAt = t.CreatedAt
 	f.updatedAt = t.UpdatedAt.ToNullTime()
+	f.provenance.shardID = t.ShardID
+	f.provenance.writtenAt = t.WrittenAt.ToNullTime()
 
 	f.asset.isin = t.Asset.Isin
 	f.asset.deleted = t.Asset.Deleted.ToNullBool()
@@ -384,6 +453,23 @@ func (f *Favorites) String() string {
 	)
 }
 
+// Tombstone пометка записи удалённой по надгробию из MongoDB, без события outbox:
+// удаление уже есть в реплике.
+func (f *Favorites) Tombstone(ctx context.Context, repo domain.Repo[*Favorites]) (err error) {
+
+	_, er0 := repo.Delete(ctx, f, func(s domain.Scanner) {
+		t := *f
+		err = s.Scan(&t.id, &t.asset.isin, &t.user.upk, &t.version, &t.deleted, &t.createdAt, &t.updatedAt)
+		if err == nil {
+			*f = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
 func (f Favorites) ToJSON() ([]byte, error) {
 
 	result, err := json.Marshal(favorites{
@@ -408,9 +494,12 @@ func (f Favorites) ToJSON() ([]byte, error) {
 			UpdatedAt: FromNullTime(f.user.updatedAt),
 		},
 		Version:   f.version.Int64,
+		Metadata:  f.metadata,
 		Deleted:   FromNullBool(f.deleted),
 		CreatedAt: f.createdAt,
 		UpdatedAt: FromNullTime(f.updatedAt),
+		ShardID:   f.provenance.shardID,
+		WrittenAt: FromNullTime(f.provenance.writtenAt),
 	})
 	if err != nil {
 		return nil, err
@@ -479,13 +568,18 @@ func (f *Favorites) UpsertTxArgs() domain.TxArgs {
 			FavoritesUpsertTxAssetTypeSQL,
 			FavoritesUpsertTxAssetSQL,
 			FavoritesUpsertTxUserSQL,
+			OutboxFavoritesUpsertTxSQL,
 			FavoritesUpsertTxFavoritesSQL,
 		},
 		Args: [][]any{
 			{f.asset.assetType.name, f.asset.assetType.createdAt},
 			{f.asset.isin, f.asset.assetType.name, f.asset.createdAt, f.asset.updatedAt},
 			{f.user.upk, f.user.createdAt},
-			{f.asset.isin, f.user.upk, f.createdAt, f.updatedAt, f.asset.assetType.name},
+			{f.asset.isin, f.user.upk},
+			{
+				f.asset.isin, f.user.upk, f.createdAt, f.updatedAt, f.asset.assetType.name, f.metadata,
+				f.provenance.shardID, f.provenance.writtenAt, f.version.Int64,
+			},
 		},
 	}
 }
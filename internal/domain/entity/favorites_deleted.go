@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-03 13:01 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 03:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites_deleted.go
@@ -26,37 +26,73 @@ const (
 	WHERE user_upk = $1`
 
 	FavoritesDeletedSelectForUserSQL = `SELECT
-	f.id, f.version, f.deleted, f.created_at, f.updated_at,
+	f.id, f.version, f.metadata, f.deleted, f.created_at, f.updated_at,
     a.isin, a.deleted, a.created_at, a.updated_at,
     t.name, t.deleted, t.created_at, t.updated_at,
-    u.upk, u.version, u.deleted, u.created_at, u.updated_at
+    u.upk, u.version, u.deleted, u.created_at, u.updated_at,
+    COALESCE(f.shard_id, ''), f.written_at
     FROM favorites f
     JOIN assets a ON f.isin = a.isin
     JOIN asset_types t ON a.asset_type = t.name 
     JOIN users u ON f.user_upk = u.upk
     WHERE f.user_upk = $1 AND f.version IS NULL
 	AND f.deleted IS TRUE`
+
+	FavoritesDeletedSelectTombstonesForUserSQL = `SELECT
+	f.id, f.version, f.metadata, f.deleted, f.created_at, f.updated_at,
+    a.isin, a.deleted, a.created_at, a.updated_at,
+    t.name, t.deleted, t.created_at, t.updated_at,
+    u.upk, u.version, u.deleted, u.created_at, u.updated_at,
+    COALESCE(f.shard_id, ''), f.written_at
+    FROM favorites f
+    JOIN assets a ON f.isin = a.isin
+    JOIN asset_types t ON a.asset_type = t.name
+    JOIN users u ON f.user_upk = u.upk
+    WHERE f.user_upk = $1 AND f.deleted IS TRUE`
 )
 
 type FavoritesDeleted struct {
 	TAttributes
-	id      uuid.UUID
-	asset   Asset
-	user    User
-	version sql.NullInt64
+	id       uuid.UUID
+	asset    Asset
+	user     User
+	version  sql.NullInt64
+	metadata string
+	// provenance шард и время последней записи.
+	provenance Provenance
+	// tombstones выбирать все удалённые записи, а не только не удалённые в MongoDB.
+	tombstones bool
 }
 
 var _ domain.Entity = (*FavoritesDeleted)(nil)
 
+// GetFavoritesDeletedForUser удалённые записи избранного пользователя ещё не удалённые в MongoDB.
 func GetFavoritesDeletedForUser(ctx context.Context, repo domain.Repo[*FavoritesDeleted], upk string) ([]FavoritesDeleted, error) {
+	return getFavoritesDeleted(ctx, repo, &FavoritesDeleted{user: User{upk: upk}})
+}
+
+// GetFavoritesTombstonesForUser все удалённые записи избранного пользователя (надгробия, tombstones)
+// для слияния избранного из MongoDB и PostgreSQL по записям.
+func GetFavoritesTombstonesForUser(ctx context.Context, repo domain.Repo[*FavoritesDeleted], upk string) ([]FavoritesDeleted, error) {
+
+	results, err := getFavoritesDeleted(ctx, repo, &FavoritesDeleted{user: User{upk: upk}, tombstones: true})
+
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func getFavoritesDeleted(ctx context.Context, repo domain.Repo[*FavoritesDeleted], filter *FavoritesDeleted) ([]FavoritesDeleted, error) {
 
 	var err error
 	results := make([]FavoritesDeleted, 0)
-	_, er0 := repo.GetByFilter(ctx, &FavoritesDeleted{user: User{upk: upk}}, func(scanner domain.Scanner) *FavoritesDeleted {
+	_, er0 := repo.GetByFilter(ctx, filter, func(scanner domain.Scanner) *FavoritesDeleted {
 		result := FavoritesDeleted{}
 		err = scanner.Scan(
 			&result.id,
 			&result.version,
+			&result.metadata,
 			&result.deleted,
 			&result.createdAt,
 			&result.updatedAt,
@@ -76,6 +112,9 @@ func GetFavoritesDeletedForUser(ctx context.Context, repo domain.Repo[*Favorites
 			&result.user.deleted,
 			&result.user.createdAt,
 			&result.user.updatedAt,
+
+			&result.provenance.shardID,
+			&result.provenance.writtenAt,
 		)
 		results = append(results, result)
 		return &result
@@ -120,6 +159,9 @@ func (f *FavoritesDeleted) GetByFilterArgs() []any {
 }
 
 func (f *FavoritesDeleted) GetByFilterSQL() string {
+	if f.tombstones {
+		return FavoritesDeletedSelectTombstonesForUserSQL
+	}
 	return FavoritesDeletedSelectForUserSQL
 }
 
@@ -206,10 +248,12 @@ func (f FavoritesDeleted) ToFavorites() *Favorites {
 			createdAt: f.createdAt,
 			updatedAt: f.updatedAt,
 		},
-		id:      f.id,
-		asset:   f.asset,
-		user:    f.user,
-		version: f.version,
+		id:         f.id,
+		asset:      f.asset,
+		user:       f.user,
+		version:    f.version,
+		metadata:   f.metadata,
+		provenance: f.provenance,
 	}
 }
 
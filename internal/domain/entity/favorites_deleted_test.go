@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-15 17:10 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-17 18:20 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites_deleted_test.go
@@ -63,6 +63,9 @@ func testFavoritesDeletedRepoOk(t *testing.T) {
 	got, err := GetFavoritesDeletedForUser(context.TODO(), &stubRepoOk[*FavoritesDeleted]{}, "")
 	assert.Nil(t, err)
 	assert.Equal(t, []FavoritesDeleted{favorites}, got)
+	got, err = GetFavoritesTombstonesForUser(context.TODO(), &stubRepoOk[*FavoritesDeleted]{}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []FavoritesDeleted{favorites}, got)
 	err = favorites.Update(context.TODO(), &stubRepoOk[*FavoritesDeleted]{})
 	assert.Nil(t, err)
 }
@@ -73,6 +76,8 @@ func testFavoritesDeletedRepoErr(t *testing.T) {
 	assert.NotNil(t, err)
 	_, err = GetFavoritesDeletedForUser(context.TODO(), &stubRepoErr[*FavoritesDeleted]{}, "")
 	assert.NotNil(t, err)
+	_, err = GetFavoritesTombstonesForUser(context.TODO(), &stubRepoErr[*FavoritesDeleted]{}, "")
+	assert.NotNil(t, err)
 	err = favorites.Update(context.TODO(), &stubRepoErr[*FavoritesDeleted]{})
 	assert.NotNil(t, err)
 }
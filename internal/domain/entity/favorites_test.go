@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-15 17:10 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites_test.go
@@ -14,6 +14,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -32,6 +33,9 @@ func TestFavorites(t *testing.T) {
 		{name: "positive test #2 Favorites IsFavoritesNotFound", fRun: testIsFavoritesNotFound},
 		{name: "positive test #3 Favorites stubRepoOk", fRun: testFavoritesRepoOk},
 		{name: "negative test #4 Favorites stubRepoErr", fRun: testFavoritesRepoErr},
+		{name: "positive test #5 Favorites WithMetadata", fRun: testFavoritesWithMetadata},
+		{name: "positive test #6 Favorites WithProvenance", fRun: testFavoritesWithProvenance},
+		{name: "positive test #7 Favorites UpsertTxArgs expected version", fRun: testFavoritesUpsertTxArgs},
 	}
 
 	assert.NotNil(t, t)
@@ -99,6 +103,8 @@ func testFavoritesRepoOk(t *testing.T) {
 	err = favorites.Delete(context.TODO(), &stubTxRepoOk[*Favorites]{}, func() {})
 	assert.Nil(t, err)
 	assert.False(t, favorites.Deleted().Valid)
+	err = favorites.Tombstone(context.TODO(), &stubRepoOk[*Favorites]{})
+	assert.Nil(t, err)
 }
 
 func testFavoritesRepoErr(t *testing.T) {
@@ -109,6 +115,49 @@ func testFavoritesRepoErr(t *testing.T) {
 	assert.NotNil(t, err)
 	err = favorites.Delete(context.TODO(), &stubTxRepoErr[*Favorites]{}, func() {})
 	assert.NotNil(t, err)
+	err = favorites.Tombstone(context.TODO(), &stubRepoErr[*Favorites]{})
+	assert.NotNil(t, err)
+}
+
+func testFavoritesWithMetadata(t *testing.T) {
+	favorites := MakeFavorites(uuid.New(), Asset{}, User{}, sql.NullInt64{}, DefaultTAttributes())
+	expected := favorites.WithMetadata("https://www.moex.com")
+	assert.Equal(t, "", favorites.Metadata())
+	assert.Equal(t, "https://www.moex.com", expected.Metadata())
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	got := Favorites{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func testFavoritesWithProvenance(t *testing.T) {
+	favorites := MakeFavorites(uuid.New(), Asset{}, User{}, sql.NullInt64{}, DefaultTAttributes())
+	writtenAt := time.Date(2024, 8, 18, 3, 0, 0, 0, time.UTC)
+	expected := favorites.WithProvenance(MakeProvenance("shard-1", writtenAt))
+	assert.Equal(t, Provenance{}, favorites.Provenance())
+	assert.Equal(t, "shard-1", expected.Provenance().ShardID())
+	assert.Equal(t, sql.NullTime{Time: writtenAt, Valid: true}, expected.Provenance().WrittenAt())
+	assert.False(t, MakeProvenance("", time.Time{}).WrittenAt().Valid)
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	got := Favorites{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func testFavoritesUpsertTxArgs(t *testing.T) {
+	favorites := MakeFavorites(uuid.New(), Asset{}, User{}, sql.NullInt64{}, DefaultTAttributes())
+	args := favorites.UpsertTxArgs()
+	assert.Equal(t, FavoritesUpsertTxFavoritesSQL, args.SQLs[len(args.SQLs)-1])
+	assert.Equal(t, int64(0), args.Args[len(args.Args)-1][8])
+	favorites = MakeFavorites(uuid.New(), Asset{}, User{}, sql.NullInt64{Int64: 7, Valid: true}, DefaultTAttributes())
+	args = favorites.UpsertTxArgs()
+	assert.Equal(t, int64(7), args.Args[len(args.Args)-1][8])
+	_, doUpdate, _ := strings.Cut(FavoritesUpsertTxFavoritesSQL, "DO UPDATE SET")
+	assert.Contains(t, doUpdate, "deleted = NULL", "повторное добавление снимает надгробие")
 }
 
 //!-
@@ -0,0 +1,441 @@
+/*
+ * This file was last modified at 2024-08-12 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * note.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	NoteSelectSQL = `SELECT
+	n.id, n.name, n.body, n.version, n.deleted, n.created_at, n.updated_at,
+    u.upk, u.version, u.deleted, u.created_at, u.updated_at
+    FROM notes n
+    JOIN users u ON n.user_upk = u.upk
+    WHERE n.name = $1 AND n.user_upk = $2`
+
+	NoteSelectForUserSQL = `SELECT
+	n.id, n.name, n.body, n.version, n.deleted, n.created_at, n.updated_at,
+    u.upk, u.version, u.deleted, u.created_at, u.updated_at
+    FROM notes n
+    JOIN users u ON n.user_upk = u.upk
+    WHERE n.user_upk = $1
+	AND n.deleted IS NOT TRUE
+	AND u.deleted IS NOT TRUE`
+
+	NoteDeleteSQL = `UPDATE notes
+	SET deleted = true, version = (SELECT u.version FROM users u WHERE u.upk = $3)
+	WHERE name = $1 AND user_upk = $2
+	RETURNING id, name, user_upk, version, deleted, created_at, updated_at`
+
+	NoteInsertSQL = `INSERT INTO notes
+    (name, user_upk, body, version, created_at)
+    VALUES ($1, $2, $3, (SELECT u.version FROM users u WHERE u.upk = $4), $5)
+    RETURNING id, version, created_at`
+
+	NoteUpdateSQL = `UPDATE notes
+    SET updated_at = $3, version = (SELECT u.version FROM users u WHERE u.upk = $4)
+    WHERE name = $1 AND user_upk = $2
+    RETURNING version, updated_at`
+
+	NoteDeleteTxUserSQL = `UPDATE users
+	SET version = version + 1
+	WHERE upk = $2 AND EXISTS (
+		SELECT 1 FROM notes n WHERE n.name = $1 AND n.user_upk = $2 AND n.deleted IS NOT TRUE
+	)`
+
+	NoteDeleteTxSQL = `UPDATE notes
+	SET version = NULL, deleted = true
+	WHERE name = $1 AND user_upk = $2 AND deleted IS NOT TRUE
+	RETURNING id, name, user_upk, version, deleted, created_at, updated_at,
+	(SELECT u.version FROM users u WHERE u.upk = $2)`
+
+	NoteUpsertTxUserSQL = FavoritesUpsertTxUserSQL
+
+	NoteUpsertTxNoteSQL = `INSERT INTO notes
+    (name, user_upk, body, created_at)
+    VALUES ($1, $2, $3, $4)
+	ON CONFLICT (name, user_upk)
+	DO UPDATE SET body = $3, version = NULL, deleted = NULL, updated_at = $5
+    RETURNING id, name, user_upk, version, deleted, created_at, updated_at,
+	(SELECT version FROM users WHERE upk = $2),
+	(SELECT created_at FROM users WHERE upk = $2)`
+)
+
+// Note произвольные текстовые данные пользователя, тело хранится в зашифрованном виде.
+type Note struct {
+	TAttributes
+	id      uuid.UUID
+	name    string
+	user    User
+	body    string
+	version sql.NullInt64
+}
+
+type note struct {
+	ID        uuid.UUID
+	Name      string
+	User      user
+	Body      string
+	Version   int64
+	Deleted   JsonNullBool `json:",omitempty"`
+	CreatedAt time.Time
+	UpdatedAt JsonNullTime `json:",omitempty"`
+}
+
+var _ domain.Suite = (*Note)(nil)
+
+func GetNote(ctx context.Context, repo domain.Repo[*Note], name, upk string) (Note, error) {
+
+	var err error
+	result := &Note{name: name, user: User{upk: upk}}
+
+	_, er0 := repo.Get(ctx, result, func(scanner domain.Scanner) {
+		err = scanner.Scan(
+			&result.id,
+			&result.name,
+			&result.body,
+			&result.version,
+			&result.deleted,
+			&result.createdAt,
+			&result.updatedAt,
+
+			&result.user.upk,
+			&result.user.version,
+			&result.user.deleted,
+			&result.user.createdAt,
+			&result.user.updatedAt,
+		)
+	})
+	if er0 != nil {
+		return Note{}, er0
+	}
+	if err != nil {
+		return Note{}, err
+	}
+	return *result, nil
+}
+
+func GetNotesForUser(ctx context.Context, repo domain.Repo[*Note], upk string) ([]Note, error) {
+
+	var err error
+	results := make([]Note, 0)
+	_, er0 := repo.GetByFilter(ctx, &Note{user: User{upk: upk}}, func(scanner domain.Scanner) *Note {
+		result := Note{}
+		err = scanner.Scan(
+			&result.id,
+			&result.name,
+			&result.body,
+			&result.version,
+			&result.deleted,
+			&result.createdAt,
+			&result.updatedAt,
+
+			&result.user.upk,
+			&result.user.version,
+			&result.user.deleted,
+			&result.user.createdAt,
+			&result.user.updatedAt,
+		)
+		results = append(results, result)
+		return &result
+	})
+	if er0 != nil {
+		return results, er0
+	}
+	return results, err
+}
+
+func MakeNote(id uuid.UUID, name string, user User, body string, version sql.NullInt64, a TAttributes) Note {
+	return Note{
+		TAttributes: struct {
+			deleted   sql.NullBool
+			createdAt time.Time
+			updatedAt sql.NullTime
+		}{
+			deleted:   a.deleted,
+			createdAt: a.createdAt,
+			updatedAt: a.updatedAt,
+		},
+		id:      id,
+		name:    name,
+		user:    user,
+		body:    body,
+		version: version,
+	}
+}
+
+func IsNoteNotFound(n Note, err error) bool {
+	return tool.NoRowsInResultSet(err) || n == Note{}
+}
+
+func (n Note) ID() uuid.UUID {
+	return n.id
+}
+
+func (n Note) Name() string {
+	return n.name
+}
+
+func (n Note) User() User {
+	return n.user
+}
+
+func (n Note) Body() string {
+	return n.body
+}
+
+func (n Note) WithBody(body string) Note {
+	t := n
+	t.body = body
+	return t
+}
+
+func (n Note) Version() sql.NullInt64 {
+	return n.version
+}
+
+func (n Note) Deleted() sql.NullBool {
+	return n.deleted
+}
+
+func (n Note) CreatedAt() time.Time {
+	return n.createdAt
+}
+
+func (n Note) UpdatedAt() sql.NullTime {
+	return n.updatedAt
+}
+
+func (n *Note) Copy() domain.Entity {
+	c := *n
+	return &c
+}
+
+func (n *Note) Delete(ctx context.Context, dtf domain.Dft[*Note], inTransaction func()) (err error) {
+
+	var nt Note
+
+	er0 := dtf.DoDelete(ctx, n, func(scanner domain.Scanner) {
+		err = scanner.Scan(
+			&nt.id, &nt.name, &nt.user.upk, &nt.version, &nt.deleted, &nt.createdAt, &nt.updatedAt,
+			&nt.user.version,
+		)
+		if err != nil {
+			slog.ErrorContext(ctx, env.MSG+"Note.Delete", "err", err)
+		} else {
+			n.id = nt.id
+			n.version = nt.version
+			n.deleted = nt.deleted
+			n.createdAt = nt.createdAt
+			n.updatedAt = nt.updatedAt
+			n.name = nt.name
+			n.user.upk = nt.user.upk
+			n.user.version = nt.user.version
+			inTransaction()
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (n *Note) DeleteArgs() []any {
+	return []any{n.name, n.user.upk, n.user.upk}
+}
+
+func (n *Note) DeleteSQL() string {
+	return NoteDeleteSQL
+}
+
+func (n *Note) DeleteTxArgs() domain.TxArgs {
+	return domain.TxArgs{
+		SQLs: []string{
+			NoteDeleteTxUserSQL,
+			NoteDeleteTxSQL,
+		},
+		Args: [][]any{
+			{n.name, n.user.upk},
+			{n.name, n.user.upk},
+		},
+	}
+}
+
+func (n *Note) FromJSON(data []byte) (err error) {
+
+	var t note
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	n.id = t.ID
+	n.name = t.Name
+	n.body = t.Body
+	n.deleted = t.Deleted.ToNullBool()
+	n.createdAt = t.CreatedAt
+	n.updatedAt = t.UpdatedAt.ToNullTime()
+
+	n.user.upk = t.User.UPK
+	n.user.version = t.User.Version
+	n.user.deleted = t.User.Deleted.ToNullBool()
+	n.user.createdAt = t.User.CreatedAt
+	n.user.updatedAt = t.User.UpdatedAt.ToNullTime()
+
+	return nil
+}
+
+func (n *Note) GetArgs() []any {
+	return []any{n.name, n.user.upk}
+}
+
+func (n *Note) GetByFilterArgs() []any {
+	return []any{n.user.upk}
+}
+
+func (n *Note) GetByFilterSQL() string {
+	return NoteSelectForUserSQL
+}
+
+func (n *Note) GetSQL() string {
+	return NoteSelectSQL
+}
+
+func (n *Note) InsertArgs() []any {
+	return []any{n.name, n.user.upk, n.body, n.user.upk, n.createdAt}
+}
+
+func (n *Note) InsertSQL() string {
+	return NoteInsertSQL
+}
+
+func (n *Note) Key() string {
+	return fmt.Sprintf(KeyFormat, n.name, n.user.upk)
+}
+
+func (n *Note) String() string {
+	return fmt.Sprintf(
+		"{%v %s {%s %v %v %v} %v %v %v %v}\n",
+		n.id,
+		n.name,
+		n.user.upk,
+		n.user.deleted,
+		n.user.createdAt,
+		n.user.updatedAt,
+		n.version,
+		n.deleted,
+		n.createdAt,
+		n.updatedAt,
+	)
+}
+
+func (n Note) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(note{
+		ID:   n.id,
+		Name: n.name,
+		User: user{
+			UPK:       n.user.upk,
+			Version:   n.user.version,
+			Deleted:   FromNullBool(n.user.deleted),
+			CreatedAt: n.user.createdAt,
+			UpdatedAt: FromNullTime(n.user.updatedAt),
+		},
+		Body:      n.body,
+		Version:   n.version.Int64,
+		Deleted:   FromNullBool(n.deleted),
+		CreatedAt: n.createdAt,
+		UpdatedAt: FromNullTime(n.updatedAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (n *Note) Update(ctx context.Context, repo domain.Repo[*Note]) (err error) {
+
+	_, er0 := repo.Update(ctx, n, func(s domain.Scanner) {
+		t := *n
+		err = s.Scan(&n.version, &n.updatedAt)
+		if err == nil {
+			*n = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (n *Note) UpdateArgs() []any {
+	return []any{n.name, n.user.upk, n.updatedAt, n.user.upk}
+}
+
+func (n *Note) UpdateSQL() string {
+	return NoteUpdateSQL
+}
+
+func (n *Note) Upsert(ctx context.Context, dtf domain.Dft[*Note], inTransaction func()) (err error) {
+
+	var nt Note
+	er0 := dtf.DoUpsert(ctx, n, func(scanner domain.Scanner) {
+		err = scanner.Scan(
+			&nt.id, &nt.name, &nt.user.upk, &nt.version, &nt.deleted, &nt.createdAt, &nt.updatedAt,
+			&nt.user.version, &nt.user.createdAt,
+		)
+		if err != nil {
+			slog.ErrorContext(ctx, env.MSG+"Note.Upsert", "err", err)
+		} else {
+			n.id = nt.id
+			n.version = nt.version
+			n.deleted = nt.deleted
+			n.createdAt = nt.createdAt
+			n.updatedAt = nt.updatedAt
+			n.name = nt.name
+			n.user.upk = nt.user.upk
+			n.user.version = nt.user.version
+			n.user.createdAt = nt.user.createdAt
+			inTransaction()
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (n *Note) UpsertTxArgs() domain.TxArgs {
+	return domain.TxArgs{
+		SQLs: []string{
+			NoteUpsertTxUserSQL,
+			NoteUpsertTxNoteSQL,
+		},
+		Args: [][]any{
+			{n.user.upk, n.user.createdAt},
+			{n.name, n.user.upk, n.body, n.createdAt, n.updatedAt},
+		},
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
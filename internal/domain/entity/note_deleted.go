@@ -0,0 +1,184 @@
+/*
+ * This file was last modified at 2024-08-12 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * note_deleted.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+)
+
+const (
+	NoteDeletedDeleteForUserSQL = `UPDATE notes
+	SET deleted = true, version = (SELECT u.version FROM users u WHERE u.upk = $2)
+	WHERE user_upk = $1`
+
+	NoteDeletedSelectForUserSQL = `SELECT
+	n.id, n.name, n.body, n.version, n.deleted, n.created_at, n.updated_at,
+    u.upk, u.version, u.deleted, u.created_at, u.updated_at
+    FROM notes n
+    JOIN users u ON n.user_upk = u.upk
+    WHERE n.user_upk = $1 AND n.version IS NULL
+	AND n.deleted IS TRUE`
+)
+
+type NoteDeleted struct {
+	TAttributes
+	id      uuid.UUID
+	name    string
+	user    User
+	body    string
+	version sql.NullInt64
+}
+
+var _ domain.Entity = (*NoteDeleted)(nil)
+
+func GetNotesDeletedForUser(ctx context.Context, repo domain.Repo[*NoteDeleted], upk string) ([]NoteDeleted, error) {
+
+	var err error
+	results := make([]NoteDeleted, 0)
+	_, er0 := repo.GetByFilter(ctx, &NoteDeleted{user: User{upk: upk}}, func(scanner domain.Scanner) *NoteDeleted {
+		result := NoteDeleted{}
+		err = scanner.Scan(
+			&result.id,
+			&result.name,
+			&result.body,
+			&result.version,
+			&result.deleted,
+			&result.createdAt,
+			&result.updatedAt,
+
+			&result.user.upk,
+			&result.user.version,
+			&result.user.deleted,
+			&result.user.createdAt,
+			&result.user.updatedAt,
+		)
+		results = append(results, result)
+		return &result
+	})
+	if er0 != nil {
+		return results, er0
+	}
+	return results, err
+}
+
+func MakeNoteDeletedUser(upk string) NoteDeleted {
+	return NoteDeleted{
+		user: User{upk: upk},
+	}
+}
+
+func (n *NoteDeleted) Copy() domain.Entity {
+	c := *n
+	return &c
+}
+
+func (n *NoteDeleted) Delete(ctx context.Context, repo domain.Repo[*NoteDeleted]) (err error) {
+	_, err = repo.Delete(ctx, n, func(s domain.Scanner) {})
+	return err
+}
+
+func (n *NoteDeleted) DeleteArgs() []any {
+	return []any{n.user.upk, n.user.upk}
+}
+
+func (n *NoteDeleted) DeleteSQL() string {
+	return NoteDeletedDeleteForUserSQL
+}
+
+func (n *NoteDeleted) GetArgs() []any {
+	return n.ToNote().GetArgs()
+}
+
+func (n *NoteDeleted) GetByFilterArgs() []any {
+	return []any{n.user.upk}
+}
+
+func (n *NoteDeleted) GetByFilterSQL() string {
+	return NoteDeletedSelectForUserSQL
+}
+
+func (n *NoteDeleted) GetSQL() string {
+	return n.ToNote().GetSQL()
+}
+
+func (n *NoteDeleted) InsertArgs() []any {
+	return n.ToNote().InsertArgs()
+}
+
+func (n *NoteDeleted) InsertSQL() string {
+	return n.ToNote().InsertSQL()
+}
+
+func (n *NoteDeleted) FromJSON(data []byte) (err error) {
+	return n.ToNote().FromJSON(data)
+}
+
+func (n *NoteDeleted) Key() string {
+	return n.ToNote().Key()
+}
+
+func (n *NoteDeleted) ToJSON() ([]byte, error) {
+	return n.ToNote().ToJSON()
+}
+
+func (n *NoteDeleted) Update(ctx context.Context, repo domain.Repo[*NoteDeleted]) (err error) {
+
+	_, er0 := repo.Update(ctx, n, func(s domain.Scanner) {
+		t := *n
+		err = s.Scan(&n.version, &n.updatedAt)
+		if err == nil {
+			*n = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (n *NoteDeleted) UpdateArgs() []any {
+	return n.ToNote().UpdateArgs()
+}
+
+func (n *NoteDeleted) UpdateSQL() string {
+	return n.ToNote().UpdateSQL()
+}
+
+func (n *NoteDeleted) String() string {
+	return n.ToNote().String()
+}
+
+func (n NoteDeleted) ToNote() *Note {
+	return &Note{
+		TAttributes: struct {
+			deleted   sql.NullBool
+			createdAt time.Time
+			updatedAt sql.NullTime
+		}{
+			deleted:   n.deleted,
+			createdAt: n.createdAt,
+			updatedAt: n.updatedAt,
+		},
+		id:      n.id,
+		name:    n.name,
+		user:    n.user,
+		body:    n.body,
+		version: n.version,
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,143 @@
+/*
+ * This file was last modified at 2024-08-12 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * note_test.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNote(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 Note Cloneable", fRun: testNoteCloneable},
+		{name: "positive test #1 Note FromJSON and ToJSON", fRun: testNoteJSON},
+		{name: "positive test #2 Note IsNoteNotFound", fRun: testIsNoteNotFound},
+		{name: "positive test #3 Note stubRepoOk", fRun: testNoteRepoOk},
+		{name: "negative test #4 Note stubRepoErr", fRun: testNoteRepoErr},
+		{name: "positive test #5 NoteDeleted stubRepoOk", fRun: testNoteDeletedRepoOk},
+		{name: "negative test #6 NoteDeleted stubRepoErr", fRun: testNoteDeletedRepoErr},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testNoteCloneable(t *testing.T) {
+	expected := MakeNote(uuid.New(), "note", User{}, "body", sql.NullInt64{}, DefaultTAttributes())
+	got := expected.Copy()
+	assert.NotNil(t, got)
+	assert.Equal(t, &expected, got)
+}
+
+func testNoteJSON(t *testing.T) {
+	expected := MakeNote(
+		uuid.New(),
+		"note",
+		User{},
+		"body",
+		sql.NullInt64{},
+		MakeTAttributes(
+			sql.NullBool{Bool: true, Valid: true},
+			time.Time{},
+			sql.NullTime{},
+		))
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	assert.NotNil(t, j)
+	got := Note{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, expected.String(), got.String())
+	assert.Equal(t, "                            note", got.Key())
+}
+
+func testIsNoteNotFound(t *testing.T) {
+	assert.True(t, IsNoteNotFound(Note{id: uuid.New()}, pgx.ErrNoRows))
+	assert.True(t, IsNoteNotFound(Note{}, errors.New("")))
+	assert.False(t, IsNoteNotFound(Note{id: uuid.New()}, errors.New("")))
+}
+
+func testNoteRepoOk(t *testing.T) {
+	note := MakeNote(uuid.New(), "note", User{}, "body", sql.NullInt64{}, DefaultTAttributes())
+	err := note.Upsert(context.TODO(), &stubTxRepoOk[*Note]{}, func() {})
+	assert.Nil(t, err)
+	assert.False(t, note.Deleted().Valid)
+	got, err := GetNote(context.TODO(), &stubRepoOk[*Note]{}, "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, note.CreatedAt(), got.CreatedAt())
+	assert.Equal(t, note.Deleted(), got.Deleted())
+	assert.False(t, note.UpdatedAt().Valid)
+	notes, err := GetNotesForUser(context.TODO(), &stubRepoOk[*Note]{}, "")
+	assert.Nil(t, err)
+	assert.Len(t, notes, 1)
+	err = note.Update(context.TODO(), &stubRepoOk[*Note]{})
+	assert.Nil(t, err)
+	err = note.Delete(context.TODO(), &stubTxRepoOk[*Note]{}, func() {})
+	assert.Nil(t, err)
+	assert.Equal(t, "cipher", note.WithBody("cipher").Body())
+}
+
+func testNoteRepoErr(t *testing.T) {
+	note := MakeNote(uuid.New(), "note", User{}, "body", sql.NullInt64{}, DefaultTAttributes())
+	err := note.Upsert(context.TODO(), &stubTxRepoErr[*Note]{}, func() {})
+	assert.NotNil(t, err)
+	_, err = GetNote(context.TODO(), &stubRepoErr[*Note]{}, "", "")
+	assert.NotNil(t, err)
+	_, err = GetNotesForUser(context.TODO(), &stubRepoErr[*Note]{}, "")
+	assert.NotNil(t, err)
+	err = note.Update(context.TODO(), &stubRepoErr[*Note]{})
+	assert.NotNil(t, err)
+	err = note.Delete(context.TODO(), &stubTxRepoErr[*Note]{}, func() {})
+	assert.NotNil(t, err)
+}
+
+func testNoteDeletedRepoOk(t *testing.T) {
+	note := MakeNoteDeletedUser("")
+	err := note.Delete(context.TODO(), &stubRepoOk[*NoteDeleted]{})
+	assert.Nil(t, err)
+	got, err := GetNotesDeletedForUser(context.TODO(), &stubRepoOk[*NoteDeleted]{}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []NoteDeleted{note}, got)
+	err = note.Update(context.TODO(), &stubRepoOk[*NoteDeleted]{})
+	assert.Nil(t, err)
+	j, err := note.ToJSON()
+	assert.Nil(t, err)
+	assert.Nil(t, note.FromJSON(j))
+	assert.Equal(t, note.ToNote().Key(), note.Key())
+}
+
+func testNoteDeletedRepoErr(t *testing.T) {
+	note := MakeNoteDeletedUser("")
+	err := note.Delete(context.TODO(), &stubRepoErr[*NoteDeleted]{})
+	assert.NotNil(t, err)
+	_, err = GetNotesDeletedForUser(context.TODO(), &stubRepoErr[*NoteDeleted]{}, "")
+	assert.NotNil(t, err)
+	err = note.Update(context.TODO(), &stubRepoErr[*NoteDeleted]{})
+	assert.NotNil(t, err)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,508 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * otp.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	OtpKindHOTP = "HOTP"
+	OtpKindTOTP = "TOTP"
+
+	OtpSelectSQL = `SELECT
+	o.id, o.name, o.kind, o.secret, o.digits, o.period, o.counter, o.deleted, o.created_at, o.updated_at,
+    u.upk, u.version, u.deleted, u.created_at, u.updated_at
+    FROM otp_secrets o
+    JOIN users u ON o.user_upk = u.upk
+    WHERE o.name = $1 AND o.user_upk = $2
+	AND o.deleted IS NOT TRUE`
+
+	OtpSelectForUserSQL = `SELECT
+	o.id, o.name, o.kind, o.secret, o.digits, o.period, o.counter, o.deleted, o.created_at, o.updated_at,
+    u.upk, u.version, u.deleted, u.created_at, u.updated_at
+    FROM otp_secrets o
+    JOIN users u ON o.user_upk = u.upk
+    WHERE o.user_upk = $1
+	AND o.deleted IS NOT TRUE
+	AND u.deleted IS NOT TRUE`
+
+	OtpDeleteSQL = `UPDATE otp_secrets
+	SET deleted = true
+	WHERE name = $1 AND user_upk = $2
+	RETURNING id, deleted, updated_at`
+
+	OtpInsertSQL = `INSERT INTO otp_secrets
+    (name, user_upk, kind, secret, digits, period, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
+    RETURNING id, counter, created_at`
+
+	// OtpUpdateSQL атомарный сдвиг счётчика HOTP, возвращается значение до сдвига.
+	OtpUpdateSQL = `UPDATE otp_secrets
+    SET counter = counter + 1, updated_at = $3
+    WHERE name = $1 AND user_upk = $2 AND kind = 'HOTP' AND deleted IS NOT TRUE
+    RETURNING counter - 1, updated_at`
+
+	OtpDeleteTxCodesSQL = `DELETE FROM otp_codes
+	WHERE name = $1 AND user_upk = $2`
+
+	OtpDeleteTxSQL = `UPDATE otp_secrets
+	SET deleted = true
+	WHERE name = $1 AND user_upk = $2 AND deleted IS NOT TRUE
+	RETURNING id, name, user_upk, deleted, created_at, updated_at`
+
+	OtpUpsertTxUserSQL = `INSERT INTO users
+	(upk, version, created_at)
+	VALUES ($1, 1, $2)
+	ON CONFLICT (upk)
+	DO NOTHING`
+
+	OtpUpsertTxSecretSQL = `INSERT INTO otp_secrets
+    (name, user_upk, kind, secret, digits, period, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (name, user_upk)
+	DO UPDATE SET kind = $3, secret = $4, digits = $5, period = $6, counter = 0, deleted = NULL, updated_at = $8`
+
+	OtpUpsertTxCodesSQL = `INSERT INTO otp_codes
+	(name, user_upk, code_hash)
+	SELECT $1, $2, unnest($3::varchar[])
+	ON CONFLICT (name, user_upk, code_hash)
+	DO NOTHING`
+
+	OtpUpsertTxSQL = `SELECT
+	o.id, o.counter, o.deleted, o.created_at, o.updated_at, u.version, u.created_at
+    FROM otp_secrets o
+    JOIN users u ON o.user_upk = u.upk
+    WHERE o.name = $1 AND o.user_upk = $2`
+)
+
+// Otp секрет одноразовых паролей TOTP/HOTP пользователя, секрет хранится в зашифрованном виде.
+// Коды активации хранятся только в виде хэшей и используются однократно, см. OtpCode.
+type Otp struct {
+	TAttributes
+	id      uuid.UUID
+	name    string
+	user    User
+	kind    string
+	secret  string
+	digits  int32
+	period  int32
+	counter int64
+	codes   []string
+}
+
+type otp struct {
+	ID        uuid.UUID
+	Name      string
+	User      user
+	Kind      string
+	Secret    string
+	Digits    int32
+	Period    int32
+	Counter   int64
+	Deleted   JsonNullBool `json:",omitempty"`
+	CreatedAt time.Time
+	UpdatedAt JsonNullTime `json:",omitempty"`
+}
+
+var _ domain.Suite = (*Otp)(nil)
+
+func GetOtp(ctx context.Context, repo domain.Repo[*Otp], name, upk string) (Otp, error) {
+
+	var err error
+	result := &Otp{name: name, user: User{upk: upk}}
+
+	_, er0 := repo.Get(ctx, result, func(scanner domain.Scanner) {
+		err = scanner.Scan(
+			&result.id,
+			&result.name,
+			&result.kind,
+			&result.secret,
+			&result.digits,
+			&result.period,
+			&result.counter,
+			&result.deleted,
+			&result.createdAt,
+			&result.updatedAt,
+
+			&result.user.upk,
+			&result.user.version,
+			&result.user.deleted,
+			&result.user.createdAt,
+			&result.user.updatedAt,
+		)
+	})
+	if er0 != nil {
+		return Otp{}, er0
+	}
+	if err != nil {
+		return Otp{}, err
+	}
+	return *result, nil
+}
+
+// GetOtpsForUser не удалённые секреты одноразовых паролей пользователя.
+func GetOtpsForUser(ctx context.Context, repo domain.Repo[*Otp], upk string) ([]Otp, error) {
+
+	var err error
+	results := make([]Otp, 0)
+	_, er0 := repo.GetByFilter(ctx, &Otp{user: User{upk: upk}}, func(scanner domain.Scanner) *Otp {
+		result := Otp{}
+		err = scanner.Scan(
+			&result.id,
+			&result.name,
+			&result.kind,
+			&result.secret,
+			&result.digits,
+			&result.period,
+			&result.counter,
+			&result.deleted,
+			&result.createdAt,
+			&result.updatedAt,
+
+			&result.user.upk,
+			&result.user.version,
+			&result.user.deleted,
+			&result.user.createdAt,
+			&result.user.updatedAt,
+		)
+		results = append(results, result)
+		return &result
+	})
+	if er0 != nil {
+		return nil, er0
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func MakeOtp(
+	id uuid.UUID,
+	name string,
+	user User,
+	kind, secret string,
+	digits, period int32,
+	codes []string,
+	a TAttributes,
+) Otp {
+	return Otp{
+		TAttributes: struct {
+			deleted   sql.NullBool
+			createdAt time.Time
+			updatedAt sql.NullTime
+		}{
+			deleted:   a.deleted,
+			createdAt: a.createdAt,
+			updatedAt: a.updatedAt,
+		},
+		id:     id,
+		name:   name,
+		user:   user,
+		kind:   kind,
+		secret: secret,
+		digits: digits,
+		period: period,
+		codes:  codes,
+	}
+}
+
+func IsOtpNotFound(o Otp, err error) bool {
+	return tool.NoRowsInResultSet(err) || o.id == uuid.Nil
+}
+
+func (o Otp) ID() uuid.UUID {
+	return o.id
+}
+
+func (o Otp) Name() string {
+	return o.name
+}
+
+func (o Otp) User() User {
+	return o.user
+}
+
+func (o Otp) Kind() string {
+	return o.kind
+}
+
+func (o Otp) Secret() string {
+	return o.secret
+}
+
+func (o Otp) Digits() int32 {
+	return o.digits
+}
+
+func (o Otp) Period() int32 {
+	return o.period
+}
+
+func (o Otp) Counter() int64 {
+	return o.counter
+}
+
+func (o Otp) Codes() []string {
+	return o.codes
+}
+
+func (o Otp) Deleted() sql.NullBool {
+	return o.deleted
+}
+
+func (o Otp) CreatedAt() time.Time {
+	return o.createdAt
+}
+
+func (o Otp) UpdatedAt() sql.NullTime {
+	return o.updatedAt
+}
+
+func (o *Otp) Copy() domain.Entity {
+	c := *o
+	c.codes = append([]string(nil), o.codes...)
+	return &c
+}
+
+func (o *Otp) Delete(ctx context.Context, dtf domain.Dft[*Otp]) (err error) {
+
+	var t Otp
+
+	er0 := dtf.DoDelete(ctx, o, func(scanner domain.Scanner) {
+		err = scanner.Scan(&t.id, &t.name, &t.user.upk, &t.deleted, &t.createdAt, &t.updatedAt)
+		if err != nil {
+			slog.ErrorContext(ctx, env.MSG+"Otp.Delete", "err", err)
+		} else {
+			o.id = t.id
+			o.deleted = t.deleted
+			o.createdAt = t.createdAt
+			o.updatedAt = t.updatedAt
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (o *Otp) DeleteArgs() []any {
+	return []any{o.name, o.user.upk}
+}
+
+func (o *Otp) DeleteSQL() string {
+	return OtpDeleteSQL
+}
+
+func (o *Otp) DeleteTxArgs() domain.TxArgs {
+	return domain.TxArgs{
+		SQLs: []string{
+			OtpDeleteTxCodesSQL,
+			OtpDeleteTxSQL,
+		},
+		Args: [][]any{
+			{o.name, o.user.upk},
+			{o.name, o.user.upk},
+		},
+	}
+}
+
+func (o *Otp) FromJSON(data []byte) (err error) {
+
+	var t otp
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	o.id = t.ID
+	o.name = t.Name
+	o.kind = t.Kind
+	o.secret = t.Secret
+	o.digits = t.Digits
+	o.period = t.Period
+	o.counter = t.Counter
+	o.deleted = t.Deleted.ToNullBool()
+	o.createdAt = t.CreatedAt
+	o.updatedAt = t.UpdatedAt.ToNullTime()
+
+	o.user.upk = t.User.UPK
+	o.user.version = t.User.Version
+	o.user.deleted = t.User.Deleted.ToNullBool()
+	o.user.createdAt = t.User.CreatedAt
+	o.user.updatedAt = t.User.UpdatedAt.ToNullTime()
+
+	return nil
+}
+
+func (o *Otp) GetArgs() []any {
+	return []any{o.name, o.user.upk}
+}
+
+func (o *Otp) GetByFilterArgs() []any {
+	return []any{o.user.upk}
+}
+
+func (o *Otp) GetByFilterSQL() string {
+	return OtpSelectForUserSQL
+}
+
+func (o *Otp) GetSQL() string {
+	return OtpSelectSQL
+}
+
+func (o *Otp) InsertArgs() []any {
+	return []any{o.name, o.user.upk, o.kind, o.secret, o.digits, o.period, o.createdAt}
+}
+
+func (o *Otp) InsertSQL() string {
+	return OtpInsertSQL
+}
+
+func (o *Otp) Key() string {
+	return fmt.Sprintf(KeyFormat, o.name, o.user.upk)
+}
+
+func (o *Otp) String() string {
+	return fmt.Sprintf(
+		"{%v %s {%s %v %v %v} %s %d %d %d %v %v %v}\n",
+		o.id,
+		o.name,
+		o.user.upk,
+		o.user.deleted,
+		o.user.createdAt,
+		o.user.updatedAt,
+		o.kind,
+		o.digits,
+		o.period,
+		o.counter,
+		o.deleted,
+		o.createdAt,
+		o.updatedAt,
+	)
+}
+
+func (o Otp) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(otp{
+		ID:   o.id,
+		Name: o.name,
+		User: user{
+			UPK:       o.user.upk,
+			Version:   o.user.version,
+			Deleted:   FromNullBool(o.user.deleted),
+			CreatedAt: o.user.createdAt,
+			UpdatedAt: FromNullTime(o.user.updatedAt),
+		},
+		Kind:      o.kind,
+		Secret:    o.secret,
+		Digits:    o.digits,
+		Period:    o.period,
+		Counter:   o.counter,
+		Deleted:   FromNullBool(o.deleted),
+		CreatedAt: o.createdAt,
+		UpdatedAt: FromNullTime(o.updatedAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Update атомарный сдвиг счётчика HOTP, в counter возвращается значение для текущего кода.
+func (o *Otp) Update(ctx context.Context, repo domain.Repo[*Otp]) (err error) {
+
+	_, er0 := repo.Update(ctx, o, func(s domain.Scanner) {
+		t := *o
+		err = s.Scan(&t.counter, &t.updatedAt)
+		if err == nil {
+			*o = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (o *Otp) UpdateArgs() []any {
+	return []any{o.name, o.user.upk, time.Now().UTC()}
+}
+
+func (o *Otp) UpdateSQL() string {
+	return OtpUpdateSQL
+}
+
+func (o *Otp) Upsert(ctx context.Context, dtf domain.Dft[*Otp]) (err error) {
+
+	var t Otp
+
+	er0 := dtf.DoUpsert(ctx, o, func(scanner domain.Scanner) {
+		err = scanner.Scan(
+			&t.id, &t.counter, &t.deleted, &t.createdAt, &t.updatedAt, &t.user.version, &t.user.createdAt,
+		)
+		if err != nil {
+			slog.ErrorContext(ctx, env.MSG+"Otp.Upsert", "err", err)
+		} else {
+			o.id = t.id
+			o.counter = t.counter
+			o.deleted = t.deleted
+			o.createdAt = t.createdAt
+			o.updatedAt = t.updatedAt
+			o.user.version = t.user.version
+			o.user.createdAt = t.user.createdAt
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+// UpsertTxArgs сохранение секрета и замена списка кодов активации в одной транзакции.
+func (o *Otp) UpsertTxArgs() domain.TxArgs {
+
+	codes := o.codes
+
+	if codes == nil {
+		codes = []string{}
+	}
+	return domain.TxArgs{
+		SQLs: []string{
+			OtpUpsertTxUserSQL,
+			OtpUpsertTxSecretSQL,
+			OtpDeleteTxCodesSQL,
+			OtpUpsertTxCodesSQL,
+			OtpUpsertTxSQL,
+		},
+		Args: [][]any{
+			{o.user.upk, o.user.createdAt},
+			{o.name, o.user.upk, o.kind, o.secret, o.digits, o.period, o.createdAt, o.updatedAt},
+			{o.name, o.user.upk},
+			{o.name, o.user.upk, codes},
+			{o.name, o.user.upk},
+		},
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
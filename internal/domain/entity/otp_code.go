@@ -0,0 +1,231 @@
+/*
+ * This file was last modified at 2024-08-13 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * otp_code.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	OtpCodeSelectSQL = `SELECT id, used_at, created_at
+	FROM otp_codes
+	WHERE name = $1 AND user_upk = $2 AND code_hash = $3`
+
+	OtpCodeSelectForSecretSQL = `SELECT id, code_hash, used_at, created_at
+	FROM otp_codes
+	WHERE name = $1 AND user_upk = $2 AND used_at IS NULL`
+
+	OtpCodeDeleteSQL = `DELETE FROM otp_codes
+	WHERE name = $1 AND user_upk = $2 AND code_hash = $3
+	RETURNING id, used_at, created_at`
+
+	OtpCodeInsertSQL = `INSERT INTO otp_codes
+	(name, user_upk, code_hash)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at`
+
+	OtpCodeUpdateSQL = OtpCodeConsumeTxSQL
+
+	// OtpCodeConsumeTxSQL погашение кода одним оператором: строка блокируется на время
+	// транзакции, и конкурирующий запрос после её завершения не находит used_at IS NULL.
+	OtpCodeConsumeTxSQL = `UPDATE otp_codes c
+	SET used_at = $4
+	WHERE c.name = $1 AND c.user_upk = $2 AND c.code_hash = $3 AND c.used_at IS NULL
+	AND EXISTS (
+		SELECT 1 FROM otp_secrets s WHERE s.name = $1 AND s.user_upk = $2 AND s.deleted IS NOT TRUE
+	)
+	RETURNING c.id, c.used_at, c.created_at`
+)
+
+// OtpCode одноразовый код активации секрета Otp, в базе данных хранится SHA-256 кода.
+type OtpCode struct {
+	id        uuid.UUID
+	name      string
+	upk       string
+	codeHash  string
+	usedAt    sql.NullTime
+	createdAt time.Time
+}
+
+type otpCode struct {
+	ID        uuid.UUID
+	Name      string
+	UPK       string
+	CodeHash  string
+	UsedAt    JsonNullTime `json:",omitempty"`
+	CreatedAt time.Time
+}
+
+var _ domain.Suite = (*OtpCode)(nil)
+
+// MakeOtpCode создание кода активации по открытому значению кода.
+func MakeOtpCode(name, upk, code string) OtpCode {
+	return OtpCode{name: name, upk: upk, codeHash: tool.OtpCodeHash(code)}
+}
+
+func IsOtpCodeNotFound(c OtpCode, err error) bool {
+	return tool.NoRowsInResultSet(err) || c.id == uuid.Nil
+}
+
+func (c OtpCode) ID() uuid.UUID {
+	return c.id
+}
+
+func (c OtpCode) Name() string {
+	return c.name
+}
+
+func (c OtpCode) Upk() string {
+	return c.upk
+}
+
+func (c OtpCode) CodeHash() string {
+	return c.codeHash
+}
+
+func (c OtpCode) UsedAt() sql.NullTime {
+	return c.usedAt
+}
+
+func (c OtpCode) CreatedAt() time.Time {
+	return c.createdAt
+}
+
+// Consume атомарное погашение кода в транзакции, повторное погашение возвращает ошибку отсутствия строк.
+func (c *OtpCode) Consume(ctx context.Context, dtf domain.Dft[*OtpCode]) (err error) {
+
+	var t OtpCode
+
+	er0 := dtf.DoUpsert(ctx, c, func(scanner domain.Scanner) {
+		err = scanner.Scan(&t.id, &t.usedAt, &t.createdAt)
+		if err == nil {
+			c.id = t.id
+			c.usedAt = t.usedAt
+			c.createdAt = t.createdAt
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (c *OtpCode) Copy() domain.Entity {
+	t := *c
+	return &t
+}
+
+func (c *OtpCode) DeleteArgs() []any {
+	return []any{c.name, c.upk, c.codeHash}
+}
+
+func (c *OtpCode) DeleteSQL() string {
+	return OtpCodeDeleteSQL
+}
+
+func (c *OtpCode) DeleteTxArgs() domain.TxArgs {
+	return domain.TxArgs{
+		SQLs: []string{OtpCodeDeleteSQL},
+		Args: [][]any{{c.name, c.upk, c.codeHash}},
+	}
+}
+
+func (c *OtpCode) FromJSON(data []byte) (err error) {
+
+	var t otpCode
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	c.id = t.ID
+	c.name = t.Name
+	c.upk = t.UPK
+	c.codeHash = t.CodeHash
+	c.usedAt = t.UsedAt.ToNullTime()
+	c.createdAt = t.CreatedAt
+
+	return nil
+}
+
+func (c *OtpCode) GetArgs() []any {
+	return []any{c.name, c.upk, c.codeHash}
+}
+
+func (c *OtpCode) GetByFilterArgs() []any {
+	return []any{c.name, c.upk}
+}
+
+func (c *OtpCode) GetByFilterSQL() string {
+	return OtpCodeSelectForSecretSQL
+}
+
+func (c *OtpCode) GetSQL() string {
+	return OtpCodeSelectSQL
+}
+
+func (c *OtpCode) InsertArgs() []any {
+	return []any{c.name, c.upk, c.codeHash}
+}
+
+func (c *OtpCode) InsertSQL() string {
+	return OtpCodeInsertSQL
+}
+
+func (c *OtpCode) Key() string {
+	return fmt.Sprintf(KeyFormat, c.codeHash, c.name+c.upk)
+}
+
+func (c OtpCode) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(otpCode{
+		ID:        c.id,
+		Name:      c.name,
+		UPK:       c.upk,
+		CodeHash:  c.codeHash,
+		UsedAt:    FromNullTime(c.usedAt),
+		CreatedAt: c.createdAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *OtpCode) UpdateArgs() []any {
+	return c.consumeArgs()
+}
+
+func (c *OtpCode) UpdateSQL() string {
+	return OtpCodeUpdateSQL
+}
+
+func (c *OtpCode) UpsertTxArgs() domain.TxArgs {
+	return domain.TxArgs{
+		SQLs: []string{OtpCodeConsumeTxSQL},
+		Args: [][]any{c.consumeArgs()},
+	}
+}
+
+func (c *OtpCode) consumeArgs() []any {
+	return []any{c.name, c.upk, c.codeHash, time.Now().UTC()}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
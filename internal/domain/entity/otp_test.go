@@ -0,0 +1,141 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * otp_test.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOtp(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 Otp Cloneable", fRun: testOtpCloneable},
+		{name: "positive test #1 Otp FromJSON and ToJSON", fRun: testOtpJSON},
+		{name: "positive test #2 Otp IsOtpNotFound", fRun: testIsOtpNotFound},
+		{name: "positive test #3 Otp stubRepoOk", fRun: testOtpRepoOk},
+		{name: "negative test #4 Otp stubRepoErr", fRun: testOtpRepoErr},
+		{name: "positive test #5 OtpCode stubRepoOk", fRun: testOtpCodeRepoOk},
+		{name: "negative test #6 OtpCode stubRepoErr", fRun: testOtpCodeRepoErr},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testOtpCloneable(t *testing.T) {
+	expected := MakeOtp(uuid.New(), "otp", User{}, OtpKindTOTP, "secret", 6, 30, []string{"hash"}, DefaultTAttributes())
+	got := expected.Copy()
+	assert.NotNil(t, got)
+	assert.Equal(t, &expected, got)
+}
+
+func testOtpJSON(t *testing.T) {
+	expected := MakeOtp(
+		uuid.New(),
+		"otp",
+		User{},
+		OtpKindHOTP,
+		"secret",
+		8,
+		30,
+		nil,
+		MakeTAttributes(
+			sql.NullBool{Bool: true, Valid: true},
+			time.Time{},
+			sql.NullTime{},
+		))
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	assert.NotNil(t, j)
+	got := Otp{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, expected.String(), got.String())
+	assert.Equal(t, "                             otp", got.Key())
+}
+
+func testIsOtpNotFound(t *testing.T) {
+	assert.True(t, IsOtpNotFound(Otp{id: uuid.New()}, pgx.ErrNoRows))
+	assert.True(t, IsOtpNotFound(Otp{}, errors.New("")))
+	assert.False(t, IsOtpNotFound(Otp{id: uuid.New()}, errors.New("")))
+	assert.True(t, IsOtpCodeNotFound(OtpCode{id: uuid.New()}, pgx.ErrNoRows))
+	assert.True(t, IsOtpCodeNotFound(OtpCode{}, nil))
+}
+
+func testOtpRepoOk(t *testing.T) {
+	o := MakeOtp(uuid.New(), "otp", User{}, OtpKindHOTP, "secret", 6, 30, nil, DefaultTAttributes())
+	err := o.Upsert(context.TODO(), &stubTxRepoOk[*Otp]{})
+	assert.Nil(t, err)
+	assert.Equal(t, []any{o.name, o.user.upk, []string{}}, o.UpsertTxArgs().Args[3])
+	got, err := GetOtp(context.TODO(), &stubRepoOk[*Otp]{}, "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, o.CreatedAt(), got.CreatedAt())
+	list, err := GetOtpsForUser(context.TODO(), &stubRepoOk[*Otp]{}, "upk")
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	err = o.Update(context.TODO(), &stubRepoOk[*Otp]{})
+	assert.Nil(t, err)
+	err = o.Delete(context.TODO(), &stubTxRepoOk[*Otp]{})
+	assert.Nil(t, err)
+}
+
+func testOtpRepoErr(t *testing.T) {
+	o := MakeOtp(uuid.New(), "otp", User{}, OtpKindTOTP, "secret", 6, 30, nil, DefaultTAttributes())
+	err := o.Upsert(context.TODO(), &stubTxRepoErr[*Otp]{})
+	assert.NotNil(t, err)
+	_, err = GetOtp(context.TODO(), &stubRepoErr[*Otp]{}, "", "")
+	assert.NotNil(t, err)
+	_, err = GetOtpsForUser(context.TODO(), &stubRepoErr[*Otp]{}, "upk")
+	assert.NotNil(t, err)
+	err = o.Update(context.TODO(), &stubRepoErr[*Otp]{})
+	assert.NotNil(t, err)
+	err = o.Delete(context.TODO(), &stubTxRepoErr[*Otp]{})
+	assert.NotNil(t, err)
+}
+
+func testOtpCodeRepoOk(t *testing.T) {
+	code := MakeOtpCode("otp", "upk", "12345678")
+	assert.Len(t, code.CodeHash(), 64)
+	err := code.Consume(context.TODO(), &stubTxRepoOk[*OtpCode]{})
+	assert.Nil(t, err)
+	j, err := code.ToJSON()
+	assert.Nil(t, err)
+	got := OtpCode{}
+	assert.Nil(t, got.FromJSON(j))
+	assert.Equal(t, code, got)
+	assert.Equal(t, code.Key(), got.Key())
+	assert.Equal(t, &code, code.Copy())
+}
+
+func testOtpCodeRepoErr(t *testing.T) {
+	code := MakeOtpCode("otp", "upk", "12345678")
+	err := code.Consume(context.TODO(), &stubTxRepoErr[*OtpCode]{})
+	assert.NotNil(t, err)
+	assert.False(t, code.UsedAt().Valid)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
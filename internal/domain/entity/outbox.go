@@ -0,0 +1,369 @@
+/*
+ * This file was last modified at 2024-08-17 14:40 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * outbox.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+)
+
+const (
+	OutboxAggregateFavorites = "favorites"
+	OutboxOpDelete           = "delete"
+	OutboxOpUpsert           = "upsert"
+	OutboxStatusDead         = "dead"
+	OutboxStatusPending      = "pending"
+)
+
+const (
+	// OutboxFavoritesUpsertTxSQL событие сохранения избранного, пишется в транзакции Favorites.Upsert.
+	OutboxFavoritesUpsertTxSQL = `INSERT INTO outbox
+	(aggregate, op, isin, user_upk)
+	VALUES ('favorites', 'upsert', $1, $2)`
+
+	// OutboxFavoritesDeleteTxSQL событие удаления избранного, пишется в транзакции Favorites.Delete
+	// только если удаляемая запись существует.
+	OutboxFavoritesDeleteTxSQL = `INSERT INTO outbox
+	(aggregate, op, isin, user_upk)
+	SELECT 'favorites', 'delete', f.isin, f.user_upk
+	FROM favorites f
+	WHERE f.isin = $1 AND f.user_upk = $2 AND f.deleted IS NOT TRUE`
+
+	OutboxSelectBacklogSQL = `SELECT
+	count(*) FILTER (WHERE status = 'pending'),
+	count(*) FILTER (WHERE status = 'dead'),
+	min(created_at) FILTER (WHERE status = 'pending')
+	FROM outbox`
+
+	// OutboxClaimSQL захват пачки событий на время аренды: событие не выдаётся
+	// другому диспетчеру до next_attempt_at, SKIP LOCKED не ждёт чужих транзакций.
+	OutboxClaimSQL = `UPDATE outbox
+	SET next_attempt_at = $2, attempts = attempts + 1
+	WHERE id IN (
+		SELECT id FROM outbox
+		WHERE status = 'pending' AND next_attempt_at <= now()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, aggregate, op, isin, user_upk, status, attempts, last_error, next_attempt_at, created_at`
+
+	OutboxDeleteSQL = `DELETE FROM outbox
+	WHERE id = $1
+	RETURNING id`
+
+	OutboxInsertSQL = `INSERT INTO outbox
+	(aggregate, op, isin, user_upk)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, status, attempts, next_attempt_at, created_at`
+
+	OutboxUpdateSQL = `UPDATE outbox
+	SET status = $2, last_error = $3, next_attempt_at = $4
+	WHERE id = $1
+	RETURNING status, attempts`
+)
+
+// OutboxEvent событие репликации изменения из PostgreSQL в MongoDB.
+// Событие не несёт состояние: при доставке читается текущая запись,
+// поэтому повторная и неупорядоченная доставка безопасны.
+type OutboxEvent struct {
+	id            int64
+	aggregate     string
+	op            string
+	isin          string
+	upk           string
+	status        string
+	attempts      int
+	lastError     sql.NullString
+	nextAttemptAt time.Time
+	createdAt     time.Time
+	batchSize     int
+}
+
+type outboxEvent struct {
+	ID            int64
+	Aggregate     string
+	Op            string
+	Isin          string
+	Upk           string
+	Status        string
+	Attempts      int
+	LastError     string `json:",omitempty"`
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+// OutboxBacklog очередь событий outbox: ожидающие доставки,
+// dead-letter и время создания самого старого ожидающего события.
+type OutboxBacklog struct {
+	Pending       int64
+	Dead          int64
+	OldestPending sql.NullTime
+}
+
+var _ domain.Entity = (*OutboxEvent)(nil)
+
+func MakeOutboxEvent(aggregate, op, isin, upk string) OutboxEvent {
+	return OutboxEvent{aggregate: aggregate, op: op, isin: isin, upk: upk, status: OutboxStatusPending}
+}
+
+// ClaimOutboxEvents захват не более batchSize событий готовых к доставке до leaseUntil.
+func ClaimOutboxEvents(
+	ctx context.Context,
+	repo domain.Repo[*OutboxEvent],
+	batchSize int,
+	leaseUntil time.Time,
+) ([]OutboxEvent, error) {
+
+	var err error
+	result := make([]OutboxEvent, 0, batchSize)
+	filter := &OutboxEvent{batchSize: batchSize, nextAttemptAt: leaseUntil}
+
+	_, er0 := repo.GetByFilter(ctx, filter, func(scanner domain.Scanner) *OutboxEvent {
+		var o OutboxEvent
+		if e := scanner.Scan(
+			&o.id, &o.aggregate, &o.op, &o.isin, &o.upk, &o.status, &o.attempts, &o.lastError,
+			&o.nextAttemptAt, &o.createdAt,
+		); e != nil {
+			err = e
+		} else {
+			result = append(result, o)
+		}
+		return &o
+	})
+	if er0 != nil {
+		return nil, er0
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].id < result[j].id })
+
+	return result, err
+}
+
+// GetOutboxBacklog состояние очереди outbox.
+func GetOutboxBacklog(ctx context.Context, repo domain.Repo[*OutboxEvent]) (OutboxBacklog, error) {
+
+	var err error
+	var result OutboxBacklog
+
+	_, er0 := repo.Get(ctx, &OutboxEvent{}, func(scanner domain.Scanner) {
+		err = scanner.Scan(&result.Pending, &result.Dead, &result.OldestPending)
+	})
+	if er0 != nil {
+		return OutboxBacklog{}, er0
+	}
+	if err != nil {
+		return OutboxBacklog{}, err
+	}
+	return result, nil
+}
+
+func (o OutboxEvent) ID() int64 {
+	return o.id
+}
+
+func (o OutboxEvent) Aggregate() string {
+	return o.aggregate
+}
+
+func (o OutboxEvent) Op() string {
+	return o.op
+}
+
+func (o OutboxEvent) Isin() string {
+	return o.isin
+}
+
+func (o OutboxEvent) Upk() string {
+	return o.upk
+}
+
+func (o OutboxEvent) Status() string {
+	return o.status
+}
+
+func (o OutboxEvent) Attempts() int {
+	return o.attempts
+}
+
+func (o OutboxEvent) LastError() sql.NullString {
+	return o.lastError
+}
+
+func (o OutboxEvent) NextAttemptAt() time.Time {
+	return o.nextAttemptAt
+}
+
+func (o OutboxEvent) CreatedAt() time.Time {
+	return o.createdAt
+}
+
+func (o *OutboxEvent) Copy() domain.Entity {
+	c := *o
+	return &c
+}
+
+// Delivered удаление доставленного события.
+func (o *OutboxEvent) Delivered(ctx context.Context, repo domain.Repo[*OutboxEvent]) (err error) {
+
+	_, er0 := repo.Delete(ctx, o, func(s domain.Scanner) {
+		err = s.Scan(&o.id)
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (o *OutboxEvent) DeleteArgs() []any {
+	return []any{o.id}
+}
+
+func (o *OutboxEvent) DeleteSQL() string {
+	return OutboxDeleteSQL
+}
+
+// Failed неудачная попытка доставки: следующая попытка не раньше nextAttemptAt,
+// при dead событие переводится в dead-letter и больше не выдаётся.
+func (o *OutboxEvent) Failed(
+	ctx context.Context,
+	repo domain.Repo[*OutboxEvent],
+	cause error,
+	nextAttemptAt time.Time,
+	dead bool,
+) (err error) {
+
+	o.lastError = sql.NullString{String: cause.Error(), Valid: true}
+	o.nextAttemptAt = nextAttemptAt
+
+	if dead {
+		o.status = OutboxStatusDead
+	} else {
+		o.status = OutboxStatusPending
+	}
+	_, er0 := repo.Update(ctx, o, func(s domain.Scanner) {
+		err = s.Scan(&o.status, &o.attempts)
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (o *OutboxEvent) FromJSON(data []byte) (err error) {
+
+	var t outboxEvent
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	o.id = t.ID
+	o.aggregate = t.Aggregate
+	o.op = t.Op
+	o.isin = t.Isin
+	o.upk = t.Upk
+	o.status = t.Status
+	o.attempts = t.Attempts
+	o.lastError = sql.NullString{String: t.LastError, Valid: t.LastError != ""}
+	o.nextAttemptAt = t.NextAttemptAt
+	o.createdAt = t.CreatedAt
+
+	return nil
+}
+
+func (o *OutboxEvent) GetArgs() []any {
+	return []any{}
+}
+
+func (o *OutboxEvent) GetByFilterArgs() []any {
+	return []any{o.batchSize, o.nextAttemptAt}
+}
+
+func (o *OutboxEvent) GetByFilterSQL() string {
+	return OutboxClaimSQL
+}
+
+// GetSQL сводка очереди, см. GetOutboxBacklog.
+func (o *OutboxEvent) GetSQL() string {
+	return OutboxSelectBacklogSQL
+}
+
+func (o *OutboxEvent) Insert(ctx context.Context, repo domain.Repo[*OutboxEvent]) (err error) {
+
+	_, er0 := repo.Insert(ctx, o, func(s domain.Scanner) {
+		t := *o
+		err = s.Scan(&t.id, &t.status, &t.attempts, &t.nextAttemptAt, &t.createdAt)
+		if err == nil {
+			*o = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (o *OutboxEvent) InsertArgs() []any {
+	return []any{o.aggregate, o.op, o.isin, o.upk}
+}
+
+func (o *OutboxEvent) InsertSQL() string {
+	return OutboxInsertSQL
+}
+
+func (o *OutboxEvent) Key() string {
+	return strconv.FormatInt(o.id, 10)
+}
+
+func (o *OutboxEvent) String() string {
+	return fmt.Sprintf(
+		"{%d %s %s %s %s %s %d %v %v %v}\n",
+		o.id, o.aggregate, o.op, o.isin, o.upk, o.status, o.attempts, o.lastError, o.nextAttemptAt, o.createdAt,
+	)
+}
+
+func (o *OutboxEvent) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(outboxEvent{
+		ID:            o.id,
+		Aggregate:     o.aggregate,
+		Op:            o.op,
+		Isin:          o.isin,
+		Upk:           o.upk,
+		Status:        o.status,
+		Attempts:      o.attempts,
+		LastError:     o.lastError.String,
+		NextAttemptAt: o.nextAttemptAt,
+		CreatedAt:     o.createdAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (o *OutboxEvent) UpdateArgs() []any {
+	return []any{o.id, o.status, o.lastError, o.nextAttemptAt}
+}
+
+func (o *OutboxEvent) UpdateSQL() string {
+	return OutboxUpdateSQL
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,107 @@
+/*
+ * This file was last modified at 2024-08-17 14:40 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * outbox_test.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+func TestOutboxEvent(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 OutboxEvent Cloneable", fRun: testOutboxEventCloneable},
+		{name: "positive test #1 OutboxEvent FromJSON and ToJSON", fRun: testOutboxEventJSON},
+		{name: "positive test #2 OutboxEvent stubRepoOk", fRun: testOutboxEventRepoOk},
+		{name: "negative test #3 OutboxEvent stubRepoErr", fRun: testOutboxEventRepoErr},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testOutboxEventCloneable(t *testing.T) {
+	expected := MakeOutboxEvent(OutboxAggregateFavorites, OutboxOpUpsert, tool.RandStringBytes(12), tool.RandStringBytes(32))
+	got := expected.Copy()
+	assert.NotNil(t, got)
+	assert.Equal(t, &expected, got)
+}
+
+func testOutboxEventJSON(t *testing.T) {
+	expected := MakeOutboxEvent(OutboxAggregateFavorites, OutboxOpDelete, tool.RandStringBytes(12), tool.RandStringBytes(32))
+	expected.id = 42
+	expected.createdAt = time.Now().UTC().Truncate(time.Second)
+	expected.nextAttemptAt = expected.createdAt
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	assert.NotNil(t, j)
+	got := OutboxEvent{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, expected.String(), got.String())
+	assert.Equal(t, "42", got.Key())
+}
+
+func testOutboxEventRepoOk(t *testing.T) {
+	isin := tool.RandStringBytes(12)
+	upk := tool.RandStringBytes(32)
+	event := MakeOutboxEvent(OutboxAggregateFavorites, OutboxOpUpsert, isin, upk)
+	err := event.Insert(context.TODO(), &stubRepoOk[*OutboxEvent]{})
+	assert.Nil(t, err)
+	assert.Equal(t, OutboxAggregateFavorites, event.Aggregate())
+	assert.Equal(t, OutboxOpUpsert, event.Op())
+	assert.Equal(t, isin, event.Isin())
+	assert.Equal(t, upk, event.Upk())
+	assert.Equal(t, OutboxStatusPending, event.Status())
+	events, err := ClaimOutboxEvents(context.TODO(), &stubRepoOk[*OutboxEvent]{}, 10, time.Now())
+	assert.Nil(t, err)
+	assert.Len(t, events, 1)
+	next := time.Now().Add(time.Minute)
+	err = event.Failed(context.TODO(), &stubRepoOk[*OutboxEvent]{}, fmt.Errorf("test"), next, false)
+	assert.Nil(t, err)
+	assert.Equal(t, OutboxStatusPending, event.Status())
+	assert.Equal(t, "test", event.LastError().String)
+	assert.Equal(t, next, event.NextAttemptAt())
+	err = event.Failed(context.TODO(), &stubRepoOk[*OutboxEvent]{}, fmt.Errorf("test"), next, true)
+	assert.Nil(t, err)
+	assert.Equal(t, OutboxStatusDead, event.Status())
+	err = event.Delivered(context.TODO(), &stubRepoOk[*OutboxEvent]{})
+	assert.Nil(t, err)
+}
+
+func testOutboxEventRepoErr(t *testing.T) {
+	event := MakeOutboxEvent(OutboxAggregateFavorites, OutboxOpUpsert, tool.RandStringBytes(12), tool.RandStringBytes(32))
+	err := event.Insert(context.TODO(), &stubRepoErr[*OutboxEvent]{})
+	assert.NotNil(t, err)
+	_, err = ClaimOutboxEvents(context.TODO(), &stubRepoErr[*OutboxEvent]{}, 10, time.Now())
+	assert.NotNil(t, err)
+	_, err = GetOutboxBacklog(context.TODO(), &stubRepoErr[*OutboxEvent]{})
+	assert.NotNil(t, err)
+	err = event.Failed(context.TODO(), &stubRepoErr[*OutboxEvent]{}, fmt.Errorf("test"), time.Now(), false)
+	assert.NotNil(t, err)
+	err = event.Delivered(context.TODO(), &stubRepoErr[*OutboxEvent]{})
+	assert.NotNil(t, err)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
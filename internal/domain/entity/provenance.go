@@ -0,0 +1,40 @@
+/*
+ * This file was last modified at 2024-08-18 03:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * provenance.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Provenance происхождение записи: идентификатор шарда последней записи и её время.
+type Provenance struct {
+	shardID   string
+	writtenAt sql.NullTime
+}
+
+func MakeProvenance(shardID string, writtenAt time.Time) Provenance {
+	return Provenance{
+		shardID:   shardID,
+		writtenAt: sql.NullTime{Time: writtenAt, Valid: !writtenAt.IsZero()},
+	}
+}
+
+func (p Provenance) ShardID() string {
+	return p.shardID
+}
+
+func (p Provenance) WrittenAt() sql.NullTime {
+	return p.writtenAt
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,256 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * refresh_token.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	RefreshTokenSelectSQL = `SELECT id, subject, role, expires_at, revoked_at, created_at
+	FROM refresh_tokens
+	WHERE token_hash = $1`
+
+	RefreshTokenSelectExpiredSQL = `SELECT id, token_hash, subject, role, expires_at, revoked_at, created_at
+	FROM refresh_tokens
+	WHERE expires_at < $1`
+
+	// RefreshTokenPurgeSQL удаление истёкших до $1 и погашенных токенов,
+	// возвращается количество удалённых.
+	RefreshTokenPurgeSQL = `WITH purged AS (
+	    DELETE FROM refresh_tokens WHERE expires_at < $1 OR revoked_at IS NOT NULL RETURNING id
+	)
+	SELECT count(*) FROM purged`
+
+	RefreshTokenInsertSQL = `INSERT INTO refresh_tokens
+	(token_hash, subject, role, expires_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at`
+
+	// RefreshTokenConsumeSQL погашение одним оператором: повторное или
+	// конкурентное предъявление того же токена не находит revoked_at IS NULL.
+	RefreshTokenConsumeSQL = `UPDATE refresh_tokens
+	SET revoked_at = $2
+	WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > $2
+	RETURNING id, subject, role, expires_at, revoked_at, created_at`
+)
+
+// RefreshToken токен обновления, в базе данных хранится SHA-256 токена,
+// зашифрованный субъект (персональный ключ) и роль для выпуска нового JWT.
+type RefreshToken struct {
+	id        uuid.UUID
+	tokenHash string
+	subject   string
+	role      string
+	expiresAt time.Time
+	revokedAt sql.NullTime
+	createdAt time.Time
+}
+
+type refreshToken struct {
+	ID        uuid.UUID
+	TokenHash string
+	Subject   string
+	Role      string
+	ExpiresAt time.Time
+	RevokedAt JsonNullTime `json:",omitempty"`
+	CreatedAt time.Time
+}
+
+var _ domain.Entity = (*RefreshToken)(nil)
+
+// MakeRefreshToken создание токена обновления по открытому значению токена.
+func MakeRefreshToken(token, subject, role string, expiresAt time.Time) RefreshToken {
+	return RefreshToken{tokenHash: tool.TokenHash(token), subject: subject, role: role, expiresAt: expiresAt}
+}
+
+// PurgeRefreshTokens удаление токенов обновления истёкших до before
+// и погашенных: предъявить их повторно уже нельзя.
+func PurgeRefreshTokens(ctx context.Context, repo domain.Repo[*RefreshToken], before time.Time) (int64, error) {
+
+	var err error
+	var purged int64
+
+	_, er0 := repo.Delete(ctx, &RefreshToken{expiresAt: before}, func(scanner domain.Scanner) {
+		err = scanner.Scan(&purged)
+	})
+	if er0 != nil {
+		return 0, er0
+	}
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+func IsRefreshTokenNotFound(r RefreshToken, err error) bool {
+	return tool.NoRowsInResultSet(err) || r.id == uuid.Nil
+}
+
+func (r RefreshToken) ID() uuid.UUID {
+	return r.id
+}
+
+func (r RefreshToken) TokenHash() string {
+	return r.tokenHash
+}
+
+func (r RefreshToken) Subject() string {
+	return r.subject
+}
+
+func (r RefreshToken) Role() string {
+	return r.role
+}
+
+func (r RefreshToken) ExpiresAt() time.Time {
+	return r.expiresAt
+}
+
+func (r RefreshToken) RevokedAt() sql.NullTime {
+	return r.revokedAt
+}
+
+func (r RefreshToken) CreatedAt() time.Time {
+	return r.createdAt
+}
+
+// Consume атомарное погашение токена, повторное погашение возвращает ошибку отсутствия строк.
+func (r *RefreshToken) Consume(ctx context.Context, repo domain.Repo[*RefreshToken]) (err error) {
+
+	_, er0 := repo.Update(ctx, r, func(s domain.Scanner) {
+		t := *r
+		err = s.Scan(&t.id, &t.subject, &t.role, &t.expiresAt, &t.revokedAt, &t.createdAt)
+		if err == nil {
+			*r = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (r *RefreshToken) Copy() domain.Entity {
+	c := *r
+	return &c
+}
+
+// DeleteArgs срок действия до которого удаляются токены, см. PurgeRefreshTokens.
+func (r *RefreshToken) DeleteArgs() []any {
+	return []any{r.expiresAt}
+}
+
+func (r *RefreshToken) DeleteSQL() string {
+	return RefreshTokenPurgeSQL
+}
+
+func (r *RefreshToken) FromJSON(data []byte) (err error) {
+
+	var t refreshToken
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	r.id = t.ID
+	r.tokenHash = t.TokenHash
+	r.subject = t.Subject
+	r.role = t.Role
+	r.expiresAt = t.ExpiresAt
+	r.revokedAt = t.RevokedAt.ToNullTime()
+	r.createdAt = t.CreatedAt
+
+	return nil
+}
+
+func (r *RefreshToken) GetArgs() []any {
+	return []any{r.tokenHash}
+}
+
+func (r *RefreshToken) GetByFilterArgs() []any {
+	return []any{r.expiresAt}
+}
+
+func (r *RefreshToken) GetByFilterSQL() string {
+	return RefreshTokenSelectExpiredSQL
+}
+
+func (r *RefreshToken) GetSQL() string {
+	return RefreshTokenSelectSQL
+}
+
+func (r *RefreshToken) Insert(ctx context.Context, repo domain.Repo[*RefreshToken]) (err error) {
+
+	_, er0 := repo.Insert(ctx, r, func(s domain.Scanner) {
+		t := *r
+		err = s.Scan(&t.id, &t.createdAt)
+		if err == nil {
+			*r = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (r *RefreshToken) InsertArgs() []any {
+	return []any{r.tokenHash, r.subject, r.role, r.expiresAt}
+}
+
+func (r *RefreshToken) InsertSQL() string {
+	return RefreshTokenInsertSQL
+}
+
+func (r *RefreshToken) Key() string {
+	return r.tokenHash
+}
+
+func (r *RefreshToken) String() string {
+	return fmt.Sprintf("{%v %s %s %v %v %v}\n", r.id, r.tokenHash, r.role, r.expiresAt, r.revokedAt, r.createdAt)
+}
+
+func (r *RefreshToken) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(refreshToken{
+		ID:        r.id,
+		TokenHash: r.tokenHash,
+		Subject:   r.subject,
+		Role:      r.role,
+		ExpiresAt: r.expiresAt,
+		RevokedAt: FromNullTime(r.revokedAt),
+		CreatedAt: r.createdAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *RefreshToken) UpdateArgs() []any {
+	return []any{r.tokenHash, time.Now().UTC()}
+}
+
+func (r *RefreshToken) UpdateSQL() string {
+	return RefreshTokenConsumeSQL
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
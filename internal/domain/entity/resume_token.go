@@ -0,0 +1,216 @@
+/*
+ * This file was last modified at 2024-08-18 04:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * resume_token.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/goccy/go-json"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	ResumeTokenSelectSQL = `SELECT name, token, updated_at
+	FROM resume_tokens
+	WHERE name = $1`
+
+	ResumeTokenSelectAllSQL = `SELECT name, token, updated_at
+	FROM resume_tokens
+	ORDER BY name`
+
+	ResumeTokenDeleteSQL = `DELETE FROM resume_tokens
+	WHERE name = $1
+	RETURNING name, token, updated_at`
+
+	ResumeTokenInsertSQL = `INSERT INTO resume_tokens
+	(name, token, updated_at)
+	VALUES ($1, $2, now())
+	ON CONFLICT (name) DO UPDATE SET token = EXCLUDED.token, updated_at = now()
+	RETURNING name, token, updated_at`
+
+	ResumeTokenUpdateSQL = `UPDATE resume_tokens
+	SET token = $2, updated_at = now()
+	WHERE name = $1
+	RETURNING name, token, updated_at`
+)
+
+// ResumeToken маркер возобновления (resume token) потока изменений MongoDB,
+// ключ — имя потока. Поток продолжается с события после маркера,
+// поэтому события между перезапусками сервиса не теряются.
+type ResumeToken struct {
+	name      string
+	token     []byte
+	updatedAt sql.NullTime
+}
+
+type resumeToken struct {
+	Name      string
+	Token     []byte
+	UpdatedAt JsonNullTime `json:",omitempty"`
+}
+
+var _ domain.Entity = (*ResumeToken)(nil)
+
+func GetResumeToken(ctx context.Context, repo domain.Repo[*ResumeToken], name string) (ResumeToken, error) {
+
+	var err error
+	result := &ResumeToken{name: name}
+
+	result, er0 := repo.Get(ctx, result, func(scanner domain.Scanner) {
+		err = scanner.Scan(&result.name, &result.token, &result.updatedAt)
+	})
+	if er0 != nil {
+		return ResumeToken{}, er0
+	}
+	if err != nil {
+		return ResumeToken{}, err
+	}
+	return *result, nil
+}
+
+func MakeResumeToken(name string, token []byte) ResumeToken {
+	return ResumeToken{name: name, token: token}
+}
+
+func IsResumeTokenNotFound(r ResumeToken, err error) bool {
+	return tool.NoRowsInResultSet(err) || r.name == ""
+}
+
+func (r ResumeToken) Name() string {
+	return r.name
+}
+
+func (r ResumeToken) Token() []byte {
+	return r.token
+}
+
+func (r ResumeToken) UpdatedAt() sql.NullTime {
+	return r.updatedAt
+}
+
+func (r *ResumeToken) Copy() domain.Entity {
+	c := *r
+	return &c
+}
+
+// Delete сброс маркера: поток будет прочитан с текущего момента.
+func (r *ResumeToken) Delete(ctx context.Context, repo domain.Repo[*ResumeToken]) (err error) {
+
+	_, er0 := repo.Delete(ctx, r, func(s domain.Scanner) {
+		t := *r
+		err = s.Scan(&t.name, &t.token, &t.updatedAt)
+	})
+	if er0 != nil {
+		return er0
+	}
+	if tool.NoRowsInResultSet(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *ResumeToken) DeleteArgs() []any {
+	return []any{r.name}
+}
+
+func (r *ResumeToken) DeleteSQL() string {
+	return ResumeTokenDeleteSQL
+}
+
+func (r *ResumeToken) FromJSON(data []byte) (err error) {
+
+	var t resumeToken
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	r.name = t.Name
+	r.token = t.Token
+	r.updatedAt = t.UpdatedAt.ToNullTime()
+
+	return nil
+}
+
+func (r *ResumeToken) GetArgs() []any {
+	return []any{r.name}
+}
+
+func (r *ResumeToken) GetByFilterArgs() []any {
+	return []any{}
+}
+
+func (r *ResumeToken) GetByFilterSQL() string {
+	return ResumeTokenSelectAllSQL
+}
+
+func (r *ResumeToken) GetSQL() string {
+	return ResumeTokenSelectSQL
+}
+
+// Insert сохранение маркера потока, существующий маркер заменяется.
+func (r *ResumeToken) Insert(ctx context.Context, repo domain.Repo[*ResumeToken]) (err error) {
+
+	_, er0 := repo.Insert(ctx, r, func(s domain.Scanner) {
+		t := *r
+		err = s.Scan(&t.name, &t.token, &t.updatedAt)
+		if err == nil {
+			*r = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (r *ResumeToken) InsertArgs() []any {
+	return []any{r.name, r.token}
+}
+
+func (r *ResumeToken) InsertSQL() string {
+	return ResumeTokenInsertSQL
+}
+
+func (r *ResumeToken) Key() string {
+	return r.name
+}
+
+func (r *ResumeToken) String() string {
+	return fmt.Sprintf("{%s %x %v}\n", r.name, r.token, r.updatedAt)
+}
+
+func (r *ResumeToken) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(resumeToken{
+		Name:      r.name,
+		Token:     r.token,
+		UpdatedAt: FromNullTime(r.updatedAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *ResumeToken) UpdateArgs() []any {
+	return []any{r.name, r.token}
+}
+
+func (r *ResumeToken) UpdateSQL() string {
+	return ResumeTokenUpdateSQL
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
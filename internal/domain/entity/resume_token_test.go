@@ -0,0 +1,91 @@
+/*
+ * This file was last modified at 2024-08-18 04:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * resume_token_test.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResumeToken(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 ResumeToken Cloneable", fRun: testResumeTokenCloneable},
+		{name: "positive test #1 ResumeToken FromJSON and ToJSON", fRun: testResumeTokenJSON},
+		{name: "positive test #2 ResumeToken NotFound", fRun: testResumeTokenNotFound},
+		{name: "positive test #3 ResumeToken stubRepoOk", fRun: testResumeTokenRepoOk},
+		{name: "negative test #4 ResumeToken stubRepoErr", fRun: testResumeTokenRepoErr},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testResumeTokenCloneable(t *testing.T) {
+	expected := MakeResumeToken("favorites:shard-1", []byte{1, 2, 3})
+	got := expected.Copy()
+	assert.NotNil(t, got)
+	assert.Equal(t, &expected, got)
+	assert.Equal(t, "favorites:shard-1", got.Key())
+}
+
+func testResumeTokenJSON(t *testing.T) {
+	expected := MakeResumeToken("favorites:shard-1", []byte(`{"_data":"8266C1"}`))
+	expected.updatedAt = sql.NullTime{Time: time.Time{}.Add(time.Hour), Valid: true}
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	got := ResumeToken{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, expected.Token(), got.Token())
+}
+
+func testResumeTokenNotFound(t *testing.T) {
+	assert.True(t, IsResumeTokenNotFound(ResumeToken{name: "name"}, pgx.ErrNoRows))
+	assert.True(t, IsResumeTokenNotFound(ResumeToken{}, nil))
+	assert.False(t, IsResumeTokenNotFound(MakeResumeToken("name", nil), nil))
+}
+
+func testResumeTokenRepoOk(t *testing.T) {
+	r := MakeResumeToken("favorites:shard-1", []byte{1})
+	err := r.Insert(context.TODO(), &stubRepoOk[*ResumeToken]{})
+	assert.Nil(t, err)
+	got, err := GetResumeToken(context.TODO(), &stubRepoOk[*ResumeToken]{}, "favorites:shard-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "favorites:shard-1", got.Name())
+	err = r.Delete(context.TODO(), &stubRepoOk[*ResumeToken]{})
+	assert.Nil(t, err)
+}
+
+func testResumeTokenRepoErr(t *testing.T) {
+	r := MakeResumeToken("favorites:shard-1", []byte{1})
+	err := r.Insert(context.TODO(), &stubRepoErr[*ResumeToken]{})
+	assert.NotNil(t, err)
+	_, err = GetResumeToken(context.TODO(), &stubRepoErr[*ResumeToken]{}, "favorites:shard-1")
+	assert.NotNil(t, err)
+	err = r.Delete(context.TODO(), &stubRepoErr[*ResumeToken]{})
+	assert.NotNil(t, err)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,221 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * revoked_token.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	RevokedTokenSelectSQL = `SELECT jti, expires_at, created_at
+	FROM revoked_tokens
+	WHERE jti = $1`
+
+	RevokedTokenSelectActiveSQL = `SELECT jti, expires_at, created_at
+	FROM revoked_tokens
+	WHERE expires_at > $1`
+
+	// RevokedTokenPurgeSQL удаление записей с истёкшим до $1 сроком действия,
+	// возвращается количество удалённых.
+	RevokedTokenPurgeSQL = `WITH purged AS (
+	    DELETE FROM revoked_tokens WHERE expires_at < $1 RETURNING jti
+	)
+	SELECT count(*) FROM purged`
+
+	RevokedTokenInsertSQL = `INSERT INTO revoked_tokens
+	(jti, expires_at)
+	VALUES ($1, $2)
+	ON CONFLICT (jti) DO UPDATE SET expires_at = GREATEST(revoked_tokens.expires_at, EXCLUDED.expires_at)
+	RETURNING jti, expires_at, created_at`
+
+	RevokedTokenUpdateSQL = `UPDATE revoked_tokens
+	SET expires_at = $2
+	WHERE jti = $1
+	RETURNING jti, expires_at, created_at`
+)
+
+// RevokedToken отозванный до истечения срока действия JWT, ключ — утверждение «jti».
+// Запись нужна только до expires_at, после него токен отклоняется и так.
+type RevokedToken struct {
+	jti       string
+	expiresAt time.Time
+	createdAt time.Time
+}
+
+type revokedToken struct {
+	JTI       string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+var _ domain.Entity = (*RevokedToken)(nil)
+
+func GetRevokedToken(ctx context.Context, repo domain.Repo[*RevokedToken], jti string) (RevokedToken, error) {
+
+	var err error
+	result := &RevokedToken{jti: jti}
+
+	result, er0 := repo.Get(ctx, result, func(scanner domain.Scanner) {
+		err = scanner.Scan(&result.jti, &result.expiresAt, &result.createdAt)
+	})
+	if er0 != nil {
+		return RevokedToken{}, er0
+	}
+	if err != nil {
+		return RevokedToken{}, err
+	}
+	return *result, nil
+}
+
+func MakeRevokedToken(jti string, expiresAt time.Time) RevokedToken {
+	return RevokedToken{jti: jti, expiresAt: expiresAt}
+}
+
+// PurgeRevokedTokens удаление записей отозванных токенов с истёкшим до before
+// сроком действия: после него токен отклоняется и без записи.
+func PurgeRevokedTokens(ctx context.Context, repo domain.Repo[*RevokedToken], before time.Time) (int64, error) {
+
+	var err error
+	var purged int64
+
+	_, er0 := repo.Delete(ctx, &RevokedToken{expiresAt: before}, func(scanner domain.Scanner) {
+		err = scanner.Scan(&purged)
+	})
+	if er0 != nil {
+		return 0, er0
+	}
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+func IsRevokedTokenNotFound(r RevokedToken, err error) bool {
+	return tool.NoRowsInResultSet(err) || r.jti == ""
+}
+
+func (r RevokedToken) JTI() string {
+	return r.jti
+}
+
+func (r RevokedToken) ExpiresAt() time.Time {
+	return r.expiresAt
+}
+
+func (r RevokedToken) CreatedAt() time.Time {
+	return r.createdAt
+}
+
+func (r *RevokedToken) Copy() domain.Entity {
+	c := *r
+	return &c
+}
+
+// DeleteArgs срок действия до которого удаляются записи, см. PurgeRevokedTokens.
+func (r *RevokedToken) DeleteArgs() []any {
+	return []any{r.expiresAt}
+}
+
+func (r *RevokedToken) DeleteSQL() string {
+	return RevokedTokenPurgeSQL
+}
+
+func (r *RevokedToken) FromJSON(data []byte) (err error) {
+
+	var t revokedToken
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	r.jti = t.JTI
+	r.expiresAt = t.ExpiresAt
+	r.createdAt = t.CreatedAt
+
+	return nil
+}
+
+func (r *RevokedToken) GetArgs() []any {
+	return []any{r.jti}
+}
+
+func (r *RevokedToken) GetByFilterArgs() []any {
+	return []any{r.expiresAt}
+}
+
+func (r *RevokedToken) GetByFilterSQL() string {
+	return RevokedTokenSelectActiveSQL
+}
+
+func (r *RevokedToken) GetSQL() string {
+	return RevokedTokenSelectSQL
+}
+
+func (r *RevokedToken) Insert(ctx context.Context, repo domain.Repo[*RevokedToken]) (err error) {
+
+	_, er0 := repo.Insert(ctx, r, func(s domain.Scanner) {
+		t := *r
+		err = s.Scan(&t.jti, &t.expiresAt, &t.createdAt)
+		if err == nil {
+			*r = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (r *RevokedToken) InsertArgs() []any {
+	return []any{r.jti, r.expiresAt}
+}
+
+func (r *RevokedToken) InsertSQL() string {
+	return RevokedTokenInsertSQL
+}
+
+func (r *RevokedToken) Key() string {
+	return r.jti
+}
+
+func (r *RevokedToken) String() string {
+	return fmt.Sprintf("{%s %v %v}\n", r.jti, r.expiresAt, r.createdAt)
+}
+
+func (r *RevokedToken) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(revokedToken{
+		JTI:       r.jti,
+		ExpiresAt: r.expiresAt,
+		CreatedAt: r.createdAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *RevokedToken) UpdateArgs() []any {
+	return []any{r.jti, r.expiresAt}
+}
+
+func (r *RevokedToken) UpdateSQL() string {
+	return RevokedTokenUpdateSQL
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,144 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * token_test.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+func TestTokens(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 RefreshToken Cloneable", fRun: testRefreshTokenCloneable},
+		{name: "positive test #1 RefreshToken FromJSON and ToJSON", fRun: testRefreshTokenJSON},
+		{name: "positive test #2 RefreshToken and RevokedToken NotFound", fRun: testTokensNotFound},
+		{name: "positive test #3 RefreshToken stubRepoOk", fRun: testRefreshTokenRepoOk},
+		{name: "negative test #4 RefreshToken stubRepoErr", fRun: testRefreshTokenRepoErr},
+		{name: "positive test #5 RevokedToken FromJSON and ToJSON", fRun: testRevokedTokenJSON},
+		{name: "positive test #6 RevokedToken stubRepoOk", fRun: testRevokedTokenRepoOk},
+		{name: "negative test #7 RevokedToken stubRepoErr", fRun: testRevokedTokenRepoErr},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testRefreshTokenCloneable(t *testing.T) {
+	expected := MakeRefreshToken("token", "subject", "USER", time.Now())
+	got := expected.Copy()
+	assert.NotNil(t, got)
+	assert.Equal(t, &expected, got)
+}
+
+func testRefreshTokenJSON(t *testing.T) {
+	expected := MakeRefreshToken("token", "subject", "USER", time.Time{})
+	expected.id = uuid.New()
+	expected.revokedAt = sql.NullTime{Time: time.Time{}.Add(time.Hour), Valid: true}
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	got := RefreshToken{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, expected.String(), got.String())
+	assert.Equal(t, tool.TokenHash("token"), got.Key())
+	assert.Equal(t, got.TokenHash(), got.Key())
+	assert.Equal(t, "subject", got.Subject())
+	assert.Equal(t, expected.ID(), got.ID())
+	assert.Equal(t, expected.RevokedAt(), got.RevokedAt())
+	assert.NotNil(t, (&got).FromJSON([]byte("{")))
+}
+
+func testTokensNotFound(t *testing.T) {
+	assert.True(t, IsRefreshTokenNotFound(RefreshToken{id: uuid.New()}, pgx.ErrNoRows))
+	assert.True(t, IsRefreshTokenNotFound(RefreshToken{}, nil))
+	assert.False(t, IsRefreshTokenNotFound(RefreshToken{id: uuid.New()}, errors.New("")))
+	assert.True(t, IsRevokedTokenNotFound(RevokedToken{jti: "jti"}, pgx.ErrNoRows))
+	assert.True(t, IsRevokedTokenNotFound(RevokedToken{}, nil))
+	assert.False(t, IsRevokedTokenNotFound(RevokedToken{jti: "jti"}, nil))
+}
+
+func testRefreshTokenRepoOk(t *testing.T) {
+	r := MakeRefreshToken("token", "subject", "USER", time.Now())
+	err := r.Insert(context.TODO(), &stubRepoOk[*RefreshToken]{})
+	assert.Nil(t, err)
+	err = r.Consume(context.TODO(), &stubRepoOk[*RefreshToken]{})
+	assert.Nil(t, err)
+	assert.Equal(t, RefreshTokenConsumeSQL, r.UpdateSQL())
+	assert.Equal(t, r.TokenHash(), r.UpdateArgs()[0])
+	before := time.Now()
+	_, err = PurgeRefreshTokens(context.TODO(), &stubRepoOk[*RefreshToken]{}, before)
+	assert.Nil(t, err)
+	assert.Equal(t, []any{before}, (&RefreshToken{expiresAt: before}).DeleteArgs())
+	assert.Contains(t, r.DeleteSQL(), "revoked_at IS NOT NULL")
+}
+
+func testRefreshTokenRepoErr(t *testing.T) {
+	r := MakeRefreshToken("token", "subject", "USER", time.Now())
+	err := r.Insert(context.TODO(), &stubRepoErr[*RefreshToken]{})
+	assert.NotNil(t, err)
+	err = r.Consume(context.TODO(), &stubRepoErr[*RefreshToken]{})
+	assert.NotNil(t, err)
+	_, err = PurgeRefreshTokens(context.TODO(), &stubRepoErr[*RefreshToken]{}, time.Now())
+	assert.NotNil(t, err)
+}
+
+func testRevokedTokenJSON(t *testing.T) {
+	expected := MakeRevokedToken("jti", time.Time{}.Add(time.Hour))
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	got := RevokedToken{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, expected.String(), got.String())
+	assert.Equal(t, "jti", got.Key())
+	assert.Equal(t, &expected, expected.Copy())
+	assert.NotNil(t, (&got).FromJSON([]byte("{")))
+}
+
+func testRevokedTokenRepoOk(t *testing.T) {
+	r := MakeRevokedToken("jti", time.Now())
+	err := r.Insert(context.TODO(), &stubRepoOk[*RevokedToken]{})
+	assert.Nil(t, err)
+	_, err = GetRevokedToken(context.TODO(), &stubRepoOk[*RevokedToken]{}, "jti")
+	assert.Nil(t, err)
+	_, err = PurgeRevokedTokens(context.TODO(), &stubRepoOk[*RevokedToken]{}, time.Now())
+	assert.Nil(t, err)
+}
+
+func testRevokedTokenRepoErr(t *testing.T) {
+	r := MakeRevokedToken("jti", time.Now())
+	err := r.Insert(context.TODO(), &stubRepoErr[*RevokedToken]{})
+	assert.NotNil(t, err)
+	_, err = GetRevokedToken(context.TODO(), &stubRepoErr[*RevokedToken]{}, "jti")
+	assert.NotNil(t, err)
+	_, err = PurgeRevokedTokens(context.TODO(), &stubRepoErr[*RevokedToken]{}, time.Now())
+	assert.NotNil(t, err)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
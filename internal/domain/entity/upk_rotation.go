@@ -0,0 +1,293 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * upk_rotation.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	UpkRotationSelectSQL = `SELECT key_version, after_upk, rotated, failed, started_at, finished_at, updated_at
+	FROM upk_rotations
+	WHERE key_version = $1`
+
+	// UpkRotationSelectKeyVersionsSQL версии секрета UPK, которыми могут быть
+	// зашифрованы данные: версии перешифрования и версии ключей пользователей.
+	UpkRotationSelectKeyVersionsSQL = `SELECT key_version FROM upk_rotations
+	UNION
+	SELECT key_version FROM users
+	ORDER BY key_version DESC`
+
+	UpkRotationDeleteSQL = `DELETE FROM upk_rotations
+	WHERE key_version = $1
+	RETURNING key_version, after_upk, rotated, failed, started_at, finished_at, updated_at`
+
+	UpkRotationUpsertSQL = `INSERT INTO upk_rotations
+	(key_version, after_upk, rotated, failed, finished_at, started_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, now(), now())
+	ON CONFLICT (key_version) DO UPDATE SET
+	    after_upk = EXCLUDED.after_upk,
+	    rotated = EXCLUDED.rotated,
+	    failed = EXCLUDED.failed,
+	    finished_at = EXCLUDED.finished_at,
+	    updated_at = now()
+	RETURNING key_version, after_upk, rotated, failed, started_at, finished_at, updated_at`
+)
+
+// UpkRotation ход перешифрования пользователей на версию секрета UPK,
+// ключ — версия. После перезапуска перешифрование продолжается
+// с пользователя следующего за afterUpk.
+type UpkRotation struct {
+	keyVersion int
+	afterUpk   string
+	rotated    int64
+	failed     int64
+	startedAt  time.Time
+	finishedAt sql.NullTime
+	updatedAt  sql.NullTime
+}
+
+type upkRotation struct {
+	KeyVersion int
+	AfterUpk   string
+	Rotated    int64
+	Failed     int64
+	StartedAt  time.Time
+	FinishedAt JsonNullTime `json:",omitempty"`
+	UpdatedAt  JsonNullTime `json:",omitempty"`
+}
+
+var _ domain.Entity = (*UpkRotation)(nil)
+
+func GetUpkRotation(ctx context.Context, repo domain.Repo[*UpkRotation], keyVersion int) (UpkRotation, error) {
+
+	var err error
+	result := &UpkRotation{keyVersion: keyVersion}
+
+	result, er0 := repo.Get(ctx, result, func(scanner domain.Scanner) {
+		err = scanner.Scan(
+			&result.keyVersion,
+			&result.afterUpk,
+			&result.rotated,
+			&result.failed,
+			&result.startedAt,
+			&result.finishedAt,
+			&result.updatedAt,
+		)
+	})
+	if er0 != nil {
+		return UpkRotation{}, er0
+	}
+	if err != nil {
+		return UpkRotation{}, err
+	}
+	return *result, nil
+}
+
+// GetUpkKeyVersions версии секрета UPK, которыми записаны данные в PostgreSQL,
+// от новых к старым.
+func GetUpkKeyVersions(ctx context.Context, repo domain.Repo[*UpkRotation]) ([]int, error) {
+
+	var err error
+	results := make([]int, 0)
+	_, er0 := repo.GetByFilter(ctx, &UpkRotation{}, func(scanner domain.Scanner) *UpkRotation {
+		result := UpkRotation{}
+		err = scanner.Scan(&result.keyVersion)
+		results = append(results, result.keyVersion)
+		return &result
+	})
+	if er0 != nil {
+		return nil, er0
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// MakeUpkRotation ход перешифрования на версию keyVersion.
+func MakeUpkRotation(keyVersion int, afterUpk string, rotated, failed int64, finishedAt sql.NullTime) UpkRotation {
+	return UpkRotation{
+		keyVersion: keyVersion,
+		afterUpk:   afterUpk,
+		rotated:    rotated,
+		failed:     failed,
+		finishedAt: finishedAt,
+	}
+}
+
+func IsUpkRotationNotFound(r UpkRotation, err error) bool {
+	return tool.NoRowsInResultSet(err) || r.startedAt.IsZero()
+}
+
+func (r UpkRotation) AfterUpk() string {
+	return r.afterUpk
+}
+
+func (r UpkRotation) Failed() int64 {
+	return r.failed
+}
+
+func (r UpkRotation) FinishedAt() sql.NullTime {
+	return r.finishedAt
+}
+
+func (r UpkRotation) KeyVersion() int {
+	return r.keyVersion
+}
+
+func (r UpkRotation) Rotated() int64 {
+	return r.rotated
+}
+
+func (r UpkRotation) StartedAt() time.Time {
+	return r.startedAt
+}
+
+func (r UpkRotation) UpdatedAt() sql.NullTime {
+	return r.updatedAt
+}
+
+func (r *UpkRotation) Copy() domain.Entity {
+	c := *r
+	return &c
+}
+
+// Delete сброс хода перешифрования: следующий проход начнётся сначала.
+func (r *UpkRotation) Delete(ctx context.Context, repo domain.Repo[*UpkRotation]) (err error) {
+
+	_, er0 := repo.Delete(ctx, r, func(s domain.Scanner) {
+		t := *r
+		err = s.Scan(&t.keyVersion, &t.afterUpk, &t.rotated, &t.failed, &t.startedAt, &t.finishedAt, &t.updatedAt)
+	})
+	if er0 != nil {
+		return er0
+	}
+	if tool.NoRowsInResultSet(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *UpkRotation) DeleteArgs() []any {
+	return []any{r.keyVersion}
+}
+
+func (r *UpkRotation) DeleteSQL() string {
+	return UpkRotationDeleteSQL
+}
+
+func (r *UpkRotation) FromJSON(data []byte) (err error) {
+
+	var t upkRotation
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	r.keyVersion = t.KeyVersion
+	r.afterUpk = t.AfterUpk
+	r.rotated = t.Rotated
+	r.failed = t.Failed
+	r.startedAt = t.StartedAt
+	r.finishedAt = t.FinishedAt.ToNullTime()
+	r.updatedAt = t.UpdatedAt.ToNullTime()
+
+	return nil
+}
+
+func (r *UpkRotation) GetArgs() []any {
+	return []any{r.keyVersion}
+}
+
+func (r *UpkRotation) GetByFilterArgs() []any {
+	return []any{}
+}
+
+func (r *UpkRotation) GetByFilterSQL() string {
+	return UpkRotationSelectKeyVersionsSQL
+}
+
+func (r *UpkRotation) GetSQL() string {
+	return UpkRotationSelectSQL
+}
+
+// Insert сохранение хода перешифрования, существующий заменяется,
+// время начала остаётся от первого сохранения.
+func (r *UpkRotation) Insert(ctx context.Context, repo domain.Repo[*UpkRotation]) (err error) {
+
+	_, er0 := repo.Insert(ctx, r, func(s domain.Scanner) {
+		t := *r
+		err = s.Scan(&t.keyVersion, &t.afterUpk, &t.rotated, &t.failed, &t.startedAt, &t.finishedAt, &t.updatedAt)
+		if err == nil {
+			*r = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (r *UpkRotation) InsertArgs() []any {
+	return []any{r.keyVersion, r.afterUpk, r.rotated, r.failed, r.finishedAt}
+}
+
+func (r *UpkRotation) InsertSQL() string {
+	return UpkRotationUpsertSQL
+}
+
+func (r *UpkRotation) Key() string {
+	return strconv.Itoa(r.keyVersion)
+}
+
+func (r *UpkRotation) String() string {
+	return fmt.Sprintf(
+		"{%d %s %d %d %v %v %v}\n",
+		r.keyVersion, r.afterUpk, r.rotated, r.failed, r.startedAt, r.finishedAt, r.updatedAt,
+	)
+}
+
+func (r *UpkRotation) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(upkRotation{
+		KeyVersion: r.keyVersion,
+		AfterUpk:   r.afterUpk,
+		Rotated:    r.rotated,
+		Failed:     r.failed,
+		StartedAt:  r.startedAt,
+		FinishedAt: FromNullTime(r.finishedAt),
+		UpdatedAt:  FromNullTime(r.updatedAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *UpkRotation) UpdateArgs() []any {
+	return r.InsertArgs()
+}
+
+func (r *UpkRotation) UpdateSQL() string {
+	return UpkRotationUpsertSQL
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,101 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * upk_rotation_test.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpkRotation(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 UpkRotation Cloneable", fRun: testUpkRotationCloneable},
+		{name: "positive test #1 UpkRotation FromJSON and ToJSON", fRun: testUpkRotationJSON},
+		{name: "positive test #2 UpkRotation NotFound", fRun: testUpkRotationNotFound},
+		{name: "positive test #3 UpkRotation stubRepoOk", fRun: testUpkRotationRepoOk},
+		{name: "negative test #4 UpkRotation stubRepoErr", fRun: testUpkRotationRepoErr},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testUpkRotationCloneable(t *testing.T) {
+	expected := MakeUpkRotation(2, "upk", 3, 1, sql.NullTime{})
+	got := expected.Copy()
+	assert.NotNil(t, got)
+	assert.Equal(t, &expected, got)
+	assert.Equal(t, "2", got.Key())
+	assert.Equal(t, []any{2, "upk", int64(3), int64(1), sql.NullTime{}}, expected.UpdateArgs())
+}
+
+func testUpkRotationJSON(t *testing.T) {
+	expected := MakeUpkRotation(2, "upk", 3, 1, sql.NullTime{Time: time.Time{}.Add(2 * time.Hour), Valid: true})
+	expected.startedAt = time.Time{}.Add(time.Hour).UTC()
+	expected.updatedAt = sql.NullTime{Time: time.Time{}.Add(time.Hour), Valid: true}
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	got := UpkRotation{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, "upk", got.AfterUpk())
+	assert.Equal(t, int64(3), got.Rotated())
+	assert.Equal(t, int64(1), got.Failed())
+	assert.True(t, got.FinishedAt().Valid)
+}
+
+func testUpkRotationNotFound(t *testing.T) {
+	assert.True(t, IsUpkRotationNotFound(UpkRotation{startedAt: time.Now()}, pgx.ErrNoRows))
+	assert.True(t, IsUpkRotationNotFound(MakeUpkRotation(1, "", 0, 0, sql.NullTime{}), nil))
+	assert.False(t, IsUpkRotationNotFound(UpkRotation{keyVersion: 1, startedAt: time.Now()}, nil))
+}
+
+func testUpkRotationRepoOk(t *testing.T) {
+	r := MakeUpkRotation(2, "upk", 3, 1, sql.NullTime{})
+	err := r.Insert(context.TODO(), &stubRepoOk[*UpkRotation]{})
+	assert.Nil(t, err)
+	got, err := GetUpkRotation(context.TODO(), &stubRepoOk[*UpkRotation]{}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, got.KeyVersion())
+	versions, err := GetUpkKeyVersions(context.TODO(), &stubRepoOk[*UpkRotation]{})
+	assert.Nil(t, err)
+	assert.Len(t, versions, 1)
+	err = r.Delete(context.TODO(), &stubRepoOk[*UpkRotation]{})
+	assert.Nil(t, err)
+}
+
+func testUpkRotationRepoErr(t *testing.T) {
+	r := MakeUpkRotation(2, "upk", 3, 1, sql.NullTime{})
+	err := r.Insert(context.TODO(), &stubRepoErr[*UpkRotation]{})
+	assert.NotNil(t, err)
+	_, err = GetUpkRotation(context.TODO(), &stubRepoErr[*UpkRotation]{}, 2)
+	assert.NotNil(t, err)
+	_, err = GetUpkKeyVersions(context.TODO(), &stubRepoErr[*UpkRotation]{})
+	assert.NotNil(t, err)
+	err = r.Delete(context.TODO(), &stubRepoErr[*UpkRotation]{})
+	assert.NotNil(t, err)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
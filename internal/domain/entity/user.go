@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-03 17:21 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-17 22:40 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * user.go
@@ -25,6 +25,8 @@ type User struct {
 	TAttributes
 	upk     string
 	version int64
+	// limit размер страницы для GetUsersPage, upk при этом — последний ключ предыдущей страницы.
+	limit int
 }
 
 type user struct {
@@ -60,6 +62,33 @@ func GetUser(ctx context.Context, repo domain.Repo[*User], upk string) (User, er
 	return *result, nil
 }
 
+// GetUsersPage страница не удалённых пользователей упорядоченных по upk,
+// начиная с пользователя следующего за after (keyset pagination).
+func GetUsersPage(ctx context.Context, repo domain.Repo[*User], after string, limit int) ([]User, error) {
+
+	var err error
+	results := make([]User, 0, limit)
+	_, er0 := repo.GetByFilter(ctx, &User{upk: after, limit: limit}, func(scanner domain.Scanner) *User {
+		result := User{}
+		err = scanner.Scan(
+			&result.upk,
+			&result.version,
+			&result.deleted,
+			&result.createdAt,
+			&result.updatedAt,
+		)
+		results = append(results, result)
+		return &result
+	})
+	if er0 != nil {
+		return nil, er0
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 func MakeUser(upk string, a TAttributes) User {
 	return User{
 		TAttributes: struct {
@@ -168,11 +197,15 @@ func (u *User) GetArgs() []any {
 }
 
 func (u *User) GetByFilterArgs() []any {
-	return []any{}
+	return []any{u.upk, u.limit}
 }
 
 func (u *User) GetByFilterSQL() string {
-	return `SELECT upk, version, deleted, created_at, updated_at FROM users WHERE deleted IS NOT TRUE`
+	return `SELECT upk, version, deleted, created_at, updated_at
+	FROM users
+	WHERE deleted IS NOT TRUE AND upk > $1
+	ORDER BY upk
+	LIMIT $2`
 }
 
 func (u *User) GetSQL() string {
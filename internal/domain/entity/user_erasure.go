@@ -0,0 +1,295 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * user_erasure.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+)
+
+const (
+	UserErasureSelectSQL = `SELECT
+	id, key_version, users, favorites, notes, otp_secrets, outbox, synced_favorites, synced_notes, erased_at
+	FROM erasure_audit
+	WHERE id = $1`
+
+	UserErasureSelectAllSQL = `SELECT
+	id, key_version, users, favorites, notes, otp_secrets, outbox, synced_favorites, synced_notes, erased_at
+	FROM erasure_audit
+	ORDER BY id DESC
+	LIMIT $1`
+
+	UserErasureDeleteSQL = `DELETE FROM erasure_audit
+	WHERE id = $1
+	RETURNING id, key_version, users, favorites, notes, otp_secrets, outbox, synced_favorites, synced_notes, erased_at`
+
+	// UserErasureInsertSQL удаление строк пользователя со всеми прежними UPK и запись аудита одним оператором:
+	// проверка внешних ключей выполняется в конце оператора, коды OTP удаляются каскадно.
+	// Строки assets и asset_types общие для пользователей и не удаляются, удаляются ссылки на них.
+	UserErasureInsertSQL = `WITH
+	o AS (DELETE FROM otp_secrets WHERE user_upk = ANY($1) RETURNING 1),
+	n AS (DELETE FROM notes WHERE user_upk = ANY($1) RETURNING 1),
+	f AS (DELETE FROM favorites WHERE user_upk = ANY($1) RETURNING 1),
+	x AS (DELETE FROM outbox WHERE user_upk = ANY($1) RETURNING 1),
+	u AS (DELETE FROM users WHERE upk = ANY($1) RETURNING 1)
+	INSERT INTO erasure_audit
+	(key_version, users, favorites, notes, otp_secrets, outbox, synced_favorites, synced_notes)
+	SELECT $2,
+	(SELECT count(*) FROM u), (SELECT count(*) FROM f), (SELECT count(*) FROM n),
+	(SELECT count(*) FROM o), (SELECT count(*) FROM x), $3, $4
+	RETURNING id, key_version, users, favorites, notes, otp_secrets, outbox, synced_favorites, synced_notes, erased_at`
+)
+
+// UserErasure удаление данных пользователя по его запросу и запись аудита удаления.
+// Аудит содержит только количество удалённых строк и документов: UPK пользователя
+// известен только до удаления и в аудит не попадает.
+type UserErasure struct {
+	id              int64
+	upks            []string
+	keyVersion      int
+	users           int64
+	favorites       int64
+	notes           int64
+	otpSecrets      int64
+	outbox          int64
+	syncedFavorites int64
+	syncedNotes     int64
+	erasedAt        time.Time
+	// limit количество записей аудита для GetUserErasures.
+	limit int
+}
+
+type userErasure struct {
+	ID              int64
+	KeyVersion      int
+	Users           int64
+	Favorites       int64
+	Notes           int64
+	OtpSecrets      int64
+	Outbox          int64
+	SyncedFavorites int64
+	SyncedNotes     int64
+	ErasedAt        time.Time
+}
+
+var _ domain.Entity = (*UserErasure)(nil)
+
+// GetUserErasures последние limit записей аудита удаления.
+func GetUserErasures(ctx context.Context, repo domain.Repo[*UserErasure], limit int) ([]UserErasure, error) {
+
+	var err error
+	results := make([]UserErasure, 0, limit)
+	_, er0 := repo.GetByFilter(ctx, &UserErasure{limit: limit}, func(scanner domain.Scanner) *UserErasure {
+		result := UserErasure{}
+		err = scanner.Scan(result.scanArgs()...)
+		results = append(results, result)
+		return &result
+	})
+	if er0 != nil {
+		return nil, er0
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// MakeUserErasure удаление строк пользователя с UPK upks (текущим и прежними),
+// keyVersion версия секрета текущего UPK, syncedFavorites и syncedNotes количество
+// документов уже удалённых из хранилища синхронизации.
+func MakeUserErasure(upks []string, keyVersion int, syncedFavorites, syncedNotes int64) UserErasure {
+	return UserErasure{
+		upks:            upks,
+		keyVersion:      keyVersion,
+		syncedFavorites: syncedFavorites,
+		syncedNotes:     syncedNotes,
+	}
+}
+
+func (e UserErasure) ID() int64 {
+	return e.id
+}
+
+func (e UserErasure) KeyVersion() int {
+	return e.keyVersion
+}
+
+func (e UserErasure) Users() int64 {
+	return e.users
+}
+
+func (e UserErasure) Favorites() int64 {
+	return e.favorites
+}
+
+func (e UserErasure) Notes() int64 {
+	return e.notes
+}
+
+func (e UserErasure) OtpSecrets() int64 {
+	return e.otpSecrets
+}
+
+func (e UserErasure) Outbox() int64 {
+	return e.outbox
+}
+
+func (e UserErasure) SyncedFavorites() int64 {
+	return e.syncedFavorites
+}
+
+func (e UserErasure) SyncedNotes() int64 {
+	return e.syncedNotes
+}
+
+func (e UserErasure) ErasedAt() time.Time {
+	return e.erasedAt
+}
+
+func (e *UserErasure) Copy() domain.Entity {
+	c := *e
+	return &c
+}
+
+func (e *UserErasure) DeleteArgs() []any {
+	return []any{e.id}
+}
+
+func (e *UserErasure) DeleteSQL() string {
+	return UserErasureDeleteSQL
+}
+
+// Erase удаление строк пользователя и запись аудита в одной транзакции,
+// количество удалённых строк заполняется из записи аудита.
+func (e *UserErasure) Erase(ctx context.Context, repo domain.Repo[*UserErasure]) (err error) {
+
+	_, er0 := repo.Insert(ctx, e, func(s domain.Scanner) {
+		t := *e
+		err = s.Scan(t.scanArgs()...)
+		if err == nil {
+			*e = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (e *UserErasure) FromJSON(data []byte) (err error) {
+
+	var t userErasure
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	e.id = t.ID
+	e.keyVersion = t.KeyVersion
+	e.users = t.Users
+	e.favorites = t.Favorites
+	e.notes = t.Notes
+	e.otpSecrets = t.OtpSecrets
+	e.outbox = t.Outbox
+	e.syncedFavorites = t.SyncedFavorites
+	e.syncedNotes = t.SyncedNotes
+	e.erasedAt = t.ErasedAt
+
+	return nil
+}
+
+func (e *UserErasure) GetArgs() []any {
+	return []any{e.id}
+}
+
+func (e *UserErasure) GetByFilterArgs() []any {
+	return []any{e.limit}
+}
+
+func (e *UserErasure) GetByFilterSQL() string {
+	return UserErasureSelectAllSQL
+}
+
+func (e *UserErasure) GetSQL() string {
+	return UserErasureSelectSQL
+}
+
+func (e *UserErasure) InsertArgs() []any {
+	return []any{e.upks, e.keyVersion, e.syncedFavorites, e.syncedNotes}
+}
+
+func (e *UserErasure) InsertSQL() string {
+	return UserErasureInsertSQL
+}
+
+func (e *UserErasure) Key() string {
+	return strconv.FormatInt(e.id, 10)
+}
+
+func (e *UserErasure) String() string {
+	return fmt.Sprintf(
+		"{%d %d %d %d %d %d %d %d %d %v}\n",
+		e.id, e.keyVersion, e.users, e.favorites, e.notes, e.otpSecrets, e.outbox,
+		e.syncedFavorites, e.syncedNotes, e.erasedAt,
+	)
+}
+
+func (e *UserErasure) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(userErasure{
+		ID:              e.id,
+		KeyVersion:      e.keyVersion,
+		Users:           e.users,
+		Favorites:       e.favorites,
+		Notes:           e.notes,
+		OtpSecrets:      e.otpSecrets,
+		Outbox:          e.outbox,
+		SyncedFavorites: e.syncedFavorites,
+		SyncedNotes:     e.syncedNotes,
+		ErasedAt:        e.erasedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateArgs записи аудита не изменяются, повторное удаление записывает новую запись.
+func (e *UserErasure) UpdateArgs() []any {
+	return e.InsertArgs()
+}
+
+func (e *UserErasure) UpdateSQL() string {
+	return UserErasureInsertSQL
+}
+
+func (e *UserErasure) scanArgs() []any {
+	return []any{
+		&e.id,
+		&e.keyVersion,
+		&e.users,
+		&e.favorites,
+		&e.notes,
+		&e.otpSecrets,
+		&e.outbox,
+		&e.syncedFavorites,
+		&e.syncedNotes,
+		&e.erasedAt,
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,89 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * user_erasure_test.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserErasure(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 UserErasure Cloneable", fRun: testUserErasureCloneable},
+		{name: "positive test #1 UserErasure FromJSON and ToJSON", fRun: testUserErasureJSON},
+		{name: "positive test #2 UserErasure stubRepoOk", fRun: testUserErasureRepoOk},
+		{name: "negative test #3 UserErasure stubRepoErr", fRun: testUserErasureRepoErr},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testUserErasureCloneable(t *testing.T) {
+	expected := MakeUserErasure([]string{"upk", "legacy"}, 1, 2, 3)
+	got := expected.Copy()
+	assert.NotNil(t, got)
+	assert.Equal(t, &expected, got)
+	assert.Equal(t, "0", got.Key())
+	assert.Equal(t, []any{[]string{"upk", "legacy"}, 1, int64(2), int64(3)}, expected.InsertArgs())
+	assert.False(t, strings.Contains(expected.String(), "upk"), "аудит без UPK")
+}
+
+func testUserErasureJSON(t *testing.T) {
+	expected := MakeUserErasure([]string{"upk"}, 1, 2, 3)
+	expected.id = 7
+	expected.users = 1
+	expected.favorites = 4
+	expected.erasedAt = time.Time{}.Add(time.Hour).UTC()
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	assert.NotContains(t, string(j), "upk")
+	got := UserErasure{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	expected.upks = nil
+	assert.Equal(t, expected, got)
+	assert.Equal(t, int64(7), got.ID())
+	assert.Equal(t, int64(4), got.Favorites())
+	assert.Equal(t, int64(2), got.SyncedFavorites())
+	assert.Equal(t, int64(3), got.SyncedNotes())
+}
+
+func testUserErasureRepoOk(t *testing.T) {
+	e := MakeUserErasure([]string{"upk"}, 1, 2, 3)
+	err := e.Erase(context.TODO(), &stubRepoOk[*UserErasure]{})
+	assert.Nil(t, err)
+	got, err := GetUserErasures(context.TODO(), &stubRepoOk[*UserErasure]{}, 10)
+	assert.Nil(t, err)
+	assert.Len(t, got, 1)
+}
+
+func testUserErasureRepoErr(t *testing.T) {
+	e := MakeUserErasure([]string{"upk"}, 1, 2, 3)
+	err := e.Erase(context.TODO(), &stubRepoErr[*UserErasure]{})
+	assert.NotNil(t, err)
+	_, err = GetUserErasures(context.TODO(), &stubRepoErr[*UserErasure]{}, 10)
+	assert.NotNil(t, err)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,266 @@
+/*
+ * This file was last modified at 2024-08-18 06:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * user_key.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/goccy/go-json"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+)
+
+const (
+	UserKeySelectSQL = `SELECT upk, sealed_key, key_version, updated_at
+	FROM users
+	WHERE upk = $1`
+
+	// UserKeySelectPageSQL страница пользователей с ключом не текущей версии $1.
+	UserKeySelectPageSQL = `SELECT upk, sealed_key, key_version, updated_at
+	FROM users
+	WHERE sealed_key IS NOT NULL AND key_version <> $1 AND upk > $2
+	ORDER BY upk
+	LIMIT $3`
+
+	UserKeyEraseSQL = `UPDATE users
+	SET sealed_key = NULL
+	WHERE upk = $1
+	RETURNING upk, sealed_key, key_version, updated_at`
+
+	// UserKeyRekeySQL перенос пользователя с одного из прежних UPK $2 на новый $1:
+	// favorites, notes и otp переносятся каскадом внешних ключей,
+	// события outbox — в том же операторе. Пользователь уже с новым UPK
+	// получает зашифрованный персональный ключ версии $4 если его ещё нет
+	// или он другой версии. Пользователь без изменений возвращается как есть,
+	// нет строк в результате — пользователя нет.
+	UserKeyRekeySQL = `WITH rekeyed_outbox AS (
+	    UPDATE outbox SET user_upk = $1 WHERE user_upk = ANY($2) AND user_upk <> $1
+	), rekeyed AS (
+	    UPDATE users
+	    SET upk = $1, sealed_key = $3, key_version = $4
+	    WHERE (upk = ANY($2) AND upk <> $1)
+	       OR (upk = $1 AND (sealed_key IS NULL OR key_version <> $4))
+	    RETURNING upk, sealed_key, key_version, updated_at
+	)
+	SELECT upk, sealed_key, key_version, updated_at FROM rekeyed
+	UNION ALL
+	SELECT upk, sealed_key, key_version, updated_at FROM users
+	WHERE upk = $1 AND NOT EXISTS (SELECT 1 FROM rekeyed)`
+)
+
+// UserKey ключи пользователя: UPK (слепой индекс персонального ключа),
+// персональный ключ, зашифрованный с привязкой к UPK, и версия секрета UPK
+// которым они получены.
+type UserKey struct {
+	upk          string
+	previousUpks []string
+	sealedKey    []byte
+	keyVersion   int
+	updatedAt    sql.NullTime
+	// limit размер страницы для GetUserKeysPage, upk при этом — последний ключ предыдущей страницы.
+	limit int
+}
+
+type userKey struct {
+	Upk          string
+	PreviousUpks []string `json:",omitempty"`
+	SealedKey    []byte
+	KeyVersion   int
+	UpdatedAt    JsonNullTime `json:",omitempty"`
+}
+
+var _ domain.Entity = (*UserKey)(nil)
+
+func GetUserKey(ctx context.Context, repo domain.Repo[*UserKey], upk string) (UserKey, error) {
+
+	var err error
+	result := &UserKey{upk: upk}
+
+	result, er0 := repo.Get(ctx, result, func(scanner domain.Scanner) {
+		err = scanner.Scan(&result.upk, &result.sealedKey, &result.keyVersion, &result.updatedAt)
+	})
+	if er0 != nil {
+		return UserKey{}, er0
+	}
+	if err != nil {
+		return UserKey{}, err
+	}
+	return *result, nil
+}
+
+// GetUserKeysPage страница пользователей с зашифрованным персональным ключом
+// версии отличной от keyVersion, упорядоченных по upk, начиная со следующего за after.
+func GetUserKeysPage(ctx context.Context, repo domain.Repo[*UserKey], keyVersion int, after string, limit int) ([]UserKey, error) {
+
+	var err error
+	results := make([]UserKey, 0, limit)
+	_, er0 := repo.GetByFilter(ctx, &UserKey{upk: after, keyVersion: keyVersion, limit: limit}, func(scanner domain.Scanner) *UserKey {
+		result := UserKey{}
+		err = scanner.Scan(&result.upk, &result.sealedKey, &result.keyVersion, &result.updatedAt)
+		results = append(results, result)
+		return &result
+	})
+	if er0 != nil {
+		return nil, er0
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// MakeUserKey ключи пользователя upk версии keyVersion,
+// previousUpks — UPK прежних версий и прежнего формата.
+func MakeUserKey(upk string, previousUpks []string, sealedKey []byte, keyVersion int) UserKey {
+	return UserKey{upk: upk, previousUpks: previousUpks, sealedKey: sealedKey, keyVersion: keyVersion}
+}
+
+func (k UserKey) KeyVersion() int {
+	return k.keyVersion
+}
+
+func (k UserKey) PreviousUpks() []string {
+	return k.previousUpks
+}
+
+func (k UserKey) SealedKey() []byte {
+	return k.sealedKey
+}
+
+func (k UserKey) Upk() string {
+	return k.upk
+}
+
+func (k UserKey) UpdatedAt() sql.NullTime {
+	return k.updatedAt
+}
+
+func (k *UserKey) Copy() domain.Entity {
+	c := *k
+	return &c
+}
+
+// Erase удаление зашифрованного персонального ключа.
+func (k *UserKey) Erase(ctx context.Context, repo domain.Repo[*UserKey]) (err error) {
+
+	_, er0 := repo.Delete(ctx, k, func(s domain.Scanner) {
+		t := *k
+		err = s.Scan(&t.upk, &t.sealedKey, &t.keyVersion, &t.updatedAt)
+		if err == nil {
+			*k = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (k *UserKey) DeleteArgs() []any {
+	return []any{k.upk}
+}
+
+func (k *UserKey) DeleteSQL() string {
+	return UserKeyEraseSQL
+}
+
+func (k *UserKey) FromJSON(data []byte) (err error) {
+
+	var t userKey
+	err = json.Unmarshal(data, &t)
+
+	if err != nil {
+		return err
+	}
+	k.upk = t.Upk
+	k.previousUpks = t.PreviousUpks
+	k.sealedKey = t.SealedKey
+	k.keyVersion = t.KeyVersion
+	k.updatedAt = t.UpdatedAt.ToNullTime()
+
+	return nil
+}
+
+func (k *UserKey) GetArgs() []any {
+	return []any{k.upk}
+}
+
+func (k *UserKey) GetByFilterArgs() []any {
+	return []any{k.keyVersion, k.upk, k.limit}
+}
+
+func (k *UserKey) GetByFilterSQL() string {
+	return UserKeySelectPageSQL
+}
+
+func (k *UserKey) GetSQL() string {
+	return UserKeySelectSQL
+}
+
+func (k *UserKey) InsertArgs() []any {
+	return k.UpdateArgs()
+}
+
+func (k *UserKey) InsertSQL() string {
+	return UserKeyRekeySQL
+}
+
+func (k *UserKey) Key() string {
+	return k.upk
+}
+
+// Rekey перенос данных пользователя с прежних UPK на новый и сохранение
+// зашифрованного персонального ключа, нет строк в результате — пользователя нет.
+func (k *UserKey) Rekey(ctx context.Context, repo domain.Repo[*UserKey]) (err error) {
+
+	_, er0 := repo.Update(ctx, k, func(s domain.Scanner) {
+		t := *k
+		err = s.Scan(&t.upk, &t.sealedKey, &t.keyVersion, &t.updatedAt)
+		if err == nil {
+			*k = t
+		}
+	})
+	if er0 != nil {
+		return er0
+	}
+	return err
+}
+
+func (k *UserKey) String() string {
+	return fmt.Sprintf("{%s %v %d %d %v}\n", k.upk, k.previousUpks, len(k.sealedKey), k.keyVersion, k.updatedAt)
+}
+
+func (k *UserKey) ToJSON() ([]byte, error) {
+
+	result, err := json.Marshal(userKey{
+		Upk:          k.upk,
+		PreviousUpks: k.previousUpks,
+		SealedKey:    k.sealedKey,
+		KeyVersion:   k.keyVersion,
+		UpdatedAt:    FromNullTime(k.updatedAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (k *UserKey) UpdateArgs() []any {
+	return []any{k.upk, k.previousUpks, k.sealedKey, k.keyVersion}
+}
+
+func (k *UserKey) UpdateSQL() string {
+	return UserKeyRekeySQL
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
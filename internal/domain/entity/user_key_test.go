@@ -0,0 +1,99 @@
+/*
+ * This file was last modified at 2024-08-18 06:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * user_key_test.go
+ * $Id$
+ */
+//!+
+
+// Package entity TODO.
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserKey(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 UserKey Cloneable", fRun: testUserKeyCloneable},
+		{name: "positive test #1 UserKey FromJSON and ToJSON", fRun: testUserKeyJSON},
+		{name: "positive test #2 UserKey stubRepoOk", fRun: testUserKeyRepoOk},
+		{name: "negative test #3 UserKey stubRepoErr", fRun: testUserKeyRepoErr},
+		{name: "positive test #4 UserKey page", fRun: testUserKeyPage},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testUserKeyCloneable(t *testing.T) {
+	expected := MakeUserKey("upk", []string{"legacy"}, []byte{1, 2, 3}, 2)
+	got := expected.Copy()
+	assert.NotNil(t, got)
+	assert.Equal(t, &expected, got)
+	assert.Equal(t, "upk", got.Key())
+	assert.Equal(t, []any{"upk", []string{"legacy"}, []byte{1, 2, 3}, 2}, expected.UpdateArgs())
+	assert.Equal(t, expected.UpdateArgs(), expected.InsertArgs())
+}
+
+func testUserKeyJSON(t *testing.T) {
+	expected := MakeUserKey("upk", []string{"legacy"}, []byte{1, 2, 3}, 2)
+	expected.updatedAt = sql.NullTime{Time: time.Time{}.Add(time.Hour), Valid: true}
+	j, err := expected.ToJSON()
+	assert.Nil(t, err)
+	got := UserKey{}
+	err = (&got).FromJSON(j)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, []string{"legacy"}, got.PreviousUpks())
+	assert.Equal(t, 2, got.KeyVersion())
+	assert.Equal(t, []byte{1, 2, 3}, got.SealedKey())
+}
+
+func testUserKeyRepoOk(t *testing.T) {
+	k := MakeUserKey("upk", []string{"legacy"}, []byte{1}, 1)
+	err := k.Rekey(context.TODO(), &stubRepoOk[*UserKey]{})
+	assert.Nil(t, err)
+	got, err := GetUserKey(context.TODO(), &stubRepoOk[*UserKey]{}, "upk")
+	assert.Nil(t, err)
+	assert.Equal(t, "upk", got.Upk())
+	err = k.Erase(context.TODO(), &stubRepoOk[*UserKey]{})
+	assert.Nil(t, err)
+}
+
+func testUserKeyRepoErr(t *testing.T) {
+	k := MakeUserKey("upk", []string{"legacy"}, []byte{1}, 1)
+	err := k.Rekey(context.TODO(), &stubRepoErr[*UserKey]{})
+	assert.NotNil(t, err)
+	_, err = GetUserKey(context.TODO(), &stubRepoErr[*UserKey]{}, "upk")
+	assert.NotNil(t, err)
+	err = k.Erase(context.TODO(), &stubRepoErr[*UserKey]{})
+	assert.NotNil(t, err)
+}
+
+func testUserKeyPage(t *testing.T) {
+	k := UserKey{upk: "after", keyVersion: 2, limit: 10}
+	assert.Equal(t, []any{2, "after", 10}, k.GetByFilterArgs())
+	assert.Equal(t, UserKeySelectPageSQL, k.GetByFilterSQL())
+	got, err := GetUserKeysPage(context.TODO(), &stubRepoOk[*UserKey]{}, 2, "after", 10)
+	assert.Nil(t, err)
+	assert.Len(t, got, 1)
+	_, err = GetUserKeysPage(context.TODO(), &stubRepoErr[*UserKey]{}, 2, "after", 10)
+	assert.NotNil(t, err)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
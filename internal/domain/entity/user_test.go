@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-15 17:10 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-17 22:40 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * user_test.go
@@ -97,6 +97,10 @@ func testUserRepoOk(t *testing.T) {
 	err = user.Delete(context.TODO(), &stubRepoOk[*User]{})
 	assert.Nil(t, err)
 	assert.False(t, user.Deleted().Valid)
+	page, err := GetUsersPage(context.TODO(), &stubRepoOk[*User]{}, "", 10)
+	assert.Nil(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, []any{upk, 10}, (&User{upk: upk, limit: 10}).GetByFilterArgs())
 }
 
 func testUserRepoErr(t *testing.T) {
@@ -110,6 +114,8 @@ func testUserRepoErr(t *testing.T) {
 	assert.NotNil(t, err)
 	err = user.Delete(context.TODO(), &stubRepoErr[*User]{})
 	assert.NotNil(t, err)
+	_, err = GetUsersPage(context.TODO(), &stubRepoErr[*User]{}, upk, 10)
+	assert.NotNil(t, err)
 }
 
 //!-
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-18 16:47 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * memory.go
@@ -97,6 +97,20 @@ func (s *Storage) Delete(key string) error {
 	return nil
 }
 
+// DeleteFunc delete keys for which fn returns true, returns the number of deleted keys
+func (s *Storage) DeleteFunc(fn func(key string) bool) int {
+	deleted := 0
+	s.mux.Lock()
+	for key := range s.db {
+		if fn(key) {
+			delete(s.db, key)
+			deleted++
+		}
+	}
+	s.mux.Unlock()
+	return deleted
+}
+
 // Reset all keys
 func (s *Storage) Invalidate() error {
 	ndb := make(map[string]entry)
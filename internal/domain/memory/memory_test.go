@@ -119,6 +119,28 @@ func Test_Storage_Memory_Reset(t *testing.T) {
 	utils.AssertEqual(t, true, len(result) == 0)
 }
 
+func Test_Storage_Memory_DeleteFunc(t *testing.T) {
+	store := New()
+	defer func() { _ = store.Close() }()
+
+	utils.AssertEqual(t, nil, store.Set("isin1upk1", []byte("doe"), 0))
+	utils.AssertEqual(t, nil, store.Set("isin2upk1", []byte("doe"), 0))
+	utils.AssertEqual(t, nil, store.Set("isin1upk2", []byte("doe"), 0))
+
+	deleted := store.DeleteFunc(func(key string) bool {
+		return len(key) > 4 && key[len(key)-4:] == "upk1"
+	})
+	utils.AssertEqual(t, 2, deleted)
+
+	result, err := store.Get("isin1upk1")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, len(result) == 0)
+
+	result, err = store.Get("isin1upk2")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, []byte("doe"), result)
+}
+
 func Test_Storage_Memory_Close(t *testing.T) {
 	t.Parallel()
 	utils.AssertEqual(t, nil, testStore.Close())
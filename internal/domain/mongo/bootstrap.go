@@ -0,0 +1,171 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * bootstrap.go
+ * $Id$
+ */
+//!+
+
+// Package mongo TODO.
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodb "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// IndexUpkIsin уникальный индекс: не больше одного документа (upk, isin),
+	// на нём основан условный upsert Save и Delete.
+	IndexUpkIsin = "upk_isin"
+	// IndexTombstones частичный индекс надгробий для Compact.
+	IndexTombstones = "tombstones"
+	// namespaceExistsCode код ошибки MongoDB создания существующей коллекции.
+	namespaceExistsCode = 48
+)
+
+// Bootstrap подготовка коллекции избранного в MongoDB при старте: валидатор схемы
+// и индексы. Дубликаты (upk, isin), мешающие созданию уникального индекса,
+// удаляются. Для встроенных хранилищ и без клиента MongoDB ничего не делает.
+// Повторный вызов безопасен.
+func Bootstrap(ctx context.Context, prop env.Properties) error {
+
+	if storeName(prop) != StoreMongoDB || prop.MongodbClient() == nil {
+		return nil
+	}
+	return GetMongoRepo(prop).(*repo).bootstrap(ctx)
+}
+
+func (r *repo) bootstrap(ctx context.Context) error {
+
+	db, err := r.mongodbClient.Database(r.dbName)
+
+	if err != nil {
+		return err
+	}
+	// moderate: существующие документы не прошедшие проверку можно обновлять
+	// без ошибки, пока они не исправлены.
+	err = db.CreateCollection(ctx, Collection, options.CreateCollection().
+		SetValidator(favoritesValidator()).
+		SetValidationLevel("moderate"))
+
+	var ce mongodb.CommandError
+
+	if errors.As(err, &ce) && ce.HasErrorCode(namespaceExistsCode) {
+		err = db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: Collection},
+			{Key: "validator", Value: favoritesValidator()},
+			{Key: "validationLevel", Value: "moderate"},
+		}).Err()
+	}
+	if err != nil {
+		return err
+	}
+	collection := db.Collection(Collection)
+	names, err := collection.Indexes().CreateMany(ctx, favoritesIndexes())
+
+	// уникальный индекс не создаётся, пока в коллекции есть дубликаты (upk, isin).
+	if mongodb.IsDuplicateKeyError(err) {
+		var removed int64
+		if removed, err = dedupe(ctx, collection); err != nil {
+			return err
+		}
+		r.sLog.WarnContext(ctx, env.MSG+"MongoRepo.bootstrap", "msg", "duplicates removed", "count", removed)
+		names, err = collection.Indexes().CreateMany(ctx, favoritesIndexes())
+	}
+	if err != nil {
+		return err
+	}
+	r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.bootstrap", "indexes", names)
+
+	return nil
+}
+
+// dedupe удаление дубликатов (upk, isin): остаётся документ старшей версии,
+// при равных версиях — записанный позже.
+func dedupe(ctx context.Context, collection *mongodb.Collection) (int64, error) {
+
+	cur, err := collection.Aggregate(ctx, dedupePipeline(), options.Aggregate().SetAllowDiskUse(true))
+
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = cur.Close(ctx) }()
+
+	var removed int64
+
+	for cur.Next(ctx) {
+		var group struct {
+			IDs bson.A `bson:"ids"`
+		}
+		if err = cur.Decode(&group); err != nil {
+			return removed, err
+		}
+		result, err := collection.DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: group.IDs[1:]}}}})
+
+		if err != nil {
+			return removed, err
+		}
+		removed += result.DeletedCount
+	}
+	return removed, cur.Err()
+}
+
+// dedupePipeline группы дубликатов (upk, isin), идентификаторы документов
+// упорядочены от оставляемого к удаляемым.
+func dedupePipeline() mongodb.Pipeline {
+	return mongodb.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: Version, Value: -1}, {Key: WrittenAt, Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: UPK, Value: "$" + UPK}, {Key: ISIN, Value: "$" + ISIN}}},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+	}
+}
+
+// favoritesIndexes индексы коллекции избранного.
+func favoritesIndexes() []mongodb.IndexModel {
+	return []mongodb.IndexModel{
+		{
+			Keys:    bson.D{{Key: UPK, Value: 1}, {Key: ISIN, Value: 1}},
+			Options: options.Index().SetName(IndexUpkIsin).SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: Deleted, Value: 1}, {Key: DeletedAt, Value: 1}},
+			Options: options.Index().
+				SetName(IndexTombstones).
+				SetPartialFilterExpression(bson.D{{Key: Deleted, Value: true}}),
+		},
+	}
+}
+
+// favoritesValidator схема документа избранного ($jsonSchema).
+func favoritesValidator() bson.D {
+	return bson.D{{Key: "$jsonSchema", Value: bson.D{
+		{Key: "bsonType", Value: "object"},
+		{Key: "required", Value: bson.A{UPK, ISIN, Version}},
+		{Key: "properties", Value: bson.D{
+			{Key: UPK, Value: bson.D{{Key: "bsonType", Value: "string"}, {Key: "minLength", Value: 1}}},
+			{Key: ISIN, Value: bson.D{{Key: "bsonType", Value: "string"}, {Key: "minLength", Value: 1}}},
+			{Key: AssetType, Value: bson.D{{Key: "bsonType", Value: "string"}}},
+			{Key: Metadata, Value: bson.D{{Key: "bsonType", Value: "string"}}},
+			{Key: Version, Value: bson.D{{Key: "bsonType", Value: bson.A{"long", "int"}}, {Key: "minimum", Value: 0}}},
+			{Key: Deleted, Value: bson.D{{Key: "bsonType", Value: "bool"}}},
+			{Key: DeletedAt, Value: bson.D{{Key: "bsonType", Value: "date"}}},
+			{Key: AckedBy, Value: bson.D{{Key: "bsonType", Value: "array"}, {Key: "items", Value: bson.D{{Key: "bsonType", Value: "string"}}}}},
+			{Key: ShardID, Value: bson.D{{Key: "bsonType", Value: "string"}}},
+			{Key: WrittenAt, Value: bson.D{{Key: "bsonType", Value: "date"}}},
+		}},
+	}}}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,341 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * conformance_test.go
+ * $Id$
+ */
+//!+
+
+// Package mongo TODO.
+package mongo
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const conformanceShard = "shard-1"
+
+// conformanceStores реализации Mongo, проверяемые общим набором тестов.
+// MongoDB проверяется при заданной переменной окружения GO_FAVORITES_TEST_MONGODB_DSN.
+var conformanceStores = []struct {
+	name  string
+	store func(*testing.T) Mongo
+}{
+	{
+		name: StoreMemory,
+		store: func(_ *testing.T) Mongo {
+			return NewMemoryRepo(conformanceShard, slog.Default())
+		},
+	},
+	{
+		name: StoreBolt,
+		store: func(t *testing.T) Mongo {
+			store, err := NewBoltRepo(filepath.Join(t.TempDir(), "sync.db"), conformanceShard, slog.Default())
+			assert.Nil(t, err)
+			t.Cleanup(func() { _ = store.(*kvRepo).store.(*boltStore).db.Close() })
+			return store
+		},
+	},
+	{
+		name: StoreMongoDB,
+		store: func(t *testing.T) Mongo {
+			dsn := os.Getenv("GO_FAVORITES_TEST_MONGODB_DSN")
+			if dsn == "" {
+				t.Skip("GO_FAVORITES_TEST_MONGODB_DSN is not set")
+			}
+			return &repo{
+				dbName:        "conformance_" + tool.RandStringBytes(8),
+				mongodbClient: tool.MongodbConnect(dsn, tool.MongoOptions{}),
+				shardID:       conformanceShard,
+				sLog:          slog.Default(),
+			}
+		},
+	},
+}
+
+func TestStoreConformance(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T, Mongo)
+	}{
+		{name: "positive test #0 Save and Load", fRun: testConformanceSaveLoad},
+		{name: "positive test #1 Save older version is ignored", fRun: testConformanceSaveOlder},
+		{name: "positive test #2 Save same version updates metadata", fRun: testConformanceSaveSameVersion},
+		{name: "positive test #3 Delete writes tombstone", fRun: testConformanceDelete},
+		{name: "positive test #4 Delete older version is ignored", fRun: testConformanceDeleteOlder},
+		{name: "positive test #5 Delete unknown version", fRun: testConformanceDeleteUnknownVersion},
+		{name: "positive test #6 Delete absent record", fRun: testConformanceDeleteAbsent},
+		{name: "positive test #7 Save restores tombstone", fRun: testConformanceSaveRestores},
+		{name: "positive test #8 Acknowledge and Compact", fRun: testConformanceCompact},
+		{name: "positive test #9 users are isolated", fRun: testConformanceUsers},
+		{name: "positive test #10 SaveAll and DeleteAll", fRun: testConformanceBulk},
+		{name: "positive test #11 concurrent Save keeps one record", fRun: testConformanceConcurrentSave},
+		{name: "positive test #12 Save and Delete keep provenance", fRun: testConformanceProvenance},
+		{name: "positive test #13 Rekey moves records to new UPK", fRun: testConformanceRekey},
+		{name: "positive test #14 Erase removes records and tombstones", fRun: testConformanceErase},
+	}
+	for _, store := range conformanceStores {
+		t.Run(store.name, func(t *testing.T) {
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					test.fRun(t, store.store(t))
+				})
+			}
+		})
+	}
+}
+
+func testConformanceSaveLoad(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin1", 1, "a")))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin2", 1, "b")))
+	got := conformanceLoad(t, store, upk)
+	assert.Len(t, got, 2)
+	assert.Equal(t, "a", got["isin1"].Metadata())
+	assert.Equal(t, "STOCK", got["isin1"].Asset().AssetType().Name())
+	assert.Equal(t, sql.NullInt64{Int64: 1, Valid: true}, got["isin1"].Version())
+	assert.Equal(t, int64(1), got["isin1"].User().Version())
+	assert.False(t, got["isin1"].Deleted().Bool)
+	assert.Equal(t, "b", got["isin2"].Metadata())
+}
+
+func testConformanceSaveOlder(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 2, "b")))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 1, "a")))
+	got := conformanceLoad(t, store, upk)
+	assert.Equal(t, "b", got["isin"].Metadata())
+	assert.Equal(t, int64(2), got["isin"].Version().Int64)
+}
+
+func testConformanceSaveSameVersion(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 1, "a")))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 1, "b")))
+	got := conformanceLoad(t, store, upk)
+	assert.Len(t, got, 1)
+	assert.Equal(t, "b", got["isin"].Metadata())
+}
+
+func testConformanceDelete(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 1, "a")))
+	assert.Nil(t, store.Delete(context.TODO(), conformanceFavorites(upk, "isin", 2, "")))
+	got := conformanceLoad(t, store, upk)
+	assert.Len(t, got, 1)
+	assert.True(t, got["isin"].Deleted().Bool)
+	assert.Equal(t, int64(2), got["isin"].Version().Int64)
+	assert.True(t, got["isin"].UpdatedAt().Valid)
+	assert.Equal(t, "STOCK", got["isin"].Asset().AssetType().Name())
+}
+
+func testConformanceDeleteOlder(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 3, "a")))
+	assert.Nil(t, store.Delete(context.TODO(), conformanceFavorites(upk, "isin", 2, "")))
+	got := conformanceLoad(t, store, upk)
+	assert.False(t, got["isin"].Deleted().Bool)
+	assert.Equal(t, int64(3), got["isin"].Version().Int64)
+}
+
+func testConformanceDeleteUnknownVersion(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 3, "a")))
+	assert.Nil(t, store.Delete(context.TODO(), conformanceFavorites(upk, "isin", 0, "")))
+	got := conformanceLoad(t, store, upk)
+	assert.True(t, got["isin"].Deleted().Bool)
+	assert.Equal(t, int64(3), got["isin"].Version().Int64)
+}
+
+func testConformanceDeleteAbsent(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	assert.Nil(t, store.Delete(context.TODO(), conformanceFavorites(upk, "isin", 1, "")))
+	got := conformanceLoad(t, store, upk)
+	assert.Len(t, got, 1)
+	assert.True(t, got["isin"].Deleted().Bool)
+	assert.Equal(t, int64(1), got["isin"].Version().Int64)
+}
+
+func testConformanceSaveRestores(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 1, "a")))
+	assert.Nil(t, store.Delete(context.TODO(), conformanceFavorites(upk, "isin", 1, "")))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 1, "a")))
+	got := conformanceLoad(t, store, upk)
+	assert.False(t, got["isin"].Deleted().Bool)
+	assert.Equal(t, "a", got["isin"].Metadata())
+}
+
+func testConformanceCompact(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	shards := []string{conformanceShard, "shard-2"}
+	tombstone := conformanceFavorites(upk, "isin1", 1, "")
+	assert.Nil(t, store.Delete(context.TODO(), tombstone))
+	assert.Nil(t, store.Delete(context.TODO(), conformanceFavorites(upk, "isin2", 1, "")))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin3", 1, "a")))
+
+	deleted, err := store.Compact(context.TODO(), shards, time.Now().Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), deleted, "надгробие не подтверждено shard-2")
+
+	assert.Nil(t, store.Acknowledge(context.TODO(), []entity.Favorites{tombstone}, "shard-2"))
+	assert.Nil(t, store.Acknowledge(context.TODO(), []entity.Favorites{tombstone}, "shard-2"))
+	deleted, err = store.Compact(context.TODO(), shards, time.Now().Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), deleted, "надгробие моложе срока хранения")
+
+	deleted, err = store.Compact(context.TODO(), shards, time.Now().Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), deleted)
+	got := conformanceLoad(t, store, upk)
+	assert.Len(t, got, 2)
+	assert.True(t, got["isin2"].Deleted().Bool)
+	assert.False(t, got["isin3"].Deleted().Bool)
+}
+
+func testConformanceUsers(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", 1, "a")))
+	assert.Len(t, conformanceLoad(t, store, upk), 1)
+	assert.Len(t, conformanceLoad(t, store, tool.RandStringBytes(32)), 0)
+}
+
+func testConformanceBulk(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	other := tool.RandStringBytes(32)
+	assert.Nil(t, store.SaveAll(context.TODO(), nil))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin2", 3, "c")))
+	assert.Nil(t, store.SaveAll(context.TODO(), []entity.Favorites{
+		conformanceFavorites(upk, "isin1", 1, "a"),
+		conformanceFavorites(upk, "isin2", 2, "b"),
+		conformanceFavorites(other, "isin1", 1, "d"),
+	}))
+	got := conformanceLoad(t, store, upk)
+	assert.Len(t, got, 2)
+	assert.Equal(t, "a", got["isin1"].Metadata())
+	assert.Equal(t, "c", got["isin2"].Metadata(), "более новая версия не перезаписана")
+	assert.Len(t, conformanceLoad(t, store, other), 1)
+
+	assert.Nil(t, store.DeleteAll(context.TODO(), nil))
+	assert.Nil(t, store.DeleteAll(context.TODO(), []entity.Favorites{
+		conformanceFavorites(upk, "isin1", 2, ""),
+		conformanceFavorites(upk, "isin2", 2, ""),
+		conformanceFavorites(upk, "isin3", 1, ""),
+	}))
+	got = conformanceLoad(t, store, upk)
+	assert.Len(t, got, 3)
+	assert.True(t, got["isin1"].Deleted().Bool)
+	assert.False(t, got["isin2"].Deleted().Bool, "более новая версия не удалена")
+	assert.True(t, got["isin3"].Deleted().Bool)
+}
+
+func testConformanceConcurrentSave(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	var wg sync.WaitGroup
+
+	for i := int64(1); i <= 16; i++ {
+		wg.Add(1)
+		go func(version int64) {
+			defer wg.Done()
+			assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin", version, "a")))
+		}(i)
+	}
+	wg.Wait()
+	favorites, err := store.Load(context.TODO(), upk)
+	assert.Nil(t, err)
+	assert.Len(t, favorites, 1)
+	assert.Equal(t, int64(16), favorites[0].Version().Int64)
+}
+
+func testConformanceProvenance(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	writtenAt := time.Date(2024, 8, 18, 3, 0, 0, 0, time.UTC)
+	provenance := entity.MakeProvenance("shard-2", writtenAt)
+
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin1", 1, "a").WithProvenance(provenance)))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin2", 1, "b")))
+	got := conformanceLoad(t, store, upk)
+	assert.Equal(t, "shard-2", got["isin1"].Provenance().ShardID())
+	assert.True(t, writtenAt.Equal(got["isin1"].Provenance().WrittenAt().Time))
+	assert.Equal(t, conformanceShard, got["isin2"].Provenance().ShardID(), "запись без происхождения — этот шард")
+	assert.True(t, got["isin2"].Provenance().WrittenAt().Valid)
+
+	assert.Nil(t, store.Delete(context.TODO(), conformanceFavorites(upk, "isin2", 2, "").WithProvenance(provenance)))
+	got = conformanceLoad(t, store, upk)
+	assert.True(t, got["isin2"].Deleted().Bool)
+	assert.Equal(t, "shard-2", got["isin2"].Provenance().ShardID())
+}
+
+func conformanceFavorites(upk, isin string, version int64, metadata string) entity.Favorites {
+	return entity.MakeFavorites(
+		uuid.New(),
+		entity.MakeAsset(isin, entity.MakeAssetType("STOCK", entity.DefaultTAttributes()), entity.DefaultTAttributes()),
+		entity.MakeUserWithVersion(upk, version, entity.DefaultTAttributes()),
+		sql.NullInt64{Int64: version, Valid: version > 0},
+		entity.DefaultTAttributes(),
+	).WithMetadata(metadata)
+}
+
+func conformanceLoad(t *testing.T, store Mongo, upk string) map[string]entity.Favorites {
+
+	favorites, err := store.Load(context.TODO(), upk)
+	assert.Nil(t, err)
+	result := make(map[string]entity.Favorites, len(favorites))
+
+	for _, favorite := range favorites {
+		result[favorite.Asset().Isin()] = favorite
+	}
+	return result
+}
+
+func testConformanceRekey(t *testing.T, store Mongo) {
+	from := tool.RandStringBytes(32)
+	to := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(from, "isin1", 1, "a")))
+	assert.Nil(t, store.Delete(context.TODO(), conformanceFavorites(from, "isin2", 2, "")))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(from, "isin3", 1, "b")))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(to, "isin3", 3, "c")))
+	assert.Nil(t, store.Rekey(context.TODO(), from, to))
+	assert.Len(t, conformanceLoad(t, store, from), 0)
+	got := conformanceLoad(t, store, to)
+	assert.Len(t, got, 3)
+	assert.Equal(t, "a", got["isin1"].Metadata())
+	assert.Equal(t, to, got["isin1"].User().Upk())
+	assert.True(t, got["isin2"].Deleted().Bool)
+	assert.Equal(t, int64(2), got["isin2"].Version().Int64)
+	assert.Equal(t, "c", got["isin3"].Metadata(), "более новая версия не перезаписана")
+	assert.Nil(t, store.Rekey(context.TODO(), from, to))
+	assert.Len(t, conformanceLoad(t, store, to), 3)
+}
+
+func testConformanceErase(t *testing.T, store Mongo) {
+	upk := tool.RandStringBytes(32)
+	other := tool.RandStringBytes(32)
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(upk, "isin1", 1, "a")))
+	assert.Nil(t, store.Delete(context.TODO(), conformanceFavorites(upk, "isin2", 2, "")))
+	assert.Nil(t, store.Save(context.TODO(), conformanceFavorites(other, "isin1", 1, "b")))
+	deleted, err := store.Erase(context.TODO(), upk)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), deleted)
+	assert.Len(t, conformanceLoad(t, store, upk), 0)
+	assert.Len(t, conformanceLoad(t, store, other), 1)
+	deleted, err = store.Erase(context.TODO(), upk)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), deleted)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,303 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * kv.go
+ * $Id$
+ */
+//!+
+
+// Package mongo TODO.
+package mongo
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/env"
+)
+
+// kvStore документы избранного сгруппированные по пользователю,
+// основа встроенных реализаций Mongo. Документы пользователя в docs по ISIN.
+type kvStore interface {
+	// update изменение документов пользователя в одной транзакции.
+	update(upk string, fn func(docs map[string]favorites) error) error
+	// updateAll изменение документов всех пользователей.
+	updateAll(fn func(upk string, docs map[string]favorites) error) error
+	// view документы пользователя.
+	view(upk string) (map[string]favorites, error)
+}
+
+// kvRepo реализация Mongo поверх kvStore с теми же правилами версий
+// и надгробий (tombstones), что и у MongoDB.
+type kvRepo struct {
+	shardID string
+	sLog    *slog.Logger
+	store   kvStore
+}
+
+var _ Mongo = (*kvRepo)(nil)
+
+func (r *kvRepo) Acknowledge(ctx context.Context, tombstones []entity.Favorites, shard string) error {
+
+	if len(tombstones) < 1 || shard == "" {
+		return nil
+	}
+	if r.store == nil {
+		return ErrStoreUnavailable
+	}
+	return r.store.update(tombstones[0].User().Upk(), func(docs map[string]favorites) error {
+		for _, tombstone := range tombstones {
+			doc, ok := docs[tombstone.Asset().Isin()]
+			if ok && doc.Deleted && !slices.Contains(doc.AckedBy, shard) {
+				doc.AckedBy = append(slices.Clone(doc.AckedBy), shard)
+				docs[doc.Isin] = doc
+			}
+		}
+		r.sLog.DebugContext(ctx, env.MSG+"KVRepo.Acknowledge", "upk", tombstones[0].User().Upk(), "shard", shard)
+		return nil
+	})
+}
+
+func (r *kvRepo) Compact(ctx context.Context, shards []string, before time.Time) (int64, error) {
+
+	if len(shards) < 1 {
+		return 0, nil
+	}
+	if r.store == nil {
+		return 0, ErrStoreUnavailable
+	}
+	var deleted int64
+	err := r.store.updateAll(func(_ string, docs map[string]favorites) error {
+		for isin, doc := range docs {
+			if isCompactable(doc, shards, before) {
+				delete(docs, isin)
+				deleted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	r.sLog.DebugContext(ctx, env.MSG+"KVRepo.Compact", "deleted", deleted)
+
+	return deleted, nil
+}
+
+func (r *kvRepo) Delete(ctx context.Context, favorite entity.Favorites) error {
+	return r.DeleteAll(ctx, []entity.Favorites{favorite})
+}
+
+func (r *kvRepo) DeleteAll(ctx context.Context, entities []entity.Favorites) error {
+	return r.updateEach(entities, func(docs map[string]favorites, entity entity.Favorites) {
+
+		doc, ok := docs[entity.Asset().Isin()]
+		version := entity.Version().Int64
+
+		if ok && !entity.Version().Valid {
+			version = doc.Version
+		}
+		if ok && (doc.Deleted || doc.Version > version) {
+			return
+		}
+		tombstone := r.tombstoneDoc(entity, version)
+
+		if ok {
+			tombstone.AssetType = doc.AssetType
+		}
+		docs[tombstone.Isin] = tombstone
+		r.sLog.DebugContext(ctx, env.MSG+"KVRepo.Delete", "upk", tombstone.Upk, "isin", tombstone.Isin)
+	})
+}
+
+func (r *kvRepo) Erase(ctx context.Context, upk string) (int64, error) {
+
+	if r.store == nil {
+		return 0, ErrStoreUnavailable
+	}
+	var deleted int64
+	err := r.store.update(upk, func(docs map[string]favorites) error {
+		for isin := range docs {
+			delete(docs, isin)
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	r.sLog.DebugContext(ctx, env.MSG+"KVRepo.Erase", "deleted", deleted)
+
+	return deleted, nil
+}
+
+func (r *kvRepo) Load(_ context.Context, upk string) ([]entity.Favorites, error) {
+
+	result := make([]entity.Favorites, 0)
+
+	if r.store == nil {
+		return result, ErrStoreUnavailable
+	}
+	docs, err := r.store.view(upk)
+
+	if err != nil {
+		return result, err
+	}
+	sorted := make([]favorites, 0, len(docs))
+
+	for _, doc := range docs {
+		sorted = append(sorted, doc)
+	}
+	slices.SortFunc(sorted, func(x, y favorites) int {
+		return cmp.Compare(x.Isin, y.Isin)
+	})
+	for _, doc := range sorted {
+		result = append(result, doc.toEntity())
+	}
+	return result, nil
+}
+
+func (r *kvRepo) Rekey(ctx context.Context, from, to string) error {
+
+	if err := rekeyFavorites(ctx, r, from, to); err != nil {
+		return err
+	}
+	return r.store.update(from, func(docs map[string]favorites) error {
+		for isin := range docs {
+			delete(docs, isin)
+		}
+		r.sLog.DebugContext(ctx, env.MSG+"KVRepo.Rekey", "from", from, "to", to)
+		return nil
+	})
+}
+
+func (r *kvRepo) Save(ctx context.Context, favorite entity.Favorites) error {
+	return r.SaveAll(ctx, []entity.Favorites{favorite})
+}
+
+func (r *kvRepo) SaveAll(ctx context.Context, entities []entity.Favorites) error {
+	return r.updateEach(entities, func(docs map[string]favorites, entity entity.Favorites) {
+
+		doc, ok := docs[entity.Asset().Isin()]
+		version := entity.Version().Int64
+
+		// надгробие той же версии заменяется: запись восстановлена после удаления.
+		if ok && doc.Version > version ||
+			ok && doc.Version == version && !doc.Deleted && doc.Metadata == entity.Metadata() {
+			return
+		}
+		shardID, writtenAt := provenance(entity, r.shardID)
+		docs[entity.Asset().Isin()] = favorites{
+			Upk:       entity.User().Upk(),
+			Isin:      entity.Asset().Isin(),
+			AssetType: entity.Asset().AssetType().Name(),
+			Metadata:  entity.Metadata(),
+			Version:   version,
+			ShardID:   shardID,
+			WrittenAt: writtenAt,
+		}
+		r.sLog.DebugContext(ctx, env.MSG+"KVRepo.Save", "upk", entity.User().Upk(), "isin", entity.Asset().Isin())
+	})
+}
+
+// updateEach применение fn к каждой записи entities,
+// записи одного пользователя изменяются в одной транзакции.
+func (r *kvRepo) updateEach(entities []entity.Favorites, fn func(docs map[string]favorites, entity entity.Favorites)) error {
+
+	if r.store == nil {
+		return ErrStoreUnavailable
+	}
+	upks := make([]string, 0)
+	byUpk := make(map[string][]entity.Favorites)
+
+	for _, entity := range entities {
+		upk := entity.User().Upk()
+		if _, ok := byUpk[upk]; !ok {
+			upks = append(upks, upk)
+		}
+		byUpk[upk] = append(byUpk[upk], entity)
+	}
+	for _, upk := range upks {
+		err := r.store.update(upk, func(docs map[string]favorites) error {
+			for _, entity := range byUpk[upk] {
+				fn(docs, entity)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tombstoneDoc документ надгробия, подтверждён этим шардом если он задан.
+func (r *kvRepo) tombstoneDoc(entity entity.Favorites, version int64) favorites {
+
+	ackedBy := make([]string, 0, 1)
+
+	if r.shardID != "" {
+		ackedBy = append(ackedBy, r.shardID)
+	}
+	shardID, writtenAt := provenance(entity, r.shardID)
+
+	return favorites{
+		Upk:       entity.User().Upk(),
+		Isin:      entity.Asset().Isin(),
+		AssetType: entity.Asset().AssetType().Name(),
+		Version:   version,
+		Deleted:   true,
+		DeletedAt: time.Now().UTC(),
+		AckedBy:   ackedBy,
+		ShardID:   shardID,
+		WrittenAt: writtenAt,
+	}
+}
+
+// rekeyFavorites запись избранного и надгробий пользователя from от имени to,
+// удаление документов from остаётся за реализацией.
+func rekeyFavorites(ctx context.Context, store Mongo, from, to string) error {
+
+	entities, err := store.Load(ctx, from)
+
+	if err != nil {
+		return err
+	}
+	live := make([]entity.Favorites, 0, len(entities))
+	tombstones := make([]entity.Favorites, 0)
+
+	for _, favorite := range entities {
+		user := entity.MakeUserWithVersion(to, favorite.User().Version(), entity.DefaultTAttributes())
+		if favorite.Deleted().Bool {
+			tombstones = append(tombstones, favorite.WithUser(user))
+		} else {
+			live = append(live, favorite.WithUser(user))
+		}
+	}
+	if err = store.SaveAll(ctx, live); err != nil {
+		return err
+	}
+	return store.DeleteAll(ctx, tombstones)
+}
+
+// isCompactable надгробие подтверждено всеми шардами shards и создано раньше before.
+func isCompactable(doc favorites, shards []string, before time.Time) bool {
+
+	if !doc.Deleted || !doc.DeletedAt.Before(before) {
+		return false
+	}
+	for _, shard := range shards {
+		if !slices.Contains(doc.AckedBy, shard) {
+			return false
+		}
+	}
+	return true
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
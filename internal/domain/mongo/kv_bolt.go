@@ -0,0 +1,195 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * kv_bolt.go
+ * $Id$
+ */
+//!+
+
+// Package mongo TODO.
+package mongo
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	boltDefaultPath = "go-favorites-sync.db"
+	boltOpenTimeout = time.Second
+)
+
+// boltStore встроенное хранилище документов на диске: корзина (bucket) Collection,
+// в ней вложенная корзина на пользователя с документами по ISIN в JSON.
+type boltStore struct {
+	db *bolt.DB
+}
+
+var _ kvStore = (*boltStore)(nil)
+var (
+	onceBolt = new(sync.Once)
+	boltErr  error
+	boltRepo Mongo
+)
+
+// GetBoltRepo встроенное хранилище синхронизации на диске (sync.store_path),
+// для одного узла и разработки без MongoDB. Если файл открыть не удалось,
+// хранилище недоступно (ErrStoreUnavailable), а ошибку возвращает CheckStore.
+func GetBoltRepo(prop env.Properties) Mongo {
+	onceBolt.Do(func() {
+		path := prop.Config().SyncStorePath()
+
+		if path == "" {
+			path = boltDefaultPath
+		}
+		repo, err := NewBoltRepo(path, prop.Config().SyncShardID(), prop.Logger())
+
+		if err != nil {
+			prop.Logger().Error(env.MSG+"GetBoltRepo", "path", path, "err", err)
+			boltErr = fmt.Errorf("%w: %s: %w", ErrStoreUnavailable, path, err)
+			repo = &kvRepo{shardID: prop.Config().SyncShardID(), sLog: prop.Logger()}
+		}
+		boltRepo = repo
+	})
+	return boltRepo
+}
+
+// closeBolt закрытие файла встроенного хранилища, открытого GetBoltRepo.
+func closeBolt(prop env.Properties) error {
+
+	if kv, ok := GetBoltRepo(prop).(*kvRepo); ok {
+		if b, ok := kv.store.(*boltStore); ok {
+			return b.db.Close()
+		}
+	}
+	return nil
+}
+
+// NewBoltRepo хранилище синхронизации в файле path, файл создаётся при отсутствии.
+func NewBoltRepo(path, shardID string, sLog *slog.Logger) (Mongo, error) {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(Collection))
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &kvRepo{shardID: shardID, sLog: sLog, store: &boltStore{db: db}}, nil
+}
+
+func (b *boltStore) update(upk string, fn func(docs map[string]favorites) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return updateBoltDocs(tx.Bucket([]byte(Collection)), upk, fn)
+	})
+}
+
+func (b *boltStore) updateAll(fn func(upk string, docs map[string]favorites) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+
+		root := tx.Bucket([]byte(Collection))
+		upks := make([]string, 0)
+
+		err := root.ForEachBucket(func(k []byte) error {
+			upks = append(upks, string(k))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, upk := range upks {
+			err = updateBoltDocs(root, upk, func(docs map[string]favorites) error {
+				return fn(upk, docs)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltStore) view(upk string) (result map[string]favorites, err error) {
+
+	err = b.db.View(func(tx *bolt.Tx) error {
+		result, err = readBoltDocs(tx.Bucket([]byte(Collection)).Bucket([]byte(upk)))
+		return err
+	})
+	return result, err
+}
+
+// updateBoltDocs перезапись корзины пользователя документами после fn,
+// корзина без документов удаляется.
+func updateBoltDocs(root *bolt.Bucket, upk string, fn func(docs map[string]favorites) error) error {
+
+	docs, err := readBoltDocs(root.Bucket([]byte(upk)))
+
+	if err != nil {
+		return err
+	}
+	if err = fn(docs); err != nil {
+		return err
+	}
+	if root.Bucket([]byte(upk)) != nil {
+		if err = root.DeleteBucket([]byte(upk)); err != nil {
+			return err
+		}
+	}
+	if len(docs) < 1 {
+		return nil
+	}
+	bucket, err := root.CreateBucket([]byte(upk))
+
+	if err != nil {
+		return err
+	}
+	for isin, doc := range docs {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if err = bucket.Put([]byte(isin), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readBoltDocs(bucket *bolt.Bucket) (map[string]favorites, error) {
+
+	result := make(map[string]favorites)
+
+	if bucket == nil {
+		return result, nil
+	}
+	err := bucket.ForEach(func(k, v []byte) error {
+		var doc favorites
+		if err := json.Unmarshal(v, &doc); err != nil {
+			return err
+		}
+		result[string(k)] = doc
+		return nil
+	})
+	return result, err
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,111 @@
+/*
+ * This file was last modified at 2024-08-18 00:20 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * kv_memory.go
+ * $Id$
+ */
+//!+
+
+// Package mongo TODO.
+package mongo
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/vskurikhin/gofavorites/internal/env"
+)
+
+// memoryStore хранилище документов в памяти процесса.
+type memoryStore struct {
+	db  map[string]map[string]favorites
+	mux sync.RWMutex
+}
+
+var _ kvStore = (*memoryStore)(nil)
+var (
+	onceMemory = new(sync.Once)
+	memoryRepo Mongo
+)
+
+// GetMemoryRepo хранилище синхронизации в памяти процесса, для тестов:
+// данные не переживают перезапуск и не видны другим шардам.
+func GetMemoryRepo(prop env.Properties) Mongo {
+	onceMemory.Do(func() {
+		memoryRepo = NewMemoryRepo(prop.Config().SyncShardID(), prop.Logger())
+	})
+	return memoryRepo
+}
+
+// NewMemoryRepo новое пустое хранилище синхронизации в памяти процесса.
+func NewMemoryRepo(shardID string, sLog *slog.Logger) Mongo {
+	return &kvRepo{
+		shardID: shardID,
+		sLog:    sLog,
+		store:   &memoryStore{db: make(map[string]map[string]favorites)},
+	}
+}
+
+func (m *memoryStore) update(upk string, fn func(docs map[string]favorites) error) error {
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	docs := cloneDocs(m.db[upk])
+
+	if err := fn(docs); err != nil {
+		return err
+	}
+	m.put(upk, docs)
+
+	return nil
+}
+
+func (m *memoryStore) updateAll(fn func(upk string, docs map[string]favorites) error) error {
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for upk, stored := range m.db {
+		docs := cloneDocs(stored)
+		if err := fn(upk, docs); err != nil {
+			return err
+		}
+		m.put(upk, docs)
+	}
+	return nil
+}
+
+func (m *memoryStore) view(upk string) (map[string]favorites, error) {
+
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	return cloneDocs(m.db[upk]), nil
+}
+
+func (m *memoryStore) put(upk string, docs map[string]favorites) {
+
+	if len(docs) < 1 {
+		delete(m.db, upk)
+	} else {
+		m.db[upk] = docs
+	}
+}
+
+// cloneDocs копия документов, изменения в fn не видны до успешного завершения.
+func cloneDocs(docs map[string]favorites) map[string]favorites {
+
+	result := make(map[string]favorites, len(docs))
+
+	for isin, doc := range docs {
+		doc.AckedBy = slices.Clone(doc.AckedBy)
+		result[isin] = doc
+	}
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
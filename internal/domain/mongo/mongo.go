@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-04 22:13 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * mongo.go
@@ -13,9 +13,10 @@ package mongo
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/vskurikhin/gofavorites/internal/tool"
 
@@ -24,34 +25,86 @@ import (
 	"github.com/vskurikhin/gofavorites/internal/env"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
-	Collection = "favorites"
-	AssetType  = "asset-type"
-	UPK        = "upk"
-	ISIN       = "isin"
-	Version    = "version"
+	Collection      = "favorites"
+	NotesCollection = "notes"
+	AckedBy         = "acked_by"
+	AssetType       = "asset-type"
+	Body            = "body"
+	Deleted         = "deleted"
+	DeletedAt       = "deleted_at"
+	UPK             = "upk"
+	ISIN            = "isin"
+	Metadata        = "metadata"
+	Name            = "name"
+	ShardID         = "shard_id"
+	Version         = "version"
+	WrittenAt       = "written_at"
 )
 
+// duplicateKeyCode код ошибки MongoDB нарушения уникального индекса.
+const duplicateKeyCode = 11000
+
+// Mongo реплика избранного в хранилище синхронизации, см. GetStore.
+// Удаление хранится как надгробие (tombstone):
+// документ с флагом deleted, временем удаления и версией удаления,
+// чтобы шард не видевший удаления мог отличить «удалено» от «никогда не было».
 type Mongo interface {
+	// Acknowledge отметка шардом shard применения надгробий tombstones.
+	Acknowledge(ctx context.Context, tombstones []entity.Favorites, shard string) error
+	// Compact удаление надгробий подтверждённых всеми шардами shards и созданных раньше before.
+	Compact(ctx context.Context, shards []string, before time.Time) (int64, error)
+	// Delete запись надгробия, если в MongoDB нет более новой версии записи.
 	Delete(ctx context.Context, entity entity.Favorites) error
+	// DeleteAll запись надгробий одним пакетом, правила версий как у Delete.
+	DeleteAll(ctx context.Context, entities []entity.Favorites) error
+	// Erase удаление всех документов пользователя вместе с надгробиями без записи
+	// новых надгробий (удаление данных по запросу пользователя), возвращает количество документов.
+	Erase(ctx context.Context, upk string) (int64, error)
+	// Load избранное пользователя вместе с надгробиями (Deleted).
 	Load(ctx context.Context, upk string) ([]entity.Favorites, error)
+	// Rekey перенос избранного пользователя с UPK from на UPK to,
+	// записи переносятся по правилам версий Save и Delete.
+	Rekey(ctx context.Context, from, to string) error
+	// Save запись избранного, если в MongoDB нет более новой версии записи.
 	Save(ctx context.Context, entity entity.Favorites) error
+	// SaveAll запись избранного одним пакетом, правила версий как у Save.
+	SaveAll(ctx context.Context, entities []entity.Favorites) error
+}
+
+type Notes interface {
+	Delete(ctx context.Context, entity entity.Note) error
+	// Erase удаление всех заметок пользователя, возвращает количество документов.
+	Erase(ctx context.Context, upk string) (int64, error)
+	Load(ctx context.Context, upk string) ([]entity.Note, error)
+	// Rekey перенос заметок пользователя с UPK from на UPK to.
+	Rekey(ctx context.Context, from, to string) error
+	Save(ctx context.Context, entity entity.Note) error
 }
 
 type repo struct {
-	dbName      string
-	mongodbPool *tool.MongoPool
-	sLog        *slog.Logger
+	dbName        string
+	mongodbClient *tool.MongoClient
+	shardID       string
+	sLog          *slog.Logger
 }
 
 type favorites struct {
-	ID        primitive.ObjectID `bson:"_id"`
-	Upk       string             `bson:"upk"`
-	Isin      string             `bson:"isin"`
-	AssetType string             `bson:"asset-type"`
-	Version   int64              `bson:"version"`
+	ID        primitive.ObjectID `bson:"_id" json:"-"`
+	Upk       string             `bson:"upk" json:"upk"`
+	Isin      string             `bson:"isin" json:"isin"`
+	AssetType string             `bson:"asset-type" json:"asset-type"`
+	Metadata  string             `bson:"metadata" json:"metadata"`
+	Version   int64              `bson:"version" json:"version"`
+	Deleted   bool               `bson:"deleted,omitempty" json:"deleted,omitempty"`
+	DeletedAt time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	AckedBy   []string           `bson:"acked_by,omitempty" json:"acked_by,omitempty"`
+	ShardID   string             `bson:"shard_id,omitempty" json:"shard_id,omitempty"`
+	WrittenAt time.Time          `bson:"written_at,omitempty" json:"written_at,omitempty"`
 }
 
 var _ Mongo = (*repo)(nil)
@@ -64,125 +117,359 @@ func GetMongoRepo(prop env.Properties) Mongo {
 	onceMongo.Do(func() {
 		mongoRepo = new(repo)
 		mongoRepo.dbName = prop.Config().MongoName()
-		mongoRepo.mongodbPool = prop.MongodbPool()
+		mongoRepo.mongodbClient = prop.MongodbClient()
+		mongoRepo.shardID = prop.Config().SyncShardID()
 		mongoRepo.sLog = prop.Logger()
 	})
 	return mongoRepo
 }
 
-func (r *repo) Delete(ctx context.Context, entity entity.Favorites) error {
+func (r *repo) Acknowledge(ctx context.Context, tombstones []entity.Favorites, shard string) error {
 
-	conn, err := r.mongodbPool.GetConnection()
+	if len(tombstones) < 1 || shard == "" {
+		return nil
+	}
+	collection, err := r.mongodbClient.Collection(r.dbName, Collection)
 
 	if err != nil {
 		return err
 	}
-	defer func() { _ = r.mongodbPool.CloseConnection(conn) }()
+	isins := make([]string, 0, len(tombstones))
 
-	collection := tool.GetCollection(conn, r.dbName, Collection)
-	res, err := collection.DeleteOne(ctx, bson.D{
-		{Key: UPK, Value: entity.User().Upk()},
-		{Key: ISIN, Value: entity.Asset().Isin()},
+	for _, tombstone := range tombstones {
+		isins = append(isins, tombstone.Asset().Isin())
+	}
+	res, err := collection.UpdateMany(ctx,
+		bson.D{
+			{Key: UPK, Value: tombstones[0].User().Upk()},
+			{Key: ISIN, Value: bson.D{{Key: "$in", Value: isins}}},
+			{Key: Deleted, Value: true},
+			{Key: AckedBy, Value: bson.D{{Key: "$ne", Value: shard}}},
+		},
+		bson.D{{Key: "$addToSet", Value: bson.D{{Key: AckedBy, Value: shard}}}},
+	)
+	if err != nil {
+		return err
+	}
+	r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Acknowledge", "res.ModifiedCount", res.ModifiedCount)
+
+	return nil
+}
+
+func (r *repo) Compact(ctx context.Context, shards []string, before time.Time) (int64, error) {
+
+	if len(shards) < 1 {
+		return 0, nil
+	}
+	collection, err := r.mongodbClient.Collection(r.dbName, Collection)
+
+	if err != nil {
+		return 0, err
+	}
+	res, err := collection.DeleteMany(ctx, bson.D{
+		{Key: Deleted, Value: true},
+		{Key: DeletedAt, Value: bson.D{{Key: "$lt", Value: before}}},
+		{Key: AckedBy, Value: bson.D{{Key: "$all", Value: shards}}},
 	})
-	fmt.Printf("Number of documents deleted: %d\n", res.DeletedCount)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
 
-	return err
+func (r *repo) Delete(ctx context.Context, entity entity.Favorites) error {
+
+	collection, err := r.mongodbClient.Collection(r.dbName, Collection)
+
+	if err != nil {
+		return err
+	}
+	filter, update := r.deleteModel(entity)
+	res, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+
+	if err = ignoreDuplicateKey(err); err != nil {
+		r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Delete collection.UpdateOne", "err", err)
+		return err
+	}
+	if res != nil {
+		r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Delete", "res.ModifiedCount", res.ModifiedCount, "res.UpsertedID", res.UpsertedID)
+	}
+	return nil
+}
+
+func (r *repo) DeleteAll(ctx context.Context, entities []entity.Favorites) error {
+
+	models := make([]mongodb.WriteModel, 0, len(entities))
+
+	for _, entity := range entities {
+		filter, update := r.deleteModel(entity)
+		models = append(models, mongodb.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+	return r.bulkWrite(ctx, "MongoRepo.DeleteAll", models)
+}
+
+func (r *repo) Erase(ctx context.Context, upk string) (int64, error) {
+
+	collection, err := r.mongodbClient.Collection(r.dbName, Collection)
+
+	if err != nil {
+		return 0, err
+	}
+	res, err := collection.DeleteMany(ctx, bson.D{{Key: UPK, Value: upk}})
+
+	if err != nil {
+		return 0, err
+	}
+	r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Erase", "res.DeletedCount", res.DeletedCount)
+
+	return res.DeletedCount, nil
 }
 
 func (r *repo) Load(ctx context.Context, upk string) ([]entity.Favorites, error) {
 
-	conn, err := r.mongodbPool.GetConnection()
+	collection, err := r.mongodbClient.Collection(r.dbName, Collection)
 	result := make([]entity.Favorites, 0)
 
 	if err != nil {
 		return result, err
 	}
-	defer func() { _ = r.mongodbPool.CloseConnection(conn) }()
-
-	collection := tool.GetCollection(conn, r.dbName, Collection)
 	cur, err := collection.Find(ctx, bson.D{
 		{Key: UPK, Value: upk},
 	})
 	if err != nil {
 		return result, err
 	}
+	defer func() { _ = cur.Close(ctx) }()
+
 	for cur.Next(ctx) {
 		// To decode into a struct, use cursor.Decode()
 		var fav favorites
 		err = cur.Decode(&fav)
 
 		if err != nil {
-			r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Save", "cur.Decode(&result)", err)
+			r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Load", "cur.Decode(&result)", err)
 			return result, err
 		}
-		at := entity.MakeAssetType(fav.AssetType, entity.DefaultTAttributes())
-		us := entity.MakeUserWithVersion(fav.Upk, fav.Version, entity.DefaultTAttributes())
-		as := entity.MakeAsset(fav.Isin, at, entity.DefaultTAttributes())
-		vn := sql.NullInt64{Int64: fav.Version, Valid: true}
-		fv := entity.MakeFavorites(uuid.Max, as, us, vn, entity.DefaultTAttributes())
-		result = append(result, fv)
+		result = append(result, fav.toEntity())
+	}
+	if err = cur.Err(); err != nil {
+		r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Load", "cur.Err()", err)
+		return result, err
 	}
 	return result, nil
 }
 
+func (r *repo) Rekey(ctx context.Context, from, to string) error {
+
+	if err := rekeyFavorites(ctx, r, from, to); err != nil {
+		return err
+	}
+	collection, err := r.mongodbClient.Collection(r.dbName, Collection)
+
+	if err != nil {
+		return err
+	}
+	res, err := collection.DeleteMany(ctx, bson.D{{Key: UPK, Value: from}})
+
+	if err != nil {
+		return err
+	}
+	r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Rekey", "res.DeletedCount", res.DeletedCount)
+
+	return nil
+}
+
 func (r *repo) Save(ctx context.Context, entity entity.Favorites) error {
 
-	conn, err := r.mongodbPool.GetConnection()
+	collection, err := r.mongodbClient.Collection(r.dbName, Collection)
 
 	if err != nil {
 		return err
 	}
-	defer func() { _ = r.mongodbPool.CloseConnection(conn) }()
+	filter, update := r.saveModel(entity)
+	res, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
 
-	collection := tool.GetCollection(conn, r.dbName, Collection)
-	cur, err := collection.Find(ctx, bson.D{
-		{Key: UPK, Value: entity.User().Upk()},
-		{Key: ISIN, Value: entity.Asset().Isin()},
-	})
+	if err = ignoreDuplicateKey(err); err != nil {
+		r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Save collection.UpdateOne", "err", err)
+		return err
+	}
+	if res != nil {
+		r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Save", "res.ModifiedCount", res.ModifiedCount, "res.UpsertedID", res.UpsertedID)
+	}
+	return nil
+}
+
+func (r *repo) SaveAll(ctx context.Context, entities []entity.Favorites) error {
+
+	models := make([]mongodb.WriteModel, 0, len(entities))
+
+	for _, entity := range entities {
+		filter, update := r.saveModel(entity)
+		models = append(models, mongodb.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+	return r.bulkWrite(ctx, "MongoRepo.SaveAll", models)
+}
+
+// bulkWrite неупорядоченная пакетная запись models одним запросом.
+func (r *repo) bulkWrite(ctx context.Context, msg string, models []mongodb.WriteModel) error {
+
+	if len(models) < 1 {
+		return nil
+	}
+	collection, err := r.mongodbClient.Collection(r.dbName, Collection)
 
 	if err != nil {
 		return err
 	}
-	defer func() { _ = cur.Close(ctx) }()
+	res, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+
+	if err = ignoreDuplicateKey(err); err != nil {
+		r.sLog.DebugContext(ctx, env.MSG+msg+" collection.BulkWrite", "err", err)
+		return err
+	}
+	if res != nil {
+		r.sLog.DebugContext(ctx, env.MSG+msg, "res.ModifiedCount", res.ModifiedCount, "res.UpsertedCount", res.UpsertedCount)
+	}
+	return nil
+}
+
+// saveModel условный upsert записи: применяется только к документу с меньшей версией,
+// к надгробию той же версии (запись восстановлена после удаления) или к той же версии
+// с другими метаданными. Иначе upsert пытается вставить второй документ (upk, isin)
+// и получает ошибку уникального индекса — в хранилище уже более новая версия.
+func (r *repo) saveModel(entity entity.Favorites) (filter, update bson.D) {
+
+	shardID, writtenAt := provenance(entity, r.shardID)
+	version := entity.Version().Int64
+	filter = bson.D{
+		{Key: UPK, Value: entity.User().Upk()},
+		{Key: ISIN, Value: entity.Asset().Isin()},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: Version, Value: bson.D{{Key: "$lt", Value: version}}}},
+			bson.D{{Key: Version, Value: version}, {Key: Deleted, Value: true}},
+			bson.D{{Key: Version, Value: version}, {Key: Metadata, Value: bson.D{{Key: "$ne", Value: entity.Metadata()}}}},
+		}},
+	}
+	update = bson.D{
+		{Key: "$set",
+			Value: bson.D{
+				{Key: AssetType, Value: entity.Asset().AssetType().Name()},
+				{Key: Metadata, Value: entity.Metadata()},
+				{Key: ShardID, Value: shardID},
+				{Key: Version, Value: version},
+				{Key: WrittenAt, Value: writtenAt},
+			}},
+		{Key: "$unset",
+			Value: bson.D{
+				{Key: Deleted, Value: ""},
+				{Key: DeletedAt, Value: ""},
+				{Key: AckedBy, Value: ""},
+			}},
+	}
+	return filter, update
+}
+
+// deleteModel условный upsert надгробия: применяется к неудалённому документу
+// не новее версии удаления. Версия удаления неизвестна — запись удалена в PostgreSQL
+// целиком, надгробие сохраняет текущую версию документа.
+func (r *repo) deleteModel(entity entity.Favorites) (filter, update bson.D) {
 
-	if cur.RemainingBatchLength() == 0 {
-		res, err := collection.InsertOne(ctx, bson.D{
-			{Key: UPK, Value: entity.User().Upk()},
-			{Key: ISIN, Value: entity.Asset().Isin()},
-			{Key: AssetType, Value: entity.Asset().AssetType().Name()},
-			{Key: Version, Value: entity.Version().Int64},
-		})
-		r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Save", "res.InsertedID", res.InsertedID, "err", err)
+	filter = bson.D{
+		{Key: UPK, Value: entity.User().Upk()},
+		{Key: ISIN, Value: entity.Asset().Isin()},
+		{Key: Deleted, Value: bson.D{{Key: "$ne", Value: true}}},
+	}
+	shardID, writtenAt := provenance(entity, r.shardID)
+	tombstone := append(r.tombstone(), bson.E{Key: ShardID, Value: shardID}, bson.E{Key: WrittenAt, Value: writtenAt})
+	onInsert := bson.D{{Key: AssetType, Value: entity.Asset().AssetType().Name()}}
+
+	if entity.Version().Valid {
+		filter = append(filter, bson.E{Key: Version, Value: bson.D{{Key: "$lte", Value: entity.Version().Int64}}})
+		tombstone = append(tombstone, bson.E{Key: Version, Value: entity.Version().Int64})
 	} else {
-		for cur.Next(ctx) {
-			// To decode into a struct, use cursor.Decode()
-			var result favorites
-			err := cur.Decode(&result)
+		onInsert = append(onInsert, bson.E{Key: Version, Value: entity.Version().Int64})
+	}
+	update = bson.D{
+		{Key: "$set", Value: tombstone},
+		{Key: "$setOnInsert", Value: onInsert},
+	}
+	return filter, update
+}
 
-			if err != nil {
-				r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Save cur.Decode", "err", err)
+// ignoreDuplicateKey ошибка записи без нарушений уникального индекса (upk, isin):
+// условный upsert не применился, так как в хранилище уже более новая версия.
+func ignoreDuplicateKey(err error) error {
+
+	var bwe mongodb.BulkWriteException
+
+	if errors.As(err, &bwe) {
+		if bwe.WriteConcernError != nil {
+			return err
+		}
+		for _, we := range bwe.WriteErrors {
+			if !we.HasErrorCode(duplicateKeyCode) {
 				return err
 			}
-			if result.Version < entity.Version().Int64 {
-				filter := bson.D{{Key: "_id", Value: result.ID}}
-				update := bson.D{{Key: "$set",
-					Value: bson.D{
-						{Key: UPK, Value: entity.User().Upk()},
-						{Key: ISIN, Value: entity.Asset().Isin()},
-						{Key: AssetType, Value: entity.Asset().AssetType().Name()},
-						{Key: Version, Value: entity.Version().Int64},
-					}},
-				}
-				res, err := collection.UpdateOne(ctx, filter, update)
-				if err != nil {
-					r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Save collection.UpdateOne", "err", err)
-					return err
-				}
-				r.sLog.DebugContext(ctx, env.MSG+"MongoRepo.Save", "res.UpsertedID", res.UpsertedID, "err", err)
-			}
 		}
+		return nil
+	}
+	if mongodb.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// provenance шард и время записи документа: происхождение записи entity,
+// для записи без происхождения — шард shardID и текущее время.
+func provenance(entity entity.Favorites, shardID string) (string, time.Time) {
+
+	shard, writtenAt := entity.Provenance().ShardID(), entity.Provenance().WrittenAt()
+
+	if shard == "" {
+		shard = shardID
+	}
+	if !writtenAt.Valid {
+		return shard, time.Now().UTC()
+	}
+	return shard, writtenAt.Time.UTC()
+}
+
+// toEntity запись избранного из документа, надгробие — удалённая запись
+// со временем изменения равным времени удаления.
+func (f favorites) toEntity() entity.Favorites {
+
+	at := entity.MakeAssetType(f.AssetType, entity.DefaultTAttributes())
+	us := entity.MakeUserWithVersion(f.Upk, f.Version, entity.DefaultTAttributes())
+	as := entity.MakeAsset(f.Isin, at, entity.DefaultTAttributes())
+	vn := sql.NullInt64{Int64: f.Version, Valid: true}
+	ta := entity.DefaultTAttributes()
+
+	if f.Deleted {
+		ta = entity.MakeTAttributes(
+			sql.NullBool{Bool: true, Valid: true},
+			time.Time{},
+			sql.NullTime{Time: f.DeletedAt, Valid: !f.DeletedAt.IsZero()},
+		)
+	}
+	return entity.MakeFavorites(uuid.Max, as, us, vn, ta).
+		WithMetadata(f.Metadata).
+		WithProvenance(entity.MakeProvenance(f.ShardID, f.WrittenAt))
+}
+
+// tombstone поля надгробия, подтверждён этим шардом если он задан.
+func (r *repo) tombstone() bson.D {
+
+	ackedBy := make([]string, 0, 1)
+
+	if r.shardID != "" {
+		ackedBy = append(ackedBy, r.shardID)
+	}
+	return bson.D{
+		{Key: Metadata, Value: ""},
+		{Key: Deleted, Value: true},
+		{Key: DeletedAt, Value: time.Now().UTC()},
+		{Key: AckedBy, Value: ackedBy},
 	}
-	return nil
 }
 
 //!-
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-04 22:13 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * mongo_test.go
@@ -12,11 +12,15 @@ package mongo
 
 import (
 	"context"
+	"errors"
 	"github.com/vskurikhin/gofavorites/internal/domain/entity"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vskurikhin/gofavorites/internal/env"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodb "go.mongodb.org/mongo-driver/mongo"
 )
 
 func TestFavoritesInsertsBatch(t *testing.T) {
@@ -36,6 +40,40 @@ func TestFavoritesInsertsBatch(t *testing.T) {
 			name: "test #2 negative",
 			fRun: testMongoSaveNegative,
 		},
+		{
+			name: "test #4 negative notes",
+			fRun: testMongoNotesNegative,
+		},
+		{
+			name: "test #5 negative tombstones",
+			fRun: testMongoTombstonesNegative,
+		},
+		{
+			name: "test #6 negative watch",
+			fRun: testMongoWatchNegative,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func TestMongoModels(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 saveModel", fRun: testMongoSaveModel},
+		{name: "positive test #1 deleteModel", fRun: testMongoDeleteModel},
+		{name: "positive test #2 deleteModel unknown version", fRun: testMongoDeleteModelUnknownVersion},
+		{name: "positive test #3 ignoreDuplicateKey", fRun: testMongoIgnoreDuplicateKey},
+		{name: "positive test #4 watchPipeline", fRun: testMongoWatchPipeline},
+		{name: "positive test #5 watchError", fRun: testMongoWatchError},
+		{name: "positive test #6 dedupePipeline", fRun: testMongoDedupePipeline},
 	}
 
 	assert.NotNil(t, t)
@@ -46,6 +84,100 @@ func TestFavoritesInsertsBatch(t *testing.T) {
 	}
 }
 
+func testMongoSaveModel(t *testing.T) {
+	r := &repo{shardID: "shard-1"}
+	filter, update := r.saveModel(conformanceFavorites("upk", "isin", 2, "a"))
+	assert.Equal(t, bson.E{Key: UPK, Value: "upk"}, filter[0])
+	assert.Equal(t, bson.E{Key: ISIN, Value: "isin"}, filter[1])
+	assert.Equal(t, "$or", filter[2].Key)
+	assert.Len(t, filter[2].Value, 3)
+	assert.Equal(t, "$set", update[0].Key)
+	assert.Contains(t, update[0].Value, bson.E{Key: Version, Value: int64(2)})
+	assert.Contains(t, update[0].Value, bson.E{Key: Metadata, Value: "a"})
+	assert.Contains(t, update[0].Value, bson.E{Key: ShardID, Value: "shard-1"})
+	assert.Equal(t, "$unset", update[1].Key)
+
+	writtenAt := time.Date(2024, 8, 18, 3, 0, 0, 0, time.UTC)
+	_, update = r.saveModel(conformanceFavorites("upk", "isin", 2, "a").
+		WithProvenance(entity.MakeProvenance("shard-2", writtenAt)))
+	assert.Contains(t, update[0].Value, bson.E{Key: ShardID, Value: "shard-2"})
+	assert.Contains(t, update[0].Value, bson.E{Key: WrittenAt, Value: writtenAt})
+}
+
+func testMongoDeleteModel(t *testing.T) {
+	r := &repo{shardID: "shard-1"}
+	filter, update := r.deleteModel(conformanceFavorites("upk", "isin", 2, ""))
+	assert.Contains(t, filter, bson.E{Key: Version, Value: bson.D{{Key: "$lte", Value: int64(2)}}})
+	assert.Contains(t, filter, bson.E{Key: Deleted, Value: bson.D{{Key: "$ne", Value: true}}})
+	assert.Contains(t, update[0].Value, bson.E{Key: Version, Value: int64(2)})
+	assert.Contains(t, update[0].Value, bson.E{Key: AckedBy, Value: []string{"shard-1"}})
+	assert.Contains(t, update[0].Value, bson.E{Key: ShardID, Value: "shard-1"})
+	assert.Equal(t, bson.D{{Key: AssetType, Value: "STOCK"}}, update[1].Value)
+}
+
+func testMongoDeleteModelUnknownVersion(t *testing.T) {
+	r := &repo{}
+	filter, update := r.deleteModel(conformanceFavorites("upk", "isin", 0, ""))
+	assert.Len(t, filter, 3)
+	assert.NotContains(t, update[0].Value, bson.E{Key: Version, Value: int64(0)})
+	assert.Contains(t, update[0].Value, bson.E{Key: AckedBy, Value: []string{}})
+	assert.Contains(t, update[1].Value, bson.E{Key: Version, Value: int64(0)})
+}
+
+func testMongoIgnoreDuplicateKey(t *testing.T) {
+	duplicate := mongodb.WriteError{Code: duplicateKeyCode}
+	other := mongodb.WriteError{Code: 121}
+	assert.Nil(t, ignoreDuplicateKey(nil))
+	assert.Nil(t, ignoreDuplicateKey(mongodb.WriteException{WriteErrors: mongodb.WriteErrors{duplicate}}))
+	assert.Nil(t, ignoreDuplicateKey(mongodb.BulkWriteException{
+		WriteErrors: []mongodb.BulkWriteError{{WriteError: duplicate}, {WriteError: duplicate}},
+	}))
+	assert.NotNil(t, ignoreDuplicateKey(mongodb.BulkWriteException{
+		WriteErrors: []mongodb.BulkWriteError{{WriteError: duplicate}, {WriteError: other}},
+	}))
+	assert.NotNil(t, ignoreDuplicateKey(mongodb.WriteException{WriteErrors: mongodb.WriteErrors{other}}))
+	assert.NotNil(t, ignoreDuplicateKey(errors.New("error")))
+}
+
+func testMongoWatchPipeline(t *testing.T) {
+	pipeline := watchPipeline()
+	assert.Len(t, pipeline, 1)
+	assert.Equal(t, "$match", pipeline[0][0].Key)
+	assert.Equal(t, bson.D{
+		{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace"}}}},
+	}, pipeline[0][0].Value)
+}
+
+func testMongoDedupePipeline(t *testing.T) {
+	pipeline := dedupePipeline()
+	assert.Len(t, pipeline, 3)
+	assert.Equal(t, "$sort", pipeline[0][0].Key)
+	assert.Equal(t, bson.D{{Key: Version, Value: -1}, {Key: WrittenAt, Value: -1}, {Key: "_id", Value: -1}}, pipeline[0][0].Value)
+	assert.Equal(t, "$group", pipeline[1][0].Key)
+	assert.Equal(t, "$match", pipeline[2][0].Key)
+}
+
+func testMongoWatchError(t *testing.T) {
+	assert.Nil(t, watchError(nil))
+	assert.ErrorIs(t, watchError(mongodb.CommandError{Code: changeStreamHistoryLostCode}), ErrResumeTokenLost)
+	assert.ErrorIs(t, watchError(mongodb.CommandError{Code: changeStreamFatalErrorCode}), ErrResumeTokenLost)
+	assert.NotErrorIs(t, watchError(mongodb.CommandError{Code: 121}), ErrResumeTokenLost)
+	assert.NotErrorIs(t, watchError(context.Canceled), ErrResumeTokenLost)
+}
+
+func testMongoWatchNegative(t *testing.T) {
+	defer func() { _ = recover() }() // TODO
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	assert.False(t, WatchEnabled(prop))
+	err := GetWatcher(prop).Watch(context.Background(), nil, func(context.Context, Change) error {
+		return nil
+	})
+	assert.NotNil(t, err)
+}
+
 func testMongoDeleteNegative(t *testing.T) {
 	defer func() { _ = recover() }() // TODO
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
@@ -65,7 +197,7 @@ func testMongoLoadNegative(t *testing.T) {
 	prop := env.GetProperties()
 	mongo := GetMongoRepo(prop)
 	got, err := mongo.Load(context.Background(), "")
-	assert.Nil(t, got)
+	assert.Len(t, got, 0)
 	assert.NotNil(t, err)
 }
 
@@ -80,5 +212,39 @@ func testMongoSaveNegative(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func testMongoNotesNegative(t *testing.T) {
+	defer func() { _ = recover() }() // TODO
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	notes := GetMongoNotesRepo(prop)
+	err := notes.Save(context.Background(), entity.Note{})
+	assert.NotNil(t, err)
+	got, err := notes.Load(context.Background(), "")
+	assert.NotNil(t, err)
+	assert.Len(t, got, 0)
+	err = notes.Delete(context.Background(), entity.Note{})
+	assert.NotNil(t, err)
+}
+
+func testMongoTombstonesNegative(t *testing.T) {
+	defer func() { _ = recover() }() // TODO
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	mongo := GetMongoRepo(prop)
+	err := mongo.Acknowledge(context.Background(), nil, "shard-1")
+	assert.Nil(t, err)
+	got, err := mongo.Compact(context.Background(), nil, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), got)
+	err = mongo.Acknowledge(context.Background(), []entity.Favorites{{}}, "shard-1")
+	assert.NotNil(t, err)
+	_, err = mongo.Compact(context.Background(), []string{"shard-1"}, time.Now())
+	assert.NotNil(t, err)
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
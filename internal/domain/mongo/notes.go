@@ -0,0 +1,203 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * notes.go
+ * $Id$
+ */
+//!+
+
+// Package mongo TODO.
+package mongo
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type notesRepo struct {
+	dbName        string
+	mongodbClient *tool.MongoClient
+	sLog          *slog.Logger
+}
+
+type note struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Upk     string             `bson:"upk"`
+	Name    string             `bson:"name"`
+	Body    string             `bson:"body"`
+	Version int64              `bson:"version"`
+}
+
+var _ Notes = (*notesRepo)(nil)
+var (
+	onceMongoNotes = new(sync.Once)
+	mongoNotesRepo *notesRepo
+)
+
+func GetMongoNotesRepo(prop env.Properties) Notes {
+	onceMongoNotes.Do(func() {
+		mongoNotesRepo = new(notesRepo)
+		mongoNotesRepo.dbName = prop.Config().MongoName()
+		mongoNotesRepo.mongodbClient = prop.MongodbClient()
+		mongoNotesRepo.sLog = prop.Logger()
+	})
+	return mongoNotesRepo
+}
+
+func (r *notesRepo) Delete(ctx context.Context, entity entity.Note) error {
+
+	collection, err := r.mongodbClient.Collection(r.dbName, NotesCollection)
+
+	if err != nil {
+		return err
+	}
+	res, err := collection.DeleteOne(ctx, bson.D{
+		{Key: UPK, Value: entity.User().Upk()},
+		{Key: Name, Value: entity.Name()},
+	})
+	if err != nil {
+		return err
+	}
+	r.sLog.DebugContext(ctx, env.MSG+"MongoNotesRepo.Delete", "res.DeletedCount", res.DeletedCount)
+
+	return nil
+}
+
+func (r *notesRepo) Erase(ctx context.Context, upk string) (int64, error) {
+
+	collection, err := r.mongodbClient.Collection(r.dbName, NotesCollection)
+
+	if err != nil {
+		return 0, err
+	}
+	res, err := collection.DeleteMany(ctx, bson.D{{Key: UPK, Value: upk}})
+
+	if err != nil {
+		return 0, err
+	}
+	r.sLog.DebugContext(ctx, env.MSG+"MongoNotesRepo.Erase", "res.DeletedCount", res.DeletedCount)
+
+	return res.DeletedCount, nil
+}
+
+func (r *notesRepo) Load(ctx context.Context, upk string) ([]entity.Note, error) {
+
+	collection, err := r.mongodbClient.Collection(r.dbName, NotesCollection)
+	result := make([]entity.Note, 0)
+
+	if err != nil {
+		return result, err
+	}
+	cur, err := collection.Find(ctx, bson.D{
+		{Key: UPK, Value: upk},
+	})
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = cur.Close(ctx) }()
+
+	for cur.Next(ctx) {
+		var nt note
+		err = cur.Decode(&nt)
+
+		if err != nil {
+			r.sLog.DebugContext(ctx, env.MSG+"MongoNotesRepo.Load", "cur.Decode(&result)", err)
+			return result, err
+		}
+		us := entity.MakeUserWithVersion(nt.Upk, nt.Version, entity.DefaultTAttributes())
+		vn := sql.NullInt64{Int64: nt.Version, Valid: true}
+		result = append(result, entity.MakeNote(uuid.Max, nt.Name, us, nt.Body, vn, entity.DefaultTAttributes()))
+	}
+	return result, nil
+}
+
+func (r *notesRepo) Rekey(ctx context.Context, from, to string) error {
+
+	collection, err := r.mongodbClient.Collection(r.dbName, NotesCollection)
+
+	if err != nil {
+		return err
+	}
+	res, err := collection.UpdateMany(
+		ctx,
+		bson.D{{Key: UPK, Value: from}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: UPK, Value: to}}}},
+	)
+	if err != nil {
+		return err
+	}
+	r.sLog.DebugContext(ctx, env.MSG+"MongoNotesRepo.Rekey", "res.ModifiedCount", res.ModifiedCount)
+
+	return nil
+}
+
+func (r *notesRepo) Save(ctx context.Context, entity entity.Note) error {
+
+	collection, err := r.mongodbClient.Collection(r.dbName, NotesCollection)
+
+	if err != nil {
+		return err
+	}
+	cur, err := collection.Find(ctx, bson.D{
+		{Key: UPK, Value: entity.User().Upk()},
+		{Key: Name, Value: entity.Name()},
+	})
+
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cur.Close(ctx) }()
+
+	if cur.RemainingBatchLength() == 0 {
+		res, err := collection.InsertOne(ctx, bson.D{
+			{Key: UPK, Value: entity.User().Upk()},
+			{Key: Name, Value: entity.Name()},
+			{Key: Body, Value: entity.Body()},
+			{Key: Version, Value: entity.Version().Int64},
+		})
+		if err != nil {
+			r.sLog.DebugContext(ctx, env.MSG+"MongoNotesRepo.Save collection.InsertOne", "err", err)
+			return err
+		}
+		r.sLog.DebugContext(ctx, env.MSG+"MongoNotesRepo.Save", "res.InsertedID", res.InsertedID)
+		return nil
+	}
+	for cur.Next(ctx) {
+		var result note
+		err := cur.Decode(&result)
+
+		if err != nil {
+			r.sLog.DebugContext(ctx, env.MSG+"MongoNotesRepo.Save cur.Decode", "err", err)
+			return err
+		}
+		if result.Version < entity.Version().Int64 {
+			filter := bson.D{{Key: "_id", Value: result.ID}}
+			update := bson.D{{Key: "$set",
+				Value: bson.D{
+					{Key: Body, Value: entity.Body()},
+					{Key: Version, Value: entity.Version().Int64},
+				}},
+			}
+			res, err := collection.UpdateOne(ctx, filter, update)
+			if err != nil {
+				r.sLog.DebugContext(ctx, env.MSG+"MongoNotesRepo.Save collection.UpdateOne", "err", err)
+				return err
+			}
+			r.sLog.DebugContext(ctx, env.MSG+"MongoNotesRepo.Save", "res.ModifiedCount", res.ModifiedCount)
+		}
+	}
+	return nil
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,102 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * store.go
+ * $Id$
+ */
+//!+
+
+// Package mongo TODO.
+package mongo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vskurikhin/gofavorites/internal/env"
+)
+
+const (
+	// StoreMongoDB хранилище синхронизации в MongoDB, общее для всех шардов.
+	StoreMongoDB = "mongodb"
+	// StoreBolt встроенное хранилище на диске для одного узла и разработки.
+	StoreBolt = "bolt"
+	// StoreMemory хранилище в памяти процесса для тестов.
+	StoreMemory = "memory"
+)
+
+var (
+	ErrStore            = fmt.Errorf("unknown sync store")
+	ErrStoreUnavailable = fmt.Errorf("sync store is unavailable")
+)
+
+// GetStore хранилище синхронизации избранного выбранное в конфигурации (sync.store),
+// по умолчанию MongoDB. Для неизвестного имени хранилище недоступно
+// (ErrStoreUnavailable), ошибку конфигурации возвращает CheckStore.
+func GetStore(prop env.Properties) Mongo {
+
+	switch storeName(prop) {
+	case StoreBolt:
+		return GetBoltRepo(prop)
+	case StoreMemory:
+		return GetMemoryRepo(prop)
+	case StoreMongoDB:
+		return GetMongoRepo(prop)
+	}
+	prop.Logger().Error(env.MSG+"GetStore", "err", fmt.Errorf("%w: %s", ErrStore, prop.Config().SyncStore()))
+
+	return &kvRepo{shardID: prop.Config().SyncShardID(), sLog: prop.Logger()}
+}
+
+// CheckStore проверка хранилища синхронизации при старте: имя хранилища известно,
+// файл встроенного хранилища на диске открыт.
+func CheckStore(prop env.Properties) error {
+
+	switch storeName(prop) {
+	case StoreBolt:
+		GetBoltRepo(prop)
+		return boltErr
+	case StoreMemory, StoreMongoDB:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrStore, prop.Config().SyncStore())
+}
+
+// CloseStore освобождение ресурсов хранилища синхронизации при остановке.
+// Клиент MongoDB закрывается отдельно.
+func CloseStore(prop env.Properties) error {
+
+	if storeName(prop) == StoreBolt {
+		return closeBolt(prop)
+	}
+	return nil
+}
+
+// StoreEnabled хранилище синхронизации доступно: хранилище в памяти доступно всегда,
+// на диске — если файл открыт, MongoDB — при наличии клиента.
+func StoreEnabled(prop env.Properties) bool {
+
+	switch storeName(prop) {
+	case StoreBolt:
+		return CheckStore(prop) == nil
+	case StoreMemory:
+		return true
+	case StoreMongoDB:
+		return prop.MongodbClient() != nil
+	}
+	return false
+}
+
+func storeName(prop env.Properties) string {
+
+	name := strings.ToLower(strings.TrimSpace(prop.Config().SyncStore()))
+
+	if name == "" {
+		return StoreMongoDB
+	}
+	return name
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
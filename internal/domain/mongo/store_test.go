@@ -0,0 +1,160 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * store_test.go
+ * $Id$
+ */
+//!+
+
+// Package mongo TODO.
+package mongo
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/env"
+)
+
+func TestStore(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 GetStore default", fRun: testGetStoreDefault},
+		{name: "negative test #1 store is unavailable", fRun: testStoreUnavailable},
+		{name: "negative test #2 NewBoltRepo", fRun: testNewBoltRepoNegative},
+		{name: "positive test #3 Bootstrap without MongoDB", fRun: testBootstrapSkipped},
+		{name: "negative test #4 unknown store", fRun: testStoreUnknown},
+		{name: "negative test #5 bolt store is not opened", fRun: testStoreBoltNegative},
+		{name: "positive test #6 bolt store", fRun: testStoreBolt},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testGetStoreDefault(t *testing.T) {
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	assert.IsType(t, &repo{}, GetStore(prop))
+	assert.Equal(t, StoreMongoDB, storeName(prop))
+	assert.Equal(t, prop.MongodbClient() != nil, StoreEnabled(prop))
+}
+
+func testStoreUnavailable(t *testing.T) {
+	store := &kvRepo{sLog: slog.Default()}
+	favorites := []entity.Favorites{conformanceFavorites("upk", "isin", 1, "")}
+	assert.ErrorIs(t, store.Acknowledge(context.TODO(), favorites, conformanceShard), ErrStoreUnavailable)
+	_, err := store.Compact(context.TODO(), []string{conformanceShard}, time.Now())
+	assert.ErrorIs(t, err, ErrStoreUnavailable)
+	assert.ErrorIs(t, store.Delete(context.TODO(), favorites[0]), ErrStoreUnavailable)
+	_, err = store.Load(context.TODO(), "upk")
+	assert.ErrorIs(t, err, ErrStoreUnavailable)
+	assert.ErrorIs(t, store.Save(context.TODO(), favorites[0]), ErrStoreUnavailable)
+	assert.ErrorIs(t, store.SaveAll(context.TODO(), favorites), ErrStoreUnavailable)
+	assert.ErrorIs(t, store.DeleteAll(context.TODO(), favorites), ErrStoreUnavailable)
+}
+
+func testBootstrapSkipped(t *testing.T) {
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	if prop.MongodbClient() != nil {
+		t.Skip("MongoDB client is configured")
+	}
+	assert.Nil(t, Bootstrap(context.TODO(), prop))
+	assert.NotEmpty(t, favoritesIndexes())
+	assert.Equal(t, "$jsonSchema", favoritesValidator()[0].Key)
+}
+
+func testStoreUnknown(t *testing.T) {
+	prop := getTestStoreProperties(t, "flie", "")
+	assert.IsType(t, &kvRepo{}, GetStore(prop))
+	assert.ErrorIs(t, CheckStore(prop), ErrStore)
+	assert.False(t, StoreEnabled(prop))
+	assert.Nil(t, CloseStore(prop))
+}
+
+func testStoreBoltNegative(t *testing.T) {
+	prop := getTestStoreProperties(t, StoreBolt, t.TempDir())
+	resetTestBolt(t)
+	assert.ErrorIs(t, CheckStore(prop), ErrStoreUnavailable, "путь к каталогу вместо файла")
+	assert.False(t, StoreEnabled(prop))
+	_, err := GetStore(prop).Load(context.TODO(), "upk")
+	assert.ErrorIs(t, err, ErrStoreUnavailable)
+	assert.Nil(t, CloseStore(prop))
+}
+
+func testStoreBolt(t *testing.T) {
+	prop := getTestStoreProperties(t, StoreBolt, filepath.Join(t.TempDir(), "sync.db"))
+	resetTestBolt(t)
+	assert.Nil(t, CheckStore(prop))
+	assert.True(t, StoreEnabled(prop))
+	assert.Nil(t, CloseStore(prop))
+	_, err := GetStore(prop).Load(context.TODO(), "upk")
+	assert.NotNil(t, err, "файл закрыт")
+}
+
+func testNewBoltRepoNegative(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewBoltRepo(dir, conformanceShard, slog.Default())
+	assert.NotNil(t, err, "путь к каталогу вместо файла")
+	_, err = NewBoltRepo(filepath.Join(dir, "sync.db", "sync.db"), conformanceShard, slog.Default())
+	assert.Nil(t, err)
+	_, err = NewBoltRepo(filepath.Join(dir, "sync.db", "sync.db", "sync.db"), conformanceShard, slog.Default())
+	assert.NotNil(t, err, "каталог поверх файла")
+}
+
+type stubStoreConfig struct {
+	env.Config
+	store, path string
+}
+
+func (c stubStoreConfig) SyncStore() string {
+	return c.store
+}
+
+func (c stubStoreConfig) SyncStorePath() string {
+	return c.path
+}
+
+type stubStoreProperties struct {
+	env.Properties
+	config env.Config
+}
+
+func (p stubStoreProperties) Config() env.Config {
+	return p.config
+}
+
+func resetTestBolt(t *testing.T) {
+	reset := func() { onceBolt, boltErr, boltRepo = new(sync.Once), nil, nil }
+	reset()
+	t.Cleanup(reset)
+}
+
+func getTestStoreProperties(t *testing.T, store, path string) env.Properties {
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	return stubStoreProperties{Properties: prop, config: stubStoreConfig{Config: prop.Config(), store: store, path: path}}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
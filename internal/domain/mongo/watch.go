@@ -0,0 +1,127 @@
+/*
+ * This file was last modified at 2024-08-18 04:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * watch.go
+ * $Id$
+ */
+//!+
+
+// Package mongo TODO.
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodb "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// changeStreamHistoryLostCode код ошибки MongoDB: событие маркера уже вытеснено из oplog.
+	changeStreamHistoryLostCode = 286
+	// changeStreamFatalErrorCode код ошибки MongoDB: поток нельзя возобновить с маркера.
+	changeStreamFatalErrorCode = 280
+)
+
+// ErrResumeTokenLost поток изменений нельзя возобновить с сохранённого маркера.
+var ErrResumeTokenLost = fmt.Errorf("change stream resume token is lost")
+
+// Change изменение документа избранного из потока изменений и маркер
+// возобновления потока после него.
+type Change struct {
+	Favorites   entity.Favorites
+	ResumeToken []byte
+}
+
+// Watcher поток изменений (change stream) коллекции избранного MongoDB.
+type Watcher interface {
+	// Watch чтение изменений после маркера resumeToken (пустой — с текущего момента)
+	// до отмены ctx. Изменения передаются fn по порядку, ошибка fn прерывает поток.
+	Watch(ctx context.Context, resumeToken []byte, fn func(ctx context.Context, change Change) error) error
+}
+
+var _ Watcher = (*repo)(nil)
+
+// GetWatcher поток изменений коллекции избранного MongoDB,
+// для встроенных хранилищ (sync.store) поток недоступен.
+func GetWatcher(prop env.Properties) Watcher {
+	return GetMongoRepo(prop).(*repo)
+}
+
+// WatchEnabled поток изменений доступен: хранилище синхронизации MongoDB с клиентом.
+func WatchEnabled(prop env.Properties) bool {
+	return storeName(prop) == StoreMongoDB && prop.MongodbClient() != nil
+}
+
+func (r *repo) Watch(ctx context.Context, resumeToken []byte, fn func(ctx context.Context, change Change) error) error {
+
+	collection, err := r.mongodbClient.Collection(r.dbName, Collection)
+
+	if err != nil {
+		return err
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	if len(resumeToken) > 0 {
+		opts.SetResumeAfter(bson.Raw(resumeToken))
+	}
+	stream, err := collection.Watch(ctx, watchPipeline(), opts)
+
+	if err != nil {
+		return watchError(err)
+	}
+	defer func() { _ = stream.Close(context.Background()) }()
+
+	for stream.Next(ctx) {
+
+		var event struct {
+			FullDocument *favorites `bson:"fullDocument"`
+		}
+		if err = stream.Decode(&event); err != nil {
+			r.sLog.ErrorContext(ctx, env.MSG+"MongoRepo.Watch stream.Decode", "err", err)
+			return err
+		}
+		// документ удалён (Compact) раньше чем прочитано его изменение.
+		if event.FullDocument == nil {
+			continue
+		}
+		change := Change{
+			Favorites:   event.FullDocument.toEntity(),
+			ResumeToken: slices.Clone(stream.ResumeToken()),
+		}
+		if err = fn(ctx, change); err != nil {
+			return err
+		}
+	}
+	return watchError(stream.Err())
+}
+
+// watchPipeline изменения документов избранного, надгробия — тоже изменения.
+// Физическое удаление документов выполняет только Compact для подтверждённых надгробий.
+func watchPipeline() mongodb.Pipeline {
+	return mongodb.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace"}}}},
+		}}},
+	}
+}
+
+func watchError(err error) error {
+
+	var se mongodb.ServerError
+
+	if errors.As(err, &se) && (se.HasErrorCode(changeStreamHistoryLostCode) || se.HasErrorCode(changeStreamFatalErrorCode)) {
+		return fmt.Errorf("%w: %w", ErrResumeTokenLost, err)
+	}
+	return err
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
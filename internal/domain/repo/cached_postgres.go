@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-03 12:13 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * cached_postgres.go
@@ -14,6 +14,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 
 type cache[E domain.Serializable] interface {
 	delete(entity E) error
+	evict(upks []string) int
 	get(entity E) (E, error)
 	invalidate() error
 	set(entity E) (E, error)
@@ -48,10 +50,27 @@ var (
 	assetTypeCachedRepo     *CachedPostgres[*entity.AssetType]
 	onceFavoritesCachedRepo = new(sync.Once)
 	favoritesCachedRepo     *CachedPostgres[*entity.Favorites]
+	onceNoteCachedRepo      = new(sync.Once)
+	noteCachedRepo          *CachedPostgres[*entity.Note]
+	onceOtpCachedRepo       = new(sync.Once)
+	otpCachedRepo           *CachedPostgres[*entity.Otp]
+	onceOtpCodeCachedRepo   = new(sync.Once)
+	otpCodeCachedRepo       *CachedPostgres[*entity.OtpCode]
 	onceUserCachedRepo      = new(sync.Once)
 	userCachedRepo          *CachedPostgres[*entity.User]
 )
 
+// EvictUser вытеснение из кэшей записей пользователей с UPK upks, например после удаления
+// данных пользователя одним оператором в обход репозиториев. Ключи кэша записей
+// пользователя (Key) оканчиваются его UPK. Возвращает количество вытесненных записей.
+func EvictUser(prop env.Properties, upks ...string) int {
+	return getUserCachedPostgresRepo(prop).evict(upks) +
+		getFavoritesCachedPostgresRepo(prop).evict(upks) +
+		getNoteCachedPostgresRepo(prop).evict(upks) +
+		getOtpCachedPostgresRepo(prop).evict(upks) +
+		getOtpCodeCachedPostgresRepo(prop).evict(upks)
+}
+
 func getAssetCache(prop env.Properties) cache[*entity.Asset] {
 	return getAssetCachedPostgresRepo(prop)
 }
@@ -105,6 +124,59 @@ func getFavoritesCachedPostgresRepo(prop env.Properties) *CachedPostgres[*entity
 	return favoritesCachedRepo
 }
 
+func getNoteCache(prop env.Properties) cache[*entity.Note] {
+	return getNoteCachedPostgresRepo(prop)
+}
+
+func GetNotePostgresCachedRepo(prop env.Properties) domain.Repo[*entity.Note] {
+	return getNoteCachedPostgresRepo(prop)
+}
+
+func getNoteCachedPostgresRepo(prop env.Properties) *CachedPostgres[*entity.Note] {
+	onceNoteCachedRepo.Do(func() {
+		noteCachedRepo = new(CachedPostgres[*entity.Note])
+		noteCachedRepo.cache = memory.New(memory.Config{GCInterval: prop.CacheGCInterval()})
+		noteCachedRepo.pool = prop.DBPool()
+		noteCachedRepo.exp = prop.CacheExpire()
+		noteCachedRepo.sLog = prop.Logger()
+	})
+	return noteCachedRepo
+}
+
+func getOtpCache(prop env.Properties) cache[*entity.Otp] {
+	return getOtpCachedPostgresRepo(prop)
+}
+
+func GetOtpPostgresCachedRepo(prop env.Properties) domain.Repo[*entity.Otp] {
+	return getOtpCachedPostgresRepo(prop)
+}
+
+func getOtpCachedPostgresRepo(prop env.Properties) *CachedPostgres[*entity.Otp] {
+	onceOtpCachedRepo.Do(func() {
+		otpCachedRepo = new(CachedPostgres[*entity.Otp])
+		otpCachedRepo.cache = memory.New(memory.Config{GCInterval: prop.CacheGCInterval()})
+		otpCachedRepo.pool = prop.DBPool()
+		otpCachedRepo.exp = prop.CacheExpire()
+		otpCachedRepo.sLog = prop.Logger()
+	})
+	return otpCachedRepo
+}
+
+func getOtpCodeCache(prop env.Properties) cache[*entity.OtpCode] {
+	return getOtpCodeCachedPostgresRepo(prop)
+}
+
+func getOtpCodeCachedPostgresRepo(prop env.Properties) *CachedPostgres[*entity.OtpCode] {
+	onceOtpCodeCachedRepo.Do(func() {
+		otpCodeCachedRepo = new(CachedPostgres[*entity.OtpCode])
+		otpCodeCachedRepo.cache = memory.New(memory.Config{GCInterval: prop.CacheGCInterval()})
+		otpCodeCachedRepo.pool = prop.DBPool()
+		otpCodeCachedRepo.exp = prop.CacheExpire()
+		otpCodeCachedRepo.sLog = prop.Logger()
+	})
+	return otpCodeCachedRepo
+}
+
 func GetUserPostgresCachedRepo(prop env.Properties) domain.Repo[*entity.User] {
 	return getUserCachedPostgresRepo(prop)
 }
@@ -183,6 +255,17 @@ func (p *CachedPostgres[E]) delete(entity E) error {
 	return p.cache.Delete(entity.Key())
 }
 
+func (p *CachedPostgres[E]) evict(upks []string) int {
+	return p.cache.DeleteFunc(func(key string) bool {
+		for _, upk := range upks {
+			if upk != "" && strings.HasSuffix(key, upk) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
 func (p *CachedPostgres[E]) get(entity E) (E, error) {
 
 	t := entity.Copy()
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-15 18:40 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * postgres.go
@@ -12,8 +12,10 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/vskurikhin/gofavorites/internal/domain/entity"
 	"github.com/vskurikhin/gofavorites/internal/env"
@@ -33,6 +35,10 @@ func TestCachedPostgresRepos(t *testing.T) {
 			name: "negative test #2 User Cached Postgres Repo",
 			fRun: testUserCachedPostgresRepoNegative,
 		},
+		{
+			name: "positive test #3 EvictUser",
+			fRun: testEvictUser,
+		},
 	}
 
 	assert.NotNil(t, t)
@@ -134,6 +140,37 @@ func testUserCachedPostgresRepoNegative(t *testing.T) {
 	assert.Equal(t, ErrBadPool, err)
 }
 
+func testEvictUser(t *testing.T) {
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	upk1 := tool.RandStringBytes(44)
+	upk2 := tool.RandStringBytes(44)
+	users := getUserCachedPostgresRepo(prop)
+	favorites := getFavoritesCachedPostgresRepo(prop)
+	user1 := entity.MakeUser(upk1, entity.DefaultTAttributes())
+	user2 := entity.MakeUser(upk2, entity.DefaultTAttributes())
+	asset := entity.MakeAsset("isin", entity.MakeAssetType("type", entity.DefaultTAttributes()), entity.DefaultTAttributes())
+
+	for _, user := range []entity.User{user1, user2} {
+		_, err := users.set(&user)
+		assert.Nil(t, err)
+		favorite := entity.MakeFavorites(uuid.New(), asset, user, sql.NullInt64{}, entity.DefaultTAttributes())
+		_, err = favorites.set(&favorite)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2, EvictUser(prop, upk1, ""))
+
+	data, err := users.cache.Get(upk1)
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+	data, err = users.cache.Get(upk2)
+	assert.Nil(t, err)
+	assert.NotNil(t, data)
+	assert.Equal(t, 2, EvictUser(prop, upk2))
+}
+
 func testUserCachedPostgresRepoPositive(t *testing.T) {
 	defer func() { _ = recover() }()
 	prop := env.GetProperties()
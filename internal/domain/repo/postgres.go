@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-03 12:03 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * postgres.go
@@ -41,8 +41,26 @@ var (
 	assetTypeRepo            *Postgres[*entity.AssetType]
 	onceFavoritesDeletedRepo = new(sync.Once)
 	favoritesDeletedRepo     *Postgres[*entity.FavoritesDeleted]
+	onceFavoritesRepo        = new(sync.Once)
+	favoritesRepo            *Postgres[*entity.Favorites]
+	onceNoteDeletedRepo      = new(sync.Once)
+	noteDeletedRepo          *Postgres[*entity.NoteDeleted]
+	onceOutboxRepo           = new(sync.Once)
+	outboxRepo               *Postgres[*entity.OutboxEvent]
+	onceRefreshTokenRepo     = new(sync.Once)
+	refreshTokenRepo         *Postgres[*entity.RefreshToken]
+	onceResumeTokenRepo      = new(sync.Once)
+	resumeTokenRepo          *Postgres[*entity.ResumeToken]
+	onceUserErasureRepo      = new(sync.Once)
+	userErasureRepo          *Postgres[*entity.UserErasure]
+	onceUpkRotationRepo      = new(sync.Once)
+	upkRotationRepo          *Postgres[*entity.UpkRotation]
+	onceRevokedTokenRepo     = new(sync.Once)
+	revokedTokenRepo         *Postgres[*entity.RevokedToken]
 	onceUserRepo             = new(sync.Once)
 	userRepo                 *Postgres[*entity.User]
+	onceUserKeyRepo          = new(sync.Once)
+	userKeyRepo              *Postgres[*entity.UserKey]
 )
 
 func GetAssetTypePostgresRepo(prop env.Properties) domain.Repo[*entity.AssetType] {
@@ -63,6 +81,69 @@ func GetFavoritesDeletedPostgresRepo(prop env.Properties) domain.Repo[*entity.Fa
 	return favoritesDeletedRepo
 }
 
+func GetFavoritesPostgresRepo(prop env.Properties) domain.Repo[*entity.Favorites] {
+	onceFavoritesRepo.Do(func() {
+		favoritesRepo = new(Postgres[*entity.Favorites])
+		favoritesRepo.pool = prop.DBPool()
+		favoritesRepo.sLog = prop.Logger()
+	})
+	return favoritesRepo
+}
+
+func GetNoteDeletedPostgresRepo(prop env.Properties) domain.Repo[*entity.NoteDeleted] {
+	onceNoteDeletedRepo.Do(func() {
+		noteDeletedRepo = new(Postgres[*entity.NoteDeleted])
+		noteDeletedRepo.pool = prop.DBPool()
+		noteDeletedRepo.sLog = prop.Logger()
+	})
+	return noteDeletedRepo
+}
+
+func GetOutboxPostgresRepo(prop env.Properties) domain.Repo[*entity.OutboxEvent] {
+	onceOutboxRepo.Do(func() {
+		outboxRepo = new(Postgres[*entity.OutboxEvent])
+		outboxRepo.pool = prop.DBPool()
+		outboxRepo.sLog = prop.Logger()
+	})
+	return outboxRepo
+}
+
+func GetRefreshTokenPostgresRepo(prop env.Properties) domain.Repo[*entity.RefreshToken] {
+	onceRefreshTokenRepo.Do(func() {
+		refreshTokenRepo = new(Postgres[*entity.RefreshToken])
+		refreshTokenRepo.pool = prop.DBPool()
+		refreshTokenRepo.sLog = prop.Logger()
+	})
+	return refreshTokenRepo
+}
+
+func GetResumeTokenPostgresRepo(prop env.Properties) domain.Repo[*entity.ResumeToken] {
+	onceResumeTokenRepo.Do(func() {
+		resumeTokenRepo = new(Postgres[*entity.ResumeToken])
+		resumeTokenRepo.pool = prop.DBPool()
+		resumeTokenRepo.sLog = prop.Logger()
+	})
+	return resumeTokenRepo
+}
+
+func GetRevokedTokenPostgresRepo(prop env.Properties) domain.Repo[*entity.RevokedToken] {
+	onceRevokedTokenRepo.Do(func() {
+		revokedTokenRepo = new(Postgres[*entity.RevokedToken])
+		revokedTokenRepo.pool = prop.DBPool()
+		revokedTokenRepo.sLog = prop.Logger()
+	})
+	return revokedTokenRepo
+}
+
+func GetUpkRotationPostgresRepo(prop env.Properties) domain.Repo[*entity.UpkRotation] {
+	onceUpkRotationRepo.Do(func() {
+		upkRotationRepo = new(Postgres[*entity.UpkRotation])
+		upkRotationRepo.pool = prop.DBPool()
+		upkRotationRepo.sLog = prop.Logger()
+	})
+	return upkRotationRepo
+}
+
 func GetUserPostgresRepo(prop env.Properties) domain.Repo[*entity.User] {
 	onceUserRepo.Do(func() {
 		userRepo = new(Postgres[*entity.User])
@@ -72,6 +153,24 @@ func GetUserPostgresRepo(prop env.Properties) domain.Repo[*entity.User] {
 	return userRepo
 }
 
+func GetUserErasurePostgresRepo(prop env.Properties) domain.Repo[*entity.UserErasure] {
+	onceUserErasureRepo.Do(func() {
+		userErasureRepo = new(Postgres[*entity.UserErasure])
+		userErasureRepo.pool = prop.DBPool()
+		userErasureRepo.sLog = prop.Logger()
+	})
+	return userErasureRepo
+}
+
+func GetUserKeyPostgresRepo(prop env.Properties) domain.Repo[*entity.UserKey] {
+	onceUserKeyRepo.Do(func() {
+		userKeyRepo = new(Postgres[*entity.UserKey])
+		userKeyRepo.pool = prop.DBPool()
+		userKeyRepo.sLog = prop.Logger()
+	})
+	return userKeyRepo
+}
+
 func (p *Postgres[E]) Delete(ctx context.Context, entity E, scan func(domain.Scanner)) (E, error) {
 	err := scanPostgreSQL(ctx, p.sLog, p.pool, scan, entity.DeleteSQL(), entity.DeleteArgs()...)
 	return entity, err
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-03 12:13 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * transactional.go
@@ -33,6 +33,12 @@ var (
 	assetDft         *TxPostgres[*entity.Asset]
 	onceFavoritesDft = new(sync.Once)
 	favoritesDft     *TxPostgres[*entity.Favorites]
+	onceNoteDft      = new(sync.Once)
+	noteDft          *TxPostgres[*entity.Note]
+	onceOtpDft       = new(sync.Once)
+	otpDft           *TxPostgres[*entity.Otp]
+	onceOtpCodeDft   = new(sync.Once)
+	otpCodeDft       *TxPostgres[*entity.OtpCode]
 )
 
 func GetAssetTxPostgres(prop env.Properties) domain.Dft[*entity.Asset] {
@@ -55,6 +61,36 @@ func GetFavoritesTxPostgres(prop env.Properties) domain.Dft[*entity.Favorites] {
 	return favoritesDft
 }
 
+func GetNoteTxPostgres(prop env.Properties) domain.Dft[*entity.Note] {
+	onceNoteDft.Do(func() {
+		noteDft = new(TxPostgres[*entity.Note])
+		noteDft.cache = getNoteCache(prop)
+		noteDft.pool = prop.DBPool()
+		noteDft.sLog = prop.Logger()
+	})
+	return noteDft
+}
+
+func GetOtpTxPostgres(prop env.Properties) domain.Dft[*entity.Otp] {
+	onceOtpDft.Do(func() {
+		otpDft = new(TxPostgres[*entity.Otp])
+		otpDft.cache = getOtpCache(prop)
+		otpDft.pool = prop.DBPool()
+		otpDft.sLog = prop.Logger()
+	})
+	return otpDft
+}
+
+func GetOtpCodeTxPostgres(prop env.Properties) domain.Dft[*entity.OtpCode] {
+	onceOtpCodeDft.Do(func() {
+		otpCodeDft = new(TxPostgres[*entity.OtpCode])
+		otpCodeDft.cache = getOtpCodeCache(prop)
+		otpCodeDft.pool = prop.DBPool()
+		otpCodeDft.sLog = prop.Logger()
+	})
+	return otpCodeDft
+}
+
 func (p *TxPostgres[S]) DoDelete(ctx context.Context, entity S, scan func(domain.Scanner)) (err error) {
 
 	err = p.cache.delete(entity)
@@ -131,10 +167,22 @@ func scanPostgreTxArgs(
 	}
 	query := txArgs.SQLs[len(txArgs.SQLs)-1]
 	args := getTxArgs(txArgs, len(txArgs.SQLs)-1)
-	row := tx.QueryRow(ctx, query, args...)
+	row := &txRow{Row: tx.QueryRow(ctx, query, args...)}
 	scan(row)
 
-	return err
+	return row.err
+}
+
+// txRow строка результата последнего оператора транзакции: ошибка её чтения,
+// например строка не найдена, откатывает транзакцию.
+type txRow struct {
+	pgx.Row
+	err error
+}
+
+func (r *txRow) Scan(dest ...any) error {
+	r.err = r.Row.Scan(dest...)
+	return r.err
 }
 
 func getTxArgs(txArgs domain.TxArgs, i int) []any {
@@ -29,6 +29,14 @@ func TestTxPostgres(t *testing.T) {
 			name: "test #3 positive",
 			fRun: testAssetTxPostgresPositive,
 		},
+		{
+			name: "test #4 negative Note TxPostgres Repo",
+			fRun: testNoteTxPostgresNegative,
+		},
+		{
+			name: "test #5 negative Otp TxPostgres Repo",
+			fRun: testOtpTxPostgresNegative,
+		},
 	}
 
 	assert.NotNil(t, t)
@@ -119,6 +127,79 @@ func testFavoritesTxPostgresNegative(t *testing.T) {
 	assert.Equal(t, ErrBadPool, err)
 }
 
+func testNoteTxPostgresNegative(t *testing.T) {
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	dft := GetNoteTxPostgres(prop)
+	repo := GetNotePostgresCachedRepo(prop)
+	deletedRepo := GetNoteDeletedPostgresRepo(prop)
+
+	id = uuid.New()
+	upk = tool.RandStringBytes(32)
+	user := entity.MakeUser(upk, entity.DefaultTAttributes())
+	expected := entity.MakeNote(id, "note", user, "body", sql.NullInt64{}, entity.DefaultTAttributes())
+
+	var ok bool
+	inTransaction := func() {
+		ok = !ok
+	}
+	err := expected.Upsert(context.TODO(), dft, inTransaction)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrBadPool, err)
+
+	_, err = entity.GetNote(context.TODO(), repo, "note", upk)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrBadPool, err)
+
+	_, err = entity.GetNotesForUser(context.TODO(), repo, upk)
+	assert.NotNil(t, err)
+
+	_, err = entity.GetNotesDeletedForUser(context.TODO(), deletedRepo, upk)
+	assert.NotNil(t, err)
+
+	err = expected.Delete(context.TODO(), dft, inTransaction)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrBadPool, err)
+	assert.False(t, ok)
+}
+
+func testOtpTxPostgresNegative(t *testing.T) {
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("DATABASE_DSN", "")
+
+	prop := env.GetProperties()
+	dft := GetOtpTxPostgres(prop)
+	codeDft := GetOtpCodeTxPostgres(prop)
+	repo := GetOtpPostgresCachedRepo(prop)
+
+	upk = tool.RandStringBytes(32)
+	user := entity.MakeUser(upk, entity.DefaultTAttributes())
+	expected := entity.MakeOtp(
+		uuid.New(), "otp", user, entity.OtpKindHOTP, "secret", 6, 30, nil, entity.DefaultTAttributes(),
+	)
+	err := expected.Upsert(context.TODO(), dft)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrBadPool, err)
+
+	_, err = entity.GetOtp(context.TODO(), repo, "otp", upk)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrBadPool, err)
+
+	err = expected.Update(context.TODO(), repo)
+	assert.NotNil(t, err)
+
+	code := entity.MakeOtpCode("otp", upk, "12345678")
+	err = code.Consume(context.TODO(), codeDft)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrBadPool, err)
+
+	err = expected.Delete(context.TODO(), dft)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrBadPool, err)
+}
+
 func testFavoritesTxPostgresPositive(t *testing.T) {
 	defer func() { _ = recover() }()
 
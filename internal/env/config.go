@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 18:20 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * config.go
@@ -21,6 +21,9 @@ var _ Config = (*config)(nil)
 // Config статичная конфигурация собранная из Yaml-файла.
 type Config interface {
 	fmt.Stringer
+	AuthPolicyFile() string
+	AuthProvider() string
+	AuthUsersFile() string
 	CacheEnabled() bool
 	CacheExpireMs() int
 	CacheGCIntervalSec() int
@@ -33,6 +36,10 @@ type Config interface {
 	Enabled() bool
 	ExternalAssetGRPCAddress() string
 	ExternalAuthGRPCAddress() string
+	ExternalBackoffMaxDelayMs() int
+	ExternalConnectTimeoutMs() int
+	ExternalKeepaliveTimeMs() int
+	ExternalKeepaliveTimeoutMs() int
 	ExternalRequestTimeoutInterval() int
 	GRPCAddress() string
 	GRPCEnabled() bool
@@ -49,23 +56,71 @@ type Config interface {
 	HTTPTLSCertFile() string
 	HTTPTLSEnabled() bool
 	HTTPTLSKeyFile() string
+	JwtActiveKid() string
+	JwtAlgorithm() string
+	JwtCleanupIntervalSec() int
 	JwtExpiresIn() time.Duration
+	JwtKeys() []JwtKey
 	JwtMaxAgeSec() int
+	JwtRefreshExpiresIn() time.Duration
 	JwtSecret() string
+	KeysFileDir() string
+	KeysTransitAddress() string
+	KeysTransitMount() string
+	KeysTransitTimeoutMs() int
+	KeysTransitToken() string
+	MongoConnectTimeoutMs() int
 	MongoEnabled() bool
 	MongoHost() string
+	MongoMaxPoolSize() int
+	MongoMinPoolSize() int
 	MongoName() string
 	MongoPort() int
+	MongoReadConcern() string
+	MongoServerSelectionTimeoutMs() int
+	MongoTimeoutMs() int
 	MongoUserName() string
 	MongoUserPassword() string
-	Token() string
+	MongoWriteConcern() string
+	OutboxBackoffMaxMs() int
+	OutboxBackoffMs() int
+	OutboxBatchSize() int
+	OutboxEnabled() bool
+	OutboxMaxAttempts() int
+	OutboxPollIntervalMs() int
+	ReconcileConcurrency() int
+	ReconcileDryRun() bool
+	ReconcileEnabled() bool
+	ReconcileIntervalSec() int
+	ReconcilePageSize() int
+	SyncConflictResolver() string
+	SyncDrainTimeoutMs() int
+	SyncQueueSize() int
+	SyncShardID() string
+	SyncShards() []string
+	SyncStore() string
+	SyncStorePath() string
+	SyncTombstoneCompactionIntervalSec() int
+	SyncTombstoneRetentionSec() int
+	SyncWorkers() int
+	UpkKeyVersion() int
+	UpkKeyring() []UpkKeyConfig
+	UpkLegacyMigration() bool
 	UpkRSAPrivateKeyFile() string
 	UpkRSAPublicKeyFile() string
+	UpkRotationBatchSize() int
+	UpkRotationEnabled() bool
+	UpkRotationIntervalSec() int
 	UpkSecret() string
+	WatchEnabled() bool
+	WatchRetryIntervalMs() int
 }
 
 type config struct {
 	Favorites struct {
+		Auth struct {
+			authConfig `mapstructure:",squash"`
+		}
 		Cache struct {
 			Enabled     bool
 			cacheConfig `mapstructure:",squash"`
@@ -97,17 +152,41 @@ type config struct {
 		JWT struct {
 			jwtConfig `mapstructure:",squash"`
 		}
+		Keys struct {
+			keysConfig `mapstructure:",squash"`
+		}
 		MONGO struct {
-			Enabled  bool
-			dbConfig `mapstructure:",squash"`
+			Enabled     bool
+			dbConfig    `mapstructure:",squash"`
+			mongoConfig `mapstructure:",squash"`
+		}
+		Outbox struct {
+			Enabled      *bool
+			outboxConfig `mapstructure:",squash"`
 		}
-		goFavoritesConfig `mapstructure:",squash"`
-		UPK               struct {
+		Reconcile struct {
+			Enabled         bool
+			reconcileConfig `mapstructure:",squash"`
+		}
+		Sync struct {
+			syncConfig `mapstructure:",squash"`
+		}
+		UPK struct {
 			upkConfig `mapstructure:",squash"`
 		}
+		Watch struct {
+			Enabled     bool
+			watchConfig `mapstructure:",squash"`
+		}
 	}
 }
 
+type authConfig struct {
+	PolicyFile string `mapstructure:"policy_file"`
+	Provider   string `mapstructure:"provider"`
+	UsersFile  string `mapstructure:"users_file"`
+}
+
 type cacheConfig struct {
 	ExpireMs      int `mapstructure:"expire_ms"`
 	GCIntervalSec int `mapstructure:"gc_interval_sec"`
@@ -124,6 +203,10 @@ type dbConfig struct {
 type externalConfig struct {
 	AssetGRPCAddress       string `mapstructure:"asset_grpc_address"`
 	AuthGRPCAddress        string `mapstructure:"auth_grpc_address"`
+	BackoffMaxDelayMs      int    `mapstructure:"backoff_max_delay_ms"`
+	ConnectTimeoutMs       int    `mapstructure:"connect_timeout_ms"`
+	KeepaliveTimeMs        int    `mapstructure:"keepalive_time_ms"`
+	KeepaliveTimeoutMs     int    `mapstructure:"keepalive_timeout_ms"`
 	RequestTimeoutInterval int    `mapstructure:"request_timeout_interval_ms"`
 }
 
@@ -139,9 +222,78 @@ type httpConfig struct {
 }
 
 type jwtConfig struct {
+	ActiveKid    string        `mapstructure:"active_kid"`
+	Algorithm    string        `mapstructure:"algorithm"`
+	CleanupSec   int           `mapstructure:"cleanup_interval_sec"`
 	JwtSecret    string        `mapstructure:"jwt_secret"`
 	JwtExpiresIn time.Duration `mapstructure:"jwt_expired_in"`
 	JwtMaxAgeSec int           `mapstructure:"jwt_max_age_sec"`
+	Keys         []JwtKey      `mapstructure:"keys"`
+	RefreshIn    time.Duration `mapstructure:"refresh_expired_in"`
+}
+
+// JwtKey ключ связки для асимметричной подписи JWT (RS256/ES256).
+// Ключ без закрытой части применяется только для проверки подписи,
+// выведенный из оборота (retired) ключ не принимается и не публикуется.
+type JwtKey struct {
+	Kid            string `mapstructure:"kid"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+	Retired        bool   `mapstructure:"retired"`
+}
+
+type keysConfig struct {
+	FileDir string        `mapstructure:"file_dir"`
+	Transit transitConfig `mapstructure:"transit"`
+}
+
+type transitConfig struct {
+	Address   string `mapstructure:"address"`
+	Mount     string `mapstructure:"mount"`
+	TimeoutMs int    `mapstructure:"timeout_ms"`
+	Token     string `mapstructure:"token"`
+}
+
+type mongoConfig struct {
+	ConnectTimeoutMs         int    `mapstructure:"connect_timeout_ms"`
+	MaxPoolSize              int    `mapstructure:"max_pool_size"`
+	MinPoolSize              int    `mapstructure:"min_pool_size"`
+	ReadConcern              string `mapstructure:"read_concern"`
+	ServerSelectionTimeoutMs int    `mapstructure:"server_selection_timeout_ms"`
+	TimeoutMs                int    `mapstructure:"timeout_ms"`
+	WriteConcern             string `mapstructure:"write_concern"`
+}
+
+type outboxConfig struct {
+	BackoffMaxMs   int `mapstructure:"backoff_max_ms"`
+	BackoffMs      int `mapstructure:"backoff_ms"`
+	BatchSize      int `mapstructure:"batch_size"`
+	MaxAttempts    int `mapstructure:"max_attempts"`
+	PollIntervalMs int `mapstructure:"poll_interval_ms"`
+}
+
+type reconcileConfig struct {
+	Concurrency int  `mapstructure:"concurrency"`
+	DryRun      bool `mapstructure:"dry_run"`
+	IntervalSec int  `mapstructure:"interval_sec"`
+	PageSize    int  `mapstructure:"page_size"`
+}
+
+type syncConfig struct {
+	ConflictResolver               string   `mapstructure:"conflict_resolver"`
+	DrainTimeoutMs                 int      `mapstructure:"drain_timeout_ms"`
+	QueueSize                      int      `mapstructure:"queue_size"`
+	ShardID                        string   `mapstructure:"shard_id"`
+	Shards                         []string `mapstructure:"shards"`
+	Store                          string   `mapstructure:"store"`
+	StorePath                      string   `mapstructure:"store_path"`
+	TombstoneCompactionIntervalSec int      `mapstructure:"tombstone_compaction_interval_sec"`
+	TombstoneRetentionSec          int      `mapstructure:"tombstone_retention_sec"`
+	Workers                        int      `mapstructure:"workers"`
+}
+
+type watchConfig struct {
+	RetryIntervalMs int `mapstructure:"retry_interval_ms"`
 }
 
 type tlsConfig struct {
@@ -150,16 +302,59 @@ type tlsConfig struct {
 	KeyFile  string `mapstructure:"key_file"`
 }
 
-type goFavoritesConfig struct {
-	Token string `mapstructure:"token"`
+type upkConfig struct {
+	KeyVersion        int               `mapstructure:"key_version"`
+	Keyring           []UpkKeyConfig    `mapstructure:"keyring"`
+	LegacyMigration   bool              `mapstructure:"legacy_migration"`
+	RSAPrivateKeyFile string            `mapstructure:"rsa_private_key_file"`
+	RSAPublicKeyFile  string            `mapstructure:"rsa_public_key_file"`
+	Rotation          upkRotationConfig `mapstructure:"rotation"`
+	Secret            string            `mapstructure:"secret"`
 }
 
-type upkConfig struct {
+// UpkKeyConfig предыдущая версия секрета UPK: секрет зашифрован RSA ключом
+// из RSAPrivateKeyFile, если он не задан — текущим RSA ключом UPK.
+type UpkKeyConfig struct {
+	Version           int    `mapstructure:"version"`
 	RSAPrivateKeyFile string `mapstructure:"rsa_private_key_file"`
-	RSAPublicKeyFile  string `mapstructure:"rsa_public_key_file"`
 	Secret            string `mapstructure:"secret"`
 }
 
+type upkRotationConfig struct {
+	BatchSize   int  `mapstructure:"batch_size"`
+	Enabled     bool `mapstructure:"enabled"`
+	IntervalSec int  `mapstructure:"interval_sec"`
+}
+
+// AuthPolicyFile файл политики доступа: роли для методов gRPC и маршрутов HTTP,
+// если не задан применяется встроенная политика.
+func (y *config) AuthPolicyFile() string {
+
+	if y != nil {
+		return y.Favorites.Auth.PolicyFile
+	}
+	return ""
+}
+
+// AuthProvider способ проверки учётных данных пользователя при входе:
+// grpc — внешний сервис аутентификации, local — локальный файл пользователей.
+func (y *config) AuthProvider() string {
+
+	if y != nil {
+		return y.Favorites.Auth.Provider
+	}
+	return ""
+}
+
+// AuthUsersFile файл пользователей для локальной проверки учётных данных.
+func (y *config) AuthUsersFile() string {
+
+	if y != nil {
+		return y.Favorites.Auth.UsersFile
+	}
+	return ""
+}
+
 // CacheEnabled тумблер включения локального кэша.
 func (y *config) CacheEnabled() bool {
 
@@ -266,6 +461,42 @@ func (y *config) ExternalAuthGRPCAddress() string {
 	return ""
 }
 
+// ExternalBackoffMaxDelayMs наибольшая задержка переподключения к внешним gRPC-сервисам в миллисекундах.
+func (y *config) ExternalBackoffMaxDelayMs() int {
+
+	if y != nil {
+		return y.Favorites.External.BackoffMaxDelayMs
+	}
+	return 0
+}
+
+// ExternalConnectTimeoutMs время в миллисекундах на установку соединения с внешними gRPC-сервисами.
+func (y *config) ExternalConnectTimeoutMs() int {
+
+	if y != nil {
+		return y.Favorites.External.ConnectTimeoutMs
+	}
+	return 0
+}
+
+// ExternalKeepaliveTimeMs интервал проверки (keepalive) соединений с внешними gRPC-сервисами в миллисекундах.
+func (y *config) ExternalKeepaliveTimeMs() int {
+
+	if y != nil {
+		return y.Favorites.External.KeepaliveTimeMs
+	}
+	return 0
+}
+
+// ExternalKeepaliveTimeoutMs время ожидания ответа на проверку (keepalive) соединения в миллисекундах.
+func (y *config) ExternalKeepaliveTimeoutMs() int {
+
+	if y != nil {
+		return y.Favorites.External.KeepaliveTimeoutMs
+	}
+	return 0
+}
+
 // ExternalRequestTimeoutInterval интервал ожидания ответа от внешних gRPC-сервисов.
 func (y *config) ExternalRequestTimeoutInterval() int {
 
@@ -410,6 +641,34 @@ func (y *config) HTTPTLSEnabled() bool {
 	return false
 }
 
+// JwtActiveKid идентификатор (kid) ключа связки которым подписываются новые JWT.
+func (y *config) JwtActiveKid() string {
+
+	if y != nil {
+		return y.Favorites.JWT.ActiveKid
+	}
+	return ""
+}
+
+// JwtAlgorithm алгоритм подписи JWT: HS256 (по умолчанию), RS256 или ES256.
+func (y *config) JwtAlgorithm() string {
+
+	if y != nil {
+		return y.Favorites.JWT.Algorithm
+	}
+	return ""
+}
+
+// JwtCleanupIntervalSec интервал удаления истёкших отозванных JWT и истёкших
+// или погашенных токенов обновления в секундах.
+func (y *config) JwtCleanupIntervalSec() int {
+
+	if y != nil {
+		return y.Favorites.JWT.CleanupSec
+	}
+	return 0
+}
+
 // JwtExpiresIn Утверждение «exp» (время истечения срока действия)
 // определяет время истечения срока действия или после чего JWT
 // НЕ ДОЛЖЕН приниматься в обработку.
@@ -421,6 +680,15 @@ func (y *config) JwtExpiresIn() time.Duration {
 	return 0
 }
 
+// JwtKeys связка ключей для асимметричной подписи JWT.
+func (y *config) JwtKeys() []JwtKey {
+
+	if y != nil {
+		return y.Favorites.JWT.Keys
+	}
+	return nil
+}
+
 // JwtMaxAgeSec определяет время жизни куки в секундах.
 func (y *config) JwtMaxAgeSec() int {
 
@@ -430,6 +698,15 @@ func (y *config) JwtMaxAgeSec() int {
 	return 0
 }
 
+// JwtRefreshExpiresIn время истечения срока действия токена обновления.
+func (y *config) JwtRefreshExpiresIn() time.Duration {
+
+	if y != nil {
+		return y.Favorites.JWT.RefreshIn
+	}
+	return 0
+}
+
 // JwtSecret секрет для подписи JWТокена.
 func (y *config) JwtSecret() string {
 
@@ -439,6 +716,61 @@ func (y *config) JwtSecret() string {
 	return ""
 }
 
+// KeysFileDir каталог для относительных ссылок file: поставщика ключей.
+func (y *config) KeysFileDir() string {
+
+	if y != nil {
+		return y.Favorites.Keys.FileDir
+	}
+	return ""
+}
+
+// KeysTransitAddress адрес сервиса transit (Vault) для ссылок transit:,
+// пустой — ссылки transit: не поддерживаются.
+func (y *config) KeysTransitAddress() string {
+
+	if y != nil {
+		return y.Favorites.Keys.Transit.Address
+	}
+	return ""
+}
+
+// KeysTransitMount путь механизма transit, по умолчанию transit.
+func (y *config) KeysTransitMount() string {
+
+	if y != nil {
+		return y.Favorites.Keys.Transit.Mount
+	}
+	return ""
+}
+
+// KeysTransitTimeoutMs время в миллисекундах на запрос к сервису transit.
+func (y *config) KeysTransitTimeoutMs() int {
+
+	if y != nil {
+		return y.Favorites.Keys.Transit.TimeoutMs
+	}
+	return 0
+}
+
+// KeysTransitToken токен доступа к сервису transit, может быть ссылкой env:, env-file: или file:.
+func (y *config) KeysTransitToken() string {
+
+	if y != nil {
+		return y.Favorites.Keys.Transit.Token
+	}
+	return ""
+}
+
+// MongoConnectTimeoutMs время в миллисекундах на установку соединения с MongoDB.
+func (y *config) MongoConnectTimeoutMs() int {
+
+	if y != nil {
+		return y.Favorites.MONGO.ConnectTimeoutMs
+	}
+	return 0
+}
+
 // MongoEnabled тумблер подключения к MongoDB.
 func (y *config) MongoEnabled() bool {
 
@@ -457,6 +789,24 @@ func (y *config) MongoHost() string {
 	return ""
 }
 
+// MongoMaxPoolSize наибольшее число соединений в пуле клиента MongoDB.
+func (y *config) MongoMaxPoolSize() int {
+
+	if y != nil {
+		return y.Favorites.MONGO.MaxPoolSize
+	}
+	return 0
+}
+
+// MongoMinPoolSize число соединений, которые клиент MongoDB держит открытыми.
+func (y *config) MongoMinPoolSize() int {
+
+	if y != nil {
+		return y.Favorites.MONGO.MinPoolSize
+	}
+	return 0
+}
+
 // MongoName имя базы данных MongoDB.
 func (y *config) MongoName() string {
 
@@ -475,6 +825,33 @@ func (y *config) MongoPort() int {
 	return 0
 }
 
+// MongoReadConcern уровень read concern MongoDB: local, majority, available, linearizable, snapshot.
+func (y *config) MongoReadConcern() string {
+
+	if y != nil {
+		return y.Favorites.MONGO.ReadConcern
+	}
+	return ""
+}
+
+// MongoServerSelectionTimeoutMs время в миллисекундах на выбор сервера MongoDB для операции.
+func (y *config) MongoServerSelectionTimeoutMs() int {
+
+	if y != nil {
+		return y.Favorites.MONGO.ServerSelectionTimeoutMs
+	}
+	return 0
+}
+
+// MongoTimeoutMs время в миллисекундах на выполнение одной операции MongoDB.
+func (y *config) MongoTimeoutMs() int {
+
+	if y != nil {
+		return y.Favorites.MONGO.TimeoutMs
+	}
+	return 0
+}
+
 // MongoUserName имя пользователя базы данных MongoDB.
 func (y *config) MongoUserName() string {
 
@@ -493,14 +870,44 @@ func (y *config) MongoUserPassword() string {
 	return ""
 }
 
-func (y *config) Token() string {
+// MongoWriteConcern write concern MongoDB: majority или число подтверждающих узлов.
+func (y *config) MongoWriteConcern() string {
 
 	if y != nil {
-		return y.Favorites.Token
+		return y.Favorites.MONGO.WriteConcern
 	}
 	return ""
 }
 
+// UpkKeyVersion версия текущего секрета UPK, записывается вместе с UPK пользователя.
+func (y *config) UpkKeyVersion() int {
+
+	if y != nil {
+		return y.Favorites.UPK.KeyVersion
+	}
+	return 0
+}
+
+// UpkKeyring предыдущие версии секрета UPK: ими дешифруются заметки, секреты OTP
+// и токены обновления, записанные до смены версии, поэтому версии не удаляются.
+func (y *config) UpkKeyring() []UpkKeyConfig {
+
+	if y != nil {
+		return y.Favorites.UPK.Keyring
+	}
+	return nil
+}
+
+// UpkLegacyMigration тумблер переноса данных пользователей с UPK прежнего
+// формата (AES одного блока) на слепой индекс при обращении пользователя.
+func (y *config) UpkLegacyMigration() bool {
+
+	if y != nil {
+		return y.Favorites.UPK.LegacyMigration
+	}
+	return false
+}
+
 // UpkRSAPrivateKeyFile RSA ключ для дешифрации секрета
 // который применяется в симметричном шифровании UPK (User Personal Key).
 func (y *config) UpkRSAPrivateKeyFile() string {
@@ -521,6 +928,33 @@ func (y *config) UpkRSAPublicKeyFile() string {
 	return ""
 }
 
+// UpkRotationBatchSize количество пользователей переносимых на текущую версию секрета UPK за шаг.
+func (y *config) UpkRotationBatchSize() int {
+
+	if y != nil {
+		return y.Favorites.UPK.Rotation.BatchSize
+	}
+	return 0
+}
+
+// UpkRotationEnabled тумблер фонового переноса пользователей на текущую версию секрета UPK.
+func (y *config) UpkRotationEnabled() bool {
+
+	if y != nil {
+		return y.Favorites.UPK.Rotation.Enabled
+	}
+	return false
+}
+
+// UpkRotationIntervalSec интервал между проходами переноса на текущую версию секрета UPK в секундах.
+func (y *config) UpkRotationIntervalSec() int {
+
+	if y != nil {
+		return y.Favorites.UPK.Rotation.IntervalSec
+	}
+	return 0
+}
+
 // UpkSecret секрет который применяется в симметричном шифровании UPK (User Personal Key).
 func (y *config) UpkSecret() string {
 
@@ -530,9 +964,231 @@ func (y *config) UpkSecret() string {
 	return ""
 }
 
+// OutboxBackoffMaxMs верхняя граница задержки повторной доставки события outbox в миллисекундах.
+func (y *config) OutboxBackoffMaxMs() int {
+
+	if y != nil {
+		return y.Favorites.Outbox.BackoffMaxMs
+	}
+	return 0
+}
+
+// OutboxBackoffMs начальная задержка повторной доставки события outbox в миллисекундах,
+// удваивается с каждой неудачной попыткой.
+func (y *config) OutboxBackoffMs() int {
+
+	if y != nil {
+		return y.Favorites.Outbox.BackoffMs
+	}
+	return 0
+}
+
+// OutboxBatchSize количество событий outbox выбираемых диспетчером за один проход.
+func (y *config) OutboxBatchSize() int {
+
+	if y != nil {
+		return y.Favorites.Outbox.BatchSize
+	}
+	return 0
+}
+
+// OutboxEnabled тумблер запуска диспетчера доставки событий outbox в MongoDB,
+// по умолчанию включён: изменения избранного доходят до MongoDB только через outbox.
+func (y *config) OutboxEnabled() bool {
+
+	if y != nil && y.Favorites.Outbox.Enabled != nil {
+		return *y.Favorites.Outbox.Enabled
+	}
+	return true
+}
+
+// OutboxMaxAttempts количество попыток доставки после которого событие
+// переводится в dead-letter.
+func (y *config) OutboxMaxAttempts() int {
+
+	if y != nil {
+		return y.Favorites.Outbox.MaxAttempts
+	}
+	return 0
+}
+
+// OutboxPollIntervalMs интервал опроса таблицы outbox диспетчером в миллисекундах.
+func (y *config) OutboxPollIntervalMs() int {
+
+	if y != nil {
+		return y.Favorites.Outbox.PollIntervalMs
+	}
+	return 0
+}
+
+// ReconcileConcurrency количество пользователей сверяемых одновременно.
+func (y *config) ReconcileConcurrency() int {
+
+	if y != nil {
+		return y.Favorites.Reconcile.Concurrency
+	}
+	return 0
+}
+
+// ReconcileDryRun сверка только формирует отчёт о расхождениях, без исправления.
+func (y *config) ReconcileDryRun() bool {
+
+	if y != nil {
+		return y.Favorites.Reconcile.DryRun
+	}
+	return false
+}
+
+// ReconcileEnabled тумблер запуска фоновой сверки избранного между PostgreSQL и MongoDB.
+func (y *config) ReconcileEnabled() bool {
+
+	if y != nil {
+		return y.Favorites.Reconcile.Enabled
+	}
+	return false
+}
+
+// ReconcileIntervalSec интервал между проходами сверки в секундах.
+func (y *config) ReconcileIntervalSec() int {
+
+	if y != nil {
+		return y.Favorites.Reconcile.IntervalSec
+	}
+	return 0
+}
+
+// ReconcilePageSize количество пользователей выбираемых из PostgreSQL за один запрос.
+func (y *config) ReconcilePageSize() int {
+
+	if y != nil {
+		return y.Favorites.Reconcile.PageSize
+	}
+	return 0
+}
+
+// SyncConflictResolver стратегия разрешения конфликтов при синхронизации
+// избранного между MongoDB и PostgreSQL: lww, union или prefer_local.
+func (y *config) SyncConflictResolver() string {
+
+	if y != nil {
+		return y.Favorites.Sync.ConflictResolver
+	}
+	return ""
+}
+
+// SyncDrainTimeoutMs время в миллисекундах на выполнение задач синхронизации
+// оставшихся в очереди при выключении сервиса.
+func (y *config) SyncDrainTimeoutMs() int {
+
+	if y != nil {
+		return y.Favorites.Sync.DrainTimeoutMs
+	}
+	return 0
+}
+
+// SyncQueueSize размер очереди задач фоновой синхронизации, при переполнении
+// задача отбрасывается до следующего обращения пользователя или сверки.
+func (y *config) SyncQueueSize() int {
+
+	if y != nil {
+		return y.Favorites.Sync.QueueSize
+	}
+	return 0
+}
+
+// SyncShardID идентификатор шарда (экземпляра PostgreSQL) этого сервиса,
+// которым подтверждаются надгробия (tombstones) в MongoDB и помечается
+// происхождение записей избранного в обеих базах.
+func (y *config) SyncShardID() string {
+
+	if y != nil {
+		return y.Favorites.Sync.ShardID
+	}
+	return ""
+}
+
+// SyncShards идентификаторы всех шардов синхронизируемых через MongoDB,
+// надгробие удаляется после подтверждения всеми шардами.
+func (y *config) SyncShards() []string {
+
+	if y != nil {
+		return y.Favorites.Sync.Shards
+	}
+	return nil
+}
+
+// SyncStore хранилище синхронизации избранного между шардами:
+// mongodb (по умолчанию), bolt — встроенное на диске, memory — в памяти процесса.
+func (y *config) SyncStore() string {
+
+	if y != nil {
+		return y.Favorites.Sync.Store
+	}
+	return ""
+}
+
+// SyncStorePath путь к файлу встроенного хранилища синхронизации bolt.
+func (y *config) SyncStorePath() string {
+
+	if y != nil {
+		return y.Favorites.Sync.StorePath
+	}
+	return ""
+}
+
+// SyncTombstoneCompactionIntervalSec интервал очистки подтверждённых надгробий в секундах,
+// 0 — очистка выключена.
+func (y *config) SyncTombstoneCompactionIntervalSec() int {
+
+	if y != nil {
+		return y.Favorites.Sync.TombstoneCompactionIntervalSec
+	}
+	return 0
+}
+
+// SyncTombstoneRetentionSec минимальное время хранения надгробия в секундах.
+func (y *config) SyncTombstoneRetentionSec() int {
+
+	if y != nil {
+		return y.Favorites.Sync.TombstoneRetentionSec
+	}
+	return 0
+}
+
+// SyncWorkers количество исполнителей задач фоновой синхронизации.
+func (y *config) SyncWorkers() int {
+
+	if y != nil {
+		return y.Favorites.Sync.Workers
+	}
+	return 0
+}
+
+// WatchEnabled тумблер чтения потока изменений (change stream) избранного MongoDB
+// для применения изменений других шардов к PostgreSQL этого шарда.
+func (y *config) WatchEnabled() bool {
+
+	if y != nil {
+		return y.Favorites.Watch.Enabled
+	}
+	return false
+}
+
+// WatchRetryIntervalMs задержка переподключения к потоку изменений после ошибки в миллисекундах.
+func (y *config) WatchRetryIntervalMs() int {
+
+	if y != nil {
+		return y.Favorites.Watch.RetryIntervalMs
+	}
+	return 0
+}
+
 func (y *config) String() string {
 	return fmt.Sprintf(
-		`CacheEnabled: %v
+		`AuthPolicyFile: %s
+AuthProvider: %s
+AuthUsersFile: %s
+CacheEnabled: %v
 CacheExpire: %d
 CacheGCInterval: %d
 DBHost: %s
@@ -544,6 +1200,10 @@ DBUserPassword: %s
 Enabled: %v
 ExternalAssetGRPCAddress: %s
 ExternalAuthGRPCAddress: %s
+ExternalBackoffMaxDelayMs: %d
+ExternalConnectTimeoutMs: %d
+ExternalKeepaliveTimeMs: %d
+ExternalKeepaliveTimeoutMs: %d
 ExternalRequestTimeoutInterval: %d
 GRPCAddress: %s
 GRPCEnabled: %v
@@ -560,16 +1220,59 @@ HTTPTLSCAFile: %s
 HTTPTLSCertFile: %s
 HTTPTLSEnabled: %v
 HTTPTLSKeyFile: %s
+KeysFileDir: %s
+KeysTransitAddress: %s
+KeysTransitMount: %s
+KeysTransitTimeoutMs: %d
+KeysTransitToken: %s
+MongoConnectTimeoutMs: %d
 MongoHost: %s
+MongoMaxPoolSize: %d
+MongoMinPoolSize: %d
 MongoName: %s
 MongoEnabled: %v
 MongoPort: %d
+MongoReadConcern: %s
+MongoServerSelectionTimeoutMs: %d
+MongoTimeoutMs: %d
 MongoUserName: %s
 MongoUserPassword: %s
-Token: %s
+MongoWriteConcern: %s
+OutboxBackoffMaxMs: %d
+OutboxBackoffMs: %d
+OutboxBatchSize: %d
+OutboxEnabled: %v
+OutboxMaxAttempts: %d
+OutboxPollIntervalMs: %d
+ReconcileConcurrency: %d
+ReconcileDryRun: %v
+ReconcileEnabled: %v
+ReconcileIntervalSec: %d
+ReconcilePageSize: %d
+SyncConflictResolver: %s
+SyncDrainTimeoutMs: %d
+SyncQueueSize: %d
+SyncShardID: %s
+SyncShards: %v
+SyncStore: %s
+SyncStorePath: %s
+SyncTombstoneCompactionIntervalSec: %d
+SyncTombstoneRetentionSec: %d
+SyncWorkers: %d
+UpkKeyVersion: %d
+UpkKeyring: %v
+UpkLegacyMigration: %v
 UpkRSAPrivateKeyFile: %s
 UpkRSAPublicKeyFile: %s
-UpkSecretKey: %s`,
+UpkRotationBatchSize: %d
+UpkRotationEnabled: %v
+UpkRotationIntervalSec: %d
+UpkSecretKey: %s
+WatchEnabled: %v
+WatchRetryIntervalMs: %d`,
+		y.AuthPolicyFile(),
+		y.AuthProvider(),
+		y.AuthUsersFile(),
 		y.CacheEnabled(),
 		y.CacheExpireMs(),
 		y.CacheGCIntervalSec(),
@@ -582,6 +1285,10 @@ UpkSecretKey: %s`,
 		y.Enabled(),
 		y.ExternalAssetGRPCAddress(),
 		y.ExternalAuthGRPCAddress(),
+		y.ExternalBackoffMaxDelayMs(),
+		y.ExternalConnectTimeoutMs(),
+		y.ExternalKeepaliveTimeMs(),
+		y.ExternalKeepaliveTimeoutMs(),
 		y.ExternalRequestTimeoutInterval(),
 		y.GRPCAddress(),
 		y.GRPCEnabled(),
@@ -598,18 +1305,69 @@ UpkSecretKey: %s`,
 		y.HTTPTLSCertFile(),
 		y.HTTPTLSEnabled(),
 		y.HTTPTLSKeyFile(),
+		y.KeysFileDir(),
+		y.KeysTransitAddress(),
+		y.KeysTransitMount(),
+		y.KeysTransitTimeoutMs(),
+		y.KeysTransitToken(),
+		y.MongoConnectTimeoutMs(),
 		y.MongoHost(),
+		y.MongoMaxPoolSize(),
+		y.MongoMinPoolSize(),
 		y.MongoName(),
 		y.MongoEnabled(),
 		y.MongoPort(),
+		y.MongoReadConcern(),
+		y.MongoServerSelectionTimeoutMs(),
+		y.MongoTimeoutMs(),
 		y.MongoUserName(),
 		base64.StdEncoding.EncodeToString([]byte(y.MongoUserPassword())),
-		y.Token(),
+		y.MongoWriteConcern(),
+		y.OutboxBackoffMaxMs(),
+		y.OutboxBackoffMs(),
+		y.OutboxBatchSize(),
+		y.OutboxEnabled(),
+		y.OutboxMaxAttempts(),
+		y.OutboxPollIntervalMs(),
+		y.ReconcileConcurrency(),
+		y.ReconcileDryRun(),
+		y.ReconcileEnabled(),
+		y.ReconcileIntervalSec(),
+		y.ReconcilePageSize(),
+		y.SyncConflictResolver(),
+		y.SyncDrainTimeoutMs(),
+		y.SyncQueueSize(),
+		y.SyncShardID(),
+		y.SyncShards(),
+		y.SyncStore(),
+		y.SyncStorePath(),
+		y.SyncTombstoneCompactionIntervalSec(),
+		y.SyncTombstoneRetentionSec(),
+		y.SyncWorkers(),
+		y.UpkKeyVersion(),
+		upkKeyringVersions(y.UpkKeyring()),
+		y.UpkLegacyMigration(),
 		y.UpkRSAPrivateKeyFile(),
 		y.UpkRSAPublicKeyFile(),
+		y.UpkRotationBatchSize(),
+		y.UpkRotationEnabled(),
+		y.UpkRotationIntervalSec(),
 		base64.StdEncoding.EncodeToString([]byte(y.UpkSecret())),
+		y.WatchEnabled(),
+		y.WatchRetryIntervalMs(),
 	)
 }
 
+// upkKeyringVersions версии предыдущих секретов UPK без самих секретов.
+func upkKeyringVersions(keyring []UpkKeyConfig) []int {
+
+	result := make([]int, 0, len(keyring))
+
+	for _, key := range keyring {
+		result = append(result, key.Version)
+	}
+	return result
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -1,6 +1,6 @@
 /*
  * Copyright text:
- * This file was last modified at 2024-07-10 20:02 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * config_test.go
@@ -28,7 +28,10 @@ func TestConfig(t *testing.T) {
 			name:  `positive test #0 nil config`,
 			fRun:  nilConfig,
 			isNil: true,
-			want: `CacheEnabled: false
+			want: `AuthPolicyFile: 
+AuthProvider: 
+AuthUsersFile: 
+CacheEnabled: false
 CacheExpire: 0
 CacheGCInterval: 0
 DBHost: 
@@ -40,6 +43,10 @@ DBUserPassword:
 Enabled: false
 ExternalAssetGRPCAddress: 
 ExternalAuthGRPCAddress: 
+ExternalBackoffMaxDelayMs: 0
+ExternalConnectTimeoutMs: 0
+ExternalKeepaliveTimeMs: 0
+ExternalKeepaliveTimeoutMs: 0
 ExternalRequestTimeoutInterval: 0
 GRPCAddress: 
 GRPCEnabled: false
@@ -56,21 +63,64 @@ HTTPTLSCAFile:
 HTTPTLSCertFile: 
 HTTPTLSEnabled: false
 HTTPTLSKeyFile: 
+KeysFileDir: 
+KeysTransitAddress: 
+KeysTransitMount: 
+KeysTransitTimeoutMs: 0
+KeysTransitToken: 
+MongoConnectTimeoutMs: 0
 MongoHost: 
+MongoMaxPoolSize: 0
+MongoMinPoolSize: 0
 MongoName: 
 MongoEnabled: false
 MongoPort: 0
+MongoReadConcern: 
+MongoServerSelectionTimeoutMs: 0
+MongoTimeoutMs: 0
 MongoUserName: 
 MongoUserPassword: 
-Token: 
+MongoWriteConcern: 
+OutboxBackoffMaxMs: 0
+OutboxBackoffMs: 0
+OutboxBatchSize: 0
+OutboxEnabled: true
+OutboxMaxAttempts: 0
+OutboxPollIntervalMs: 0
+ReconcileConcurrency: 0
+ReconcileDryRun: false
+ReconcileEnabled: false
+ReconcileIntervalSec: 0
+ReconcilePageSize: 0
+SyncConflictResolver: 
+SyncDrainTimeoutMs: 0
+SyncQueueSize: 0
+SyncShardID: 
+SyncShards: []
+SyncStore: 
+SyncStorePath: 
+SyncTombstoneCompactionIntervalSec: 0
+SyncTombstoneRetentionSec: 0
+SyncWorkers: 0
+UpkKeyVersion: 0
+UpkKeyring: []
+UpkLegacyMigration: false
 UpkRSAPrivateKeyFile: 
 UpkRSAPublicKeyFile: 
-UpkSecretKey: `,
+UpkRotationBatchSize: 0
+UpkRotationEnabled: false
+UpkRotationIntervalSec: 0
+UpkSecretKey: 
+WatchEnabled: false
+WatchRetryIntervalMs: 0`,
 		},
 		{
 			name: `positive test #1 zero config`,
 			fRun: zeroConfig,
-			want: `CacheEnabled: false
+			want: `AuthPolicyFile: 
+AuthProvider: 
+AuthUsersFile: 
+CacheEnabled: false
 CacheExpire: 0
 CacheGCInterval: 0
 DBHost: 
@@ -82,6 +132,10 @@ DBUserPassword:
 Enabled: false
 ExternalAssetGRPCAddress: 
 ExternalAuthGRPCAddress: 
+ExternalBackoffMaxDelayMs: 0
+ExternalConnectTimeoutMs: 0
+ExternalKeepaliveTimeMs: 0
+ExternalKeepaliveTimeoutMs: 0
 ExternalRequestTimeoutInterval: 0
 GRPCAddress: 
 GRPCEnabled: false
@@ -98,16 +152,56 @@ HTTPTLSCAFile:
 HTTPTLSCertFile: 
 HTTPTLSEnabled: false
 HTTPTLSKeyFile: 
+KeysFileDir: 
+KeysTransitAddress: 
+KeysTransitMount: 
+KeysTransitTimeoutMs: 0
+KeysTransitToken: 
+MongoConnectTimeoutMs: 0
 MongoHost: 
+MongoMaxPoolSize: 0
+MongoMinPoolSize: 0
 MongoName: 
 MongoEnabled: false
 MongoPort: 0
+MongoReadConcern: 
+MongoServerSelectionTimeoutMs: 0
+MongoTimeoutMs: 0
 MongoUserName: 
 MongoUserPassword: 
-Token: 
+MongoWriteConcern: 
+OutboxBackoffMaxMs: 0
+OutboxBackoffMs: 0
+OutboxBatchSize: 0
+OutboxEnabled: true
+OutboxMaxAttempts: 0
+OutboxPollIntervalMs: 0
+ReconcileConcurrency: 0
+ReconcileDryRun: false
+ReconcileEnabled: false
+ReconcileIntervalSec: 0
+ReconcilePageSize: 0
+SyncConflictResolver: 
+SyncDrainTimeoutMs: 0
+SyncQueueSize: 0
+SyncShardID: 
+SyncShards: []
+SyncStore: 
+SyncStorePath: 
+SyncTombstoneCompactionIntervalSec: 0
+SyncTombstoneRetentionSec: 0
+SyncWorkers: 0
+UpkKeyVersion: 0
+UpkKeyring: []
+UpkLegacyMigration: false
 UpkRSAPrivateKeyFile: 
 UpkRSAPublicKeyFile: 
-UpkSecretKey: `,
+UpkRotationBatchSize: 0
+UpkRotationEnabled: false
+UpkRotationIntervalSec: 0
+UpkSecretKey: 
+WatchEnabled: false
+WatchRetryIntervalMs: 0`,
 		},
 	}
 	assert.NotNil(t, t)
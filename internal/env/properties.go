@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 20:17 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * properties.go
@@ -13,7 +13,6 @@ package env
 import (
 	"crypto/rsa"
 	"crypto/tls"
-	"encoding/base64"
 	"fmt"
 	"log/slog"
 	"net"
@@ -21,18 +20,23 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vskurikhin/gofavorites/internal/keys"
+	"github.com/vskurikhin/gofavorites/internal/policy"
 	"github.com/vskurikhin/gofavorites/internal/tool"
 	"google.golang.org/grpc/credentials"
 )
 
 const (
+	propertyAuthPolicy                     = "auth-policy"
 	propertyCacheExpireMs                  = "cache-expire"
 	propertyCacheGCIntervalSec             = "cache-gc-interval"
 	propertyConfig                         = "config"
 	propertyDBPool                         = "db-pool"
 	propertyEnvironments                   = "environments"
 	propertyExternalAssetGRPCAddress       = "external-asset-grpc-address"
+	propertyExternalAssetGRPCClient        = "external-asset-grpc-client"
 	propertyExternalAuthGRPCAddress        = "external-auth-grpc-address"
+	propertyExternalAuthGRPCClient         = "external-auth-grpc-client"
 	propertyExternalRequestTimeoutInterval = "external-request-timeout-interval"
 	propertyFlags                          = "flags"
 	propertyGRPCAddress                    = "grpc-address"
@@ -42,8 +46,10 @@ const (
 	propertyJwtExpiresIn                   = "jwt-expires-in"
 	propertyJwtMaxAgeSec                   = "jwt-max-age-sec"
 	propertyJwtSecret                      = "jwt-secret"
+	propertyKeyResolver                    = "key-resolver"
 	propertyLogger                         = "logger"
-	propertyMongodbPool                    = "mongodb-pool"
+	propertyMongodbClient                  = "mongodb-client"
+	propertyUpkKeyring                     = "upk-keyring"
 	propertyUpkRSAPrivateKey               = "upk-rsa-private-key"
 	propertyUpkRSAPublicKey                = "upk-rsa-public-key"
 	propertyUpkSecretKey                   = "upk-secret-key"
@@ -52,13 +58,16 @@ const (
 // Properties конфигурация собранная из Yaml-файла, переменных окружения и флагов командной строки.
 type Properties interface {
 	fmt.Stringer
+	AuthPolicy() *policy.Policy
 	CacheExpire() time.Duration
 	CacheGCInterval() time.Duration
 	Config() Config
 	DBPool() *pgxpool.Pool
 	Environments() environments
 	ExternalAssetGRPCAddress() string
+	ExternalAssetGRPCClient() *tool.GRPCClient
 	ExternalAuthGRPCAddress() string
+	ExternalAuthGRPCClient() *tool.GRPCClient
 	ExternalRequestTimeoutInterval() time.Duration
 	Flags() map[string]interface{}
 	GRPCAddress() string
@@ -68,10 +77,12 @@ type Properties interface {
 	JwtExpiresIn() time.Duration
 	JwtMaxAgeSec() int
 	JwtSecret() string
+	KeyResolver() *keys.Resolver
 	Logger() *slog.Logger
-	MongodbPool() *tool.MongoPool
+	MongodbClient() *tool.MongoClient
 	SlogJSON() bool
 	OutboundIP() net.IP
+	UpkKeyring() *tool.Keyring
 	UpkRSAPrivateKey() *rsa.PrivateKey
 	UpkRSAPublicKey() *rsa.PublicKey
 	UpkSecretKey() []byte
@@ -95,6 +106,13 @@ func GetProperties() Properties {
 		tool.IfErrorThenPanic(err)
 		flm := makeFlagsParse()
 
+		// с ошибкой загрузки политики сервис не запускается, см. CheckAuthPolicy.
+		authPolicy, err := getAuthPolicy(yml)
+		if err != nil {
+			slog.Error(MSG+"GetProperties", "msg", "load auth policy", "err", err)
+			authPolicy = new(policy.Policy)
+		}
+
 		cacheExpire, err := getCacheExpire(flm, env, yml)
 		slog.Debug(MSG+"GetProperties", "cacheExpire", cacheExpire, "err", err)
 		cacheGCInterval, err := getCacheGCInterval(flm, env, yml)
@@ -117,6 +135,10 @@ func GetProperties() Properties {
 		slog.Debug(MSG+"GetProperties", "assetGRPCAddress", assetGRPCAddress, "err", err)
 		authGRPCAddress, err := getExternalAuthGRPCAddress(flm, env, yml)
 		slog.Debug(MSG+"GetProperties", "authGRPCAddress", authGRPCAddress, "err", err)
+		assetGRPCClient, err := makeExternalGRPCClient(assetGRPCAddress, yml)
+		slog.Debug(MSG+"GetProperties", "assetGRPCDisable", err)
+		authGRPCClient, err := makeExternalGRPCClient(authGRPCAddress, yml)
+		slog.Debug(MSG+"GetProperties", "authGRPCDisable", err)
 		requestTimeoutInterval, err := getExternalRequestTimeoutInterval(flm, env, yml)
 		slog.Debug(MSG+"GetProperties", "requestTimeoutInterval", requestTimeoutInterval, "err", err)
 
@@ -124,26 +146,41 @@ func GetProperties() Properties {
 		slog.Debug(MSG+"GetProperties", "jwtExpiresIn", jwtExpiresIn, "err", err)
 		jwtMaxAgeSec, err := getJwtMaxAgeSec(flm, env, yml)
 		slog.Debug(MSG+"GetProperties", "jwtMaxAgeSec", jwtMaxAgeSec, "err", err)
-		jwtSecret, err := getJwtSecret(flm, env, yml)
-		slog.Debug(MSG+"GetProperties", "jwtSecret", jwtSecret, "err", err)
+		keyResolver, err := getKeyResolver(yml)
+		if err != nil {
+			slog.Error(MSG+"GetProperties", "msg", "key resolver", "err", err)
+		}
+		jwtSecret, err := getJwtSecret(flm, env, yml, keyResolver)
+		if err != nil {
+			slog.Error(MSG+"GetProperties", "msg", "jwt secret", "err", err)
+		}
 
-		mongodbPool, err := makeMongodbPool(flm, env, yml)
+		mongodbClient, err := makeMongodbClient(flm, env, yml)
 		slog.Debug(MSG+"GetProperties", "mongodbDisable", err)
 
-		upkRSAPrivateKey, err := getRSAPrivateKey(flm, env, yml)
+		upkRSAPrivateKey, err := getRSAPrivateKey(flm, env, yml, keyResolver)
 		slog.Debug(MSG+"GetProperties", "upkRSAPrivateKey", upkRSAPrivateKey, "err", err)
-		upkRSAPublicKey, err := getRSAPublicKey(flm, env, yml)
+		upkRSAPublicKey, err := getRSAPublicKey(flm, env, yml, keyResolver)
 		slog.Debug(MSG+"GetProperties", "upkRSAPublicKey", upkRSAPublicKey, "err", err)
-		upkSecretKey, err := getUpkSecretKey(flm, env, yml, upkRSAPrivateKey)
-		slog.Debug(MSG+"GetProperties", "upkSecretKey", base64.StdEncoding.EncodeToString(upkSecretKey), "err", err)
+		upkSecretKey, err := getUpkSecretKey(flm, env, yml, keyResolver, upkRSAPrivateKey)
+		if err != nil {
+			slog.Error(MSG+"GetProperties", "msg", "upk secret", "err", err)
+		}
+		upkKeyring, err := getUpkKeyring(yml, keyResolver, upkSecretKey, upkRSAPrivateKey)
+		if err != nil {
+			slog.Error(MSG+"GetProperties", "msg", "load upk keyring", "err", err)
+		}
 
 		properties = getProperties(
+			WithAuthPolicy(authPolicy),
 			WithCacheExpire(cacheExpire),
 			WithCacheGCInterval(cacheGCInterval),
 			WithConfig(yml),
 			WithEnvironments(*env),
 			WithExternalAssetGRPCAddress(assetGRPCAddress),
 			WithExternalAuthGRPCAddress(authGRPCAddress),
+			withExternalAssetGRPCClient(assetGRPCClient),
+			withExternalAuthGRPCClient(authGRPCClient),
 			WithExternalRequestTimeoutInterval(requestTimeoutInterval),
 			WithFlags(flm),
 			withDBPool(dbPool),
@@ -154,8 +191,10 @@ func GetProperties() Properties {
 			WithJwtExpiresIn(jwtExpiresIn),
 			WithJwtMaxAgeSec(jwtMaxAgeSec),
 			WithJwtSecret(jwtSecret),
+			WithKeyResolver(keyResolver),
 			WithLogger(setupLogger(slogJSON(flm))),
-			withMongodbPool(mongodbPool),
+			withMongodbClient(mongodbClient),
+			WithUpkKeyring(upkKeyring),
 			WithUpkRSAPrivateKey(upkRSAPrivateKey),
 			WithUpkRSAPublicKey(upkRSAPublicKey),
 			WithUpkSecretKey(upkSecretKey),
@@ -165,6 +204,32 @@ func GetProperties() Properties {
 	return properties
 }
 
+// WithAuthPolicy — политика доступа.
+func WithAuthPolicy(authPolicy *policy.Policy) func(*mapProperties) {
+	return func(p *mapProperties) {
+		if authPolicy != nil {
+			p.mp.Store(propertyAuthPolicy, authPolicy)
+		}
+	}
+}
+
+// AuthPolicy геттер политики доступа, если она не задана — встроенная политика.
+func (p *mapProperties) AuthPolicy() *policy.Policy {
+	if a, ok := p.mp.Load(propertyAuthPolicy); ok {
+		if authPolicy, ok := a.(*policy.Policy); ok {
+			return authPolicy
+		}
+	}
+	return policy.Default()
+}
+
+// CheckAuthPolicy проверка файла политики доступа при старте: файл из auth.policy_file
+// прочитан и разобран, иначе все вызовы были бы запрещены.
+func CheckAuthPolicy(prop Properties) error {
+	_, err := getAuthPolicy(prop.Config())
+	return err
+}
+
 // WithCacheExpire — срок действия записи в кэше.
 func WithCacheExpire(cacheExpire time.Duration) func(*mapProperties) {
 	return func(p *mapProperties) {
@@ -256,6 +321,25 @@ func (p *mapProperties) ExternalAssetGRPCAddress() string {
 	return ""
 }
 
+// ExternalAssetGRPCClient долгоживущий клиент gRPC-сервиса по биржевым инструментам.
+func (p *mapProperties) ExternalAssetGRPCClient() *tool.GRPCClient {
+	if c, ok := p.mp.Load(propertyExternalAssetGRPCClient); ok {
+		if client, ok := c.(*tool.GRPCClient); ok {
+			return client
+		}
+	}
+	return nil
+}
+
+// withExternalAssetGRPCClient — долгоживущий клиент gRPC-сервиса по биржевым инструментам.
+func withExternalAssetGRPCClient(client *tool.GRPCClient) func(*mapProperties) {
+	return func(p *mapProperties) {
+		if client != nil {
+			p.mp.Store(propertyExternalAssetGRPCClient, client)
+		}
+	}
+}
+
 // WithExternalAuthGRPCAddress — внешний адрес gRPC-сервиса аутентификации пользователей.
 func WithExternalAuthGRPCAddress(address string) func(*mapProperties) {
 	return func(p *mapProperties) {
@@ -273,6 +357,25 @@ func (p *mapProperties) ExternalAuthGRPCAddress() string {
 	return ""
 }
 
+// ExternalAuthGRPCClient долгоживущий клиент gRPC-сервиса аутентификации пользователей.
+func (p *mapProperties) ExternalAuthGRPCClient() *tool.GRPCClient {
+	if c, ok := p.mp.Load(propertyExternalAuthGRPCClient); ok {
+		if client, ok := c.(*tool.GRPCClient); ok {
+			return client
+		}
+	}
+	return nil
+}
+
+// withExternalAuthGRPCClient — долгоживущий клиент gRPC-сервиса аутентификации пользователей.
+func withExternalAuthGRPCClient(client *tool.GRPCClient) func(*mapProperties) {
+	return func(p *mapProperties) {
+		if client != nil {
+			p.mp.Store(propertyExternalAuthGRPCClient, client)
+		}
+	}
+}
+
 // WithExternalRequestTimeoutInterval — интервал ожидания ответа от внешних gRPC-сервисов.
 func WithExternalRequestTimeoutInterval(timeoutInterval time.Duration) func(*mapProperties) {
 	return func(p *mapProperties) {
@@ -444,6 +547,26 @@ func (p *mapProperties) JwtSecret() string {
 	return ""
 }
 
+// WithKeyResolver — поставщики ключевого материала по ссылкам в настройках.
+func WithKeyResolver(resolver *keys.Resolver) func(*mapProperties) {
+	return func(p *mapProperties) {
+		if resolver != nil {
+			p.mp.Store(propertyKeyResolver, resolver)
+		}
+	}
+}
+
+// KeyResolver геттер поставщиков ключевого материала, если они не заданы —
+// nil: ссылки не разрешаются, значения используются как есть.
+func (p *mapProperties) KeyResolver() *keys.Resolver {
+	if a, ok := p.mp.Load(propertyKeyResolver); ok {
+		if resolver, ok := a.(*keys.Resolver); ok {
+			return resolver
+		}
+	}
+	return nil
+}
+
 // WithLogger — логгер приложения.
 func WithLogger(logger *slog.Logger) func(*mapProperties) {
 	return func(p *mapProperties) {
@@ -463,11 +586,11 @@ func (p *mapProperties) Logger() *slog.Logger {
 	return slog.Default()
 }
 
-// MongodbPool пул подключения к базе данных MongoDB.
-func (p *mapProperties) MongodbPool() *tool.MongoPool {
-	if p, ok := p.mp.Load(propertyMongodbPool); ok {
-		if pool, ok := p.(*tool.MongoPool); ok {
-			return pool
+// MongodbClient клиент базы данных MongoDB с пулом соединений.
+func (p *mapProperties) MongodbClient() *tool.MongoClient {
+	if p, ok := p.mp.Load(propertyMongodbClient); ok {
+		if client, ok := p.(*tool.MongoClient); ok {
+			return client
 		}
 	}
 	return nil
@@ -517,6 +640,31 @@ func (p *mapProperties) UpkRSAPublicKey() *rsa.PublicKey {
 	return nil
 }
 
+// WithUpkKeyring — связка версий секрета UPK (User Personal Key).
+func WithUpkKeyring(keyring *tool.Keyring) func(*mapProperties) {
+	return func(p *mapProperties) {
+		if keyring != nil {
+			p.mp.Store(propertyUpkKeyring, keyring)
+		}
+	}
+}
+
+// UpkKeyring геттер связки версий секрета UPK, если она не задана —
+// связка из одного секрета UpkSecretKey.
+func (p *mapProperties) UpkKeyring() *tool.Keyring {
+	if a, ok := p.mp.Load(propertyUpkKeyring); ok {
+		if keyring, ok := a.(*tool.Keyring); ok {
+			return keyring
+		}
+	}
+	version := 0
+
+	if p.Config() != nil {
+		version = p.Config().UpkKeyVersion()
+	}
+	return tool.NewKeyring(tool.KeyVersion{Version: version, Key: p.UpkSecretKey()})
+}
+
 // WithUpkSecretKey — секрет симметричного шифрования UPK (User Personal Key).
 func WithUpkSecretKey(secretKey []byte) func(*mapProperties) {
 	return func(p *mapProperties) {
@@ -555,11 +703,11 @@ func (p *mapProperties) DBPool() *pgxpool.Pool {
 	return nil
 }
 
-// withMongodbPool — пул подключения к базе данных MongoDB.
-func withMongodbPool(pool *tool.MongoPool) func(*mapProperties) {
+// withMongodbClient — клиент базы данных MongoDB с пулом соединений.
+func withMongodbClient(client *tool.MongoClient) func(*mapProperties) {
 	return func(p *mapProperties) {
-		if pool != nil {
-			p.mp.Store(propertyMongodbPool, pool)
+		if client != nil {
+			p.mp.Store(propertyMongodbClient, client)
 		}
 	}
 }
@@ -576,7 +724,9 @@ CacheGCInterval: %v
 DBPool: %v
 Environments: %v
 ExternalAssetGRPCAddress: %s
+ExternalAssetGRPCClient: %v
 ExternalAuthGRPCAddress: %s
+ExternalAuthGRPCClient: %v
 ExternalRequestTimeoutInterval: %d
 Flags: %v
 GRPCAddress: %s
@@ -586,7 +736,7 @@ HTTPTransportCredentials: %v
 JwtExpiresIn: %v
 JwtMaxAgeSec: %d
 JwtSecret: %s
-MongodbPool: %v
+MongodbClient: %v
 OutboundIP: %v
 UpkRSAPrivateKey: %v
 UpkRSAPublicKey: %v
@@ -599,7 +749,9 @@ UpkSecretKey: %v
 		p.DBPool(),
 		p.Environments(),
 		p.ExternalAssetGRPCAddress(),
+		p.ExternalAssetGRPCClient().Stats(),
 		p.ExternalAuthGRPCAddress(),
+		p.ExternalAuthGRPCClient().Stats(),
 		p.ExternalRequestTimeoutInterval(),
 		p.Flags(),
 		p.GRPCAddress(),
@@ -609,7 +761,7 @@ UpkSecretKey: %v
 		p.JwtExpiresIn(),
 		p.JwtMaxAgeSec(),
 		p.JwtSecret(),
-		p.MongodbPool(),
+		p.MongodbClient(),
 		p.OutboundIP(),
 		p.UpkRSAPrivateKey(),
 		p.UpkRSAPublicKey(),
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-04 18:46 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * properties_tool.go
@@ -12,9 +12,11 @@ package env
 
 import (
 	"bytes"
+	"context"
 	"crypto/rsa"
 	"crypto/tls"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -24,11 +26,20 @@ import (
 	"github.com/vskurikhin/gofavorites/internal/alog"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vskurikhin/gofavorites/internal/keys"
+	"github.com/vskurikhin/gofavorites/internal/policy"
 	"github.com/vskurikhin/gofavorites/internal/tool"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
 )
 
-var ErrEmptyAddress = fmt.Errorf("can't configure epmty address")
+var (
+	ErrBadRSAKey       = fmt.Errorf("bad RSA key")
+	ErrEmptyAddress    = fmt.Errorf("can't configure epmty address")
+	ErrUpkSecretLength = fmt.Errorf("upk secret must be 16, 24 or 32 bytes")
+)
 
 func getCacheExpire(flm map[string]interface{}, env *environments, yml Config) (time.Duration, error) {
 	return toTimePrepareProperty(
@@ -59,6 +70,13 @@ func getExternalAssetGRPCAddress(flm map[string]interface{}, env *environments,
 	)
 }
 
+func getAuthPolicy(yml Config) (*policy.Policy, error) {
+	if yml.AuthPolicyFile() == "" {
+		return policy.Default(), nil
+	}
+	return policy.Load(yml.AuthPolicyFile())
+}
+
 func getExternalAuthGRPCAddress(flm map[string]interface{}, env *environments, yml Config) (string, error) {
 	return stringsAddressPrepareProperty(
 		flagExternalAuthGRPCAddress,
@@ -151,30 +169,69 @@ func getJwtMaxAgeSec(flm map[string]interface{}, env *environments, yml Config)
 	)
 }
 
-func getJwtSecret(flm map[string]interface{}, env *environments, yml Config) (string, error) {
-	return stringPrepareProperty(
+func getJwtSecret(flm map[string]interface{}, env *environments, yml Config, resolver *keys.Resolver) (string, error) {
+
+	secret, err := stringPrepareProperty(
 		flagJwtSecret,
 		flm[flagJwtSecret],
 		env.JwtSecret,
 		yml.JwtSecret(),
 	)
+	if err != nil {
+		return secret, err
+	}
+	result, err := resolver.Resolve(context.Background(), secret)
+
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// getKeyResolver поставщики ключевого материала по ссылкам в настройках:
+// file:, env:, env-file: и, если задан адрес сервиса transit, transit:.
+// Без адреса сервиса ссылка transit: — ошибка keys.ErrProviderNotConfigured.
+func getKeyResolver(yml Config) (*keys.Resolver, error) {
+
+	providers := map[string]keys.KeyProvider{
+		keys.SchemeEnv:     keys.NewEnvProvider(),
+		keys.SchemeEnvFile: keys.NewEnvFileProvider(),
+		keys.SchemeFile:    keys.NewFileProvider(yml.KeysFileDir()),
+	}
+	if yml.KeysTransitAddress() == "" {
+		return keys.NewResolver(providers), nil
+	}
+	token, err := keys.NewResolver(providers).Resolve(context.Background(), yml.KeysTransitToken())
+
+	if err != nil {
+		return keys.NewResolver(providers), fmt.Errorf("transit token: %w", err)
+	}
+	providers[keys.SchemeTransit] = keys.NewTransitProvider(
+		yml.KeysTransitAddress(),
+		yml.KeysTransitMount(),
+		string(token),
+		time.Duration(yml.KeysTransitTimeoutMs())*time.Millisecond,
+	)
+	return keys.NewResolver(providers), nil
 }
 
-func getRSAPrivateKey(flm map[string]interface{}, env *environments, yml Config) (*rsa.PrivateKey, error) {
+func getRSAPrivateKey(flm map[string]interface{}, env *environments, yml Config, resolver *keys.Resolver) (*rsa.PrivateKey, error) {
 	return loadRSAPrivateKey(
 		flagUpkPrivateKeyFile,
 		flm[flagUpkPrivateKeyFile],
 		env.UpkPrivateKeyFile,
 		yml.UpkRSAPrivateKeyFile(),
+		resolver,
 	)
 }
 
-func getRSAPublicKey(flm map[string]interface{}, env *environments, yml Config) (*rsa.PublicKey, error) {
+func getRSAPublicKey(flm map[string]interface{}, env *environments, yml Config, resolver *keys.Resolver) (*rsa.PublicKey, error) {
 	return loadRSAPublicKey(
 		flagUpkPublicKeyFile,
 		flm[flagUpkPublicKeyFile],
 		env.UpkPublicKeyFile,
 		yml.UpkRSAPublicKeyFile(),
+		resolver,
 	)
 }
 
@@ -191,6 +248,7 @@ func getUpkSecretKey(
 	flm map[string]interface{},
 	env *environments,
 	yml Config,
+	resolver *keys.Resolver,
 	rsaPrivateKey *rsa.PrivateKey,
 ) ([]byte, error) {
 
@@ -199,6 +257,66 @@ func getUpkSecretKey(
 	if err != nil {
 		return nil, err
 	}
+	return resolveUpkSecret(resolver, secret, rsaPrivateKey)
+}
+
+// getUpkKeyring связка версий секрета UPK: текущий секрет secretKey
+// и предыдущие версии из upk.keyring, версии с ошибкой пропускаются.
+func getUpkKeyring(
+	yml Config,
+	resolver *keys.Resolver,
+	secretKey []byte,
+	rsaPrivateKey *rsa.PrivateKey,
+) (*tool.Keyring, error) {
+
+	var errs error
+	current := tool.KeyVersion{Version: yml.UpkKeyVersion(), Key: secretKey}
+	previous := make([]tool.KeyVersion, 0, len(yml.UpkKeyring()))
+
+	for _, key := range yml.UpkKeyring() {
+
+		privateKey := rsaPrivateKey
+
+		if key.RSAPrivateKeyFile != "" {
+			var err error
+			if privateKey, err = resolveRSAPrivateKey(resolver, key.RSAPrivateKeyFile); err != nil {
+				errs = errors.Join(errs, fmt.Errorf("upk key version %d: %w", key.Version, err))
+				continue
+			}
+		}
+		secret, err := resolveUpkSecret(resolver, key.Secret, privateKey)
+
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("upk key version %d: %w", key.Version, err))
+			continue
+		}
+		previous = append(previous, tool.KeyVersion{Version: key.Version, Key: secret})
+	}
+	return tool.NewKeyring(current, previous...), errs
+}
+
+// resolveUpkSecret секрет UPK: по ссылке поставщика ключей — сам ключ AES
+// длиной 16, 24 или 32 байта, иначе — секрет зашифрованный RSA, base64.
+func resolveUpkSecret(resolver *keys.Resolver, secret string, rsaPrivateKey *rsa.PrivateKey) ([]byte, error) {
+
+	if !resolver.IsReference(secret) {
+		return decryptUpkSecret(secret, rsaPrivateKey)
+	}
+	secretKey, err := resolver.Resolve(context.Background(), secret)
+
+	if err != nil {
+		return nil, err
+	}
+	switch len(secretKey) {
+	case 16, 24, 32:
+		return secretKey, nil
+	}
+	return nil, fmt.Errorf("%w: %d bytes", ErrUpkSecretLength, len(secretKey))
+}
+
+// decryptUpkSecret дешифрация секрета UPK зашифрованного RSA, base64.
+func decryptUpkSecret(secret string, rsaPrivateKey *rsa.PrivateKey) ([]byte, error) {
+
 	encrypt, err := base64.StdEncoding.DecodeString(secret)
 
 	if err != nil {
@@ -240,24 +358,65 @@ func intPrepareProperty(name string, flag interface{}, env int, yaml int) (int,
 	return result, err
 }
 
-func loadRSAPrivateKey(name string, flag interface{}, env string, yaml string) (*rsa.PrivateKey, error) {
+func loadRSAPrivateKey(
+	name string,
+	flag interface{},
+	env string,
+	yaml string,
+	resolver *keys.Resolver,
+) (*rsa.PrivateKey, error) {
 
 	fileName, err := getFileName(name, flag, env, yaml)
 
 	if err != nil {
 		return nil, err
 	}
-	return tool.LoadPrivateKey(fileName), err
+	return resolveRSAPrivateKey(resolver, fileName)
 }
 
-func loadRSAPublicKey(name string, flag interface{}, env string, yaml string) (*rsa.PublicKey, error) {
+func loadRSAPublicKey(
+	name string,
+	flag interface{},
+	env string,
+	yaml string,
+	resolver *keys.Resolver,
+) (*rsa.PublicKey, error) {
 
 	fileName, err := getFileName(name, flag, env, yaml)
 
 	if err != nil {
 		return nil, err
 	}
-	return tool.LoadPublicKey(fileName), err
+	if !resolver.IsReference(fileName) {
+		return tool.LoadPublicKey(fileName), nil
+	}
+	pem, err := resolver.Resolve(context.Background(), fileName)
+
+	if err != nil {
+		return nil, err
+	}
+	if result := tool.ParsePublicKey(pem); result != nil {
+		return result, nil
+	}
+	return nil, ErrBadRSAKey
+}
+
+// resolveRSAPrivateKey RSA ключ из файла fileName или, если это ссылка
+// поставщика ключей, из полученного по ссылке PEM.
+func resolveRSAPrivateKey(resolver *keys.Resolver, fileName string) (*rsa.PrivateKey, error) {
+
+	if !resolver.IsReference(fileName) {
+		return tool.LoadPrivateKey(fileName), nil
+	}
+	pem, err := resolver.Resolve(context.Background(), fileName)
+
+	if err != nil {
+		return nil, err
+	}
+	if result := tool.ParsePrivateKey(pem); result != nil {
+		return result, nil
+	}
+	return nil, ErrBadRSAKey
 }
 
 func getFileName(name string, flag interface{}, env string, yaml string) (string, error) {
@@ -308,7 +467,7 @@ func makeDBPool(flm map[string]interface{}, env *environments, yml Config) (*pgx
 	return nil, fmt.Errorf("connect to DataBase disabled")
 }
 
-func makeMongodbPool(flm map[string]interface{}, env *environments, yml Config) (*tool.MongoPool, error) {
+func makeMongodbClient(flm map[string]interface{}, env *environments, yml Config) (*tool.MongoClient, error) {
 	if yml.MongoEnabled() {
 
 		dsn := fmt.Sprintf(
@@ -324,13 +483,56 @@ func makeMongodbPool(flm map[string]interface{}, env *environments, yml Config)
 			getFlagDatabaseDSN()
 		}
 		setIfFlagChanged(flagMongodbDSN, getFlagDatabaseDSN)
-		slog.Debug(MSG+"makeMongodbPool", "MongodbDSN", dsn)
+		slog.Debug(MSG+"makeMongodbClient", "MongodbDSN", dsn)
 
-		return tool.MongodbConnect(dsn), nil
+		return tool.MongodbConnect(dsn, mongoOptions(yml)), nil
 	}
 	return nil, fmt.Errorf("connect to MongoDB disabled")
 }
 
+// makeExternalGRPCClient долгоживущий клиент внешнего gRPC-сервиса по адресам address,
+// TLS включается если задан сертификат центра сертификации gRPC.
+func makeExternalGRPCClient(address string, yml Config) (*tool.GRPCClient, error) {
+
+	if address == "" {
+		return nil, ErrEmptyAddress
+	}
+	opts := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
+	}
+	tlsCredentials, err := tool.LoadClientTLSCredentials(yml.GRPCTLSCAFile())
+
+	if err != nil {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(tlsCredentials))
+	}
+	return tool.GRPCConnect(address, grpcOptions(yml), opts...), nil
+}
+
+// grpcOptions параметры клиентов внешних gRPC-сервисов из секции external конфигурации.
+func grpcOptions(yml Config) tool.GRPCOptions {
+	return tool.GRPCOptions{
+		BackoffMaxDelay:  time.Duration(yml.ExternalBackoffMaxDelayMs()) * time.Millisecond,
+		ConnectTimeout:   time.Duration(yml.ExternalConnectTimeoutMs()) * time.Millisecond,
+		KeepaliveTime:    time.Duration(yml.ExternalKeepaliveTimeMs()) * time.Millisecond,
+		KeepaliveTimeout: time.Duration(yml.ExternalKeepaliveTimeoutMs()) * time.Millisecond,
+	}
+}
+
+// mongoOptions параметры клиента MongoDB из секции mongo конфигурации.
+func mongoOptions(yml Config) tool.MongoOptions {
+	return tool.MongoOptions{
+		ConnectTimeout:         time.Duration(yml.MongoConnectTimeoutMs()) * time.Millisecond,
+		MaxPoolSize:            uint64(max(yml.MongoMaxPoolSize(), 0)),
+		MinPoolSize:            uint64(max(yml.MongoMinPoolSize(), 0)),
+		ReadConcern:            yml.MongoReadConcern(),
+		ServerSelectionTimeout: time.Duration(yml.MongoServerSelectionTimeoutMs()) * time.Millisecond,
+		Timeout:                time.Duration(yml.MongoTimeoutMs()) * time.Millisecond,
+		WriteConcern:           yml.MongoWriteConcern(),
+	}
+}
+
 func parseEnvAddress(address []string) string {
 
 	port, err := strconv.Atoi(address[len(address)-1])
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-03 12:36 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * properties_tool_test.go
@@ -11,9 +11,19 @@
 package env
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/keys"
+	"github.com/vskurikhin/gofavorites/internal/tool"
 )
 
 func TestPropertiesToolNegative(t *testing.T) {
@@ -55,7 +65,7 @@ func TestPropertiesToolNegative(t *testing.T) {
 }
 
 func testGetUpkSecretKey(_ *testing.T) (interface{}, error) {
-	return getUpkSecretKey(make(map[string]interface{}), &environments{}, &config{}, nil)
+	return getUpkSecretKey(make(map[string]interface{}), &environments{}, &config{}, nil, nil)
 }
 
 func testIntPrepareProperty(t *testing.T) (interface{}, error) {
@@ -94,5 +104,92 @@ func testStringsAddressPrepareProperty(t *testing.T) (interface{}, error) {
 	return nil, err
 }
 
+func TestGetUpkKeyring(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	encrypted, err := tool.EncryptRSA(&privateKey.PublicKey, []byte("previous"))
+	assert.Nil(t, err)
+	yml := &config{}
+	yml.Favorites.UPK.KeyVersion = 3
+	yml.Favorites.UPK.Keyring = []UpkKeyConfig{
+		{Version: 2, Secret: base64.StdEncoding.EncodeToString(encrypted)},
+		{Version: 1, Secret: "!"},
+	}
+	got, err := getUpkKeyring(yml, nil, []byte("current"), privateKey)
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, got.Current().Version)
+	assert.Equal(t, []byte("current"), got.Current().Key)
+	key, ok := got.Key(2)
+	assert.True(t, ok)
+	assert.Equal(t, "previous", string(key[:8]))
+	assert.Len(t, key, 32)
+	_, ok = got.Key(1)
+	assert.False(t, ok)
+}
+
+func TestCheckAuthPolicy(t *testing.T) {
+	yml := &config{}
+	assert.Nil(t, CheckAuthPolicy(getProperties(WithConfig(yml))))
+	yml.Favorites.Auth.PolicyFile = filepath.Join(t.TempDir(), "missing.policy.yaml")
+	assert.NotNil(t, CheckAuthPolicy(getProperties(WithConfig(yml))))
+	yml.Favorites.Auth.PolicyFile = filepath.Join(t.TempDir(), "invalid.policy.yaml")
+	assert.Nil(t, os.WriteFile(yml.Favorites.Auth.PolicyFile, []byte("grpc:\n  /proto.FavoritesService/Get: []\n"), 0600))
+	assert.NotNil(t, CheckAuthPolicy(getProperties(WithConfig(yml))))
+}
+
+func TestGetKeyResolver(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "upk-secret"), []byte("0123456789abcdef\n"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "upk.pem"), pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}), 0600))
+	t.Setenv("GOFAVORITES_TEST_JWT_SECRET", "jwt secret")
+
+	yml := &config{}
+	yml.Favorites.Keys.FileDir = dir
+	yml.Favorites.JWT.JwtSecret = "env:GOFAVORITES_TEST_JWT_SECRET"
+	yml.Favorites.UPK.Secret = "file:upk-secret"
+	yml.Favorites.UPK.RSAPrivateKeyFile = "file:upk.pem"
+	resolver, err := getKeyResolver(yml)
+	assert.Nil(t, err)
+	_, err = resolver.Resolve(context.TODO(), "transit:upk:vault:v1:AA==")
+	assert.ErrorIs(t, err, keys.ErrProviderNotConfigured)
+	_, err = resolver.Resolve(context.TODO(), "flie:upk-secret")
+	assert.ErrorIs(t, err, keys.ErrUnknownScheme)
+
+	jwtSecret, err := getJwtSecret(make(map[string]interface{}), &environments{}, yml, resolver)
+	assert.Nil(t, err)
+	assert.Equal(t, "jwt secret", jwtSecret)
+	got, err := getRSAPrivateKey(make(map[string]interface{}), &environments{}, yml, resolver)
+	assert.Nil(t, err)
+	assert.Equal(t, privateKey, got)
+	secretKey, err := getUpkSecretKey(make(map[string]interface{}), &environments{}, yml, resolver, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("0123456789abcdef"), secretKey)
+
+	t.Setenv("GOFAVORITES_TEST_UPK_SECRET", "secret")
+	yml.Favorites.UPK.Secret = "env:GOFAVORITES_TEST_UPK_SECRET"
+	_, err = getUpkSecretKey(make(map[string]interface{}), &environments{}, yml, resolver, nil)
+	assert.ErrorIs(t, err, ErrUpkSecretLength)
+	yml.Favorites.UPK.Secret = "file:upk-secret"
+
+	yml.Favorites.UPK.RSAPrivateKeyFile = "file:upk-secret"
+	_, err = getRSAPrivateKey(make(map[string]interface{}), &environments{}, yml, resolver)
+	assert.ErrorIs(t, err, ErrBadRSAKey)
+
+	yml.Favorites.Keys.Transit.Address = "http://127.0.0.1:8200"
+	yml.Favorites.Keys.Transit.Token = "env:GOFAVORITES_TEST_UNDEFINED"
+	_, err = getKeyResolver(yml)
+	assert.ErrorIs(t, err, keys.ErrKeyNotFound)
+	t.Setenv("GOFAVORITES_TEST_VAULT_TOKEN", "token")
+	yml.Favorites.Keys.Transit.Token = "env:GOFAVORITES_TEST_VAULT_TOKEN"
+	resolver, err = getKeyResolver(yml)
+	assert.Nil(t, err)
+	assert.True(t, resolver.IsReference("transit:upk:vault:v1:AA=="))
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 18:25 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * yaml_load.go
@@ -22,6 +22,10 @@ import (
 // favorites:
 //
 //	enabled: true
+//	auth:
+//	  policy_file: go-favorites.policy.yaml
+//	  provider: grpc
+//	  users_file: go-favorites.users
 //	cache:
 //	  enabled: true
 //	  expire_ms: 1000
@@ -36,6 +40,10 @@ import (
 //	external:
 //	  asset_grpc_address: 127.0.0.1:8444
 //	  auth_grpc_address: 127.0.0.1:8444
+//	  backoff_max_delay_ms: 120000
+//	  connect_timeout_ms: 20000
+//	  keepalive_time_ms: 300000
+//	  keepalive_timeout_ms: 20000
 //	  request_timeout_interval_ms: 911
 //	grpc:
 //	  address: localhost
@@ -60,6 +68,21 @@ import (
 //	  jwt_secret: TzzVGdLUJGcYKaf5he4zeLW5QdSJws9UoUug3Q3kCMeLVijBSjPY3k0pNu2XWhB
 //	  jwt_expired_in: 60m
 //	  jwt_max_age_sec: 3600
+//	  refresh_expired_in: 720h
+//	  algorithm: RS256
+//	  active_kid: 2024-08
+//	  keys:
+//	    - kid: 2024-07
+//	      public_key_file: cert/jwt-2024-07-public-key.pem
+//	    - kid: 2024-08
+//	      private_key_file: cert/jwt-2024-08-private-key.pem
+//	keys:
+//	  file_dir: /run/secrets
+//	  transit:
+//	    address: http://127.0.0.1:8200
+//	    mount: transit
+//	    timeout_ms: 5000
+//	    token: env:VAULT_TOKEN
 //	mongo:
 //	  enabled: true
 //	  name: db
@@ -67,11 +90,51 @@ import (
 //	  port: 27017
 //	  username: mongouser
 //	  password: password
-//	token: '$2a$11$ZTzzVGdLUJGcYKJws9UoUug3Q3kCMELVziajBSJPY3k0pNu2XWHBy'
+//	  max_pool_size: 100
+//	  min_pool_size: 0
+//	  connect_timeout_ms: 10000
+//	  server_selection_timeout_ms: 30000
+//	  timeout_ms: 5000
+//	  read_concern: majority
+//	  write_concern: majority
+//	outbox:
+//	  enabled: true
+//	  batch_size: 100
+//	  poll_interval_ms: 1000
+//	  max_attempts: 10
+//	  backoff_ms: 500
+//	  backoff_max_ms: 60000
+//	reconcile:
+//	  enabled: true
+//	  interval_sec: 86400
+//	  page_size: 100
+//	  concurrency: 4
+//	  dry_run: true
+//	sync:
+//	  conflict_resolver: lww
+//	  shard_id: shard-1
+//	  shards: [shard-1]
+//	  store: mongodb
+//	  store_path: data/go-favorites-sync.db
+//	  tombstone_compaction_interval_sec: 3600
+//	  tombstone_retention_sec: 604800
+//	  workers: 4
+//	  queue_size: 1024
+//	  drain_timeout_ms: 10000
 //	upk:
+//	  key_version: 1
+//	  keyring: []
+//	  legacy_migration: true
 //	  rsa_private_key_file: cert/upk-private-key.pem
 //	  rsa_public_key_file: cert/upk-public-key.pem
+//	  rotation:
+//	    batch_size: 100
+//	    enabled: false
+//	    interval_sec: 3600
 //	  secret: g16Ug0b1zVaCYQzxD45C6p99fUxMkaSL2npjmi5qBjRMAy6kjpZP0/zahKE4zQGvTlp7lKavV4z3RWIm9Uch1pBgaYLZ/pAZDbgr8roqVc/QEzQnsaLqoe7ZzOcPsj7NzbrXz/l+rWVAGdyAkLGs7NIZ3GgNlyZ5lrjglAIRdHA6PpW0jBzbcKb5Z5Y5U80N75+wrenlWPFUKTrN8exuUhzLK6FHWpAzuivD+pg42bZFvdSLE/0oXd0U1W+SxSBXv3RxEkRMquYG+9/VHpT745BzF+QQlR+CicLC5XaUusAZKtqFf3LokISPY1kxjP32gW3SqtZThZa/4pPMpesrXA==
+//	watch:
+//	  enabled: false
+//	  retry_interval_ms: 5000
 func LoadConfig(path string) (cfg Config, err error) {
 
 	if os.Getenv("GO_FAVORITES_SKIP_LOAD_CONFIG") != "" {
@@ -1,6 +1,6 @@
 /*
  * Copyright text:
- * This file was last modified at 2024-07-10 20:32 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * yaml_load_test.go
@@ -36,6 +36,9 @@ func TestLoadConfig(t *testing.T) {
 			fRun:  LoadConfig,
 			want: want{
 				yamlConfig: &config{Favorites: struct {
+					Auth struct {
+						authConfig `mapstructure:",squash"`
+					}
 					Cache struct {
 						Enabled     bool
 						cacheConfig `mapstructure:",squash"`
@@ -67,15 +70,40 @@ func TestLoadConfig(t *testing.T) {
 					JWT struct {
 						jwtConfig `mapstructure:",squash"`
 					}
+					Keys struct {
+						keysConfig `mapstructure:",squash"`
+					}
 					MONGO struct {
-						Enabled  bool
-						dbConfig `mapstructure:",squash"`
+						Enabled     bool
+						dbConfig    `mapstructure:",squash"`
+						mongoConfig `mapstructure:",squash"`
 					}
-					goFavoritesConfig `mapstructure:",squash"`
-					UPK               struct {
+					Outbox struct {
+						Enabled      *bool
+						outboxConfig `mapstructure:",squash"`
+					}
+					Reconcile struct {
+						Enabled         bool
+						reconcileConfig `mapstructure:",squash"`
+					}
+					Sync struct {
+						syncConfig `mapstructure:",squash"`
+					}
+					UPK struct {
 						upkConfig `mapstructure:",squash"`
 					}
+					Watch struct {
+						Enabled     bool
+						watchConfig `mapstructure:",squash"`
+					}
 				}{
+					Auth: struct {
+						authConfig `mapstructure:",squash"`
+					}{
+						authConfig: authConfig{
+							Provider: "grpc",
+						},
+					},
 					Cache: struct {
 						Enabled     bool
 						cacheConfig `mapstructure:",squash"`
@@ -170,8 +198,9 @@ func TestLoadConfig(t *testing.T) {
 						},
 					},
 					MONGO: struct {
-						Enabled  bool
-						dbConfig `mapstructure:",squash"`
+						Enabled     bool
+						dbConfig    `mapstructure:",squash"`
+						mongoConfig `mapstructure:",squash"`
 					}{
 						Enabled: false,
 						dbConfig: dbConfig{
@@ -181,9 +210,10 @@ func TestLoadConfig(t *testing.T) {
 							UserName:     "mongouser",
 							UserPassword: "password",
 						},
-					},
-					goFavoritesConfig: goFavoritesConfig{
-						Token: "$2a$11$ZTzzVGdLUJGcYKJws9UoUug3Q3kCMELVziajBSJPY3k0pNu2XWHBy",
+						mongoConfig: mongoConfig{
+							MaxPoolSize: 16,
+							ReadConcern: "majority",
+						},
 					},
 					UPK: struct {
 						upkConfig `mapstructure:",squash"`
@@ -208,3 +238,14 @@ func TestLoadConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfigOutboxDefault(t *testing.T) {
+	got, err := LoadConfig(".")
+	assert.Nil(t, err)
+	assert.True(t, got.OutboxEnabled(), "без раздела outbox диспетчер включён")
+	assert.True(t, (&config{}).OutboxEnabled())
+	disabled := false
+	c := config{}
+	c.Favorites.Outbox.Enabled = &disabled
+	assert.False(t, c.OutboxEnabled())
+}
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 20:17 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-16 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * auth_interceptor.go
@@ -17,6 +17,8 @@ import (
 
 	"github.com/vskurikhin/gofavorites/internal/env"
 	"github.com/vskurikhin/gofavorites/internal/jwt"
+	"github.com/vskurikhin/gofavorites/internal/policy"
+	"github.com/vskurikhin/gofavorites/internal/services"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -29,9 +31,10 @@ type AuthInterceptor interface {
 }
 
 type authInterceptor struct {
-	accessibleRoles map[string][]string
-	jwtManager      jwt.Manager
-	sLog            *slog.Logger
+	authPolicy *policy.Policy
+	jwtManager jwt.Manager
+	revocation services.RevocationService
+	sLog       *slog.Logger
 }
 
 var _ AuthInterceptor = (*authInterceptor)(nil)
@@ -39,6 +42,7 @@ var (
 	ErrAuthorizationTokenIsNotProvided = status.Errorf(codes.Unauthenticated, "authorization token is not provided")
 	ErrMetadataIsNotProvided           = status.Errorf(codes.Unauthenticated, "metadata is not provided")
 	ErrNoPermissionToAccessThisRPC     = status.Error(codes.PermissionDenied, "no permission to access this RPC")
+	ErrTokenHasBeenRevoked             = status.Error(codes.Unauthenticated, "access token has been revoked")
 	authInter                          *authInterceptor
 	onceAuth                           = new(sync.Once)
 )
@@ -47,8 +51,9 @@ func GetAuthInterceptor(prop env.Properties) AuthInterceptor {
 
 	onceAuth.Do(func() {
 		authInter = new(authInterceptor)
-		authInter.accessibleRoles = authMethods()
+		authInter.authPolicy = prop.AuthPolicy()
 		authInter.jwtManager = jwt.GetJWTManager(prop)
+		authInter.revocation = services.GetRevocationService(prop)
 		authInter.sLog = prop.Logger()
 	})
 	return authInter
@@ -63,12 +68,12 @@ func (a *authInterceptor) Stream() grpc.StreamServerInterceptor {
 	) error {
 		a.sLog.DebugContext(stream.Context(), "--> stream interceptor: ", "FullMethod", info.FullMethod)
 
-		err := a.authorize(stream.Context(), info.FullMethod)
+		ctx, err := a.authorize(stream.Context(), info.FullMethod)
 		if err != nil {
 			return err
 		}
 
-		return handler(srv, stream)
+		return handler(srv, &subjectServerStream{ServerStream: stream, ctx: ctx})
 	}
 }
 
@@ -81,7 +86,7 @@ func (a *authInterceptor) Unary() grpc.UnaryServerInterceptor {
 	) (interface{}, error) {
 		a.sLog.DebugContext(ctx, "--> unary interceptor: ", "FullMethod", info.FullMethod)
 
-		err := a.authorize(ctx, info.FullMethod)
+		ctx, err := a.authorize(ctx, info.FullMethod)
 		if err != nil {
 			return nil, err
 		}
@@ -90,45 +95,52 @@ func (a *authInterceptor) Unary() grpc.UnaryServerInterceptor {
 	}
 }
 
-func (a *authInterceptor) authorize(ctx context.Context, method string) error {
+// authorize проверка токена и роли по политике доступа, методы которых нет в политике
+// запрещены. В возвращаемый контекст помещается субъект токена, по которому сервисы
+// сверяют пользователя из запроса.
+func (a *authInterceptor) authorize(ctx context.Context, method string) (context.Context, error) {
 
-	accessibleRoles, ok := a.accessibleRoles[method]
+	accessibleRoles, ok := a.authPolicy.GRPCRoles(method)
 
 	if !ok {
-		return nil
+		a.sLog.WarnContext(ctx, env.MSG+"authInterceptor.authorize", "msg", "method is not in policy", "method", method)
+		return ctx, ErrNoPermissionToAccessThisRPC
+	}
+	if accessibleRoles.Anonymous() {
+		return ctx, nil
 	}
 	md, ok := metadata.FromIncomingContext(ctx)
 
 	if !ok {
-		return ErrMetadataIsNotProvided
+		return ctx, ErrMetadataIsNotProvided
 	}
 	values := md["authorization"]
 
 	if len(values) == 0 {
-		return ErrAuthorizationTokenIsNotProvided
+		return ctx, ErrAuthorizationTokenIsNotProvided
 	}
 	accessToken := values[0]
 	claims, err := a.jwtManager.Verify(accessToken)
 
 	if err != nil {
-		return status.Errorf(codes.Unauthenticated, "access token is invalid: %v", err)
+		return ctx, status.Errorf(codes.Unauthenticated, "access token is invalid: %v", err)
 	}
-	for _, role := range accessibleRoles {
-		if role == claims.Role() {
-			return nil
-		}
+	if a.revocation.IsRevoked(ctx, claims.ID) {
+		return ctx, ErrTokenHasBeenRevoked
+	}
+	if accessibleRoles.Allows(claims.Role()) {
+		return jwt.WithSubject(ctx, claims.UserName()), nil
 	}
-	return ErrNoPermissionToAccessThisRPC
+	return ctx, ErrNoPermissionToAccessThisRPC
 }
 
-func authMethods() map[string][]string {
-	const methodServicePath = "/proto.FavoritesService/"
+type subjectServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
 
-	return map[string][]string{
-		methodServicePath + "Get":        {"USER"},
-		methodServicePath + "GetForUser": {"USER"},
-		methodServicePath + "Set":        {"USER"},
-	}
+func (s *subjectServerStream) Context() context.Context {
+	return s.ctx
 }
 
 //!-
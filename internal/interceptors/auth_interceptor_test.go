@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-04 22:01 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * auth_interceptor_test.go
@@ -24,15 +24,21 @@ import (
 	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
 	"github.com/vskurikhin/gofavorites/internal/env"
 	"github.com/vskurikhin/gofavorites/internal/jwt"
+	"github.com/vskurikhin/gofavorites/internal/policy"
 	"github.com/vskurikhin/gofavorites/internal/tool"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/vskurikhin/gofavorites/proto"
 )
 
+const testJwtSecret = "Gx5nG0bQvhU3Y8fCmtK2eWzRaPj7sL4dNq9XoTi1"
+
 func TestAuthInterceptor(t *testing.T) {
 	var tests = []struct {
 		name string
@@ -58,6 +64,18 @@ func TestAuthInterceptor(t *testing.T) {
 			name: "test #5 negative #4",
 			fRun: testNegative4,
 		},
+		{
+			name: "test #6 negative #5",
+			fRun: testNegative5,
+		},
+		{
+			name: "test #7 negative #6 revoked token",
+			fRun: testNegative6,
+		},
+		{
+			name: "test #8 policy roles ADMIN and ANONYMOUS",
+			fRun: testPolicyRoles,
+		},
 	}
 
 	assert.NotNil(t, t)
@@ -72,6 +90,7 @@ func testPositive1(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65285+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -105,6 +124,7 @@ func testNegative1(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65285+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -129,14 +149,16 @@ func testNegative1(t *testing.T) {
 	var request testpb.PingRequest
 	resp, err := client.Ping(ctx, &request)
 
-	assert.Nil(t, err)
-	assert.NotNil(t, resp)
+	// метода нет в политике доступа
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Nil(t, resp)
 }
 
 func testNegative2(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65285+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -163,6 +185,7 @@ func testNegative3(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65285+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -198,6 +221,7 @@ func testNegative4(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65285+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -221,6 +245,113 @@ func testNegative4(t *testing.T) {
 	assert.Nil(t, resp)
 }
 
+func testNegative5(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+	address := fmt.Sprintf("127.0.0.1:%d", 65285+rnd.Intn(34))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer func() {
+		cancel()
+		ctx.Done()
+		time.Sleep(100 * time.Millisecond)
+	}()
+	up := make(chan struct{})
+	go grpcServeFavoritesServiceServer(ctx, address, favoritesServiceSubject{}, up)
+	<-up
+	manager := jwt.GetJWTManager(env.GetProperties())
+	token, err := manager.Generate(dto.SignInRequest{
+		UserName: "test",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fail()
+	}
+	conn, client, err := makeFavoritesServiceClient(t, address, token)
+	defer func() { _ = conn.Close() }()
+
+	own := pb.FavoritesRequest{Favorites: &pb.Favorites{User: &pb.User{PersonalKey: "test"}}}
+	resp, err := client.Get(ctx, &own)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, resp)
+
+	other := pb.FavoritesRequest{Favorites: &pb.Favorites{User: &pb.User{PersonalKey: "other"}}}
+	resp, err = client.Get(ctx, &other)
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Nil(t, resp)
+}
+
+func testNegative6(t *testing.T) {
+
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+	prop := env.GetProperties()
+	manager := jwt.GetJWTManager(prop)
+	token, err := manager.Generate(dto.SignInRequest{UserName: "test"})
+	assert.Nil(t, err)
+	claims, err := manager.Verify(token)
+	assert.Nil(t, err)
+
+	a := &authInterceptor{
+		authPolicy: policy.Default(),
+		jwtManager: manager,
+		revocation: revocationStub{revoked: map[string]bool{claims.ID: true}},
+		sLog:       prop.Logger(),
+	}
+	ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", token))
+	_, err = a.authorize(ctx, "/proto.FavoritesService/Get")
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, ErrTokenHasBeenRevoked, err)
+
+	a.revocation = revocationStub{}
+	ctx, err = a.authorize(ctx, "/proto.FavoritesService/Get")
+	assert.Nil(t, err)
+
+	subject, ok := jwt.SubjectFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "test", subject)
+}
+
+func testPolicyRoles(t *testing.T) {
+
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+	prop := env.GetProperties()
+	manager := jwt.GetJWTManager(prop)
+	authPolicy, err := policy.Parse([]byte(`
+grpc:
+  /test.Service/Admin: [admin]
+  /test.Service/Public: [ANONYMOUS]
+`))
+	assert.Nil(t, err)
+	a := &authInterceptor{
+		authPolicy: authPolicy,
+		jwtManager: manager,
+		revocation: revocationStub{},
+		sLog:       prop.Logger(),
+	}
+	userToken, err := manager.Generate(dto.SignInRequest{UserName: "test"})
+	assert.Nil(t, err)
+	adminToken, err := manager.Generate(dto.SignInRequest{UserName: "root", Role: policy.RoleAdmin})
+	assert.Nil(t, err)
+
+	_, err = a.authorize(context.TODO(), "/test.Service/Public")
+	assert.Nil(t, err)
+	ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", userToken))
+	_, err = a.authorize(ctx, "/test.Service/Admin")
+	assert.Equal(t, ErrNoPermissionToAccessThisRPC, err)
+	ctx = metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", adminToken))
+	_, err = a.authorize(ctx, "/test.Service/Admin")
+	assert.Nil(t, err)
+	_, err = a.authorize(ctx, "/test.Service/Unknown")
+	assert.Equal(t, ErrNoPermissionToAccessThisRPC, err)
+}
+
 func grpcServeFavoritesServiceServer(ctx context.Context, address string, srv pb.FavoritesServiceServer, up chan struct{}) {
 	listen, err := net.Listen("tcp", address)
 	tool.IfErrorThenPanic(err)
@@ -336,10 +467,26 @@ type favoritesServicePositive struct {
 	pb.UnimplementedFavoritesServiceServer
 }
 
+type favoritesServiceSubject struct {
+	pb.UnimplementedFavoritesServiceServer
+}
+
 type testServicePositive struct {
 	testpb.UnimplementedTestServiceServer
 }
 
+type revocationStub struct {
+	revoked map[string]bool
+}
+
+func (r revocationStub) IsRevoked(_ context.Context, jti string) bool {
+	return r.revoked[jti]
+}
+
+func (r revocationStub) Revoke(_ context.Context, _ string, _ time.Time) error {
+	return nil
+}
+
 var _ FavoritesServiceTest = (*favoritesServicePositive)(nil)
 
 func (f favoritesServicePositive) Get(_ context.Context, _ *pb.FavoritesRequest) (*pb.FavoritesResponse, error) {
@@ -354,6 +501,16 @@ func (f favoritesServicePositive) Set(_ context.Context, _ *pb.FavoritesRequest)
 	return &pb.FavoritesResponse{}, nil
 }
 
+func (f favoritesServiceSubject) Get(ctx context.Context, request *pb.FavoritesRequest) (*pb.FavoritesResponse, error) {
+
+	subject, ok := jwt.SubjectFromContext(ctx)
+
+	if !ok || subject != request.GetFavorites().GetUser().GetPersonalKey() {
+		return nil, status.Error(codes.PermissionDenied, subject)
+	}
+	return &pb.FavoritesResponse{}, nil
+}
+
 func (t testServicePositive) PingEmpty(ctx context.Context, request *testpb.PingEmptyRequest) (*testpb.PingEmptyResponse, error) {
 	return &testpb.PingEmptyResponse{}, nil
 }
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-04 22:01 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * jwt_manager.go
@@ -16,12 +16,15 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
 	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/policy"
 )
 
 type Manager interface {
 	Generate(user dto.SignInRequest) (string, error)
+	JWKS() JWKS
 	Verify(accessToken string) (*Claims, error)
 }
 
@@ -48,10 +51,13 @@ type UserClaims struct {
 type manager struct {
 	jwtSecret    string
 	jwtExpiresIn time.Duration
+	keyring      *Keyring
+	keyringErr   error
 }
 
 var _ Manager = (*manager)(nil)
 var (
+	ErrEmptyJwtSecret               = fmt.Errorf("empty jwt secret")
 	ErrInvalidTokenClaims           = fmt.Errorf("invalid token claims")
 	ErrUnexpectedTokenSigningMethod = fmt.Errorf("unexpected token signing method")
 	onceManager                     = new(sync.Once)
@@ -64,35 +70,84 @@ func GetJWTManager(prop env.Properties) Manager {
 		jwtManager = new(manager)
 		jwtManager.jwtSecret = prop.JwtSecret()
 		jwtManager.jwtExpiresIn = prop.JwtExpiresIn()
+
+		cfg := prop.Config()
+		algorithm := cfg.JwtAlgorithm()
+
+		if algorithm != "" && algorithm != AlgorithmHS256 {
+			jwtManager.keyring, jwtManager.keyringErr = LoadKeyring(algorithm, cfg.JwtActiveKid(), cfg.JwtKeys(), prop.KeyResolver())
+			if jwtManager.keyringErr != nil {
+				prop.Logger().Error(env.MSG+"GetJWTManager", "msg", "load keyring", "err", jwtManager.keyringErr)
+			}
+		} else if jwtManager.jwtSecret == "" {
+			prop.Logger().Error(env.MSG+"GetJWTManager", "err", ErrEmptyJwtSecret)
+		}
 	})
 	return jwtManager
 }
 
 func (m *manager) Generate(user dto.SignInRequest) (string, error) {
 
+	now := time.Now()
+	role := user.Role
+
+	if role == "" {
+		role = policy.RoleUser
+	}
 	claims := UserClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.jwtExpiresIn)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.jwtExpiresIn)),
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Subject:   user.UserName,
 		},
 		Username: user.UserName,
-		Role:     "USER",
+		Role:     role,
+	}
+	if m.keyringErr != nil {
+		return "", m.keyringErr
+	}
+	if m.keyring != nil {
+		return m.keyring.sign(claims)
+	}
+	// HMAC с пустым ключом позволяет подделать любой токен.
+	if m.jwtSecret == "" {
+		return "", ErrEmptyJwtSecret
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	return token.SignedString([]byte(m.jwtSecret))
 }
 
+// JWKS открытые ключи проверки подписи, при HS256 набор пуст.
+func (m *manager) JWKS() JWKS {
+
+	if m.keyring != nil {
+		return m.keyring.JWKS()
+	}
+	return JWKS{Keys: []JWK{}}
+}
+
 // Verify verifies the access token string and return a user claim if the token is valid
 func (m *manager) Verify(accessToken string) (*Claims, error) {
 
+	if m.keyringErr != nil {
+		return nil, m.keyringErr
+	}
 	token, err := jwt.ParseWithClaims(
 		accessToken,
 		&UserClaims{},
 		func(token *jwt.Token) (interface{}, error) {
+			if m.keyring != nil {
+				return m.keyring.verificationKey(token)
+			}
 			_, ok := token.Method.(*jwt.SigningMethodHMAC)
 			if !ok {
 				return nil, ErrUnexpectedTokenSigningMethod
 			}
+			if m.jwtSecret == "" {
+				return nil, ErrEmptyJwtSecret
+			}
 
 			return []byte(m.jwtSecret), nil
 		},
@@ -106,8 +161,9 @@ func (m *manager) Verify(accessToken string) (*Claims, error) {
 		return nil, ErrInvalidTokenClaims
 	}
 	claims := &Claims{
-		userName: userClaims.Username,
-		role:     userClaims.Role,
+		RegisteredClaims: userClaims.RegisteredClaims,
+		userName:         userClaims.Username,
+		role:             userClaims.Role,
 	}
 	return claims, nil
 }
@@ -0,0 +1,244 @@
+/*
+ * This file was last modified at 2024-08-18 07:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * keyring.go
+ * $Id$
+ */
+//!+
+
+// Package jwt TODO.
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/keys"
+)
+
+const (
+	AlgorithmES256 = "ES256"
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+var (
+	ErrKeyringActiveKey = fmt.Errorf("keyring has no active signing key")
+	ErrKeyringAlgorithm = fmt.Errorf("unsupported JWT signing algorithm")
+	ErrKeyringKeyType   = fmt.Errorf("unsupported JWT key type")
+	ErrUnknownKeyID     = fmt.Errorf("unknown or retired key id")
+)
+
+// JWK открытый ключ в формате RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS набор открытых ключей публикуемый по адресу /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type key struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// Keyring связка ключей асимметричной подписи, ключи различаются по kid.
+// Новые токены подписываются активным ключом, проверка принимает любой
+// ключ который не выведен из оборота. Для ротации в связку добавляется новый
+// ключ и делается активным, старый остаётся до истечения выданных им токенов.
+type Keyring struct {
+	active *key
+	keys   map[string]*key
+	order  []string
+}
+
+// LoadKeyring загрузка связки ключей из PEM-файлов конфигурации,
+// вместо имени файла может быть ссылка поставщика ключей resolver.
+func LoadKeyring(algorithm, activeKid string, configKeys []env.JwtKey, resolver *keys.Resolver) (*Keyring, error) {
+
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmES256 {
+		return nil, fmt.Errorf("%w: %s", ErrKeyringAlgorithm, algorithm)
+	}
+	k := &Keyring{keys: make(map[string]*key)}
+
+	for _, cfg := range configKeys {
+		if cfg.Retired {
+			continue
+		}
+		loaded, err := loadKey(cfg, resolver)
+
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := k.keys[loaded.kid]; ok {
+			return nil, fmt.Errorf("duplicate key id: %s", loaded.kid)
+		}
+		k.keys[loaded.kid] = loaded
+		k.order = append(k.order, loaded.kid)
+
+		if k.active == nil && loaded.private != nil && (activeKid == "" || activeKid == loaded.kid) {
+			k.active = loaded
+		}
+	}
+	if k.active == nil {
+		return nil, ErrKeyringActiveKey
+	}
+	if k.active.method.Alg() != algorithm {
+		return nil, fmt.Errorf("%w: active key %s is %s", ErrKeyringAlgorithm, k.active.kid, k.active.method.Alg())
+	}
+	return k, nil
+}
+
+// JWKS открытые ключи связки которые принимаются при проверке.
+func (k *Keyring) JWKS() JWKS {
+
+	result := JWKS{Keys: make([]JWK, 0, len(k.order))}
+
+	for _, kid := range k.order {
+		result.Keys = append(result.Keys, k.keys[kid].jwk())
+	}
+	return result
+}
+
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+
+	token := jwt.NewWithClaims(k.active.method, claims)
+	token.Header["kid"] = k.active.kid
+
+	return token.SignedString(k.active.private)
+}
+
+func (k *Keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+
+	kid, _ := token.Header["kid"].(string)
+	found, ok := k.keys[kid]
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, kid)
+	}
+	if token.Method.Alg() != found.method.Alg() {
+		return nil, ErrUnexpectedTokenSigningMethod
+	}
+	return found.public, nil
+}
+
+func (k *key) jwk() JWK {
+
+	result := JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		result.Kty = "RSA"
+		result.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		result.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		result.Kty = "EC"
+		result.Crv = public.Curve.Params().Name
+		result.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+		result.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+	}
+	return result
+}
+
+func loadKey(cfg env.JwtKey, resolver *keys.Resolver) (*key, error) {
+
+	if cfg.Kid == "" {
+		return nil, fmt.Errorf("key id is empty")
+	}
+	result := &key{kid: cfg.Kid}
+
+	if cfg.PrivateKeyFile != "" {
+		private, err := loadPrivateKey(cfg.PrivateKeyFile, resolver)
+
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", cfg.Kid, err)
+		}
+		result.private = private
+		result.public = private.Public()
+	} else {
+		public, err := loadPublicKey(cfg.PublicKeyFile, resolver)
+
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", cfg.Kid, err)
+		}
+		result.public = public
+	}
+	switch public := result.public.(type) {
+	case *rsa.PublicKey:
+		result.method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("key %s: %w: curve %s", cfg.Kid, ErrKeyringKeyType, public.Curve.Params().Name)
+		}
+		result.method = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("key %s: %w", cfg.Kid, ErrKeyringKeyType)
+	}
+	return result, nil
+}
+
+func loadPrivateKey(name string, resolver *keys.Resolver) (crypto.Signer, error) {
+
+	bytes, err := readKeyFile(name, resolver)
+
+	if err != nil {
+		return nil, err
+	}
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(bytes); err == nil {
+		return private, nil
+	}
+	if private, err := jwt.ParseECPrivateKeyFromPEM(bytes); err == nil {
+		return private, nil
+	}
+	return nil, ErrKeyringKeyType
+}
+
+func loadPublicKey(name string, resolver *keys.Resolver) (crypto.PublicKey, error) {
+
+	bytes, err := readKeyFile(name, resolver)
+
+	if err != nil {
+		return nil, err
+	}
+	if public, err := jwt.ParseRSAPublicKeyFromPEM(bytes); err == nil {
+		return public, nil
+	}
+	if public, err := jwt.ParseECPublicKeyFromPEM(bytes); err == nil {
+		return public, nil
+	}
+	return nil, ErrKeyringKeyType
+}
+
+func readKeyFile(name string, resolver *keys.Resolver) ([]byte, error) {
+
+	if resolver.IsReference(name) {
+		return resolver.Resolve(context.Background(), name)
+	}
+	return os.ReadFile(name)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,263 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * keyring_test.go
+ * $Id$
+ */
+//!+
+
+// Package jwt TODO.
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/keys"
+)
+
+func TestKeyring(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "positive test #0 Keyring RS256 Generate and Verify",
+			fRun: testKeyringRS256,
+		},
+		{
+			name: "positive test #1 Keyring ES256 Generate and Verify",
+			fRun: testKeyringES256,
+		},
+		{
+			name: "positive test #2 Keyring rotation keeps live tokens",
+			fRun: testKeyringRotation,
+		},
+		{
+			name: "negative test #3 Keyring rejects HS256 and unknown kid",
+			fRun: testKeyringRejects,
+		},
+		{
+			name: "negative test #4 LoadKeyring configuration errors",
+			fRun: testLoadKeyringNegative,
+		},
+		{
+			name: "positive test #5 HS256 manager",
+			fRun: testManagerHS256,
+		},
+		{
+			name: "positive test #6 LoadKeyring key by provider reference",
+			fRun: testLoadKeyringReference,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testKeyringRS256(t *testing.T) {
+	k1 := writeTestRSAKey(t, "k1")
+	m := getTestKeyringManager(t, AlgorithmRS256, "", k1)
+	token, err := m.Generate(dto.SignInRequest{UserName: "test"})
+	assert.Nil(t, err)
+	assert.Equal(t, "k1", getTestTokenHeader(t, token)["kid"])
+	assert.Equal(t, AlgorithmRS256, getTestTokenHeader(t, token)["alg"])
+	claims, err := m.Verify(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", claims.UserName())
+	jwks := m.JWKS()
+	assert.Len(t, jwks.Keys, 1)
+	assert.Equal(t, JWK{Kty: "RSA", Kid: "k1", Use: "sig", Alg: AlgorithmRS256, N: jwks.Keys[0].N, E: "AQAB"}, jwks.Keys[0])
+}
+
+func testKeyringES256(t *testing.T) {
+	k1 := writeTestECKey(t, "k1")
+	m := getTestKeyringManager(t, AlgorithmES256, "k1", k1)
+	token, err := m.Generate(dto.SignInRequest{UserName: "test"})
+	assert.Nil(t, err)
+	claims, err := m.Verify(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", claims.UserName())
+	jwks := m.JWKS()
+	assert.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "EC", jwks.Keys[0].Kty)
+	assert.Equal(t, "P-256", jwks.Keys[0].Crv)
+	assert.Len(t, jwks.Keys[0].X, 43)
+	assert.Len(t, jwks.Keys[0].Y, 43)
+}
+
+func testKeyringRotation(t *testing.T) {
+	k1 := writeTestRSAKey(t, "k1")
+	k2 := writeTestRSAKey(t, "k2")
+	before := getTestKeyringManager(t, AlgorithmRS256, "k1", k1)
+	token1, err := before.Generate(dto.SignInRequest{UserName: "test"})
+	assert.Nil(t, err)
+
+	// k1 оставлен только открытым ключом, подписывает новый k2
+	k1.PrivateKeyFile = ""
+	during := getTestKeyringManager(t, AlgorithmRS256, "k2", k1, k2)
+	token2, err := during.Generate(dto.SignInRequest{UserName: "test"})
+	assert.Nil(t, err)
+	assert.Equal(t, "k2", getTestTokenHeader(t, token2)["kid"])
+	_, err = during.Verify(token1)
+	assert.Nil(t, err)
+	_, err = during.Verify(token2)
+	assert.Nil(t, err)
+	assert.Len(t, during.JWKS().Keys, 2)
+
+	k1.Retired = true
+	after := getTestKeyringManager(t, AlgorithmRS256, "k2", k1, k2)
+	_, err = after.Verify(token1)
+	assert.True(t, errors.Is(err, ErrUnknownKeyID))
+	_, err = after.Verify(token2)
+	assert.Nil(t, err)
+	assert.Len(t, after.JWKS().Keys, 1)
+	assert.Equal(t, "k2", after.JWKS().Keys[0].Kid)
+}
+
+func testKeyringRejects(t *testing.T) {
+	k1 := writeTestRSAKey(t, "k1")
+	m := getTestKeyringManager(t, AlgorithmRS256, "", k1)
+	hs := &manager{jwtSecret: "secret", jwtExpiresIn: time.Minute}
+	token, err := hs.Generate(dto.SignInRequest{UserName: "test"})
+	assert.Nil(t, err)
+	_, err = m.Verify(token)
+	assert.NotNil(t, err)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaims{Username: "test"})
+	forged.Header["kid"] = "k1"
+	forgedString, err := forged.SignedString([]byte("secret"))
+	assert.Nil(t, err)
+	_, err = m.Verify(forgedString)
+	assert.NotNil(t, err)
+
+	other := getTestKeyringManager(t, AlgorithmRS256, "", writeTestRSAKey(t, "k9"))
+	token, err = other.Generate(dto.SignInRequest{UserName: "test"})
+	assert.Nil(t, err)
+	_, err = m.Verify(token)
+	assert.True(t, errors.Is(err, ErrUnknownKeyID))
+}
+
+func testLoadKeyringNegative(t *testing.T) {
+	rsaKey := writeTestRSAKey(t, "k1")
+	ecKey := writeTestECKey(t, "k2")
+	_, err := LoadKeyring("none", "", []env.JwtKey{rsaKey}, nil)
+	assert.True(t, errors.Is(err, ErrKeyringAlgorithm))
+	_, err = LoadKeyring(AlgorithmES256, "", []env.JwtKey{rsaKey}, nil)
+	assert.True(t, errors.Is(err, ErrKeyringAlgorithm))
+	_, err = LoadKeyring(AlgorithmRS256, "k3", []env.JwtKey{rsaKey, ecKey}, nil)
+	assert.Equal(t, ErrKeyringActiveKey, err)
+	_, err = LoadKeyring(AlgorithmRS256, "", []env.JwtKey{rsaKey, rsaKey}, nil)
+	assert.NotNil(t, err)
+	_, err = LoadKeyring(AlgorithmRS256, "", []env.JwtKey{{Kid: "k4", PrivateKeyFile: "not-exists.pem"}}, nil)
+	assert.NotNil(t, err)
+	rsaKey.PrivateKeyFile = ""
+	_, err = LoadKeyring(AlgorithmRS256, "k1", []env.JwtKey{rsaKey}, nil)
+	assert.Equal(t, ErrKeyringActiveKey, err)
+	m := &manager{keyringErr: err}
+	_, err = m.Generate(dto.SignInRequest{UserName: "test"})
+	assert.NotNil(t, err)
+	_, err = m.Verify("")
+	assert.NotNil(t, err)
+}
+
+func testLoadKeyringReference(t *testing.T) {
+	rsaKey := writeTestRSAKey(t, "k1")
+	t.Setenv("GOFAVORITES_TEST_JWT_KEY_FILE", rsaKey.PrivateKeyFile)
+	resolver := keys.NewResolver(map[string]keys.KeyProvider{keys.SchemeEnvFile: keys.NewEnvFileProvider()})
+	keyring, err := LoadKeyring(
+		AlgorithmRS256,
+		"k1",
+		[]env.JwtKey{{Kid: "k1", PrivateKeyFile: "env-file:GOFAVORITES_TEST_JWT_KEY"}},
+		resolver,
+	)
+	assert.Nil(t, err)
+	assert.Len(t, keyring.JWKS().Keys, 1)
+	_, err = LoadKeyring(
+		AlgorithmRS256,
+		"k1",
+		[]env.JwtKey{{Kid: "k1", PrivateKeyFile: "env-file:GOFAVORITES_TEST_JWT_UNDEFINED"}},
+		resolver,
+	)
+	assert.ErrorIs(t, err, keys.ErrKeyNotFound)
+}
+
+func testManagerHS256(t *testing.T) {
+	m := &manager{jwtSecret: "secret", jwtExpiresIn: time.Minute}
+	token, err := m.Generate(dto.SignInRequest{UserName: "test"})
+	assert.Nil(t, err)
+	claims, err := m.Verify(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", claims.UserName())
+	assert.Equal(t, "test", claims.Subject)
+	assert.Empty(t, m.JWKS().Keys)
+
+	empty := &manager{jwtExpiresIn: time.Minute}
+	_, err = empty.Generate(dto.SignInRequest{UserName: "test"})
+	assert.ErrorIs(t, err, ErrEmptyJwtSecret)
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaims{Username: "test", Role: "admin"}).
+		SignedString([]byte{})
+	assert.Nil(t, err)
+	_, err = empty.Verify(forged)
+	assert.ErrorIs(t, err, ErrEmptyJwtSecret)
+}
+
+func getTestKeyringManager(t *testing.T, algorithm, activeKid string, configKeys ...env.JwtKey) Manager {
+	keyring, err := LoadKeyring(algorithm, activeKid, configKeys, nil)
+	assert.Nil(t, err)
+	return &manager{jwtExpiresIn: time.Minute, keyring: keyring}
+}
+
+func getTestTokenHeader(t *testing.T, tokenString string) map[string]interface{} {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &UserClaims{})
+	assert.Nil(t, err)
+	return token.Header
+}
+
+func writeTestRSAKey(t *testing.T, kid string) env.JwtKey {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	return writeTestKey(t, kid, x509.MarshalPKCS1PrivateKey(private), "RSA PRIVATE KEY", &private.PublicKey)
+}
+
+func writeTestECKey(t *testing.T, kid string) env.JwtKey {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	bytes, err := x509.MarshalECPrivateKey(private)
+	assert.Nil(t, err)
+	return writeTestKey(t, kid, bytes, "EC PRIVATE KEY", &private.PublicKey)
+}
+
+func writeTestKey(t *testing.T, kid string, private []byte, privateType string, public any) env.JwtKey {
+	dir := t.TempDir()
+	privateFile := filepath.Join(dir, kid+"-private.pem")
+	publicFile := filepath.Join(dir, kid+"-public.pem")
+	err := os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: privateType, Bytes: private}), 0600)
+	assert.Nil(t, err)
+	bytes, err := x509.MarshalPKIXPublicKey(public)
+	assert.Nil(t, err)
+	err = os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: bytes}), 0600)
+	assert.Nil(t, err)
+	return env.JwtKey{Kid: kid, PrivateKeyFile: privateFile, PublicKeyFile: publicFile}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,29 @@
+/*
+ * This file was last modified at 2024-08-14 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * subject.go
+ * $Id$
+ */
+//!+
+
+// Package jwt TODO.
+package jwt
+
+import "context"
+
+type subjectKey struct{}
+
+// WithSubject сохраняет в контексте субъект (имя пользователя) проверенного токена.
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// SubjectFromContext субъект проверенного токена, если он был сохранён в контексте.
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	subject, ok := ctx.Value(subjectKey{}).(string)
+	return subject, ok
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,50 @@
+/*
+ * This file was last modified at 2024-08-14 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * subject_test.go
+ * $Id$
+ */
+//!+
+
+// Package jwt TODO.
+package jwt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubject(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 WithSubject", fRun: testWithSubject},
+		{name: "negative test #1 SubjectFromContext", fRun: testSubjectFromContextNegative},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testWithSubject(t *testing.T) {
+	subject, ok := SubjectFromContext(WithSubject(context.TODO(), "test"))
+	assert.True(t, ok)
+	assert.Equal(t, "test", subject)
+}
+
+func testSubjectFromContextNegative(t *testing.T) {
+	subject, ok := SubjectFromContext(context.TODO())
+	assert.False(t, ok)
+	assert.Equal(t, "", subject)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,60 @@
+/*
+ * This file was last modified at 2024-08-18 07:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * env.go
+ * $Id$
+ */
+//!+
+
+// Package keys поставщики ключевого материала.
+package keys
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// EnvProvider ключевой материал из переменной окружения, ссылка — имя переменной.
+type EnvProvider struct {
+	lookupEnv func(string) (string, bool)
+}
+
+// EnvFileProvider ключевой материал в стиле _FILE: если задана переменная
+// окружения <ссылка>_FILE — из файла по её значению, иначе из переменной <ссылка>.
+type EnvFileProvider struct {
+	env *EnvProvider
+}
+
+var _ KeyProvider = (*EnvProvider)(nil)
+var _ KeyProvider = (*EnvFileProvider)(nil)
+
+func NewEnvProvider() *EnvProvider {
+	return &EnvProvider{lookupEnv: os.LookupEnv}
+}
+
+func NewEnvFileProvider() *EnvFileProvider {
+	return &EnvFileProvider{env: NewEnvProvider()}
+}
+
+func (e *EnvProvider) Key(_ context.Context, ref string) ([]byte, error) {
+
+	value, ok := e.lookupEnv(ref)
+
+	if !ok || value == "" {
+		return nil, fmt.Errorf("%s: %w", ref, ErrKeyNotFound)
+	}
+	return []byte(value), nil
+}
+
+func (e *EnvFileProvider) Key(ctx context.Context, ref string) ([]byte, error) {
+
+	if name, ok := e.env.lookupEnv(ref + "_FILE"); ok && name != "" {
+		return readKeyFile(name)
+	}
+	return e.env.Key(ctx, ref)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,62 @@
+/*
+ * This file was last modified at 2024-08-18 07:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * file.go
+ * $Id$
+ */
+//!+
+
+// Package keys поставщики ключевого материала.
+package keys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileProvider ключевой материал из файла, ссылка — путь к файлу,
+// относительный путь — от каталога dir. Завершающий перевод строки отбрасывается.
+type FileProvider struct {
+	dir string
+}
+
+var _ KeyProvider = (*FileProvider)(nil)
+
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{dir: dir}
+}
+
+func (f *FileProvider) Key(_ context.Context, ref string) ([]byte, error) {
+
+	if ref == "" {
+		return nil, ErrKeyNotFound
+	}
+	name := ref
+
+	if f.dir != "" && !filepath.IsAbs(name) {
+		name = filepath.Join(f.dir, name)
+	}
+	return readKeyFile(name)
+}
+
+func readKeyFile(name string) ([]byte, error) {
+
+	result, err := os.ReadFile(name)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", name, ErrKeyNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(result, "\r\n"), nil
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,142 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * provider.go
+ * $Id$
+ */
+//!+
+
+// Package keys поставщики ключевого материала: секреты и ключи шифрования
+// читаются по ссылке из файла, переменной окружения или дешифруются
+// сервисом transit (совместимым с HashiCorp Vault), поэтому сами ключи
+// не обязаны храниться в репозитории конфигурации.
+// Ссылка — значение вида «схема:ссылка», например:
+//
+//	file:/run/secrets/upk-secret
+//	env:UPK_SECRET_KEY
+//	env-file:JWT_SECRET
+//	transit:upk:vault:v1:8SDd3WHDOjf7mq69CyCqYjBXAiQQAVZRkFM13ok481zoCmHnSeDX9vyf7w==
+//
+// Значение без схемы — сам ключ (literal). Ссылка на неизвестную схему
+// (например, опечатка «flie:») или на схему, поставщик которой не настроен,
+// — ошибка: такое значение не должно молча стать ключом.
+package keys
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	SchemeEnv     = "env"
+	SchemeEnvFile = "env-file"
+	SchemeFile    = "file"
+	SchemeTransit = "transit"
+)
+
+var (
+	ErrKeyNotFound           = fmt.Errorf("key not found")
+	ErrProviderNotConfigured = fmt.Errorf("key provider not configured")
+	ErrUnknownScheme         = fmt.Errorf("unknown key reference scheme")
+)
+
+var knownSchemes = map[string]struct{}{
+	SchemeEnv:     {},
+	SchemeEnvFile: {},
+	SchemeFile:    {},
+	SchemeTransit: {},
+}
+
+// KeyProvider поставщик ключевого материала по ссылке ref.
+type KeyProvider interface {
+	Key(ctx context.Context, ref string) ([]byte, error)
+}
+
+// Resolver выбор поставщика ключевого материала по схеме ссылки.
+type Resolver struct {
+	providers map[string]KeyProvider
+}
+
+// NewResolver поставщики по схемам ссылок, поставщик nil не регистрируется.
+func NewResolver(providers map[string]KeyProvider) *Resolver {
+
+	result := &Resolver{providers: make(map[string]KeyProvider, len(providers))}
+
+	for scheme, provider := range providers {
+		if provider != nil {
+			result.providers[scheme] = provider
+		}
+	}
+	return result
+}
+
+// IsReference значение — ссылка вида «схема:ссылка», в том числе на неизвестную
+// или не настроенную схему.
+func (r *Resolver) IsReference(value string) bool {
+
+	if r == nil {
+		return false
+	}
+	_, _, ok := cutScheme(value)
+
+	return ok
+}
+
+// Resolve ключевой материал по ссылке value, значение не ссылка — само значение.
+func (r *Resolver) Resolve(ctx context.Context, value string) ([]byte, error) {
+
+	if !r.IsReference(value) {
+		return []byte(value), nil
+	}
+	provider, ref, err := r.provider(value)
+
+	if err != nil {
+		return nil, err
+	}
+	result, err := provider.Key(ctx, ref)
+
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", scheme(value), err)
+	}
+	return result, nil
+}
+
+func (r *Resolver) provider(value string) (KeyProvider, string, error) {
+
+	s, ref, _ := cutScheme(value)
+
+	if provider, ok := r.providers[s]; ok {
+		return provider, ref, nil
+	}
+	if _, ok := knownSchemes[s]; ok {
+		return nil, "", fmt.Errorf("resolve %s: %w", s, ErrProviderNotConfigured)
+	}
+	return nil, "", fmt.Errorf("resolve %s: %w", s, ErrUnknownScheme)
+}
+
+// cutScheme схема ссылки: латинские строчные буквы, цифры, «+», «-» и «.»,
+// начиная с буквы (RFC 3986).
+func cutScheme(value string) (string, string, bool) {
+
+	s, ref, found := strings.Cut(value, ":")
+
+	if !found || s == "" || s[0] < 'a' || s[0] > 'z' {
+		return "", "", false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.') {
+			return "", "", false
+		}
+	}
+	return s, ref, true
+}
+
+func scheme(value string) string {
+	s, _, _ := strings.Cut(value, ":")
+	return s
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
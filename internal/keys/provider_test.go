@@ -0,0 +1,146 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * provider_test.go
+ * $Id$
+ */
+//!+
+
+// Package keys поставщики ключевого материала.
+package keys
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyProvider(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "positive test #0 Resolver literal and references",
+			fRun: testResolver,
+		},
+		{
+			name: "positive test #1 FileProvider",
+			fRun: testFileProvider,
+		},
+		{
+			name: "positive test #2 EnvProvider and EnvFileProvider",
+			fRun: testEnvProvider,
+		},
+		{
+			name: "negative test #3 key not found",
+			fRun: testKeyNotFound,
+		},
+		{
+			name: "negative test #4 unknown or not configured scheme",
+			fRun: testUnresolvedScheme,
+		},
+	}
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testResolver(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "secret"), []byte("from file\n"), 0600))
+	resolver := NewResolver(map[string]KeyProvider{
+		SchemeFile:    NewFileProvider(dir),
+		SchemeTransit: nil,
+	})
+	got, err := resolver.Resolve(context.TODO(), "file:secret")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("from file"), got)
+	assert.True(t, resolver.IsReference("file:secret"))
+
+	for _, literal := range []string{"literal", "Secret:With:Colons", "1a:2b", "/run/secrets/key.pem", ""} {
+		assert.False(t, resolver.IsReference(literal))
+		got, err = resolver.Resolve(context.TODO(), literal)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte(literal), got)
+	}
+	var empty *Resolver
+	assert.False(t, empty.IsReference("file:secret"))
+}
+
+func testFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "key.pem")
+	assert.Nil(t, os.WriteFile(name, []byte("-----BEGIN-----\r\n"), 0600))
+	got, err := NewFileProvider("").Key(context.TODO(), name)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("-----BEGIN-----"), got)
+	got, err = NewFileProvider("/nonexistent").Key(context.TODO(), name)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("-----BEGIN-----"), got)
+}
+
+func testEnvProvider(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "jwt")
+	assert.Nil(t, os.WriteFile(name, []byte("from file"), 0600))
+	t.Setenv("GOFAVORITES_TEST_SECRET", "from env")
+	t.Setenv("GOFAVORITES_TEST_FILE_SECRET", "from env")
+	t.Setenv("GOFAVORITES_TEST_FILE_SECRET_FILE", name)
+
+	got, err := NewEnvProvider().Key(context.TODO(), "GOFAVORITES_TEST_SECRET")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("from env"), got)
+	got, err = NewEnvFileProvider().Key(context.TODO(), "GOFAVORITES_TEST_SECRET")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("from env"), got)
+	got, err = NewEnvFileProvider().Key(context.TODO(), "GOFAVORITES_TEST_FILE_SECRET")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("from file"), got)
+}
+
+func testKeyNotFound(t *testing.T) {
+	resolver := NewResolver(map[string]KeyProvider{
+		SchemeEnv:     NewEnvProvider(),
+		SchemeEnvFile: NewEnvFileProvider(),
+		SchemeFile:    NewFileProvider(t.TempDir()),
+	})
+	t.Setenv("GOFAVORITES_TEST_MISSING_FILE", filepath.Join(t.TempDir(), "missing"))
+	for _, ref := range []string{
+		"env:GOFAVORITES_TEST_UNDEFINED",
+		"env-file:GOFAVORITES_TEST_UNDEFINED",
+		"env-file:GOFAVORITES_TEST_MISSING",
+		"file:missing",
+		"file:",
+	} {
+		_, err := resolver.Resolve(context.TODO(), ref)
+		assert.ErrorIs(t, err, ErrKeyNotFound, ref)
+	}
+}
+
+func testUnresolvedScheme(t *testing.T) {
+	resolver := NewResolver(map[string]KeyProvider{
+		SchemeFile:    NewFileProvider(t.TempDir()),
+		SchemeTransit: nil,
+	})
+	for _, ref := range []string{"transit:upk:vault:v1:AA==", "env:SECRET", "env-file:SECRET"} {
+		assert.True(t, resolver.IsReference(ref), ref)
+		_, err := resolver.Resolve(context.TODO(), ref)
+		assert.ErrorIs(t, err, ErrProviderNotConfigured, ref)
+	}
+	for _, ref := range []string{"flie:secret", "vault:secret"} {
+		assert.True(t, resolver.IsReference(ref), ref)
+		_, err := resolver.Resolve(context.TODO(), ref)
+		assert.ErrorIs(t, err, ErrUnknownScheme, ref)
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
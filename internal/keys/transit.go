@@ -0,0 +1,151 @@
+/*
+ * This file was last modified at 2024-08-18 07:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * transit.go
+ * $Id$
+ */
+//!+
+
+// Package keys поставщики ключевого материала.
+package keys
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	transitDefaultMount   = "transit"
+	transitDefaultTimeout = 5 * time.Second
+	transitTokenHeader    = "X-Vault-Token"
+)
+
+var ErrTransit = fmt.Errorf("transit request failed")
+
+// TransitProvider ключевой материал зашифрованный сервисом transit, HTTP API
+// совместим с Vault Transit Secrets Engine: POST /v1/<mount>/decrypt/<key>.
+// Ссылка — «<имя ключа>:<шифротекст>», шифротекст в формате vault:v<версия>:...
+// В конфигурации хранится только шифротекст, ключ шифрования остаётся в transit.
+type TransitProvider struct {
+	address string
+	client  *http.Client
+	mount   string
+	token   string
+}
+
+type transitRequest struct {
+	Ciphertext string `json:"ciphertext,omitempty"`
+	Plaintext  string `json:"plaintext,omitempty"`
+}
+
+type transitResponse struct {
+	Data struct {
+		Ciphertext string `json:"ciphertext"`
+		Plaintext  string `json:"plaintext"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+var _ KeyProvider = (*TransitProvider)(nil)
+
+// NewTransitProvider клиент transit по адресу address, mount — путь механизма
+// transit (по умолчанию transit), token — токен доступа.
+func NewTransitProvider(address, mount, token string, timeout time.Duration) *TransitProvider {
+
+	if mount == "" {
+		mount = transitDefaultMount
+	}
+	if timeout <= 0 {
+		timeout = transitDefaultTimeout
+	}
+	return &TransitProvider{
+		address: strings.TrimRight(address, "/"),
+		client:  &http.Client{Timeout: timeout},
+		mount:   strings.Trim(mount, "/"),
+		token:   token,
+	}
+}
+
+func (t *TransitProvider) Key(ctx context.Context, ref string) ([]byte, error) {
+
+	name, ciphertext, found := strings.Cut(ref, ":")
+
+	if !found || name == "" || ciphertext == "" {
+		return nil, fmt.Errorf("transit reference must be <key>:<ciphertext>: %w", ErrKeyNotFound)
+	}
+	return t.Decrypt(ctx, name, ciphertext)
+}
+
+// Decrypt дешифрация шифротекста ciphertext ключом name.
+func (t *TransitProvider) Decrypt(ctx context.Context, name, ciphertext string) ([]byte, error) {
+
+	response, err := t.do(ctx, "decrypt", name, transitRequest{Ciphertext: ciphertext})
+
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(response.Data.Plaintext)
+}
+
+// Encrypt шифрование ключевого материала plain ключом name, результат —
+// шифротекст для ссылки transit в конфигурации.
+func (t *TransitProvider) Encrypt(ctx context.Context, name string, plain []byte) (string, error) {
+
+	response, err := t.do(ctx, "encrypt", name, transitRequest{Plaintext: base64.StdEncoding.EncodeToString(plain)})
+
+	if err != nil {
+		return "", err
+	}
+	return response.Data.Ciphertext, nil
+}
+
+func (t *TransitProvider) do(ctx context.Context, operation, name string, body transitRequest) (transitResponse, error) {
+
+	var result transitResponse
+	data, err := json.Marshal(body)
+
+	if err != nil {
+		return result, err
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", t.address, t.mount, operation, url.PathEscape(name))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+
+	if err != nil {
+		return result, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(transitTokenHeader, t.token)
+	response, err := t.client.Do(request)
+
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = response.Body.Close() }()
+	data, err = io.ReadAll(response.Body)
+
+	if err != nil {
+		return result, err
+	}
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &result); err != nil && response.StatusCode < http.StatusMultipleChoices {
+			return result, err
+		}
+	}
+	if response.StatusCode >= http.StatusMultipleChoices || len(result.Errors) > 0 {
+		return result, fmt.Errorf("%w: %s %s: %d %s", ErrTransit, operation, name, response.StatusCode, strings.Join(result.Errors, "; "))
+	}
+	return result, nil
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,152 @@
+/*
+ * This file was last modified at 2024-08-18 07:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * transit_test.go
+ * $Id$
+ */
+//!+
+
+// Package keys поставщики ключевого материала.
+package keys
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const testTransitToken = "test-token"
+
+func TestTransitProvider(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "positive test #0 TransitProvider Encrypt and Key",
+			fRun: testTransitProvider,
+		},
+		{
+			name: "negative test #1 TransitProvider permission denied",
+			fRun: testTransitProviderDenied,
+		},
+		{
+			name: "negative test #2 TransitProvider bad reference and ciphertext",
+			fRun: testTransitProviderBadReference,
+		},
+	}
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testTransitProvider(t *testing.T) {
+	server := newTestTransitServer(t)
+	defer server.Close()
+	provider := NewTransitProvider(server.URL+"/", "", testTransitToken, 0)
+
+	ciphertext, err := provider.Encrypt(context.TODO(), "upk", []byte("secret key"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(ciphertext, "vault:v1:"))
+
+	resolver := NewResolver(map[string]KeyProvider{SchemeTransit: provider})
+	got, err := resolver.Resolve(context.TODO(), "transit:upk:"+ciphertext)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret key"), got)
+}
+
+func testTransitProviderDenied(t *testing.T) {
+	server := newTestTransitServer(t)
+	defer server.Close()
+
+	_, err := NewTransitProvider(server.URL, "transit", "bad", 0).Encrypt(context.TODO(), "upk", []byte("secret key"))
+	assert.ErrorIs(t, err, ErrTransit)
+	assert.Contains(t, err.Error(), "permission denied")
+}
+
+func testTransitProviderBadReference(t *testing.T) {
+	server := newTestTransitServer(t)
+	defer server.Close()
+	provider := NewTransitProvider(server.URL, "transit", testTransitToken, 0)
+
+	_, err := provider.Key(context.TODO(), "upk")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = provider.Key(context.TODO(), "upk:vault:v1:AAAA")
+	assert.ErrorIs(t, err, ErrTransit)
+	_, err = NewTransitProvider(server.URL, "other", testTransitToken, 0).Key(context.TODO(), "upk:vault:v1:AAAA")
+	assert.ErrorIs(t, err, ErrTransit)
+}
+
+// newTestTransitServer локальная замена transit: AES-GCM ключом из нулей,
+// шифротекст в формате vault:v1:<base64>.
+func newTestTransitServer(t *testing.T) *httptest.Server {
+
+	key := make([]byte, 32)
+	reply := func(w http.ResponseWriter, status int, response transitResponse) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		assert.Nil(t, json.NewEncoder(w).Encode(response))
+	}
+	fail := func(w http.ResponseWriter, status int, message string) {
+		reply(w, status, transitResponse{Errors: []string{message}})
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Header.Get(transitTokenHeader) != testTransitToken {
+			fail(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		var request transitRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var response transitResponse
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/transit/encrypt/"):
+			plain, err := base64.StdEncoding.DecodeString(request.Plaintext)
+			if err != nil {
+				fail(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			encrypted, err := tool.EncryptGCM(key, plain)
+			if err != nil {
+				fail(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			response.Data.Ciphertext = "vault:v1:" + base64.StdEncoding.EncodeToString(encrypted)
+		case strings.HasPrefix(r.URL.Path, "/v1/transit/decrypt/"):
+			encrypted, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(request.Ciphertext, "vault:v1:"))
+			if err != nil {
+				fail(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			plain, err := tool.DecryptGCM(key, encrypted)
+			if err != nil {
+				fail(w, http.StatusBadRequest, "invalid ciphertext: unable to decrypt")
+				return
+			}
+			response.Data.Plaintext = base64.StdEncoding.EncodeToString(plain)
+		default:
+			fail(w, http.StatusNotFound, "no handler for route")
+			return
+		}
+		reply(w, http.StatusOK, response)
+	}))
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-04 22:01 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-16 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * deserialize-user.go
@@ -14,13 +14,17 @@ import (
 	"sync"
 
 	"github.com/vskurikhin/gofavorites/internal/jwt"
+	"github.com/vskurikhin/gofavorites/internal/policy"
+	"github.com/vskurikhin/gofavorites/internal/services"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/vskurikhin/gofavorites/internal/env"
 )
 
 type UserJwtHandler struct {
+	authPolicy *policy.Policy
 	jwtManager jwt.Manager
+	revocation services.RevocationService
 }
 
 var (
@@ -32,13 +36,27 @@ func GetUserJwtHandler(prop env.Properties) *UserJwtHandler {
 
 	onceUserJwt.Do(func() {
 		userJwtMidl = new(UserJwtHandler)
+		userJwtMidl.authPolicy = prop.AuthPolicy()
 		userJwtMidl.jwtManager = jwt.GetJWTManager(prop)
+		userJwtMidl.revocation = services.GetRevocationService(prop)
 	})
 	return userJwtMidl
 }
 
+// DeserializeUser проверка токена и роли по политике доступа,
+// маршруты которых нет в политике запрещены.
 func (u *UserJwtHandler) DeserializeUser(c *fiber.Ctx) error {
 
+	accessibleRoles, ok := u.authPolicy.HTTPRoles(c.Method(), c.Route().Path)
+
+	if !ok {
+		return c.
+			Status(fiber.StatusForbidden).
+			JSON(fiber.Map{"status": "fail", "message": "no permission to access this route"})
+	}
+	if accessibleRoles.Anonymous() {
+		return c.Next()
+	}
 	var tokenString string
 	authorization := c.Get("Authorization")
 
@@ -62,7 +80,23 @@ func (u *UserJwtHandler) DeserializeUser(c *fiber.Ctx) error {
 				"message": fmt.Sprintf("invalidate token: %v", err),
 			})
 	}
+	if u.revocation.IsRevoked(c.UserContext(), claims.ID) {
+		return c.
+			Status(fiber.StatusUnauthorized).
+			JSON(fiber.Map{"status": "fail", "message": "invalidate token: token has been revoked"})
+	}
+	if !accessibleRoles.Allows(claims.Role()) {
+		return c.
+			Status(fiber.StatusForbidden).
+			JSON(fiber.Map{"status": "fail", "message": "no permission to access this route"})
+	}
 	c.Locals("user", fmt.Sprint(claims.UserName()))
+	c.Locals("role", claims.Role())
+	c.Locals("jti", claims.ID)
+
+	if claims.ExpiresAt != nil {
+		c.Locals("exp", claims.ExpiresAt.Time)
+	}
 
 	return c.Next()
 }
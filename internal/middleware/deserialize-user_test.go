@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-23 15:18 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * deserialize-user_test.go
@@ -11,6 +11,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
@@ -21,22 +22,31 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
 	"github.com/vskurikhin/gofavorites/internal/env"
+	appjwt "github.com/vskurikhin/gofavorites/internal/jwt"
+	"github.com/vskurikhin/gofavorites/internal/policy"
 )
 
+// testJwtSecret секрет подписи JWT для тестов без файла настроек.
+const testJwtSecret = "Gx5nG0bQvhU3Y8fCmtK2eWzRaPj7sL4dNq9XoTi1"
+
 // go test -run TestDeserializeUser
 func TestDeserializeUser(t *testing.T) {
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	httpPort := 65500 + rnd.Intn(34)
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	t.Setenv("HTTP_ADDRESS", fmt.Sprintf("127.0.0.1:%d", httpPort))
 
 	prop := env.GetProperties()
 	testHandler := GetUserJwtHandler(prop).DeserializeUser
 	t.Run("test", func(t *testing.T) {
 		app := fiber.New()
+		micro := fiber.New()
+		app.Mount("/api", micro)
 
-		app.Get("/", testHandler, func(c *fiber.Ctx) error {
+		micro.Get("/favorites/get", testHandler, func(c *fiber.Ctx) error {
 			c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
 			return c.Send([]byte("ok"))
 		})
@@ -46,13 +56,14 @@ func TestDeserializeUser(t *testing.T) {
 		claims := tokenByte.Claims.(jwt.MapClaims)
 
 		claims["sub"] = "test"
+		claims["role"] = "USER"
 		claims["exp"] = now.Add(prop.JwtExpiresIn()).Unix()
 		claims["iat"] = now.Unix()
 		claims["nbf"] = now.Unix()
 
 		tokenString, err := tokenByte.SignedString([]byte(prop.JwtSecret()))
 
-		req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		req := httptest.NewRequest(fiber.MethodGet, "/api/favorites/get", nil)
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenString))
 
 		resp, err := app.Test(req)
@@ -66,5 +77,120 @@ func TestDeserializeUser(t *testing.T) {
 	})
 }
 
+// go test -run TestDeserializeUserRevoked
+func TestDeserializeUserRevoked(t *testing.T) {
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+
+	prop := env.GetProperties()
+	manager := appjwt.GetJWTManager(prop)
+	tokenString, err := manager.Generate(dto.SignInRequest{UserName: "test"})
+	utils.AssertEqual(t, nil, err)
+	claims, err := manager.Verify(tokenString)
+	utils.AssertEqual(t, nil, err)
+
+	var tests = []struct {
+		name    string
+		revoked bool
+		want    int
+	}{
+		{
+			name: "DeserializeUser positive token is not revoked",
+			want: 200,
+		},
+		{
+			name:    "DeserializeUser negative token is revoked",
+			revoked: true,
+			want:    401,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &UserJwtHandler{
+				authPolicy: &policy.Policy{HTTP: map[string][]string{"GET /": {policy.RoleUser}}},
+				jwtManager: manager,
+				revocation: revocationStub{revoked: map[string]bool{claims.ID: test.revoked}},
+			}
+			app := fiber.New()
+			app.Get("/", handler.DeserializeUser, func(c *fiber.Ctx) error {
+				utils.AssertEqual(t, claims.ID, c.Locals("jti"))
+				utils.AssertEqual(t, claims.ExpiresAt.Time, c.Locals("exp"))
+				return c.SendString("ok")
+			})
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenString))
+
+			resp, err := app.Test(req)
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, test.want, resp.StatusCode, "Status code")
+		})
+	}
+}
+
+// go test -run TestDeserializeUserPolicy
+func TestDeserializeUserPolicy(t *testing.T) {
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+
+	prop := env.GetProperties()
+	manager := appjwt.GetJWTManager(prop)
+	userToken, err := manager.Generate(dto.SignInRequest{UserName: "test"})
+	utils.AssertEqual(t, nil, err)
+	adminToken, err := manager.Generate(dto.SignInRequest{UserName: "root", Role: policy.RoleAdmin})
+	utils.AssertEqual(t, nil, err)
+	authPolicy, err := policy.Parse([]byte(`
+http:
+  GET /api/admin: [ADMIN]
+  GET /api/public: [ANONYMOUS]
+`))
+	utils.AssertEqual(t, nil, err)
+
+	var tests = []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{name: "DeserializeUser positive ADMIN route with ADMIN role", path: "/api/admin", token: adminToken, want: 200},
+		{name: "DeserializeUser negative ADMIN route with USER role", path: "/api/admin", token: userToken, want: 403},
+		{name: "DeserializeUser positive ANONYMOUS route without token", path: "/api/public", want: 200},
+		{name: "DeserializeUser negative route is not in policy", path: "/api/other", token: adminToken, want: 403},
+	}
+	handler := &UserJwtHandler{authPolicy: authPolicy, jwtManager: manager, revocation: revocationStub{}}
+	app := fiber.New()
+	micro := fiber.New()
+	app.Mount("/api", micro)
+
+	for _, path := range []string{"/admin", "/public", "/other"} {
+		micro.Get(path, handler.DeserializeUser, func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, test.path, nil)
+
+			if test.token != "" {
+				req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", test.token))
+			}
+			resp, err := app.Test(req)
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, test.want, resp.StatusCode, "Status code")
+		})
+	}
+}
+
+type revocationStub struct {
+	revoked map[string]bool
+}
+
+func (r revocationStub) IsRevoked(_ context.Context, jti string) bool {
+	return r.revoked[jti]
+}
+
+func (r revocationStub) Revoke(_ context.Context, _ string, _ time.Time) error {
+	return nil
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-05 22:59 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites.go
@@ -11,6 +11,7 @@ package models
 import (
 	"database/sql"
 	"math"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ssoroka/slice"
@@ -34,10 +35,15 @@ func (a Asset) ToEntity() entity.Asset {
 }
 
 type Favorites struct {
-	id      uuid.UUID
-	asset   Asset
-	user    User
-	version int64
+	id       uuid.UUID
+	asset    Asset
+	user     User
+	version  int64
+	deleted  bool
+	metadata string
+	// shardID и writtenAt шард и время последней записи.
+	shardID   string
+	writtenAt time.Time
 }
 
 func AssetFromEntity(entity entity.Asset) Asset {
@@ -55,18 +61,61 @@ func (f Favorites) User() User {
 	return f.user
 }
 
+func (f Favorites) Version() int64 {
+	return f.version
+}
+
+func (f Favorites) Deleted() bool {
+	return f.deleted
+}
+
+func (f Favorites) Metadata() string {
+	return f.metadata
+}
+
+func (f Favorites) ShardID() string {
+	return f.shardID
+}
+
+func (f Favorites) WrittenAt() time.Time {
+	return f.writtenAt
+}
+
 func (f Favorites) WithUpk(upk string) Favorites {
 	t := f
 	t.user.upk = upk
 	return t
 }
 
+// WithVersion версия избранного ожидаемая клиентом при сохранении.
+func (f Favorites) WithVersion(version int64) Favorites {
+	t := f
+	t.version = version
+	return t
+}
+
+func (f Favorites) WithUser(user User) Favorites {
+	t := f
+	t.user = user
+	return t
+}
+
 func (f Favorites) ToDto() dto.Favorites {
-	return dto.Favorites{
+
+	result := dto.Favorites{
 		ID:        f.id.String(),
 		Isin:      f.asset.isin,
 		AssetType: f.asset.assetType,
+		Metadata:  f.metadata,
+		Version:   f.version,
+		Deleted:   f.deleted,
+		ShardID:   f.shardID,
 	}
+	if !f.writtenAt.IsZero() {
+		writtenAt := f.writtenAt
+		result.WrittenAt = &writtenAt
+	}
+	return result
 }
 
 func (f Favorites) ToEntity() entity.Favorites {
@@ -80,10 +129,16 @@ func (f Favorites) ToEntity() entity.Favorites {
 	return entity.MakeFavorites(
 		f.id, f.asset.ToEntity(), f.user.ToEntity(), version,
 		entity.DefaultTAttributes(),
-	)
+	).WithMetadata(f.metadata)
 }
 
 func (f Favorites) ToProto() *pb.Favorites {
+
+	var writtenAt int64
+
+	if !f.writtenAt.IsZero() {
+		writtenAt = f.writtenAt.UnixMilli()
+	}
 	return &pb.Favorites{
 		Asset: &pb.Asset{
 			Isin: f.asset.isin,
@@ -95,6 +150,11 @@ func (f Favorites) ToProto() *pb.Favorites {
 			PersonalKey: f.user.personalKey,
 			Upk:         f.user.upk,
 		},
+		Version:   f.version,
+		Deleted:   f.deleted,
+		Metadata:  f.metadata,
+		ShardId:   f.shardID,
+		WrittenAt: writtenAt,
 	}
 }
 
@@ -134,7 +194,10 @@ func FavoritesFromDto(dto dto.Favorites, personalKey, upk string) Favorites {
 	asset := makeAsset(isin, assetType)
 	user := MakeUser(personalKey, upk)
 
-	return makeFavorites(uuid.Max, asset, user, math.MinInt64)
+	result := makeFavorites(uuid.Max, asset, user, math.MinInt64)
+	result.metadata = dto.Metadata
+
+	return result
 }
 
 func FavoritesFromEntity(entity entity.Favorites) Favorites {
@@ -142,7 +205,13 @@ func FavoritesFromEntity(entity entity.Favorites) Favorites {
 	asset := AssetFromEntity(entity.Asset())
 	user := UserFromEntity(entity.User())
 
-	return makeFavorites(entity.ID(), asset, user, entity.Version().Int64)
+	result := makeFavorites(entity.ID(), asset, user, entity.Version().Int64)
+	result.deleted = entity.Deleted().Bool
+	result.metadata = entity.Metadata()
+	result.shardID = entity.Provenance().ShardID()
+	result.writtenAt = entity.Provenance().WrittenAt().Time
+
+	return result
 }
 
 func FavoritesFromProto(proto *pb.Favorites) Favorites {
@@ -155,7 +224,10 @@ func FavoritesFromProto(proto *pb.Favorites) Favorites {
 	asset := makeAsset(isin, assetType)
 	user := MakeUser(proto.GetUser().GetPersonalKey(), proto.GetUser().GetUpk())
 
-	return makeFavorites(uuid.Max, asset, user, math.MinInt64)
+	result := makeFavorites(uuid.Max, asset, user, math.MinInt64)
+	result.metadata = proto.GetMetadata()
+
+	return result
 }
 
 func UserFromEntity(entity entity.User) User {
@@ -0,0 +1,149 @@
+/*
+ * This file was last modified at 2024-08-12 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * note.go
+ * $Id$
+ */
+
+package models
+
+import (
+	"database/sql"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/ssoroka/slice"
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+type Note struct {
+	id      uuid.UUID
+	name    string
+	user    User
+	body    string
+	version int64
+	deleted bool
+}
+
+func (n Note) Name() string {
+	return n.name
+}
+
+func (n Note) User() User {
+	return n.user
+}
+
+func (n Note) Body() string {
+	return n.body
+}
+
+func (n Note) Version() int64 {
+	return n.version
+}
+
+func (n Note) Deleted() bool {
+	return n.deleted
+}
+
+func (n Note) WithBody(body string) Note {
+	t := n
+	t.body = body
+	return t
+}
+
+func (n Note) WithUpk(upk string) Note {
+	t := n
+	t.user.upk = upk
+	return t
+}
+
+func (n Note) WithUser(user User) Note {
+	t := n
+	t.user = user
+	return t
+}
+
+func (n Note) ToDto() dto.Note {
+	return dto.Note{
+		ID:      n.id.String(),
+		Name:    n.name,
+		Body:    n.body,
+		Version: n.version,
+		Deleted: n.deleted,
+	}
+}
+
+func (n Note) ToEntity() entity.Note {
+
+	var version sql.NullInt64
+
+	if n.version > 0 {
+		version.Int64 = n.version
+		version.Valid = true
+	}
+	return entity.MakeNote(n.id, n.name, n.user.ToEntity(), n.body, version, entity.DefaultTAttributes())
+}
+
+func (n Note) ToProto() *pb.Note {
+	return &pb.Note{
+		User: &pb.User{
+			PersonalKey: n.user.personalKey,
+			Upk:         n.user.upk,
+		},
+		Name:    n.name,
+		Body:    n.body,
+		Version: n.version,
+		Deleted: n.deleted,
+	}
+}
+
+func NotesSliceToDto(notes []Note) (result []dto.Note) {
+
+	result = make([]dto.Note, 0, len(notes))
+	result = slice.Map[Note, dto.Note](
+		notes,
+		func(i int, n Note) dto.Note {
+			return n.ToDto()
+		})
+	return result
+}
+
+func NoteFromDto(dto dto.Note, personalKey, upk string) Note {
+
+	user := MakeUser(personalKey, upk)
+	result := makeNote(uuid.Max, dto.Name, user, math.MinInt64)
+
+	return result.WithBody(dto.Body)
+}
+
+func NoteFromEntity(entity entity.Note) Note {
+
+	user := UserFromEntity(entity.User())
+	result := makeNote(entity.ID(), entity.Name(), user, entity.Version().Int64)
+	result.deleted = entity.Deleted().Bool
+
+	return result.WithBody(entity.Body())
+}
+
+func NoteFromProto(proto *pb.Note) Note {
+
+	if proto == nil {
+		return Note{id: uuid.Max, version: math.MinInt64}
+	}
+	user := MakeUser(proto.GetUser().GetPersonalKey(), proto.GetUser().GetUpk())
+	result := makeNote(uuid.Max, proto.GetName(), user, math.MinInt64)
+
+	return result.WithBody(proto.GetBody())
+}
+
+func makeNote(id uuid.UUID, name string, user User, version int64) Note {
+	return Note{
+		id:      id,
+		name:    name,
+		user:    user,
+		version: version,
+	}
+}
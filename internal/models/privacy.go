@@ -0,0 +1,256 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * privacy.go
+ * $Id$
+ */
+
+package models
+
+import (
+	"time"
+
+	"github.com/ssoroka/slice"
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+// OtpSecret секрет одноразовых паролей пользователя без самого секрета:
+// секрет возвращается только при создании.
+type OtpSecret struct {
+	name      string
+	kind      string
+	digits    int32
+	period    int32
+	createdAt time.Time
+}
+
+func OtpSecretFromEntity(entity entity.Otp) OtpSecret {
+	return OtpSecret{
+		name:      entity.Name(),
+		kind:      entity.Kind(),
+		digits:    entity.Digits(),
+		period:    entity.Period(),
+		createdAt: entity.CreatedAt(),
+	}
+}
+
+func (o OtpSecret) Name() string {
+	return o.name
+}
+
+func (o OtpSecret) Kind() string {
+	return o.kind
+}
+
+func (o OtpSecret) ToDto() dto.OtpSecret {
+	return dto.OtpSecret{
+		Name:      o.name,
+		Kind:      o.kind,
+		Digits:    o.digits,
+		Period:    o.period,
+		CreatedAt: o.createdAt,
+	}
+}
+
+func (o OtpSecret) ToProto() *pb.OtpSecret {
+	return &pb.OtpSecret{
+		Name:   o.name,
+		Kind:   pb.OtpKind(pb.OtpKind_value[o.kind]),
+		Digits: o.digits,
+		Period: o.period,
+	}
+}
+
+// UserData все данные пользователя для выгрузки по его запросу.
+type UserData struct {
+	user            User
+	createdAt       time.Time
+	favorites       []Favorites
+	syncedFavorites []Favorites
+	notes           []Note
+	otpSecrets      []OtpSecret
+}
+
+// MakeUserData выгрузка данных пользователя user, createdAt время регистрации
+// пользователя, нулевое если пользователя нет.
+func MakeUserData(user User, version int64, createdAt time.Time) UserData {
+	user.version = version
+	return UserData{
+		user:            user,
+		createdAt:       createdAt,
+		favorites:       make([]Favorites, 0),
+		syncedFavorites: make([]Favorites, 0),
+		notes:           make([]Note, 0),
+		otpSecrets:      make([]OtpSecret, 0),
+	}
+}
+
+func (d UserData) User() User {
+	return d.user
+}
+
+func (d UserData) Version() int64 {
+	return d.user.version
+}
+
+func (d UserData) CreatedAt() time.Time {
+	return d.createdAt
+}
+
+func (d UserData) Favorites() []Favorites {
+	return d.favorites
+}
+
+func (d UserData) SyncedFavorites() []Favorites {
+	return d.syncedFavorites
+}
+
+func (d UserData) Notes() []Note {
+	return d.notes
+}
+
+func (d UserData) OtpSecrets() []OtpSecret {
+	return d.otpSecrets
+}
+
+func (d UserData) WithFavorites(favorites []Favorites) UserData {
+	t := d
+	t.favorites = favorites
+	return t
+}
+
+func (d UserData) WithSyncedFavorites(favorites []Favorites) UserData {
+	t := d
+	t.syncedFavorites = favorites
+	return t
+}
+
+func (d UserData) WithNotes(notes []Note) UserData {
+	t := d
+	t.notes = notes
+	return t
+}
+
+func (d UserData) WithOtpSecrets(otpSecrets []OtpSecret) UserData {
+	t := d
+	t.otpSecrets = otpSecrets
+	return t
+}
+
+func (d UserData) ToDto() dto.UserData {
+
+	result := dto.UserData{
+		User:            d.user.personalKey,
+		Upk:             d.user.upk,
+		Version:         d.user.version,
+		Favorites:       FavoritesSliceToDto(d.favorites),
+		SyncedFavorites: FavoritesSliceToDto(d.syncedFavorites),
+		Notes:           NotesSliceToDto(d.notes),
+		OtpSecrets: slice.Map[OtpSecret, dto.OtpSecret](
+			d.otpSecrets,
+			func(i int, o OtpSecret) dto.OtpSecret {
+				return o.ToDto()
+			}),
+	}
+	if !d.createdAt.IsZero() {
+		createdAt := d.createdAt
+		result.CreatedAt = &createdAt
+	}
+	return result
+}
+
+func (d UserData) ToProto() *pb.UserData {
+
+	var createdAt int64
+
+	if !d.createdAt.IsZero() {
+		createdAt = d.createdAt.UnixMilli()
+	}
+	result := &pb.UserData{
+		User: &pb.User{
+			PersonalKey: d.user.personalKey,
+			Upk:         d.user.upk,
+		},
+		Version:         d.user.version,
+		CreatedAt:       createdAt,
+		Favorites:       make([]*pb.Favorites, 0, len(d.favorites)),
+		SyncedFavorites: make([]*pb.Favorites, 0, len(d.syncedFavorites)),
+		Notes:           make([]*pb.Note, 0, len(d.notes)),
+		OtpSecrets:      make([]*pb.OtpSecret, 0, len(d.otpSecrets)),
+	}
+	for _, favorites := range d.favorites {
+		result.Favorites = append(result.Favorites, favorites.ToProto())
+	}
+	for _, favorites := range d.syncedFavorites {
+		result.SyncedFavorites = append(result.SyncedFavorites, favorites.ToProto())
+	}
+	for _, note := range d.notes {
+		result.Notes = append(result.Notes, note.ToProto())
+	}
+	for _, otpSecret := range d.otpSecrets {
+		result.OtpSecrets = append(result.OtpSecrets, otpSecret.ToProto())
+	}
+	return result
+}
+
+// Erasure запись аудита удаления данных пользователя.
+type Erasure struct {
+	id              int64
+	users           int64
+	favorites       int64
+	notes           int64
+	otpSecrets      int64
+	outbox          int64
+	syncedFavorites int64
+	syncedNotes     int64
+	erasedAt        time.Time
+}
+
+func ErasureFromEntity(entity entity.UserErasure) Erasure {
+	return Erasure{
+		id:              entity.ID(),
+		users:           entity.Users(),
+		favorites:       entity.Favorites(),
+		notes:           entity.Notes(),
+		otpSecrets:      entity.OtpSecrets(),
+		outbox:          entity.Outbox(),
+		syncedFavorites: entity.SyncedFavorites(),
+		syncedNotes:     entity.SyncedNotes(),
+		erasedAt:        entity.ErasedAt(),
+	}
+}
+
+func (e Erasure) ID() int64 {
+	return e.id
+}
+
+func (e Erasure) ToDto() dto.Erasure {
+	return dto.Erasure{
+		ID:              e.id,
+		Users:           e.users,
+		Favorites:       e.favorites,
+		Notes:           e.notes,
+		OtpSecrets:      e.otpSecrets,
+		Outbox:          e.outbox,
+		SyncedFavorites: e.syncedFavorites,
+		SyncedNotes:     e.syncedNotes,
+		ErasedAt:        e.erasedAt,
+	}
+}
+
+func (e Erasure) ToProto() *pb.Erasure {
+	return &pb.Erasure{
+		Id:              e.id,
+		Users:           e.users,
+		Favorites:       e.favorites,
+		Notes:           e.notes,
+		OtpSecrets:      e.otpSecrets,
+		Outbox:          e.outbox,
+		SyncedFavorites: e.syncedFavorites,
+		SyncedNotes:     e.syncedNotes,
+		ErasedAt:        e.erasedAt.UnixMilli(),
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * policy.go
+ * $Id$
+ */
+//!+
+
+// Package policy политика доступа: роли, которым разрешены методы gRPC и маршруты HTTP.
+package policy
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RoleAdmin роль для операционных (служебных) методов и маршрутов.
+	RoleAdmin = "ADMIN"
+	// RoleAnonymous разрешает вызов без токена.
+	RoleAnonymous = "ANONYMOUS"
+	// RoleUser роль пользователя по умолчанию.
+	RoleUser = "USER"
+)
+
+var ErrPolicyRules = fmt.Errorf("policy rule without roles")
+
+//go:embed go-favorites.policy.yaml
+var defaultPolicy []byte
+
+// Policy соответствие методов gRPC (полное имя метода) и маршрутов HTTP
+// («МЕТОД /путь» как он объявлен в маршрутизаторе) списку ролей.
+// Методы и маршруты которых нет в политике запрещены.
+// Пример файла:
+//
+//	grpc:
+//	  /proto.FavoritesService/Get: [USER]
+//	  /grpc.reflection.v1.ServerReflection/ServerReflectionInfo: [ADMIN]
+//	http:
+//	  GET /api/favorites/get: [USER]
+//	  GET /api/admin/policy: [ADMIN]
+type Policy struct {
+	GRPC map[string][]string `yaml:"grpc" json:"grpc"`
+	HTTP map[string][]string `yaml:"http" json:"http"`
+}
+
+// Default встроенная политика из go-favorites.policy.yaml, применяется если файл политики не задан.
+func Default() *Policy {
+
+	result, err := Parse(defaultPolicy)
+
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// Load загрузка политики из Yaml-файла.
+func Load(file string) (*Policy, error) {
+
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse разбор политики в формате Yaml, имена ролей приводятся к верхнему регистру.
+func Parse(data []byte) (*Policy, error) {
+
+	var result Policy
+
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	for _, rules := range []map[string][]string{result.GRPC, result.HTTP} {
+		for key, roles := range rules {
+			if len(roles) == 0 {
+				return nil, fmt.Errorf("%w: %s", ErrPolicyRules, key)
+			}
+			for i, role := range roles {
+				roles[i] = strings.ToUpper(strings.TrimSpace(role))
+			}
+		}
+	}
+	return &result, nil
+}
+
+// GRPCRoles роли, которым разрешён метод gRPC, false если метода нет в политике.
+func (p *Policy) GRPCRoles(fullMethod string) (Roles, bool) {
+
+	if p == nil {
+		return nil, false
+	}
+	roles, ok := p.GRPC[fullMethod]
+
+	return roles, ok
+}
+
+// HTTPRoles роли, которым разрешён маршрут HTTP, false если маршрута нет в политике.
+func (p *Policy) HTTPRoles(method, route string) (Roles, bool) {
+
+	if p == nil {
+		return nil, false
+	}
+	roles, ok := p.HTTP[strings.ToUpper(method)+" "+route]
+
+	return roles, ok
+}
+
+func (p *Policy) String() string {
+
+	if p == nil {
+		return "{}"
+	}
+	var sb strings.Builder
+
+	for _, rules := range []map[string][]string{p.GRPC, p.HTTP} {
+		keys := make([]string, 0, len(rules))
+		for key := range rules {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sb.WriteString(fmt.Sprintf("%s: %v\n", key, rules[key]))
+		}
+	}
+	return sb.String()
+}
+
+// Roles роли из правила политики.
+type Roles []string
+
+// Allows проверка что роль входит в правило.
+func (r Roles) Allows(role string) bool {
+
+	for _, allowed := range r {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Anonymous правило разрешает вызов без токена.
+func (r Roles) Anonymous() bool {
+	return r.Allows(RoleAnonymous)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,102 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * policy_test.go
+ * $Id$
+ */
+//!+
+
+// Package policy политика доступа: роли, которым разрешены методы gRPC и маршруты HTTP.
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "positive test #0 Policy Parse and Roles",
+			fRun: testPolicyParse,
+		},
+		{
+			name: "positive test #1 Policy Default matches go-favorites.policy.yaml",
+			fRun: testPolicyDefaultFile,
+		},
+		{
+			name: "negative test #2 Policy denies what is not listed",
+			fRun: testPolicyDenies,
+		},
+		{
+			name: "negative test #3 Policy Parse and Load errors",
+			fRun: testPolicyNegative,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testPolicyParse(t *testing.T) {
+	got, err := Parse([]byte(`
+grpc:
+  /proto.FavoritesService/Get: [user, " admin "]
+http:
+  get /api/health: [ANONYMOUS]
+  GET /api/admin/policy: [ADMIN]
+`))
+	assert.Nil(t, err)
+	roles, ok := got.GRPCRoles("/proto.FavoritesService/Get")
+	assert.True(t, ok)
+	assert.Equal(t, Roles{RoleUser, RoleAdmin}, roles)
+	assert.True(t, roles.Allows(RoleAdmin))
+	assert.False(t, roles.Anonymous())
+	roles, ok = got.HTTPRoles("get", "/api/admin/policy")
+	assert.True(t, ok)
+	assert.True(t, roles.Allows(RoleAdmin))
+	assert.False(t, roles.Allows(RoleUser))
+	assert.Equal(t, "/proto.FavoritesService/Get: [USER ADMIN]\nGET /api/admin/policy: [ADMIN]\nget /api/health: [ANONYMOUS]\n", got.String())
+}
+
+func testPolicyDefaultFile(t *testing.T) {
+	got, err := Load("go-favorites.policy.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, Default(), got)
+}
+
+func testPolicyDenies(t *testing.T) {
+	_, ok := Default().GRPCRoles("/testing.testpb.v1.TestService/Ping")
+	assert.False(t, ok)
+	_, ok = Default().HTTPRoles("DELETE", "/api/favorites/get")
+	assert.False(t, ok)
+	var p *Policy
+	_, ok = p.GRPCRoles("/proto.FavoritesService/Get")
+	assert.False(t, ok)
+	_, ok = p.HTTPRoles("GET", "/api/favorites/get")
+	assert.False(t, ok)
+	assert.Equal(t, "{}", p.String())
+	assert.False(t, Roles(nil).Allows(""))
+}
+
+func testPolicyNegative(t *testing.T) {
+	_, err := Parse([]byte("grpc: [\n"))
+	assert.NotNil(t, err)
+	_, err = Parse([]byte("grpc:\n  /proto.FavoritesService/Get: []\n"))
+	assert.True(t, errors.Is(err, ErrPolicyRules))
+	_, err = Load("not-exists.yaml")
+	assert.NotNil(t, err)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
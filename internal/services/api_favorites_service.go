@@ -17,6 +17,7 @@ import (
 )
 
 type ApiFavoritesService interface {
+	ApiFavoritesDelete(ctx context.Context, model models.Favorites) (models.Favorites, error)
 	ApiFavoritesGet(ctx context.Context, model models.Favorites) (models.Favorites, error)
 	ApiFavoritesGetForUser(ctx context.Context, model models.User) ([]models.Favorites, error)
 	ApiFavoritesSet(ctx context.Context, model models.Favorites) (models.Favorites, error)
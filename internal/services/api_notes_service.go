@@ -0,0 +1,27 @@
+/*
+ * This file was last modified at 2024-08-12 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * api_notes_service.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+
+	"github.com/vskurikhin/gofavorites/internal/models"
+)
+
+type ApiNotesService interface {
+	ApiNotesDelete(ctx context.Context, model models.Note) (models.Note, error)
+	ApiNotesGet(ctx context.Context, model models.Note) (models.Note, error)
+	ApiNotesGetForUser(ctx context.Context, model models.User) ([]models.Note, error)
+	ApiNotesSet(ctx context.Context, model models.Note) (models.Note, error)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,25 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * api_privacy_service.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+
+	"github.com/vskurikhin/gofavorites/internal/models"
+)
+
+type ApiPrivacyService interface {
+	ApiPrivacyErase(ctx context.Context, model models.User) (models.Erasure, error)
+	ApiPrivacyExport(ctx context.Context, model models.User) (models.UserData, error)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 20:32 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 09:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * asset_search_service.go
@@ -21,10 +21,6 @@ import (
 	"github.com/vskurikhin/gofavorites/internal/domain/repo"
 	"github.com/vskurikhin/gofavorites/internal/env"
 	"github.com/vskurikhin/gofavorites/internal/tool"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/encoding/gzip"
 
 	pb "github.com/vskurikhin/gofavorites/proto"
 )
@@ -34,12 +30,10 @@ type AssetSearchService interface {
 }
 
 type assetSearchService struct {
-	assetGRPCAddress string
-	creds            credentials.TransportCredentials
-	opts             []grpc.DialOption
-	repoAsset        domain.Repo[*entity.Asset]
-	requestInterval  time.Duration
-	sLog             *slog.Logger
+	assetGRPC       *tool.GRPCClient
+	repoAsset       domain.Repo[*entity.Asset]
+	requestInterval time.Duration
+	sLog            *slog.Logger
 }
 
 var _ AssetSearchService = (*assetSearchService)(nil)
@@ -54,17 +48,7 @@ func GetAssetSearchService(prop env.Properties) AssetSearchService {
 
 	onceAssetSearch.Do(func() {
 		assetSearchServ = new(assetSearchService)
-		opts := []grpc.DialOption{
-			grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
-		}
-		tlsCredentials, err := tool.LoadClientTLSCredentials(prop.Config().GRPCTLSCAFile())
-		if err != nil {
-			opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		} else {
-			opts = append(opts, grpc.WithTransportCredentials(tlsCredentials))
-		}
-		assetSearchServ.assetGRPCAddress = prop.ExternalAssetGRPCAddress()
-		assetSearchServ.opts = opts
+		assetSearchServ.assetGRPC = prop.ExternalAssetGRPCClient()
 		assetSearchServ.repoAsset = repo.GetAssetPostgresCachedRepo(prop)
 		assetSearchServ.requestInterval = prop.ExternalRequestTimeoutInterval()
 		assetSearchServ.sLog = prop.Logger()
@@ -120,11 +104,10 @@ func (a *assetSearchService) dbLookup(ctx context.Context, isin string) bool {
 
 func (a *assetSearchService) grpcLookup(ctx context.Context, isin string) bool {
 
-	conn, err := grpc.NewClient(a.assetGRPCAddress, a.opts...)
+	conn, err := a.assetGRPC.Conn()
 	if err != nil {
 		return false
 	}
-	defer func() { _ = conn.Close() }()
 	c := pb.NewAssetServiceClient(conn)
 	var request pb.AssetRequest
 	request.Asset = &pb.Asset{Isin: isin}
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-20 19:34 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * asset_search_service_test.go
@@ -57,6 +57,7 @@ func TestAssetSearchService(t *testing.T) {
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	t.Setenv("ASSET_GRPC_ADDRESS", fmt.Sprintf("127.0.0.1:%d", 65501+rnd.Intn(34)))
 	t.Setenv("AUTH_GRPC_ADDRESS", fmt.Sprintf("127.0.0.1:%d", 65501+rnd.Intn(34)))
 	t.Setenv("REQUEST_TIMEOUT_INTERVAL_MS", "500")
@@ -136,12 +137,10 @@ func testAssetSearchServiceLookupNegativeCase2(t *testing.T) {
 func getAssetSearchService(prop env.Properties, repoAsset domain.Repo[*entity.Asset]) AssetSearchService {
 	assetSearchServ = new(assetSearchService)
 	opts := []grpc.DialOption{
-		grpc.WithBlock(),
 		grpc.WithNoProxy(),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	}
-	assetSearchServ.assetGRPCAddress = prop.ExternalAssetGRPCAddress()
-	assetSearchServ.opts = opts
+	assetSearchServ.assetGRPC = tool.GRPCConnect(prop.ExternalAssetGRPCAddress(), tool.GRPCOptions{}, opts...)
 	assetSearchServ.repoAsset = repoAsset
 	assetSearchServ.requestInterval = prop.ExternalRequestTimeoutInterval()
 	assetSearchServ.sLog = prop.Logger()
@@ -0,0 +1,144 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * authenticator.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+const (
+	AuthProviderGRPC  = "grpc"
+	AuthProviderLocal = "local"
+
+	authenticatorTimeout = time.Second
+)
+
+var (
+	ErrAuthProvider       = fmt.Errorf("unknown auth provider")
+	ErrInvalidCredentials = fmt.Errorf("invalid user name or password")
+)
+
+// Identity проверенный пользователь: персональный ключ и роль,
+// пустая роль означает policy.RoleUser.
+type Identity struct {
+	PersonalKey string
+	Role        string
+}
+
+// Authenticator проверка учётных данных пользователя при входе.
+type Authenticator interface {
+	// Authenticate возвращает проверенного пользователя
+	// или ErrInvalidCredentials если пара логин/пароль неверна.
+	Authenticate(ctx context.Context, userName, password string) (Identity, error)
+}
+
+type grpcAuthenticator struct {
+	authGRPC *tool.GRPCClient
+	sLog     *slog.Logger
+}
+
+// unknownAuthenticator отказ во входе при неизвестном auth.provider,
+// чтобы опечатка в настройках не включала чужой способ проверки.
+type unknownAuthenticator struct {
+	err error
+}
+
+var _ Authenticator = (*grpcAuthenticator)(nil)
+var _ Authenticator = (*unknownAuthenticator)(nil)
+var (
+	onceAuthenticator = new(sync.Once)
+	authenticator     Authenticator
+)
+
+// GetAuthenticator — потокобезопасное (thread-safe) создание
+// проверки учётных данных выбранной в настройках auth.provider.
+func GetAuthenticator(prop env.Properties) Authenticator {
+
+	onceAuthenticator.Do(func() {
+		switch prop.Config().AuthProvider() {
+		case AuthProviderLocal:
+			authenticator = getLocalAuthenticator(prop)
+		case AuthProviderGRPC, "":
+			authenticator = getGRPCAuthenticator(prop)
+		default:
+			err := CheckAuthProvider(prop)
+			prop.Logger().Error(env.MSG+"GetAuthenticator", "msg", "auth provider", "err", err)
+			authenticator = &unknownAuthenticator{err: err}
+		}
+	})
+	return authenticator
+}
+
+// CheckAuthProvider проверка настройки auth.provider при старте.
+func CheckAuthProvider(prop env.Properties) error {
+
+	switch provider := prop.Config().AuthProvider(); provider {
+	case AuthProviderLocal, AuthProviderGRPC, "":
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrAuthProvider, provider)
+	}
+}
+
+func getGRPCAuthenticator(prop env.Properties) *grpcAuthenticator {
+
+	result := new(grpcAuthenticator)
+	result.authGRPC = prop.ExternalAuthGRPCClient()
+	result.sLog = prop.Logger()
+
+	return result
+}
+
+// Authenticate проверка учётных данных во внешнем сервисе аутентификации пользователей.
+func (g *grpcAuthenticator) Authenticate(ctx context.Context, userName, password string) (Identity, error) {
+
+	conn, err := g.authGRPC.Conn()
+	if err != nil {
+		return Identity{}, err
+	}
+	c := pb.NewUserServiceClient(conn)
+	ctx, cancel := context.WithTimeout(ctx, authenticatorTimeout)
+	defer func() {
+		cancel()
+	}()
+	request := pb.CredentialsRequest{UserName: userName, Password: password}
+	resp, err := c.Verify(ctx, &request)
+
+	if err != nil {
+		g.sLog.ErrorContext(ctx,
+			env.MSG+"Authenticator.Authenticate",
+			"msg", "authenticator gRPC verify credentials",
+			"err", err,
+		)
+		return Identity{}, err
+	}
+	if resp.GetStatus() != pb.Status_OK || resp.GetUser().GetPersonalKey() == "" {
+		return Identity{}, ErrInvalidCredentials
+	}
+	return Identity{PersonalKey: resp.GetUser().GetPersonalKey(), Role: resp.GetRole()}, nil
+}
+
+// Authenticate всегда отказ: способ проверки учётных данных не настроен.
+func (u *unknownAuthenticator) Authenticate(context.Context, string, string) (Identity, error) {
+	return Identity{}, u.err
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,233 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * authenticator_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/policy"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials/local"
+
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+func TestAuthenticator(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive GetAuthenticator",
+			fRun: testGetAuthenticator,
+		},
+		{
+			name: "test #1 positive local Authenticate",
+			fRun: testLocalAuthenticatorPositive,
+		},
+		{
+			name: "test #2 negative local Authenticate",
+			fRun: testLocalAuthenticatorNegative,
+		},
+		{
+			name: "test #3 negative local users file malformed",
+			fRun: testParseLocalUsersNegative,
+		},
+		{
+			name: "test #4 positive gRPC Authenticate",
+			fRun: testGRPCAuthenticatorPositive,
+		},
+		{
+			name: "test #5 negative gRPC Authenticate",
+			fRun: testGRPCAuthenticatorNegative,
+		},
+		{
+			name: "test #6 negative unknown auth provider",
+			fRun: testUnknownAuthenticator,
+		},
+	}
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testGetAuthenticator(t *testing.T) {
+	prop := env.GetProperties()
+	got := GetAuthenticator(prop)
+	assert.NotNil(t, got)
+}
+
+func testUnknownAuthenticator(t *testing.T) {
+	prop := env.GetProperties()
+	assert.Nil(t, CheckAuthProvider(prop))
+	for _, provider := range []string{AuthProviderGRPC, AuthProviderLocal} {
+		assert.Nil(t, CheckAuthProvider(stubAuthProperties{Properties: prop, provider: provider}))
+	}
+	err := CheckAuthProvider(stubAuthProperties{Properties: prop, provider: "lokal"})
+	assert.ErrorIs(t, err, ErrAuthProvider)
+	authenticator := &unknownAuthenticator{err: err}
+	_, err = authenticator.Authenticate(context.TODO(), "alice", "password")
+	assert.ErrorIs(t, err, ErrAuthProvider)
+}
+
+type stubAuthConfig struct {
+	env.Config
+	provider string
+}
+
+func (c stubAuthConfig) AuthProvider() string {
+	return c.provider
+}
+
+type stubAuthProperties struct {
+	env.Properties
+	provider string
+}
+
+func (p stubAuthProperties) Config() env.Config {
+	return stubAuthConfig{Config: p.Properties.Config(), provider: p.provider}
+}
+
+func testLocalAuthenticatorPositive(t *testing.T) {
+	authenticator := getTestLocalAuthenticator(t)
+	got, err := authenticator.Authenticate(context.TODO(), "alice", "password")
+	assert.Nil(t, err)
+	assert.Equal(t, Identity{PersonalKey: "alice", Role: policy.RoleUser}, got)
+	got, err = authenticator.Authenticate(context.TODO(), "bob", "password")
+	assert.Nil(t, err)
+	assert.Equal(t, Identity{PersonalKey: "personal-key-of-bob", Role: policy.RoleUser}, got)
+	got, err = authenticator.Authenticate(context.TODO(), "root", "password")
+	assert.Nil(t, err)
+	assert.Equal(t, Identity{PersonalKey: "root", Role: policy.RoleAdmin}, got)
+}
+
+func testLocalAuthenticatorNegative(t *testing.T) {
+	authenticator := getTestLocalAuthenticator(t)
+	_, err := authenticator.Authenticate(context.TODO(), "alice", "wrong")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	_, err = authenticator.Authenticate(context.TODO(), "carol", "password")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	_, err = (&localAuthenticator{}).Authenticate(context.TODO(), "alice", "password")
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func testParseLocalUsersNegative(t *testing.T) {
+	_, err := parseLocalUsers(strings.NewReader("alice\n"))
+	assert.NotNil(t, err)
+	_, err = parseLocalUsers(strings.NewReader(":hash\n"))
+	assert.NotNil(t, err)
+	_, err = parseLocalUsers(strings.NewReader("alice:hash:key:USER:extra\n"))
+	assert.NotNil(t, err)
+}
+
+func testGRPCAuthenticatorPositive(t *testing.T) {
+	address := getTestAuthenticatorAddress()
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+	defer func() {
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+	}()
+	up := make(chan struct{})
+	go grpcServeAuthenticatorServer(ctx, address, userServiceVerify{}, up)
+	<-up
+
+	authenticator := getTestGRPCAuthenticator(address)
+	got, err := authenticator.Authenticate(context.TODO(), "alice", "password")
+	assert.Nil(t, err)
+	assert.Equal(t, Identity{PersonalKey: "personal-key-of-alice", Role: policy.RoleAdmin}, got)
+	_, err = authenticator.Authenticate(context.TODO(), "alice", "wrong")
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func testGRPCAuthenticatorNegative(t *testing.T) {
+	authenticator := getTestGRPCAuthenticator(getTestAuthenticatorAddress())
+	_, err := authenticator.Authenticate(context.TODO(), "alice", "password")
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrInvalidCredentials, err)
+}
+
+func getTestAuthenticatorAddress() string {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return fmt.Sprintf("127.0.0.1:%d", 65385+rnd.Intn(34))
+}
+
+func getTestGRPCAuthenticator(address string) Authenticator {
+	return &grpcAuthenticator{
+		authGRPC: tool.GRPCConnect(
+			address,
+			tool.GRPCOptions{},
+			grpc.WithNoProxy(),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		),
+		sLog: slog.Default(),
+	}
+}
+
+func getTestLocalAuthenticator(t *testing.T) Authenticator {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.Nil(t, err)
+	users, err := parseLocalUsers(strings.NewReader(fmt.Sprintf(
+		"# users\n\nalice:%s\nbob:%s:personal-key-of-bob\nroot:%s::admin\n", hash, hash, hash,
+	)))
+	assert.Nil(t, err)
+	return &localAuthenticator{sLog: slog.Default(), users: users}
+}
+
+type userServiceVerify struct {
+	pb.UnimplementedUserServiceServer
+}
+
+func (a userServiceVerify) Verify(_ context.Context, request *pb.CredentialsRequest) (*pb.UserResponse, error) {
+	if request.GetUserName() == "alice" && request.GetPassword() == "password" {
+		return &pb.UserResponse{User: &pb.User{PersonalKey: "personal-key-of-alice"}, Status: pb.Status_OK, Role: policy.RoleAdmin}, nil
+	}
+	return &pb.UserResponse{User: &pb.User{}, Status: pb.Status_FAIL}, nil
+}
+
+func grpcServeAuthenticatorServer(ctx context.Context, address string, srv pb.UserServiceServer, up chan struct{}) {
+	listen, err := net.Listen("tcp", address)
+	tool.IfErrorThenPanic(err)
+	opts := []grpc.ServerOption{grpc.Creds(local.NewCredentials())}
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterUserServiceServer(grpcServer, srv)
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+	if up != nil {
+		close(up)
+	}
+	if err := grpcServer.Serve(listen); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -53,3 +53,40 @@ func (mr *MockFavoritesInsertsBatchMockRecorder) Do(ctx, favorites, upk any) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockFavoritesInsertsBatch)(nil).Do), ctx, favorites, upk)
 }
+
+// MockNotesInsertsBatch is a mock of NotesInsertsBatch interface.
+type MockNotesInsertsBatch struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotesInsertsBatchMockRecorder
+}
+
+// MockNotesInsertsBatchMockRecorder is the mock recorder for MockNotesInsertsBatch.
+type MockNotesInsertsBatchMockRecorder struct {
+	mock *MockNotesInsertsBatch
+}
+
+// NewMockNotesInsertsBatch creates a new mock instance.
+func NewMockNotesInsertsBatch(ctrl *gomock.Controller) *MockNotesInsertsBatch {
+	mock := &MockNotesInsertsBatch{ctrl: ctrl}
+	mock.recorder = &MockNotesInsertsBatchMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotesInsertsBatch) EXPECT() *MockNotesInsertsBatchMockRecorder {
+	return m.recorder
+}
+
+// Do mocks base method.
+func (m *MockNotesInsertsBatch) Do(ctx context.Context, notes []entity.Note, upk string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Do", ctx, notes, upk)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Do indicates an expected call of Do.
+func (mr *MockNotesInsertsBatchMockRecorder) Do(ctx, notes, upk any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockNotesInsertsBatch)(nil).Do), ctx, notes, upk)
+}
@@ -0,0 +1,227 @@
+/*
+ * This file was last modified at 2024-08-18 04:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * change_watcher.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/batch"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	watchDefaultRetryIntervalMs = 5000
+	// watchStream имя потока изменений избранного, ключ маркера возобновления.
+	watchStream = "favorites"
+)
+
+// ChangeWatcher применение изменений избранного других шардов из потока изменений
+// (change stream) MongoDB к PostgreSQL этого шарда, не дожидаясь обращения
+// пользователя через SyncUtilService. Применяются только более новые версии
+// записей пользователей, известных этому шарду.
+type ChangeWatcher interface {
+	// Apply применение одного изменения из потока.
+	Apply(ctx context.Context, change mongo.Change) error
+	// Run чтение потока изменений до отмены ctx, после перезапуска поток
+	// продолжается с сохранённого маркера возобновления.
+	Run(ctx context.Context)
+}
+
+type changeWatcher struct {
+	assetLookup     AssetSearchService
+	batch           batch.FavoritesInsertsBatch
+	enabled         bool
+	mongo           mongo.Mongo
+	repoFavorites   domain.Repo[*entity.Favorites]
+	repoResumeToken domain.Repo[*entity.ResumeToken]
+	retryInterval   time.Duration
+	shardID         string
+	sLog            *slog.Logger
+	stream          string
+	userRepo        domain.Repo[*entity.User]
+	watcher         mongo.Watcher
+}
+
+var _ ChangeWatcher = (*changeWatcher)(nil)
+var (
+	onceChangeWatcher = new(sync.Once)
+	changeWatcherServ *changeWatcher
+)
+
+// GetChangeWatcher — потокобезопасное (thread-safe) создание
+// сервиса применения потока изменений избранного MongoDB.
+func GetChangeWatcher(prop env.Properties) ChangeWatcher {
+
+	onceChangeWatcher.Do(func() {
+		changeWatcherServ = new(changeWatcher)
+		changeWatcherServ.assetLookup = GetAssetSearchService(prop)
+		changeWatcherServ.batch = batch.GetBatchPostgres(prop)
+		changeWatcherServ.mongo = mongo.GetStore(prop)
+		changeWatcherServ.repoFavorites = repo.GetFavoritesPostgresCachedRepo(prop)
+		changeWatcherServ.repoResumeToken = repo.GetResumeTokenPostgresRepo(prop)
+		changeWatcherServ.retryInterval = time.Duration(
+			intOrDefault(prop.Config().WatchRetryIntervalMs(), watchDefaultRetryIntervalMs),
+		) * time.Millisecond
+		changeWatcherServ.shardID = prop.Config().SyncShardID()
+		changeWatcherServ.sLog = prop.Logger()
+		changeWatcherServ.stream = watchStreamName(changeWatcherServ.shardID)
+		changeWatcherServ.userRepo = repo.GetUserPostgresCachedRepo(prop)
+		changeWatcherServ.watcher = mongo.GetWatcher(prop)
+		changeWatcherServ.enabled = prop.Config().WatchEnabled() &&
+			prop.DBPool() != nil &&
+			mongo.WatchEnabled(prop)
+	})
+	return changeWatcherServ
+}
+
+func (w *changeWatcher) Apply(ctx context.Context, change mongo.Change) error {
+
+	remote := change.Favorites
+	upk, isin := remote.User().Upk(), remote.Asset().Isin()
+
+	// собственные записи этого шарда возвращаются в поток из MongoDB.
+	if w.shardID != "" && remote.Provenance().ShardID() == w.shardID {
+		return nil
+	}
+	user, err := entity.GetUser(ctx, w.userRepo, upk)
+
+	if entity.IsUserNotFound(user, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	local, err := entity.GetFavorites(ctx, w.repoFavorites, isin, upk)
+	found := !entity.IsFavoritesNotFound(local, err)
+
+	if err != nil && found {
+		return err
+	}
+	if found && favoritesVersion(remote) <= favoritesVersion(local) {
+		return nil
+	}
+	switch {
+	case remote.Deleted().Bool && (!found || local.Deleted().Bool):
+		w.acknowledge(ctx, remote)
+		return nil
+	case remote.Deleted().Bool:
+		tombstone := local.WithProvenance(remote.Provenance())
+		if err = tombstone.Tombstone(ctx, w.repoFavorites); err != nil {
+			return err
+		}
+		w.acknowledge(ctx, remote)
+	case !w.assetLookup.Lookup(ctx, isin):
+		w.sLog.WarnContext(ctx, env.MSG+"ChangeWatcher.Apply", "msg", "asset is not found", "isin", isin)
+		return nil
+	default:
+		if err = w.batch.Do(ctx, []entity.Favorites{remote}, upk); err != nil {
+			return err
+		}
+		u := entity.MakeUserWithVersion(upk, max(user.Version(), remote.User().Version()), entity.DefaultTAttributes())
+		if err = u.Update(ctx, w.userRepo); err != nil {
+			return err
+		}
+	}
+	w.sLog.DebugContext(ctx, env.MSG+"ChangeWatcher.Apply",
+		"upk", upk,
+		"isin", isin,
+		"deleted", remote.Deleted().Bool,
+		"version", favoritesVersion(remote),
+		"remoteShard", remote.Provenance().ShardID(),
+		"remoteWrittenAt", remote.Provenance().WrittenAt().Time,
+	)
+	// прогрев кэша избранного пользователя новым состоянием.
+	if _, err = entity.GetFavoritesForUser(ctx, w.repoFavorites, upk); err != nil {
+		w.sLog.WarnContext(ctx, env.MSG+"ChangeWatcher.Apply", "msg", "favorites cache is not warmed", "err", err)
+	}
+	return nil
+}
+
+func (w *changeWatcher) Run(ctx context.Context) {
+
+	if !w.enabled {
+		return
+	}
+	for {
+		err := w.watch(ctx)
+
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, mongo.ErrResumeTokenLost) {
+			// пропущенные изменения согласуют SyncUtilService и Reconciler.
+			w.sLog.WarnContext(ctx, env.MSG+"ChangeWatcher.Run", "msg", "watch from now", "err", err)
+			token := entity.MakeResumeToken(w.stream, nil)
+			if er0 := token.Delete(ctx, w.repoResumeToken); er0 != nil {
+				w.sLog.ErrorContext(ctx, env.MSG+"ChangeWatcher.Run", "er0", er0)
+			}
+		} else {
+			w.sLog.ErrorContext(ctx, env.MSG+"ChangeWatcher.Run", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.retryInterval):
+		}
+	}
+}
+
+// acknowledge подтверждение применённого надгробия этим шардом.
+func (w *changeWatcher) acknowledge(ctx context.Context, tombstone entity.Favorites) {
+
+	if w.shardID == "" {
+		return
+	}
+	if err := w.mongo.Acknowledge(ctx, []entity.Favorites{tombstone}, w.shardID); err != nil {
+		w.sLog.ErrorContext(ctx, env.MSG+"ChangeWatcher.acknowledge", "err", err)
+	}
+}
+
+// watch чтение потока с сохранённого маркера, маркер сохраняется после
+// применения каждого изменения — после перезапуска изменение применяется не раньше.
+func (w *changeWatcher) watch(ctx context.Context) error {
+
+	token, err := entity.GetResumeToken(ctx, w.repoResumeToken, w.stream)
+
+	if err != nil && !tool.NoRowsInResultSet(err) {
+		return err
+	}
+	w.sLog.InfoContext(ctx, env.MSG+"ChangeWatcher.watch", "stream", w.stream, "resume", len(token.Token()) > 0)
+
+	return w.watcher.Watch(ctx, token.Token(), func(ctx context.Context, change mongo.Change) error {
+		if err := w.Apply(ctx, change); err != nil {
+			return err
+		}
+		next := entity.MakeResumeToken(w.stream, change.ResumeToken)
+		return next.Insert(ctx, w.repoResumeToken)
+	})
+}
+
+// watchStreamName имя потока изменений шарда shardID.
+func watchStreamName(shardID string) string {
+
+	if shardID == "" {
+		return watchStream
+	}
+	return watchStream + ":" + shardID
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,299 @@
+/*
+ * This file was last modified at 2024-08-18 04:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * change_watcher_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"go.uber.org/mock/gomock"
+)
+
+func TestChangeWatcher(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive ChangeWatcher Apply own shard is skipped",
+			fRun: testChangeWatcherApplyOwnShard,
+		},
+		{
+			name: "test #1 positive ChangeWatcher Apply unknown user is skipped",
+			fRun: testChangeWatcherApplyUnknownUser,
+		},
+		{
+			name: "test #2 positive ChangeWatcher Apply newer version",
+			fRun: testChangeWatcherApplyNewer,
+		},
+		{
+			name: "test #3 positive ChangeWatcher Apply older version is skipped",
+			fRun: testChangeWatcherApplyOlder,
+		},
+		{
+			name: "test #4 positive ChangeWatcher Apply tombstone",
+			fRun: testChangeWatcherApplyTombstone,
+		},
+		{
+			name: "test #5 positive ChangeWatcher Run saves and resets resume token",
+			fRun: testChangeWatcherRun,
+		},
+		{
+			name: "test #6 negative ChangeWatcher Apply batch error",
+			fRun: testChangeWatcherApplyBatchError,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testChangeWatcherApplyOwnShard(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	watcher := getTestChangeWatcher(ctrl)
+	remote := testConflictFavorites("isin1", 5, 5, "", false, sql.NullTime{}).
+		WithProvenance(entity.MakeProvenance("shard-1", time.Now()))
+	err := watcher.Apply(context.TODO(), mongo.Change{Favorites: remote})
+	assert.Nil(t, err)
+}
+
+func testChangeWatcherApplyUnknownUser(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	watcher := getTestChangeWatcher(ctrl)
+	expectTestChangeWatcherUser(watcher, &stubValuesScanner{err: pgx.ErrNoRows})
+	remote := testChangeWatcherRemote(5, false)
+	err := watcher.Apply(context.TODO(), mongo.Change{Favorites: remote})
+	assert.Nil(t, err)
+}
+
+func testChangeWatcherApplyNewer(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	watcher := getTestChangeWatcher(ctrl)
+	expectTestChangeWatcherUser(watcher, &stubValuesScanner{values: []any{"upk", int64(4)}})
+	expectTestChangeWatcherFavorites(watcher, &stubValuesScanner{values: []any{nil, sql.NullInt64{Int64: 4, Valid: true}}})
+	remote := testChangeWatcherRemote(5, false)
+	watcher.assetLookup.(*MockAssetSearchService).
+		EXPECT().
+		Lookup(gomock.Any(), "isin1").
+		Return(true).
+		Times(1)
+	watcher.batch.(*MockFavoritesInsertsBatch).
+		EXPECT().
+		Do(gomock.Any(), []entity.Favorites{remote}, "upk").
+		Return(nil).
+		Times(1)
+	watcher.userRepo.(*MockRepo[*entity.User]).
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, u *entity.User, _ func(domain.Scanner)) (*entity.User, error) {
+			assert.Equal(t, int64(5), u.Version())
+			return u, nil
+		}).
+		Times(1)
+	expectTestChangeWatcherWarm(watcher)
+	err := watcher.Apply(context.TODO(), mongo.Change{Favorites: remote})
+	assert.Nil(t, err)
+}
+
+func testChangeWatcherApplyOlder(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	watcher := getTestChangeWatcher(ctrl)
+	expectTestChangeWatcherUser(watcher, &stubValuesScanner{values: []any{"upk", int64(6)}})
+	expectTestChangeWatcherFavorites(watcher, &stubValuesScanner{values: []any{nil, sql.NullInt64{Int64: 6, Valid: true}}})
+	remote := testChangeWatcherRemote(5, false)
+	err := watcher.Apply(context.TODO(), mongo.Change{Favorites: remote})
+	assert.Nil(t, err)
+}
+
+func testChangeWatcherApplyTombstone(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	watcher := getTestChangeWatcher(ctrl)
+	expectTestChangeWatcherUser(watcher, &stubValuesScanner{values: []any{"upk", int64(4)}})
+	expectTestChangeWatcherFavorites(watcher, &stubValuesScanner{values: []any{nil, sql.NullInt64{Int64: 4, Valid: true}}})
+	remote := testChangeWatcherRemote(5, true)
+	watcher.repoFavorites.(*MockRepo[*entity.Favorites]).
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, f *entity.Favorites, _ func(domain.Scanner)) (*entity.Favorites, error) {
+			assert.Equal(t, "shard-2", f.Provenance().ShardID())
+			return f, nil
+		}).
+		Times(1)
+	watcher.mongo.(*MockMongo).
+		EXPECT().
+		Acknowledge(gomock.Any(), []entity.Favorites{remote}, "shard-1").
+		Return(nil).
+		Times(1)
+	expectTestChangeWatcherWarm(watcher)
+	err := watcher.Apply(context.TODO(), mongo.Change{Favorites: remote})
+	assert.Nil(t, err)
+}
+
+func testChangeWatcherApplyBatchError(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	watcher := getTestChangeWatcher(ctrl)
+	expectTestChangeWatcherUser(watcher, &stubValuesScanner{values: []any{"upk", int64(4)}})
+	expectTestChangeWatcherFavorites(watcher, &stubValuesScanner{err: pgx.ErrNoRows})
+	watcher.assetLookup.(*MockAssetSearchService).
+		EXPECT().
+		Lookup(gomock.Any(), "isin1").
+		Return(true).
+		Times(1)
+	watcher.batch.(*MockFavoritesInsertsBatch).
+		EXPECT().
+		Do(gomock.Any(), gomock.Any(), "upk").
+		Return(fmt.Errorf("test")).
+		Times(1)
+	err := watcher.Apply(context.TODO(), mongo.Change{Favorites: testChangeWatcherRemote(5, false)})
+	assert.NotNil(t, err)
+}
+
+func testChangeWatcherRun(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	watcher := getTestChangeWatcher(ctrl)
+	stub := &stubChangeWatcher{
+		changes: []mongo.Change{{
+			Favorites: testConflictFavorites("isin1", 5, 5, "", false, sql.NullTime{}).
+				WithProvenance(entity.MakeProvenance("shard-1", time.Now())),
+			ResumeToken: []byte{1},
+		}},
+		err: fmt.Errorf("%w: test", mongo.ErrResumeTokenLost),
+	}
+	watcher.watcher = stub
+	watcher.enabled = true
+	watcher.retryInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repoResumeToken := watcher.repoResumeToken.(*MockRepo[*entity.ResumeToken])
+	repoResumeToken.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.ResumeToken, scan func(domain.Scanner)) (*entity.ResumeToken, error) {
+			scan(&stubValuesScanner{values: []any{"favorites:shard-1", []byte{0}}})
+			return r, nil
+		}).
+		Times(1)
+	repoResumeToken.
+		EXPECT().
+		Insert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.ResumeToken, _ func(domain.Scanner)) (*entity.ResumeToken, error) {
+			assert.Equal(t, "favorites:shard-1", r.Name())
+			assert.Equal(t, []byte{1}, r.Token())
+			return r, nil
+		}).
+		Times(1)
+	repoResumeToken.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.ResumeToken, _ func(domain.Scanner)) (*entity.ResumeToken, error) {
+			cancel()
+			return r, nil
+		}).
+		Times(1)
+	watcher.Run(ctx)
+	assert.Equal(t, []byte{0}, stub.resumeToken)
+
+	watcher.enabled = false
+	watcher.Run(context.Background()) // выключенный поток сразу возвращает управление
+}
+
+type stubChangeWatcher struct {
+	changes     []mongo.Change
+	err         error
+	resumeToken []byte
+}
+
+func (s *stubChangeWatcher) Watch(ctx context.Context, resumeToken []byte, fn func(context.Context, mongo.Change) error) error {
+
+	s.resumeToken = resumeToken
+
+	for _, change := range s.changes {
+		if err := fn(ctx, change); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func expectTestChangeWatcherFavorites(watcher *changeWatcher, scanner domain.Scanner) {
+	watcher.repoFavorites.(*MockRepo[*entity.Favorites]).
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, f *entity.Favorites, scan func(domain.Scanner)) (*entity.Favorites, error) {
+			scan(scanner)
+			return f, nil
+		}).
+		Times(1)
+}
+
+func expectTestChangeWatcherUser(watcher *changeWatcher, scanner domain.Scanner) {
+	watcher.userRepo.(*MockRepo[*entity.User]).
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, u *entity.User, scan func(domain.Scanner)) (*entity.User, error) {
+			scan(scanner)
+			return u, nil
+		}).
+		Times(1)
+}
+
+func expectTestChangeWatcherWarm(watcher *changeWatcher) {
+	watcher.repoFavorites.(*MockRepo[*entity.Favorites]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+}
+
+func getTestChangeWatcher(ctrl *gomock.Controller) *changeWatcher {
+	result := new(changeWatcher)
+	result.assetLookup = NewMockAssetSearchService(ctrl)
+	result.batch = NewMockFavoritesInsertsBatch(ctrl)
+	result.mongo = NewMockMongo(ctrl)
+	result.repoFavorites = NewMockRepo[*entity.Favorites](ctrl)
+	result.repoResumeToken = NewMockRepo[*entity.ResumeToken](ctrl)
+	result.retryInterval = time.Millisecond
+	result.shardID = "shard-1"
+	result.sLog = slog.Default()
+	result.stream = watchStreamName(result.shardID)
+	result.userRepo = NewMockRepo[*entity.User](ctrl)
+	result.watcher = &stubChangeWatcher{}
+	return result
+}
+
+// testChangeWatcherRemote изменение избранного пользователя "upk" с шарда shard-2.
+func testChangeWatcherRemote(version int64, deleted bool) entity.Favorites {
+	return testConflictFavorites("isin1", version, version, "", deleted, sql.NullTime{}).
+		WithProvenance(entity.MakeProvenance("shard-2", time.Now()))
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
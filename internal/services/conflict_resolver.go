@@ -0,0 +1,147 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * conflict_resolver.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+)
+
+const (
+	// ConflictResolverLWW побеждает последняя запись (last-writer-wins), в том числе удаление.
+	ConflictResolverLWW = "lww"
+	// ConflictResolverPreferLocal побеждает запись PostgreSQL, если она есть.
+	ConflictResolverPreferLocal = "prefer_local"
+	// ConflictResolverUnion побеждает не удалённая запись (add-wins), между двумя
+	// не удалёнными — последняя.
+	ConflictResolverUnion = "union"
+)
+
+var ErrConflictResolver = fmt.Errorf("unknown conflict resolver")
+
+// ConflictSide сторона синхронизации, запись которой выбрана.
+type ConflictSide int
+
+const (
+	// ConflictLocal запись PostgreSQL.
+	ConflictLocal ConflictSide = iota
+	// ConflictRemote запись MongoDB.
+	ConflictRemote
+)
+
+func (s ConflictSide) String() string {
+	if s == ConflictRemote {
+		return "remote"
+	}
+	return "local"
+}
+
+// ConflictResolver стратегия выбора между записями избранного с одним ISIN
+// из PostgreSQL (local) и MongoDB (remote). Любая из записей может быть
+// надгробием (tombstone) — удалённой записью с версией удаления.
+type ConflictResolver interface {
+	Resolve(local, remote entity.Favorites) ConflictSide
+}
+
+type lastWriterWins struct{}
+
+type preferLocal struct{}
+
+type union struct{}
+
+var _ ConflictResolver = (*lastWriterWins)(nil)
+var _ ConflictResolver = (*preferLocal)(nil)
+var _ ConflictResolver = (*union)(nil)
+
+// MakeConflictResolver стратегия по имени из конфигурации, по умолчанию last-writer-wins.
+func MakeConflictResolver(name string) (ConflictResolver, error) {
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", ConflictResolverLWW:
+		return lastWriterWins{}, nil
+	case ConflictResolverPreferLocal:
+		return preferLocal{}, nil
+	case ConflictResolverUnion:
+		return union{}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrConflictResolver, name)
+}
+
+func (lastWriterWins) Resolve(local, remote entity.Favorites) ConflictSide {
+
+	if compareFavorites(local, remote) < 0 {
+		return ConflictRemote
+	}
+	return ConflictLocal
+}
+
+func (preferLocal) Resolve(_, _ entity.Favorites) ConflictSide {
+	return ConflictLocal
+}
+
+func (union) Resolve(local, remote entity.Favorites) ConflictSide {
+
+	switch {
+	case local.Deleted().Bool && !remote.Deleted().Bool:
+		return ConflictRemote
+	case !local.Deleted().Bool && remote.Deleted().Bool:
+		return ConflictLocal
+	}
+	return lastWriterWins{}.Resolve(local, remote)
+}
+
+// compareFavorites порядок записей по времени изменения: версия записи, затем
+// updated_at и время записи written_at если они известны для обеих, затем
+// удаление старше изменения, затем идентификатор шарда записи. Версии
+// пользователя на разных шардах независимы и могут совпасть, порядок не зависит
+// от того, какая из записей локальная, поэтому все шарды выбирают одну запись.
+// При полном равенстве записи считаются равными и выбирается PostgreSQL.
+func compareFavorites(x, y entity.Favorites) int {
+
+	if c := cmp.Compare(favoritesVersion(x), favoritesVersion(y)); c != 0 {
+		return c
+	}
+	if x.UpdatedAt().Valid && y.UpdatedAt().Valid {
+		if c := x.UpdatedAt().Time.Compare(y.UpdatedAt().Time); c != 0 {
+			return c
+		}
+	}
+	xWrittenAt, yWrittenAt := x.Provenance().WrittenAt(), y.Provenance().WrittenAt()
+
+	if xWrittenAt.Valid && yWrittenAt.Valid {
+		if c := xWrittenAt.Time.Compare(yWrittenAt.Time); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case x.Deleted().Bool && !y.Deleted().Bool:
+		return 1
+	case !x.Deleted().Bool && y.Deleted().Bool:
+		return -1
+	}
+	return cmp.Compare(x.Provenance().ShardID(), y.Provenance().ShardID())
+}
+
+// favoritesVersion версия записи, для ещё не синхронизированной записи
+// (версия NULL) — текущая версия пользователя.
+func favoritesVersion(f entity.Favorites) int64 {
+
+	if f.Version().Valid {
+		return f.Version().Int64
+	}
+	return f.User().Version()
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
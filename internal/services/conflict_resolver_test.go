@@ -0,0 +1,386 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * conflict_resolver_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+)
+
+func TestMakeConflictResolver(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		want  ConflictResolver
+		err   error
+	}{
+		{name: "positive test #0 default", input: "", want: lastWriterWins{}},
+		{name: "positive test #1 lww", input: "LWW", want: lastWriterWins{}},
+		{name: "positive test #2 prefer_local", input: "prefer_local", want: preferLocal{}},
+		{name: "positive test #3 union", input: " union ", want: union{}},
+		{name: "negative test #4 unknown", input: "first", err: ErrConflictResolver},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := MakeConflictResolver(test.input)
+			assert.True(t, errors.Is(err, test.err))
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestMergeFavorites(t *testing.T) {
+	type want struct {
+		result           []string
+		saveMongoDB      []string
+		deleteMongoDB    []string
+		savePostgreSQL   []string
+		deletePostgreSQL []string
+		acknowledge      []string
+	}
+	now := time.Now()
+	// записи двух шардов с одной версией: побеждает записанная позже,
+	// при равном времени — шард с большим идентификатором.
+	shard1 := entity.MakeProvenance("shard-1", now.Add(time.Second))
+	shard2 := entity.MakeProvenance("shard-2", now.Add(2*time.Second))
+	shard1Now := entity.MakeProvenance("shard-1", now)
+	shard2Now := entity.MakeProvenance("shard-2", now)
+	var tests = []struct {
+		name     string
+		resolver ConflictResolver
+		local    []entity.Favorites
+		remote   []entity.Favorites
+		want     want
+	}{
+		{
+			name:     "positive test #0 lww in sync",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 3, 3, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "x", false, sql.NullTime{})},
+			want:     want{result: []string{"A:x"}},
+		},
+		{
+			name:     "positive test #1 lww disjoint additions on both shards",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 1, 1, "a", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("B", 2, 2, "b", false, sql.NullTime{})},
+			want:     want{result: []string{"A:a", "B:b"}, saveMongoDB: []string{"A"}, savePostgreSQL: []string{"B"}},
+		},
+		{
+			name:     "positive test #2 lww concurrent edit remote newer",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 2, 2, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{result: []string{"A:y"}, savePostgreSQL: []string{"A"}},
+		},
+		{
+			name:     "positive test #3 lww concurrent edit local newer",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 4, 4, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{result: []string{"A:x"}, saveMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #4 lww local pending write",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 0, 4, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{result: []string{"A:x"}, saveMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #5 lww tie keeps local",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 3, 3, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{result: []string{"A:x"}, saveMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #6 lww tie broken by updated_at",
+			resolver: lastWriterWins{},
+			local: []entity.Favorites{
+				testConflictFavorites("A", 3, 3, "x", false, sql.NullTime{Time: now, Valid: true}),
+			},
+			remote: []entity.Favorites{
+				testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{Time: now.Add(time.Second), Valid: true}),
+			},
+			want: want{result: []string{"A:y"}, savePostgreSQL: []string{"A"}},
+		},
+		{
+			name:     "positive test #7 lww local delete after remote edit",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 5, 5, "", true, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{deleteMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #8 lww remote edit after local delete",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 2, 2, "", true, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{result: []string{"A:y"}, savePostgreSQL: []string{"A"}},
+		},
+		{
+			name:     "positive test #9 lww delete and edit with the same version",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 3, 3, "", true, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{deleteMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #10 lww pending local delete of missing remote",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 0, 4, "", true, sql.NullTime{})},
+			want:     want{deleteMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #11 union remote edit survives local delete",
+			resolver: union{},
+			local:    []entity.Favorites{testConflictFavorites("A", 5, 5, "", true, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{result: []string{"A:y"}, savePostgreSQL: []string{"A"}},
+		},
+		{
+			name:     "positive test #12 union concurrent edit remote newer",
+			resolver: union{},
+			local:    []entity.Favorites{testConflictFavorites("A", 2, 2, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{result: []string{"A:y"}, savePostgreSQL: []string{"A"}},
+		},
+		{
+			name:     "positive test #13 prefer_local older local edit",
+			resolver: preferLocal{},
+			local:    []entity.Favorites{testConflictFavorites("A", 1, 1, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{result: []string{"A:x"}, saveMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #14 prefer_local older local delete",
+			resolver: preferLocal{},
+			local:    []entity.Favorites{testConflictFavorites("A", 1, 1, "", true, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{})},
+			want:     want{deleteMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #15 lww remote delete after local edit",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 2, 2, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "", true, sql.NullTime{})},
+			want:     want{deletePostgreSQL: []string{"A"}},
+		},
+		{
+			name:     "positive test #16 lww local edit after remote delete",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 4, 4, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "", true, sql.NullTime{})},
+			want:     want{result: []string{"A:x"}, saveMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #17 lww remote delete never seen locally",
+			resolver: lastWriterWins{},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "", true, sql.NullTime{})},
+			want:     want{acknowledge: []string{"A"}},
+		},
+		{
+			name:     "positive test #18 lww deleted on both shards",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 2, 2, "", true, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "", true, sql.NullTime{})},
+			want:     want{acknowledge: []string{"A"}},
+		},
+		{
+			name:     "positive test #19 union local edit survives remote delete",
+			resolver: union{},
+			local:    []entity.Favorites{testConflictFavorites("A", 2, 2, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "", true, sql.NullTime{})},
+			want:     want{result: []string{"A:x"}, saveMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #20 prefer_local local edit survives remote delete",
+			resolver: preferLocal{},
+			local:    []entity.Favorites{testConflictFavorites("A", 2, 2, "x", false, sql.NullTime{})},
+			remote:   []entity.Favorites{testConflictFavorites("A", 5, 5, "", true, sql.NullTime{})},
+			want:     want{result: []string{"A:x"}, saveMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #21 lww divergent histories",
+			resolver: lastWriterWins{},
+			local: []entity.Favorites{
+				testConflictFavorites("A", 5, 6, "a2", false, sql.NullTime{}),
+				testConflictFavorites("C", 2, 6, "c", false, sql.NullTime{}),
+				testConflictFavorites("B", 6, 6, "", true, sql.NullTime{}),
+			},
+			remote: []entity.Favorites{
+				testConflictFavorites("A", 4, 5, "a1", false, sql.NullTime{}),
+				testConflictFavorites("B", 5, 5, "b", false, sql.NullTime{}),
+				testConflictFavorites("D", 5, 5, "d", false, sql.NullTime{}),
+				testConflictFavorites("C", 7, 7, "", true, sql.NullTime{}),
+				testConflictFavorites("E", 7, 7, "", true, sql.NullTime{}),
+			},
+			want: want{
+				result:           []string{"A:a2", "D:d"},
+				saveMongoDB:      []string{"A"},
+				deleteMongoDB:    []string{"B"},
+				savePostgreSQL:   []string{"D"},
+				deletePostgreSQL: []string{"C"},
+				acknowledge:      []string{"E"},
+			},
+		},
+		{
+			name:     "positive test #22 union divergent histories",
+			resolver: union{},
+			local: []entity.Favorites{
+				testConflictFavorites("A", 5, 6, "a2", false, sql.NullTime{}),
+				testConflictFavorites("C", 2, 6, "c", false, sql.NullTime{}),
+				testConflictFavorites("B", 6, 6, "", true, sql.NullTime{}),
+			},
+			remote: []entity.Favorites{
+				testConflictFavorites("A", 4, 5, "a1", false, sql.NullTime{}),
+				testConflictFavorites("B", 5, 5, "b", false, sql.NullTime{}),
+				testConflictFavorites("D", 5, 5, "d", false, sql.NullTime{}),
+			},
+			want: want{
+				result:         []string{"A:a2", "C:c", "B:b", "D:d"},
+				saveMongoDB:    []string{"A", "C"},
+				savePostgreSQL: []string{"B", "D"},
+			},
+		},
+		{
+			name:     "positive test #23 lww same version on two shards shard-1 view",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 3, 3, "x", false, sql.NullTime{}).WithProvenance(shard1)},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{}).WithProvenance(shard2)},
+			want:     want{result: []string{"A:y"}, savePostgreSQL: []string{"A"}},
+		},
+		{
+			name:     "positive test #24 lww same version on two shards shard-2 view",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{}).WithProvenance(shard2)},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "x", false, sql.NullTime{}).WithProvenance(shard1)},
+			want:     want{result: []string{"A:y"}, saveMongoDB: []string{"A"}},
+		},
+		{
+			name:     "positive test #25 lww same version and written_at broken by shard shard-1 view",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 3, 3, "x", false, sql.NullTime{}).WithProvenance(shard1Now)},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{}).WithProvenance(shard2Now)},
+			want:     want{result: []string{"A:y"}, savePostgreSQL: []string{"A"}},
+		},
+		{
+			name:     "positive test #26 lww same version and written_at broken by shard shard-2 view",
+			resolver: lastWriterWins{},
+			local:    []entity.Favorites{testConflictFavorites("A", 3, 3, "y", false, sql.NullTime{}).WithProvenance(shard2Now)},
+			remote:   []entity.Favorites{testConflictFavorites("A", 3, 3, "x", false, sql.NullTime{}).WithProvenance(shard1Now)},
+			want:     want{result: []string{"A:y"}, saveMongoDB: []string{"A"}},
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := mergeFavorites(test.resolver, test.local, test.remote)
+			assert.Equal(t, test.want.result, testConflictIsinMetadata(got.result))
+			assert.Equal(t, test.want.saveMongoDB, testConflictIsins(got.saveMongoDB))
+			assert.Equal(t, test.want.deleteMongoDB, testConflictIsins(got.deleteMongoDB))
+			assert.Equal(t, test.want.savePostgreSQL, testConflictIsins(got.savePostgreSQL))
+			assert.Equal(t, test.want.deletePostgreSQL, testConflictIsins(got.deletePostgreSQL))
+			assert.Equal(t, test.want.acknowledge, testConflictIsins(got.acknowledge))
+			assert.Equal(t, testConflictIsins(got.deletePostgreSQL), testConflictIsins(got.applied))
+			assert.Len(t, got.decisions, len(slices.Concat(
+				got.saveMongoDB, got.deleteMongoDB, got.savePostgreSQL, got.deletePostgreSQL, got.acknowledge,
+			)))
+			// запись выбранная стратегией должна заменить в MongoDB более новую
+			for _, written := range slices.Concat(got.saveMongoDB, got.deleteMongoDB) {
+				for _, r := range test.remote {
+					if r.Asset().Isin() == written.Asset().Isin() && r.Deleted() != written.Deleted() {
+						assert.GreaterOrEqual(t, favoritesVersion(written), favoritesVersion(r))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMergeFavoritesProvenance(t *testing.T) {
+	now := time.Now().UTC()
+	local := entity.MakeProvenance("shard-1", now.Add(-time.Minute))
+	remote := entity.MakeProvenance("shard-2", now)
+
+	got := mergeFavorites(
+		lastWriterWins{},
+		[]entity.Favorites{
+			testConflictFavorites("A", 5, 5, "a", false, sql.NullTime{}).WithProvenance(local),
+			testConflictFavorites("B", 5, 5, "b", false, sql.NullTime{}).WithProvenance(local),
+		},
+		[]entity.Favorites{
+			testConflictFavorites("A", 6, 6, "", true, sql.NullTime{}).WithProvenance(remote),
+			testConflictFavorites("B", 6, 6, "b2", false, sql.NullTime{}).WithProvenance(remote),
+		},
+	)
+	assert.Equal(t, []string{"A"}, testConflictIsins(got.deletePostgreSQL))
+	assert.Equal(t, remote, got.deletePostgreSQL[0].Provenance(), "надгробие с происхождением удаления")
+	assert.Equal(t, []string{"B"}, testConflictIsins(got.savePostgreSQL))
+	assert.Equal(t, remote, got.savePostgreSQL[0].Provenance())
+	assert.Equal(t, []syncDecision{
+		{isin: "A", action: syncDeletePostgreSQL, side: ConflictRemote, local: local, remote: remote},
+		{isin: "B", action: syncSavePostgreSQL, side: ConflictRemote, local: local, remote: remote},
+	}, got.decisions)
+	assert.Equal(t, "remote", ConflictRemote.String())
+	assert.Equal(t, "local", ConflictLocal.String())
+}
+
+// testConflictFavorites запись избранного с версией version (0 — NULL, запись ещё не синхронизирована).
+func testConflictFavorites(
+	isin string,
+	version, userVersion int64,
+	metadata string,
+	deleted bool,
+	updatedAt sql.NullTime,
+) entity.Favorites {
+	return entity.MakeFavorites(
+		uuid.New(),
+		entity.MakeAsset(isin, entity.MakeAssetType("STOCK", entity.DefaultTAttributes()), entity.DefaultTAttributes()),
+		entity.MakeUserWithVersion("upk", userVersion, entity.DefaultTAttributes()),
+		sql.NullInt64{Int64: version, Valid: version > 0},
+		entity.MakeTAttributes(sql.NullBool{Bool: deleted, Valid: deleted}, time.Time{}, updatedAt),
+	).WithMetadata(metadata)
+}
+
+func testConflictIsinMetadata(favorites []entity.Favorites) []string {
+
+	var result []string
+
+	for _, favorite := range favorites {
+		result = append(result, favorite.Asset().Isin()+":"+favorite.Metadata())
+	}
+	return result
+}
+
+func testConflictIsins(favorites []entity.Favorites) []string {
+
+	var result []string
+
+	for _, favorite := range favorites {
+		result = append(result, favorite.Asset().Isin())
+	}
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 21:05 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites_service.go
@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -42,18 +43,24 @@ type favoritesService struct {
 	assetLookup   AssetSearchService
 	dftFavorites  domain.Dft[*entity.Favorites]
 	mongo         mongo.Mongo
+	outbox        OutboxDispatcher
 	repoFavorites domain.Repo[*entity.Favorites]
+	shardID       string
 	sLog          *slog.Logger
 	syncService   SyncUtilService
+	upkMigrator   UpkMigrator
 	upkUtil       UpkUtilService
 	userLookup    UserSearchService
 }
 
 var _ FavoritesService = (*favoritesService)(nil)
 var (
-	ErrRequestNil = fmt.Errorf("request is nil")
-	onceFavorites = new(sync.Once)
-	favoritesServ *favoritesService
+	ErrFavoritesNotFound        = fmt.Errorf("favorites not found")
+	ErrFavoritesVersionConflict = fmt.Errorf("favorites version conflict")
+	ErrRequestNil               = fmt.Errorf("request is nil")
+	ErrUpstreamLookup           = fmt.Errorf("upstream lookup failed")
+	onceFavorites               = new(sync.Once)
+	favoritesServ               *favoritesService
 )
 
 // GetFavoritesService — потокобезопасное (thread-safe) создание
@@ -64,16 +71,25 @@ func GetFavoritesService(prop env.Properties) FavoritesService {
 		favoritesServ = new(favoritesService)
 		favoritesServ.assetLookup = GetAssetSearchService(prop)
 		favoritesServ.dftFavorites = repo.GetFavoritesTxPostgres(prop)
-		favoritesServ.mongo = mongo.GetMongoRepo(prop)
+		favoritesServ.mongo = mongo.GetStore(prop)
+		favoritesServ.outbox = GetOutboxDispatcher(prop)
 		favoritesServ.repoFavorites = repo.GetFavoritesPostgresCachedRepo(prop)
+		favoritesServ.shardID = prop.Config().SyncShardID()
 		favoritesServ.sLog = prop.Logger()
 		favoritesServ.syncService = GetSyncUtilService(prop)
+		favoritesServ.upkMigrator = GetUpkMigrator(prop)
 		favoritesServ.upkUtil = GetUpkUtilService(prop)
 		favoritesServ.userLookup = GetUserSearchService(prop)
 	})
 	return favoritesServ
 }
 
+// ApiFavoritesDelete удаление биржевого инструментов для пользователя (API для HTTP).
+func (f *favoritesService) ApiFavoritesDelete(ctx context.Context, favorites models.Favorites) (models.Favorites, error) {
+	defer tool.TraceInOut(ctx, "ApiFavoritesDelete", "%v", favorites)()
+	return f.delete(ctx, favorites)
+}
+
 // ApiFavoritesGet получения биржевого инструментов для пользователя (API для HTTP).
 func (f *favoritesService) ApiFavoritesGet(ctx context.Context, favorites models.Favorites) (models.Favorites, error) {
 	defer tool.TraceInOut(ctx, "ApiFavoritesGet", "%v", favorites)()
@@ -92,6 +108,35 @@ func (f *favoritesService) ApiFavoritesSet(ctx context.Context, favorites models
 	return f.set(ctx, favorites)
 }
 
+// Delete удаление биржевого инструментов для пользователя.
+func (f *favoritesService) Delete(ctx context.Context, request *pb.FavoritesRequest) (*pb.FavoritesResponse, error) {
+
+	var response pb.FavoritesResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	favorites := models.FavoritesFromProto(request.GetFavorites())
+	user, err := subjectUser(ctx, f.upkUtil, favorites.User())
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	favorites = favorites.WithUser(user)
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	model, err := f.delete(ctx, favorites)
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+	} else {
+		response.Favorites = model.ToProto()
+		response.Status = pb.Status_OK
+	}
+	return &response, err
+}
+
 // Get получения биржевого инструментов для пользователя.
 func (f *favoritesService) Get(ctx context.Context, request *pb.FavoritesRequest) (*pb.FavoritesResponse, error) {
 
@@ -102,6 +147,13 @@ func (f *favoritesService) Get(ctx context.Context, request *pb.FavoritesRequest
 		return &response, ErrRequestNil
 	}
 	favorites := models.FavoritesFromProto(request.GetFavorites())
+	user, err := subjectUser(ctx, f.upkUtil, favorites.User())
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	favorites = favorites.WithUser(user)
 	ctx = context.WithValue(ctx, "requestId", uuid.New())
 	model, err := f.get(ctx, favorites)
 
@@ -124,7 +176,12 @@ func (f *favoritesService) GetForUser(ctx context.Context, request *pb.UserFavor
 		response.Status = pb.Status_FAIL
 		return &response, ErrRequestNil
 	}
-	user := models.UserFromProto(request.GetUser())
+	user, err := subjectUser(ctx, f.upkUtil, models.UserFromProto(request.GetUser()))
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
 	ctx = context.WithValue(ctx, "requestId", uuid.New())
 	favorites, err := f.getForUser(ctx, user)
 
@@ -153,6 +210,13 @@ func (f *favoritesService) Set(ctx context.Context, request *pb.FavoritesRequest
 		return &response, ErrRequestNil
 	}
 	favorites := models.FavoritesFromProto(request.GetFavorites())
+	user, err := subjectUser(ctx, f.upkUtil, favorites.User())
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	favorites = favorites.WithUser(user)
 	ctx = context.WithValue(ctx, "requestId", uuid.New())
 	model, err := f.set(ctx, favorites)
 
@@ -165,9 +229,39 @@ func (f *favoritesService) Set(ctx context.Context, request *pb.FavoritesRequest
 	return &response, err
 }
 
+func (f *favoritesService) delete(ctx context.Context, model models.Favorites) (models.Favorites, error) {
+
+	var err error
+	var response models.Favorites
+
+	personalKey := model.User().PersonalKey()
+	upk := model.User().Upk()
+
+	if upk == "" {
+		if upk, err = f.encrypt(ctx, personalKey); err != nil {
+			return response, err
+		}
+	}
+	model = model.WithUpk(upk)
+	favorites := model.ToEntity().WithProvenance(f.provenance())
+	// удаление из MongoDB доставляется диспетчером outbox, событие пишется в той же транзакции
+	err = favorites.Delete(ctx, f.dftFavorites, func() {})
+
+	if err != nil {
+		f.sLog.ErrorContext(ctx, env.MSG+"FavoritesService.delete", "msg", "favorites service delete", "err", err)
+		if tool.NoRowsInResultSet(err) {
+			err = fmt.Errorf("%w: %w", ErrFavoritesNotFound, err)
+		}
+		return response, err
+	}
+	f.outbox.Notify()
+
+	return models.FavoritesFromEntity(favorites), nil
+}
+
 func (f *favoritesService) encrypt(ctx context.Context, personalKey string) (string, error) {
 
-	upk, err := f.upkUtil.EncryptPersonalKey(personalKey)
+	upk, err := f.upkMigrator.Migrate(ctx, personalKey)
 
 	if err != nil {
 		f.sLog.ErrorContext(ctx, env.MSG+"FavoritesService.encrypt", "msg", "favorites service encrypt", "err", err)
@@ -194,6 +288,9 @@ func (f *favoritesService) get(ctx context.Context, model models.Favorites) (mod
 
 	if err != nil {
 		f.sLog.ErrorContext(ctx, env.MSG+"FavoritesService.get", "msg", "favorites service get", "err", err)
+		if tool.NoRowsInResultSet(err) {
+			err = fmt.Errorf("%w: %w", ErrFavoritesNotFound, err)
+		}
 	} else {
 		response = models.FavoritesFromEntity(favorites)
 	}
@@ -261,6 +358,11 @@ func (f *favoritesService) getForUser(ctx context.Context, model models.User) ([
 	return response, err
 }
 
+// provenance происхождение записи этим шардом сейчас.
+func (f *favoritesService) provenance() entity.Provenance {
+	return entity.MakeProvenance(f.shardID, time.Now().UTC())
+}
+
 func (f *favoritesService) set(ctx context.Context, model models.Favorites) (models.Favorites, error) {
 
 	var err error
@@ -281,43 +383,33 @@ func (f *favoritesService) set(ctx context.Context, model models.Favorites) (mod
 		if f.userLookup.Lookup(c, user) {
 			return nil
 		}
-		return fmt.Errorf("user by upk: %s not found", model.User().Upk())
+		return fmt.Errorf("%w: user by upk: %s not found", ErrUpstreamLookup, model.User().Upk())
 	})
 	g.Go(func() error {
 		if f.assetLookup.Lookup(c, model.Asset().Isin()) {
 			return nil
 		}
-		return fmt.Errorf("asset by isin: %s not found", model.Asset().Isin())
+		return fmt.Errorf("%w: asset by isin: %s not found", ErrUpstreamLookup, model.Asset().Isin())
 	})
 	if err = g.Wait(); err != nil {
 		f.sLog.ErrorContext(ctx, env.MSG+"FavoritesService.set", "msg", "favorites service set", "err", err)
 	} else {
 		model = model.WithUpk(upk)
-		favorites := model.ToEntity()
-		var er0 error
-		err = favorites.Upsert(ctx, f.dftFavorites, func() {
-			er0 = f.mongo.Save(ctx, favorites)
-			if er0 != nil {
-				f.sLog.ErrorContext(ctx,
-					env.MSG+"FavoritesService.set",
-					"msg", "favorites service set in transaction",
-					"er0", er0,
-				)
-				return
-			}
-		})
+		favorites := model.ToEntity().WithProvenance(f.provenance())
+		// сохранение в MongoDB доставляется диспетчером outbox, событие пишется в той же транзакции,
+		// ожидаемая клиентом версия (If-Match) проверяется в ней же.
+		err = favorites.Upsert(ctx, f.dftFavorites, func() {})
+
 		if err != nil {
 			f.sLog.ErrorContext(ctx, env.MSG+"FavoritesService.set", "msg", "favorites service set", "err", err)
-		} else {
-			if er0 == nil {
-				if er0 := favorites.Update(ctx, f.repoFavorites); er0 != nil {
-					f.sLog.ErrorContext(ctx,
-						env.MSG+"FavoritesService.set",
-						"msg", "favorites service ack in transaction",
-						"er0", er0,
-					)
-				}
+			if model.Version() > 0 && tool.NoRowsInResultSet(err) {
+				err = fmt.Errorf(
+					"%w: isin: %s expected version: %d: %w",
+					ErrFavoritesVersionConflict, model.Asset().Isin(), model.Version(), err,
+				)
 			}
+		} else {
+			f.outbox.Notify()
 			response = models.FavoritesFromEntity(favorites)
 		}
 	}
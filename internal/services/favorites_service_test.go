@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-19 17:50 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * favorites_service_test.go
@@ -12,11 +12,13 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"log/slog"
 	"math/rand"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,16 +26,20 @@ import (
 	"github.com/vskurikhin/gofavorites/internal/env"
 	"github.com/vskurikhin/gofavorites/internal/models"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
 	"github.com/vskurikhin/gofavorites/internal/domain"
 	"github.com/vskurikhin/gofavorites/internal/domain/entity"
 	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
 	"github.com/vskurikhin/gofavorites/internal/tool"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/vskurikhin/gofavorites/proto"
 )
@@ -131,6 +137,54 @@ func TestFavoritesService(t *testing.T) {
 			name: "test #21 negative #6 Favorites Service Set",
 			fRun: testFavoritesServiceSetNegative6,
 		},
+		{
+			name: "test #22 positive Favorites Service ApiFavoritesDelete",
+			fRun: testFavoritesServiceApiFavoritesDeletePositive,
+		},
+		{
+			name: "test #23 positive Favorites Service Delete",
+			fRun: testFavoritesServiceDeletePositive,
+		},
+		{
+			name: "test #24 negative #0 Favorites Service Delete",
+			fRun: testFavoritesServiceDeleteNegative0,
+		},
+		{
+			name: "test #25 negative #1 Favorites Service Delete",
+			fRun: testFavoritesServiceDeleteNegative1,
+		},
+		{
+			name: "test #26 negative #2 Favorites Service Delete",
+			fRun: testFavoritesServiceDeleteNegative2,
+		},
+		{
+			name: "test #27 negative Favorites Service permission denied",
+			fRun: testFavoritesServicePermissionDenied,
+		},
+		{
+			name: "test #28 negative Favorites Service ApiFavoritesGet not found",
+			fRun: testFavoritesServiceApiFavoritesGetNotFound,
+		},
+		{
+			name: "test #29 negative Favorites Service ApiFavoritesSet upstream lookup",
+			fRun: testFavoritesServiceApiFavoritesSetUpstreamLookup,
+		},
+		{
+			name: "test #30 negative Favorites Service ApiFavoritesSet version conflict",
+			fRun: testFavoritesServiceApiFavoritesSetVersionConflict,
+		},
+		{
+			name: "test #31 positive Favorites Service Set and Get metadata",
+			fRun: testFavoritesServiceSetGetMetadata,
+		},
+		{
+			name: "test #32 positive Favorites Service ApiFavoritesDelete tombstone version",
+			fRun: testFavoritesServiceApiFavoritesDeleteTombstone,
+		},
+		{
+			name: "test #33 positive Favorites Service Set after Delete restores favorites",
+			fRun: testFavoritesServiceSetAfterDelete,
+		},
 	}
 
 	assert.NotNil(t, t)
@@ -192,7 +246,7 @@ func testFavoritesServiceGetPositive(t *testing.T) {
 		Return("", nil).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Get(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Get(getTestSubjectContext(), &pb.FavoritesRequest{})
 	assert.Nil(t, err)
 	assert.Equal(t, pb.Status_OK, resp.GetStatus())
 	assert.Equal(t, "", resp.GetFavorites().GetAsset().GetIsin())
@@ -245,7 +299,7 @@ func testFavoritesServiceGetNegative1(t *testing.T) {
 		Return("", nil).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Get(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Get(getTestSubjectContext(), &pb.FavoritesRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
 }
@@ -270,7 +324,7 @@ func testFavoritesServiceGetNegative2(t *testing.T) {
 		Return("", tool.ErrEncryptAES).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Get(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Get(getTestSubjectContext(), &pb.FavoritesRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
 }
@@ -340,7 +394,7 @@ func testFavoritesServiceGetForUserPositive1(t *testing.T) {
 		Return("", nil).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.GetForUser(context.TODO(), &pb.UserFavoritesRequest{})
+	resp, err := favoritesService.GetForUser(getTestSubjectContext(), &pb.UserFavoritesRequest{})
 	assert.Nil(t, err)
 	assert.Equal(t, pb.Status_OK, resp.GetStatus())
 	assert.Equal(t, 0, len(resp.GetFavorites()))
@@ -377,7 +431,7 @@ func testFavoritesServiceGetForUserPositive2(t *testing.T) {
 		Return("", nil).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.GetForUser(context.TODO(), &pb.UserFavoritesRequest{})
+	resp, err := favoritesService.GetForUser(getTestSubjectContext(), &pb.UserFavoritesRequest{})
 	assert.Nil(t, err)
 	assert.Equal(t, pb.Status_OK, resp.GetStatus())
 	assert.Equal(t, 1, len(resp.GetFavorites()))
@@ -439,7 +493,7 @@ func testFavoritesServiceGetForUserNegative1(t *testing.T) {
 		Return("", nil).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.GetForUser(context.TODO(), &pb.UserFavoritesRequest{})
+	resp, err := favoritesService.GetForUser(getTestSubjectContext(), &pb.UserFavoritesRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
 	assert.Equal(t, 0, len(resp.GetFavorites()))
@@ -465,7 +519,7 @@ func testFavoritesServiceGetForUserNegative2(t *testing.T) {
 		Return("", tool.ErrEncryptAES).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.GetForUser(context.TODO(), &pb.UserFavoritesRequest{})
+	resp, err := favoritesService.GetForUser(getTestSubjectContext(), &pb.UserFavoritesRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
 	assert.Equal(t, 0, len(resp.GetFavorites()))
@@ -508,6 +562,8 @@ func testFavoritesServiceApiFavoritesSetPositive(t *testing.T) {
 	resp, err := favoritesService.ApiFavoritesSet(context.TODO(), models.Favorites{})
 	assert.Nil(t, err)
 	assert.NotNil(t, resp)
+	assert.Equal(t, "shard-1", resp.ShardID())
+	assert.False(t, resp.WrittenAt().IsZero())
 }
 
 func testFavoritesServiceSetPositive(t *testing.T) {
@@ -544,7 +600,7 @@ func testFavoritesServiceSetPositive(t *testing.T) {
 		Return(true).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Set(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{})
 	assert.Nil(t, err)
 	assert.Equal(t, pb.Status_OK, resp.GetStatus())
 	assert.Equal(t, "", resp.GetFavorites().GetAsset().GetIsin())
@@ -616,7 +672,7 @@ func testFavoritesServiceSetNegative1(t *testing.T) {
 		Return(true).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Set(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
 }
@@ -651,7 +707,7 @@ func testFavoritesServiceSetNegative2(t *testing.T) {
 		Return(false).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Set(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
 }
@@ -686,7 +742,7 @@ func testFavoritesServiceSetNegative3(t *testing.T) {
 		Return(true).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Set(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
 }
@@ -721,7 +777,7 @@ func testFavoritesServiceSetNegative4(t *testing.T) {
 		Return(true).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Set(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
 }
@@ -756,7 +812,7 @@ func testFavoritesServiceSetNegative5(t *testing.T) {
 		Return(true).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Set(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
 }
@@ -796,11 +852,471 @@ func testFavoritesServiceSetNegative6(t *testing.T) {
 		Return(true).
 		AnyTimes()
 	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
-	resp, err := favoritesService.Set(context.TODO(), &pb.FavoritesRequest{})
+	resp, err := favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+}
+
+func testFavoritesServiceApiFavoritesDeletePositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	dftFavorites := NewMockDft[*entity.Favorites](ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	syncUtil := NewMockSyncUtilService(ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	dftFavorites.
+		EXPECT().
+		DoDelete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	repoFavorites.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.Favorites{}, nil).
+		AnyTimes()
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("", nil).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
+	resp, err := favoritesService.ApiFavoritesDelete(context.TODO(), models.Favorites{})
+	assert.Nil(t, err)
+	assert.NotNil(t, resp)
+	assert.Equal(t, "shard-1", resp.ShardID())
+	assert.Equal(t, resp.WrittenAt().UnixMilli(), resp.ToProto().GetWrittenAt())
+	assert.Equal(t, "shard-1", resp.ToDto().ShardID)
+}
+
+func testFavoritesServiceApiFavoritesDeleteTombstone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	dftFavorites := NewMockDft[*entity.Favorites](ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	syncUtil := NewMockSyncUtilService(ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	dftFavorites.
+		EXPECT().
+		DoDelete(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *entity.Favorites, scan func(domain.Scanner)) error {
+			scan(&stubValuesScanner{values: []any{
+				nil, "isin1", "upk1",
+				sql.NullInt64{Int64: 7, Valid: true}, sql.NullBool{Bool: true, Valid: true},
+				nil, nil, int64(7),
+			}})
+			return nil
+		}).
+		Times(1)
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("upk1", nil).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
+	resp, err := favoritesService.ApiFavoritesDelete(context.TODO(), models.Favorites{})
+	assert.Nil(t, err)
+	assert.Equal(t, "isin1", resp.Asset().Isin())
+	assert.True(t, resp.Deleted())
+	assert.Equal(t, int64(7), resp.Version())
+}
+
+func testFavoritesServiceDeletePositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	dftFavorites := NewMockDft[*entity.Favorites](ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	syncUtil := NewMockSyncUtilService(ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	dftFavorites.
+		EXPECT().
+		DoDelete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	repoFavorites.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.Favorites{}, nil).
+		AnyTimes()
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("", nil).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
+	resp, err := favoritesService.Delete(getTestSubjectContext(), &pb.FavoritesRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+}
+
+func testFavoritesServiceDeleteNegative0(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	dftFavorites := NewMockDft[*entity.Favorites](ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	syncUtil := NewMockSyncUtilService(ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
+	resp, err := favoritesService.Delete(context.TODO(), nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
+}
+
+func testFavoritesServiceDeleteNegative1(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	dftFavorites := NewMockDft[*entity.Favorites](ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	syncUtil := NewMockSyncUtilService(ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("", tool.ErrEncryptAES).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
+	resp, err := favoritesService.Delete(getTestSubjectContext(), &pb.FavoritesRequest{})
+	assert.NotNil(t, err)
+	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
+}
+
+func testFavoritesServiceDeleteNegative2(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	dftFavorites := NewMockDft[*entity.Favorites](ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	syncUtil := NewMockSyncUtilService(ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	dftFavorites.
+		EXPECT().
+		DoDelete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(repo.ErrNotFound).
+		AnyTimes()
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("", nil).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
+	resp, err := favoritesService.Delete(getTestSubjectContext(), &pb.FavoritesRequest{})
+	assert.NotNil(t, err)
+	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
+}
+
+func testFavoritesServiceApiFavoritesGetNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	repoFavorites.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, pgx.ErrNoRows).
+		AnyTimes()
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("", nil).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(nil, nil, nil, repoFavorites, nil, upkUtil, nil)
+	_, err := favoritesService.ApiFavoritesGet(context.TODO(), models.Favorites{})
+	assert.ErrorIs(t, err, ErrFavoritesNotFound)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func testFavoritesServiceApiFavoritesSetUpstreamLookup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	assetLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(false).
+		AnyTimes()
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("", nil).
+		AnyTimes()
+	userLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(true).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, nil, nil, nil, nil, upkUtil, userLookup)
+	_, err := favoritesService.ApiFavoritesSet(context.TODO(), models.Favorites{})
+	assert.ErrorIs(t, err, ErrUpstreamLookup)
+}
+
+func testFavoritesServiceApiFavoritesSetVersionConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	dftFavorites := NewMockDft[*entity.Favorites](ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	assetLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).Return(true).
+		AnyTimes()
+	// строка с ожидаемой версией не найдена в транзакции сохранения
+	dftFavorites.
+		EXPECT().
+		DoUpsert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, e *entity.Favorites, _ func(domain.Scanner)) error {
+			assert.Equal(t, sql.NullInt64{Int64: 1, Valid: true}, e.Version())
+			return pgx.ErrNoRows
+		}).
+		Times(1)
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("", nil).
+		AnyTimes()
+	userLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(true).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, nil, nil, nil, upkUtil, userLookup)
+	_, err := favoritesService.ApiFavoritesSet(context.TODO(), models.Favorites{}.WithVersion(1))
+	assert.ErrorIs(t, err, ErrFavoritesVersionConflict)
+}
+
+func testFavoritesServiceSetGetMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	dftFavorites := NewMockDft[*entity.Favorites](ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	var stored *entity.Favorites
+	assetLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).Return(true).
+		AnyTimes()
+	dftFavorites.
+		EXPECT().
+		DoUpsert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, e *entity.Favorites, _ func(domain.Scanner)) error {
+			stored = e
+			return nil
+		}).
+		Times(1)
+	repoFavorites.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, e *entity.Favorites, scan func(domain.Scanner)) (*entity.Favorites, error) {
+			scan(&stubValuesScanner{values: []any{stored.ID(), stored.Version(), stored.Metadata()}})
+			return e, nil
+		}).
+		Times(1)
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("", nil).
+		AnyTimes()
+	userLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(true).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, nil, repoFavorites, nil, upkUtil, userLookup)
+	favorites := &pb.Favorites{Asset: &pb.Asset{Isin: "RU0000000000"}, Metadata: "note"}
+	resp, err := favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{Favorites: favorites})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+	assert.Equal(t, "note", resp.GetFavorites().GetMetadata())
+	resp, err = favoritesService.Get(getTestSubjectContext(), &pb.FavoritesRequest{Favorites: favorites})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+	assert.Equal(t, "note", resp.GetFavorites().GetMetadata())
+
+	model := models.FavoritesFromDto(dto.Favorites{Isin: "RU0000000000", Metadata: "note"}, "test", "")
+	assert.Equal(t, "note", model.Metadata())
+}
+
+func testFavoritesServiceSetAfterDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	syncUtil := NewMockSyncUtilService(ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	table := newTestFavoritesTable(ctrl)
+	assetLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(true).
+		AnyTimes()
+	mockMongo.
+		EXPECT().
+		Load(gomock.Any(), gomock.Any()).
+		Return(make([]entity.Favorites, 0), nil).
+		AnyTimes()
+	syncUtil.
+		EXPECT().
+		Sync(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, pgDBFavorites []entity.Favorites) ([]entity.Favorites, error) {
+			return pgDBFavorites, nil
+		}).
+		AnyTimes()
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Any()).
+		Return("upk", nil).
+		AnyTimes()
+	userLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(true).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, table.dft, mockMongo, table.repo, syncUtil, upkUtil, userLookup)
+	favorites := &pb.Favorites{Asset: &pb.Asset{Isin: "RU0000000000"}}
+	forUser := func() []string {
+		resp, err := favoritesService.GetForUser(getTestSubjectContext(), &pb.UserFavoritesRequest{})
+		assert.Nil(t, err)
+		isins := make([]string, 0)
+		for _, fav := range resp.GetFavorites() {
+			isins = append(isins, fav.GetAsset().GetIsin())
+		}
+		return isins
+	}
+	resp, err := favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{Favorites: favorites})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+	assert.Equal(t, []string{"RU0000000000"}, forUser())
+
+	resp, err = favoritesService.Delete(getTestSubjectContext(), &pb.FavoritesRequest{Favorites: favorites})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+	assert.Empty(t, forUser())
+
+	resp, err = favoritesService.Set(getTestSubjectContext(), &pb.FavoritesRequest{Favorites: favorites})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+	assert.False(t, resp.GetFavorites().GetDeleted())
+	assert.Equal(t, []string{"RU0000000000"}, forUser())
+
+	resp, err = favoritesService.Delete(getTestSubjectContext(), &pb.FavoritesRequest{Favorites: favorites})
 	assert.Nil(t, err)
 	assert.Equal(t, pb.Status_OK, resp.GetStatus())
 }
 
+// testFavoritesTable таблица favorites одного пользователя в памяти: повторяет
+// ON CONFLICT выражений FavoritesUpsertTxFavoritesSQL и FavoritesDeleteTxSQL,
+// надгробие снимается только если DO UPDATE сбрасывает deleted.
+type testFavoritesTable struct {
+	dft     *MockDft[*entity.Favorites]
+	repo    *MockRepo[*entity.Favorites]
+	rows    map[string]*testFavoritesRow
+	version int64
+}
+
+type testFavoritesRow struct {
+	id       uuid.UUID
+	deleted  bool
+	metadata string
+	version  int64
+}
+
+func newTestFavoritesTable(ctrl *gomock.Controller) *testFavoritesTable {
+
+	table := &testFavoritesTable{
+		dft:  NewMockDft[*entity.Favorites](ctrl),
+		repo: NewMockRepo[*entity.Favorites](ctrl),
+		rows: make(map[string]*testFavoritesRow),
+	}
+	table.dft.
+		EXPECT().
+		DoUpsert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(table.upsert).
+		AnyTimes()
+	table.dft.
+		EXPECT().
+		DoDelete(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(table.delete).
+		AnyTimes()
+	table.repo.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(table.getForUser).
+		AnyTimes()
+	return table
+}
+
+func (t *testFavoritesTable) upsert(_ context.Context, f *entity.Favorites, scan func(domain.Scanner)) error {
+
+	args := f.UpsertTxArgs()
+	stmt := args.SQLs[len(args.SQLs)-1]
+	t.version++
+	row, ok := t.rows[f.Asset().Isin()]
+
+	if !ok {
+		row = &testFavoritesRow{id: uuid.New()}
+		t.rows[f.Asset().Isin()] = row
+	} else if _, doUpdate, _ := strings.Cut(stmt, "DO UPDATE SET"); strings.Contains(doUpdate, "deleted = NULL") {
+		row.deleted = false
+	}
+	row.metadata = f.Metadata()
+	row.version = t.version
+	scan(&stubValuesScanner{values: []any{
+		row.id, f.Asset().Isin(), f.User().Upk(),
+		sql.NullInt64{Int64: row.version, Valid: true}, sql.NullBool{Bool: row.deleted, Valid: row.deleted},
+	}})
+	return nil
+}
+
+func (t *testFavoritesTable) delete(_ context.Context, f *entity.Favorites, scan func(domain.Scanner)) error {
+
+	row, ok := t.rows[f.Asset().Isin()]
+
+	if !ok || row.deleted {
+		return pgx.ErrNoRows
+	}
+	t.version++
+	row.deleted = true
+	row.version = t.version
+	scan(&stubValuesScanner{values: []any{
+		row.id, f.Asset().Isin(), f.User().Upk(),
+		sql.NullInt64{Int64: row.version, Valid: true}, sql.NullBool{Bool: true, Valid: true},
+		nil, nil, t.version,
+	}})
+	return nil
+}
+
+func (t *testFavoritesTable) getForUser(
+	_ context.Context, f *entity.Favorites, scan func(domain.Scanner) *entity.Favorites,
+) ([]*entity.Favorites, error) {
+
+	result := make([]*entity.Favorites, 0)
+
+	for isin, row := range t.rows {
+		if row.deleted {
+			continue
+		}
+		values := make([]any, 16)
+		values[0] = row.id
+		values[1] = sql.NullInt64{Int64: row.version, Valid: true}
+		values[2] = row.metadata
+		values[6] = isin
+		values[14] = f.User().Upk()
+		values[15] = t.version
+		result = append(result, scan(&stubValuesScanner{values: values}))
+	}
+	return result, nil
+}
+
 func getTestFavoritesService(
 	assetLookup AssetSearchService,
 	dftFavorites domain.Dft[*entity.Favorites],
@@ -814,7 +1330,10 @@ func getTestFavoritesService(
 	favoritesServ.assetLookup = assetLookup
 	favoritesServ.dftFavorites = dftFavorites
 	favoritesServ.mongo = mongo
+	favoritesServ.outbox = new(stubOutboxDispatcher)
 	favoritesServ.repoFavorites = repoFavorites
+	favoritesServ.shardID = "shard-1"
+	favoritesServ.upkMigrator = getTestUpkMigrator(upkUtil)
 	favoritesServ.upkUtil = upkUtil
 	favoritesServ.userLookup = userLookup
 	favoritesServ.sLog = slog.Default()
@@ -851,6 +1370,14 @@ func TestGRPCFavoritesService(t *testing.T) {
 			name: "negative test #6 Favorites Service Get",
 			fRun: testGRPCFavoritesServiceSetNegative,
 		},
+		{
+			name: "positive test #7 Favorites Service Delete",
+			fRun: testGRPCFavoritesServiceDeletePositive,
+		},
+		{
+			name: "negative test #8 Favorites Service Delete",
+			fRun: testGRPCFavoritesServiceDeleteNegative,
+		},
 	}
 
 	assert.NotNil(t, t)
@@ -865,6 +1392,7 @@ func testGRPCFavoritesServiceGetPositive(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65285+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -891,6 +1419,7 @@ func testGRPCFavoritesServiceGetForUserPositive(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65321+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -916,6 +1445,7 @@ func testGRPCFavoritesServiceSetPositive(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65357+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -941,6 +1471,7 @@ func testGRPCFavoritesServiceGetNegative(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65393+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -966,6 +1497,7 @@ func testGRPCFavoritesServiceGetForUserNegative(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65429+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -991,6 +1523,7 @@ func testGRPCFavoritesServiceSetNegative(t *testing.T) {
 
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	address := fmt.Sprintf("127.0.0.1:%d", 65465+rnd.Intn(34))
 
 	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
@@ -1011,6 +1544,57 @@ func testGRPCFavoritesServiceSetNegative(t *testing.T) {
 	assert.Nil(t, resp)
 }
 
+func testGRPCFavoritesServiceDeletePositive(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+	address := fmt.Sprintf("127.0.0.1:%d", 65249+rnd.Intn(34))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer func() {
+		cancel()
+		ctx.Done()
+		time.Sleep(100 * time.Millisecond)
+	}()
+	up := make(chan struct{})
+	go grpcServeFavoritesServiceServer(ctx, address, favoritesServicePositive{}, up)
+	<-up
+
+	conn, client, err := makeFavoritesServiceClient(t, address)
+	defer func() { _ = conn.Close() }()
+
+	var request pb.FavoritesRequest
+	resp, err := client.Delete(ctx, &request)
+	assert.Nil(t, err)
+	assert.NotNil(t, resp)
+}
+
+func testGRPCFavoritesServiceDeleteNegative(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+	address := fmt.Sprintf("127.0.0.1:%d", 65213+rnd.Intn(34))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer func() {
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+	}()
+	up := make(chan struct{})
+	go grpcServeFavoritesServiceServer(ctx, address, favoritesServiceNegative{}, up)
+	<-up
+
+	conn, client, err := makeFavoritesServiceClient(t, address)
+	defer func() { _ = conn.Close() }()
+
+	var request pb.FavoritesRequest
+	resp, err := client.Delete(ctx, &request)
+	assert.NotNil(t, err)
+	assert.Nil(t, resp)
+}
+
 func makeFavoritesServiceClient(t *testing.T, address string) (*grpc.ClientConn, pb.FavoritesServiceClient, error) {
 
 	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
@@ -1052,6 +1636,10 @@ type favoritesServicePositive struct {
 
 var _ FavoritesServicePositive = (*favoritesServicePositive)(nil)
 
+func (f favoritesServicePositive) Delete(_ context.Context, _ *pb.FavoritesRequest) (*pb.FavoritesResponse, error) {
+	return &pb.FavoritesResponse{}, nil
+}
+
 func (f favoritesServicePositive) Get(_ context.Context, _ *pb.FavoritesRequest) (*pb.FavoritesResponse, error) {
 	return &pb.FavoritesResponse{}, nil
 }
@@ -1074,6 +1662,10 @@ type favoritesServiceNegative struct {
 
 var _ FavoritesServiceNegative = (*favoritesServiceNegative)(nil)
 
+func (f favoritesServiceNegative) Delete(_ context.Context, request *pb.FavoritesRequest) (*pb.FavoritesResponse, error) {
+	return nil, fmt.Errorf("test")
+}
+
 func (f favoritesServiceNegative) Get(_ context.Context, request *pb.FavoritesRequest) (*pb.FavoritesResponse, error) {
 	return nil, fmt.Errorf("test")
 }
@@ -1086,5 +1678,40 @@ func (f favoritesServiceNegative) Set(_ context.Context, request *pb.FavoritesRe
 	return nil, fmt.Errorf("test")
 }
 
+func testFavoritesServicePermissionDenied(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	dftFavorites := NewMockDft[*entity.Favorites](ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	syncUtil := NewMockSyncUtilService(ctrl)
+	upkUtil := NewMockUpkUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	upkUtil.
+		EXPECT().
+		EncryptPersonalKey(gomock.Eq("test")).
+		Return("upk-test", nil).
+		AnyTimes()
+	favoritesService := getTestFavoritesService(assetLookup, dftFavorites, mockMongo, repoFavorites, syncUtil, upkUtil, userLookup)
+	other := &pb.Favorites{User: &pb.User{PersonalKey: "other"}}
+	stolen := &pb.Favorites{User: &pb.User{Upk: "upk-other"}}
+	for _, request := range []*pb.FavoritesRequest{{Favorites: other}, {Favorites: stolen}} {
+		resp0, err := favoritesService.Get(getTestSubjectContext(), request)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, pb.Status_FAIL, resp0.GetStatus())
+		resp1, err := favoritesService.Set(getTestSubjectContext(), request)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, pb.Status_FAIL, resp1.GetStatus())
+		resp2, err := favoritesService.Delete(getTestSubjectContext(), request)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, pb.Status_FAIL, resp2.GetStatus())
+	}
+	resp, err := favoritesService.GetForUser(getTestSubjectContext(), &pb.UserFavoritesRequest{User: other.User})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
+	_, err = favoritesService.Get(context.TODO(), &pb.FavoritesRequest{Favorites: &pb.Favorites{}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
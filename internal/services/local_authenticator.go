@@ -0,0 +1,118 @@
+/*
+ * This file was last modified at 2024-08-15 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * local_authenticator.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/policy"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// localDummyHash bcrypt хэш для сравнения при неизвестном пользователе,
+// чтобы время ответа не выдавало существование логина.
+const localDummyHash = "$2a$11$ZTzzVGdLUJGcYKJws9UoUug3Q3kCMELVziajBSJPY3k0pNu2XWHBy"
+
+type localUser struct {
+	hash     []byte
+	identity Identity
+}
+
+// localAuthenticator проверка учётных данных по локальному файлу пользователей,
+// предназначена для разработки. Формат строки файла:
+//
+//	user_name:bcrypt_hash[:personal_key[:role]]
+//
+// Пустые строки и строки начинающиеся с # пропускаются,
+// если personal_key не задан им становится user_name, если role не задана — USER.
+type localAuthenticator struct {
+	sLog  *slog.Logger
+	users map[string]localUser
+}
+
+var _ Authenticator = (*localAuthenticator)(nil)
+
+func getLocalAuthenticator(prop env.Properties) *localAuthenticator {
+
+	result := new(localAuthenticator)
+	result.sLog = prop.Logger()
+	result.users = make(map[string]localUser)
+	file, err := os.Open(prop.Config().AuthUsersFile())
+
+	if err != nil {
+		result.sLog.Error(env.MSG+"getLocalAuthenticator", "msg", "open users file", "err", err)
+		return result
+	}
+	defer func() { _ = file.Close() }()
+	users, err := parseLocalUsers(file)
+
+	if err != nil {
+		result.sLog.Error(env.MSG+"getLocalAuthenticator", "msg", "parse users file", "err", err)
+		return result
+	}
+	result.users = users
+
+	return result
+}
+
+// Authenticate проверка учётных данных по локальному файлу пользователей.
+func (l *localAuthenticator) Authenticate(_ context.Context, userName, password string) (Identity, error) {
+
+	user, ok := l.users[userName]
+
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword([]byte(localDummyHash), []byte(password))
+		return Identity{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword(user.hash, []byte(password)); err != nil {
+		return Identity{}, ErrInvalidCredentials
+	}
+	return user.identity, nil
+}
+
+func parseLocalUsers(reader io.Reader) (map[string]localUser, error) {
+
+	users := make(map[string]localUser)
+	scanner := bufio.NewScanner(reader)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+
+		if len(fields) < 2 || len(fields) > 4 || fields[0] == "" || fields[1] == "" {
+			return nil, fmt.Errorf("users file line %d: malformed entry", n)
+		}
+		user := localUser{hash: []byte(fields[1]), identity: Identity{PersonalKey: fields[0], Role: policy.RoleUser}}
+
+		if len(fields) > 2 && fields[2] != "" {
+			user.identity.PersonalKey = fields[2]
+		}
+		if len(fields) > 3 && fields[3] != "" {
+			user.identity.Role = strings.ToUpper(fields[3])
+		}
+		users[fields[0]] = user
+	}
+	return users, scanner.Err()
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
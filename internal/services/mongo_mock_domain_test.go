@@ -12,6 +12,7 @@ package services
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	entity "github.com/vskurikhin/gofavorites/internal/domain/entity"
 	gomock "go.uber.org/mock/gomock"
@@ -40,6 +41,35 @@ func (m *MockMongo) EXPECT() *MockMongoMockRecorder {
 	return m.recorder
 }
 
+// Acknowledge mocks base method.
+func (m *MockMongo) Acknowledge(ctx context.Context, tombstones []entity.Favorites, shard string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acknowledge", ctx, tombstones, shard)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Acknowledge indicates an expected call of Acknowledge.
+func (mr *MockMongoMockRecorder) Acknowledge(ctx, tombstones, shard any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acknowledge", reflect.TypeOf((*MockMongo)(nil).Acknowledge), ctx, tombstones, shard)
+}
+
+// Compact mocks base method.
+func (m *MockMongo) Compact(ctx context.Context, shards []string, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Compact", ctx, shards, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Compact indicates an expected call of Compact.
+func (mr *MockMongoMockRecorder) Compact(ctx, shards, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Compact", reflect.TypeOf((*MockMongo)(nil).Compact), ctx, shards, before)
+}
+
 // Delete mocks base method.
 func (m *MockMongo) Delete(ctx context.Context, entity entity.Favorites) error {
 	m.ctrl.T.Helper()
@@ -54,6 +84,35 @@ func (mr *MockMongoMockRecorder) Delete(ctx, entity any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockMongo)(nil).Delete), ctx, entity)
 }
 
+// DeleteAll mocks base method.
+func (m *MockMongo) DeleteAll(ctx context.Context, entities []entity.Favorites) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAll", ctx, entities)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAll indicates an expected call of DeleteAll.
+func (mr *MockMongoMockRecorder) DeleteAll(ctx, entities any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAll", reflect.TypeOf((*MockMongo)(nil).DeleteAll), ctx, entities)
+}
+
+// Erase mocks base method.
+func (m *MockMongo) Erase(ctx context.Context, upk string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Erase", ctx, upk)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Erase indicates an expected call of Erase.
+func (mr *MockMongoMockRecorder) Erase(ctx, upk any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Erase", reflect.TypeOf((*MockMongo)(nil).Erase), ctx, upk)
+}
+
 // Load mocks base method.
 func (m *MockMongo) Load(ctx context.Context, upk string) ([]entity.Favorites, error) {
 	m.ctrl.T.Helper()
@@ -69,18 +128,18 @@ func (mr *MockMongoMockRecorder) Load(ctx, upk any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockMongo)(nil).Load), ctx, upk)
 }
 
-// MaxVersion mocks base method.
-func (m *MockMongo) MaxVersion(ctx context.Context, upk string) int64 {
+// Rekey mocks base method.
+func (m *MockMongo) Rekey(ctx context.Context, from, to string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MaxVersion", ctx, upk)
-	ret0, _ := ret[0].(int64)
+	ret := m.ctrl.Call(m, "Rekey", ctx, from, to)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// MaxVersion indicates an expected call of MaxVersion.
-func (mr *MockMongoMockRecorder) MaxVersion(ctx, upk any) *gomock.Call {
+// Rekey indicates an expected call of Rekey.
+func (mr *MockMongoMockRecorder) Rekey(ctx, from, to any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxVersion", reflect.TypeOf((*MockMongo)(nil).MaxVersion), ctx, upk)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rekey", reflect.TypeOf((*MockMongo)(nil).Rekey), ctx, from, to)
 }
 
 // Save mocks base method.
@@ -96,3 +155,112 @@ func (mr *MockMongoMockRecorder) Save(ctx, entity any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockMongo)(nil).Save), ctx, entity)
 }
+
+// SaveAll mocks base method.
+func (m *MockMongo) SaveAll(ctx context.Context, entities []entity.Favorites) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAll", ctx, entities)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAll indicates an expected call of SaveAll.
+func (mr *MockMongoMockRecorder) SaveAll(ctx, entities any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockMongo)(nil).SaveAll), ctx, entities)
+}
+
+// MockNotes is a mock of Notes interface.
+type MockNotes struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotesMockRecorder
+}
+
+// MockNotesMockRecorder is the mock recorder for MockNotes.
+type MockNotesMockRecorder struct {
+	mock *MockNotes
+}
+
+// NewMockNotes creates a new mock instance.
+func NewMockNotes(ctrl *gomock.Controller) *MockNotes {
+	mock := &MockNotes{ctrl: ctrl}
+	mock.recorder = &MockNotesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotes) EXPECT() *MockNotesMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockNotes) Delete(ctx context.Context, entity entity.Note) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, entity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockNotesMockRecorder) Delete(ctx, entity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockNotes)(nil).Delete), ctx, entity)
+}
+
+// Erase mocks base method.
+func (m *MockNotes) Erase(ctx context.Context, upk string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Erase", ctx, upk)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Erase indicates an expected call of Erase.
+func (mr *MockNotesMockRecorder) Erase(ctx, upk any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Erase", reflect.TypeOf((*MockNotes)(nil).Erase), ctx, upk)
+}
+
+// Load mocks base method.
+func (m *MockNotes) Load(ctx context.Context, upk string) ([]entity.Note, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Load", ctx, upk)
+	ret0, _ := ret[0].([]entity.Note)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Load indicates an expected call of Load.
+func (mr *MockNotesMockRecorder) Load(ctx, upk any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockNotes)(nil).Load), ctx, upk)
+}
+
+// Rekey mocks base method.
+func (m *MockNotes) Rekey(ctx context.Context, from, to string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rekey", ctx, from, to)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rekey indicates an expected call of Rekey.
+func (mr *MockNotesMockRecorder) Rekey(ctx, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rekey", reflect.TypeOf((*MockNotes)(nil).Rekey), ctx, from, to)
+}
+
+// Save mocks base method.
+func (m *MockNotes) Save(ctx context.Context, entity entity.Note) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, entity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockNotesMockRecorder) Save(ctx, entity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockNotes)(nil).Save), ctx, entity)
+}
@@ -0,0 +1,434 @@
+/*
+ * This file was last modified at 2024-08-18 05:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * notes_service.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+// NotesService бизнес логика хранения зашифрованных заметок пользователя,
+// консолидирует gRPC и HTTP (endpoints) конечные точки.
+type NotesService interface {
+	ApiNotesService
+	pb.NotesServiceServer
+}
+
+type notesService struct {
+	pb.UnimplementedNotesServiceServer
+	dftNote     domain.Dft[*entity.Note]
+	mongo       mongo.Notes
+	repoNote    domain.Repo[*entity.Note]
+	sLog        *slog.Logger
+	syncService NotesSyncUtilService
+	upkMigrator UpkMigrator
+	upkUtil     UpkUtilService
+	userLookup  UserSearchService
+}
+
+var _ NotesService = (*notesService)(nil)
+var (
+	onceNotes = new(sync.Once)
+	notesServ *notesService
+)
+
+// GetNotesService — потокобезопасное (thread-safe) создание
+// сервиса сохранения, получения и получения списка заметок пользователя.
+func GetNotesService(prop env.Properties) NotesService {
+
+	onceNotes.Do(func() {
+		notesServ = new(notesService)
+		notesServ.dftNote = repo.GetNoteTxPostgres(prop)
+		notesServ.mongo = mongo.GetMongoNotesRepo(prop)
+		notesServ.repoNote = repo.GetNotePostgresCachedRepo(prop)
+		notesServ.sLog = prop.Logger()
+		notesServ.syncService = GetNotesSyncUtilService(prop)
+		notesServ.upkMigrator = GetUpkMigrator(prop)
+		notesServ.upkUtil = GetUpkUtilService(prop)
+		notesServ.userLookup = GetUserSearchService(prop)
+	})
+	return notesServ
+}
+
+// ApiNotesDelete удаление заметки пользователя (API для HTTP).
+func (n *notesService) ApiNotesDelete(ctx context.Context, note models.Note) (models.Note, error) {
+	defer tool.TraceInOut(ctx, "ApiNotesDelete", "%v", note.Name())()
+	return n.delete(ctx, note)
+}
+
+// ApiNotesGet получение заметки пользователя (API для HTTP).
+func (n *notesService) ApiNotesGet(ctx context.Context, note models.Note) (models.Note, error) {
+	defer tool.TraceInOut(ctx, "ApiNotesGet", "%v", note.Name())()
+	return n.get(ctx, note)
+}
+
+// ApiNotesGetForUser получение списка заметок пользователя (API для HTTP).
+func (n *notesService) ApiNotesGetForUser(ctx context.Context, user models.User) ([]models.Note, error) {
+	defer tool.TraceInOut(ctx, "ApiNotesGetForUser", "%v", user)()
+	return n.getForUser(ctx, user)
+}
+
+// ApiNotesSet сохранение заметки пользователя (API для HTTP).
+func (n *notesService) ApiNotesSet(ctx context.Context, note models.Note) (models.Note, error) {
+	defer tool.TraceInOut(ctx, "ApiNotesSet", "%v", note.Name())()
+	return n.set(ctx, note)
+}
+
+// Delete удаление заметки пользователя.
+func (n *notesService) Delete(ctx context.Context, request *pb.NoteRequest) (*pb.NoteResponse, error) {
+
+	var response pb.NoteResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	note := models.NoteFromProto(request.GetNote())
+	user, err := subjectUser(ctx, n.upkUtil, note.User())
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	note = note.WithUser(user)
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	model, err := n.delete(ctx, note)
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+	} else {
+		response.Note = model.ToProto()
+		response.Status = pb.Status_OK
+	}
+	return &response, err
+}
+
+// Get получение заметки пользователя.
+func (n *notesService) Get(ctx context.Context, request *pb.NoteRequest) (*pb.NoteResponse, error) {
+
+	var response pb.NoteResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	note := models.NoteFromProto(request.GetNote())
+	user, err := subjectUser(ctx, n.upkUtil, note.User())
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	note = note.WithUser(user)
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	model, err := n.get(ctx, note)
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+	} else {
+		response.Note = model.ToProto()
+		response.Status = pb.Status_OK
+	}
+	return &response, err
+}
+
+// GetForUser получение списка заметок пользователя.
+func (n *notesService) GetForUser(ctx context.Context, request *pb.UserNotesRequest) (*pb.UserNotesResponse, error) {
+
+	var response pb.UserNotesResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	user, err := subjectUser(ctx, n.upkUtil, models.UserFromProto(request.GetUser()))
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	notes, err := n.getForUser(ctx, user)
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	response.Notes = make([]*pb.Note, 0, len(notes))
+
+	for _, note := range notes {
+		response.Notes = append(response.Notes, note.ToProto())
+	}
+	response.Count = int32(len(notes))
+	response.Status = pb.Status_OK
+
+	return &response, err
+}
+
+// Set сохранение заметки пользователя.
+func (n *notesService) Set(ctx context.Context, request *pb.NoteRequest) (*pb.NoteResponse, error) {
+
+	var response pb.NoteResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	note := models.NoteFromProto(request.GetNote())
+	user, err := subjectUser(ctx, n.upkUtil, note.User())
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	note = note.WithUser(user)
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	model, err := n.set(ctx, note)
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+	} else {
+		response.Note = model.ToProto()
+		response.Status = pb.Status_OK
+	}
+	return &response, err
+}
+
+func (n *notesService) decrypt(ctx context.Context, note entity.Note) (models.Note, error) {
+
+	bytes, err := base64.StdEncoding.DecodeString(note.Body())
+
+	if err == nil {
+		bytes, err = n.upkUtil.DecryptGCM(bytes)
+	}
+	if err != nil {
+		n.sLog.ErrorContext(ctx, env.MSG+"NotesService.decrypt", "msg", "notes service decrypt", "err", err)
+		return models.Note{}, tool.ErrDecryptGCM
+	}
+	return models.NoteFromEntity(note).WithBody(string(bytes)), nil
+}
+
+func (n *notesService) delete(ctx context.Context, model models.Note) (models.Note, error) {
+
+	var err error
+	var response models.Note
+
+	upk := model.User().Upk()
+
+	if upk == "" {
+		if upk, err = n.encryptPersonalKey(ctx, model.User().PersonalKey()); err != nil {
+			return response, err
+		}
+	}
+	note := model.WithUpk(upk).ToEntity()
+	var er0 error
+	err = note.Delete(ctx, n.dftNote, func() {
+		er0 = n.mongo.Delete(ctx, note)
+		if er0 != nil {
+			n.sLog.ErrorContext(ctx,
+				env.MSG+"NotesService.delete",
+				"msg", "notes service delete in transaction",
+				"er0", er0,
+			)
+		}
+	})
+	if err != nil {
+		n.sLog.ErrorContext(ctx, env.MSG+"NotesService.delete", "msg", "notes service delete", "err", err)
+		return response, err
+	}
+	a := entity.MakeTAttributes(note.Deleted(), note.CreatedAt(), note.UpdatedAt())
+	v := sql.NullInt64{Int64: note.User().Version(), Valid: true}
+	tombstone := entity.MakeNote(note.ID(), note.Name(), note.User(), "", v, a)
+
+	if er0 == nil {
+		if er0 := tombstone.Update(ctx, n.repoNote); er0 != nil {
+			n.sLog.ErrorContext(ctx,
+				env.MSG+"NotesService.delete",
+				"msg", "notes service ack in transaction",
+				"er0", er0,
+			)
+		}
+	}
+	return models.NoteFromEntity(tombstone), nil
+}
+
+func (n *notesService) encryptBody(ctx context.Context, body string) (string, error) {
+
+	bytes, err := n.upkUtil.EncryptGCM([]byte(body))
+
+	if err != nil {
+		n.sLog.ErrorContext(ctx, env.MSG+"NotesService.encryptBody", "msg", "notes service encrypt", "err", err)
+		return "", tool.ErrEncryptAES
+	}
+	return base64.StdEncoding.EncodeToString(bytes), nil
+}
+
+func (n *notesService) encryptPersonalKey(ctx context.Context, personalKey string) (string, error) {
+
+	upk, err := n.upkMigrator.Migrate(ctx, personalKey)
+
+	if err != nil {
+		n.sLog.ErrorContext(ctx, env.MSG+"NotesService.encrypt", "msg", "notes service encrypt", "err", err)
+		return "", tool.ErrEncryptAES
+	}
+	return upk, nil
+}
+
+func (n *notesService) get(ctx context.Context, model models.Note) (models.Note, error) {
+
+	var err error
+
+	upk := model.User().Upk()
+
+	if upk == "" {
+		if upk, err = n.encryptPersonalKey(ctx, model.User().PersonalKey()); err != nil {
+			return models.Note{}, err
+		}
+	}
+	note, err := entity.GetNote(ctx, n.repoNote, model.Name(), upk)
+
+	if err != nil {
+		n.sLog.ErrorContext(ctx, env.MSG+"NotesService.get", "msg", "notes service get", "err", err)
+		return models.Note{}, err
+	}
+	return n.decrypt(ctx, note)
+}
+
+const cntNotesSources = 2
+
+func (n *notesService) getForUser(ctx context.Context, model models.User) ([]models.Note, error) {
+
+	var (
+		er0, err                error
+		wg                      sync.WaitGroup
+		pgDBNotes, mongodbNotes []entity.Note
+	)
+
+	upk := model.Upk()
+
+	if upk == "" {
+		if upk, err = n.encryptPersonalKey(ctx, model.PersonalKey()); err != nil {
+			return nil, err
+		}
+	}
+	wg.Add(cntNotesSources)
+
+	go func() {
+		defer wg.Done()
+		pgDBNotes, err = entity.GetNotesForUser(ctx, n.repoNote, upk)
+		if err != nil {
+			n.sLog.ErrorContext(ctx,
+				env.MSG+"NotesService.getForUser",
+				"msg", "notes service get notes from Postgres",
+				"err", err,
+			)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		mongodbNotes, er0 = n.mongo.Load(ctx, upk)
+		if er0 != nil {
+			n.sLog.ErrorContext(ctx,
+				env.MSG+"NotesService.getForUser",
+				"msg", "notes service get notes from MongoDB",
+				"er0", er0,
+			)
+		}
+	}()
+	wg.Wait()
+	last, err := n.syncService.Sync(ctx, mongodbNotes, pgDBNotes)
+
+	if err != nil {
+		n.sLog.ErrorContext(ctx,
+			env.MSG+"NotesService.getForUser",
+			"msg", "notes service sync Postgres and MongoDB",
+			"err", err,
+		)
+	}
+	response := make([]models.Note, 0, len(last))
+
+	for _, note := range last {
+		if m, er1 := n.decrypt(ctx, note); er1 == nil {
+			response = append(response, m)
+		}
+	}
+	return response, err
+}
+
+func (n *notesService) set(ctx context.Context, model models.Note) (models.Note, error) {
+
+	var err error
+
+	personalKey := model.User().PersonalKey()
+	upk := model.User().Upk()
+
+	if upk == "" {
+		if upk, err = n.encryptPersonalKey(ctx, personalKey); err != nil {
+			return models.Note{}, err
+		}
+	}
+	if !n.userLookup.Lookup(ctx, models.MakeUser(personalKey, upk)) {
+		err = fmt.Errorf("user by upk: %s not found", upk)
+		n.sLog.ErrorContext(ctx, env.MSG+"NotesService.set", "msg", "notes service set", "err", err)
+		return models.Note{}, err
+	}
+	body, err := n.encryptBody(ctx, model.Body())
+
+	if err != nil {
+		return models.Note{}, err
+	}
+	note := model.WithUpk(upk).ToEntity().WithBody(body)
+	var er0 error
+	err = note.Upsert(ctx, n.dftNote, func() {
+		a := entity.MakeTAttributes(note.Deleted(), note.CreatedAt(), note.UpdatedAt())
+		v := sql.NullInt64{Int64: note.User().Version(), Valid: true}
+		er0 = n.mongo.Save(ctx, entity.MakeNote(note.ID(), note.Name(), note.User(), note.Body(), v, a))
+		if er0 != nil {
+			n.sLog.ErrorContext(ctx,
+				env.MSG+"NotesService.set",
+				"msg", "notes service set in transaction",
+				"er0", er0,
+			)
+		}
+	})
+	if err != nil {
+		n.sLog.ErrorContext(ctx, env.MSG+"NotesService.set", "msg", "notes service set", "err", err)
+		return models.Note{}, err
+	}
+	if er0 == nil {
+		if er0 := note.Update(ctx, n.repoNote); er0 != nil {
+			n.sLog.ErrorContext(ctx,
+				env.MSG+"NotesService.set",
+				"msg", "notes service ack in transaction",
+				"er0", er0,
+			)
+		}
+	}
+	return models.NoteFromEntity(note).WithBody(model.Body()), nil
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,288 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * notes_service_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"go.uber.org/mock/gomock"
+
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+func TestNotesService(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive Notes Service ApiNotesSet",
+			fRun: testNotesServiceApiNotesSetPositive,
+		},
+		{
+			name: "test #1 positive Notes Service encrypt and decrypt body",
+			fRun: testNotesServiceEncryptDecryptPositive,
+		},
+		{
+			name: "test #2 positive Notes Service GetForUser",
+			fRun: testNotesServiceGetForUserPositive,
+		},
+		{
+			name: "test #3 positive Notes Service Delete",
+			fRun: testNotesServiceDeletePositive,
+		},
+		{
+			name: "test #4 negative #0 Notes Service nil requests",
+			fRun: testNotesServiceNegative0,
+		},
+		{
+			name: "test #5 negative #1 Notes Service Set user not found",
+			fRun: testNotesServiceSetNegative1,
+		},
+		{
+			name: "test #6 negative #2 Notes Service Get",
+			fRun: testNotesServiceGetNegative2,
+		},
+		{
+			name: "test #7 negative #3 Notes Service Delete",
+			fRun: testNotesServiceDeleteNegative3,
+		},
+	}
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testNotesServiceApiNotesSetPositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dftNote := NewMockDft[*entity.Note](ctrl)
+	mockNotes := NewMockNotes(ctrl)
+	repoNote := NewMockRepo[*entity.Note](ctrl)
+	syncUtil := NewMockNotesSyncUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	var stored *entity.Note
+	dftNote.
+		EXPECT().
+		DoUpsert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, note *entity.Note, _ func(domain.Scanner)) error {
+			stored = note
+			return nil
+		}).
+		AnyTimes()
+	repoNote.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.Note{}, nil).
+		AnyTimes()
+	userLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(true).
+		AnyTimes()
+	notesService := getTestNotesService(dftNote, mockNotes, repoNote, syncUtil, getTestNotesUpkUtil(), userLookup)
+	model := models.NoteFromDto(dto.Note{Name: "note", Body: "secret text"}, "test", "")
+	resp, err := notesService.ApiNotesSet(context.TODO(), model)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret text", resp.Body())
+	assert.NotNil(t, stored)
+	assert.NotEqual(t, "secret text", stored.Body())
+	assert.NotContains(t, stored.Body(), "secret")
+}
+
+func testNotesServiceEncryptDecryptPositive(t *testing.T) {
+	service := getTestNotesService(nil, nil, nil, nil, getTestNotesUpkUtil(), nil).(*notesService)
+	body, err := service.encryptBody(context.TODO(), "рентгеноэлектрокардиографический")
+	assert.Nil(t, err)
+	note := entity.MakeNote(uuid.New(), "note", entity.User{}, body, sql.NullInt64{}, entity.DefaultTAttributes())
+	got, err := service.decrypt(context.TODO(), note)
+	assert.Nil(t, err)
+	assert.Equal(t, "рентгеноэлектрокардиографический", got.Body())
+	_, err = service.decrypt(context.TODO(), note.WithBody("plain text"))
+	assert.Equal(t, tool.ErrDecryptGCM, err)
+}
+
+func testNotesServiceGetForUserPositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dftNote := NewMockDft[*entity.Note](ctrl)
+	mockNotes := NewMockNotes(ctrl)
+	repoNote := NewMockRepo[*entity.Note](ctrl)
+	syncUtil := NewMockNotesSyncUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	upkUtil := getTestNotesUpkUtil()
+	service := getTestNotesService(dftNote, mockNotes, repoNote, syncUtil, upkUtil, userLookup)
+	body, err := service.(*notesService).encryptBody(context.TODO(), "text")
+	assert.Nil(t, err)
+	note := entity.MakeNote(uuid.New(), "note", entity.User{}, body, sql.NullInt64{}, entity.DefaultTAttributes())
+	repoNote.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*entity.Note{}, nil).
+		AnyTimes()
+	mockNotes.
+		EXPECT().
+		Load(gomock.Any(), gomock.Any()).
+		Return([]entity.Note{note}, nil).
+		AnyTimes()
+	syncUtil.
+		EXPECT().
+		Sync(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]entity.Note{note, note.WithBody("broken")}, nil).
+		AnyTimes()
+	resp, err := service.GetForUser(getTestSubjectContext(), &pb.UserNotesRequest{User: &pb.User{PersonalKey: "test"}})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+	assert.Equal(t, int32(1), resp.GetCount())
+	assert.Equal(t, "text", resp.GetNotes()[0].GetBody())
+}
+
+func testNotesServiceDeletePositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dftNote := NewMockDft[*entity.Note](ctrl)
+	mockNotes := NewMockNotes(ctrl)
+	repoNote := NewMockRepo[*entity.Note](ctrl)
+	syncUtil := NewMockNotesSyncUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	dftNote.
+		EXPECT().
+		DoDelete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	repoNote.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.Note{}, nil).
+		AnyTimes()
+	notesService := getTestNotesService(dftNote, mockNotes, repoNote, syncUtil, getTestNotesUpkUtil(), userLookup)
+	resp, err := notesService.Delete(getTestSubjectContext(), &pb.NoteRequest{Note: &pb.Note{Name: "note", User: &pb.User{PersonalKey: "test"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+	assert.Equal(t, "", resp.GetNote().GetBody())
+	resp0, err := notesService.ApiNotesDelete(context.TODO(), models.Note{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", resp0.Body())
+}
+
+func testNotesServiceNegative0(t *testing.T) {
+	notesService := getTestNotesService(nil, nil, nil, nil, nil, nil)
+	resp0, err := notesService.Delete(context.TODO(), nil)
+	assert.Equal(t, ErrRequestNil, err)
+	assert.Equal(t, pb.Status_FAIL, resp0.GetStatus())
+	resp1, err := notesService.Get(context.TODO(), nil)
+	assert.Equal(t, ErrRequestNil, err)
+	assert.Equal(t, pb.Status_FAIL, resp1.GetStatus())
+	resp2, err := notesService.GetForUser(context.TODO(), nil)
+	assert.Equal(t, ErrRequestNil, err)
+	assert.Equal(t, pb.Status_FAIL, resp2.GetStatus())
+	resp3, err := notesService.Set(context.TODO(), nil)
+	assert.Equal(t, ErrRequestNil, err)
+	assert.Equal(t, pb.Status_FAIL, resp3.GetStatus())
+}
+
+func testNotesServiceSetNegative1(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dftNote := NewMockDft[*entity.Note](ctrl)
+	mockNotes := NewMockNotes(ctrl)
+	repoNote := NewMockRepo[*entity.Note](ctrl)
+	syncUtil := NewMockNotesSyncUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	userLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(false).
+		AnyTimes()
+	notesService := getTestNotesService(dftNote, mockNotes, repoNote, syncUtil, getTestNotesUpkUtil(), userLookup)
+	resp, err := notesService.Set(getTestSubjectContext(), &pb.NoteRequest{Note: &pb.Note{Name: "note", User: &pb.User{PersonalKey: "test"}}})
+	assert.NotNil(t, err)
+	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
+}
+
+func testNotesServiceGetNegative2(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dftNote := NewMockDft[*entity.Note](ctrl)
+	mockNotes := NewMockNotes(ctrl)
+	repoNote := NewMockRepo[*entity.Note](ctrl)
+	syncUtil := NewMockNotesSyncUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	repoNote.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.Note{}, repo.ErrBadPool).
+		AnyTimes()
+	notesService := getTestNotesService(dftNote, mockNotes, repoNote, syncUtil, getTestNotesUpkUtil(), userLookup)
+	resp, err := notesService.Get(getTestSubjectContext(), &pb.NoteRequest{Note: &pb.Note{Name: "note", User: &pb.User{PersonalKey: "test"}}})
+	assert.Equal(t, repo.ErrBadPool, err)
+	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
+	_, err = notesService.ApiNotesGet(context.TODO(), models.Note{})
+	assert.NotNil(t, err)
+}
+
+func testNotesServiceDeleteNegative3(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dftNote := NewMockDft[*entity.Note](ctrl)
+	mockNotes := NewMockNotes(ctrl)
+	repoNote := NewMockRepo[*entity.Note](ctrl)
+	syncUtil := NewMockNotesSyncUtilService(ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	dftNote.
+		EXPECT().
+		DoDelete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(repo.ErrNotFound).
+		AnyTimes()
+	notesService := getTestNotesService(dftNote, mockNotes, repoNote, syncUtil, getTestNotesUpkUtil(), userLookup)
+	resp, err := notesService.Delete(getTestSubjectContext(), &pb.NoteRequest{Note: &pb.Note{Name: "note", User: &pb.User{PersonalKey: "test"}}})
+	assert.NotNil(t, err)
+	assert.Equal(t, pb.Status_FAIL, resp.GetStatus())
+}
+
+func getTestNotesUpkUtil() UpkUtilService {
+	return getTestUpkUtilService(nil, nil, make([]byte, 32))
+}
+
+func getTestNotesService(
+	dftNote domain.Dft[*entity.Note],
+	mongo mongo.Notes,
+	repoNote domain.Repo[*entity.Note],
+	syncService NotesSyncUtilService,
+	upkUtil UpkUtilService,
+	userLookup UserSearchService,
+) NotesService {
+	notesServ = new(notesService)
+	notesServ.dftNote = dftNote
+	notesServ.mongo = mongo
+	notesServ.repoNote = repoNote
+	notesServ.sLog = slog.Default()
+	notesServ.syncService = syncService
+	notesServ.upkMigrator = getTestUpkMigrator(upkUtil)
+	notesServ.upkUtil = upkUtil
+	notesServ.userLookup = userLookup
+	return notesServ
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
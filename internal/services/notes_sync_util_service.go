@@ -0,0 +1,188 @@
+/*
+ * This file was last modified at 2024-08-17 23:30 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * notes_sync_util_service.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/batch"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/models"
+)
+
+type NotesSyncUtilService interface {
+	Sync(ctx context.Context, mongodbNotes, pgDBNotes []entity.Note) ([]entity.Note, error)
+}
+
+type notesSyncUtilService struct {
+	batch           batch.NotesInsertsBatch
+	executor        SyncExecutor
+	mongo           mongo.Notes
+	repoNote        domain.Repo[*entity.Note]
+	repoNoteDeleted domain.Repo[*entity.NoteDeleted]
+	sLog            *slog.Logger
+	userLookup      UserSearchService
+	userRepo        domain.Repo[*entity.User]
+}
+
+var _ NotesSyncUtilService = (*notesSyncUtilService)(nil)
+var (
+	onceNotesSyncUtil = new(sync.Once)
+	notesSyncUtilServ *notesSyncUtilService
+)
+
+// GetNotesSyncUtilService — потокобезопасное (thread-safe) создание
+// сервиса синхронизации заметок пользователя между базами данных MongoDB и PostgreSQL.
+func GetNotesSyncUtilService(prop env.Properties) NotesSyncUtilService {
+
+	onceNotesSyncUtil.Do(func() {
+		notesSyncUtilServ = new(notesSyncUtilService)
+		notesSyncUtilServ.batch = batch.GetNotesBatchPostgres(prop)
+		notesSyncUtilServ.executor = GetSyncExecutor(prop)
+		notesSyncUtilServ.mongo = mongo.GetMongoNotesRepo(prop)
+		notesSyncUtilServ.repoNote = repo.GetNotePostgresCachedRepo(prop)
+		notesSyncUtilServ.repoNoteDeleted = repo.GetNoteDeletedPostgresRepo(prop)
+		notesSyncUtilServ.sLog = prop.Logger()
+		notesSyncUtilServ.userLookup = GetUserSearchService(prop)
+		notesSyncUtilServ.userRepo = repo.GetUserPostgresCachedRepo(prop)
+	})
+	return notesSyncUtilServ
+}
+
+// Sync синхронизация заметок пользователя между базами данных MongoDB и PostgreSQL.
+// Версии заметок берутся из общей для пользователя версии, как и у биржевых инструментов.
+func (s *notesSyncUtilService) Sync(ctx context.Context, mongodbNotes, pgDBNotes []entity.Note) ([]entity.Note, error) {
+
+	if len(mongodbNotes) < 1 {
+		return pgDBNotes, nil
+	}
+	maxMongodbUser := slices.MaxFunc[[]entity.Note, entity.Note](mongodbNotes, func(x, y entity.Note) int {
+		if x.Version().Int64 > y.Version().Int64 {
+			return 1
+		} else if x.Version().Int64 < y.Version().Int64 {
+			return -1
+		}
+		return 0
+	}).User()
+	s.sLog.InfoContext(ctx, env.MSG+"NotesSync", "maxMongodb", maxMongodbUser.Version())
+
+	var maxPostgresUser entity.User
+
+	if len(pgDBNotes) < 1 {
+		if !s.userLookup.Lookup(ctx, models.UserFromEntity(maxMongodbUser)) {
+			return mongodbNotes, nil
+		}
+		maxPostgresUser = entity.MakeUser(maxMongodbUser.Upk(), entity.DefaultTAttributes())
+	} else {
+		maxPostgresUser = slices.MaxFunc[[]entity.Note, entity.Note](pgDBNotes, func(x, y entity.Note) int {
+			if x.User().Version() > y.User().Version() {
+				return 1
+			} else if x.User().Version() < y.User().Version() {
+				return -1
+			}
+			return 0
+		}).User()
+	}
+	s.sLog.InfoContext(ctx, env.MSG+"NotesSync", "maxPostgresUser", maxPostgresUser.Version())
+	result := pgDBNotes
+	var task SyncTask
+
+	if maxPostgresUser.Version() > maxMongodbUser.Version() {
+		task = func(ctx context.Context) { s.syncToMongoDB(ctx, pgDBNotes, maxPostgresUser) }
+		result = pgDBNotes
+	} else if maxPostgresUser.Version() < maxMongodbUser.Version() {
+		task = func(ctx context.Context) { s.syncToPostgreSQL(ctx, mongodbNotes, maxMongodbUser) }
+		result = mongodbNotes
+	} else if isBodyDiffer(mongodbNotes, pgDBNotes) {
+		task = func(ctx context.Context) { s.syncToMongoDB(ctx, pgDBNotes, maxPostgresUser) }
+		result = pgDBNotes
+	}
+	if task != nil && !s.executor.Submit("notes:"+maxMongodbUser.Upk(), task) {
+		s.sLog.WarnContext(ctx, env.MSG+"NotesSync", "msg", "sync task is not submitted", "upk", maxMongodbUser.Upk())
+	}
+	return result, nil
+}
+
+func isBodyDiffer(mongodbNotes, pgDBNotes []entity.Note) bool {
+
+	bodies := make(map[string]string, len(mongodbNotes))
+
+	for _, note := range mongodbNotes {
+		bodies[note.Name()] = note.Body()
+	}
+	for _, note := range pgDBNotes {
+		if b, ok := bodies[note.Name()]; ok && b != note.Body() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *notesSyncUtilService) syncToMongoDB(ctx context.Context, notes []entity.Note, user entity.User) {
+
+	for _, nt := range notes {
+
+		var n = nt
+
+		if !nt.Version().Valid {
+
+			a := entity.MakeTAttributes(nt.Deleted(), nt.CreatedAt(), nt.UpdatedAt())
+			v := sql.NullInt64{Int64: nt.User().Version(), Valid: true}
+			n = entity.MakeNote(nt.ID(), nt.Name(), nt.User(), nt.Body(), v, a)
+
+			if err := n.Update(ctx, s.repoNote); err != nil {
+				s.sLog.ErrorContext(ctx, env.MSG+"NotesSync.syncToMongoDB", "err", err)
+			}
+		}
+		if !n.Deleted().Bool {
+			if err := s.mongo.Save(ctx, n); err != nil {
+				s.sLog.ErrorContext(ctx, env.MSG+"NotesSync.syncToMongoDB save", "err", err)
+			}
+		}
+	}
+	notesDeleted, err := entity.GetNotesDeletedForUser(ctx, s.repoNoteDeleted, user.Upk())
+
+	if err != nil {
+		s.sLog.ErrorContext(ctx, env.MSG+"NotesSync.syncToMongoDB get notes deleted", "err", err)
+	}
+	for _, deleted := range notesDeleted {
+		if err := s.mongo.Delete(ctx, *deleted.ToNote()); err != nil {
+			s.sLog.ErrorContext(ctx, env.MSG+"NotesSync.syncToMongoDB delete in MongoDB", "err", err)
+		} else if err := deleted.Update(ctx, s.repoNoteDeleted); err != nil {
+			s.sLog.ErrorContext(ctx, env.MSG+"NotesSync.syncToMongoDB delete in PostgreSQL", "err", err)
+		}
+	}
+}
+
+func (s *notesSyncUtilService) syncToPostgreSQL(ctx context.Context, notes []entity.Note, user entity.User) {
+
+	deleted := entity.MakeNoteDeletedUser(user.Upk())
+
+	if err := deleted.Delete(ctx, s.repoNoteDeleted); err != nil {
+		s.sLog.ErrorContext(ctx, env.MSG+"NotesSync.syncToPostgreSQL delete in PostgreSQL", "err", err)
+	}
+	if err := s.batch.Do(ctx, notes, user.Upk()); err != nil {
+		s.sLog.ErrorContext(ctx, env.MSG+"NotesSync.syncToPostgreSQL batch to PostgreSQL", "err", err)
+	} else if err := user.Update(ctx, s.userRepo); err != nil {
+		s.sLog.ErrorContext(ctx, env.MSG+"NotesSync.syncToPostgreSQL batch to PostgreSQL and update user", "err", err)
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/services/notes_sync_util_service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/services/notes_sync_util_service.go -package=services
+//
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/vskurikhin/gofavorites/internal/domain/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNotesSyncUtilService is a mock of NotesSyncUtilService interface.
+type MockNotesSyncUtilService struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotesSyncUtilServiceMockRecorder
+}
+
+// MockNotesSyncUtilServiceMockRecorder is the mock recorder for MockNotesSyncUtilService.
+type MockNotesSyncUtilServiceMockRecorder struct {
+	mock *MockNotesSyncUtilService
+}
+
+// NewMockNotesSyncUtilService creates a new mock instance.
+func NewMockNotesSyncUtilService(ctrl *gomock.Controller) *MockNotesSyncUtilService {
+	mock := &MockNotesSyncUtilService{ctrl: ctrl}
+	mock.recorder = &MockNotesSyncUtilServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotesSyncUtilService) EXPECT() *MockNotesSyncUtilServiceMockRecorder {
+	return m.recorder
+}
+
+// Sync mocks base method.
+func (m *MockNotesSyncUtilService) Sync(ctx context.Context, mongodbNotes, pgDBNotes []entity.Note) ([]entity.Note, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sync", ctx, mongodbNotes, pgDBNotes)
+	ret0, _ := ret[0].([]entity.Note)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sync indicates an expected call of Sync.
+func (mr *MockNotesSyncUtilServiceMockRecorder) Sync(ctx, mongodbNotes, pgDBNotes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sync", reflect.TypeOf((*MockNotesSyncUtilService)(nil).Sync), ctx, mongodbNotes, pgDBNotes)
+}
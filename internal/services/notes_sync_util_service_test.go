@@ -0,0 +1,232 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * notes_sync_util_service_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/batch"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNotesSyncUtilService(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive Notes Sync util Service empty MongoDB",
+			fRun: testNotesSyncPositive0,
+		},
+		{
+			name: "test #1 positive Notes Sync util Service MongoDB is newer",
+			fRun: testNotesSyncPositive1,
+		},
+		{
+			name: "test #2 positive Notes Sync util Service body differ",
+			fRun: testNotesSyncPositive2,
+		},
+		{
+			name: "test #3 positive Notes Sync util Service syncToMongoDB",
+			fRun: testNotesSyncToMongoDBPositive,
+		},
+		{
+			name: "test #4 negative Notes Sync util Service syncToPostgreSQL",
+			fRun: testNotesSyncToPostgreSQLNegative,
+		},
+	}
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testNotesSyncPositive0(t *testing.T) {
+	notesSync := getTestNotesSyncUtilService(nil, nil, nil, nil, nil, nil)
+	pgDBNotes := []entity.Note{makeTestNote("note", "body", 1, sql.NullInt64{})}
+	got, err := notesSync.Sync(context.TODO(), nil, pgDBNotes)
+	assert.Nil(t, err)
+	assert.Equal(t, pgDBNotes, got)
+}
+
+func testNotesSyncPositive1(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	notesInsertsBatch := NewMockNotesInsertsBatch(ctrl)
+	mockNotes := NewMockNotes(ctrl)
+	repoNote := NewMockRepo[*entity.Note](ctrl)
+	repoNoteDeleted := NewMockRepo[*entity.NoteDeleted](ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	repoUser := NewMockRepo[*entity.User](ctrl)
+	repoNoteDeleted.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.NoteDeleted{}, nil).
+		AnyTimes()
+	notesInsertsBatch.
+		EXPECT().
+		Do(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	repoUser.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.User{}, nil).
+		AnyTimes()
+	notesSync := getTestNotesSyncUtilService(
+		notesInsertsBatch, mockNotes, repoNote, repoNoteDeleted, userLookup, repoUser,
+	)
+	mongodbNotes := []entity.Note{makeTestNote("note", "new", 3, sql.NullInt64{Int64: 3, Valid: true})}
+	pgDBNotes := []entity.Note{makeTestNote("note", "old", 2, sql.NullInt64{Int64: 2, Valid: true})}
+	got, err := notesSync.Sync(context.TODO(), mongodbNotes, pgDBNotes)
+	assert.Nil(t, err)
+	assert.Equal(t, mongodbNotes, got)
+}
+
+func testNotesSyncPositive2(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	notesInsertsBatch := NewMockNotesInsertsBatch(ctrl)
+	mockNotes := NewMockNotes(ctrl)
+	repoNote := NewMockRepo[*entity.Note](ctrl)
+	repoNoteDeleted := NewMockRepo[*entity.NoteDeleted](ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	repoUser := NewMockRepo[*entity.User](ctrl)
+	mockNotes.
+		EXPECT().
+		Save(gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	repoNoteDeleted.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*entity.NoteDeleted{}, nil).
+		AnyTimes()
+	notesSync := getTestNotesSyncUtilService(
+		notesInsertsBatch, mockNotes, repoNote, repoNoteDeleted, userLookup, repoUser,
+	)
+	mongodbNotes := []entity.Note{makeTestNote("note", "old", 2, sql.NullInt64{Int64: 2, Valid: true})}
+	pgDBNotes := []entity.Note{makeTestNote("note", "new", 2, sql.NullInt64{Int64: 2, Valid: true})}
+	got, err := notesSync.Sync(context.TODO(), mongodbNotes, pgDBNotes)
+	assert.Nil(t, err)
+	assert.Equal(t, pgDBNotes, got)
+	assert.True(t, isBodyDiffer(mongodbNotes, pgDBNotes))
+	assert.False(t, isBodyDiffer(pgDBNotes, pgDBNotes))
+}
+
+func testNotesSyncToMongoDBPositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockNotes := NewMockNotes(ctrl)
+	repoNote := NewMockRepo[*entity.Note](ctrl)
+	repoNoteDeleted := NewMockRepo[*entity.NoteDeleted](ctrl)
+	var saved []entity.Note
+	mockNotes.
+		EXPECT().
+		Save(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, note entity.Note) error {
+			saved = append(saved, note)
+			return nil
+		}).
+		Times(1)
+	mockNotes.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(1)
+	repoNote.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.Note{}, nil).
+		Times(1)
+	repoNoteDeleted.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, e *entity.NoteDeleted, scan func(domain.Scanner) *entity.NoteDeleted) ([]*entity.NoteDeleted, error) {
+			scan(&stubNoteScanner{})
+			return nil, nil
+		}).
+		Times(1)
+	repoNoteDeleted.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.NoteDeleted{}, nil).
+		Times(1)
+	notesSync := getTestNotesSyncUtilService(nil, mockNotes, repoNote, repoNoteDeleted, nil, nil)
+	notes := []entity.Note{makeTestNote("note", "body", 5, sql.NullInt64{})}
+	notesSync.(*notesSyncUtilService).syncToMongoDB(context.TODO(), notes, notes[0].User())
+	assert.Len(t, saved, 1)
+	assert.Equal(t, sql.NullInt64{Int64: 5, Valid: true}, saved[0].Version())
+}
+
+func testNotesSyncToPostgreSQLNegative(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	notesInsertsBatch := NewMockNotesInsertsBatch(ctrl)
+	repoNoteDeleted := NewMockRepo[*entity.NoteDeleted](ctrl)
+	repoNoteDeleted.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.NoteDeleted{}, repo.ErrBadPool).
+		Times(1)
+	notesInsertsBatch.
+		EXPECT().
+		Do(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(batch.ErrBadPool).
+		Times(1)
+	notesSync := getTestNotesSyncUtilService(notesInsertsBatch, nil, nil, repoNoteDeleted, nil, nil)
+	notes := []entity.Note{makeTestNote("note", "body", 5, sql.NullInt64{Int64: 5, Valid: true})}
+	notesSync.(*notesSyncUtilService).syncToPostgreSQL(context.TODO(), notes, notes[0].User())
+}
+
+type stubNoteScanner struct{}
+
+func (s *stubNoteScanner) Scan(_ ...any) error {
+	return nil
+}
+
+func makeTestNote(name, body string, userVersion int64, version sql.NullInt64) entity.Note {
+	user := entity.MakeUserWithVersion("upk", userVersion, entity.DefaultTAttributes())
+	return entity.MakeNote(uuid.New(), name, user, body, version, entity.DefaultTAttributes())
+}
+
+func getTestNotesSyncUtilService(
+	batch batch.NotesInsertsBatch,
+	mongo mongo.Notes,
+	repoNote domain.Repo[*entity.Note],
+	repoNoteDeleted domain.Repo[*entity.NoteDeleted],
+	userLookup UserSearchService,
+	userRepo domain.Repo[*entity.User],
+) NotesSyncUtilService {
+	notesSyncUtilServ = new(notesSyncUtilService)
+	notesSyncUtilServ.batch = batch
+	notesSyncUtilServ.executor = newSyncExecutor(2, 16, slog.Default())
+	notesSyncUtilServ.mongo = mongo
+	notesSyncUtilServ.repoNote = repoNote
+	notesSyncUtilServ.repoNoteDeleted = repoNoteDeleted
+	notesSyncUtilServ.sLog = slog.Default()
+	notesSyncUtilServ.userLookup = userLookup
+	notesSyncUtilServ.userRepo = userRepo
+	return notesSyncUtilServ
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
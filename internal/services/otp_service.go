@@ -0,0 +1,328 @@
+/*
+ * This file was last modified at 2024-08-18 05:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * otp_service.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+const (
+	otpCodeLength = 8
+	otpCodesMax   = 100
+)
+
+var (
+	ErrOtpCodeUsed   = fmt.Errorf("OTP code not found or already used")
+	ErrOtpCodesCount = fmt.Errorf("OTP codes count out of range")
+	ErrOtpSecretNil  = fmt.Errorf("OTP secret is nil")
+)
+
+// OtpService бизнес логика хранения секретов одноразовых паролей TOTP/HOTP
+// и одноразовых кодов активации. Секреты хранятся только в PostgreSQL
+// в зашифрованном виде и в MongoDB не записываются.
+type OtpService interface {
+	pb.OtpServiceServer
+}
+
+type otpService struct {
+	pb.UnimplementedOtpServiceServer
+	dftOtp      domain.Dft[*entity.Otp]
+	dftOtpCode  domain.Dft[*entity.OtpCode]
+	repoOtp     domain.Repo[*entity.Otp]
+	sLog        *slog.Logger
+	upkMigrator UpkMigrator
+	upkUtil     UpkUtilService
+	userLookup  UserSearchService
+}
+
+var _ OtpService = (*otpService)(nil)
+var (
+	onceOtp = new(sync.Once)
+	otpServ *otpService
+)
+
+// GetOtpService — потокобезопасное (thread-safe) создание
+// сервиса секретов одноразовых паролей пользователя.
+func GetOtpService(prop env.Properties) OtpService {
+
+	onceOtp.Do(func() {
+		otpServ = new(otpService)
+		otpServ.dftOtp = repo.GetOtpTxPostgres(prop)
+		otpServ.dftOtpCode = repo.GetOtpCodeTxPostgres(prop)
+		otpServ.repoOtp = repo.GetOtpPostgresCachedRepo(prop)
+		otpServ.sLog = prop.Logger()
+		otpServ.upkMigrator = GetUpkMigrator(prop)
+		otpServ.upkUtil = GetUpkUtilService(prop)
+		otpServ.userLookup = GetUserSearchService(prop)
+	})
+	return otpServ
+}
+
+// Add сохранение секрета и выпуск одноразовых кодов активации,
+// коды возвращаются в открытом виде только в ответе на этот запрос.
+func (o *otpService) Add(ctx context.Context, request *pb.OtpSecretRequest) (*pb.OtpSecretResponse, error) {
+
+	var response pb.OtpSecretResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	secret, codes, err := o.add(ctx, request.GetSecret(), request.GetCodesCount())
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+	} else {
+		response.Secret = secret
+		response.Codes = codes
+		response.Status = pb.Status_OK
+	}
+	return &response, err
+}
+
+// Code получение текущего кода, для HOTP счётчик атомарно сдвигается.
+func (o *otpService) Code(ctx context.Context, request *pb.OtpRequest) (*pb.OtpCodeResponse, error) {
+
+	var response pb.OtpCodeResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	user, err := subjectUser(ctx, o.upkUtil, models.UserFromProto(request.GetUser()))
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	code, err := o.code(ctx, user, request.GetName())
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+	} else {
+		response.Code = code
+		response.Status = pb.Status_OK
+	}
+	return &response, err
+}
+
+// Consume погашение одноразового кода активации, повторно код использовать нельзя.
+func (o *otpService) Consume(ctx context.Context, request *pb.OtpConsumeRequest) (*pb.OtpConsumeResponse, error) {
+
+	var response pb.OtpConsumeResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	user, err := subjectUser(ctx, o.upkUtil, models.UserFromProto(request.GetUser()))
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	err = o.consume(ctx, user, request.GetName(), request.GetCode())
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+	} else {
+		response.Status = pb.Status_OK
+	}
+	return &response, err
+}
+
+func (o *otpService) add(ctx context.Context, secret *pb.OtpSecret, count int32) (*pb.OtpSecret, []string, error) {
+
+	if secret == nil {
+		return nil, nil, ErrOtpSecretNil
+	}
+	if count < 0 || count > otpCodesMax {
+		return nil, nil, ErrOtpCodesCount
+	}
+	user, err := subjectUser(ctx, o.upkUtil, models.UserFromProto(secret.GetUser()))
+
+	if err != nil {
+		return nil, nil, err
+	}
+	upk, err := o.upk(ctx, user)
+
+	if err != nil {
+		return nil, nil, err
+	}
+	if !o.userLookup.Lookup(ctx, models.MakeUser(user.PersonalKey(), upk)) {
+		err = fmt.Errorf("user by upk: %s not found", upk)
+		o.sLog.ErrorContext(ctx, env.MSG+"OtpService.add", "msg", "otp service add", "err", err)
+		return nil, nil, err
+	}
+	digits, period := otpDefaults(secret.GetDigits(), secret.GetPeriod())
+	key, err := otpKey(secret.GetSecret())
+
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err = tool.TOTP(key, time.Now(), int(period), int(digits)); err != nil {
+		return nil, nil, err
+	}
+	encrypted, err := o.upkUtil.EncryptGCM(key)
+
+	if err != nil {
+		o.sLog.ErrorContext(ctx, env.MSG+"OtpService.add", "msg", "otp service encrypt", "err", err)
+		return nil, nil, tool.ErrEncryptAES
+	}
+	codes, err := tool.GenerateOtpCodes(int(count), otpCodeLength)
+
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+
+	for _, code := range codes {
+		hashes = append(hashes, tool.OtpCodeHash(code))
+	}
+	otp := entity.MakeOtp(
+		uuid.Nil,
+		secret.GetName(),
+		entity.MakeUser(upk, entity.DefaultTAttributes()),
+		secret.GetKind().String(),
+		base64.StdEncoding.EncodeToString(encrypted),
+		digits,
+		period,
+		hashes,
+		entity.DefaultTAttributes(),
+	)
+	if err = otp.Upsert(ctx, o.dftOtp); err != nil {
+		o.sLog.ErrorContext(ctx, env.MSG+"OtpService.add", "msg", "otp service upsert", "err", err)
+		return nil, nil, err
+	}
+	return &pb.OtpSecret{
+		User:   secret.GetUser(),
+		Name:   secret.GetName(),
+		Kind:   secret.GetKind(),
+		Secret: tool.EncodeOtpSecret(key),
+		Digits: digits,
+		Period: period,
+	}, codes, nil
+}
+
+func (o *otpService) code(ctx context.Context, user models.User, name string) (string, error) {
+
+	upk, err := o.upk(ctx, user)
+
+	if err != nil {
+		return "", err
+	}
+	otp, err := entity.GetOtp(ctx, o.repoOtp, name, upk)
+
+	if err != nil {
+		o.sLog.ErrorContext(ctx, env.MSG+"OtpService.code", "msg", "otp service get", "err", err)
+		return "", err
+	}
+	key, err := o.decrypt(ctx, otp)
+
+	if err != nil {
+		return "", err
+	}
+	if otp.Kind() == entity.OtpKindHOTP {
+		if err = otp.Update(ctx, o.repoOtp); err != nil {
+			o.sLog.ErrorContext(ctx, env.MSG+"OtpService.code", "msg", "otp service counter", "err", err)
+			return "", err
+		}
+		return tool.HOTP(key, uint64(otp.Counter()), int(otp.Digits()))
+	}
+	return tool.TOTP(key, time.Now(), int(otp.Period()), int(otp.Digits()))
+}
+
+func (o *otpService) consume(ctx context.Context, user models.User, name, value string) error {
+
+	upk, err := o.upk(ctx, user)
+
+	if err != nil {
+		return err
+	}
+	code := entity.MakeOtpCode(name, upk, value)
+	err = code.Consume(ctx, o.dftOtpCode)
+
+	if entity.IsOtpCodeNotFound(code, err) {
+		o.sLog.WarnContext(ctx, env.MSG+"OtpService.consume", "msg", "otp service consume", "err", err)
+		if err == nil || tool.NoRowsInResultSet(err) {
+			return ErrOtpCodeUsed
+		}
+	}
+	return err
+}
+
+func (o *otpService) decrypt(ctx context.Context, otp entity.Otp) ([]byte, error) {
+
+	bytes, err := base64.StdEncoding.DecodeString(otp.Secret())
+
+	if err == nil {
+		bytes, err = o.upkUtil.DecryptGCM(bytes)
+	}
+	if err != nil {
+		o.sLog.ErrorContext(ctx, env.MSG+"OtpService.decrypt", "msg", "otp service decrypt", "err", err)
+		return nil, tool.ErrDecryptGCM
+	}
+	return bytes, nil
+}
+
+func (o *otpService) upk(ctx context.Context, user models.User) (string, error) {
+
+	if user.Upk() != "" {
+		return user.Upk(), nil
+	}
+	upk, err := o.upkMigrator.Migrate(ctx, user.PersonalKey())
+
+	if err != nil {
+		o.sLog.ErrorContext(ctx, env.MSG+"OtpService.upk", "msg", "otp service encrypt", "err", err)
+		return "", tool.ErrEncryptAES
+	}
+	return upk, nil
+}
+
+func otpDefaults(digits, period int32) (int32, int32) {
+
+	if digits == 0 {
+		digits = tool.OtpDefaultDigits
+	}
+	if period == 0 {
+		period = tool.OtpDefaultPeriod
+	}
+	return digits, period
+}
+
+func otpKey(secret string) ([]byte, error) {
+
+	if secret == "" {
+		return tool.GenerateOtpSecret()
+	}
+	return tool.DecodeOtpSecret(secret)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,287 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * otp_service_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"go.uber.org/mock/gomock"
+
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+func TestOtpService(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive Otp Service Add",
+			fRun: testOtpServiceAddPositive,
+		},
+		{
+			name: "test #1 positive Otp Service Code TOTP",
+			fRun: testOtpServiceCodeTOTPPositive,
+		},
+		{
+			name: "test #2 positive Otp Service Code HOTP",
+			fRun: testOtpServiceCodeHOTPPositive,
+		},
+		{
+			name: "test #3 positive Otp Service Consume",
+			fRun: testOtpServiceConsumePositive,
+		},
+		{
+			name: "test #4 negative #0 Otp Service Consume already used",
+			fRun: testOtpServiceConsumeNegative0,
+		},
+		{
+			name: "test #5 negative #1 Otp Service bad requests",
+			fRun: testOtpServiceNegative1,
+		},
+		{
+			name: "test #6 negative #2 Otp Service Add user not found",
+			fRun: testOtpServiceAddNegative2,
+		},
+	}
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testOtpServiceAddPositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dftOtp := NewMockDft[*entity.Otp](ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	var stored *entity.Otp
+	dftOtp.
+		EXPECT().
+		DoUpsert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, otp *entity.Otp, scan func(domain.Scanner)) error {
+			stored = otp
+			scan(&stubValuesScanner{values: []any{uuid.New()}})
+			return nil
+		}).
+		Times(1)
+	userLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(true).
+		Times(1)
+	service := getTestOtpService(dftOtp, nil, nil, getTestNotesUpkUtil(), userLookup)
+	request := &pb.OtpSecretRequest{
+		Secret: &pb.OtpSecret{
+			User:   &pb.User{PersonalKey: "test"},
+			Name:   "otp",
+			Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		},
+		CodesCount: 5,
+	}
+	resp, err := service.Add(getTestSubjectContext(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.Status)
+	assert.Len(t, resp.Codes, 5)
+	assert.Equal(t, int32(tool.OtpDefaultDigits), resp.Secret.Digits)
+	assert.Equal(t, int32(tool.OtpDefaultPeriod), resp.Secret.Period)
+	assert.NotNil(t, stored)
+	assert.Equal(t, entity.OtpKindTOTP, stored.Kind())
+	assert.NotContains(t, stored.Secret(), request.Secret.Secret)
+	key, err := getTestOtpService(nil, nil, nil, getTestNotesUpkUtil(), nil).(*otpService).decrypt(context.TODO(), *stored)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("12345678901234567890"), key)
+	for i, code := range resp.Codes {
+		assert.Equal(t, tool.OtpCodeHash(code), stored.Codes()[i])
+	}
+}
+
+func testOtpServiceCodeTOTPPositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoOtp := NewMockRepo[*entity.Otp](ctrl)
+	key := []byte("12345678901234567890")
+	secret := getTestOtpSecret(t, key)
+	repoOtp.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, otp *entity.Otp, scan func(domain.Scanner)) (*entity.Otp, error) {
+			scan(&stubValuesScanner{values: []any{uuid.New(), "otp", entity.OtpKindTOTP, secret, int32(6), int32(30)}})
+			return otp, nil
+		}).
+		Times(1)
+	service := getTestOtpService(nil, nil, repoOtp, getTestNotesUpkUtil(), nil)
+	before, _ := tool.TOTP(key, time.Now(), 30, 6)
+	resp, err := service.Code(getTestSubjectContext(), &pb.OtpRequest{User: &pb.User{PersonalKey: "test"}, Name: "otp"})
+	after, _ := tool.TOTP(key, time.Now(), 30, 6)
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.Status)
+	assert.Contains(t, []string{before, after}, resp.Code)
+}
+
+func testOtpServiceCodeHOTPPositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoOtp := NewMockRepo[*entity.Otp](ctrl)
+	key := []byte("12345678901234567890")
+	secret := getTestOtpSecret(t, key)
+	repoOtp.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, otp *entity.Otp, scan func(domain.Scanner)) (*entity.Otp, error) {
+			scan(&stubValuesScanner{values: []any{uuid.New(), "otp", entity.OtpKindHOTP, secret, int32(6), int32(30)}})
+			return otp, nil
+		}).
+		Times(1)
+	repoOtp.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, otp *entity.Otp, scan func(domain.Scanner)) (*entity.Otp, error) {
+			scan(&stubValuesScanner{values: []any{int64(3)}})
+			return otp, nil
+		}).
+		Times(1)
+	service := getTestOtpService(nil, nil, repoOtp, getTestNotesUpkUtil(), nil)
+	resp, err := service.Code(getTestSubjectContext(), &pb.OtpRequest{User: &pb.User{PersonalKey: "test"}, Name: "otp"})
+	assert.Nil(t, err)
+	assert.Equal(t, "969429", resp.Code)
+}
+
+func testOtpServiceConsumePositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dftOtpCode := NewMockDft[*entity.OtpCode](ctrl)
+	var consumed *entity.OtpCode
+	dftOtpCode.
+		EXPECT().
+		DoUpsert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, code *entity.OtpCode, scan func(domain.Scanner)) error {
+			consumed = code
+			scan(&stubValuesScanner{values: []any{uuid.New()}})
+			return nil
+		}).
+		Times(1)
+	service := getTestOtpService(nil, dftOtpCode, nil, getTestNotesUpkUtil(), nil)
+	resp, err := service.Consume(getTestSubjectContext(), &pb.OtpConsumeRequest{User: &pb.User{PersonalKey: "test"}, Name: "otp", Code: "12345678"})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.Status)
+	assert.Equal(t, tool.OtpCodeHash("12345678"), consumed.CodeHash())
+}
+
+func testOtpServiceConsumeNegative0(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	dftOtpCode := NewMockDft[*entity.OtpCode](ctrl)
+	dftOtpCode.
+		EXPECT().
+		DoUpsert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *entity.OtpCode, scan func(domain.Scanner)) error {
+			scan(&stubValuesScanner{err: pgx.ErrNoRows})
+			return nil
+		}).
+		Times(1)
+	dftOtpCode.
+		EXPECT().
+		DoUpsert(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(ErrRequestNil).
+		Times(1)
+	service := getTestOtpService(nil, dftOtpCode, nil, getTestNotesUpkUtil(), nil)
+	request := &pb.OtpConsumeRequest{User: &pb.User{PersonalKey: "test"}, Name: "otp", Code: "12345678"}
+	resp, err := service.Consume(getTestSubjectContext(), request)
+	assert.Equal(t, ErrOtpCodeUsed, err)
+	assert.Equal(t, pb.Status_FAIL, resp.Status)
+	_, err = service.Consume(getTestSubjectContext(), request)
+	assert.Equal(t, ErrRequestNil, err)
+}
+
+func testOtpServiceNegative1(t *testing.T) {
+	service := getTestOtpService(nil, nil, nil, getTestNotesUpkUtil(), nil)
+	_, err := service.Add(context.TODO(), nil)
+	assert.Equal(t, ErrRequestNil, err)
+	_, err = service.Code(context.TODO(), nil)
+	assert.Equal(t, ErrRequestNil, err)
+	_, err = service.Consume(context.TODO(), nil)
+	assert.Equal(t, ErrRequestNil, err)
+	_, err = service.Add(getTestSubjectContext(), &pb.OtpSecretRequest{})
+	assert.Equal(t, ErrOtpSecretNil, err)
+	_, err = service.Add(getTestSubjectContext(), &pb.OtpSecretRequest{Secret: &pb.OtpSecret{}, CodesCount: otpCodesMax + 1})
+	assert.Equal(t, ErrOtpCodesCount, err)
+}
+
+func testOtpServiceAddNegative2(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userLookup := NewMockUserSearchService(ctrl)
+	userLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(false).
+		Times(1)
+	service := getTestOtpService(nil, nil, nil, getTestNotesUpkUtil(), userLookup)
+	resp, err := service.Add(getTestSubjectContext(), &pb.OtpSecretRequest{Secret: &pb.OtpSecret{User: &pb.User{PersonalKey: "test"}}})
+	assert.NotNil(t, err)
+	assert.Equal(t, pb.Status_FAIL, resp.Status)
+}
+
+type stubValuesScanner struct {
+	err    error
+	values []any
+}
+
+func (s *stubValuesScanner) Scan(dest ...any) error {
+
+	if s.err != nil {
+		return s.err
+	}
+	for i, value := range s.values {
+		if i < len(dest) && value != nil {
+			reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+		}
+	}
+	return nil
+}
+
+func getTestOtpSecret(t *testing.T, key []byte) string {
+	encrypted, err := getTestNotesUpkUtil().EncryptGCM(key)
+	assert.Nil(t, err)
+	return base64.StdEncoding.EncodeToString(encrypted)
+}
+
+func getTestOtpService(
+	dftOtp domain.Dft[*entity.Otp],
+	dftOtpCode domain.Dft[*entity.OtpCode],
+	repoOtp domain.Repo[*entity.Otp],
+	upkUtil UpkUtilService,
+	userLookup UserSearchService,
+) OtpService {
+	otpServ = new(otpService)
+	otpServ.dftOtp = dftOtp
+	otpServ.dftOtpCode = dftOtpCode
+	otpServ.repoOtp = repoOtp
+	otpServ.sLog = slog.Default()
+	otpServ.upkMigrator = getTestUpkMigrator(upkUtil)
+	otpServ.upkUtil = upkUtil
+	otpServ.userLookup = userLookup
+	return otpServ
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
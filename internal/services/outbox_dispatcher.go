@@ -0,0 +1,315 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * outbox_dispatcher.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	outboxDefaultBackoff      = 500 * time.Millisecond
+	outboxDefaultBackoffMax   = time.Minute
+	outboxDefaultBatchSize    = 100
+	outboxDefaultMaxAttempts  = 10
+	outboxDefaultPollInterval = time.Second
+	// outboxLease время на которое захваченное событие не выдаётся повторно,
+	// если диспетчер упал не отметив результат доставки.
+	outboxLease = 30 * time.Second
+)
+
+var (
+	ErrOutboxAggregate = fmt.Errorf("unknown outbox aggregate")
+	ErrOutboxDisabled  = fmt.Errorf("outbox dispatcher is disabled")
+)
+
+// OutboxDispatcher доставка событий outbox из PostgreSQL в MongoDB
+// с повторами, экспоненциальной задержкой и dead-letter.
+type OutboxDispatcher interface {
+	// Dispatch один проход: захват и доставка пачки событий, возвращает количество захваченных.
+	Dispatch(ctx context.Context) (int, error)
+	// Notify разбудить диспетчер после фиксации транзакции с событием, не блокирует.
+	Notify()
+	// Run цикл доставки до отмены ctx.
+	Run(ctx context.Context)
+	// Status состояние диспетчера и очереди для операторов.
+	Status(ctx context.Context) (OutboxStatus, error)
+}
+
+// OutboxStatus состояние диспетчера outbox и отставание репликации.
+type OutboxStatus struct {
+	Enabled         bool       `json:"enabled"`
+	Running         bool       `json:"running"`
+	Pending         int64      `json:"pending"`
+	Dead            int64      `json:"dead"`
+	LagSeconds      float64    `json:"lag_seconds"`
+	OldestPending   *time.Time `json:"oldest_pending,omitempty"`
+	Delivered       uint64     `json:"delivered"`
+	Failed          uint64     `json:"failed"`
+	DeadLettered    uint64     `json:"dead_lettered"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty"`
+}
+
+type outboxDispatcher struct {
+	backoff       time.Duration
+	backoffMax    time.Duration
+	batchSize     int
+	dbFavorites   domain.Repo[*entity.Favorites]
+	enabled       bool
+	maxAttempts   int
+	mongo         mongo.Mongo
+	mu            sync.Mutex
+	notify        chan struct{}
+	pollInterval  time.Duration
+	repoFavorites domain.Repo[*entity.Favorites]
+	repoOutbox    domain.Repo[*entity.OutboxEvent]
+	running       atomic.Bool
+	sLog          *slog.Logger
+	stats         OutboxStatus
+}
+
+var _ OutboxDispatcher = (*outboxDispatcher)(nil)
+var (
+	onceOutbox = new(sync.Once)
+	outboxServ *outboxDispatcher
+)
+
+// GetOutboxDispatcher — потокобезопасное (thread-safe) создание
+// диспетчера доставки событий outbox в MongoDB.
+func GetOutboxDispatcher(prop env.Properties) OutboxDispatcher {
+
+	onceOutbox.Do(func() {
+		outboxServ = new(outboxDispatcher)
+		outboxServ.backoff = durationOrDefault(prop.Config().OutboxBackoffMs(), outboxDefaultBackoff)
+		outboxServ.backoffMax = durationOrDefault(prop.Config().OutboxBackoffMaxMs(), outboxDefaultBackoffMax)
+		outboxServ.batchSize = intOrDefault(prop.Config().OutboxBatchSize(), outboxDefaultBatchSize)
+		outboxServ.dbFavorites = repo.GetFavoritesPostgresRepo(prop)
+		outboxServ.enabled = prop.Config().OutboxEnabled() && prop.DBPool() != nil
+		outboxServ.maxAttempts = intOrDefault(prop.Config().OutboxMaxAttempts(), outboxDefaultMaxAttempts)
+		outboxServ.mongo = mongo.GetStore(prop)
+		outboxServ.notify = make(chan struct{}, 1)
+		outboxServ.pollInterval = durationOrDefault(prop.Config().OutboxPollIntervalMs(), outboxDefaultPollInterval)
+		outboxServ.repoFavorites = repo.GetFavoritesPostgresCachedRepo(prop)
+		outboxServ.repoOutbox = repo.GetOutboxPostgresRepo(prop)
+		outboxServ.sLog = prop.Logger()
+	})
+	return outboxServ
+}
+
+func (o *outboxDispatcher) Dispatch(ctx context.Context) (int, error) {
+
+	events, err := entity.ClaimOutboxEvents(ctx, o.repoOutbox, o.batchSize, time.Now().Add(outboxLease))
+
+	if err != nil {
+		o.sLog.ErrorContext(ctx, env.MSG+"OutboxDispatcher.Dispatch", "msg", "outbox claim", "err", err)
+		return 0, err
+	}
+	for _, event := range events {
+		if er0 := o.deliver(ctx, event); er0 != nil {
+			o.failed(ctx, event, er0)
+		} else {
+			o.delivered(ctx, event)
+		}
+	}
+	return len(events), nil
+}
+
+func (o *outboxDispatcher) Notify() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (o *outboxDispatcher) Run(ctx context.Context) {
+
+	if !o.enabled || !o.running.CompareAndSwap(false, true) {
+		return
+	}
+	defer o.running.Store(false)
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		o.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.notify:
+		}
+	}
+}
+
+func (o *outboxDispatcher) Status(ctx context.Context) (OutboxStatus, error) {
+
+	o.mu.Lock()
+	result := o.stats
+	o.mu.Unlock()
+
+	result.Enabled = o.enabled
+	result.Running = o.running.Load()
+	backlog, err := entity.GetOutboxBacklog(ctx, o.repoOutbox)
+
+	if err != nil {
+		return result, err
+	}
+	result.Pending = backlog.Pending
+	result.Dead = backlog.Dead
+
+	if backlog.OldestPending.Valid {
+		oldest := backlog.OldestPending.Time
+		result.OldestPending = &oldest
+		result.LagSeconds = time.Since(oldest).Seconds()
+	}
+	return result, nil
+}
+
+// backoffFor задержка перед попыткой attempts+1: backoff * 2^(attempts-1), не более backoffMax.
+func (o *outboxDispatcher) backoffFor(attempts int) time.Duration {
+
+	delay := o.backoff
+
+	for i := 1; i < attempts && delay < o.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > o.backoffMax {
+		return o.backoffMax
+	}
+	return delay
+}
+
+// drain доставка пачками пока пачка выбирается полностью — в очереди могут быть ещё события.
+func (o *outboxDispatcher) drain(ctx context.Context) {
+	for {
+		n, err := o.Dispatch(ctx)
+		if err != nil || n < o.batchSize {
+			return
+		}
+	}
+}
+
+// CheckOutbox проверка диспетчера при старте: изменения избранного пишутся в PostgreSQL
+// только событиями outbox, поэтому при подключённой базе диспетчер не выключается.
+func CheckOutbox(prop env.Properties) error {
+
+	if prop.DBPool() != nil && !prop.Config().OutboxEnabled() {
+		return fmt.Errorf("%w: favorites are not replicated to MongoDB, set outbox.enabled", ErrOutboxDisabled)
+	}
+	return nil
+}
+
+// deliver перенос текущего состояния записи в MongoDB и подтверждение (ack) в PostgreSQL:
+// версия записи в обоих хранилищах становится равной версии пользователя.
+// Запись читается минуя кэш, чтобы в MongoDB не ушло устаревшее состояние.
+func (o *outboxDispatcher) deliver(ctx context.Context, event entity.OutboxEvent) error {
+
+	if event.Aggregate() != entity.OutboxAggregateFavorites {
+		return fmt.Errorf("%w: %s", ErrOutboxAggregate, event.Aggregate())
+	}
+	favorites, err := entity.GetFavorites(ctx, o.dbFavorites, event.Isin(), event.Upk())
+
+	if tool.NoRowsInResultSet(err) {
+		at := entity.MakeAssetType("", entity.DefaultTAttributes())
+		as := entity.MakeAsset(event.Isin(), at, entity.DefaultTAttributes())
+		us := entity.MakeUser(event.Upk(), entity.DefaultTAttributes())
+		return o.mongo.Delete(ctx, entity.MakeFavorites(uuid.Nil, as, us, sql.NullInt64{}, entity.DefaultTAttributes()))
+	}
+	if err != nil {
+		return err
+	}
+	a := entity.MakeTAttributes(favorites.Deleted(), favorites.CreatedAt(), favorites.UpdatedAt())
+	v := sql.NullInt64{Int64: favorites.User().Version(), Valid: true}
+	replica := entity.
+		MakeFavorites(favorites.ID(), favorites.Asset(), favorites.User(), v, a).
+		WithMetadata(favorites.Metadata()).
+		WithProvenance(favorites.Provenance())
+
+	if favorites.Deleted().Bool {
+		err = o.mongo.Delete(ctx, replica)
+	} else {
+		err = o.mongo.Save(ctx, replica)
+	}
+	if err != nil {
+		return err
+	}
+	return replica.Update(ctx, o.repoFavorites)
+}
+
+func (o *outboxDispatcher) delivered(ctx context.Context, event entity.OutboxEvent) {
+
+	if err := event.Delivered(ctx, o.repoOutbox); err != nil {
+		o.sLog.ErrorContext(ctx, env.MSG+"OutboxDispatcher.delivered", "msg", "outbox delete", "id", event.ID(), "err", err)
+	}
+	now := time.Now()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stats.Delivered++
+	o.stats.LastDeliveredAt = &now
+}
+
+func (o *outboxDispatcher) failed(ctx context.Context, event entity.OutboxEvent, cause error) {
+
+	dead := event.Attempts() >= o.maxAttempts
+	next := time.Now().Add(o.backoffFor(event.Attempts()))
+	o.sLog.WarnContext(ctx,
+		env.MSG+"OutboxDispatcher.failed",
+		"msg", "outbox delivery failed",
+		"id", event.ID(),
+		"attempts", event.Attempts(),
+		"dead", dead,
+		"err", cause,
+	)
+	if err := event.Failed(ctx, o.repoOutbox, cause, next, dead); err != nil {
+		o.sLog.ErrorContext(ctx, env.MSG+"OutboxDispatcher.failed", "msg", "outbox update", "id", event.ID(), "err", err)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stats.Failed++
+	o.stats.LastError = cause.Error()
+
+	if dead {
+		o.stats.DeadLettered++
+	}
+}
+
+func durationOrDefault(ms int, def time.Duration) time.Duration {
+
+	if ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return def
+}
+
+func intOrDefault(value, def int) int {
+
+	if value > 0 {
+		return value
+	}
+	return def
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,395 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * outbox_dispatcher_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"go.uber.org/mock/gomock"
+)
+
+func TestOutboxDispatcher(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive OutboxDispatcher Dispatch upsert delivered",
+			fRun: testOutboxDispatcherDeliveredUpsert,
+		},
+		{
+			name: "test #1 positive OutboxDispatcher Dispatch delete delivered",
+			fRun: testOutboxDispatcherDeliveredDelete,
+		},
+		{
+			name: "test #2 negative OutboxDispatcher Dispatch retry with backoff",
+			fRun: testOutboxDispatcherRetry,
+		},
+		{
+			name: "test #3 negative OutboxDispatcher Dispatch dead-letter",
+			fRun: testOutboxDispatcherDeadLetter,
+		},
+		{
+			name: "test #4 negative OutboxDispatcher Dispatch claim error",
+			fRun: testOutboxDispatcherClaimError,
+		},
+		{
+			name: "test #5 positive OutboxDispatcher backoff",
+			fRun: testOutboxDispatcherBackoff,
+		},
+		{
+			name: "test #6 positive OutboxDispatcher Status",
+			fRun: testOutboxDispatcherStatus,
+		},
+		{
+			name: "test #7 positive OutboxDispatcher Notify Run",
+			fRun: testOutboxDispatcherNotifyRun,
+		},
+		{
+			name: "test #8 negative CheckOutbox disabled with database",
+			fRun: testCheckOutbox,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testOutboxDispatcherDeliveredUpsert(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoOutbox := NewMockRepo[*entity.OutboxEvent](ctrl)
+	expectOutboxClaim(repoOutbox, entity.OutboxOpUpsert, 1)
+	expectOutboxFavorites(repoFavorites, false)
+	mockMongo.
+		EXPECT().
+		Save(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, favorites entity.Favorites) error {
+			assert.Equal(t, "isin", favorites.Asset().Isin())
+			assert.Equal(t, int64(7), favorites.Version().Int64)
+			return nil
+		}).
+		Times(1)
+	repoFavorites.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+	repoOutbox.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+	dispatcher := getTestOutboxDispatcher(mockMongo, repoFavorites, repoOutbox)
+	n, err := dispatcher.Dispatch(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, uint64(1), dispatcher.stats.Delivered)
+	assert.NotNil(t, dispatcher.stats.LastDeliveredAt)
+}
+
+func testOutboxDispatcherDeliveredDelete(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoOutbox := NewMockRepo[*entity.OutboxEvent](ctrl)
+	expectOutboxClaim(repoOutbox, entity.OutboxOpDelete, 1)
+	expectOutboxFavorites(repoFavorites, true)
+	mockMongo.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(1)
+	repoFavorites.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+	repoOutbox.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, nil).
+		Times(1)
+	dispatcher := getTestOutboxDispatcher(mockMongo, repoFavorites, repoOutbox)
+	n, err := dispatcher.Dispatch(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, uint64(1), dispatcher.stats.Delivered)
+}
+
+func testOutboxDispatcherRetry(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoOutbox := NewMockRepo[*entity.OutboxEvent](ctrl)
+	expectOutboxClaim(repoOutbox, entity.OutboxOpUpsert, 2)
+	expectOutboxFavorites(repoFavorites, false)
+	mockMongo.
+		EXPECT().
+		Save(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("mongo unavailable")).
+		Times(1)
+	repoOutbox.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, event *entity.OutboxEvent, _ func(domain.Scanner)) (*entity.OutboxEvent, error) {
+			assert.Equal(t, entity.OutboxStatusPending, event.Status())
+			assert.Equal(t, "mongo unavailable", event.LastError().String)
+			assert.WithinDuration(t, time.Now().Add(2*time.Second), event.NextAttemptAt(), time.Second)
+			return event, nil
+		}).
+		Times(1)
+	dispatcher := getTestOutboxDispatcher(mockMongo, repoFavorites, repoOutbox)
+	n, err := dispatcher.Dispatch(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, uint64(1), dispatcher.stats.Failed)
+	assert.Equal(t, uint64(0), dispatcher.stats.DeadLettered)
+	assert.Equal(t, "mongo unavailable", dispatcher.stats.LastError)
+}
+
+func testOutboxDispatcherDeadLetter(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoOutbox := NewMockRepo[*entity.OutboxEvent](ctrl)
+	repoOutbox.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			_ *entity.OutboxEvent,
+			scan func(domain.Scanner) *entity.OutboxEvent,
+		) ([]*entity.OutboxEvent, error) {
+			e := scan(&stubValuesScanner{values: []any{int64(1), "unknown", entity.OutboxOpUpsert, "isin", "upk", entity.OutboxStatusPending, 3}})
+			return []*entity.OutboxEvent{e}, nil
+		}).
+		Times(1)
+	repoOutbox.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, event *entity.OutboxEvent, _ func(domain.Scanner)) (*entity.OutboxEvent, error) {
+			assert.Equal(t, entity.OutboxStatusDead, event.Status())
+			assert.Contains(t, event.LastError().String, ErrOutboxAggregate.Error())
+			return event, nil
+		}).
+		Times(1)
+	dispatcher := getTestOutboxDispatcher(mockMongo, repoFavorites, repoOutbox)
+	n, err := dispatcher.Dispatch(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, uint64(1), dispatcher.stats.DeadLettered)
+}
+
+func testOutboxDispatcherClaimError(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	repoOutbox := NewMockRepo[*entity.OutboxEvent](ctrl)
+	repoOutbox.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("test")).
+		Times(1)
+	dispatcher := getTestOutboxDispatcher(nil, nil, repoOutbox)
+	n, err := dispatcher.Dispatch(context.TODO())
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func testOutboxDispatcherBackoff(t *testing.T) {
+
+	dispatcher := getTestOutboxDispatcher(nil, nil, nil)
+	assert.Equal(t, time.Second, dispatcher.backoffFor(1))
+	assert.Equal(t, 2*time.Second, dispatcher.backoffFor(2))
+	assert.Equal(t, 8*time.Second, dispatcher.backoffFor(4))
+	assert.Equal(t, 10*time.Second, dispatcher.backoffFor(5))
+	assert.Equal(t, 10*time.Second, dispatcher.backoffFor(100))
+}
+
+func testOutboxDispatcherStatus(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	repoOutbox := NewMockRepo[*entity.OutboxEvent](ctrl)
+	oldest := time.Now().Add(-time.Minute)
+	repoOutbox.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, event *entity.OutboxEvent, scan func(domain.Scanner)) (*entity.OutboxEvent, error) {
+			scan(&stubValuesScanner{values: []any{int64(5), int64(1), sql.NullTime{Time: oldest, Valid: true}}})
+			return event, nil
+		}).
+		Times(1)
+	dispatcher := getTestOutboxDispatcher(nil, nil, repoOutbox)
+	dispatcher.stats.Delivered = 3
+	status, err := dispatcher.Status(context.TODO())
+	assert.Nil(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, int64(5), status.Pending)
+	assert.Equal(t, int64(1), status.Dead)
+	assert.Equal(t, uint64(3), status.Delivered)
+	assert.GreaterOrEqual(t, status.LagSeconds, 60.0)
+}
+
+func testOutboxDispatcherNotifyRun(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	repoOutbox := NewMockRepo[*entity.OutboxEvent](ctrl)
+	repoOutbox.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*entity.OutboxEvent{}, nil).
+		MinTimes(2)
+	dispatcher := getTestOutboxDispatcher(nil, nil, repoOutbox)
+	dispatcher.pollInterval = time.Hour
+	dispatcher.Notify()
+	dispatcher.Notify() // не блокирует при уже ожидающем уведомлении
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return len(dispatcher.notify) == 0 }, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+	assert.False(t, dispatcher.running.Load())
+
+	dispatcher.enabled = false
+	dispatcher.Run(context.Background()) // выключенный диспетчер сразу возвращает управление
+}
+
+func expectOutboxClaim(repoOutbox *MockRepo[*entity.OutboxEvent], op string, attempts int) {
+	repoOutbox.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			_ *entity.OutboxEvent,
+			scan func(domain.Scanner) *entity.OutboxEvent,
+		) ([]*entity.OutboxEvent, error) {
+			e := scan(&stubValuesScanner{values: []any{
+				int64(1), entity.OutboxAggregateFavorites, op, "isin", "upk", entity.OutboxStatusPending, attempts,
+			}})
+			return []*entity.OutboxEvent{e}, nil
+		}).
+		Times(1)
+}
+
+func expectOutboxFavorites(repoFavorites *MockRepo[*entity.Favorites], deleted bool) {
+	repoFavorites.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, favorites *entity.Favorites, scan func(domain.Scanner)) (*entity.Favorites, error) {
+			values := make([]any, 19)
+			values[0] = uuid.New()
+			values[3] = sql.NullBool{Bool: deleted, Valid: true}
+			values[15] = int64(7)
+			scan(&stubValuesScanner{values: values})
+			return favorites, nil
+		}).
+		Times(1)
+}
+
+func testCheckOutbox(t *testing.T) {
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	prop := env.GetProperties()
+	disabled := false
+	assert.Nil(t, CheckOutbox(stubOutboxProperties{Properties: prop, pool: &pgxpool.Pool{}}))
+	assert.Nil(t, CheckOutbox(stubOutboxProperties{Properties: prop, enabled: &disabled}))
+	err := CheckOutbox(stubOutboxProperties{Properties: prop, enabled: &disabled, pool: &pgxpool.Pool{}})
+	assert.ErrorIs(t, err, ErrOutboxDisabled)
+}
+
+type stubOutboxConfig struct {
+	env.Config
+	enabled *bool
+}
+
+func (c stubOutboxConfig) OutboxEnabled() bool {
+	if c.enabled != nil {
+		return *c.enabled
+	}
+	return c.Config.OutboxEnabled()
+}
+
+type stubOutboxProperties struct {
+	env.Properties
+	enabled *bool
+	pool    *pgxpool.Pool
+}
+
+func (p stubOutboxProperties) Config() env.Config {
+	return stubOutboxConfig{Config: p.Properties.Config(), enabled: p.enabled}
+}
+
+func (p stubOutboxProperties) DBPool() *pgxpool.Pool {
+	return p.pool
+}
+
+func getTestOutboxDispatcher(
+	mongo mongo.Mongo,
+	repoFavorites domain.Repo[*entity.Favorites],
+	repoOutbox domain.Repo[*entity.OutboxEvent],
+) *outboxDispatcher {
+	result := new(outboxDispatcher)
+	result.backoff = time.Second
+	result.backoffMax = 10 * time.Second
+	result.batchSize = 10
+	result.dbFavorites = repoFavorites
+	result.enabled = true
+	result.maxAttempts = 3
+	result.mongo = mongo
+	result.notify = make(chan struct{}, 1)
+	result.pollInterval = time.Second
+	result.repoFavorites = repoFavorites
+	result.repoOutbox = repoOutbox
+	result.sLog = slog.Default()
+	return result
+}
+
+type stubOutboxDispatcher struct{}
+
+func (s *stubOutboxDispatcher) Dispatch(_ context.Context) (int, error) {
+	return 0, nil
+}
+
+func (s *stubOutboxDispatcher) Notify() {}
+
+func (s *stubOutboxDispatcher) Run(_ context.Context) {}
+
+func (s *stubOutboxDispatcher) Status(_ context.Context) (OutboxStatus, error) {
+	return OutboxStatus{}, nil
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
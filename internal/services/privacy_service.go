@@ -0,0 +1,341 @@
+/*
+ * This file was last modified at 2024-08-18 08:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * privacy_service.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+// PrivacyService выгрузка всех данных пользователя и удаление их по запросу пользователя,
+// консолидирует gRPC и HTTP (endpoints) конечные точки.
+//
+// Удаление выполняется с текущим и всеми прежними UPK пользователя: сначала
+// документы хранилища синхронизации, общего для шардов, затем строки PostgreSQL
+// этого шарда одним оператором вместе с записью аудита, затем записи кэшей.
+// Документы хранилища синхронизации удаляются повторно после удаления строк
+// PostgreSQL: за это время их мог записать обработчик outbox.
+// Аудит и журнал не содержат ни персонального ключа, ни UPK пользователя.
+type PrivacyService interface {
+	ApiPrivacyService
+	pb.PrivacyServiceServer
+}
+
+type privacyService struct {
+	pb.UnimplementedPrivacyServiceServer
+	evictUser            func(upks ...string) int
+	legacy               bool
+	mongo                mongo.Mongo
+	mongoEnabled         bool
+	notes                mongo.Notes
+	notesEnabled         bool
+	repoErasure          domain.Repo[*entity.UserErasure]
+	repoFavorites        domain.Repo[*entity.Favorites]
+	repoFavoritesDeleted domain.Repo[*entity.FavoritesDeleted]
+	repoNote             domain.Repo[*entity.Note]
+	repoOtp              domain.Repo[*entity.Otp]
+	repoUser             domain.Repo[*entity.User]
+	sLog                 *slog.Logger
+	upkMigrator          UpkMigrator
+	upkUtil              UpkUtilService
+}
+
+var _ PrivacyService = (*privacyService)(nil)
+var (
+	ErrSyncStoreErase = fmt.Errorf("sync store erase failed")
+	oncePrivacy       = new(sync.Once)
+	privacyServ       *privacyService
+)
+
+// GetPrivacyService — потокобезопасное (thread-safe) создание
+// сервиса выгрузки и удаления данных пользователя.
+func GetPrivacyService(prop env.Properties) PrivacyService {
+
+	oncePrivacy.Do(func() {
+		privacyServ = new(privacyService)
+		privacyServ.evictUser = func(upks ...string) int {
+			return repo.EvictUser(prop, upks...)
+		}
+		privacyServ.legacy = prop.Config().UpkLegacyMigration()
+		privacyServ.mongo = mongo.GetStore(prop)
+		privacyServ.mongoEnabled = mongo.StoreEnabled(prop)
+		privacyServ.notes = mongo.GetMongoNotesRepo(prop)
+		privacyServ.notesEnabled = prop.MongodbClient() != nil
+		privacyServ.repoErasure = repo.GetUserErasurePostgresRepo(prop)
+		privacyServ.repoFavorites = repo.GetFavoritesPostgresCachedRepo(prop)
+		privacyServ.repoFavoritesDeleted = repo.GetFavoritesDeletedPostgresRepo(prop)
+		privacyServ.repoNote = repo.GetNotePostgresCachedRepo(prop)
+		privacyServ.repoOtp = repo.GetOtpPostgresCachedRepo(prop)
+		privacyServ.repoUser = repo.GetUserPostgresRepo(prop)
+		privacyServ.sLog = prop.Logger()
+		privacyServ.upkMigrator = GetUpkMigrator(prop)
+		privacyServ.upkUtil = GetUpkUtilService(prop)
+	})
+	return privacyServ
+}
+
+// ApiPrivacyErase удаление всех данных пользователя (API для HTTP).
+func (p *privacyService) ApiPrivacyErase(ctx context.Context, user models.User) (models.Erasure, error) {
+	defer tool.TraceInOut(ctx, "ApiPrivacyErase", "")()
+	return p.erase(ctx, user)
+}
+
+// ApiPrivacyExport выгрузка всех данных пользователя (API для HTTP).
+func (p *privacyService) ApiPrivacyExport(ctx context.Context, user models.User) (models.UserData, error) {
+	defer tool.TraceInOut(ctx, "ApiPrivacyExport", "")()
+	return p.export(ctx, user)
+}
+
+// Erase удаление всех данных пользователя.
+func (p *privacyService) Erase(ctx context.Context, request *pb.PrivacyRequest) (*pb.EraseResponse, error) {
+
+	var response pb.EraseResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	user, err := subjectUser(ctx, p.upkUtil, models.UserFromProto(request.GetUser()))
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	erasure, err := p.erase(ctx, user)
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+	} else {
+		response.Erasure = erasure.ToProto()
+		response.Status = pb.Status_OK
+	}
+	return &response, err
+}
+
+// Export выгрузка всех данных пользователя.
+func (p *privacyService) Export(ctx context.Context, request *pb.PrivacyRequest) (*pb.ExportResponse, error) {
+
+	var response pb.ExportResponse
+
+	if request == nil {
+		response.Status = pb.Status_FAIL
+		return &response, ErrRequestNil
+	}
+	user, err := subjectUser(ctx, p.upkUtil, models.UserFromProto(request.GetUser()))
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+		return &response, err
+	}
+	ctx = context.WithValue(ctx, "requestId", uuid.New())
+	data, err := p.export(ctx, user)
+
+	if err != nil {
+		response.Status = pb.Status_FAIL
+	} else {
+		response.Data = data.ToProto()
+		response.Status = pb.Status_OK
+	}
+	return &response, err
+}
+
+func (p *privacyService) erase(ctx context.Context, model models.User) (models.Erasure, error) {
+
+	upk, err := p.upk(ctx, model)
+
+	if err != nil {
+		return models.Erasure{}, err
+	}
+	previous, err := p.upkUtil.PreviousPersonalKeys(model.PersonalKey(), p.legacy)
+
+	if err != nil {
+		p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.erase", "msg", "previous upk", "err", err)
+		return models.Erasure{}, err
+	}
+	upks := append([]string{upk}, previous...)
+	syncedFavorites, syncedNotes, err := p.eraseSynced(ctx, upks)
+
+	if err != nil {
+		p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.erase", "msg", "privacy service erase sync store", "err", err)
+		return models.Erasure{}, fmt.Errorf("%w: %v", ErrSyncStoreErase, err)
+	}
+	erasure := entity.MakeUserErasure(upks, p.upkUtil.KeyVersion(), syncedFavorites, syncedNotes)
+
+	if err = erasure.Erase(ctx, p.repoErasure); err != nil {
+		p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.erase", "msg", "privacy service erase Postgres", "err", err)
+		return models.Erasure{}, err
+	}
+	p.upkMigrator.Forget(upk)
+	evicted := p.evictUser(upks...)
+
+	if _, _, err = p.eraseSynced(ctx, upks); err != nil {
+		p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.erase", "msg", "privacy service repeat erase sync store", "err", err)
+	}
+	p.sLog.InfoContext(ctx, env.MSG+"PrivacyService.erase", "audit", erasure.ID(), "evicted", evicted)
+
+	return models.ErasureFromEntity(erasure), nil
+}
+
+// eraseSynced удаление документов пользователя с UPK upks из хранилища синхронизации.
+func (p *privacyService) eraseSynced(ctx context.Context, upks []string) (favorites, notes int64, err error) {
+
+	for _, upk := range upks {
+		if p.mongoEnabled {
+			deleted, err := p.mongo.Erase(ctx, upk)
+			if err != nil {
+				return favorites, notes, err
+			}
+			favorites += deleted
+		}
+		if p.notesEnabled {
+			deleted, err := p.notes.Erase(ctx, upk)
+			if err != nil {
+				return favorites, notes, err
+			}
+			notes += deleted
+		}
+	}
+	return favorites, notes, nil
+}
+
+func (p *privacyService) export(ctx context.Context, model models.User) (models.UserData, error) {
+
+	upk, err := p.upk(ctx, model)
+
+	if err != nil {
+		return models.UserData{}, err
+	}
+	user, err := entity.GetUser(ctx, p.repoUser, upk)
+
+	if err != nil && !tool.NoRowsInResultSet(err) {
+		p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.export", "msg", "privacy service get user", "err", err)
+		return models.UserData{}, err
+	}
+	result := models.MakeUserData(models.MakeUser(model.PersonalKey(), upk), user.Version(), user.CreatedAt())
+	favorites, err := p.exportFavorites(ctx, upk)
+
+	if err != nil {
+		p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.export", "msg", "privacy service get favorites", "err", err)
+		return models.UserData{}, err
+	}
+	notes, err := p.exportNotes(ctx, upk)
+
+	if err != nil {
+		p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.export", "msg", "privacy service get notes", "err", err)
+		return models.UserData{}, err
+	}
+	otps, err := entity.GetOtpsForUser(ctx, p.repoOtp, upk)
+
+	if err != nil {
+		p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.export", "msg", "privacy service get otp", "err", err)
+		return models.UserData{}, err
+	}
+	otpSecrets := make([]models.OtpSecret, 0, len(otps))
+
+	for _, otp := range otps {
+		otpSecrets = append(otpSecrets, models.OtpSecretFromEntity(otp))
+	}
+	synced := make([]models.Favorites, 0)
+
+	if p.mongoEnabled {
+		stored, err := p.mongo.Load(ctx, upk)
+		if err != nil {
+			p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.export", "msg", "privacy service load sync store", "err", err)
+			return models.UserData{}, err
+		}
+		for _, favorite := range stored {
+			synced = append(synced, models.FavoritesFromEntity(favorite))
+		}
+	}
+	return result.
+		WithFavorites(favorites).
+		WithSyncedFavorites(synced).
+		WithNotes(notes).
+		WithOtpSecrets(otpSecrets), nil
+}
+
+// exportFavorites избранное пользователя вместе с удалёнными записями (надгробиями).
+func (p *privacyService) exportFavorites(ctx context.Context, upk string) ([]models.Favorites, error) {
+
+	live, err := entity.GetFavoritesForUser(ctx, p.repoFavorites, upk)
+
+	if err != nil {
+		return nil, err
+	}
+	tombstones, err := entity.GetFavoritesTombstonesForUser(ctx, p.repoFavoritesDeleted, upk)
+
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Favorites, 0, len(live)+len(tombstones))
+
+	for _, favorite := range live {
+		result = append(result, models.FavoritesFromEntity(favorite))
+	}
+	for _, tombstone := range tombstones {
+		result = append(result, models.FavoritesFromEntity(*tombstone.ToFavorites()))
+	}
+	return result, nil
+}
+
+// exportNotes расшифрованные заметки пользователя.
+func (p *privacyService) exportNotes(ctx context.Context, upk string) ([]models.Note, error) {
+
+	notes, err := entity.GetNotesForUser(ctx, p.repoNote, upk)
+
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Note, 0, len(notes))
+
+	for _, note := range notes {
+		bytes, err := base64.StdEncoding.DecodeString(note.Body())
+		if err == nil {
+			bytes, err = p.upkUtil.DecryptGCM(bytes)
+		}
+		if err != nil {
+			p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.exportNotes", "msg", "privacy service decrypt", "err", err)
+			return nil, tool.ErrDecryptGCM
+		}
+		result = append(result, models.NoteFromEntity(note).WithBody(string(bytes)))
+	}
+	return result, nil
+}
+
+// upk текущий UPK пользователя, строки пользователя с прежним UPK при этом переносятся.
+func (p *privacyService) upk(ctx context.Context, model models.User) (string, error) {
+
+	upk, err := p.upkMigrator.Migrate(ctx, model.PersonalKey())
+
+	if err != nil {
+		p.sLog.ErrorContext(ctx, env.MSG+"PrivacyService.upk", "msg", "privacy service encrypt", "err", err)
+		return "", tool.ErrEncryptAES
+	}
+	return upk, nil
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,277 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * privacy_service_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"go.uber.org/mock/gomock"
+
+	pb "github.com/vskurikhin/gofavorites/proto"
+)
+
+func TestPrivacyService(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive Privacy Service Erase with previous UPK",
+			fRun: testPrivacyServiceErasePositive,
+		},
+		{
+			name: "test #1 positive Privacy Service Export",
+			fRun: testPrivacyServiceExportPositive,
+		},
+		{
+			name: "test #2 negative #0 Privacy Service nil requests and foreign user",
+			fRun: testPrivacyServiceNegative0,
+		},
+		{
+			name: "test #3 negative #1 Privacy Service Erase sync store fails",
+			fRun: testPrivacyServiceEraseNegative1,
+		},
+		{
+			name: "test #4 negative #2 Privacy Service Export broken note",
+			fRun: testPrivacyServiceExportNegative2,
+		},
+	}
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testPrivacyServiceErasePositive(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	service := getTestPrivacyService(ctrl, getTestUpkUtilServiceKeyring(tool.NewKeyring(
+		tool.KeyVersion{Version: 2, Key: []byte("01234567890123456789012345678901")},
+		tool.KeyVersion{Version: 1, Key: make([]byte, 32)},
+	)))
+	previous := getTestUpkUtilServiceKeyring(tool.NewKeyring(tool.KeyVersion{Version: 1, Key: make([]byte, 32)}))
+	upk, _ := service.upkUtil.EncryptPersonalKey("test")
+	from, _ := previous.EncryptPersonalKey("test")
+	var evicted []string
+	service.evictUser = func(upks ...string) int {
+		evicted = upks
+		return len(upks)
+	}
+	service.upkMigrator.(*upkMigrator).migrated.Store(upk, struct{}{})
+	for _, key := range []string{upk, from} {
+		service.mongo.(*MockMongo).
+			EXPECT().
+			Erase(gomock.Any(), key).
+			Return(int64(1), nil).
+			Times(2)
+		service.notes.(*MockNotes).
+			EXPECT().
+			Erase(gomock.Any(), key).
+			Return(int64(2), nil).
+			Times(2)
+	}
+	erasedAt := time.Now()
+	service.repoErasure.(*MockRepo[*entity.UserErasure]).
+		EXPECT().
+		Insert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, e *entity.UserErasure, scan func(domain.Scanner)) (*entity.UserErasure, error) {
+			args := e.InsertArgs()
+			assert.Equal(t, []string{upk, from}, args[0])
+			assert.Equal(t, 2, args[1])
+			assert.Equal(t, int64(2), args[2])
+			assert.Equal(t, int64(4), args[3])
+			scan(&stubValuesScanner{values: []any{
+				int64(7), 2, int64(1), int64(3), int64(1), int64(1), int64(0), int64(2), int64(4), erasedAt,
+			}})
+			return e, nil
+		}).
+		Times(1)
+	resp, err := service.Erase(getTestSubjectContext(), &pb.PrivacyRequest{User: &pb.User{PersonalKey: "test"}})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+	assert.Equal(t, int64(7), resp.GetErasure().GetId())
+	assert.Equal(t, int64(3), resp.GetErasure().GetFavorites())
+	assert.Equal(t, int64(4), resp.GetErasure().GetSyncedNotes())
+	assert.Equal(t, erasedAt.UnixMilli(), resp.GetErasure().GetErasedAt())
+	assert.Equal(t, []string{upk, from}, evicted)
+	_, ok := service.upkMigrator.(*upkMigrator).migrated.Load(upk)
+	assert.False(t, ok)
+}
+
+func testPrivacyServiceExportPositive(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	service := getTestPrivacyService(ctrl, getTestNotesUpkUtil())
+	upk, _ := service.upkUtil.EncryptPersonalKey("test")
+	encrypted, err := service.upkUtil.EncryptGCM([]byte("secret text"))
+	assert.Nil(t, err)
+	body := base64.StdEncoding.EncodeToString(encrypted)
+	createdAt := time.Now()
+	service.repoUser.(*MockRepo[*entity.User]).
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user *entity.User, scan func(domain.Scanner)) (*entity.User, error) {
+			scan(&stubValuesScanner{values: []any{upk, int64(3), nil, createdAt}})
+			return user, nil
+		}).
+		Times(1)
+	service.repoFavorites.(*MockRepo[*entity.Favorites]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*entity.Favorites{}, nil).
+		Times(1)
+	service.repoFavoritesDeleted.(*MockRepo[*entity.FavoritesDeleted]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*entity.FavoritesDeleted{}, nil).
+		Times(1)
+	service.repoNote.(*MockRepo[*entity.Note]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, note *entity.Note, scan func(domain.Scanner) *entity.Note) ([]*entity.Note, error) {
+			return []*entity.Note{scan(&stubValuesScanner{values: []any{uuid.New(), "note", body}})}, nil
+		}).
+		Times(1)
+	service.repoOtp.(*MockRepo[*entity.Otp]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, otp *entity.Otp, scan func(domain.Scanner) *entity.Otp) ([]*entity.Otp, error) {
+			return []*entity.Otp{scan(&stubValuesScanner{values: []any{uuid.New(), "otp", entity.OtpKindTOTP, "sealed", int32(6), int32(30)}})}, nil
+		}).
+		Times(1)
+	service.mongo.(*MockMongo).
+		EXPECT().
+		Load(gomock.Any(), upk).
+		Return([]entity.Favorites{}, nil).
+		Times(1)
+	resp, err := service.Export(getTestSubjectContext(), &pb.PrivacyRequest{User: &pb.User{PersonalKey: "test"}})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.Status_OK, resp.GetStatus())
+	assert.Equal(t, upk, resp.GetData().GetUser().GetUpk())
+	assert.Equal(t, int64(3), resp.GetData().GetVersion())
+	assert.Equal(t, createdAt.UnixMilli(), resp.GetData().GetCreatedAt())
+	assert.Equal(t, "secret text", resp.GetData().GetNotes()[0].GetBody())
+	assert.Equal(t, "otp", resp.GetData().GetOtpSecrets()[0].GetName())
+	assert.NotContains(t, resp.GetData().String(), "sealed")
+}
+
+func testPrivacyServiceNegative0(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	service := getTestPrivacyService(ctrl, getTestNotesUpkUtil())
+	resp0, err := service.Erase(context.TODO(), nil)
+	assert.Equal(t, ErrRequestNil, err)
+	assert.Equal(t, pb.Status_FAIL, resp0.GetStatus())
+	resp1, err := service.Export(context.TODO(), nil)
+	assert.Equal(t, ErrRequestNil, err)
+	assert.Equal(t, pb.Status_FAIL, resp1.GetStatus())
+	resp2, err := service.Erase(getTestSubjectContext(), &pb.PrivacyRequest{User: &pb.User{PersonalKey: "other"}})
+	assert.Equal(t, ErrPermissionDenied, err)
+	assert.Equal(t, pb.Status_FAIL, resp2.GetStatus())
+	resp3, err := service.Export(getTestSubjectContext(), &pb.PrivacyRequest{User: &pb.User{PersonalKey: "other"}})
+	assert.Equal(t, ErrPermissionDenied, err)
+	assert.Equal(t, pb.Status_FAIL, resp3.GetStatus())
+}
+
+func testPrivacyServiceEraseNegative1(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	service := getTestPrivacyService(ctrl, getTestNotesUpkUtil())
+	service.mongo.(*MockMongo).
+		EXPECT().
+		Erase(gomock.Any(), gomock.Any()).
+		Return(int64(0), fmt.Errorf("mongo unavailable")).
+		Times(1)
+	_, err := service.ApiPrivacyErase(context.TODO(), models.MakeUser("test", ""))
+	assert.ErrorIs(t, err, ErrSyncStoreErase)
+}
+
+func testPrivacyServiceExportNegative2(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	service := getTestPrivacyService(ctrl, getTestNotesUpkUtil())
+	service.repoUser.(*MockRepo[*entity.User]).
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, pgx.ErrNoRows).
+		Times(1)
+	service.repoFavorites.(*MockRepo[*entity.Favorites]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, repo.ErrBadPool).
+		Times(1)
+	_, err := service.ApiPrivacyExport(context.TODO(), models.MakeUser("test", ""))
+	assert.Equal(t, repo.ErrBadPool, err)
+	service.repoUser.(*MockRepo[*entity.User]).
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, pgx.ErrNoRows).
+		Times(1)
+	service.repoFavorites.(*MockRepo[*entity.Favorites]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*entity.Favorites{}, nil).
+		Times(1)
+	service.repoFavoritesDeleted.(*MockRepo[*entity.FavoritesDeleted]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]*entity.FavoritesDeleted{}, nil).
+		Times(1)
+	service.repoNote.(*MockRepo[*entity.Note]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, note *entity.Note, scan func(domain.Scanner) *entity.Note) ([]*entity.Note, error) {
+			return []*entity.Note{scan(&stubValuesScanner{values: []any{uuid.New(), "note", "plain text"}})}, nil
+		}).
+		Times(1)
+	_, err = service.ApiPrivacyExport(context.TODO(), models.MakeUser("test", ""))
+	assert.Equal(t, tool.ErrDecryptGCM, err)
+}
+
+func getTestPrivacyService(ctrl *gomock.Controller, upkUtil UpkUtilService) *privacyService {
+	result := new(privacyService)
+	result.evictUser = func(upks ...string) int { return 0 }
+	result.mongo = NewMockMongo(ctrl)
+	result.mongoEnabled = true
+	result.notes = NewMockNotes(ctrl)
+	result.notesEnabled = true
+	result.repoErasure = NewMockRepo[*entity.UserErasure](ctrl)
+	result.repoFavorites = NewMockRepo[*entity.Favorites](ctrl)
+	result.repoFavoritesDeleted = NewMockRepo[*entity.FavoritesDeleted](ctrl)
+	result.repoNote = NewMockRepo[*entity.Note](ctrl)
+	result.repoOtp = NewMockRepo[*entity.Otp](ctrl)
+	result.repoUser = NewMockRepo[*entity.User](ctrl)
+	result.sLog = slog.Default()
+	result.upkMigrator = getTestUpkMigrator(upkUtil)
+	result.upkUtil = upkUtil
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
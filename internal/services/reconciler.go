@@ -0,0 +1,333 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * reconciler.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	reconcileDefaultConcurrency = 4
+	reconcileDefaultPageSize    = 100
+	// reconcileMaxDrifts количество пользователей с расхождениями сохраняемых в отчёте,
+	// счётчики отчёта учитывают всех.
+	reconcileMaxDrifts = 1000
+)
+
+var (
+	ErrReconcileNotSubmitted = fmt.Errorf("reconciliation repair is not submitted")
+	ErrReconcileRunning      = fmt.Errorf("reconciliation is already running")
+)
+
+// Reconciler фоновая сверка избранного всех пользователей PostgreSQL с MongoDB
+// по тем же правилам слияния, что и SyncUtilService, с исправлением расхождений
+// или без него (dry-run). Пользователи известные только MongoDB не сверяются —
+// они синхронизируются при обращении через SyncUtilService.
+type Reconciler interface {
+	// Reconcile один проход сверки по всем пользователям.
+	Reconcile(ctx context.Context) (ReconcileReport, error)
+	// Report отчёт последнего завершённого прохода.
+	Report() ReconcileReport
+	// Run периодическая сверка до отмены ctx.
+	Run(ctx context.Context)
+}
+
+// ReconcileReport отчёт о расхождениях избранного между PostgreSQL и MongoDB.
+type ReconcileReport struct {
+	DryRun           bool        `json:"dry_run"`
+	StartedAt        *time.Time  `json:"started_at,omitempty"`
+	FinishedAt       *time.Time  `json:"finished_at,omitempty"`
+	Users            int         `json:"users"`
+	Drifted          int         `json:"drifted"`
+	Repaired         int         `json:"repaired"`
+	Failed           int         `json:"failed"`
+	SaveMongoDB      int         `json:"save_mongodb"`
+	DeleteMongoDB    int         `json:"delete_mongodb"`
+	SavePostgreSQL   int         `json:"save_postgresql"`
+	DeletePostgreSQL int         `json:"delete_postgresql"`
+	Acknowledge      int         `json:"acknowledge"`
+	Truncated        bool        `json:"truncated"`
+	Drifts           []UserDrift `json:"drifts,omitempty"`
+}
+
+// UserDrift расхождения избранного одного пользователя: ISIN записей,
+// которые нужно изменить в каждой из баз.
+type UserDrift struct {
+	Upk              string   `json:"upk"`
+	SaveMongoDB      []string `json:"save_mongodb,omitempty"`
+	DeleteMongoDB    []string `json:"delete_mongodb,omitempty"`
+	SavePostgreSQL   []string `json:"save_postgresql,omitempty"`
+	DeletePostgreSQL []string `json:"delete_postgresql,omitempty"`
+	Acknowledge      []string `json:"acknowledge,omitempty"`
+	Error            string   `json:"error,omitempty"`
+}
+
+type reconciler struct {
+	concurrency   int
+	dryRun        bool
+	enabled       bool
+	interval      time.Duration
+	mongo         mongo.Mongo
+	mu            sync.Mutex
+	pageSize      int
+	report        ReconcileReport
+	repoFavorites domain.Repo[*entity.Favorites]
+	running       atomic.Bool
+	sLog          *slog.Logger
+	sync          *syncUtilService
+	userRepo      domain.Repo[*entity.User]
+}
+
+var _ Reconciler = (*reconciler)(nil)
+var (
+	onceReconciler = new(sync.Once)
+	reconcilerServ *reconciler
+)
+
+// GetReconciler — потокобезопасное (thread-safe) создание
+// сервиса фоновой сверки избранного между PostgreSQL и MongoDB.
+func GetReconciler(prop env.Properties) Reconciler {
+
+	onceReconciler.Do(func() {
+		reconcilerServ = new(reconciler)
+		reconcilerServ.concurrency = intOrDefault(prop.Config().ReconcileConcurrency(), reconcileDefaultConcurrency)
+		reconcilerServ.dryRun = prop.Config().ReconcileDryRun()
+		reconcilerServ.interval = time.Duration(prop.Config().ReconcileIntervalSec()) * time.Second
+		reconcilerServ.mongo = mongo.GetStore(prop)
+		reconcilerServ.pageSize = intOrDefault(prop.Config().ReconcilePageSize(), reconcileDefaultPageSize)
+		reconcilerServ.repoFavorites = repo.GetFavoritesPostgresCachedRepo(prop)
+		reconcilerServ.sLog = prop.Logger()
+		reconcilerServ.sync = GetSyncUtilService(prop).(*syncUtilService)
+		reconcilerServ.userRepo = repo.GetUserPostgresRepo(prop)
+		reconcilerServ.enabled = prop.Config().ReconcileEnabled() &&
+			reconcilerServ.interval > 0 &&
+			prop.DBPool() != nil &&
+			mongo.StoreEnabled(prop)
+	})
+	return reconcilerServ
+}
+
+func (r *reconciler) Reconcile(ctx context.Context) (ReconcileReport, error) {
+
+	if !r.running.CompareAndSwap(false, true) {
+		return ReconcileReport{}, ErrReconcileRunning
+	}
+	defer r.running.Store(false)
+
+	started := time.Now()
+	report := ReconcileReport{DryRun: r.dryRun, StartedAt: &started}
+	var mu sync.Mutex
+
+	for after := ""; ; {
+		users, err := entity.GetUsersPage(ctx, r.userRepo, after, r.pageSize)
+
+		if err != nil {
+			r.sLog.ErrorContext(ctx, env.MSG+"Reconciler.Reconcile", "msg", "users page", "after", after, "err", err)
+			return report, err
+		}
+		g, gCtx := errgroup.WithContext(ctx)
+		g.SetLimit(r.concurrency)
+
+		for _, user := range users {
+			g.Go(func() error {
+				drift := r.reconcileUser(gCtx, user)
+				mu.Lock()
+				defer mu.Unlock()
+				report.add(drift, r.dryRun)
+				return nil
+			})
+		}
+		_ = g.Wait()
+
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+		if len(users) < r.pageSize {
+			break
+		}
+		after = users[len(users)-1].Upk()
+	}
+	finished := time.Now()
+	report.FinishedAt = &finished
+	r.sLog.InfoContext(ctx, env.MSG+"Reconciler.Reconcile",
+		"dryRun", report.DryRun,
+		"users", report.Users,
+		"drifted", report.Drifted,
+		"repaired", report.Repaired,
+		"failed", report.Failed,
+		"duration", finished.Sub(started),
+	)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report = report
+
+	return report, nil
+}
+
+func (r *reconciler) Report() ReconcileReport {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.report
+}
+
+func (r *reconciler) Run(ctx context.Context) {
+
+	if !r.enabled {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = r.Reconcile(ctx)
+		}
+	}
+}
+
+// reconcileUser сверка избранного одного пользователя и, если это не dry-run, исправление
+// расхождений теми же изменениями, что выполнил бы SyncUtilService.
+func (r *reconciler) reconcileUser(ctx context.Context, user entity.User) UserDrift {
+
+	drift := UserDrift{Upk: user.Upk()}
+	merged, _, err := r.merge(ctx, user.Upk())
+
+	if err != nil {
+		drift.Error = err.Error()
+		return drift
+	}
+	drift.SaveMongoDB = favoritesIsins(merged.saveMongoDB)
+	drift.DeleteMongoDB = favoritesIsins(merged.deleteMongoDB)
+	drift.SavePostgreSQL = favoritesIsins(merged.savePostgreSQL)
+	drift.DeletePostgreSQL = favoritesIsins(merged.deletePostgreSQL)
+	drift.Acknowledge = favoritesIsins(merged.acknowledge)
+
+	if r.dryRun || !drift.drifted() {
+		return drift
+	}
+	// исправление выполняется в очереди синхронизации пользователя, как и у SyncUtilService,
+	// и сливает записи заново, чтобы не затереть изменения сделанные после сверки.
+	submitted := r.sync.executor.Submit("favorites:"+user.Upk(), func(ctx context.Context) {
+		r.repair(ctx, user.Upk())
+	})
+	if !submitted {
+		drift.Error = ErrReconcileNotSubmitted.Error()
+	}
+	return drift
+}
+
+// merge слияние избранного пользователя из PostgreSQL и MongoDB,
+// возвращает и все прочитанные записи.
+func (r *reconciler) merge(ctx context.Context, upk string) (favoritesMerge, []entity.Favorites, error) {
+
+	pgDBFavorites, err := entity.GetFavoritesForUser(ctx, r.repoFavorites, upk)
+
+	if err != nil {
+		return favoritesMerge{}, nil, err
+	}
+	mongodbFavorites, err := r.mongo.Load(ctx, upk)
+
+	if err != nil {
+		return favoritesMerge{}, nil, err
+	}
+	merged, err := r.sync.merge(ctx, upk, mongodbFavorites, pgDBFavorites)
+
+	return merged, slices.Concat(pgDBFavorites, mongodbFavorites), err
+}
+
+// repair исправление расхождений пользователя в задаче синхронизации.
+func (r *reconciler) repair(ctx context.Context, upk string) {
+
+	merged, favorites, err := r.merge(ctx, upk)
+
+	if err != nil {
+		r.sLog.ErrorContext(ctx, env.MSG+"Reconciler.repair", "upk", upk, "err", err)
+		return
+	}
+	if merged.hasMongoDB() {
+		r.sync.syncToMongoDB(ctx, merged)
+	}
+	if merged.hasPostgreSQL() {
+		r.sync.syncToPostgreSQL(ctx, merged, maxVersionUser(favorites))
+	}
+}
+
+// add учёт результата сверки пользователя в отчёте, в список попадают
+// пользователи с расхождениями и с ошибками сверки.
+func (r *ReconcileReport) add(drift UserDrift, dryRun bool) {
+
+	r.Users++
+
+	switch {
+	case drift.Error != "":
+		r.Failed++
+	case !drift.drifted():
+		return
+	default:
+		r.Drifted++
+		if !dryRun {
+			r.Repaired++
+		}
+		r.SaveMongoDB += len(drift.SaveMongoDB)
+		r.DeleteMongoDB += len(drift.DeleteMongoDB)
+		r.SavePostgreSQL += len(drift.SavePostgreSQL)
+		r.DeletePostgreSQL += len(drift.DeletePostgreSQL)
+		r.Acknowledge += len(drift.Acknowledge)
+	}
+
+	if len(r.Drifts) < reconcileMaxDrifts {
+		r.Drifts = append(r.Drifts, drift)
+	} else {
+		r.Truncated = true
+	}
+}
+
+func (d UserDrift) drifted() bool {
+	return len(d.SaveMongoDB) > 0 ||
+		len(d.DeleteMongoDB) > 0 ||
+		len(d.SavePostgreSQL) > 0 ||
+		len(d.DeletePostgreSQL) > 0 ||
+		len(d.Acknowledge) > 0
+}
+
+func favoritesIsins(favorites []entity.Favorites) []string {
+
+	if len(favorites) < 1 {
+		return nil
+	}
+	result := make([]string, 0, len(favorites))
+
+	for _, favorite := range favorites {
+		result = append(result, favorite.Asset().Isin())
+	}
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,365 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * reconciler_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"go.uber.org/mock/gomock"
+)
+
+func TestReconciler(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive Reconciler Reconcile dry-run",
+			fRun: testReconcilerDryRun,
+		},
+		{
+			name: "test #1 positive Reconciler Reconcile repair",
+			fRun: testReconcilerRepair,
+		},
+		{
+			name: "test #2 positive Reconciler Reconcile pages",
+			fRun: testReconcilerPages,
+		},
+		{
+			name: "test #3 negative Reconciler Reconcile users page",
+			fRun: testReconcilerUsersPageError,
+		},
+		{
+			name: "test #4 negative Reconciler Reconcile mongo load",
+			fRun: testReconcilerMongoLoadError,
+		},
+		{
+			name: "test #5 negative Reconciler Reconcile already running",
+			fRun: testReconcilerRunning,
+		},
+		{
+			name: "test #6 positive Reconciler repair and Sync of one user are serialized",
+			fRun: testReconcilerConcurrentSync,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testReconcilerDryRun(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	mockMongo.
+		EXPECT().
+		Load(gomock.Any(), "upk").
+		Return(nil, nil).
+		Times(1)
+	reconciler := getTestReconciler(
+		mockMongo,
+		expectReconcileFavorites(ctrl, "isin"),
+		expectReconcileFavoritesDeleted(ctrl),
+		expectReconcileUsers(t, ctrl, 10, "upk"),
+	)
+	reconciler.dryRun = true
+
+	got, err := reconciler.Reconcile(context.TODO())
+	assert.Nil(t, err)
+	assert.True(t, got.DryRun)
+	assert.NotNil(t, got.FinishedAt)
+	assert.Equal(t, 1, got.Users)
+	assert.Equal(t, 1, got.Drifted)
+	assert.Equal(t, 0, got.Repaired)
+	assert.Equal(t, 1, got.SaveMongoDB)
+	assert.Equal(t, []UserDrift{{Upk: "upk", SaveMongoDB: []string{"isin"}}}, got.Drifts)
+	assert.Equal(t, got, reconciler.Report())
+}
+
+func testReconcilerRepair(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	mockMongo.
+		EXPECT().
+		Load(gomock.Any(), "upk").
+		Return(nil, nil).
+		Times(2)
+	mockMongo.
+		EXPECT().
+		SaveAll(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, favorites []entity.Favorites) error {
+			assert.Len(t, favorites, 1)
+			assert.Equal(t, "isin", favorites[0].Asset().Isin())
+			return nil
+		}).
+		Times(1)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoFavorites.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(scanReconcileFavorites("isin", 1)).
+		Times(2)
+	reconciler := getTestReconciler(
+		mockMongo,
+		repoFavorites,
+		expectReconcileFavoritesDeleted(ctrl),
+		expectReconcileUsers(t, ctrl, 10, "upk"),
+	)
+	got, err := reconciler.Reconcile(context.TODO())
+	assert.Nil(t, err)
+	assert.False(t, got.DryRun)
+	assert.Equal(t, 1, got.Drifted)
+	assert.Equal(t, 1, got.Repaired)
+	// исправление выполняется в очереди синхронизации пользователя
+	assert.Nil(t, reconciler.sync.executor.Shutdown(context.TODO()))
+}
+
+func testReconcilerPages(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	mockMongo.
+		EXPECT().
+		Load(gomock.Any(), gomock.Any()).
+		Return(nil, nil).
+		Times(2)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoFavorites.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, nil).
+		Times(2)
+	repoUser := NewMockRepo[*entity.User](ctrl)
+	gomock.InOrder(
+		repoUser.
+			EXPECT().
+			GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, filter *entity.User, scan func(domain.Scanner) *entity.User) ([]*entity.User, error) {
+				assert.Equal(t, []any{"", 1}, filter.GetByFilterArgs())
+				scan(&stubValuesScanner{values: []any{"upk1", int64(1)}})
+				return nil, nil
+			}),
+		repoUser.
+			EXPECT().
+			GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, filter *entity.User, scan func(domain.Scanner) *entity.User) ([]*entity.User, error) {
+				assert.Equal(t, []any{"upk1", 1}, filter.GetByFilterArgs())
+				scan(&stubValuesScanner{values: []any{"upk2", int64(1)}})
+				return nil, nil
+			}),
+		repoUser.
+			EXPECT().
+			GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, nil),
+	)
+	reconciler := getTestReconciler(mockMongo, repoFavorites, expectReconcileFavoritesDeleted(ctrl), repoUser)
+	reconciler.pageSize = 1
+
+	got, err := reconciler.Reconcile(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, got.Users)
+	assert.Equal(t, 0, got.Drifted)
+	assert.Empty(t, got.Drifts)
+}
+
+func testReconcilerUsersPageError(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	repoUser := NewMockRepo[*entity.User](ctrl)
+	repoUser.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, repo.ErrBadPool).
+		Times(1)
+	reconciler := getTestReconciler(nil, nil, nil, repoUser)
+
+	_, err := reconciler.Reconcile(context.TODO())
+	assert.ErrorIs(t, err, repo.ErrBadPool)
+	assert.Nil(t, reconciler.Report().StartedAt)
+}
+
+func testReconcilerMongoLoadError(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	mockMongo.
+		EXPECT().
+		Load(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("test")).
+		Times(1)
+	reconciler := getTestReconciler(
+		mockMongo,
+		expectReconcileFavorites(ctrl, "isin"),
+		nil,
+		expectReconcileUsers(t, ctrl, 10, "upk"),
+	)
+	got, err := reconciler.Reconcile(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, got.Users)
+	assert.Equal(t, 1, got.Failed)
+	assert.Equal(t, 0, got.Drifted)
+	assert.Equal(t, []UserDrift{{Upk: "upk", Error: "test"}}, got.Drifts)
+}
+
+func testReconcilerRunning(t *testing.T) {
+
+	reconciler := getTestReconciler(nil, nil, nil, nil)
+	reconciler.running.Store(true)
+
+	_, err := reconciler.Reconcile(context.TODO())
+	assert.ErrorIs(t, err, ErrReconcileRunning)
+
+	reconciler.Run(context.TODO()) // выключенная сверка сразу возвращает управление
+}
+
+func testReconcilerConcurrentSync(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	var inflight, maxInflight, saves atomic.Int32
+	mockMongo := NewMockMongo(ctrl)
+	mockMongo.
+		EXPECT().
+		Load(gomock.Any(), "upk").
+		Return(nil, nil).
+		AnyTimes()
+	mockMongo.
+		EXPECT().
+		SaveAll(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, []entity.Favorites) error {
+			n := inflight.Add(1)
+			defer inflight.Add(-1)
+			for m := maxInflight.Load(); n > m && !maxInflight.CompareAndSwap(m, n); m = maxInflight.Load() {
+			}
+			saves.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}).
+		AnyTimes()
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoFavorites.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(scanReconcileFavorites("isin", 2)).
+		AnyTimes()
+	reconciler := getTestReconciler(
+		mockMongo,
+		repoFavorites,
+		expectReconcileFavoritesDeleted(ctrl),
+		expectReconcileUsers(t, ctrl, 10, "upk"),
+	)
+	user := entity.MakeUserWithVersion("upk", 2, entity.DefaultTAttributes())
+	asset := entity.MakeAsset("isin", entity.MakeAssetType("", entity.DefaultTAttributes()), entity.DefaultTAttributes())
+	local := entity.MakeFavorites(uuid.New(), asset, user, sql.NullInt64{Int64: 2, Valid: true}, entity.DefaultTAttributes())
+	remote := entity.MakeFavorites(uuid.New(), asset, user, sql.NullInt64{Int64: 1, Valid: true}, entity.DefaultTAttributes())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := reconciler.Reconcile(context.TODO())
+		assert.Nil(t, err)
+	}()
+	go func() {
+		defer wg.Done()
+		_, err := reconciler.sync.Sync(context.TODO(), []entity.Favorites{remote}, []entity.Favorites{local})
+		assert.Nil(t, err)
+	}()
+	wg.Wait()
+	assert.Nil(t, reconciler.sync.executor.Shutdown(context.TODO()))
+	assert.Positive(t, saves.Load())
+	assert.Equal(t, int32(1), maxInflight.Load(), "записи одного пользователя не выполняются параллельно")
+}
+
+func expectReconcileFavorites(ctrl *gomock.Controller, isin string) domain.Repo[*entity.Favorites] {
+
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoFavorites.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(scanReconcileFavorites(isin, 1)).
+		Times(1)
+	return repoFavorites
+}
+
+func scanReconcileFavorites(
+	isin string, version int64,
+) func(context.Context, *entity.Favorites, func(domain.Scanner) *entity.Favorites) ([]*entity.Favorites, error) {
+	return func(_ context.Context, _ *entity.Favorites, scan func(domain.Scanner) *entity.Favorites) ([]*entity.Favorites, error) {
+		scan(&stubValuesScanner{values: []any{
+			uuid.New(), sql.NullInt64{Int64: version, Valid: true}, nil, nil, nil, nil, isin,
+		}})
+		return nil, nil
+	}
+}
+
+func expectReconcileFavoritesDeleted(ctrl *gomock.Controller) domain.Repo[*entity.FavoritesDeleted] {
+
+	repoFavoritesDeleted := NewMockRepo[*entity.FavoritesDeleted](ctrl)
+	repoFavoritesDeleted.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(make([]*entity.FavoritesDeleted, 0), nil).
+		AnyTimes()
+	return repoFavoritesDeleted
+}
+
+func expectReconcileUsers(t *testing.T, ctrl *gomock.Controller, pageSize int, upk string) domain.Repo[*entity.User] {
+
+	repoUser := NewMockRepo[*entity.User](ctrl)
+	repoUser.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, filter *entity.User, scan func(domain.Scanner) *entity.User) ([]*entity.User, error) {
+			assert.Equal(t, []any{"", pageSize}, filter.GetByFilterArgs())
+			scan(&stubValuesScanner{values: []any{upk, int64(1)}})
+			return nil, nil
+		}).
+		Times(1)
+	return repoUser
+}
+
+func getTestReconciler(
+	mongo mongo.Mongo,
+	repoFavorites domain.Repo[*entity.Favorites],
+	repoFavoritesDeleted domain.Repo[*entity.FavoritesDeleted],
+	userRepo domain.Repo[*entity.User],
+) *reconciler {
+	result := new(reconciler)
+	result.concurrency = 2
+	result.mongo = mongo
+	result.pageSize = 10
+	result.repoFavorites = repoFavorites
+	result.sLog = slog.Default()
+	result.sync = getTestSyncUtilService(nil, nil, mongo, repoFavorites, repoFavoritesDeleted, nil, userRepo).(*syncUtilService)
+	result.userRepo = userRepo
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
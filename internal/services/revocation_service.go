@@ -0,0 +1,121 @@
+/*
+ * This file was last modified at 2024-08-15 14:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * revocation_service.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/memory"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+var (
+	revocationNotRevoked = []byte{0}
+	revocationRevoked    = []byte{1}
+)
+
+// RevocationService список отозванных JWT по утверждению «jti».
+// Результаты проверок кэшируются в памяти: отозванный токен до истечения
+// его срока действия, не отозванный — на время cache.expire_ms.
+type RevocationService interface {
+	IsRevoked(ctx context.Context, jti string) bool
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+}
+
+type revocationService struct {
+	cache       *memory.Storage
+	exp         time.Duration
+	repoRevoked domain.Repo[*entity.RevokedToken]
+	sLog        *slog.Logger
+}
+
+var _ RevocationService = (*revocationService)(nil)
+var (
+	onceRevocation = new(sync.Once)
+	revocationServ *revocationService
+)
+
+// GetRevocationService — потокобезопасное (thread-safe) создание
+// сервиса списка отозванных JWT.
+func GetRevocationService(prop env.Properties) RevocationService {
+
+	onceRevocation.Do(func() {
+		revocationServ = new(revocationService)
+		revocationServ.cache = memory.New(memory.Config{GCInterval: prop.CacheGCInterval()})
+		revocationServ.exp = prop.CacheExpire()
+		revocationServ.repoRevoked = repo.GetRevokedTokenPostgresRepo(prop)
+		revocationServ.sLog = prop.Logger()
+	})
+	return revocationServ
+}
+
+// IsRevoked проверка отзыва токена, токены выпущенные без «jti» отозвать нельзя.
+// Если база данных не настроена список пуст, при прочих ошибках токен
+// считается отозванным.
+func (r *revocationService) IsRevoked(ctx context.Context, jti string) bool {
+
+	if jti == "" {
+		return false
+	}
+	if data, err := r.cache.Get(jti); err == nil && len(data) > 0 {
+		return data[0] == revocationRevoked[0]
+	}
+	revoked, err := entity.GetRevokedToken(ctx, r.repoRevoked, jti)
+
+	switch {
+	case errors.Is(err, repo.ErrBadPool):
+		return false
+	case tool.NoRowsInResultSet(err) || err == nil && revoked.JTI() == "":
+		_ = r.cache.Set(jti, revocationNotRevoked, r.exp)
+		return false
+	case err != nil:
+		r.sLog.ErrorContext(ctx, env.MSG+"RevocationService.IsRevoked", "msg", "revocation service get", "err", err)
+		return true
+	}
+	r.setRevoked(jti, revoked.ExpiresAt())
+
+	return time.Now().UTC().Before(revoked.ExpiresAt())
+}
+
+// Revoke отзыв токена до истечения срока его действия.
+func (r *revocationService) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+
+	if jti == "" {
+		return nil
+	}
+	revoked := entity.MakeRevokedToken(jti, expiresAt.UTC())
+
+	if err := revoked.Insert(ctx, r.repoRevoked); err != nil {
+		r.sLog.ErrorContext(ctx, env.MSG+"RevocationService.Revoke", "msg", "revocation service insert", "err", err)
+		return err
+	}
+	r.setRevoked(jti, revoked.ExpiresAt())
+
+	return nil
+}
+
+func (r *revocationService) setRevoked(jti string, expiresAt time.Time) {
+
+	if ttl := time.Until(expiresAt); ttl > 0 {
+		_ = r.cache.Set(jti, revocationRevoked, ttl)
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
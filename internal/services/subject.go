@@ -0,0 +1,49 @@
+/*
+ * This file was last modified at 2024-08-14 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * subject.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+
+	"github.com/vskurikhin/gofavorites/internal/jwt"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrPermissionDenied = status.Error(codes.PermissionDenied, "user does not match authenticated subject")
+
+// subjectUser пользователь запроса gRPC, сверенный с субъектом проверенного токена.
+// Пустые personal_key и upk заполняются из субъекта, несовпадение любого из них отклоняется.
+func subjectUser(ctx context.Context, upkUtil UpkUtilService, user models.User) (models.User, error) {
+
+	subject, ok := jwt.SubjectFromContext(ctx)
+
+	if !ok || subject == "" {
+		return models.User{}, ErrPermissionDenied
+	}
+	if user.PersonalKey() != "" && user.PersonalKey() != subject {
+		return models.User{}, ErrPermissionDenied
+	}
+	if user.Upk() != "" {
+		upk, err := upkUtil.EncryptPersonalKey(subject)
+		if err != nil {
+			return models.User{}, err
+		}
+		if upk != user.Upk() {
+			return models.User{}, ErrPermissionDenied
+		}
+	}
+	return models.MakeUser(subject, user.Upk()), nil
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,85 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * subject_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/jwt"
+	"github.com/vskurikhin/gofavorites/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSubjectUser(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive subjectUser",
+			fRun: testSubjectUserPositive,
+		},
+		{
+			name: "test #1 negative subjectUser",
+			fRun: testSubjectUserNegative,
+		},
+	}
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testSubjectUserPositive(t *testing.T) {
+	upkUtil := getTestNotesUpkUtil()
+	upk, err := upkUtil.EncryptPersonalKey("test")
+	assert.Nil(t, err)
+	for _, user := range []models.User{
+		models.MakeUser("", ""),
+		models.MakeUser("test", ""),
+		models.MakeUser("", upk),
+		models.MakeUser("test", upk),
+	} {
+		got, err := subjectUser(getTestSubjectContext(), upkUtil, user)
+		assert.Nil(t, err)
+		assert.Equal(t, "test", got.PersonalKey())
+		assert.Equal(t, user.Upk(), got.Upk())
+	}
+}
+
+func testSubjectUserNegative(t *testing.T) {
+	upkUtil := getTestNotesUpkUtil()
+	upk, err := upkUtil.EncryptPersonalKey("other")
+	assert.Nil(t, err)
+	_, err = subjectUser(context.TODO(), upkUtil, models.MakeUser("test", ""))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = subjectUser(jwt.WithSubject(context.TODO(), ""), upkUtil, models.MakeUser("", ""))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = subjectUser(getTestSubjectContext(), upkUtil, models.MakeUser("other", ""))
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = subjectUser(getTestSubjectContext(), upkUtil, models.MakeUser("", upk))
+	assert.Equal(t, ErrPermissionDenied, err)
+}
+
+func getTestSubjectContext() context.Context {
+	return jwt.WithSubject(context.TODO(), "test")
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,210 @@
+/*
+ * This file was last modified at 2024-08-17 23:30 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * sync_executor.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/env"
+)
+
+const (
+	syncDefaultDrainTimeout = 10 * time.Second
+	syncDefaultQueueSize    = 1024
+	syncDefaultWorkers      = 4
+)
+
+var ErrSyncExecutorClosed = fmt.Errorf("sync executor is closed")
+
+// SyncTask задача фоновой синхронизации, ctx — контекст жизненного цикла исполнителя,
+// а не запроса пользователя.
+type SyncTask func(ctx context.Context)
+
+// SyncExecutor исполнитель задач фоновой синхронизации с ограниченной очередью.
+// Задачи с одним ключом (пользователем) выполняются последовательно, ещё не начатая
+// задача заменяется новой с тем же ключом — её изменения вычислены по более свежим данным.
+type SyncExecutor interface {
+	// Submit постановка задачи в очередь, false если очередь переполнена или исполнитель остановлен.
+	Submit(key string, task SyncTask) bool
+	// Shutdown прекращение приёма задач и ожидание выполнения поставленных до отмены ctx,
+	// после чего отменяется контекст выполняемых задач.
+	Shutdown(ctx context.Context) error
+}
+
+type syncExecutor struct {
+	cancel   context.CancelFunc
+	closed   bool
+	ctx      context.Context
+	drained  chan struct{}
+	inflight int
+	mu       sync.Mutex
+	pending  map[string]SyncTask
+	queue    chan string
+	queueCap int
+	running  map[string]bool
+	sLog     *slog.Logger
+	workers  sync.WaitGroup
+}
+
+var _ SyncExecutor = (*syncExecutor)(nil)
+var (
+	onceSyncExecutor = new(sync.Once)
+	syncExecutorServ *syncExecutor
+)
+
+// GetSyncExecutor — потокобезопасное (thread-safe) создание
+// исполнителя задач фоновой синхронизации MongoDB и PostgreSQL.
+func GetSyncExecutor(prop env.Properties) SyncExecutor {
+
+	onceSyncExecutor.Do(func() {
+		syncExecutorServ = newSyncExecutor(
+			intOrDefault(prop.Config().SyncWorkers(), syncDefaultWorkers),
+			intOrDefault(prop.Config().SyncQueueSize(), syncDefaultQueueSize),
+			prop.Logger(),
+		)
+	})
+	return syncExecutorServ
+}
+
+// SyncDrainTimeout время на выполнение оставшихся задач синхронизации при выключении сервиса.
+func SyncDrainTimeout(prop env.Properties) time.Duration {
+	return durationOrDefault(prop.Config().SyncDrainTimeoutMs(), syncDefaultDrainTimeout)
+}
+
+func newSyncExecutor(workers, queueSize int, sLog *slog.Logger) *syncExecutor {
+
+	result := new(syncExecutor)
+	result.ctx, result.cancel = context.WithCancel(context.Background())
+	result.drained = make(chan struct{})
+	result.pending = make(map[string]SyncTask)
+	// ключ попадает в канал не более одного раза, поэтому очередь
+	// ограничивается количеством ожидающих ключей, а не ёмкостью канала.
+	result.queue = make(chan string, queueSize)
+	result.queueCap = queueSize
+	result.running = make(map[string]bool)
+	result.sLog = sLog
+
+	for i := 0; i < workers; i++ {
+		result.workers.Add(1)
+		go result.work()
+	}
+	return result
+}
+
+func (e *syncExecutor) Submit(key string, task SyncTask) bool {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return false
+	}
+	if _, ok := e.pending[key]; ok {
+		e.pending[key] = task
+		return true
+	}
+	if len(e.pending) >= e.queueCap {
+		e.sLog.Warn(env.MSG+"SyncExecutor.Submit", "msg", "sync queue is full", "key", key)
+		return false
+	}
+	e.pending[key] = task
+	e.inflight++
+
+	if !e.running[key] {
+		e.queue <- key
+	}
+	return true
+}
+
+func (e *syncExecutor) Shutdown(ctx context.Context) error {
+
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		e.drainedIfIdle()
+	}
+	e.mu.Unlock()
+
+	var err error
+	select {
+	case <-e.drained:
+	case <-ctx.Done():
+		e.mu.Lock()
+		e.sLog.Warn(env.MSG+"SyncExecutor.Shutdown", "msg", "sync tasks dropped", "inflight", e.inflight)
+		e.mu.Unlock()
+		err = ctx.Err()
+	}
+	e.cancel()
+	e.workers.Wait()
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSyncExecutorClosed, err)
+	}
+	return nil
+}
+
+func (e *syncExecutor) work() {
+
+	defer e.workers.Done()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case key := <-e.queue:
+			e.execute(key)
+		}
+	}
+}
+
+func (e *syncExecutor) execute(key string) {
+
+	e.mu.Lock()
+	task := e.pending[key]
+	delete(e.pending, key)
+	e.running[key] = true
+	e.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			e.sLog.Error(env.MSG+"SyncExecutor.execute", "msg", "sync task panic", "key", key, "panic", r)
+		}
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.running, key)
+		e.inflight--
+
+		if _, ok := e.pending[key]; ok {
+			e.queue <- key
+		}
+		e.drainedIfIdle()
+	}()
+	task(e.ctx)
+}
+
+// drainedIfIdle сигнал Shutdown о выполнении всех задач, вызывается под e.mu.
+func (e *syncExecutor) drainedIfIdle() {
+
+	if e.closed && e.inflight == 0 {
+		select {
+		case <-e.drained:
+		default:
+			close(e.drained)
+		}
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
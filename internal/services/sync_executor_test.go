@@ -0,0 +1,134 @@
+/*
+ * This file was last modified at 2024-08-17 23:30 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * sync_executor_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncExecutor(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive SyncExecutor serialisation and deduplication per key",
+			fRun: testSyncExecutorPerKey,
+		},
+		{
+			name: "test #1 positive SyncExecutor Shutdown drains queue",
+			fRun: testSyncExecutorDrain,
+		},
+		{
+			name: "test #2 negative SyncExecutor queue is full and Shutdown timeout",
+			fRun: testSyncExecutorQueueFull,
+		},
+		{
+			name: "test #3 negative SyncExecutor task panic",
+			fRun: testSyncExecutorPanic,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testSyncExecutorPerKey(t *testing.T) {
+
+	executor := newSyncExecutor(4, 8, slog.Default())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var ran []int
+	var active atomic.Int32
+
+	task := func(n int) SyncTask {
+		return func(_ context.Context) {
+			assert.Equal(t, int32(1), active.Add(1), "задачи одного ключа не выполняются параллельно")
+			if n == 1 {
+				close(started)
+				<-release
+			}
+			mu.Lock()
+			ran = append(ran, n)
+			mu.Unlock()
+			active.Add(-1)
+		}
+	}
+	assert.True(t, executor.Submit("upk", task(1)))
+	<-started
+	assert.True(t, executor.Submit("upk", task(2)))
+	assert.True(t, executor.Submit("upk", task(3)))
+	close(release)
+
+	assert.Nil(t, executor.Shutdown(context.Background()))
+	assert.Equal(t, []int{1, 3}, ran)
+	assert.False(t, executor.Submit("upk", task(4)))
+}
+
+func testSyncExecutorDrain(t *testing.T) {
+
+	executor := newSyncExecutor(1, 8, slog.Default())
+	var count atomic.Int32
+	var ctxErr atomic.Value
+
+	for _, key := range []string{"a", "b", "c"} {
+		assert.True(t, executor.Submit(key, func(ctx context.Context) {
+			time.Sleep(time.Millisecond)
+			if ctx.Err() != nil {
+				ctxErr.Store(ctx.Err())
+			}
+			count.Add(1)
+		}))
+	}
+	assert.Nil(t, executor.Shutdown(context.Background()))
+	assert.Equal(t, int32(3), count.Load())
+	assert.Nil(t, ctxErr.Load())
+}
+
+func testSyncExecutorQueueFull(t *testing.T) {
+
+	executor := newSyncExecutor(0, 1, slog.Default())
+	assert.True(t, executor.Submit("a", func(_ context.Context) {}))
+	assert.True(t, executor.Submit("a", func(_ context.Context) {}))
+	assert.False(t, executor.Submit("b", func(_ context.Context) {}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := executor.Shutdown(ctx)
+	assert.ErrorIs(t, err, ErrSyncExecutorClosed)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func testSyncExecutorPanic(t *testing.T) {
+
+	executor := newSyncExecutor(1, 8, slog.Default())
+	var done atomic.Bool
+
+	assert.True(t, executor.Submit("a", func(_ context.Context) { panic("test") }))
+	assert.True(t, executor.Submit("b", func(_ context.Context) { done.Store(true) }))
+	assert.Nil(t, executor.Shutdown(context.Background()))
+	assert.True(t, done.Load())
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
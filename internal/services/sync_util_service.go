@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 21:05 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 03:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * sync_util_service.go
@@ -11,6 +11,7 @@
 package services
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"log/slog"
@@ -35,9 +36,12 @@ type SyncUtilService interface {
 type syncUtilService struct {
 	assetLookup          AssetSearchService
 	batch                batch.FavoritesInsertsBatch
+	executor             SyncExecutor
 	mongo                mongo.Mongo
 	repoFavorites        domain.Repo[*entity.Favorites]
 	repoFavoritesDeleted domain.Repo[*entity.FavoritesDeleted]
+	resolver             ConflictResolver
+	shardID              string
 	sLog                 *slog.Logger
 	userLookup           UserSearchService
 	userRepo             domain.Repo[*entity.User]
@@ -57,10 +61,18 @@ func GetSyncUtilService(prop env.Properties) SyncUtilService {
 		syncUtilServ = new(syncUtilService)
 		syncUtilServ.assetLookup = GetAssetSearchService(prop)
 		syncUtilServ.batch = batch.GetBatchPostgres(prop)
-		syncUtilServ.mongo = mongo.GetMongoRepo(prop)
+		syncUtilServ.executor = GetSyncExecutor(prop)
+		syncUtilServ.mongo = mongo.GetStore(prop)
 		syncUtilServ.repoFavorites = repo.GetFavoritesPostgresCachedRepo(prop)
 		syncUtilServ.repoFavoritesDeleted = repo.GetFavoritesDeletedPostgresRepo(prop)
 		syncUtilServ.sLog = prop.Logger()
+		resolver, err := MakeConflictResolver(prop.Config().SyncConflictResolver())
+		if err != nil {
+			prop.Logger().Error(env.MSG+"GetSyncUtilService", "msg", "fallback to last-writer-wins", "err", err)
+			resolver = lastWriterWins{}
+		}
+		syncUtilServ.resolver = resolver
+		syncUtilServ.shardID = prop.Config().SyncShardID()
 		syncUtilServ.userLookup = GetUserSearchService(prop)
 		syncUtilServ.userRepo = repo.GetUserPostgresCachedRepo(prop)
 	})
@@ -68,6 +80,8 @@ func GetSyncUtilService(prop env.Properties) SyncUtilService {
 }
 
 // Sync синхронизация биржевых инструментов для пользователя между базами данных MongoDB и PostgreSQL.
+// Записи сливаются по ISIN с учётом надгробий (tombstones) обеих баз,
+// конфликт изменений одной записи в обеих базах разрешает ConflictResolver.
 func (s syncUtilService) Sync(
 	ctx context.Context,
 	mongodbFavorites, pgDBFavorites []entity.Favorites,
@@ -76,98 +90,301 @@ func (s syncUtilService) Sync(
 	if len(mongodbFavorites) < 1 {
 		return pgDBFavorites, nil
 	}
-	maxMongodbUser := slices.MaxFunc[[]entity.Favorites, entity.Favorites](mongodbFavorites, func(x, y entity.Favorites) int {
-		if x.Version().Int64 > y.Version().Int64 {
-			return 1
-		} else if x.Version().Int64 > y.Version().Int64 {
-			return -1
-		}
-		return 0
-	}).User()
+	maxMongodbUser := maxVersionUser(mongodbFavorites)
 	s.sLog.InfoContext(ctx, env.MSG+"Sync", "maxMongodb", maxMongodbUser.Version())
 
-	var maxPostgresUser entity.User
+	if len(pgDBFavorites) < 1 && !s.userLookup.Lookup(ctx, models.UserFromEntity(maxMongodbUser)) {
+		return liveFavorites(mongodbFavorites), nil
+	}
+	merged, err := s.merge(ctx, maxMongodbUser.Upk(), mongodbFavorites, pgDBFavorites)
 
-	if len(pgDBFavorites) < 1 {
-		if !s.userLookup.Lookup(ctx, models.UserFromEntity(maxMongodbUser)) {
-			return mongodbFavorites, nil
+	if err != nil {
+		s.sLog.ErrorContext(ctx, env.MSG+"Sync get favorites tombstones", "err", err)
+	}
+	if !merged.hasMongoDB() && !merged.hasPostgreSQL() {
+		return merged.result, nil
+	}
+	user := maxVersionUser(slices.Concat(pgDBFavorites, mongodbFavorites))
+	s.sLog.InfoContext(ctx, env.MSG+"Sync",
+		"saveMongoDB", len(merged.saveMongoDB),
+		"deleteMongoDB", len(merged.deleteMongoDB),
+		"acknowledge", len(merged.acknowledge),
+		"savePostgreSQL", len(merged.savePostgreSQL),
+		"deletePostgreSQL", len(merged.deletePostgreSQL),
+		"user", user.Version(),
+	)
+	s.logDecisions(ctx, user.Upk(), merged.decisions)
+	submitted := s.executor.Submit("favorites:"+user.Upk(), func(ctx context.Context) {
+		if merged.hasMongoDB() {
+			s.syncToMongoDB(ctx, merged)
 		}
-		maxPostgresUser = entity.MakeUser(maxMongodbUser.Upk(), entity.DefaultTAttributes())
-	} else {
-		maxPostgresUser = slices.
-			MaxFunc[[]entity.Favorites, entity.Favorites](pgDBFavorites, func(x, y entity.Favorites) int {
-			if x.User().Version() > y.User().Version() {
-				return 1
-			} else if x.User().Version() > y.User().Version() {
-				return -1
+		if merged.hasPostgreSQL() {
+			s.syncToPostgreSQL(ctx, merged, user)
+		}
+	})
+	if !submitted {
+		s.sLog.WarnContext(ctx, env.MSG+"Sync", "msg", "sync task is not submitted", "upk", user.Upk())
+	}
+	return merged.result, nil
+}
+
+// favoritesMerge результат слияния избранного: итоговый список и изменения для каждой из баз.
+type favoritesMerge struct {
+	result           []entity.Favorites
+	saveMongoDB      []entity.Favorites
+	deleteMongoDB    []entity.Favorites
+	savePostgreSQL   []entity.Favorites
+	deletePostgreSQL []entity.Favorites
+	// acknowledge надгробия MongoDB уже согласованные с PostgreSQL.
+	acknowledge []entity.Favorites
+	// applied надгробия MongoDB, которые будут согласованы после удаления deletePostgreSQL.
+	applied []entity.Favorites
+	// decisions решения по записям, требующим изменений, для журнала.
+	decisions []syncDecision
+}
+
+// syncDecision решение слияния по одной записи и происхождение её версий
+// в PostgreSQL (local) и MongoDB (remote).
+type syncDecision struct {
+	isin   string
+	action string
+	side   ConflictSide
+	local  entity.Provenance
+	remote entity.Provenance
+}
+
+const (
+	syncAcknowledge      = "acknowledge"
+	syncDeleteMongoDB    = "deleteMongoDB"
+	syncDeletePostgreSQL = "deletePostgreSQL"
+	syncSaveMongoDB      = "saveMongoDB"
+	syncSavePostgreSQL   = "savePostgreSQL"
+)
+
+// hasMongoDB есть изменения для MongoDB.
+func (m favoritesMerge) hasMongoDB() bool {
+	return len(m.saveMongoDB) > 0 || len(m.deleteMongoDB) > 0 || len(m.acknowledge) > 0
+}
+
+// hasPostgreSQL есть изменения для PostgreSQL.
+func (m favoritesMerge) hasPostgreSQL() bool {
+	return len(m.savePostgreSQL) > 0 || len(m.deletePostgreSQL) > 0
+}
+
+// merge слияние избранного пользователя upk из MongoDB и PostgreSQL с учётом надгробий PostgreSQL.
+// При ошибке чтения надгробий слияние выполняется без них и ошибка возвращается вместе с результатом.
+func (s *syncUtilService) merge(
+	ctx context.Context,
+	upk string,
+	mongodbFavorites, pgDBFavorites []entity.Favorites,
+) (favoritesMerge, error) {
+
+	tombstones, err := entity.GetFavoritesTombstonesForUser(ctx, s.repoFavoritesDeleted, upk)
+	local := make([]entity.Favorites, 0, len(pgDBFavorites)+len(tombstones))
+	local = append(local, pgDBFavorites...)
+
+	for _, tombstone := range tombstones {
+		local = append(local, *tombstone.ToFavorites())
+	}
+	return mergeFavorites(s.resolver, local, mongodbFavorites), err
+}
+
+// mergeFavorites слияние записей PostgreSQL (local) и MongoDB (remote) по ISIN, включая надгробия.
+// Запись есть только в одной из баз — она и побеждает, иначе выбор делает resolver.
+func mergeFavorites(resolver ConflictResolver, local, remote []entity.Favorites) favoritesMerge {
+
+	var result favoritesMerge
+	keys := make([]string, 0, len(local)+len(remote))
+	locals := make(map[string]entity.Favorites, len(local))
+	remotes := make(map[string]entity.Favorites, len(remote))
+
+	for _, favorite := range local {
+		isin := favorite.Asset().Isin()
+		if l, ok := locals[isin]; !ok {
+			keys = append(keys, isin)
+		} else if compareFavorites(l, favorite) > 0 {
+			continue
+		}
+		locals[isin] = favorite
+	}
+	for _, favorite := range remote {
+		isin := favorite.Asset().Isin()
+		if _, ok := locals[isin]; !ok {
+			if _, ok := remotes[isin]; !ok {
+				keys = append(keys, isin)
 			}
-			return 0
-		}).User()
+		}
+		if r, ok := remotes[isin]; !ok || compareFavorites(r, favorite) < 0 {
+			remotes[isin] = favorite
+		}
 	}
-	s.sLog.InfoContext(ctx, env.MSG+"Sync", "maxPostgresUser", maxPostgresUser.Version())
-	result := pgDBFavorites
+	for _, isin := range keys {
+		l, inLocal := locals[isin]
+		r, inRemote := remotes[isin]
 
-	if maxPostgresUser.Version() > maxMongodbUser.Version() {
-		s.sLog.InfoContext(ctx, env.MSG+"Sync", "maxPostgresUser", maxPostgresUser)
-		go s.syncToMongoDB(ctx, pgDBFavorites, maxPostgresUser)
-		result = pgDBFavorites
-	} else if maxPostgresUser.Version() < maxMongodbUser.Version() {
-		s.sLog.InfoContext(ctx, env.MSG+"Sync", "maxMongodb", maxMongodbUser)
-		go s.syncToPostgreSQL(ctx, mongodbFavorites, maxMongodbUser)
-		result = mongodbFavorites
+		side := ConflictLocal
+		if !inLocal || inLocal && inRemote && resolver.Resolve(l, r) == ConflictRemote {
+			side = ConflictRemote
+		}
+		decide := func(action string) {
+			result.decisions = append(result.decisions, syncDecision{
+				isin: isin, action: action, side: side, local: l.Provenance(), remote: r.Provenance(),
+			})
+		}
+		switch {
+		case side == ConflictLocal && l.Deleted().Bool:
+			if inRemote && !r.Deleted().Bool && favoritesVersion(r) > favoritesVersion(l) {
+				// удаление выбрано стратегией вопреки более новому изменению:
+				// версия поднимается чтобы надгробие заменило запись в MongoDB.
+				l = withFavoritesVersion(l, favoritesVersion(r))
+			}
+			if inRemote && !r.Deleted().Bool || !l.Version().Valid {
+				result.deleteMongoDB = append(result.deleteMongoDB, l)
+				decide(syncDeleteMongoDB)
+			} else if inRemote {
+				result.acknowledge = append(result.acknowledge, r)
+				decide(syncAcknowledge)
+			}
+		case side == ConflictLocal:
+			result.result = append(result.result, l)
+			if inRemote && r.Deleted().Bool && favoritesVersion(r) > favoritesVersion(l) {
+				// запись восстановлена стратегией вопреки более новому удалению:
+				// версия поднимается чтобы заменить надгробие в MongoDB.
+				l = withFavoritesVersion(l, favoritesVersion(r))
+			}
+			if !inRemote || !l.Version().Valid || isFavoritesDiffer(l, r) {
+				result.saveMongoDB = append(result.saveMongoDB, l)
+				decide(syncSaveMongoDB)
+			}
+		case r.Deleted().Bool:
+			if inLocal && !l.Deleted().Bool {
+				// надгробие PostgreSQL получает происхождение удаления из MongoDB.
+				result.deletePostgreSQL = append(result.deletePostgreSQL, l.WithProvenance(r.Provenance()))
+				result.applied = append(result.applied, r)
+				decide(syncDeletePostgreSQL)
+			} else {
+				result.acknowledge = append(result.acknowledge, r)
+				decide(syncAcknowledge)
+			}
+		default:
+			result.result = append(result.result, r)
+			if !inLocal || isFavoritesDiffer(l, r) {
+				result.savePostgreSQL = append(result.savePostgreSQL, r)
+				decide(syncSavePostgreSQL)
+			}
+		}
 	}
-	return result, nil
+	return result
 }
 
-func (s *syncUtilService) syncToMongoDB(ctx context.Context, favorites []entity.Favorites, user entity.User) {
+func isFavoritesDiffer(x, y entity.Favorites) bool {
+	return x.Deleted().Bool != y.Deleted().Bool ||
+		favoritesVersion(x) != favoritesVersion(y) ||
+		x.Metadata() != y.Metadata()
+}
+
+func maxVersionUser(favorites []entity.Favorites) entity.User {
+	return slices.MaxFunc[[]entity.Favorites, entity.Favorites](favorites, func(x, y entity.Favorites) int {
+		return cmp.Compare(x.User().Version(), y.User().Version())
+	}).User()
+}
+
+func liveFavorites(favorites []entity.Favorites) []entity.Favorites {
+	return slices.DeleteFunc(slices.Clone(favorites), func(f entity.Favorites) bool {
+		return f.Deleted().Bool
+	})
+}
+
+func withFavoritesVersion(f entity.Favorites, version int64) entity.Favorites {
+	a := entity.MakeTAttributes(f.Deleted(), f.CreatedAt(), f.UpdatedAt())
+	v := sql.NullInt64{Int64: version, Valid: true}
+	return entity.MakeFavorites(f.ID(), f.Asset(), f.User(), v, a).
+		WithMetadata(f.Metadata()).
+		WithProvenance(f.Provenance())
+}
+
+// logDecisions журнал решений слияния с шардами и временем записи обеих версий.
+func (s *syncUtilService) logDecisions(ctx context.Context, upk string, decisions []syncDecision) {
+	for _, d := range decisions {
+		s.sLog.DebugContext(ctx, env.MSG+"Sync decision",
+			"upk", upk,
+			"isin", d.isin,
+			"action", d.action,
+			"side", d.side,
+			"shard", s.shardID,
+			"localShard", d.local.ShardID(),
+			"localWrittenAt", d.local.WrittenAt().Time,
+			"remoteShard", d.remote.ShardID(),
+			"remoteWrittenAt", d.remote.WrittenAt().Time,
+		)
+	}
+}
 
-	for _, fav := range favorites {
+func (s *syncUtilService) syncToMongoDB(ctx context.Context, merged favoritesMerge) {
+
+	saves := make([]entity.Favorites, 0, len(merged.saveMongoDB))
+
+	for _, fav := range merged.saveMongoDB {
 
 		var f = fav
 
 		if !fav.Version().Valid {
 
-			a := entity.MakeTAttributes(fav.Deleted(), fav.CreatedAt(), fav.UpdatedAt())
-			v := sql.NullInt64{Int64: fav.User().Version(), Valid: true}
-			f = entity.MakeFavorites(fav.ID(), fav.Asset(), fav.User(), v, a)
+			f = withFavoritesVersion(fav, fav.User().Version())
 			err := f.Update(ctx, s.repoFavorites)
 
 			if err != nil {
 				s.sLog.ErrorContext(ctx, env.MSG+"syncToMongoDB", "err", err)
 			}
 		}
-		if (!f.Deleted().Valid && !f.Deleted().Bool) || !f.Deleted().Bool {
-			err := s.mongo.Save(ctx, f)
-			if err != nil {
-				s.sLog.ErrorContext(ctx, env.MSG+"syncToMongoDB save", "err", err)
-			}
-		}
+		saves = append(saves, f)
 	}
-	favoritesDeleted, err := entity.GetFavoritesDeletedForUser(ctx, s.repoFavoritesDeleted, user.Upk())
-	if err != nil {
-		s.sLog.ErrorContext(ctx, env.MSG+"syncToMongoDB get favorites deleted", "err", err)
+	if len(saves) > 0 {
+		if err := s.mongo.SaveAll(ctx, saves); err != nil {
+			s.sLog.ErrorContext(ctx, env.MSG+"syncToMongoDB save", "err", err)
+		}
 	}
-	for _, deleted := range favoritesDeleted {
-		if err := s.mongo.Delete(ctx, *deleted.ToFavorites()); err != nil {
+	if len(merged.deleteMongoDB) > 0 {
+		deletes := make([]entity.Favorites, 0, len(merged.deleteMongoDB))
+
+		for _, deleted := range merged.deleteMongoDB {
+			deletes = append(deletes, withFavoritesVersion(deleted, favoritesVersion(deleted)))
+		}
+		if err := s.mongo.DeleteAll(ctx, deletes); err != nil {
 			s.sLog.ErrorContext(ctx, env.MSG+"syncToMongoDB delete in MongoDB", "err", err)
 		} else {
-			if err := deleted.Update(ctx, s.repoFavoritesDeleted); err != nil {
-				s.sLog.ErrorContext(ctx, env.MSG+"syncToMongoDB delete in PostgreSQL", "err", err)
+			for _, deleted := range merged.deleteMongoDB {
+				if deleted.Version().Valid {
+					continue
+				}
+				if err := deleted.Update(ctx, s.repoFavorites); err != nil {
+					s.sLog.ErrorContext(ctx, env.MSG+"syncToMongoDB delete in PostgreSQL", "err", err)
+				}
 			}
 		}
 	}
+	s.acknowledge(ctx, merged.acknowledge)
 }
 
-func (s *syncUtilService) syncToPostgreSQL(ctx context.Context, favorites []entity.Favorites, user entity.User) {
+func (s *syncUtilService) syncToPostgreSQL(ctx context.Context, merged favoritesMerge, user entity.User) {
 
-	deleted := entity.MakeFavoritesDeletedUser(user.Upk())
+	applied := make([]entity.Favorites, 0, len(merged.applied))
 
-	if err := deleted.Delete(ctx, s.repoFavoritesDeleted); err != nil {
-		s.sLog.ErrorContext(ctx, env.MSG+"syncToPostgreSQL delete in PostgreSQL", "err", err)
+	for i, deleted := range merged.deletePostgreSQL {
+		if err := deleted.Tombstone(ctx, s.repoFavorites); err != nil {
+			s.sLog.ErrorContext(ctx, env.MSG+"syncToPostgreSQL delete in PostgreSQL", "err", err)
+		} else {
+			applied = append(applied, merged.applied[i])
+		}
 	}
-	batchFavorites := make([]entity.Favorites, 0, len(favorites))
+	s.acknowledge(ctx, applied)
 
-	for _, favorite := range favorites {
+	if len(merged.savePostgreSQL) < 1 {
+		return
+	}
+	batchFavorites := make([]entity.Favorites, 0, len(merged.savePostgreSQL))
+
+	for _, favorite := range merged.savePostgreSQL {
 		if s.assetLookup.Lookup(ctx, favorite.Asset().Isin()) {
 			batchFavorites = append(batchFavorites, favorite)
 			s.sLog.DebugContext(ctx, env.MSG+"syncToPostgreSQL favorite add to batchFavorites", "favorite", favorite)
@@ -176,11 +393,23 @@ func (s *syncUtilService) syncToPostgreSQL(ctx context.Context, favorites []enti
 	if err := s.batch.Do(ctx, batchFavorites, user.Upk()); err != nil {
 		s.sLog.ErrorContext(ctx, env.MSG+"syncToPostgreSQL batch to PostgreSQL", "err", err)
 	} else {
-		if er0 := user.Update(ctx, s.userRepo); err != nil {
+		if er0 := user.Update(ctx, s.userRepo); er0 != nil {
 			s.sLog.ErrorContext(ctx, env.MSG+"syncToPostgreSQL batch to PostgreSQL and update user", "er0", er0)
 		}
 	}
 }
 
+// acknowledge подтверждение надгробий MongoDB этим шардом, после подтверждения
+// всеми шардами надгробия удаляются TombstoneCompactor.
+func (s *syncUtilService) acknowledge(ctx context.Context, tombstones []entity.Favorites) {
+
+	if s.shardID == "" || len(tombstones) < 1 {
+		return
+	}
+	if err := s.mongo.Acknowledge(ctx, tombstones, s.shardID); err != nil {
+		s.sLog.ErrorContext(ctx, env.MSG+"acknowledge tombstones in MongoDB", "err", err)
+	}
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-03 17:39 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * sync_util_service.go
@@ -77,8 +77,21 @@ func TestSyncUtilService(t *testing.T) {
 			name: "test #10 negative #2 Sync util Service Sync",
 			fRun: testFavoritesServiceSyncNegative2,
 		},
+		{
+			name: "test #11 positive #9 Sync util Service Sync metadata differ",
+			fRun: testFavoritesServiceSyncPositive9,
+		},
+		{
+			name: "test #12 positive #10 Sync util Service Sync MongoDB tombstone",
+			fRun: testFavoritesServiceSyncTombstone,
+		},
+		{
+			name: "test #13 positive #11 Sync util Service Sync unknown user hides tombstones",
+			fRun: testFavoritesServiceSyncTombstoneUnknownUser,
+		},
 	}
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 
 	assert.NotNil(t, t)
 	for _, test := range tests {
@@ -99,7 +112,7 @@ func testFavoritesServiceSyncNegative1(t *testing.T) {
 	repoUser := NewMockRepo[*entity.User](ctrl)
 	mockMongo.
 		EXPECT().
-		Save(gomock.Any(), gomock.Any()).
+		SaveAll(gomock.Any(), gomock.Any()).
 		Return(repo.ErrNotFound).
 		AnyTimes()
 	repoFavorites.
@@ -188,6 +201,11 @@ func testFavoritesServiceSyncNegative2(t *testing.T) {
 		Update(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(&entity.User{}, repo.ErrBadPool).
 		AnyTimes()
+	repoFavoritesDeleted.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(make([]*entity.FavoritesDeleted, 0), nil).
+		AnyTimes()
 	syncUtilService := getTestSyncUtilService(
 		assetLookup,
 		favoritesInsertsBatch,
@@ -305,6 +323,26 @@ func testFavoritesServiceSyncPositive3(t *testing.T) {
 		Lookup(gomock.Any(), gomock.Any()).
 		Return(true).
 		AnyTimes()
+	repoFavoritesDeleted.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(make([]*entity.FavoritesDeleted, 0), nil).
+		AnyTimes()
+	assetLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(true).
+		AnyTimes()
+	favoritesInsertsBatch.
+		EXPECT().
+		Do(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		AnyTimes()
+	repoUser.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.User{}, nil).
+		AnyTimes()
 	syncUtilService := getTestSyncUtilService(
 		assetLookup,
 		favoritesInsertsBatch,
@@ -334,6 +372,20 @@ func testFavoritesServiceSyncPositive4(t *testing.T) {
 		Get(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(&entity.Favorites{}, nil).
 		AnyTimes()
+	repoFavoritesDeleted.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(make([]*entity.FavoritesDeleted, 0), nil).
+		AnyTimes()
+	mockMongo.
+		EXPECT().
+		SaveAll(gomock.Any(), gomock.Any()).Return(nil).
+		AnyTimes()
+	repoFavorites.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.Favorites{}, nil).
+		AnyTimes()
 	syncUtilService := getTestSyncUtilService(
 		assetLookup,
 		favoritesInsertsBatch,
@@ -383,6 +435,11 @@ func testFavoritesServiceSyncPositive5(t *testing.T) {
 		Update(gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(&entity.User{}, nil).
 		AnyTimes()
+	repoFavoritesDeleted.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(make([]*entity.FavoritesDeleted, 0), nil).
+		AnyTimes()
 	syncUtilService := getTestSyncUtilService(
 		assetLookup,
 		favoritesInsertsBatch,
@@ -430,7 +487,7 @@ func testFavoritesServiceSyncPositive6(t *testing.T) {
 	repoUser := NewMockRepo[*entity.User](ctrl)
 	mockMongo.
 		EXPECT().
-		Save(gomock.Any(), gomock.Any()).Return(nil).
+		SaveAll(gomock.Any(), gomock.Any()).Return(nil).
 		AnyTimes()
 	repoFavorites.
 		EXPECT().
@@ -475,7 +532,7 @@ func testFavoritesServiceSyncPositive7(t *testing.T) {
 	repoUser := NewMockRepo[*entity.User](ctrl)
 	mockMongo.
 		EXPECT().
-		Save(gomock.Any(), gomock.Any()).Return(nil).
+		SaveAll(gomock.Any(), gomock.Any()).Return(nil).
 		AnyTimes()
 	repoFavorites.
 		EXPECT().
@@ -539,7 +596,7 @@ func testFavoritesServiceSyncPositive8(t *testing.T) {
 	repoUser := NewMockRepo[*entity.User](ctrl)
 	mockMongo.
 		EXPECT().
-		Save(gomock.Any(), gomock.Any()).Return(nil).
+		SaveAll(gomock.Any(), gomock.Any()).Return(nil).
 		AnyTimes()
 	repoFavorites.
 		EXPECT().
@@ -576,6 +633,141 @@ func testFavoritesServiceSyncPositive8(t *testing.T) {
 	assert.NotNil(t, resp)
 }
 
+func testFavoritesServiceSyncPositive9(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	favoritesInsertsBatch := NewMockFavoritesInsertsBatch(ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoFavoritesDeleted := NewMockRepo[*entity.FavoritesDeleted](ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	repoUser := NewMockRepo[*entity.User](ctrl)
+	mockMongo.
+		EXPECT().
+		SaveAll(gomock.Any(), gomock.Any()).Return(nil).
+		AnyTimes()
+	repoFavoritesDeleted.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(make([]*entity.FavoritesDeleted, 0), nil).
+		AnyTimes()
+	syncUtilService := getTestSyncUtilService(
+		assetLookup,
+		favoritesInsertsBatch,
+		mockMongo,
+		repoFavorites,
+		repoFavoritesDeleted,
+		userLookup,
+		repoUser,
+	)
+	asset := entity.MakeAsset("test", entity.AssetType{}, entity.DefaultTAttributes())
+	favorites1 := entity.MakeFavorites(
+		uuid.Max,
+		asset,
+		entity.MakeUserWithVersion("", 1, entity.DefaultTAttributes()),
+		sql.NullInt64{Int64: 1, Valid: true},
+		entity.DefaultTAttributes(),
+	).WithMetadata("bank")
+	favorites2 := entity.MakeFavorites(
+		uuid.New(),
+		asset,
+		entity.MakeUserWithVersion("", 1, entity.DefaultTAttributes()),
+		sql.NullInt64{Int64: 1, Valid: true},
+		entity.DefaultTAttributes(),
+	).WithMetadata("website")
+	resp, err := syncUtilService.Sync(context.TODO(), []entity.Favorites{favorites1}, []entity.Favorites{favorites2})
+	assert.Nil(t, err)
+	assert.Equal(t, []entity.Favorites{favorites2}, resp)
+	assert.True(t, isFavoritesDiffer(favorites1, favorites2))
+	assert.False(t, isFavoritesDiffer(favorites2, favorites2))
+}
+
+func testFavoritesServiceSyncTombstone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	assetLookup := NewMockAssetSearchService(ctrl)
+	favoritesInsertsBatch := NewMockFavoritesInsertsBatch(ctrl)
+	mockMongo := NewMockMongo(ctrl)
+	repoFavorites := NewMockRepo[*entity.Favorites](ctrl)
+	repoFavoritesDeleted := NewMockRepo[*entity.FavoritesDeleted](ctrl)
+	userLookup := NewMockUserSearchService(ctrl)
+	repoUser := NewMockRepo[*entity.User](ctrl)
+	acknowledged := make(chan []entity.Favorites, 1)
+	batched := make(chan []entity.Favorites, 1)
+	repoFavoritesDeleted.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(make([]*entity.FavoritesDeleted, 0), nil).
+		Times(1)
+	repoFavorites.
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, favorites *entity.Favorites, _ func(domain.Scanner)) (*entity.Favorites, error) {
+			assert.Equal(t, "A", favorites.Asset().Isin())
+			return favorites, nil
+		}).
+		Times(1)
+	mockMongo.
+		EXPECT().
+		Acknowledge(gomock.Any(), gomock.Any(), "shard-1").
+		DoAndReturn(func(_ context.Context, tombstones []entity.Favorites, _ string) error {
+			acknowledged <- tombstones
+			return nil
+		}).
+		Times(1)
+	assetLookup.
+		EXPECT().
+		Lookup(gomock.Any(), "B").
+		Return(true).
+		Times(1)
+	favoritesInsertsBatch.
+		EXPECT().
+		Do(gomock.Any(), gomock.Any(), "upk").
+		DoAndReturn(func(_ context.Context, favorites []entity.Favorites, _ string) error {
+			batched <- favorites
+			return nil
+		}).
+		Times(1)
+	repoUser.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&entity.User{}, nil).
+		AnyTimes()
+	syncUtilService := getTestSyncUtilService(
+		assetLookup,
+		favoritesInsertsBatch,
+		mockMongo,
+		repoFavorites,
+		repoFavoritesDeleted,
+		userLookup,
+		repoUser,
+	)
+	syncUtilServ.shardID = "shard-1"
+	local := testConflictFavorites("A", 2, 2, "a", false, sql.NullTime{})
+	tombstone := testConflictFavorites("A", 3, 3, "", true, sql.NullTime{})
+	remote := testConflictFavorites("B", 3, 3, "b", false, sql.NullTime{})
+	resp, err := syncUtilService.Sync(context.TODO(), []entity.Favorites{tombstone, remote}, []entity.Favorites{local})
+	assert.Nil(t, err)
+	assert.Equal(t, []entity.Favorites{remote}, resp)
+	assert.Equal(t, []entity.Favorites{tombstone}, <-acknowledged)
+	assert.Equal(t, []entity.Favorites{remote}, <-batched)
+}
+
+func testFavoritesServiceSyncTombstoneUnknownUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userLookup := NewMockUserSearchService(ctrl)
+	userLookup.
+		EXPECT().
+		Lookup(gomock.Any(), gomock.Any()).
+		Return(false).
+		Times(1)
+	syncUtilService := getTestSyncUtilService(nil, nil, nil, nil, nil, userLookup, nil)
+	tombstone := testConflictFavorites("A", 3, 3, "", true, sql.NullTime{})
+	remote := testConflictFavorites("B", 3, 3, "b", false, sql.NullTime{})
+	resp, err := syncUtilService.Sync(context.TODO(), []entity.Favorites{tombstone, remote}, []entity.Favorites{})
+	assert.Nil(t, err)
+	assert.Equal(t, []entity.Favorites{remote}, resp)
+}
+
 func getTestSyncUtilService(
 	assetLookup AssetSearchService,
 	batch batch.FavoritesInsertsBatch,
@@ -588,9 +780,11 @@ func getTestSyncUtilService(
 	syncUtilServ = new(syncUtilService)
 	syncUtilServ.assetLookup = assetLookup
 	syncUtilServ.batch = batch
+	syncUtilServ.executor = newSyncExecutor(2, 16, slog.Default())
 	syncUtilServ.mongo = mongo
 	syncUtilServ.repoFavorites = repoFavorites
 	syncUtilServ.repoFavoritesDeleted = repoFavoritesDeleted
+	syncUtilServ.resolver = lastWriterWins{}
 	syncUtilServ.sLog = slog.Default()
 	syncUtilServ.userLookup = userLookup
 	syncUtilServ.userRepo = userRepo
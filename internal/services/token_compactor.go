@@ -0,0 +1,107 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * token_compactor.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+)
+
+const tokenCompactionDefaultIntervalSec = 3600
+
+// TokenCompactor очистка таблиц токенов в PostgreSQL: записи отозванных JWT
+// с истёкшим сроком действия, истёкшие и погашенные токены обновления.
+type TokenCompactor interface {
+	// Compact один проход очистки, возвращает количество удалённых записей.
+	Compact(ctx context.Context) (int64, error)
+	// Run периодическая очистка до отмены ctx.
+	Run(ctx context.Context)
+}
+
+type tokenCompactor struct {
+	enabled     bool
+	interval    time.Duration
+	repoRefresh domain.Repo[*entity.RefreshToken]
+	repoRevoked domain.Repo[*entity.RevokedToken]
+	sLog        *slog.Logger
+}
+
+var _ TokenCompactor = (*tokenCompactor)(nil)
+var (
+	onceTokenCompactor = new(sync.Once)
+	tokenCompactorServ *tokenCompactor
+)
+
+// GetTokenCompactor — потокобезопасное (thread-safe) создание
+// сервиса очистки истёкших токенов.
+func GetTokenCompactor(prop env.Properties) TokenCompactor {
+
+	onceTokenCompactor.Do(func() {
+		tokenCompactorServ = new(tokenCompactor)
+		tokenCompactorServ.enabled = prop.DBPool() != nil
+		tokenCompactorServ.interval = time.Duration(intOrDefault(
+			prop.Config().JwtCleanupIntervalSec(),
+			tokenCompactionDefaultIntervalSec,
+		)) * time.Second
+		tokenCompactorServ.repoRefresh = repo.GetRefreshTokenPostgresRepo(prop)
+		tokenCompactorServ.repoRevoked = repo.GetRevokedTokenPostgresRepo(prop)
+		tokenCompactorServ.sLog = prop.Logger()
+	})
+	return tokenCompactorServ
+}
+
+func (t *tokenCompactor) Compact(ctx context.Context) (int64, error) {
+
+	now := time.Now().UTC()
+	revoked, err := entity.PurgeRevokedTokens(ctx, t.repoRevoked, now)
+
+	if err != nil {
+		t.sLog.ErrorContext(ctx, env.MSG+"TokenCompactor.Compact", "msg", "revoked tokens", "err", err)
+		return 0, err
+	}
+	refresh, err := entity.PurgeRefreshTokens(ctx, t.repoRefresh, now)
+
+	if err != nil {
+		t.sLog.ErrorContext(ctx, env.MSG+"TokenCompactor.Compact", "msg", "refresh tokens", "err", err)
+		return revoked, err
+	}
+	t.sLog.InfoContext(ctx, env.MSG+"TokenCompactor.Compact", "revoked", revoked, "refresh", refresh)
+
+	return revoked + refresh, nil
+}
+
+func (t *tokenCompactor) Run(ctx context.Context) {
+
+	if !t.enabled {
+		return
+	}
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = t.Compact(ctx)
+		}
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
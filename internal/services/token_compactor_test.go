@@ -0,0 +1,148 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * token_compactor_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTokenCompactor(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive TokenCompactor Compact",
+			fRun: testTokenCompactorCompact,
+		},
+		{
+			name: "test #1 negative TokenCompactor Compact",
+			fRun: testTokenCompactorCompactError,
+		},
+		{
+			name: "test #2 positive TokenCompactor Run",
+			fRun: testTokenCompactorRun,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testTokenCompactorCompact(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	compactor := getTestTokenCompactor(ctrl)
+	compactor.repoRevoked.(*MockRepo[*entity.RevokedToken]).
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RevokedToken, scan func(domain.Scanner)) (*entity.RevokedToken, error) {
+			assert.Equal(t, entity.RevokedTokenPurgeSQL, r.DeleteSQL())
+			assert.WithinDuration(t, time.Now(), r.DeleteArgs()[0].(time.Time), time.Second)
+			scan(&stubValuesScanner{values: []any{int64(2)}})
+			return r, nil
+		}).
+		Times(1)
+	compactor.repoRefresh.(*MockRepo[*entity.RefreshToken]).
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RefreshToken, scan func(domain.Scanner)) (*entity.RefreshToken, error) {
+			assert.Equal(t, entity.RefreshTokenPurgeSQL, r.DeleteSQL())
+			assert.WithinDuration(t, time.Now(), r.DeleteArgs()[0].(time.Time), time.Second)
+			scan(&stubValuesScanner{values: []any{int64(3)}})
+			return r, nil
+		}).
+		Times(1)
+	got, err := compactor.Compact(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), got)
+}
+
+func testTokenCompactorCompactError(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	compactor := getTestTokenCompactor(ctrl)
+	compactor.repoRevoked.(*MockRepo[*entity.RevokedToken]).
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("test")).
+		Times(1)
+	got, err := compactor.Compact(context.TODO())
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(0), got)
+}
+
+func testTokenCompactorRun(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	compactor := getTestTokenCompactor(ctrl)
+	compacted := make(chan struct{}, 1)
+	compactor.repoRevoked.(*MockRepo[*entity.RevokedToken]).
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RevokedToken, scan func(domain.Scanner)) (*entity.RevokedToken, error) {
+			scan(&stubValuesScanner{values: []any{int64(0)}})
+			return r, nil
+		}).
+		MinTimes(1)
+	compactor.repoRefresh.(*MockRepo[*entity.RefreshToken]).
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RefreshToken, scan func(domain.Scanner)) (*entity.RefreshToken, error) {
+			scan(&stubValuesScanner{values: []any{int64(0)}})
+			select {
+			case compacted <- struct{}{}:
+			default:
+			}
+			return r, nil
+		}).
+		MinTimes(1)
+	compactor.interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		compactor.Run(ctx)
+		close(done)
+	}()
+	<-compacted
+	cancel()
+	<-done
+
+	compactor.enabled = false
+	compactor.Run(context.Background()) // выключенная очистка сразу возвращает управление
+}
+
+func getTestTokenCompactor(ctrl *gomock.Controller) *tokenCompactor {
+	result := new(tokenCompactor)
+	result.enabled = true
+	result.interval = time.Minute
+	result.repoRefresh = NewMockRepo[*entity.RefreshToken](ctrl)
+	result.repoRevoked = NewMockRepo[*entity.RevokedToken](ctrl)
+	result.sLog = slog.Default()
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,181 @@
+/*
+ * This file was last modified at 2024-08-16 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * token_service.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/controllers/dto"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/jwt"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const (
+	refreshTokenDefaultExpiresIn = 30 * 24 * time.Hour
+	refreshTokenSize             = 32
+)
+
+var ErrRefreshTokenInvalid = fmt.Errorf("refresh token is invalid, expired or already used")
+
+// TokenPair JWT доступа и токен обновления, токен обновления пуст
+// если база данных для их хранения не настроена.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// TokenService выпуск JWT доступа с токенами обновления, обновление и выход.
+// Токен обновления одноразовый: при обновлении он гасится и выпускается новая пара.
+type TokenService interface {
+	Issue(ctx context.Context, identity Identity) (TokenPair, error)
+	Logout(ctx context.Context, jti string, expiresAt time.Time, refreshToken string) error
+	Refresh(ctx context.Context, refreshToken string) (TokenPair, error)
+}
+
+type tokenService struct {
+	jwtManager       jwt.Manager
+	refreshEnabled   bool
+	refreshExpiresIn time.Duration
+	repoRefresh      domain.Repo[*entity.RefreshToken]
+	revocation       RevocationService
+	sLog             *slog.Logger
+	upkUtil          UpkUtilService
+}
+
+var _ TokenService = (*tokenService)(nil)
+var (
+	onceToken = new(sync.Once)
+	tokenServ *tokenService
+)
+
+// GetTokenService — потокобезопасное (thread-safe) создание
+// сервиса выпуска и отзыва токенов.
+func GetTokenService(prop env.Properties) TokenService {
+
+	onceToken.Do(func() {
+		tokenServ = new(tokenService)
+		tokenServ.jwtManager = jwt.GetJWTManager(prop)
+		tokenServ.refreshEnabled = prop.DBPool() != nil
+		tokenServ.refreshExpiresIn = prop.Config().JwtRefreshExpiresIn()
+		tokenServ.repoRefresh = repo.GetRefreshTokenPostgresRepo(prop)
+		tokenServ.revocation = GetRevocationService(prop)
+		tokenServ.sLog = prop.Logger()
+		tokenServ.upkUtil = GetUpkUtilService(prop)
+
+		if tokenServ.refreshExpiresIn <= 0 {
+			tokenServ.refreshExpiresIn = refreshTokenDefaultExpiresIn
+		}
+	})
+	return tokenServ
+}
+
+// Issue выпуск пары токенов для проверенного пользователя.
+func (t *tokenService) Issue(ctx context.Context, identity Identity) (TokenPair, error) {
+
+	accessToken, err := t.jwtManager.Generate(dto.SignInRequest{UserName: identity.PersonalKey, Role: identity.Role})
+
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !t.refreshEnabled {
+		return TokenPair{AccessToken: accessToken}, nil
+	}
+	refreshToken, err := tool.GenerateToken(refreshTokenSize)
+
+	if err != nil {
+		return TokenPair{}, err
+	}
+	subject, err := t.upkUtil.EncryptGCM([]byte(identity.PersonalKey))
+
+	if err != nil {
+		t.sLog.ErrorContext(ctx, env.MSG+"TokenService.Issue", "msg", "token service encrypt", "err", err)
+		return TokenPair{}, tool.ErrEncryptAES
+	}
+	refresh := entity.MakeRefreshToken(
+		refreshToken,
+		base64.StdEncoding.EncodeToString(subject),
+		identity.Role,
+		time.Now().UTC().Add(t.refreshExpiresIn),
+	)
+	err = refresh.Insert(ctx, t.repoRefresh)
+
+	if errors.Is(err, repo.ErrBadPool) {
+		t.sLog.WarnContext(ctx, env.MSG+"TokenService.Issue", "msg", "refresh tokens are disabled without database")
+		return TokenPair{AccessToken: accessToken}, nil
+	}
+	if err != nil {
+		t.sLog.ErrorContext(ctx, env.MSG+"TokenService.Issue", "msg", "token service insert", "err", err)
+		return TokenPair{}, err
+	}
+	return TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Logout отзыв JWT доступа по «jti» и погашение токена обновления если он передан.
+func (t *tokenService) Logout(ctx context.Context, jti string, expiresAt time.Time, refreshToken string) error {
+
+	if err := t.revocation.Revoke(ctx, jti, expiresAt); err != nil && !errors.Is(err, repo.ErrBadPool) {
+		return err
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	refresh := entity.MakeRefreshToken(refreshToken, "", "", time.Time{})
+	err := refresh.Consume(ctx, t.repoRefresh)
+
+	if entity.IsRefreshTokenNotFound(refresh, err) && (err == nil || tool.NoRowsInResultSet(err)) {
+		return nil
+	}
+	if errors.Is(err, repo.ErrBadPool) {
+		return nil
+	}
+	return err
+}
+
+// Refresh погашение токена обновления и выпуск новой пары токенов.
+func (t *tokenService) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+
+	if refreshToken == "" {
+		return TokenPair{}, ErrRefreshTokenInvalid
+	}
+	refresh := entity.MakeRefreshToken(refreshToken, "", "", time.Time{})
+	err := refresh.Consume(ctx, t.repoRefresh)
+
+	if entity.IsRefreshTokenNotFound(refresh, err) {
+		t.sLog.WarnContext(ctx, env.MSG+"TokenService.Refresh", "msg", "token service consume", "err", err)
+		if err == nil || tool.NoRowsInResultSet(err) {
+			return TokenPair{}, ErrRefreshTokenInvalid
+		}
+		return TokenPair{}, err
+	}
+	bytes, err := base64.StdEncoding.DecodeString(refresh.Subject())
+
+	if err == nil {
+		bytes, err = t.upkUtil.DecryptGCM(bytes)
+	}
+	if err != nil {
+		t.sLog.ErrorContext(ctx, env.MSG+"TokenService.Refresh", "msg", "token service decrypt", "err", err)
+		return TokenPair{}, tool.ErrDecryptGCM
+	}
+	return t.Issue(ctx, Identity{PersonalKey: string(bytes), Role: refresh.Role()})
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,277 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * token_service_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/memory"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/jwt"
+	"github.com/vskurikhin/gofavorites/internal/policy"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"go.uber.org/mock/gomock"
+)
+
+// testJwtSecret секрет подписи JWT для тестов без файла настроек.
+const testJwtSecret = "Gx5nG0bQvhU3Y8fCmtK2eWzRaPj7sL4dNq9XoTi1"
+
+func TestTokenService(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "positive test #0 TokenService Issue stores hashed refresh token",
+			fRun: testTokenServiceIssuePositive,
+		},
+		{
+			name: "positive test #1 TokenService Issue without database",
+			fRun: testTokenServiceIssueWithoutDatabase,
+		},
+		{
+			name: "positive test #2 TokenService Refresh",
+			fRun: testTokenServiceRefreshPositive,
+		},
+		{
+			name: "negative test #3 TokenService Refresh used or unknown token",
+			fRun: testTokenServiceRefreshNegative,
+		},
+		{
+			name: "positive test #4 TokenService Logout",
+			fRun: testTokenServiceLogoutPositive,
+		},
+		{
+			name: "positive test #5 RevocationService IsRevoked cached",
+			fRun: testRevocationServiceCached,
+		},
+		{
+			name: "negative test #6 RevocationService errors",
+			fRun: testRevocationServiceNegative,
+		},
+	}
+
+	assert.NotNil(t, t)
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testTokenServiceIssuePositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoRefresh := NewMockRepo[*entity.RefreshToken](ctrl)
+	var stored *entity.RefreshToken
+	repoRefresh.
+		EXPECT().
+		Insert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RefreshToken, scan func(domain.Scanner)) (*entity.RefreshToken, error) {
+			scan(&stubValuesScanner{values: []any{uuid.New(), time.Now()}})
+			stored = r
+			return r, nil
+		}).
+		Times(1)
+	service := getTestTokenService(repoRefresh, nil)
+	pair, err := service.Issue(context.TODO(), Identity{PersonalKey: "test"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, pair.AccessToken)
+	assert.NotEmpty(t, pair.RefreshToken)
+	assert.Equal(t, tool.TokenHash(pair.RefreshToken), stored.TokenHash())
+	assert.NotContains(t, stored.Subject(), "test")
+	assert.True(t, stored.ExpiresAt().After(time.Now().Add(time.Hour)))
+}
+
+func testTokenServiceIssueWithoutDatabase(t *testing.T) {
+	service := getTestTokenService(nil, nil)
+	service.(*tokenService).refreshEnabled = false
+	pair, err := service.Issue(context.TODO(), Identity{PersonalKey: "test"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, pair.AccessToken)
+	assert.Empty(t, pair.RefreshToken)
+}
+
+func testTokenServiceRefreshPositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoRefresh := NewMockRepo[*entity.RefreshToken](ctrl)
+	subject, err := getTestNotesUpkUtil().EncryptGCM([]byte("test"))
+	assert.Nil(t, err)
+	repoRefresh.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RefreshToken, scan func(domain.Scanner)) (*entity.RefreshToken, error) {
+			assert.Equal(t, tool.TokenHash("refresh"), r.TokenHash())
+			scan(&stubValuesScanner{values: []any{uuid.New(), base64.StdEncoding.EncodeToString(subject), policy.RoleAdmin}})
+			return r, nil
+		}).
+		Times(1)
+	repoRefresh.
+		EXPECT().
+		Insert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RefreshToken, scan func(domain.Scanner)) (*entity.RefreshToken, error) {
+			scan(&stubValuesScanner{values: []any{uuid.New(), time.Now()}})
+			return r, nil
+		}).
+		Times(1)
+	service := getTestTokenService(repoRefresh, nil)
+	pair, err := service.Refresh(context.TODO(), "refresh")
+	assert.Nil(t, err)
+	assert.NotEqual(t, "refresh", pair.RefreshToken)
+	claims, err := service.(*tokenService).jwtManager.Verify(pair.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", claims.Subject)
+	assert.Equal(t, policy.RoleAdmin, claims.Role())
+}
+
+func testTokenServiceRefreshNegative(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoRefresh := NewMockRepo[*entity.RefreshToken](ctrl)
+	repoRefresh.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RefreshToken, scan func(domain.Scanner)) (*entity.RefreshToken, error) {
+			scan(&stubValuesScanner{err: pgx.ErrNoRows})
+			return r, nil
+		}).
+		Times(1)
+	repoRefresh.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("database is down")).
+		Times(1)
+	service := getTestTokenService(repoRefresh, nil)
+	_, err := service.Refresh(context.TODO(), "")
+	assert.Equal(t, ErrRefreshTokenInvalid, err)
+	_, err = service.Refresh(context.TODO(), "used")
+	assert.Equal(t, ErrRefreshTokenInvalid, err)
+	_, err = service.Refresh(context.TODO(), "refresh")
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrRefreshTokenInvalid, err)
+}
+
+func testTokenServiceLogoutPositive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoRefresh := NewMockRepo[*entity.RefreshToken](ctrl)
+	repoRevoked := NewMockRepo[*entity.RevokedToken](ctrl)
+	exp := time.Now().Add(time.Hour).UTC()
+	repoRevoked.
+		EXPECT().
+		Insert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RevokedToken, scan func(domain.Scanner)) (*entity.RevokedToken, error) {
+			assert.Equal(t, "jti", r.JTI())
+			scan(&stubValuesScanner{values: []any{"jti", exp, time.Now()}})
+			return r, nil
+		}).
+		Times(1)
+	repoRefresh.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RefreshToken, scan func(domain.Scanner)) (*entity.RefreshToken, error) {
+			scan(&stubValuesScanner{err: pgx.ErrNoRows})
+			return r, nil
+		}).
+		Times(1)
+	revocation := getTestRevocationService(repoRevoked)
+	service := getTestTokenService(repoRefresh, revocation)
+	err := service.Logout(context.TODO(), "jti", exp, "refresh")
+	assert.Nil(t, err)
+	// повторная проверка берётся из кэша без обращения к базе данных
+	assert.True(t, revocation.IsRevoked(context.TODO(), "jti"))
+}
+
+func testRevocationServiceCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoRevoked := NewMockRepo[*entity.RevokedToken](ctrl)
+	repoRevoked.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RevokedToken, scan func(domain.Scanner)) (*entity.RevokedToken, error) {
+			scan(&stubValuesScanner{values: []any{"revoked", time.Now().Add(time.Hour)}})
+			return r, nil
+		}).
+		Times(1)
+	repoRevoked.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.RevokedToken, scan func(domain.Scanner)) (*entity.RevokedToken, error) {
+			scan(&stubValuesScanner{err: pgx.ErrNoRows})
+			return r, nil
+		}).
+		Times(1)
+	revocation := getTestRevocationService(repoRevoked)
+	for i := 0; i < 3; i++ {
+		assert.True(t, revocation.IsRevoked(context.TODO(), "revoked"))
+		assert.False(t, revocation.IsRevoked(context.TODO(), "active"))
+	}
+	assert.False(t, revocation.IsRevoked(context.TODO(), ""))
+	assert.Nil(t, revocation.Revoke(context.TODO(), "", time.Now()))
+}
+
+func testRevocationServiceNegative(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoRevoked := NewMockRepo[*entity.RevokedToken](ctrl)
+	repoRevoked.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, repo.ErrBadPool).
+		Times(1)
+	repoRevoked.
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("database is down")).
+		Times(1)
+	repoRevoked.
+		EXPECT().
+		Insert(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("database is down")).
+		Times(1)
+	revocation := getTestRevocationService(repoRevoked)
+	assert.False(t, revocation.IsRevoked(context.TODO(), "jti"))
+	assert.True(t, revocation.IsRevoked(context.TODO(), "jti"))
+	assert.NotNil(t, revocation.Revoke(context.TODO(), "jti", time.Now()))
+}
+
+func getTestRevocationService(repoRevoked domain.Repo[*entity.RevokedToken]) RevocationService {
+	r := new(revocationService)
+	r.cache = memory.New(memory.Config{GCInterval: time.Minute})
+	r.exp = time.Minute
+	r.repoRevoked = repoRevoked
+	r.sLog = slog.Default()
+	return r
+}
+
+func getTestTokenService(repoRefresh domain.Repo[*entity.RefreshToken], revocation RevocationService) TokenService {
+	t := new(tokenService)
+	t.jwtManager = jwt.GetJWTManager(env.GetProperties())
+	t.refreshEnabled = true
+	t.refreshExpiresIn = refreshTokenDefaultExpiresIn
+	t.repoRefresh = repoRefresh
+	t.revocation = revocation
+	t.sLog = slog.Default()
+	t.upkUtil = getTestNotesUpkUtil()
+	return t
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
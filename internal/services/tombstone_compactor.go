@@ -0,0 +1,97 @@
+/*
+ * This file was last modified at 2024-08-18 00:20 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * tombstone_compactor.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+)
+
+// TombstoneCompactor очистка надгробий (tombstones) избранного в MongoDB,
+// подтверждённых всеми шардами и хранящихся дольше срока хранения.
+type TombstoneCompactor interface {
+	// Compact один проход очистки, возвращает количество удалённых надгробий.
+	Compact(ctx context.Context) (int64, error)
+	// Run периодическая очистка до отмены ctx.
+	Run(ctx context.Context)
+}
+
+type tombstoneCompactor struct {
+	enabled   bool
+	interval  time.Duration
+	mongo     mongo.Mongo
+	retention time.Duration
+	shards    []string
+	sLog      *slog.Logger
+}
+
+var _ TombstoneCompactor = (*tombstoneCompactor)(nil)
+var (
+	onceTombstoneCompactor = new(sync.Once)
+	tombstoneCompactorServ *tombstoneCompactor
+)
+
+// GetTombstoneCompactor — потокобезопасное (thread-safe) создание
+// сервиса очистки надгробий избранного в MongoDB.
+func GetTombstoneCompactor(prop env.Properties) TombstoneCompactor {
+
+	onceTombstoneCompactor.Do(func() {
+		tombstoneCompactorServ = new(tombstoneCompactor)
+		tombstoneCompactorServ.interval = time.Duration(prop.Config().SyncTombstoneCompactionIntervalSec()) * time.Second
+		tombstoneCompactorServ.mongo = mongo.GetStore(prop)
+		tombstoneCompactorServ.retention = time.Duration(prop.Config().SyncTombstoneRetentionSec()) * time.Second
+		tombstoneCompactorServ.shards = prop.Config().SyncShards()
+		tombstoneCompactorServ.sLog = prop.Logger()
+		tombstoneCompactorServ.enabled = tombstoneCompactorServ.interval > 0 &&
+			len(tombstoneCompactorServ.shards) > 0 &&
+			mongo.StoreEnabled(prop)
+	})
+	return tombstoneCompactorServ
+}
+
+func (t *tombstoneCompactor) Compact(ctx context.Context) (int64, error) {
+
+	deleted, err := t.mongo.Compact(ctx, t.shards, time.Now().Add(-t.retention))
+
+	if err != nil {
+		t.sLog.ErrorContext(ctx, env.MSG+"TombstoneCompactor.Compact", "err", err)
+		return 0, err
+	}
+	t.sLog.InfoContext(ctx, env.MSG+"TombstoneCompactor.Compact", "deleted", deleted)
+
+	return deleted, nil
+}
+
+func (t *tombstoneCompactor) Run(ctx context.Context) {
+
+	if !t.enabled {
+		return
+	}
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = t.Compact(ctx)
+		}
+	}
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
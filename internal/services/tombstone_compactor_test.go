@@ -0,0 +1,130 @@
+/*
+ * This file was last modified at 2024-08-17 21:30 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * tombstone_compactor_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTombstoneCompactor(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive TombstoneCompactor Compact",
+			fRun: testTombstoneCompactorCompact,
+		},
+		{
+			name: "test #1 negative TombstoneCompactor Compact",
+			fRun: testTombstoneCompactorCompactError,
+		},
+		{
+			name: "test #2 positive TombstoneCompactor Run",
+			fRun: testTombstoneCompactorRun,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testTombstoneCompactorCompact(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	mockMongo.
+		EXPECT().
+		Compact(gomock.Any(), []string{"shard-1", "shard-2"}, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ []string, before time.Time) (int64, error) {
+			assert.WithinDuration(t, time.Now().Add(-time.Hour), before, time.Second)
+			return 3, nil
+		}).
+		Times(1)
+	compactor := getTestTombstoneCompactor(mockMongo)
+	got, err := compactor.Compact(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), got)
+}
+
+func testTombstoneCompactorCompactError(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	mockMongo.
+		EXPECT().
+		Compact(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(int64(0), fmt.Errorf("test")).
+		Times(1)
+	compactor := getTestTombstoneCompactor(mockMongo)
+	got, err := compactor.Compact(context.TODO())
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(0), got)
+}
+
+func testTombstoneCompactorRun(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	mockMongo := NewMockMongo(ctrl)
+	compacted := make(chan struct{}, 1)
+	mockMongo.
+		EXPECT().
+		Compact(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ []string, _ time.Time) (int64, error) {
+			select {
+			case compacted <- struct{}{}:
+			default:
+			}
+			return 0, nil
+		}).
+		MinTimes(1)
+	compactor := getTestTombstoneCompactor(mockMongo)
+	compactor.interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		compactor.Run(ctx)
+		close(done)
+	}()
+	<-compacted
+	cancel()
+	<-done
+
+	compactor.enabled = false
+	compactor.Run(context.Background()) // выключенная очистка сразу возвращает управление
+}
+
+func getTestTombstoneCompactor(mongo mongo.Mongo) *tombstoneCompactor {
+	result := new(tombstoneCompactor)
+	result.enabled = true
+	result.interval = time.Minute
+	result.mongo = mongo
+	result.retention = time.Hour
+	result.shards = []string{"shard-1", "shard-2"}
+	result.sLog = slog.Default()
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,159 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * upk_migrator.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/mongo"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+// UpkMigrator UPK пользователя по персональному ключу с переносом данных
+// пользователя с UPK прежнего формата (AES одного блока) и с UPK предыдущих
+// версий секрета на слепой индекс текущей версии. Прежние UPK не обратимы,
+// поэтому перенос выполняется при первом обращении пользователя, когда
+// известен персональный ключ: строки PostgreSQL
+// переносятся одним оператором, затем документы MongoDB. Сервис работает без
+// остановки: до переноса пользователь не виден по новому UPK, перенос
+// выполняется раньше любого чтения или записи по новому UPK.
+type UpkMigrator interface {
+	// Forget сброс признака переноса UPK, например после удаления данных пользователя:
+	// при следующем обращении персональный ключ будет сохранён заново.
+	Forget(upk string)
+	// Migrate UPK персонального ключа, данные пользователя перенесены на него.
+	Migrate(ctx context.Context, personalKey string) (string, error)
+}
+
+// ErrUpkMigration данные пользователя не перенесены на новый UPK.
+var ErrUpkMigration = fmt.Errorf("upk migration failed")
+
+type upkMigrator struct {
+	enabled      bool
+	legacy       bool
+	migrated     sync.Map
+	mongo        mongo.Mongo
+	mongoEnabled bool
+	notes        mongo.Notes
+	notesEnabled bool
+	repoUserKey  domain.Repo[*entity.UserKey]
+	sLog         *slog.Logger
+	upkUtil      UpkUtilService
+}
+
+var _ UpkMigrator = (*upkMigrator)(nil)
+var (
+	onceUpkMigrator = new(sync.Once)
+	upkMigratorServ *upkMigrator
+)
+
+// GetUpkMigrator — потокобезопасное (thread-safe) создание
+// сервиса переноса данных пользователей на новый UPK.
+func GetUpkMigrator(prop env.Properties) UpkMigrator {
+
+	onceUpkMigrator.Do(func() {
+		upkMigratorServ = new(upkMigrator)
+		upkMigratorServ.enabled = prop.DBPool() != nil
+		upkMigratorServ.legacy = prop.Config().UpkLegacyMigration()
+		upkMigratorServ.mongo = mongo.GetStore(prop)
+		upkMigratorServ.mongoEnabled = mongo.StoreEnabled(prop)
+		upkMigratorServ.notes = mongo.GetMongoNotesRepo(prop)
+		upkMigratorServ.notesEnabled = prop.MongodbClient() != nil
+		upkMigratorServ.repoUserKey = repo.GetUserKeyPostgresRepo(prop)
+		upkMigratorServ.sLog = prop.Logger()
+		upkMigratorServ.upkUtil = GetUpkUtilService(prop)
+	})
+	return upkMigratorServ
+}
+
+func (m *upkMigrator) Forget(upk string) {
+	m.migrated.Delete(upk)
+}
+
+// Migrate ошибка переноса прерывает запрос пользователя: запрос по новому UPK
+// раньше переноса создал бы пользователя, и прежние данные уже не перенести.
+// Перенос повторяется при следующем обращении.
+func (m *upkMigrator) Migrate(ctx context.Context, personalKey string) (string, error) {
+
+	upk, err := m.upkUtil.EncryptPersonalKey(personalKey)
+
+	if err != nil {
+		return "", err
+	}
+	if !m.enabled {
+		return upk, nil
+	}
+	if _, ok := m.migrated.Load(upk); ok {
+		return upk, nil
+	}
+	previous, err := m.upkUtil.PreviousPersonalKeys(personalKey, m.legacy)
+
+	if err != nil {
+		m.sLog.ErrorContext(ctx, env.MSG+"UpkMigrator.Migrate", "msg", "previous upk", "err", err)
+		return "", fmt.Errorf("%w: %w", ErrUpkMigration, err)
+	}
+	found, err := m.rekey(ctx, personalKey, upk, previous)
+
+	if err != nil {
+		m.sLog.ErrorContext(ctx, env.MSG+"UpkMigrator.Migrate", "msg", "user is not rekeyed", "err", err)
+		return "", fmt.Errorf("%w: %w", ErrUpkMigration, err)
+	}
+	// пользователя ещё нет: ключ будет сохранён при следующем обращении.
+	if found {
+		m.migrated.Store(upk, struct{}{})
+	}
+	return upk, nil
+}
+
+// rekey перенос данных с прежних UPK previous и сохранение персонального ключа
+// зашифрованного текущей версией секрета, found — пользователь есть в PostgreSQL.
+func (m *upkMigrator) rekey(ctx context.Context, personalKey, upk string, previous []string) (found bool, err error) {
+
+	sealed, err := m.upkUtil.SealPersonalKey(personalKey, upk)
+
+	if err != nil {
+		return false, err
+	}
+	key := entity.MakeUserKey(upk, previous, sealed, m.upkUtil.KeyVersion())
+
+	if err = key.Rekey(ctx, m.repoUserKey); err != nil && !tool.NoRowsInResultSet(err) {
+		return false, err
+	}
+	found = err == nil
+
+	// документы MongoDB общие для шардов: переносятся даже если в PostgreSQL
+	// этого шарда пользователя с прежним UPK нет.
+	for _, from := range previous {
+		if m.mongoEnabled {
+			if err = m.mongo.Rekey(ctx, from, upk); err != nil {
+				return found, err
+			}
+		}
+		if m.notesEnabled {
+			if err = m.notes.Rekey(ctx, from, upk); err != nil {
+				return found, err
+			}
+		}
+	}
+	m.sLog.DebugContext(ctx, env.MSG+"UpkMigrator.rekey", "upk", upk, "previous", previous, "found", found)
+
+	return found, nil
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,237 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * upk_migrator_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"go.uber.org/mock/gomock"
+)
+
+func TestUpkMigrator(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive UpkMigrator Migrate disabled",
+			fRun: testUpkMigratorDisabled,
+		},
+		{
+			name: "test #1 positive UpkMigrator Migrate legacy user",
+			fRun: testUpkMigratorLegacy,
+		},
+		{
+			name: "test #2 positive UpkMigrator Migrate absent in PostgreSQL",
+			fRun: testUpkMigratorAbsent,
+		},
+		{
+			name: "test #3 negative UpkMigrator Migrate fails and is retried after error",
+			fRun: testUpkMigratorError,
+		},
+		{
+			name: "test #4 positive UpkMigrator Migrate seals key only",
+			fRun: testUpkMigratorSealOnly,
+		},
+		{
+			name: "test #5 positive UpkMigrator Migrate from previous key version",
+			fRun: testUpkMigratorPreviousVersion,
+		},
+		{
+			name: "test #6 positive UpkMigrator Forget repeats migration",
+			fRun: testUpkMigratorForget,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testUpkMigratorDisabled(t *testing.T) {
+
+	upkUtil := getTestNotesUpkUtil()
+	expected, err := upkUtil.EncryptPersonalKey("test")
+	assert.Nil(t, err)
+	got, err := getTestUpkMigrator(upkUtil).Migrate(context.TODO(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func testUpkMigratorLegacy(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	migrator := getTestUpkMigratorEnabled(ctrl)
+	upk, _ := migrator.upkUtil.EncryptPersonalKey("test")
+	legacy, _ := migrator.upkUtil.LegacyPersonalKey("test")
+	expectTestUpkMigratorRekey(t, migrator, upk, []string{legacy}, nil, 1)
+	migrator.mongo.(*MockMongo).
+		EXPECT().
+		Rekey(gomock.Any(), legacy, upk).
+		Return(nil).
+		Times(1)
+	migrator.notes.(*MockNotes).
+		EXPECT().
+		Rekey(gomock.Any(), legacy, upk).
+		Return(nil).
+		Times(1)
+	for i := 0; i < 2; i++ {
+		got, err := migrator.Migrate(context.TODO(), "test")
+		assert.Nil(t, err)
+		assert.Equal(t, upk, got)
+	}
+}
+
+func testUpkMigratorAbsent(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	migrator := getTestUpkMigratorEnabled(ctrl)
+	migrator.notesEnabled = false
+	upk, _ := migrator.upkUtil.EncryptPersonalKey("test")
+	legacy, _ := migrator.upkUtil.LegacyPersonalKey("test")
+	expectTestUpkMigratorRekey(t, migrator, upk, []string{legacy}, pgx.ErrNoRows, 2)
+	migrator.mongo.(*MockMongo).
+		EXPECT().
+		Rekey(gomock.Any(), legacy, upk).
+		Return(nil).
+		Times(2)
+	// пользователя нет, перенос повторяется при следующем обращении.
+	for i := 0; i < 2; i++ {
+		got, err := migrator.Migrate(context.TODO(), "test")
+		assert.Nil(t, err)
+		assert.Equal(t, upk, got)
+	}
+}
+
+func testUpkMigratorError(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	migrator := getTestUpkMigratorEnabled(ctrl)
+	upk, _ := migrator.upkUtil.EncryptPersonalKey("test")
+	migrator.repoUserKey.(*MockRepo[*entity.UserKey]).
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("test")).
+		Times(2)
+	for i := 0; i < 2; i++ {
+		got, err := migrator.Migrate(context.TODO(), "test")
+		assert.ErrorIs(t, err, ErrUpkMigration)
+		assert.Equal(t, "", got)
+	}
+	_, ok := migrator.migrated.Load(upk)
+	assert.False(t, ok)
+}
+
+func testUpkMigratorSealOnly(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	migrator := getTestUpkMigratorEnabled(ctrl)
+	migrator.legacy = false
+	upk, _ := migrator.upkUtil.EncryptPersonalKey("test")
+	expectTestUpkMigratorRekey(t, migrator, upk, []string{}, nil, 1)
+	got, err := migrator.Migrate(context.TODO(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, upk, got)
+}
+
+func testUpkMigratorPreviousVersion(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	migrator := getTestUpkMigratorEnabled(ctrl)
+	migrator.legacy = false
+	migrator.notesEnabled = false
+	previous := getTestUpkUtilServiceKeyring(tool.NewKeyring(tool.KeyVersion{Version: 1, Key: make([]byte, 32)}))
+	migrator.upkUtil = getTestUpkUtilServiceKeyring(tool.NewKeyring(
+		tool.KeyVersion{Version: 2, Key: []byte("01234567890123456789012345678901")},
+		tool.KeyVersion{Version: 1, Key: make([]byte, 32)},
+	))
+	upk, _ := migrator.upkUtil.EncryptPersonalKey("test")
+	from, _ := previous.EncryptPersonalKey("test")
+	assert.NotEqual(t, upk, from)
+	expectTestUpkMigratorRekey(t, migrator, upk, []string{from}, nil, 1)
+	migrator.mongo.(*MockMongo).
+		EXPECT().
+		Rekey(gomock.Any(), from, upk).
+		Return(nil).
+		Times(1)
+	got, err := migrator.Migrate(context.TODO(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, upk, got)
+}
+
+func testUpkMigratorForget(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	migrator := getTestUpkMigratorEnabled(ctrl)
+	migrator.legacy = false
+	migrator.notesEnabled = false
+	upk, _ := migrator.upkUtil.EncryptPersonalKey("test")
+	expectTestUpkMigratorRekey(t, migrator, upk, []string{}, nil, 2)
+	for i := 0; i < 2; i++ {
+		got, err := migrator.Migrate(context.TODO(), "test")
+		assert.Nil(t, err)
+		assert.Equal(t, upk, got)
+	}
+	migrator.Forget(upk)
+	got, err := migrator.Migrate(context.TODO(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, upk, got)
+}
+
+func expectTestUpkMigratorRekey(t *testing.T, migrator *upkMigrator, upk string, previous []string, err error, times int) {
+	migrator.repoUserKey.(*MockRepo[*entity.UserKey]).
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, k *entity.UserKey, scan func(domain.Scanner)) (*entity.UserKey, error) {
+			assert.Equal(t, upk, k.Upk())
+			assert.Equal(t, previous, k.PreviousUpks())
+			assert.Equal(t, migrator.upkUtil.KeyVersion(), k.KeyVersion())
+			personalKey, er0 := migrator.upkUtil.OpenPersonalKey(k.SealedKey(), upk, k.KeyVersion())
+			assert.Nil(t, er0)
+			assert.Equal(t, "test", personalKey)
+			scan(&stubValuesScanner{err: err, values: []any{k.Upk(), k.SealedKey(), k.KeyVersion()}})
+			return k, nil
+		}).
+		Times(times)
+}
+
+func getTestUpkMigrator(upkUtil UpkUtilService) *upkMigrator {
+	result := new(upkMigrator)
+	result.sLog = slog.Default()
+	result.upkUtil = upkUtil
+	return result
+}
+
+func getTestUpkMigratorEnabled(ctrl *gomock.Controller) *upkMigrator {
+	result := getTestUpkMigrator(getTestNotesUpkUtil())
+	result.enabled = true
+	result.legacy = true
+	result.mongo = NewMockMongo(ctrl)
+	result.mongoEnabled = true
+	result.notes = NewMockNotes(ctrl)
+	result.notesEnabled = true
+	result.repoUserKey = NewMockRepo[*entity.UserKey](ctrl)
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,311 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * upk_rotator.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/domain/repo"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+)
+
+const upkRotationDefaultBatchSize = 100
+
+var ErrUpkRotationRunning = fmt.Errorf("upk rotation is already running")
+
+// ErrUpkKeyringIncomplete в связке нет версии секрета UPK, которой записаны данные.
+var ErrUpkKeyringIncomplete = fmt.Errorf("upk keyring is incomplete")
+
+// UpkRotator фоновое перешифрование пользователей на текущую версию секрета UPK:
+// персональный ключ дешифруется версией которой он зашифрован, UPK пересчитывается
+// и данные пользователя переносятся на него так же, как в UpkMigrator. Ход
+// сохраняется после каждой порции пользователей, после перезапуска перешифрование
+// продолжается со следующего пользователя. Пользователи без зашифрованного
+// персонального ключа переносятся UpkMigrator при обращении.
+//
+// Перешифровываются только UPK и персональный ключ: тексты заметок, секреты OTP
+// и субъекты токенов обновления остаются зашифрованы (GCM) версией секрета,
+// текущей при записи, и дешифруются предыдущими версиями связки. Поэтому версии
+// из связки не удаляются, при старте это проверяет CheckUpkKeyring. Пара ключей
+// RSA не версионируется и перешифрованием не затрагивается: ею дешифруются только
+// секреты UPK из конфигурации при старте, у предыдущей версии секрета может быть
+// свой rsa_private_key_file.
+type UpkRotator interface {
+	// Rotate проход перешифрования до последнего пользователя.
+	Rotate(ctx context.Context) (UpkRotationReport, error)
+	// Report ход последнего прохода.
+	Report() UpkRotationReport
+	// Run периодическое перешифрование до отмены ctx, первый проход — сразу.
+	Run(ctx context.Context)
+}
+
+// UpkRotationReport ход перешифрования пользователей на версию секрета UPK.
+type UpkRotationReport struct {
+	KeyVersion int        `json:"key_version"`
+	Running    bool       `json:"running"`
+	Resumed    bool       `json:"resumed"`
+	AfterUpk   string     `json:"after_upk,omitempty"`
+	Rotated    int64      `json:"rotated"`
+	Failed     int64      `json:"failed"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type upkRotator struct {
+	batchSize       int
+	enabled         bool
+	interval        time.Duration
+	migrator        *upkMigrator
+	mu              sync.Mutex
+	report          UpkRotationReport
+	repoUpkRotation domain.Repo[*entity.UpkRotation]
+	repoUserKey     domain.Repo[*entity.UserKey]
+	running         atomic.Bool
+	sLog            *slog.Logger
+	upkUtil         UpkUtilService
+}
+
+var _ UpkRotator = (*upkRotator)(nil)
+var (
+	onceUpkRotator = new(sync.Once)
+	upkRotatorServ *upkRotator
+)
+
+// GetUpkRotator — потокобезопасное (thread-safe) создание
+// сервиса перешифрования пользователей на текущую версию секрета UPK.
+func GetUpkRotator(prop env.Properties) UpkRotator {
+
+	onceUpkRotator.Do(func() {
+		upkRotatorServ = new(upkRotator)
+		upkRotatorServ.batchSize = intOrDefault(prop.Config().UpkRotationBatchSize(), upkRotationDefaultBatchSize)
+		upkRotatorServ.interval = time.Duration(prop.Config().UpkRotationIntervalSec()) * time.Second
+		upkRotatorServ.migrator = GetUpkMigrator(prop).(*upkMigrator)
+		upkRotatorServ.repoUpkRotation = repo.GetUpkRotationPostgresRepo(prop)
+		upkRotatorServ.repoUserKey = repo.GetUserKeyPostgresRepo(prop)
+		upkRotatorServ.sLog = prop.Logger()
+		upkRotatorServ.upkUtil = GetUpkUtilService(prop)
+		upkRotatorServ.enabled = prop.Config().UpkRotationEnabled() &&
+			upkRotatorServ.interval > 0 &&
+			prop.DBPool() != nil
+	})
+	return upkRotatorServ
+}
+
+// CheckUpkKeyring проверка связки версий секрета UPK при старте: каждая версия,
+// которой записаны данные в PostgreSQL, должна быть в связке, иначе эти данные
+// не дешифровать и сервис не запускается.
+func CheckUpkKeyring(ctx context.Context, prop env.Properties) error {
+
+	if prop.DBPool() == nil {
+		return nil
+	}
+	return checkUpkKeyring(ctx, repo.GetUpkRotationPostgresRepo(prop), prop.UpkKeyring())
+}
+
+func (r *upkRotator) Rotate(ctx context.Context) (UpkRotationReport, error) {
+
+	if !r.running.CompareAndSwap(false, true) {
+		return UpkRotationReport{}, ErrUpkRotationRunning
+	}
+	defer r.running.Store(false)
+
+	version := r.upkUtil.KeyVersion()
+	progress, err := entity.GetUpkRotation(ctx, r.repoUpkRotation, version)
+
+	if err != nil && !entity.IsUpkRotationNotFound(progress, err) {
+		r.sLog.ErrorContext(ctx, env.MSG+"UpkRotator.Rotate", "msg", "load progress", "keyVersion", version, "err", err)
+		return UpkRotationReport{}, err
+	}
+	if entity.IsUpkRotationNotFound(progress, err) {
+		progress = entity.MakeUpkRotation(version, "", 0, 0, sql.NullTime{})
+	} else if progress.FinishedAt().Valid {
+		// проход завершён: новый начинается сначала если остались пользователи
+		// не перешифрованные из-за ошибок, иначе отчёт — завершённый проход.
+		pending, err := entity.GetUserKeysPage(ctx, r.repoUserKey, version, "", 1)
+
+		if err != nil {
+			return UpkRotationReport{}, err
+		}
+		if len(pending) == 0 {
+			report := makeUpkRotationReport(progress, false)
+			r.setReport(report)
+			return report, nil
+		}
+		progress = entity.MakeUpkRotation(version, "", 0, 0, sql.NullTime{})
+	}
+	resumed := progress.AfterUpk() != ""
+	r.sLog.InfoContext(ctx, env.MSG+"UpkRotator.Rotate",
+		"msg", "start",
+		"keyVersion", version,
+		"resumed", resumed,
+		"afterUpk", progress.AfterUpk(),
+	)
+	for after, rotated, failed := progress.AfterUpk(), progress.Rotated(), progress.Failed(); ; {
+
+		keys, err := entity.GetUserKeysPage(ctx, r.repoUserKey, version, after, r.batchSize)
+
+		if err != nil {
+			r.sLog.ErrorContext(ctx, env.MSG+"UpkRotator.Rotate", "msg", "users page", "after", after, "err", err)
+			return r.Report(), err
+		}
+		for _, key := range keys {
+			if err = r.rotateUser(ctx, key); err != nil {
+				failed++
+				r.sLog.ErrorContext(ctx, env.MSG+"UpkRotator.Rotate", "msg", "user is not rotated", "upk", key.Upk(), "err", err)
+			} else {
+				rotated++
+			}
+		}
+		if len(keys) > 0 {
+			after = keys[len(keys)-1].Upk()
+		}
+		finishedAt := sql.NullTime{}
+
+		if len(keys) < r.batchSize {
+			finishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+		progress = entity.MakeUpkRotation(version, after, rotated, failed, finishedAt)
+
+		if err = progress.Insert(ctx, r.repoUpkRotation); err != nil {
+			r.sLog.ErrorContext(ctx, env.MSG+"UpkRotator.Rotate", "msg", "save progress", "after", after, "err", err)
+			return r.Report(), err
+		}
+		report := makeUpkRotationReport(progress, !finishedAt.Valid)
+		report.Resumed = resumed
+		r.setReport(report)
+		r.sLog.InfoContext(ctx, env.MSG+"UpkRotator.Rotate",
+			"keyVersion", version,
+			"afterUpk", after,
+			"rotated", rotated,
+			"failed", failed,
+			"finished", finishedAt.Valid,
+		)
+		if finishedAt.Valid {
+			return report, nil
+		}
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+}
+
+func (r *upkRotator) Report() UpkRotationReport {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.report
+}
+
+func (r *upkRotator) Run(ctx context.Context) {
+
+	if !r.enabled {
+		return
+	}
+	_, _ = r.Rotate(ctx)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = r.Rotate(ctx)
+		}
+	}
+}
+
+// rotateUser перешифрование одного пользователя: UPK и персональный ключ
+// пересчитываются текущей версией секрета, данные переносятся на новый UPK.
+func (r *upkRotator) rotateUser(ctx context.Context, key entity.UserKey) error {
+
+	personalKey, err := r.upkUtil.OpenPersonalKey(key.SealedKey(), key.Upk(), key.KeyVersion())
+
+	if err != nil {
+		return err
+	}
+	upk, err := r.upkUtil.EncryptPersonalKey(personalKey)
+
+	if err != nil {
+		return err
+	}
+	previous := make([]string, 0, 1)
+
+	if key.Upk() != upk {
+		previous = append(previous, key.Upk())
+	}
+	_, err = r.migrator.rekey(ctx, personalKey, upk, previous)
+
+	return err
+}
+
+func (r *upkRotator) setReport(report UpkRotationReport) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report = report
+}
+
+func checkUpkKeyring(ctx context.Context, repoUpkRotation domain.Repo[*entity.UpkRotation], keyring *tool.Keyring) error {
+
+	versions, err := entity.GetUpkKeyVersions(ctx, repoUpkRotation)
+
+	if err != nil {
+		return err
+	}
+	missing := make([]int, 0)
+
+	for _, version := range versions {
+		if _, ok := keyring.Key(version); !ok {
+			missing = append(missing, version)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: versions %v are not in upk.keyring", ErrUpkKeyringIncomplete, missing)
+	}
+	return nil
+}
+
+func makeUpkRotationReport(progress entity.UpkRotation, running bool) UpkRotationReport {
+
+	report := UpkRotationReport{
+		KeyVersion: progress.KeyVersion(),
+		Running:    running,
+		AfterUpk:   progress.AfterUpk(),
+		Rotated:    progress.Rotated(),
+		Failed:     progress.Failed(),
+	}
+	if startedAt := progress.StartedAt(); !startedAt.IsZero() {
+		report.StartedAt = &startedAt
+	}
+	if updatedAt := progress.UpdatedAt(); updatedAt.Valid {
+		report.UpdatedAt = &updatedAt.Time
+	}
+	if finishedAt := progress.FinishedAt(); finishedAt.Valid {
+		report.FinishedAt = &finishedAt.Time
+	}
+	return report
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,245 @@
+/*
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * upk_rotator_test.go
+ * $Id$
+ */
+//!+
+
+// Package services сервисы бизнес логики.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vskurikhin/gofavorites/internal/domain"
+	"github.com/vskurikhin/gofavorites/internal/domain/entity"
+	"github.com/vskurikhin/gofavorites/internal/env"
+	"github.com/vskurikhin/gofavorites/internal/tool"
+	"go.uber.org/mock/gomock"
+)
+
+func TestUpkRotator(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive UpkRotator Rotate to current key version",
+			fRun: testUpkRotatorRotate,
+		},
+		{
+			name: "test #1 positive UpkRotator Rotate resumes after restart",
+			fRun: testUpkRotatorResume,
+		},
+		{
+			name: "test #2 positive UpkRotator Rotate finished pass",
+			fRun: testUpkRotatorFinished,
+		},
+		{
+			name: "test #3 negative UpkRotator Rotate user is not rotated",
+			fRun: testUpkRotatorFailed,
+		},
+		{
+			name: "test #4 negative UpkRotator Rotate already running",
+			fRun: testUpkRotatorRunning,
+		},
+		{
+			name: "test #5 negative CheckUpkKeyring version is not in keyring",
+			fRun: testCheckUpkKeyring,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testUpkRotatorRotate(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	rotator := getTestUpkRotator(ctrl)
+	previous := getTestUpkUtilServiceKeyring(tool.NewKeyring(tool.KeyVersion{Version: 1, Key: make([]byte, 32)}))
+	from, _ := previous.EncryptPersonalKey("test")
+	sealed, _ := previous.SealPersonalKey("test", from)
+	upk, _ := rotator.upkUtil.EncryptPersonalKey("test")
+
+	expectTestUpkRotationGet(rotator, nil, pgx.ErrNoRows)
+	expectTestUserKeysPage(t, rotator, []any{2, "", 10}, []any{from, sealed, 1})
+	rotator.repoUserKey.(*MockRepo[*entity.UserKey]).
+		EXPECT().
+		Update(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, k *entity.UserKey, scan func(domain.Scanner)) (*entity.UserKey, error) {
+			assert.Equal(t, upk, k.Upk())
+			assert.Equal(t, []string{from}, k.PreviousUpks())
+			assert.Equal(t, 2, k.KeyVersion())
+			scan(&stubValuesScanner{values: []any{k.Upk(), k.SealedKey(), k.KeyVersion()}})
+			return k, nil
+		}).
+		Times(1)
+	expectTestUpkRotationInsert(t, rotator, from, 1, 0, true)
+
+	got, err := rotator.Rotate(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, got.KeyVersion)
+	assert.Equal(t, int64(1), got.Rotated)
+	assert.False(t, got.Running)
+	assert.False(t, got.Resumed)
+	assert.NotNil(t, got.FinishedAt)
+	assert.Equal(t, got, rotator.Report())
+}
+
+func testUpkRotatorResume(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	rotator := getTestUpkRotator(ctrl)
+
+	expectTestUpkRotationGet(rotator, []any{2, "upk1", int64(5), int64(1), time.Now(), sql.NullTime{}}, nil)
+	expectTestUserKeysPage(t, rotator, []any{2, "upk1", 10})
+	expectTestUpkRotationInsert(t, rotator, "upk1", 5, 1, true)
+
+	got, err := rotator.Rotate(context.TODO())
+	assert.Nil(t, err)
+	assert.True(t, got.Resumed)
+	assert.Equal(t, "upk1", got.AfterUpk)
+	assert.Equal(t, int64(5), got.Rotated)
+	assert.Equal(t, int64(1), got.Failed)
+}
+
+func testUpkRotatorFinished(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	rotator := getTestUpkRotator(ctrl)
+	finishedAt := sql.NullTime{Time: time.Now(), Valid: true}
+
+	expectTestUpkRotationGet(rotator, []any{2, "upk1", int64(5), int64(0), time.Now(), finishedAt}, nil)
+	expectTestUserKeysPage(t, rotator, []any{2, "", 1})
+
+	got, err := rotator.Rotate(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), got.Rotated)
+	assert.NotNil(t, got.FinishedAt)
+	assert.NotNil(t, got.StartedAt)
+}
+
+func testUpkRotatorFailed(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	rotator := getTestUpkRotator(ctrl)
+
+	expectTestUpkRotationGet(rotator, nil, pgx.ErrNoRows)
+	expectTestUserKeysPage(t, rotator, []any{2, "", 10}, []any{"upk1", []byte{1, 2, 3}, 1})
+	expectTestUpkRotationInsert(t, rotator, "upk1", 0, 1, true)
+
+	got, err := rotator.Rotate(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), got.Rotated)
+	assert.Equal(t, int64(1), got.Failed)
+}
+
+func testUpkRotatorRunning(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	rotator := getTestUpkRotator(ctrl)
+	rotator.running.Store(true)
+
+	_, err := rotator.Rotate(context.TODO())
+	assert.ErrorIs(t, err, ErrUpkRotationRunning)
+}
+
+func testCheckUpkKeyring(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	repoUpkRotation := NewMockRepo[*entity.UpkRotation](ctrl)
+	repoUpkRotation.
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.UpkRotation, scan func(domain.Scanner) *entity.UpkRotation) ([]*entity.UpkRotation, error) {
+			for _, version := range []int{2, 1, 0} {
+				scan(&stubValuesScanner{values: []any{version}})
+			}
+			return nil, nil
+		}).
+		Times(2)
+	current := tool.KeyVersion{Version: 2, Key: make([]byte, 32)}
+	previous := tool.KeyVersion{Version: 1, Key: make([]byte, 32)}
+	err := checkUpkKeyring(context.TODO(), repoUpkRotation, tool.NewKeyring(current, previous))
+	assert.ErrorIs(t, err, ErrUpkKeyringIncomplete)
+	assert.Contains(t, err.Error(), "[0]")
+	legacy := tool.KeyVersion{Version: 0, Key: make([]byte, 32)}
+	assert.Nil(t, checkUpkKeyring(context.TODO(), repoUpkRotation, tool.NewKeyring(current, previous, legacy)))
+	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	assert.Nil(t, CheckUpkKeyring(context.TODO(), stubOutboxProperties{Properties: env.GetProperties()}))
+}
+
+func expectTestUpkRotationGet(rotator *upkRotator, values []any, err error) {
+	rotator.repoUpkRotation.(*MockRepo[*entity.UpkRotation]).
+		EXPECT().
+		Get(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.UpkRotation, scan func(domain.Scanner)) (*entity.UpkRotation, error) {
+			scan(&stubValuesScanner{err: err, values: values})
+			return r, nil
+		}).
+		Times(1)
+}
+
+func expectTestUpkRotationInsert(t *testing.T, rotator *upkRotator, after string, rotated, failed int64, finished bool) {
+	rotator.repoUpkRotation.(*MockRepo[*entity.UpkRotation]).
+		EXPECT().
+		Insert(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, r *entity.UpkRotation, scan func(domain.Scanner)) (*entity.UpkRotation, error) {
+			assert.Equal(t, after, r.AfterUpk())
+			assert.Equal(t, rotated, r.Rotated())
+			assert.Equal(t, failed, r.Failed())
+			assert.Equal(t, finished, r.FinishedAt().Valid)
+			scan(&stubValuesScanner{values: []any{
+				r.KeyVersion(), r.AfterUpk(), r.Rotated(), r.Failed(), time.Now(), r.FinishedAt(),
+			}})
+			return r, nil
+		}).
+		Times(1)
+}
+
+func expectTestUserKeysPage(t *testing.T, rotator *upkRotator, args []any, rows ...[]any) {
+	rotator.repoUserKey.(*MockRepo[*entity.UserKey]).
+		EXPECT().
+		GetByFilter(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, k *entity.UserKey, scan func(domain.Scanner) *entity.UserKey) ([]*entity.UserKey, error) {
+			assert.Equal(t, args, k.GetByFilterArgs())
+			for _, row := range rows {
+				scan(&stubValuesScanner{values: row})
+			}
+			return nil, nil
+		}).
+		Times(1)
+}
+
+func getTestUpkRotator(ctrl *gomock.Controller) *upkRotator {
+	upkUtil := getTestUpkUtilServiceKeyring(tool.NewKeyring(
+		tool.KeyVersion{Version: 2, Key: []byte("01234567890123456789012345678901")},
+		tool.KeyVersion{Version: 1, Key: make([]byte, 32)},
+	))
+	result := new(upkRotator)
+	result.batchSize = 10
+	result.migrator = getTestUpkMigrator(upkUtil)
+	result.migrator.enabled = true
+	result.migrator.repoUserKey = NewMockRepo[*entity.UserKey](ctrl)
+	result.repoUpkRotation = NewMockRepo[*entity.UpkRotation](ctrl)
+	result.repoUserKey = result.migrator.repoUserKey
+	result.sLog = slog.Default()
+	result.upkUtil = upkUtil
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
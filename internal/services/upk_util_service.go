@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 21:05 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 06:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * upk_util_service.go
@@ -12,21 +12,36 @@ package services
 import (
 	"crypto/rsa"
 	"encoding/base64"
+	"slices"
 	"sync"
 
 	"github.com/vskurikhin/gofavorites/internal/env"
 	"github.com/vskurikhin/gofavorites/internal/tool"
 )
 
+// upkBlindIndexContext метка ключа слепого индекса UPK, ключ индекса выводится
+// из секрета UPK и не совпадает с ключом шифрования.
+const upkBlindIndexContext = "gofavorites upk blind index v1"
+
+// UpkUtilService шифрование User Personal Key секретом UPK из связки версий:
+// шифрование и UPK — текущей версией, дешифрация и поиск UPK — также предыдущими.
 type UpkUtilService interface {
 	EncryptAES(plain []byte) ([]byte, error)
 	DecryptAES(bytes []byte) ([]byte, error)
+	EncryptGCM(plain []byte) ([]byte, error)
+	DecryptGCM(bytes []byte) ([]byte, error)
 	EncryptPersonalKey(personalKey string) (string, error)
+	LegacyPersonalKey(personalKey string) (string, error)
+	PreviousPersonalKeys(personalKey string, legacy bool) ([]string, error)
+	KeyVersion() int
+	SealPersonalKey(personalKey, upk string) ([]byte, error)
+	OpenPersonalKey(sealed []byte, upk string, keyVersion int) (string, error)
 	EncryptRSA(plain []byte) ([]byte, error)
 	DecryptRSA(bytes []byte) ([]byte, error)
 }
 
 type upkUtilService struct {
+	keyring       *tool.Keyring
 	rsaPrivateKey *rsa.PrivateKey
 	rsaPublicKey  *rsa.PublicKey
 	secretKey     []byte
@@ -46,7 +61,8 @@ func GetUpkUtilService(prop env.Properties) UpkUtilService {
 		upkUtilServ = new(upkUtilService)
 		upkUtilServ.rsaPrivateKey = prop.UpkRSAPrivateKey()
 		upkUtilServ.rsaPublicKey = prop.UpkRSAPublicKey()
-		upkUtilServ.secretKey = prop.UpkSecretKey()
+		upkUtilServ.keyring = prop.UpkKeyring()
+		upkUtilServ.secretKey = upkUtilServ.keyring.Current().Key
 	})
 	return upkUtilServ
 }
@@ -61,19 +77,105 @@ func (u *upkUtilService) DecryptAES(bytes []byte) ([]byte, error) {
 	return tool.DecryptAES(u.secretKey, bytes)
 }
 
-// EncryptPersonalKey шифрование User Personal Key.
+// EncryptGCM аутентифицированное симметричное шифрование.
+func (u *upkUtilService) EncryptGCM(plain []byte) ([]byte, error) {
+	return tool.EncryptGCM(u.secretKey, plain)
+}
+
+// DecryptGCM аутентифицированная симметричная дешифрация,
+// данные зашифрованные предыдущими версиями секрета тоже дешифруются.
+func (u *upkUtilService) DecryptGCM(bytes []byte) ([]byte, error) {
+
+	plain, err := tool.DecryptGCM(u.secretKey, bytes)
+
+	if err == nil {
+		return plain, nil
+	}
+	for _, key := range u.keyring.Previous() {
+		if result, er0 := tool.DecryptGCM(key.Key, bytes); er0 == nil {
+			return result, nil
+		}
+	}
+	return nil, err
+}
+
+// EncryptPersonalKey User Personal Key (UPK) — слепой индекс (blind index)
+// HMAC-SHA256 всего персонального ключа, по UPK ключ не восстанавливается.
 func (u *upkUtilService) EncryptPersonalKey(personalKey string) (string, error) {
 
-	bytes := make([]byte, 32)
-	copy(bytes, personalKey)
-	encrypted, err := u.EncryptAES(bytes)
+	return blindIndex(u.secretKey, personalKey)
+}
+
+// LegacyPersonalKey UPK прежнего формата: шифрование AES одного блока, учитывает
+// только первые 16 байт персонального ключа. Только для переноса данных на новый UPK.
+func (u *upkUtilService) LegacyPersonalKey(personalKey string) (string, error) {
+
+	return legacyPersonalKey(u.secretKey, personalKey)
+}
+
+// PreviousPersonalKeys UPK персонального ключа по предыдущим версиям секрета,
+// с legacy — и UPK прежнего формата по всем версиям, без текущего UPK и повторов.
+func (u *upkUtilService) PreviousPersonalKeys(personalKey string, legacy bool) ([]string, error) {
+
+	upk, err := u.EncryptPersonalKey(personalKey)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	upk := base64.StdEncoding.EncodeToString(encrypted)
+	result := make([]string, 0)
+	add := func(key string) {
+		if key != upk && !slices.Contains(result, key) {
+			result = append(result, key)
+		}
+	}
+	for _, key := range u.keyring.Previous() {
+		index, err := blindIndex(key.Key, personalKey)
+		if err != nil {
+			return nil, err
+		}
+		add(index)
+	}
+	if !legacy {
+		return result, nil
+	}
+	for _, key := range u.keyring.Keys() {
+		legacyUpk, err := legacyPersonalKey(key.Key, personalKey)
+		if err != nil {
+			return nil, err
+		}
+		add(legacyUpk)
+	}
+	return result, nil
+}
 
-	return upk, nil
+// KeyVersion текущая версия секрета UPK.
+func (u *upkUtilService) KeyVersion() int {
+	return u.keyring.Current().Version
+}
+
+// SealPersonalKey аутентифицированное шифрование персонального ключа
+// с привязкой к его UPK, для пересчёта UPK без участия пользователя.
+func (u *upkUtilService) SealPersonalKey(personalKey, upk string) ([]byte, error) {
+	return tool.SealGCM(u.secretKey, []byte(personalKey), []byte(upk))
+}
+
+// OpenPersonalKey дешифрация персонального ключа зашифрованного SealPersonalKey
+// секретом версии keyVersion, если версия неизвестна — остальными версиями связки.
+func (u *upkUtilService) OpenPersonalKey(sealed []byte, upk string, keyVersion int) (string, error) {
+
+	var err error
+	keys := u.keyring.Keys()
+
+	if key, ok := u.keyring.Key(keyVersion); ok {
+		keys = append([]tool.KeyVersion{{Version: keyVersion, Key: key}}, keys...)
+	}
+	for _, key := range keys {
+		var plain []byte
+		if plain, err = tool.OpenGCM(key.Key, sealed, []byte(upk)); err == nil {
+			return string(plain), nil
+		}
+	}
+	return "", err
 }
 
 // EncryptRSA шифрование RSA.
@@ -86,5 +188,31 @@ func (u *upkUtilService) DecryptRSA(bytes []byte) ([]byte, error) {
 	return tool.DecryptRSA(u.rsaPrivateKey, bytes)
 }
 
+// blindIndex слепой индекс персонального ключа секретом secretKey.
+func blindIndex(secretKey []byte, personalKey string) (string, error) {
+
+	if len(secretKey) == 0 {
+		return "", tool.ErrEncryptAES
+	}
+	index := tool.HMAC(tool.HMAC(secretKey, []byte(upkBlindIndexContext)), []byte(personalKey))
+
+	return base64.StdEncoding.EncodeToString(index), nil
+}
+
+// legacyPersonalKey UPK прежнего формата секретом secretKey.
+func legacyPersonalKey(secretKey []byte, personalKey string) (string, error) {
+
+	bytes := make([]byte, 32)
+	copy(bytes, personalKey)
+	encrypted, err := tool.EncryptAES(secretKey, bytes)
+
+	if err != nil {
+		return "", err
+	}
+	upk := base64.StdEncoding.EncodeToString(encrypted)
+
+	return upk, nil
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: ./internal/services/upk_util_service.go
+// Source: ./upk_util_service.go
 //
 // Generated by this command:
 //
-//	mockgen -source=./internal/services/upk_util_service.go -package=services
+//	mockgen -source=./upk_util_service.go -package=services
 //
 
 // Package services is a generated GoMock package.
@@ -53,6 +53,21 @@ func (mr *MockUpkUtilServiceMockRecorder) DecryptAES(bytes any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptAES", reflect.TypeOf((*MockUpkUtilService)(nil).DecryptAES), bytes)
 }
 
+// DecryptGCM mocks base method.
+func (m *MockUpkUtilService) DecryptGCM(bytes []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptGCM", bytes)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptGCM indicates an expected call of DecryptGCM.
+func (mr *MockUpkUtilServiceMockRecorder) DecryptGCM(bytes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptGCM", reflect.TypeOf((*MockUpkUtilService)(nil).DecryptGCM), bytes)
+}
+
 // DecryptRSA mocks base method.
 func (m *MockUpkUtilService) DecryptRSA(bytes []byte) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -83,6 +98,21 @@ func (mr *MockUpkUtilServiceMockRecorder) EncryptAES(plain any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptAES", reflect.TypeOf((*MockUpkUtilService)(nil).EncryptAES), plain)
 }
 
+// EncryptGCM mocks base method.
+func (m *MockUpkUtilService) EncryptGCM(plain []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncryptGCM", plain)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncryptGCM indicates an expected call of EncryptGCM.
+func (mr *MockUpkUtilServiceMockRecorder) EncryptGCM(plain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptGCM", reflect.TypeOf((*MockUpkUtilService)(nil).EncryptGCM), plain)
+}
+
 // EncryptPersonalKey mocks base method.
 func (m *MockUpkUtilService) EncryptPersonalKey(personalKey string) (string, error) {
 	m.ctrl.T.Helper()
@@ -112,3 +142,77 @@ func (mr *MockUpkUtilServiceMockRecorder) EncryptRSA(plain any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptRSA", reflect.TypeOf((*MockUpkUtilService)(nil).EncryptRSA), plain)
 }
+
+// KeyVersion mocks base method.
+func (m *MockUpkUtilService) KeyVersion() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KeyVersion")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// KeyVersion indicates an expected call of KeyVersion.
+func (mr *MockUpkUtilServiceMockRecorder) KeyVersion() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeyVersion", reflect.TypeOf((*MockUpkUtilService)(nil).KeyVersion))
+}
+
+// LegacyPersonalKey mocks base method.
+func (m *MockUpkUtilService) LegacyPersonalKey(personalKey string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LegacyPersonalKey", personalKey)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LegacyPersonalKey indicates an expected call of LegacyPersonalKey.
+func (mr *MockUpkUtilServiceMockRecorder) LegacyPersonalKey(personalKey any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LegacyPersonalKey", reflect.TypeOf((*MockUpkUtilService)(nil).LegacyPersonalKey), personalKey)
+}
+
+// OpenPersonalKey mocks base method.
+func (m *MockUpkUtilService) OpenPersonalKey(sealed []byte, upk string, keyVersion int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenPersonalKey", sealed, upk, keyVersion)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenPersonalKey indicates an expected call of OpenPersonalKey.
+func (mr *MockUpkUtilServiceMockRecorder) OpenPersonalKey(sealed, upk, keyVersion any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenPersonalKey", reflect.TypeOf((*MockUpkUtilService)(nil).OpenPersonalKey), sealed, upk, keyVersion)
+}
+
+// PreviousPersonalKeys mocks base method.
+func (m *MockUpkUtilService) PreviousPersonalKeys(personalKey string, legacy bool) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PreviousPersonalKeys", personalKey, legacy)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PreviousPersonalKeys indicates an expected call of PreviousPersonalKeys.
+func (mr *MockUpkUtilServiceMockRecorder) PreviousPersonalKeys(personalKey, legacy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreviousPersonalKeys", reflect.TypeOf((*MockUpkUtilService)(nil).PreviousPersonalKeys), personalKey, legacy)
+}
+
+// SealPersonalKey mocks base method.
+func (m *MockUpkUtilService) SealPersonalKey(personalKey, upk string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SealPersonalKey", personalKey, upk)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SealPersonalKey indicates an expected call of SealPersonalKey.
+func (mr *MockUpkUtilServiceMockRecorder) SealPersonalKey(personalKey, upk any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SealPersonalKey", reflect.TypeOf((*MockUpkUtilService)(nil).SealPersonalKey), personalKey, upk)
+}
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-29 13:57 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * upk_util_service_test.go
@@ -40,6 +40,26 @@ func TestUpkUtilService(t *testing.T) {
 			name: "positive test #3 User Service AES case #2",
 			fRun: testUpkUtilServiceEncryptPersonalKeyPositiveCase2,
 		},
+		{
+			name: "positive test #4 User Service AES-GCM",
+			fRun: testUpkUtilServiceGCMPositiveCase,
+		},
+		{
+			name: "positive test #5 User Service blind index uses whole personal key",
+			fRun: testUpkUtilServiceEncryptPersonalKeyPrefix,
+		},
+		{
+			name: "positive test #6 User Service legacy UPK",
+			fRun: testUpkUtilServiceLegacyPersonalKey,
+		},
+		{
+			name: "positive test #7 User Service seal personal key",
+			fRun: testUpkUtilServiceSealPersonalKey,
+		},
+		{
+			name: "positive test #8 User Service keyring rotation",
+			fRun: testUpkUtilServiceKeyring,
+		},
 	}
 	assert.NotNil(t, t)
 	for _, test := range tests {
@@ -79,7 +99,7 @@ func testUpkUtilServiceAESPositiveCase(t *testing.T) {
 
 func testUpkUtilServiceEncryptPersonalKeyPositiveCase(t *testing.T) {
 	test := "test"
-	expected := "CdTLuDHCHE1DrSaSm2WZtgAAAAAAAAAAAAAAAAAAAAA="
+	expected := "hug09hvvQXjLOhOzjpaZoHWSPwz2a2mLJSNM9Jw/dmA="
 	secretKey := []byte{48, 17, 60, 87, 186, 101, 173, 89, 205, 24, 23, 245, 219, 42, 222, 100}
 	srv := getTestUpkUtilService(nil, nil, secretKey)
 	encrypt, err := srv.EncryptPersonalKey(test)
@@ -90,11 +110,12 @@ func testUpkUtilServiceEncryptPersonalKeyPositiveCase(t *testing.T) {
 func testUpkUtilServiceEncryptPersonalKeyPositiveCase2(t *testing.T) {
 
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	t.Setenv("UPK_PRIVATE_KEY_FILE", "test_private-key.pem")
 	t.Setenv("UPK_PUBLIC_KEY_FILE", "test_public-key.pem")
 	t.Setenv("UPK_SECRET", "qYhaPtg+PIQtBhAU5fHCeQw7XIF3WLKoLPZnJgq1H//DDOB8o2qrP9goVCUZldOdwqLAHxWOGHuvXcwaIFRrD8I3Hz5tRCgCeI+cEZD9h4c4h6ADSjkcrPXg5eRwnANasBkKKZQz8noYwvt9Z9p7HdOtrBmQOi7OVjTfY0T2SnI=")
 
-	expected := "ZRiw9fJPhGsLDByaA9eQDAAAAAAAAAAAAAAAAAAAAAA="
+	expected := "o/dC39D3Huj5tPaGb9TI1kNHw/O8N2iz1sI83s4hH94="
 	prop := env.GetProperties()
 	srv := GetUpkUtilService(prop)
 	test := "test"
@@ -103,13 +124,114 @@ func testUpkUtilServiceEncryptPersonalKeyPositiveCase2(t *testing.T) {
 	assert.Equal(t, expected, encrypt)
 }
 
+func testUpkUtilServiceGCMPositiveCase(t *testing.T) {
+	secretKey := make([]byte, 32)
+	if _, err := rand.Reader.Read(secretKey); err != nil {
+		t.Fail()
+	}
+	expected := "рентгеноэлектрокардиографический"
+	srv := getTestUpkUtilService(nil, nil, secretKey)
+	encrypt, err := srv.EncryptGCM([]byte(expected))
+	assert.Nil(t, err)
+	got, err := srv.DecryptGCM(encrypt)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(got))
+}
+
+func testUpkUtilServiceEncryptPersonalKeyPrefix(t *testing.T) {
+	srv := getTestUpkUtilService(nil, nil, make([]byte, 32))
+	prefix := "0123456789abcdef"
+	x, err := srv.EncryptPersonalKey(prefix + "x")
+	assert.Nil(t, err)
+	y, err := srv.EncryptPersonalKey(prefix + "y")
+	assert.Nil(t, err)
+	assert.NotEqual(t, x, y)
+	legacyX, err := srv.LegacyPersonalKey(prefix + "x")
+	assert.Nil(t, err)
+	legacyY, err := srv.LegacyPersonalKey(prefix + "y")
+	assert.Nil(t, err)
+	assert.Equal(t, legacyX, legacyY, "UPK прежнего формата учитывает только 16 байт")
+	_, err = getTestUpkUtilService(nil, nil, nil).EncryptPersonalKey("test")
+	assert.NotNil(t, err)
+}
+
+func testUpkUtilServiceLegacyPersonalKey(t *testing.T) {
+	expected := "CdTLuDHCHE1DrSaSm2WZtgAAAAAAAAAAAAAAAAAAAAA="
+	secretKey := []byte{48, 17, 60, 87, 186, 101, 173, 89, 205, 24, 23, 245, 219, 42, 222, 100}
+	srv := getTestUpkUtilService(nil, nil, secretKey)
+	got, err := srv.LegacyPersonalKey("test")
+	assert.Nil(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func testUpkUtilServiceSealPersonalKey(t *testing.T) {
+	srv := getTestUpkUtilService(nil, nil, make([]byte, 32))
+	sealed, err := srv.SealPersonalKey("test", "upk1")
+	assert.Nil(t, err)
+	got, err := srv.OpenPersonalKey(sealed, "upk1", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", got)
+	_, err = srv.OpenPersonalKey(sealed, "upk2", 0)
+	assert.NotNil(t, err)
+}
+
+func testUpkUtilServiceKeyring(t *testing.T) {
+	v1 := tool.KeyVersion{Version: 1, Key: make([]byte, 32)}
+	v2 := tool.KeyVersion{Version: 2, Key: []byte("01234567890123456789012345678901")}
+	old := getTestUpkUtilServiceKeyring(tool.NewKeyring(v1))
+	srv := getTestUpkUtilServiceKeyring(tool.NewKeyring(v2, v1))
+	assert.Equal(t, 2, srv.KeyVersion())
+
+	oldUpk, err := old.EncryptPersonalKey("test")
+	assert.Nil(t, err)
+	oldLegacy, err := old.LegacyPersonalKey("test")
+	assert.Nil(t, err)
+	upk, err := srv.EncryptPersonalKey("test")
+	assert.Nil(t, err)
+	assert.NotEqual(t, oldUpk, upk)
+	legacy, err := srv.LegacyPersonalKey("test")
+	assert.Nil(t, err)
+
+	got, err := srv.PreviousPersonalKeys("test", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{oldUpk}, got)
+	got, err = srv.PreviousPersonalKeys("test", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{oldUpk, legacy, oldLegacy}, got)
+
+	sealed, err := old.SealPersonalKey("test", oldUpk)
+	assert.Nil(t, err)
+	personalKey, err := srv.OpenPersonalKey(sealed, oldUpk, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", personalKey)
+	personalKey, err = srv.OpenPersonalKey(sealed, oldUpk, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", personalKey)
+
+	encrypted, err := old.EncryptGCM([]byte("test"))
+	assert.Nil(t, err)
+	plain, err := srv.DecryptGCM(encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("test"), plain)
+	_, err = getTestUpkUtilServiceKeyring(tool.NewKeyring(v2)).DecryptGCM(encrypted)
+	assert.NotNil(t, err)
+}
+
 func getTestUpkUtilService(privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey, secretKey []byte) UpkUtilService {
 	u := new(upkUtilService)
 	u.rsaPrivateKey = privateKey
 	u.rsaPublicKey = publicKey
+	u.keyring = tool.NewKeyring(tool.KeyVersion{Key: secretKey})
 	u.secretKey = secretKey
 	return u
 }
 
+func getTestUpkUtilServiceKeyring(keyring *tool.Keyring) UpkUtilService {
+	u := new(upkUtilService)
+	u.keyring = keyring
+	u.secretKey = keyring.Current().Key
+	return u
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
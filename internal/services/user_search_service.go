@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-08-06 20:32 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 09:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * user_search_service.go
@@ -22,10 +22,6 @@ import (
 	"github.com/vskurikhin/gofavorites/internal/env"
 	"github.com/vskurikhin/gofavorites/internal/models"
 	"github.com/vskurikhin/gofavorites/internal/tool"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/encoding/gzip"
 
 	pb "github.com/vskurikhin/gofavorites/proto"
 )
@@ -35,9 +31,7 @@ type UserSearchService interface {
 }
 
 type userSearchService struct {
-	authGRPCAddress string
-	creds           credentials.TransportCredentials
-	opts            []grpc.DialOption
+	authGRPC        *tool.GRPCClient
 	repoUser        domain.Repo[*entity.User]
 	requestInterval time.Duration
 	sLog            *slog.Logger
@@ -55,17 +49,7 @@ func GetUserSearchService(prop env.Properties) UserSearchService {
 
 	onceUserSearch.Do(func() {
 		userSearchServ = new(userSearchService)
-		opts := []grpc.DialOption{
-			grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
-		}
-		tlsCredentials, err := tool.LoadClientTLSCredentials(prop.Config().GRPCTLSCAFile())
-		if err != nil {
-			opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		} else {
-			opts = append(opts, grpc.WithTransportCredentials(tlsCredentials))
-		}
-		userSearchServ.authGRPCAddress = prop.ExternalAuthGRPCAddress()
-		userSearchServ.opts = opts
+		userSearchServ.authGRPC = prop.ExternalAuthGRPCClient()
 		userSearchServ.repoUser = repo.GetUserPostgresCachedRepo(prop)
 		userSearchServ.requestInterval = prop.ExternalRequestTimeoutInterval()
 		userSearchServ.sLog = prop.Logger()
@@ -125,11 +109,10 @@ func (u *userSearchService) dbLookup(ctx context.Context, upk string) bool {
 
 func (u *userSearchService) grpcLookupPersonalKey(ctx context.Context, personalKey string) bool {
 
-	conn, err := grpc.NewClient(u.authGRPCAddress, u.opts...)
+	conn, err := u.authGRPC.Conn()
 	if err != nil {
 		return false
 	}
-	defer func() { _ = conn.Close() }()
 	c := pb.NewUserServiceClient(conn)
 	ctx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
 	defer func() {
@@ -156,11 +139,10 @@ func (u *userSearchService) grpcLookupPersonalKey(ctx context.Context, personalK
 
 func (u *userSearchService) grpcLookupUpk(ctx context.Context, upk string) bool {
 
-	conn, err := grpc.NewClient(u.authGRPCAddress, u.opts...)
+	conn, err := u.authGRPC.Conn()
 	if err != nil {
 		return false
 	}
-	defer func() { _ = conn.Close() }()
 	c := pb.NewUserServiceClient(conn)
 	ctx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
 	defer func() {
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-20 19:34 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 10:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * user_search_service_test.go
@@ -66,6 +66,7 @@ func TestUserSearchService(t *testing.T) {
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	t.Setenv("GO_FAVORITES_SKIP_LOAD_CONFIG", "True")
+	t.Setenv("JWT_SECRET", testJwtSecret)
 	t.Setenv("ASSET_GRPC_ADDRESS", fmt.Sprintf("127.0.0.1:%d", 65501+rnd.Intn(34)))
 	t.Setenv("AUTH_GRPC_ADDRESS", fmt.Sprintf("127.0.0.1:%d", 65501+rnd.Intn(34)))
 	t.Setenv("REQUEST_TIMEOUT_INTERVAL_MS", "500")
@@ -211,8 +212,7 @@ func getUserSearchService(prop env.Properties, repoUser domain.Repo[*entity.User
 		grpc.WithNoProxy(),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	}
-	userSearchServ.authGRPCAddress = prop.ExternalAuthGRPCAddress()
-	userSearchServ.opts = opts
+	userSearchServ.authGRPC = tool.GRPCConnect(prop.ExternalAuthGRPCAddress(), tool.GRPCOptions{}, opts...)
 	userSearchServ.repoUser = repoUser
 	userSearchServ.requestInterval = prop.ExternalRequestTimeoutInterval()
 	userSearchServ.sLog = prop.Logger()
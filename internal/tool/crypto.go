@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-29 19:00 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 05:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * crypto.go
@@ -12,12 +12,16 @@ package tool
 
 import (
 	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"fmt"
 )
 
 var (
+	ErrDecryptGCM = fmt.Errorf("decrypt with AES-GCM")
 	ErrEncryptAES = fmt.Errorf("encrypt with AES")
 	ErrEncryptRSA = fmt.Errorf("encrypt with RSA")
 	ErrDecryptRSA = fmt.Errorf("decrypt with RSA")
@@ -49,6 +53,69 @@ func DecryptAES(secretKey, bytes []byte) ([]byte, error) {
 	return plain, nil
 }
 
+// EncryptGCM аутентифицированное шифрование AES-GCM, результат: nonce || ciphertext.
+func EncryptGCM(secretKey, plain []byte) ([]byte, error) {
+	return SealGCM(secretKey, plain, nil)
+}
+
+// SealGCM аутентифицированное шифрование AES-GCM с привязкой к дополнительным
+// данным additional, они не шифруются, но без них расшифровать нельзя.
+func SealGCM(secretKey, plain, additional []byte) ([]byte, error) {
+
+	aead, err := newGCM(secretKey)
+
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, additional), nil
+}
+
+// DecryptGCM дешифрация и проверка подлинности данных зашифрованных EncryptGCM.
+func DecryptGCM(secretKey, bytes []byte) ([]byte, error) {
+	return OpenGCM(secretKey, bytes, nil)
+}
+
+// OpenGCM дешифрация и проверка подлинности данных зашифрованных SealGCM
+// с теми же дополнительными данными additional.
+func OpenGCM(secretKey, bytes, additional []byte) ([]byte, error) {
+
+	aead, err := newGCM(secretKey)
+
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes) < aead.NonceSize() {
+		return nil, ErrDecryptGCM
+	}
+	nonce, ciphertext := bytes[:aead.NonceSize()], bytes[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, ciphertext, additional)
+}
+
+// HMAC код аутентификации HMAC-SHA256 сообщения message на ключе secretKey.
+func HMAC(secretKey, message []byte) []byte {
+
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write(message)
+
+	return mac.Sum(nil)
+}
+
+func newGCM(secretKey []byte) (cipher.AEAD, error) {
+
+	cipherBlock, err := aes.NewCipher(secretKey)
+
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(cipherBlock)
+}
+
 func EncryptRSA(rsaPublicKey *rsa.PublicKey, plain []byte) ([]byte, error) {
 
 	if rsaPublicKey != nil {
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-29 16:49 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 05:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * crypto_test.go
@@ -16,6 +16,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -46,6 +47,22 @@ func TestCrypto(t *testing.T) {
 			name: "negative test #4 RSA",
 			fRun: testRSANegativeCase,
 		},
+		{
+			name: "positive test #5 AES-GCM",
+			fRun: testGCMPositiveCase,
+		},
+		{
+			name: "negative test #6 AES-GCM",
+			fRun: testGCMNegativeCase,
+		},
+		{
+			name: "positive test #7 AES-GCM with additional data",
+			fRun: testGCMAdditionalCase,
+		},
+		{
+			name: "positive test #8 HMAC",
+			fRun: testHMACCase,
+		},
 	}
 	assert.NotNil(t, t)
 	for _, test := range tests {
@@ -108,5 +125,58 @@ func testRSANegativeCase(t *testing.T) {
 	assert.Equal(t, expected, string(got))
 }
 
+func testGCMPositiveCase(t *testing.T) {
+	secret := make([]byte, 32)
+	if _, err := rand.Reader.Read(secret); err != nil {
+		t.Fail()
+	}
+	expected := "This information is longer than one AES block"
+	encrypt, err := EncryptGCM(secret, []byte(expected))
+	assert.Nil(t, err)
+	got, err := DecryptGCM(secret, encrypt)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(got))
+	other, err := EncryptGCM(secret, []byte(expected))
+	assert.Nil(t, err)
+	assert.NotEqual(t, encrypt, other)
+}
+
+func testGCMNegativeCase(t *testing.T) {
+	secret := make([]byte, 32)
+	_, err := EncryptGCM(nil, []byte("test"))
+	assert.NotNil(t, err)
+	_, err = DecryptGCM(secret, []byte("test"))
+	assert.NotNil(t, err)
+	encrypt, err := EncryptGCM(secret, []byte("test"))
+	assert.Nil(t, err)
+	encrypt[len(encrypt)-1] ^= 0xff
+	_, err = DecryptGCM(secret, encrypt)
+	assert.NotNil(t, err)
+}
+
+func testGCMAdditionalCase(t *testing.T) {
+	secret := make([]byte, 32)
+	expected := "personal key"
+	encrypt, err := SealGCM(secret, []byte(expected), []byte("upk1"))
+	assert.Nil(t, err)
+	got, err := OpenGCM(secret, encrypt, []byte("upk1"))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(got))
+	_, err = OpenGCM(secret, encrypt, []byte("upk2"))
+	assert.NotNil(t, err)
+	_, err = DecryptGCM(secret, encrypt)
+	assert.NotNil(t, err)
+}
+
+func testHMACCase(t *testing.T) {
+	secret := make([]byte, 32)
+	prefix := strings.Repeat("a", 16)
+	x := HMAC(secret, []byte(prefix+"x"))
+	assert.Len(t, x, 32)
+	assert.Equal(t, x, HMAC(secret, []byte(prefix+"x")))
+	assert.NotEqual(t, x, HMAC(secret, []byte(prefix+"y")))
+	assert.NotEqual(t, x, HMAC([]byte("other"), []byte(prefix+"x")))
+}
+
 //!-
 /* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,187 @@
+/*
+ * This file was last modified at 2024-08-18 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * grpc_client.go
+ * $Id$
+ */
+//!+
+
+// Package tool TODO.
+package tool
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// ErrGRPCUnavailable адрес внешнего gRPC-сервиса не задан или клиент не создан.
+var ErrGRPCUnavailable = fmt.Errorf("grpc client is unavailable")
+
+// grpcRoundRobin балансировка вызовов между всеми доступными адресами.
+const grpcRoundRobin = `{"loadBalancingConfig": [{"round_robin":{}}]}`
+
+var grpcResolverSeq atomic.Int64
+
+// GRPCOptions параметры клиента gRPC, нулевые значения — умолчания.
+//
+// Умолчание KeepaliveTime пять минут: сервер gRPC по умолчанию разрывает
+// соединение с клиентом, который проверяет его чаще.
+type GRPCOptions struct {
+	BackoffMaxDelay  time.Duration
+	ConnectTimeout   time.Duration
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+}
+
+// GRPCClientStats состояние соединения клиента gRPC.
+type GRPCClientStats struct {
+	Addresses []string `json:"addresses"`
+	State     string   `json:"state"`
+}
+
+// GRPCClient долгоживущий клиент внешнего gRPC-сервиса: одно соединение на адрес,
+// вызовы распределяются между адресами по кругу, после разрыва соединение
+// восстанавливается с экспоненциальной задержкой. Клиент потокобезопасен.
+type GRPCClient struct {
+	addresses []string
+	conn      *grpc.ClientConn
+	err       error
+}
+
+// GRPCConnect клиент gRPC-сервиса по адресам addresses, перечисленным через запятую.
+// Один адрес разрешается через DNS и все его IP-адреса участвуют в балансировке.
+// Соединение устанавливается в фоне, ошибка разбора адресов возвращается из Conn.
+func GRPCConnect(addresses string, opts GRPCOptions, dialOpts ...grpc.DialOption) *GRPCClient {
+
+	gc := &GRPCClient{addresses: splitGRPCAddresses(addresses)}
+
+	if len(gc.addresses) == 0 {
+		gc.err = ErrGRPCUnavailable
+		return gc
+	}
+	target := gc.addresses[0]
+	dialOpts = append(dialOpts, opts.dialOptions()...)
+
+	if len(gc.addresses) > 1 {
+		r := manual.NewBuilderWithScheme(fmt.Sprintf("favorites-%d", grpcResolverSeq.Add(1)))
+		state := resolver.State{}
+		for _, address := range gc.addresses {
+			state.Addresses = append(state.Addresses, resolver.Address{Addr: address})
+		}
+		r.InitialState(state)
+		target = r.Scheme() + ":///" + strings.Join(gc.addresses, ",")
+		dialOpts = append(dialOpts, grpc.WithResolvers(r))
+	}
+	if gc.conn, gc.err = grpc.NewClient(target, dialOpts...); gc.err != nil {
+		sLog.Error(MSG+"GRPCConnect", "err", gc.err)
+		return gc
+	}
+	gc.conn.Connect()
+
+	return gc
+}
+
+// Addresses адреса gRPC-сервиса.
+func (gc *GRPCClient) Addresses() []string {
+
+	if gc == nil {
+		return nil
+	}
+	return gc.addresses
+}
+
+// Close закрытие соединений клиента при выключении сервиса.
+func (gc *GRPCClient) Close() error {
+
+	conn, err := gc.Conn()
+
+	if err != nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// Conn соединение клиента, общее для всех вызовов.
+func (gc *GRPCClient) Conn() (*grpc.ClientConn, error) {
+
+	if gc == nil {
+		return nil, ErrGRPCUnavailable
+	}
+	if gc.err != nil {
+		return nil, gc.err
+	}
+	return gc.conn, nil
+}
+
+// Connect выход соединения из простоя (idle) без ожидания первого вызова.
+func (gc *GRPCClient) Connect() {
+	if conn, err := gc.Conn(); err == nil {
+		conn.Connect()
+	}
+}
+
+// State состояние соединения, для недоступного клиента connectivity.Shutdown.
+func (gc *GRPCClient) State() connectivity.State {
+
+	conn, err := gc.Conn()
+
+	if err != nil {
+		return connectivity.Shutdown
+	}
+	return conn.GetState()
+}
+
+// Stats адреса и состояние соединения для проверки готовности.
+func (gc *GRPCClient) Stats() GRPCClientStats {
+	return GRPCClientStats{Addresses: gc.Addresses(), State: gc.State().String()}
+}
+
+func (o GRPCOptions) dialOptions() []grpc.DialOption {
+
+	backoffConfig := backoff.DefaultConfig
+	params := keepalive.ClientParameters{Time: 5 * time.Minute, Timeout: 20 * time.Second}
+	connectParams := grpc.ConnectParams{Backoff: backoffConfig, MinConnectTimeout: 20 * time.Second}
+
+	if o.BackoffMaxDelay > 0 {
+		connectParams.Backoff.MaxDelay = o.BackoffMaxDelay
+	}
+	if o.ConnectTimeout > 0 {
+		connectParams.MinConnectTimeout = o.ConnectTimeout
+	}
+	if o.KeepaliveTime > 0 {
+		params.Time = o.KeepaliveTime
+	}
+	if o.KeepaliveTimeout > 0 {
+		params.Timeout = o.KeepaliveTimeout
+	}
+	return []grpc.DialOption{
+		grpc.WithConnectParams(connectParams),
+		grpc.WithDefaultServiceConfig(grpcRoundRobin),
+		grpc.WithKeepaliveParams(params),
+	}
+}
+
+func splitGRPCAddresses(addresses string) []string {
+
+	result := make([]string, 0)
+
+	for _, address := range strings.Split(addresses, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -0,0 +1,163 @@
+/*
+ * This file was last modified at 2024-08-18 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * grpc_client_test.go
+ * $Id$
+ */
+//!+
+
+// Package tool TODO.
+package tool
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+func TestGRPCClient(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #0 positive round robin across addresses",
+			fRun: testGRPCClientRoundRobin,
+		},
+		{
+			name: "test #1 positive reconnect after server restart",
+			fRun: testGRPCClientReconnect,
+		},
+		{
+			name: "test #2 negative empty address and nil client",
+			fRun: testGRPCClientNegative,
+		},
+		{
+			name: "test #3 positive split addresses",
+			fRun: testGRPCClientSplitAddresses,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testGRPCClientRoundRobin(t *testing.T) {
+
+	var calls [2]atomic.Int64
+	addresses := make([]string, 0, 2)
+
+	for i := range calls {
+		listen, server := getTestGRPCServer(t, "127.0.0.1:0", &calls[i])
+		defer server.Stop()
+		addresses = append(addresses, listen)
+	}
+	client := GRPCConnect(addresses[0]+", "+addresses[1], GRPCOptions{}, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	defer func() { assert.Nil(t, client.Close()) }()
+	assert.Equal(t, addresses, client.Addresses())
+
+	conn, err := client.Conn()
+	assert.Nil(t, err)
+	healthClient := grpc_health_v1.NewHealthClient(conn)
+
+	for i := 0; i < 10; i++ {
+		_, err = healthClient.Check(context.TODO(), &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+		assert.Nil(t, err)
+	}
+	assert.Greater(t, calls[0].Load(), int64(0))
+	assert.Greater(t, calls[1].Load(), int64(0))
+	assert.Equal(t, connectivity.Ready.String(), client.Stats().State)
+}
+
+func testGRPCClientReconnect(t *testing.T) {
+
+	var calls atomic.Int64
+	address, server := getTestGRPCServer(t, "127.0.0.1:0", &calls)
+	client := GRPCConnect(address, GRPCOptions{BackoffMaxDelay: 100 * time.Millisecond}, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	defer func() { _ = client.Close() }()
+
+	conn, err := client.Conn()
+	assert.Nil(t, err)
+	healthClient := grpc_health_v1.NewHealthClient(conn)
+	_, err = healthClient.Check(context.TODO(), &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+	assert.Nil(t, err)
+
+	server.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.True(t, conn.WaitForStateChange(ctx, connectivity.Ready))
+
+	_, server = getTestGRPCServer(t, address, &calls)
+	defer server.Stop()
+
+	_, err = healthClient.Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), calls.Load())
+}
+
+func testGRPCClientNegative(t *testing.T) {
+
+	client := GRPCConnect(" , ", GRPCOptions{})
+	_, err := client.Conn()
+	assert.Equal(t, ErrGRPCUnavailable, err)
+	assert.Equal(t, connectivity.Shutdown, client.State())
+	assert.Nil(t, client.Close())
+	client.Connect()
+
+	var nilClient *GRPCClient
+	_, err = nilClient.Conn()
+	assert.Equal(t, ErrGRPCUnavailable, err)
+	assert.Equal(t, GRPCClientStats{State: connectivity.Shutdown.String()}, nilClient.Stats())
+	assert.Nil(t, nilClient.Close())
+
+	client = GRPCConnect("127.0.0.1:1", GRPCOptions{})
+	_, err = client.Conn()
+	assert.NotNil(t, err, "без учётных данных транспорта клиент не создаётся")
+}
+
+func testGRPCClientSplitAddresses(t *testing.T) {
+	assert.Equal(t, []string{"a:1", "b:2"}, splitGRPCAddresses(" a:1,,b:2 "))
+	assert.Equal(t, []string{}, splitGRPCAddresses(""))
+}
+
+// countingHealthServer сервер проверки состояния, считающий вызовы.
+type countingHealthServer struct {
+	*health.Server
+	calls *atomic.Int64
+}
+
+func (s countingHealthServer) Check(ctx context.Context, r *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if _, ok := peer.FromContext(ctx); ok {
+		s.calls.Add(1)
+	}
+	return s.Server.Check(ctx, r)
+}
+
+func getTestGRPCServer(t *testing.T, address string, calls *atomic.Int64) (string, *grpc.Server) {
+
+	listen, err := net.Listen("tcp", address)
+	assert.Nil(t, err)
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, countingHealthServer{Server: health.NewServer(), calls: calls})
+	go func() { _ = server.Serve(listen) }()
+
+	return listen.Addr().String(), server
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
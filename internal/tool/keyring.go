@@ -0,0 +1,80 @@
+/*
+ * This file was last modified at 2024-08-18 06:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * keyring.go
+ * $Id$
+ */
+//!+
+
+// Package tool TODO.
+package tool
+
+import (
+	"cmp"
+	"slices"
+)
+
+// KeyVersion версия ключа связки.
+type KeyVersion struct {
+	Version int
+	Key     []byte
+}
+
+// Keyring связка версий симметричного ключа: текущая версия для шифрования
+// и предыдущие только для дешифрации и поиска ранее записанных данных.
+type Keyring struct {
+	current  KeyVersion
+	previous []KeyVersion
+}
+
+// NewKeyring связка с текущим ключом current, предыдущие ключи previous
+// упорядочиваются от новых к старым, версия current среди них пропускается.
+func NewKeyring(current KeyVersion, previous ...KeyVersion) *Keyring {
+
+	result := &Keyring{current: current, previous: make([]KeyVersion, 0, len(previous))}
+
+	for _, key := range previous {
+		if key.Version != current.Version && !slices.ContainsFunc(result.previous, func(k KeyVersion) bool {
+			return k.Version == key.Version
+		}) {
+			result.previous = append(result.previous, key)
+		}
+	}
+	slices.SortFunc(result.previous, func(x, y KeyVersion) int {
+		return cmp.Compare(y.Version, x.Version)
+	})
+	return result
+}
+
+// Current текущая версия ключа.
+func (k *Keyring) Current() KeyVersion {
+	return k.current
+}
+
+// Key ключ версии version.
+func (k *Keyring) Key(version int) ([]byte, bool) {
+
+	if version == k.current.Version {
+		return k.current.Key, true
+	}
+	for _, key := range k.previous {
+		if key.Version == version {
+			return key.Key, true
+		}
+	}
+	return nil, false
+}
+
+// Keys все версии ключа, первой текущая.
+func (k *Keyring) Keys() []KeyVersion {
+	return append([]KeyVersion{k.current}, k.previous...)
+}
+
+// Previous предыдущие версии ключа от новых к старым.
+func (k *Keyring) Previous() []KeyVersion {
+	return slices.Clone(k.previous)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
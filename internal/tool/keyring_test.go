@@ -0,0 +1,68 @@
+/*
+ * This file was last modified at 2024-08-18 06:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * keyring_test.go
+ * $Id$
+ */
+//!+
+
+// Package tool TODO.
+package tool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyring(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "positive test #0 Keyring versions",
+			fRun: testKeyringVersions,
+		},
+		{
+			name: "negative test #1 Keyring unknown version",
+			fRun: testKeyringUnknown,
+		},
+	}
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testKeyringVersions(t *testing.T) {
+	keyring := NewKeyring(
+		KeyVersion{Version: 3, Key: []byte{3}},
+		KeyVersion{Version: 1, Key: []byte{1}},
+		KeyVersion{Version: 3, Key: []byte{33}},
+		KeyVersion{Version: 2, Key: []byte{2}},
+		KeyVersion{Version: 1, Key: []byte{11}},
+	)
+	assert.Equal(t, 3, keyring.Current().Version)
+	assert.Equal(t, []KeyVersion{{Version: 2, Key: []byte{2}}, {Version: 1, Key: []byte{1}}}, keyring.Previous())
+	assert.Len(t, keyring.Keys(), 3)
+	key, ok := keyring.Key(3)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{3}, key)
+	key, ok = keyring.Key(1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{1}, key)
+}
+
+func testKeyringUnknown(t *testing.T) {
+	keyring := NewKeyring(KeyVersion{Version: 1, Key: []byte{1}})
+	_, ok := keyring.Key(2)
+	assert.False(t, ok)
+	assert.Len(t, keyring.Previous(), 0)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-29 15:38 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 07:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * load_rsa_key.go
@@ -29,13 +29,7 @@ func LoadPrivateKey(fileName string) *rsa.PrivateKey {
 		if err != nil {
 			return nil
 		}
-		if block := readPEMString(string(buf)); block != nil {
-			privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-			if err != nil {
-				return nil
-			}
-			return privateKey
-		}
+		return ParsePrivateKey(buf)
 	}
 	return nil
 }
@@ -51,13 +45,31 @@ func LoadPublicKey(fileName string) *rsa.PublicKey {
 		if err != nil {
 			return nil
 		}
-		if block := readPEMString(string(buf)); block != nil {
-			publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
-			if err != nil {
-				return nil
-			}
-			return publicKey
+		return ParsePublicKey(buf)
+	}
+	return nil
+}
+
+// ParsePrivateKey RSA ключ PKCS #1 в формате PEM, nil если ключ не разобран.
+func ParsePrivateKey(buf []byte) *rsa.PrivateKey {
+	if block := readPEMString(string(buf)); block != nil {
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil
+		}
+		return privateKey
+	}
+	return nil
+}
+
+// ParsePublicKey открытый RSA ключ PKCS #1 в формате PEM, nil если ключ не разобран.
+func ParsePublicKey(buf []byte) *rsa.PublicKey {
+	if block := readPEMString(string(buf)); block != nil {
+		publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil
 		}
+		return publicKey
 	}
 	return nil
 }
@@ -1,5 +1,5 @@
 /*
- * This file was last modified at 2024-07-29 13:43 by Victor N. Skurikhin.
+ * This file was last modified at 2024-08-18 07:00 by Victor N. Skurikhin.
  * This is free and unencumbered software released into the public domain.
  * For more information, please refer to <http://unlicense.org>
  * load_rsa_key_test.go
@@ -53,6 +53,11 @@ func TestLoadKeys(t *testing.T) {
 	rsaPublicKey := LoadPublicKey(testPublicKeyFileName)
 	assert.Equal(t, publicKey, rsaPublicKey)
 	_ = os.RemoveAll(testPublicKeyFileName)
+
+	assert.Equal(t, privateKey, ParsePrivateKey([]byte(privateStr)))
+	assert.Equal(t, publicKey, ParsePublicKey([]byte(publicKeyStr)))
+	assert.Nil(t, ParsePrivateKey([]byte(publicKeyStr)))
+	assert.Nil(t, ParsePublicKey([]byte("test")))
 }
 
 func exportRsaPrivateKeyAsPemStr(privateKey *rsa.PrivateKey) string {
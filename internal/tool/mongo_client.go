@@ -0,0 +1,217 @@
+/*
+ * This file was last modified at 2024-08-18 02:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * mongo_client.go
+ * $Id$
+ */
+//!+
+
+// Package tool TODO.
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// ErrMongoUnavailable подключение к MongoDB выключено или клиент не создан.
+var ErrMongoUnavailable = fmt.Errorf("mongodb client is unavailable")
+
+// MongoOptions параметры клиента MongoDB, нулевые значения — умолчания драйвера.
+type MongoOptions struct {
+	ConnectTimeout         time.Duration
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	ReadConcern            string
+	ServerSelectionTimeout time.Duration
+	Timeout                time.Duration
+	WriteConcern           string
+}
+
+// MongoPoolStats статистика пула соединений клиента MongoDB с момента запуска.
+type MongoPoolStats struct {
+	CheckedOut     int64  `json:"checked_out"`
+	CheckoutFailed int64  `json:"checkout_failed"`
+	Cleared        int64  `json:"cleared"`
+	Closed         int64  `json:"closed"`
+	Created        int64  `json:"created"`
+	InUse          int64  `json:"in_use"`
+	MaxPoolSize    uint64 `json:"max_pool_size"`
+	Open           int64  `json:"open"`
+}
+
+// MongoClient долгоживущий клиент MongoDB: пул соединений ведёт драйвер,
+// клиент потокобезопасен и общий для всех хранилищ.
+type MongoClient struct {
+	client         *mongo.Client
+	err            error
+	maxPoolSize    uint64
+	checkedIn      atomic.Int64
+	checkedOut     atomic.Int64
+	checkoutFailed atomic.Int64
+	cleared        atomic.Int64
+	closed         atomic.Int64
+	created        atomic.Int64
+}
+
+// MongodbConnect клиент MongoDB по строке подключения dsn. Соединения открываются
+// по требованию, ошибка разбора dsn или параметров возвращается из Client.
+func MongodbConnect(dsn string, opts MongoOptions) *MongoClient {
+
+	mc := &MongoClient{maxPoolSize: opts.MaxPoolSize}
+	clientOptions, err := opts.clientOptions(dsn)
+
+	if err != nil {
+		mc.err = err
+		sLog.Error(MSG+"MongodbConnect", "err", err)
+		return mc
+	}
+	clientOptions.SetPoolMonitor(&event.PoolMonitor{Event: mc.poolEvent})
+
+	if mc.client, mc.err = mongo.Connect(context.Background(), clientOptions); mc.err != nil {
+		sLog.Error(MSG+"MongodbConnect", "err", mc.err)
+	}
+	return mc
+}
+
+// Client клиент драйвера MongoDB.
+func (mc *MongoClient) Client() (*mongo.Client, error) {
+
+	if mc == nil {
+		return nil, ErrMongoUnavailable
+	}
+	if mc.err != nil {
+		return nil, mc.err
+	}
+	return mc.client, nil
+}
+
+// Collection коллекция collection базы данных dbname.
+func (mc *MongoClient) Collection(dbname, collection string) (*mongo.Collection, error) {
+
+	client, err := mc.Client()
+
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(dbname).Collection(collection), nil
+}
+
+// Database база данных dbname.
+func (mc *MongoClient) Database(dbname string) (*mongo.Database, error) {
+
+	client, err := mc.Client()
+
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(dbname), nil
+}
+
+// Disconnect закрытие соединений клиента при выключении сервиса.
+func (mc *MongoClient) Disconnect(ctx context.Context) error {
+
+	client, err := mc.Client()
+
+	if err != nil {
+		return nil
+	}
+	return client.Disconnect(ctx)
+}
+
+// Ping проверка доступности основного (primary) узла MongoDB для проверки готовности.
+func (mc *MongoClient) Ping(ctx context.Context) error {
+
+	client, err := mc.Client()
+
+	if err != nil {
+		return err
+	}
+	return client.Ping(ctx, readpref.Primary())
+}
+
+// Stats статистика пула соединений.
+func (mc *MongoClient) Stats() MongoPoolStats {
+
+	if mc == nil {
+		return MongoPoolStats{}
+	}
+	created, closed := mc.created.Load(), mc.closed.Load()
+	checkedOut, checkedIn := mc.checkedOut.Load(), mc.checkedIn.Load()
+
+	return MongoPoolStats{
+		CheckedOut:     checkedOut,
+		CheckoutFailed: mc.checkoutFailed.Load(),
+		Cleared:        mc.cleared.Load(),
+		Closed:         closed,
+		Created:        created,
+		InUse:          checkedOut - checkedIn,
+		MaxPoolSize:    mc.maxPoolSize,
+		Open:           created - closed,
+	}
+}
+
+func (mc *MongoClient) poolEvent(e *event.PoolEvent) {
+
+	switch e.Type {
+	case event.ConnectionCreated:
+		mc.created.Add(1)
+	case event.ConnectionClosed:
+		mc.closed.Add(1)
+	case event.GetSucceeded:
+		mc.checkedOut.Add(1)
+	case event.ConnectionReturned:
+		mc.checkedIn.Add(1)
+	case event.GetFailed:
+		mc.checkoutFailed.Add(1)
+	case event.PoolCleared:
+		mc.cleared.Add(1)
+	}
+}
+
+func (o MongoOptions) clientOptions(dsn string) (*options.ClientOptions, error) {
+
+	clientOptions := options.Client().ApplyURI(dsn)
+
+	if o.ConnectTimeout > 0 {
+		clientOptions.SetConnectTimeout(o.ConnectTimeout)
+	}
+	if o.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(o.MaxPoolSize)
+	}
+	if o.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(o.MinPoolSize)
+	}
+	if o.ServerSelectionTimeout > 0 {
+		clientOptions.SetServerSelectionTimeout(o.ServerSelectionTimeout)
+	}
+	if o.Timeout > 0 {
+		clientOptions.SetTimeout(o.Timeout)
+	}
+	if level := strings.TrimSpace(o.ReadConcern); level != "" {
+		clientOptions.SetReadConcern(&readconcern.ReadConcern{Level: level})
+	}
+	if w := strings.TrimSpace(o.WriteConcern); w != "" {
+		wc := &writeconcern.WriteConcern{W: w}
+		if n, err := strconv.Atoi(w); err == nil {
+			wc.W = n
+		}
+		clientOptions.SetWriteConcern(wc)
+	}
+	return clientOptions, clientOptions.Validate()
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
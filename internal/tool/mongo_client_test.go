@@ -0,0 +1,142 @@
+/*
+ * This file was last modified at 2024-08-18 02:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * mongo_client_test.go
+ * $Id$
+ */
+//!+
+
+// Package tool TODO.
+package tool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestMongodb(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{
+			name: "test #1 positive ",
+			fRun: testMongodbConnect,
+		},
+		{
+			name: "test #2 negative ",
+			fRun: testMongodbConnectNegative,
+		},
+		{
+			name: "test #3 negative ",
+			fRun: testMongodbNilClient,
+		},
+		{
+			name: "test #4 positive options",
+			fRun: testMongodbClientOptions,
+		},
+		{
+			name: "test #5 positive stats",
+			fRun: testMongodbStats,
+		},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testMongodbConnect(t *testing.T) {
+	got := MongodbConnect("mongodb://localhost:1/db", MongoOptions{ServerSelectionTimeout: 50 * time.Millisecond})
+	assert.NotNil(t, got)
+	client, err := got.Client()
+	assert.NotNil(t, client)
+	assert.Nil(t, err)
+	collection, err := got.Collection("db", "favorites")
+	assert.Nil(t, err)
+	assert.Equal(t, "favorites", collection.Name())
+	assert.NotNil(t, got.Ping(context.TODO()), "сервер недоступен")
+	assert.Nil(t, got.Disconnect(context.TODO()))
+}
+
+func testMongodbConnectNegative(t *testing.T) {
+	got := MongodbConnect("", MongoOptions{})
+	assert.NotNil(t, got)
+	client, err := got.Client()
+	assert.Nil(t, client)
+	assert.NotNil(t, err)
+	_, err = got.Database("db")
+	assert.NotNil(t, err)
+	assert.NotNil(t, got.Ping(context.TODO()))
+	assert.Nil(t, got.Disconnect(context.TODO()))
+}
+
+func testMongodbNilClient(t *testing.T) {
+	var got *MongoClient
+	_, err := got.Collection("db", "favorites")
+	assert.ErrorIs(t, err, ErrMongoUnavailable)
+	assert.ErrorIs(t, got.Ping(context.TODO()), ErrMongoUnavailable)
+	assert.Equal(t, MongoPoolStats{}, got.Stats())
+}
+
+func testMongodbClientOptions(t *testing.T) {
+	opts := MongoOptions{
+		ConnectTimeout:         time.Second,
+		MaxPoolSize:            16,
+		MinPoolSize:            2,
+		ReadConcern:            "majority",
+		ServerSelectionTimeout: 2 * time.Second,
+		Timeout:                3 * time.Second,
+		WriteConcern:           "2",
+	}
+	got, err := opts.clientOptions("mongodb://localhost:27017")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, *got.ConnectTimeout)
+	assert.Equal(t, uint64(16), *got.MaxPoolSize)
+	assert.Equal(t, uint64(2), *got.MinPoolSize)
+	assert.Equal(t, "majority", got.ReadConcern.Level)
+	assert.Equal(t, 2*time.Second, *got.ServerSelectionTimeout)
+	assert.Equal(t, 3*time.Second, *got.Timeout)
+	assert.Equal(t, 2, got.WriteConcern.W)
+
+	opts.WriteConcern = "majority"
+	got, err = opts.clientOptions("mongodb://localhost:27017")
+	assert.Nil(t, err)
+	assert.Equal(t, "majority", got.WriteConcern.W)
+
+	opts.MinPoolSize = 32
+	_, err = opts.clientOptions("mongodb://localhost:27017")
+	assert.NotNil(t, err, "min_pool_size больше max_pool_size")
+}
+
+func testMongodbStats(t *testing.T) {
+	mc := &MongoClient{maxPoolSize: 4}
+	for _, typ := range []string{
+		event.ConnectionCreated, event.ConnectionCreated, event.ConnectionClosed,
+		event.GetSucceeded, event.GetSucceeded, event.ConnectionReturned,
+		event.GetFailed, event.PoolCleared, event.PoolReady,
+	} {
+		mc.poolEvent(&event.PoolEvent{Type: typ})
+	}
+	assert.Equal(t, MongoPoolStats{
+		CheckedOut:     2,
+		CheckoutFailed: 1,
+		Cleared:        1,
+		Closed:         1,
+		Created:        2,
+		InUse:          1,
+		MaxPoolSize:    4,
+		Open:           1,
+	}, mc.Stats())
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
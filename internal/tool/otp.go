@@ -0,0 +1,117 @@
+/*
+ * This file was last modified at 2024-08-13 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * otp.go
+ * $Id$
+ */
+//!+
+
+// Package tool TODO.
+package tool
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	OtpDefaultDigits = 6
+	OtpDefaultPeriod = 30
+	otpSecretSize    = 20
+	otpCodeDigits    = "0123456789"
+)
+
+var (
+	ErrOtpDigits = fmt.Errorf("OTP digits out of range")
+	ErrOtpPeriod = fmt.Errorf("OTP period must be positive")
+	otpPow10     = [...]uint32{1, 10, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000}
+)
+
+// HOTP одноразовый пароль по счётчику (RFC 4226).
+func HOTP(key []byte, counter uint64, digits int) (string, error) {
+
+	if digits < 1 || digits >= len(otpPow10) {
+		return "", ErrOtpDigits
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, code%otpPow10[digits]), nil
+}
+
+// TOTP одноразовый пароль по времени (RFC 6238).
+func TOTP(key []byte, t time.Time, period, digits int) (string, error) {
+
+	if period < 1 {
+		return "", ErrOtpPeriod
+	}
+	return HOTP(key, uint64(t.Unix())/uint64(period), digits)
+}
+
+// DecodeOtpSecret разбор секрета в кодировке base32 без учёта регистра, пробелов и выравнивания.
+func DecodeOtpSecret(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(s, "="))
+}
+
+// EncodeOtpSecret кодирование секрета в base32 без выравнивания.
+func EncodeOtpSecret(key []byte) string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+}
+
+// GenerateOtpSecret случайный секрет TOTP/HOTP.
+func GenerateOtpSecret() ([]byte, error) {
+
+	key := make([]byte, otpSecretSize)
+
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GenerateOtpCodes список одноразовых кодов активации из цифр заданной длины.
+func GenerateOtpCodes(count, length int) ([]string, error) {
+
+	codes := make([]string, 0, count)
+	max := big.NewInt(int64(len(otpCodeDigits)))
+
+	for i := 0; i < count; i++ {
+		b := make([]byte, length)
+		for j := range b {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, err
+			}
+			b[j] = otpCodeDigits[n.Int64()]
+		}
+		codes = append(codes, string(b))
+	}
+	return codes, nil
+}
+
+// OtpCodeHash хэш одноразового кода, в базе данных коды в открытом виде не хранятся.
+func OtpCodeHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
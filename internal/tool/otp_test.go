@@ -0,0 +1,105 @@
+/*
+ * This file was last modified at 2024-08-13 09:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * otp_test.go
+ * $Id$
+ */
+//!+
+
+// Package tool TODO.
+package tool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOtp(t *testing.T) {
+	var tests = []struct {
+		name string
+		fRun func(*testing.T)
+	}{
+		{name: "positive test #0 HOTP RFC 4226", fRun: testHOTP},
+		{name: "positive test #1 TOTP RFC 6238", fRun: testTOTP},
+		{name: "positive test #2 OTP secret encode/decode", fRun: testOtpSecret},
+		{name: "positive test #3 OTP codes", fRun: testOtpCodes},
+		{name: "negative test #4 OTP digits and period", fRun: testOtpNegative},
+	}
+
+	assert.NotNil(t, t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fRun(t)
+		})
+	}
+}
+
+func testHOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for i, want := range expected {
+		got, err := HOTP(key, uint64(i), 6)
+		assert.Nil(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func testTOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	expected := map[int64]string{
+		59:         "94287082",
+		1111111109: "07081804",
+		1111111111: "14050471",
+		1234567890: "89005924",
+		2000000000: "69279037",
+	}
+	for sec, want := range expected {
+		got, err := TOTP(key, time.Unix(sec, 0), 30, 8)
+		assert.Nil(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func testOtpSecret(t *testing.T) {
+	key, err := GenerateOtpSecret()
+	assert.Nil(t, err)
+	assert.Len(t, key, otpSecretSize)
+	encoded := EncodeOtpSecret(key)
+	decoded, err := DecodeOtpSecret(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, key, decoded)
+	decoded, err = DecodeOtpSecret("gezd gnbv gy3t qojq")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1234567890"), decoded)
+}
+
+func testOtpCodes(t *testing.T) {
+	codes, err := GenerateOtpCodes(10, 8)
+	assert.Nil(t, err)
+	assert.Len(t, codes, 10)
+	for _, code := range codes {
+		assert.Len(t, code, 8)
+		assert.Len(t, OtpCodeHash(code), 64)
+	}
+	assert.NotEqual(t, OtpCodeHash("1"), OtpCodeHash("2"))
+}
+
+func testOtpNegative(t *testing.T) {
+	_, err := HOTP(nil, 0, 0)
+	assert.Equal(t, ErrOtpDigits, err)
+	_, err = HOTP(nil, 0, 9)
+	assert.Equal(t, ErrOtpDigits, err)
+	_, err = TOTP(nil, time.Now(), 0, 6)
+	assert.Equal(t, ErrOtpPeriod, err)
+	_, err = DecodeOtpSecret("1")
+	assert.NotNil(t, err)
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
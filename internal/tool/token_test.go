@@ -0,0 +1,33 @@
+/*
+ * This file was last modified at 2024-08-15 14:00 by Victor N. Skurikhin.
+ * This is free and unencumbered software released into the public domain.
+ * For more information, please refer to <http://unlicense.org>
+ * token_test.go
+ * $Id$
+ */
+//!+
+
+// Package tool TODO.
+package tool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	token1, err := GenerateToken(32)
+	assert.Nil(t, err)
+	assert.Len(t, token1, 43)
+	token2, err := GenerateToken(32)
+	assert.Nil(t, err)
+	assert.NotEqual(t, token1, token2)
+	assert.Len(t, TokenHash(token1), 64)
+	assert.Equal(t, TokenHash(token1), TokenHash(token1))
+	assert.NotEqual(t, TokenHash(token1), TokenHash(token2))
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", TokenHash("hello"))
+}
+
+//!-
+/* vim: set tabstop=4 softtabstop=4 shiftwidth=4 noexpandtab: */
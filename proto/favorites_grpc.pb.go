@@ -22,6 +22,7 @@ const (
 	FavoritesService_Get_FullMethodName        = "/proto.FavoritesService/Get"
 	FavoritesService_GetForUser_FullMethodName = "/proto.FavoritesService/GetForUser"
 	FavoritesService_Set_FullMethodName        = "/proto.FavoritesService/Set"
+	FavoritesService_Delete_FullMethodName     = "/proto.FavoritesService/Delete"
 )
 
 // FavoritesServiceClient is the client API for FavoritesService service.
@@ -31,6 +32,7 @@ type FavoritesServiceClient interface {
 	Get(ctx context.Context, in *FavoritesRequest, opts ...grpc.CallOption) (*FavoritesResponse, error)
 	GetForUser(ctx context.Context, in *UserFavoritesRequest, opts ...grpc.CallOption) (*UserFavoritesResponse, error)
 	Set(ctx context.Context, in *FavoritesRequest, opts ...grpc.CallOption) (*FavoritesResponse, error)
+	Delete(ctx context.Context, in *FavoritesRequest, opts ...grpc.CallOption) (*FavoritesResponse, error)
 }
 
 type favoritesServiceClient struct {
@@ -71,6 +73,16 @@ func (c *favoritesServiceClient) Set(ctx context.Context, in *FavoritesRequest,
 	return out, nil
 }
 
+func (c *favoritesServiceClient) Delete(ctx context.Context, in *FavoritesRequest, opts ...grpc.CallOption) (*FavoritesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FavoritesResponse)
+	err := c.cc.Invoke(ctx, FavoritesService_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FavoritesServiceServer is the server API for FavoritesService service.
 // All implementations must embed UnimplementedFavoritesServiceServer
 // for forward compatibility
@@ -78,6 +90,7 @@ type FavoritesServiceServer interface {
 	Get(context.Context, *FavoritesRequest) (*FavoritesResponse, error)
 	GetForUser(context.Context, *UserFavoritesRequest) (*UserFavoritesResponse, error)
 	Set(context.Context, *FavoritesRequest) (*FavoritesResponse, error)
+	Delete(context.Context, *FavoritesRequest) (*FavoritesResponse, error)
 	mustEmbedUnimplementedFavoritesServiceServer()
 }
 
@@ -94,6 +107,9 @@ func (UnimplementedFavoritesServiceServer) GetForUser(context.Context, *UserFavo
 func (UnimplementedFavoritesServiceServer) Set(context.Context, *FavoritesRequest) (*FavoritesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
 }
+func (UnimplementedFavoritesServiceServer) Delete(context.Context, *FavoritesRequest) (*FavoritesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
 func (UnimplementedFavoritesServiceServer) mustEmbedUnimplementedFavoritesServiceServer() {}
 
 // UnsafeFavoritesServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -161,6 +177,24 @@ func _FavoritesService_Set_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FavoritesService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FavoritesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FavoritesServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FavoritesService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FavoritesServiceServer).Delete(ctx, req.(*FavoritesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FavoritesService_ServiceDesc is the grpc.ServiceDesc for FavoritesService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -180,6 +214,10 @@ var FavoritesService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Set",
 			Handler:    _FavoritesService_Set_Handler,
 		},
+		{
+			MethodName: "Delete",
+			Handler:    _FavoritesService_Delete_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/favorites.proto",
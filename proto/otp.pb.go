@@ -0,0 +1,755 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.27.1
+// source: proto/otp.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type OtpKind int32
+
+const (
+	OtpKind_TOTP OtpKind = 0
+	OtpKind_HOTP OtpKind = 1
+)
+
+// Enum value maps for OtpKind.
+var (
+	OtpKind_name = map[int32]string{
+		0: "TOTP",
+		1: "HOTP",
+	}
+	OtpKind_value = map[string]int32{
+		"TOTP": 0,
+		"HOTP": 1,
+	}
+)
+
+func (x OtpKind) Enum() *OtpKind {
+	p := new(OtpKind)
+	*p = x
+	return p
+}
+
+func (x OtpKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OtpKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_otp_proto_enumTypes[0].Descriptor()
+}
+
+func (OtpKind) Type() protoreflect.EnumType {
+	return &file_proto_otp_proto_enumTypes[0]
+}
+
+func (x OtpKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OtpKind.Descriptor instead.
+func (OtpKind) EnumDescriptor() ([]byte, []int) {
+	return file_proto_otp_proto_rawDescGZIP(), []int{0}
+}
+
+type OtpSecret struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	User   *User   `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`                     // пользователь
+	Name   string  `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                     // наименование секрета
+	Kind   OtpKind `protobuf:"varint,3,opt,name=kind,proto3,enum=proto.OtpKind" json:"kind,omitempty"` // алгоритм TOTP или HOTP
+	Secret string  `protobuf:"bytes,4,opt,name=secret,proto3" json:"secret,omitempty"`                 // секрет в кодировке base32, при пустом значении генерируется
+	Digits int32   `protobuf:"varint,5,opt,name=digits,proto3" json:"digits,omitempty"`                // количество цифр в коде, по умолчанию 6
+	Period int32   `protobuf:"varint,6,opt,name=period,proto3" json:"period,omitempty"`                // период TOTP в секундах, по умолчанию 30
+}
+
+func (x *OtpSecret) Reset() {
+	*x = OtpSecret{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_otp_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtpSecret) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtpSecret) ProtoMessage() {}
+
+func (x *OtpSecret) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_otp_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtpSecret.ProtoReflect.Descriptor instead.
+func (*OtpSecret) Descriptor() ([]byte, []int) {
+	return file_proto_otp_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *OtpSecret) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *OtpSecret) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *OtpSecret) GetKind() OtpKind {
+	if x != nil {
+		return x.Kind
+	}
+	return OtpKind_TOTP
+}
+
+func (x *OtpSecret) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+func (x *OtpSecret) GetDigits() int32 {
+	if x != nil {
+		return x.Digits
+	}
+	return 0
+}
+
+func (x *OtpSecret) GetPeriod() int32 {
+	if x != nil {
+		return x.Period
+	}
+	return 0
+}
+
+type OtpSecretRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Secret     *OtpSecret `protobuf:"bytes,1,opt,name=secret,proto3" json:"secret,omitempty"`                            // секрет
+	CodesCount int32      `protobuf:"varint,2,opt,name=codes_count,json=codesCount,proto3" json:"codes_count,omitempty"` // количество одноразовых кодов активации
+}
+
+func (x *OtpSecretRequest) Reset() {
+	*x = OtpSecretRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_otp_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtpSecretRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtpSecretRequest) ProtoMessage() {}
+
+func (x *OtpSecretRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_otp_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtpSecretRequest.ProtoReflect.Descriptor instead.
+func (*OtpSecretRequest) Descriptor() ([]byte, []int) {
+	return file_proto_otp_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *OtpSecretRequest) GetSecret() *OtpSecret {
+	if x != nil {
+		return x.Secret
+	}
+	return nil
+}
+
+func (x *OtpSecretRequest) GetCodesCount() int32 {
+	if x != nil {
+		return x.CodesCount
+	}
+	return 0
+}
+
+type OtpSecretResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Secret *OtpSecret `protobuf:"bytes,1,opt,name=secret,proto3" json:"secret,omitempty"`
+	Codes  []string   `protobuf:"bytes,2,rep,name=codes,proto3" json:"codes,omitempty"` // одноразовые коды активации, возвращаются только один раз
+	Status Status     `protobuf:"varint,3,opt,name=status,proto3,enum=proto.Status" json:"status,omitempty"`
+	Error  string     `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *OtpSecretResponse) Reset() {
+	*x = OtpSecretResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_otp_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtpSecretResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtpSecretResponse) ProtoMessage() {}
+
+func (x *OtpSecretResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_otp_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtpSecretResponse.ProtoReflect.Descriptor instead.
+func (*OtpSecretResponse) Descriptor() ([]byte, []int) {
+	return file_proto_otp_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OtpSecretResponse) GetSecret() *OtpSecret {
+	if x != nil {
+		return x.Secret
+	}
+	return nil
+}
+
+func (x *OtpSecretResponse) GetCodes() []string {
+	if x != nil {
+		return x.Codes
+	}
+	return nil
+}
+
+func (x *OtpSecretResponse) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_UNKNOWN
+}
+
+func (x *OtpSecretResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type OtpRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	User *User  `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"` // пользователь
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"` // наименование секрета
+}
+
+func (x *OtpRequest) Reset() {
+	*x = OtpRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_otp_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtpRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtpRequest) ProtoMessage() {}
+
+func (x *OtpRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_otp_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtpRequest.ProtoReflect.Descriptor instead.
+func (*OtpRequest) Descriptor() ([]byte, []int) {
+	return file_proto_otp_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *OtpRequest) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *OtpRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type OtpCodeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code   string `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"` // текущий код
+	Status Status `protobuf:"varint,2,opt,name=status,proto3,enum=proto.Status" json:"status,omitempty"`
+	Error  string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *OtpCodeResponse) Reset() {
+	*x = OtpCodeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_otp_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtpCodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtpCodeResponse) ProtoMessage() {}
+
+func (x *OtpCodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_otp_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtpCodeResponse.ProtoReflect.Descriptor instead.
+func (*OtpCodeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_otp_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *OtpCodeResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *OtpCodeResponse) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_UNKNOWN
+}
+
+func (x *OtpCodeResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type OtpConsumeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	User *User  `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"` // пользователь
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"` // наименование секрета
+	Code string `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"` // одноразовый код активации
+}
+
+func (x *OtpConsumeRequest) Reset() {
+	*x = OtpConsumeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_otp_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtpConsumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtpConsumeRequest) ProtoMessage() {}
+
+func (x *OtpConsumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_otp_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtpConsumeRequest.ProtoReflect.Descriptor instead.
+func (*OtpConsumeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_otp_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *OtpConsumeRequest) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *OtpConsumeRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *OtpConsumeRequest) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+type OtpConsumeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Status Status `protobuf:"varint,1,opt,name=status,proto3,enum=proto.Status" json:"status,omitempty"`
+	Error  string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *OtpConsumeResponse) Reset() {
+	*x = OtpConsumeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_otp_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtpConsumeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtpConsumeResponse) ProtoMessage() {}
+
+func (x *OtpConsumeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_otp_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtpConsumeResponse.ProtoReflect.Descriptor instead.
+func (*OtpConsumeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_otp_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *OtpConsumeResponse) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_UNKNOWN
+}
+
+func (x *OtpConsumeResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_proto_otp_proto protoreflect.FileDescriptor
+
+var file_proto_otp_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6f, 0x74, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x12, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
+	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x10, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2f, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xac,
+	0x01, 0x0a, 0x09, 0x4f, 0x74, 0x70, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x12, 0x1f, 0x0a, 0x04,
+	0x75, 0x73, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x52, 0x04, 0x75, 0x73, 0x65, 0x72, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x22, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x0e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x74, 0x70, 0x4b, 0x69, 0x6e, 0x64, 0x52,
+	0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x64, 0x69, 0x67, 0x69, 0x74, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x64,
+	0x69, 0x67, 0x69, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x22, 0x5d, 0x0a,
+	0x10, 0x4f, 0x74, 0x70, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x28, 0x0a, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x74, 0x70, 0x53, 0x65, 0x63,
+	0x72, 0x65, 0x74, 0x52, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x63,
+	0x6f, 0x64, 0x65, 0x73, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x0a, 0x63, 0x6f, 0x64, 0x65, 0x73, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x90, 0x01, 0x0a,
+	0x11, 0x4f, 0x74, 0x70, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x28, 0x0a, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x74, 0x70, 0x53, 0x65,
+	0x63, 0x72, 0x65, 0x74, 0x52, 0x06, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x12, 0x14, 0x0a, 0x05,
+	0x63, 0x6f, 0x64, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x63, 0x6f, 0x64,
+	0x65, 0x73, 0x12, 0x25, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22,
+	0x41, 0x0a, 0x0a, 0x4f, 0x74, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1f, 0x0a,
+	0x04, 0x75, 0x73, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x52, 0x04, 0x75, 0x73, 0x65, 0x72, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x22, 0x62, 0x0a, 0x0f, 0x4f, 0x74, 0x70, 0x43, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x25, 0x0a, 0x06, 0x73, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x5c, 0x0a, 0x11, 0x4f, 0x74, 0x70, 0x43, 0x6f, 0x6e,
+	0x73, 0x75, 0x6d, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1f, 0x0a, 0x04, 0x75,
+	0x73, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x52, 0x04, 0x75, 0x73, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x63, 0x6f, 0x64, 0x65, 0x22, 0x51, 0x0a, 0x12, 0x4f, 0x74, 0x70, 0x43, 0x6f, 0x6e, 0x73, 0x75,
+	0x6d, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x25, 0x0a, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x2a, 0x1d, 0x0a, 0x07, 0x4f, 0x74, 0x70, 0x4b, 0x69,
+	0x6e, 0x64, 0x12, 0x08, 0x0a, 0x04, 0x54, 0x4f, 0x54, 0x50, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04,
+	0x48, 0x4f, 0x54, 0x50, 0x10, 0x01, 0x32, 0xb9, 0x01, 0x0a, 0x0a, 0x4f, 0x74, 0x70, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x38, 0x0a, 0x03, 0x41, 0x64, 0x64, 0x12, 0x17, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x74, 0x70, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x74,
+	0x70, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x31, 0x0a, 0x04, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x4f, 0x74, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x4f, 0x74, 0x70, 0x43, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x3e, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x12, 0x18, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x74, 0x70, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x4f, 0x74, 0x70, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x42, 0x49, 0x0a, 0x0e, 0x73, 0x75, 0x2e, 0x73, 0x76, 0x6e, 0x2e, 0x67, 0x61, 0x74,
+	0x65, 0x77, 0x61, 0x79, 0x42, 0x0c, 0x4f, 0x74, 0x70, 0x47, 0x72, 0x70, 0x63, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x50, 0x01, 0x5a, 0x27, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x76, 0x73, 0x6b, 0x75, 0x72, 0x69, 0x6b, 0x68, 0x69, 0x6e, 0x2f, 0x67, 0x6f, 0x66, 0x61,
+	0x76, 0x6f, 0x72, 0x69, 0x74, 0x65, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_proto_otp_proto_rawDescOnce sync.Once
+	file_proto_otp_proto_rawDescData = file_proto_otp_proto_rawDesc
+)
+
+func file_proto_otp_proto_rawDescGZIP() []byte {
+	file_proto_otp_proto_rawDescOnce.Do(func() {
+		file_proto_otp_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_otp_proto_rawDescData)
+	})
+	return file_proto_otp_proto_rawDescData
+}
+
+var file_proto_otp_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_otp_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_proto_otp_proto_goTypes = []any{
+	(OtpKind)(0),               // 0: proto.OtpKind
+	(*OtpSecret)(nil),          // 1: proto.OtpSecret
+	(*OtpSecretRequest)(nil),   // 2: proto.OtpSecretRequest
+	(*OtpSecretResponse)(nil),  // 3: proto.OtpSecretResponse
+	(*OtpRequest)(nil),         // 4: proto.OtpRequest
+	(*OtpCodeResponse)(nil),    // 5: proto.OtpCodeResponse
+	(*OtpConsumeRequest)(nil),  // 6: proto.OtpConsumeRequest
+	(*OtpConsumeResponse)(nil), // 7: proto.OtpConsumeResponse
+	(*User)(nil),               // 8: proto.User
+	(Status)(0),                // 9: proto.Status
+}
+var file_proto_otp_proto_depIdxs = []int32{
+	8,  // 0: proto.OtpSecret.user:type_name -> proto.User
+	0,  // 1: proto.OtpSecret.kind:type_name -> proto.OtpKind
+	1,  // 2: proto.OtpSecretRequest.secret:type_name -> proto.OtpSecret
+	1,  // 3: proto.OtpSecretResponse.secret:type_name -> proto.OtpSecret
+	9,  // 4: proto.OtpSecretResponse.status:type_name -> proto.Status
+	8,  // 5: proto.OtpRequest.user:type_name -> proto.User
+	9,  // 6: proto.OtpCodeResponse.status:type_name -> proto.Status
+	8,  // 7: proto.OtpConsumeRequest.user:type_name -> proto.User
+	9,  // 8: proto.OtpConsumeResponse.status:type_name -> proto.Status
+	2,  // 9: proto.OtpService.Add:input_type -> proto.OtpSecretRequest
+	4,  // 10: proto.OtpService.Code:input_type -> proto.OtpRequest
+	6,  // 11: proto.OtpService.Consume:input_type -> proto.OtpConsumeRequest
+	3,  // 12: proto.OtpService.Add:output_type -> proto.OtpSecretResponse
+	5,  // 13: proto.OtpService.Code:output_type -> proto.OtpCodeResponse
+	7,  // 14: proto.OtpService.Consume:output_type -> proto.OtpConsumeResponse
+	12, // [12:15] is the sub-list for method output_type
+	9,  // [9:12] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_proto_otp_proto_init() }
+func file_proto_otp_proto_init() {
+	if File_proto_otp_proto != nil {
+		return
+	}
+	file_proto_status_proto_init()
+	file_proto_user_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_proto_otp_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*OtpSecret); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_otp_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*OtpSecretRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_otp_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*OtpSecretResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_otp_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*OtpRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_otp_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*OtpCodeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_otp_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*OtpConsumeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_otp_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*OtpConsumeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proto_otp_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_otp_proto_goTypes,
+		DependencyIndexes: file_proto_otp_proto_depIdxs,
+		EnumInfos:         file_proto_otp_proto_enumTypes,
+		MessageInfos:      file_proto_otp_proto_msgTypes,
+	}.Build()
+	File_proto_otp_proto = out.File
+	file_proto_otp_proto_rawDesc = nil
+	file_proto_otp_proto_goTypes = nil
+	file_proto_otp_proto_depIdxs = nil
+}
@@ -0,0 +1,186 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             v5.27.1
+// source: proto/otp.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	OtpService_Add_FullMethodName     = "/proto.OtpService/Add"
+	OtpService_Code_FullMethodName    = "/proto.OtpService/Code"
+	OtpService_Consume_FullMethodName = "/proto.OtpService/Consume"
+)
+
+// OtpServiceClient is the client API for OtpService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OtpServiceClient interface {
+	Add(ctx context.Context, in *OtpSecretRequest, opts ...grpc.CallOption) (*OtpSecretResponse, error)
+	Code(ctx context.Context, in *OtpRequest, opts ...grpc.CallOption) (*OtpCodeResponse, error)
+	Consume(ctx context.Context, in *OtpConsumeRequest, opts ...grpc.CallOption) (*OtpConsumeResponse, error)
+}
+
+type otpServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOtpServiceClient(cc grpc.ClientConnInterface) OtpServiceClient {
+	return &otpServiceClient{cc}
+}
+
+func (c *otpServiceClient) Add(ctx context.Context, in *OtpSecretRequest, opts ...grpc.CallOption) (*OtpSecretResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OtpSecretResponse)
+	err := c.cc.Invoke(ctx, OtpService_Add_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *otpServiceClient) Code(ctx context.Context, in *OtpRequest, opts ...grpc.CallOption) (*OtpCodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OtpCodeResponse)
+	err := c.cc.Invoke(ctx, OtpService_Code_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *otpServiceClient) Consume(ctx context.Context, in *OtpConsumeRequest, opts ...grpc.CallOption) (*OtpConsumeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OtpConsumeResponse)
+	err := c.cc.Invoke(ctx, OtpService_Consume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OtpServiceServer is the server API for OtpService service.
+// All implementations must embed UnimplementedOtpServiceServer
+// for forward compatibility
+type OtpServiceServer interface {
+	Add(context.Context, *OtpSecretRequest) (*OtpSecretResponse, error)
+	Code(context.Context, *OtpRequest) (*OtpCodeResponse, error)
+	Consume(context.Context, *OtpConsumeRequest) (*OtpConsumeResponse, error)
+	mustEmbedUnimplementedOtpServiceServer()
+}
+
+// UnimplementedOtpServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedOtpServiceServer struct {
+}
+
+func (UnimplementedOtpServiceServer) Add(context.Context, *OtpSecretRequest) (*OtpSecretResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
+}
+func (UnimplementedOtpServiceServer) Code(context.Context, *OtpRequest) (*OtpCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Code not implemented")
+}
+func (UnimplementedOtpServiceServer) Consume(context.Context, *OtpConsumeRequest) (*OtpConsumeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Consume not implemented")
+}
+func (UnimplementedOtpServiceServer) mustEmbedUnimplementedOtpServiceServer() {}
+
+// UnsafeOtpServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OtpServiceServer will
+// result in compilation errors.
+type UnsafeOtpServiceServer interface {
+	mustEmbedUnimplementedOtpServiceServer()
+}
+
+func RegisterOtpServiceServer(s grpc.ServiceRegistrar, srv OtpServiceServer) {
+	s.RegisterService(&OtpService_ServiceDesc, srv)
+}
+
+func _OtpService_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OtpSecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OtpServiceServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OtpService_Add_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OtpServiceServer).Add(ctx, req.(*OtpSecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OtpService_Code_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OtpRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OtpServiceServer).Code(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OtpService_Code_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OtpServiceServer).Code(ctx, req.(*OtpRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OtpService_Consume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OtpConsumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OtpServiceServer).Consume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OtpService_Consume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OtpServiceServer).Consume(ctx, req.(*OtpConsumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OtpService_ServiceDesc is the grpc.ServiceDesc for OtpService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OtpService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.OtpService",
+	HandlerType: (*OtpServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Add",
+			Handler:    _OtpService_Add_Handler,
+		},
+		{
+			MethodName: "Code",
+			Handler:    _OtpService_Code_Handler,
+		},
+		{
+			MethodName: "Consume",
+			Handler:    _OtpService_Consume_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/otp.proto",
+}